@@ -723,12 +723,15 @@ export class RdioScannerAdminService implements OnDestroy {
         });
     }
 
-    async saveConfig(config: Config, isFullImport: boolean = false): Promise<Config> {
+    async saveConfig(config: Config, isFullImport: boolean = false, acknowledgeRetention: boolean = false): Promise<Config> {
         try {
             let headers = this.getHeaders();
             if (isFullImport) {
                 headers = headers.set('X-Full-Import', 'true');
             }
+            if (acknowledgeRetention) {
+                headers = headers.set('X-Acknowledge-Retention', 'true');
+            }
             
             const res = await firstValueFrom(this.ngHttpClient.put<{ config: Config }>(
                 this.getUrl(url.config),
@@ -739,6 +742,15 @@ export class RdioScannerAdminService implements OnDestroy {
             return res.config;
 
         } catch (error) {
+            // Retention settings deleting calls before they can be transcribed again need a confirmation
+            if (!acknowledgeRetention && error instanceof HttpErrorResponse && error.status === 409 && Array.isArray(error.error?.warnings)) {
+                if (confirm(`${error.error.warnings.join('\n')}\n\nApply these retention settings anyway?`)) {
+                    return this.saveConfig(config, isFullImport, true);
+                }
+
+                return config;
+            }
+
             this.errorHandler(error);
 
             return config;
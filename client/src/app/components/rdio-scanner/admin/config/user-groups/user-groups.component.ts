@@ -28,6 +28,7 @@ interface PricingOption {
   label: string;
   amount: string;
   trialDays?: number; // Optional: 0 = no trial, 1-30 = trial days
+  couponId?: string; // Optional: Stripe coupon applied unless the user types a coupon code
 }
 
 interface UserGroup {
@@ -215,7 +216,8 @@ export class RdioScannerAdminUserGroupsComponent implements OnInit, OnChanges {
       priceId: [option?.priceId || ''],
       label: [option?.label || ''],
       amount: [option?.amount || ''],
-      trialDays: [option?.trialDays || 0] // Default to 0 (no trial)
+      trialDays: [option?.trialDays || 0], // Default to 0 (no trial)
+      couponId: [option?.couponId || '']
     });
   }
 
@@ -42,6 +42,8 @@ export class RdioScannerStripeCheckoutComponent implements OnInit, OnDestroy {
   loading = false;
   error: string | null = null;
   selectedPriceId: string | null = null;
+  couponCode = '';
+  couponValid: boolean | null = null;
 
   ngOnInit(): void {
     this.initializeStripe();
@@ -78,6 +80,37 @@ export class RdioScannerStripeCheckoutComponent implements OnInit, OnDestroy {
     this.error = null;
   }
 
+  async applyCoupon(): Promise<void> {
+    const code = this.couponCode.trim();
+
+    this.couponValid = null;
+    this.error = null;
+
+    if (!code) {
+      return;
+    }
+
+    try {
+      const response = await fetch('/api/stripe/validate-coupon', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ code })
+      });
+
+      const result = await response.json();
+
+      this.couponValid = !!result.valid;
+      if (!result.valid) {
+        this.error = result.error || 'Invalid coupon code.';
+      }
+    } catch (err: any) {
+      this.couponValid = false;
+      this.error = err.message || 'Failed to validate the coupon code.';
+    }
+  }
+
   async handleSubmit(): Promise<void> {
     const priceId = this.selectedPriceId;
     
@@ -110,7 +143,8 @@ export class RdioScannerStripeCheckoutComponent implements OnInit, OnDestroy {
           priceId: priceId,
           email: this.email,
           successUrl: successUrl,
-          cancelUrl: cancelUrl
+          cancelUrl: cancelUrl,
+          couponCode: this.couponCode.trim()
         })
       });
 
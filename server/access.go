@@ -242,6 +242,12 @@ func (accesses *Accesses) IsRestricted() bool {
 	return len(accesses.List) > 0
 }
 
+// legacyAccessCodes tells if the legacy access codes are in use, otherwise the accesses table
+// is left alone as the access codes are converted to user groups
+func legacyAccessCodes(db *Database) bool {
+	return db != nil && db.Config != nil && db.Config.LegacyAccessCodes
+}
+
 func (accesses *Accesses) Read(db *Database) error {
 	var (
 		err        error
@@ -258,6 +264,10 @@ func (accesses *Accesses) Read(db *Database) error {
 
 	accesses.List = []*Access{}
 
+	if !legacyAccessCodes(db) {
+		return nil
+	}
+
 	formatError := func(err error) error {
 		return fmt.Errorf("accesses.read: %v", err)
 	}
@@ -390,6 +400,13 @@ func (accesses *Accesses) Write(db *Database) error {
 	accesses.mutex.Lock()
 	defer accesses.mutex.Unlock()
 
+	if !legacyAccessCodes(db) {
+		if len(accesses.List) > 0 {
+			return fmt.Errorf("accesses.write: %d access codes not written, legacy_access_codes is off", len(accesses.List))
+		}
+		return nil
+	}
+
 	log.Printf("DEBUG: Accesses.Write() starting - writing %d access codes to database", len(accesses.List))
 
 	formatError := func(err error) error {
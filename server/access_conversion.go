@@ -0,0 +1,370 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const accessConversionMigration = "20261016000000-convert-accesses"
+
+// accessConversionDescription tells the user groups created by the conversion apart from the others
+const accessConversionDescription = "Converted from legacy access codes"
+
+// AccessConversion describes what a legacy access code was converted to
+type AccessConversion struct {
+	AccessId     uint64
+	Code         string
+	Ident        string
+	SystemAccess string // systemAccess of the user group, empty for every system
+	GroupName    string
+	NewGroup     bool
+	UserCreated  bool
+	Skipped      string // reason the access code was not converted
+	Note         string
+}
+
+// accessSystemAccess returns the user group systemAccess equivalent to the systems of an access code.
+// ok is false when the access code grants no system at all, as an empty systemAccess means every system.
+func accessSystemAccess(access *Access) (systemAccess string, ok bool) {
+	switch v := access.Systems.(type) {
+	case string:
+		return "", v == "*"
+
+	case []any:
+		scopes := map[uint]any{}
+
+		for _, f := range v {
+			m, isMap := f.(map[string]any)
+			if !isMap {
+				continue
+			}
+
+			id, isNumber := m["id"].(float64)
+			if !isNumber {
+				continue
+			}
+			systemRef := uint(id)
+
+			switch tg := m["talkgroups"].(type) {
+			case string:
+				if tg == "*" {
+					scopes[systemRef] = "*"
+				}
+
+			case []any:
+				if scopes[systemRef] == "*" {
+					continue
+				}
+				talkgroups, _ := scopes[systemRef].([]uint)
+				for _, t := range tg {
+					if ref, isNumber := t.(float64); isNumber {
+						talkgroups = append(talkgroups, uint(ref))
+					}
+				}
+				if len(talkgroups) > 0 {
+					scopes[systemRef] = talkgroups
+				}
+			}
+		}
+
+		if len(scopes) == 0 {
+			return "", false
+		}
+
+		systemRefs := make([]uint, 0, len(scopes))
+		for systemRef := range scopes {
+			systemRefs = append(systemRefs, systemRef)
+		}
+		sort.Slice(systemRefs, func(i, j int) bool { return systemRefs[i] < systemRefs[j] })
+
+		systems := []map[string]any{}
+		for _, systemRef := range systemRefs {
+			if talkgroups, isList := scopes[systemRef].([]uint); isList {
+				sort.Slice(talkgroups, func(i, j int) bool { return talkgroups[i] < talkgroups[j] })
+				scopes[systemRef] = talkgroups
+			}
+			systems = append(systems, map[string]any{"id": systemRef, "talkgroups": scopes[systemRef]})
+		}
+
+		b, err := json.Marshal(systems)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+
+	return "", false
+}
+
+// planAccessConversion maps each access code to a dedicated user group with an equivalent systemAccess,
+// reusing one created by a previous conversion, keyed by its systemAccess, or one planned for a previous
+// code. The other groups, which may bill their users, are never reused and only their names are avoided.
+func planAccessConversion(accesses []*Access, groups map[string]string, taken []string) []*AccessConversion {
+	conversions := []*AccessConversion{}
+	names := map[string]bool{}
+
+	for _, name := range taken {
+		names[strings.ToLower(name)] = true
+	}
+	for _, name := range groups {
+		names[strings.ToLower(name)] = true
+	}
+
+	for _, access := range accesses {
+		conversion := &AccessConversion{Code: access.Code, Ident: access.Ident}
+		if id, ok := access.Id.(uint64); ok {
+			conversion.AccessId = id
+		}
+		conversions = append(conversions, conversion)
+
+		systemAccess, ok := accessSystemAccess(access)
+		if !ok {
+			conversion.Skipped = "grants access to no system"
+			continue
+		}
+		conversion.SystemAccess = systemAccess
+
+		if name, found := groups[systemAccess]; found {
+			conversion.GroupName = name
+			continue
+		}
+
+		base := "Legacy Access"
+		if ident := strings.TrimSpace(access.Ident); ident != "" && ident != "Anonymous" {
+			base = fmt.Sprintf("Legacy Access %s", ident)
+		}
+
+		name := base
+		for i := 2; names[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s %d", base, i)
+		}
+
+		names[strings.ToLower(name)] = true
+		groups[systemAccess] = name
+
+		conversion.GroupName = name
+		conversion.NewGroup = true
+	}
+
+	return conversions
+}
+
+// migrateAccessesToUserGroups converts the legacy access codes into user groups with the same
+// system scope, and optionally into users with the access code as pin, before the legacy table is dropped.
+// A failed conversion doesn't prevent the server from starting, the table being kept to retry at the next start.
+func migrateAccessesToUserGroups(db *Database) error {
+	var count int
+
+	formatError := errorFormatter("migration", "migrateAccessesToUserGroups")
+
+	// the legacy access codes are kept as they are
+	if db.Config.LegacyAccessCodes {
+		return nil
+	}
+
+	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "rdioScannerMeta" WHERE "name" = $1`, accessConversionMigration).Scan(&count); err != nil {
+		return formatError(err, "")
+	}
+
+	if count == 0 {
+		if err := convertAccesses(db, db.Config.AccessCodeUsers); err != nil {
+			log.Printf("WARNING: %v, the legacy access codes are kept until the next start", formatError(err, ""))
+			return nil
+		}
+	}
+
+	// Drop legacy accesses table if it still exists
+	if _, err := db.Sql.Exec(`DROP TABLE IF EXISTS "accesses"`); err != nil {
+		log.Printf("DEBUG: Unable to drop legacy accesses table: %v", err)
+	}
+
+	return nil
+}
+
+func convertAccesses(db *Database, createUsers bool) error {
+	var (
+		accessId   sql.NullFloat64
+		expiration sql.NullTime
+		limit      sql.NullFloat64
+		systems    string
+		tx         *sql.Tx
+	)
+
+	accesses := []*Access{}
+	expirations := map[string]int64{}
+	limits := map[string]uint{}
+
+	if rows, err := db.Sql.Query(`SELECT "accessId", "code", "expiration", "ident", "limit", "systems" FROM "accesses" ORDER BY "accessId"`); err == nil {
+		for rows.Next() {
+			access := NewAccess()
+			if err := rows.Scan(&accessId, &access.Code, &expiration, &access.Ident, &limit, &systems); err != nil || access.Code == "" || !accessId.Valid {
+				continue
+			}
+			access.Id = uint64(accessId.Float64)
+			if systems != "*" {
+				if err := json.Unmarshal([]byte(systems), &access.Systems); err != nil {
+					access.Systems = []any{}
+				}
+			}
+			if expiration.Valid {
+				expirations[access.Code] = expiration.Time.Unix()
+			}
+			if limit.Valid && limit.Float64 > 0 {
+				limits[access.Code] = uint(limit.Float64)
+			}
+			accesses = append(accesses, access)
+		}
+		rows.Close()
+	}
+
+	groups := map[string]string{}
+	groupIds := map[string]uint64{}
+	names := []string{}
+
+	if rows, err := db.Sql.Query(`SELECT "userGroupId", "name", "description", "systemAccess", "billingEnabled" FROM "userGroups" ORDER BY "userGroupId"`); err == nil {
+		for rows.Next() {
+			var (
+				billingEnabled bool
+				description    sql.NullString
+				id             uint64
+				name           string
+				systemAccess   string
+			)
+			if err := rows.Scan(&id, &name, &description, &systemAccess, &billingEnabled); err != nil {
+				continue
+			}
+			names = append(names, name)
+			if description.String != accessConversionDescription || billingEnabled {
+				continue
+			}
+			if _, found := groups[systemAccess]; !found {
+				groups[systemAccess] = name
+				groupIds[name] = id
+			}
+		}
+		rows.Close()
+	} else {
+		return err
+	}
+
+	conversions := planAccessConversion(accesses, groups, names)
+
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	users := 0
+
+	for _, conversion := range conversions {
+		if conversion.Skipped != "" {
+			continue
+		}
+
+		if conversion.NewGroup {
+			var id uint64
+			if err = tx.QueryRow(`INSERT INTO "userGroups" ("name", "description", "systemAccess", "billingEnabled", "createdAt") VALUES ($1, $2, $3, false, $4) RETURNING "userGroupId"`, conversion.GroupName, accessConversionDescription, conversion.SystemAccess, now.Unix()).Scan(&id); err != nil {
+				tx.Rollback()
+				return err
+			}
+			groupIds[conversion.GroupName] = id
+		}
+
+		if !createUsers {
+			continue
+		}
+
+		var pinCount int
+		if err = tx.QueryRow(`SELECT COUNT(*) FROM "users" WHERE "pin" = $1`, conversion.Code).Scan(&pinCount); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if pinCount > 0 {
+			conversion.Note = "no user created, the access code is already used as a user pin"
+			continue
+		}
+
+		// Access code users sign in with their pin only, the address is a placeholder that never receives mail,
+		// unique as derived from the access id
+		email := fmt.Sprintf("access-%d@legacy.invalid", conversion.AccessId)
+
+		var emailCount int
+		if err = tx.QueryRow(`SELECT COUNT(*) FROM "users" WHERE "email" = $1`, email).Scan(&emailCount); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if emailCount > 0 {
+			conversion.Note = "user already created by a previous conversion"
+			continue
+		}
+
+		if err = execSavepoint(tx, `INSERT INTO "users" ("email", "password", "pin", "connectionLimit", "verified", "createdAt", "lastLogin", "firstName", "accountExpiresAt", "userGroupId") VALUES ($1, '', $2, $3, true, $4, '0', $5, $6, $7)`, email, conversion.Code, limits[conversion.Code], fmt.Sprintf("%d", now.Unix()), conversion.Ident, expirations[conversion.Code], groupIds[conversion.GroupName]); err != nil {
+			conversion.Note = fmt.Sprintf("no user created: %v", err)
+			err = nil
+			continue
+		}
+		conversion.UserCreated = true
+		users++
+	}
+
+	if _, err = tx.Exec(`INSERT INTO "rdioScannerMeta" ("name") VALUES ($1)`, accessConversionMigration); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(conversions) > 0 {
+		log.Printf("converted %d legacy access codes, %d users created", len(conversions), users)
+	}
+	for _, conversion := range conversions {
+		switch {
+		case conversion.Skipped != "":
+			log.Printf("access code %s (%s) skipped: %s", maskAccessCode(conversion.Code), conversion.Ident, conversion.Skipped)
+		case conversion.NewGroup:
+			log.Printf("access code %s (%s) mapped to new user group %q%s", maskAccessCode(conversion.Code), conversion.Ident, conversion.GroupName, conversion.note())
+		default:
+			log.Printf("access code %s (%s) mapped to user group %q%s", maskAccessCode(conversion.Code), conversion.Ident, conversion.GroupName, conversion.note())
+		}
+	}
+
+	return nil
+}
+
+func (conversion *AccessConversion) note() string {
+	if conversion.Note == "" {
+		return ""
+	}
+	return ", " + conversion.Note
+}
+
+// maskAccessCode keeps access codes out of the logs, as they are still valid as pins
+func maskAccessCode(code string) string {
+	if len(code) <= 4 {
+		return "****"
+	}
+	return code[:2] + strings.Repeat("*", len(code)-4) + code[len(code)-2:]
+}
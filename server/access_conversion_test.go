@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestPlanAccessConversion(t *testing.T) {
+	accesses := []*Access{
+		{Code: "ALLSYSTEMS", Ident: "Anonymous", Systems: "*"},
+		{Code: "FIRE", Ident: "Fire", Systems: []any{
+			map[string]any{"id": float64(2), "talkgroups": []any{float64(200), float64(100)}},
+			map[string]any{"id": float64(1), "talkgroups": "*"},
+		}},
+		{Code: "FIRE2", Ident: "Fire", Systems: []any{
+			map[string]any{"id": float64(1), "talkgroups": "*"},
+			map[string]any{"id": float64(2), "talkgroups": []any{float64(100), float64(200)}},
+		}},
+		{Code: "POLICE", Ident: "Police", Systems: []any{
+			map[string]any{"id": float64(3), "talkgroups": []any{float64(300)}},
+		}},
+		{Code: "NOTHING", Ident: "Nobody", Systems: []any{}},
+	}
+
+	// Everyone is not a converted group, it may bill its users
+	conversions := planAccessConversion(accesses, map[string]string{"": "Legacy Access"}, []string{"Everyone", "Legacy Access", "Legacy Access Fire"})
+
+	if len(conversions) != len(accesses) {
+		t.Fatalf("expected %d conversions, got %d", len(accesses), len(conversions))
+	}
+
+	if c := conversions[0]; c.GroupName != "Legacy Access" || c.NewGroup {
+		t.Errorf("wildcard access should map to the group of a previous conversion, got %q new=%t", c.GroupName, c.NewGroup)
+	}
+	if c := conversions[1]; c.GroupName != "Legacy Access Fire 2" || !c.NewGroup {
+		t.Errorf("expected a new group for the fire access, named apart from the existing ones, got %q new=%t", c.GroupName, c.NewGroup)
+	}
+	if c := conversions[2]; c.GroupName != "Legacy Access Fire 2" || c.NewGroup {
+		t.Errorf("access with the same scope should share the group, got %q new=%t", c.GroupName, c.NewGroup)
+	}
+	if c := conversions[3]; c.GroupName != "Legacy Access Police" || !c.NewGroup {
+		t.Errorf("expected a new group for the police access, got %q new=%t", c.GroupName, c.NewGroup)
+	}
+	if c := conversions[4]; c.Skipped == "" {
+		t.Error("access granting no system should be skipped")
+	}
+
+	calls := []*Call{}
+	for _, s := range []uint{1, 2, 3, 4} {
+		for _, tg := range []uint{100, 200, 300, 400} {
+			calls = append(calls, &Call{System: &System{SystemRef: s}, Talkgroup: &Talkgroup{TalkgroupRef: tg}})
+		}
+	}
+
+	for i, access := range accesses[:4] {
+		group := &UserGroup{SystemAccess: conversions[i].SystemAccess}
+		group.loadSystemAccess()
+
+		for _, call := range calls {
+			want := access.HasAccess(call)
+			got := group.HasTalkgroupAccess(uint64(call.System.SystemRef), call.Talkgroup.TalkgroupRef)
+			if got != want {
+				t.Errorf("%s: system %d talkgroup %d, group access %t, access code %t", access.Code, call.System.SystemRef, call.Talkgroup.TalkgroupRef, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a database which records the queries it receives, with their arguments, and holds no rows
+// unless given the rows every query returns. The queries returning rows are given to onQuery first, when set.
+type recordingDriver struct {
+	mutex   sync.Mutex
+	queries []string
+	args    [][]driver.Value
+	rows    [][]driver.Value
+	onQuery func(query string)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+func (d *recordingDriver) record(query string, args []driver.Value) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.queries = append(d.queries, query)
+	d.args = append(d.args, args)
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error { return nil }
+
+func (recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s *recordingStmt) Close() error { return nil }
+
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.record(s.query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.driver.onQuery != nil {
+		s.driver.onQuery(s.query)
+	}
+	s.driver.record(s.query, args)
+	return &recordingRows{rows: s.driver.rows}, nil
+}
+
+type recordingRows struct {
+	rows [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *recordingRows) Close() error { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (d *recordingDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return d.Open("")
+}
+
+func (d *recordingDriver) Driver() driver.Driver {
+	return d
+}
+
+func newRecordingDatabase(t *testing.T, config *Config) (*Database, *recordingDriver) {
+	d := &recordingDriver{}
+
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+
+	return &Database{Config: config, Sql: db}, d
+}
+
+func TestAccessesLegacyOff(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	accesses := NewAccesses()
+
+	if err := accesses.Write(db); err != nil {
+		t.Fatal(err)
+	}
+
+	accesses.Add(&Access{Code: "1234", Ident: "Scanner", Systems: "*"})
+
+	if err := accesses.Write(db); err == nil {
+		t.Error("expected the access codes not written to be reported")
+	}
+	if err := accesses.Read(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.queries) > 0 {
+		t.Errorf("expected the accesses table to be left alone, got %v", d.queries)
+	}
+	if accesses.IsRestricted() {
+		t.Error("expected no access code to be loaded")
+	}
+}
+
+func TestAccessesLegacyOn(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql, LegacyAccessCodes: true})
+
+	accesses := NewAccesses()
+
+	if err := accesses.Write(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := accesses.Read(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.queries) != 2 {
+		t.Fatalf("expected the accesses table to be written and read, got %v", d.queries)
+	}
+	for _, query := range d.queries {
+		if !strings.Contains(query, `"accesses"`) {
+			t.Errorf("expected a query of the accesses table, got %s", query)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessTrace is a readable breakdown of the access decision made for a user and a call.
+// It mirrors the checks done by Controller.userHasAccess and Controller.userEffectiveDelay.
+type AccessTrace struct {
+	CallId           uint64             `json:"callId"`
+	UserId           uint64             `json:"userId"`
+	UserGroupId      uint64             `json:"userGroupId,omitempty"`
+	SystemRef        uint               `json:"systemRef"`
+	TalkgroupRef     uint               `json:"talkgroupRef"`
+	AuthRequired     bool               `json:"authRequired"`
+	Group            *AccessTraceScope  `json:"group,omitempty"`
+	User             AccessTraceScope   `json:"user"`
+	Patches          []AccessTracePatch `json:"patches"`
+	Granted          bool               `json:"granted"`
+	BaseDelay        uint               `json:"baseDelay"`      // minutes
+	EffectiveDelay   uint               `json:"effectiveDelay"` // minutes
+	DelaySource      string             `json:"delaySource"`
+	CurrentlyDelayed bool               `json:"currentlyDelayed"`
+	Steps            []string           `json:"steps"`
+}
+
+// AccessTraceScope describes how one scope (group or user) evaluated the call
+type AccessTraceScope struct {
+	Format         string `json:"format"` // "unrestricted", "legacy" or "scoped"
+	SystemMatch    bool   `json:"systemMatch"`
+	TalkgroupMatch bool   `json:"talkgroupMatch"`
+	Granted        bool   `json:"granted"`
+}
+
+// AccessTracePatch reports whether the user could access a patched talkgroup of the call
+type AccessTracePatch struct {
+	TalkgroupRef uint `json:"talkgroupRef"`
+	Accessible   bool `json:"accessible"`
+}
+
+// TraceCallAccess evaluates the access of a user to a call and records every step of the decision.
+// The currentlyDelayed field is left for the caller to fill since it requires the database.
+func (controller *Controller) TraceCallAccess(user *User, call *Call) *AccessTrace {
+	trace := &AccessTrace{
+		CallId:  call.Id,
+		UserId:  user.Id,
+		Patches: []AccessTracePatch{},
+		Steps:   []string{},
+	}
+
+	step := func(format string, args ...any) {
+		trace.Steps = append(trace.Steps, fmt.Sprintf(format, args...))
+	}
+
+	if call.System == nil || call.Talkgroup == nil {
+		trace.Granted = controller.userHasAccess(user, call)
+		step("call has no resolved system or talkgroup, access defaults to %t", trace.Granted)
+		return trace
+	}
+
+	trace.SystemRef = call.System.SystemRef
+	trace.TalkgroupRef = call.Talkgroup.TalkgroupRef
+	trace.AuthRequired = controller.requiresUserAuth()
+
+	if !trace.AuthRequired {
+		step("user authentication is not required, every client receives this call")
+	}
+
+	granted := true
+
+	if user.UserGroupId > 0 {
+		trace.UserGroupId = user.UserGroupId
+
+		if group := controller.UserGroups.Get(user.UserGroupId); group != nil {
+			scope := &AccessTraceScope{}
+
+			switch {
+			case group.systemAccessDataNew != nil:
+				scope.Format = "scoped"
+			case len(group.systemAccessData) > 0:
+				scope.Format = "legacy"
+			default:
+				scope.Format = "unrestricted"
+			}
+
+			scope.SystemMatch = group.HasSystemAccess(uint64(call.System.SystemRef))
+			scope.TalkgroupMatch = scope.SystemMatch && group.HasTalkgroupAccess(uint64(call.System.SystemRef), call.Talkgroup.TalkgroupRef)
+			scope.Granted = scope.SystemMatch && scope.TalkgroupMatch
+			trace.Group = scope
+
+			step("group %d (%s, %s): system %d match=%t, talkgroup %d match=%t", group.Id, group.Name, scope.Format, call.System.SystemRef, scope.SystemMatch, call.Talkgroup.TalkgroupRef, scope.TalkgroupMatch)
+
+			granted = scope.Granted
+		} else {
+			step("group %d not found, only user scope applies", user.UserGroupId)
+		}
+	}
+
+	trace.User = traceUserScope(user, call)
+	step("user scope (%s): system %d match=%t, talkgroup %d match=%t", trace.User.Format, call.System.SystemRef, trace.User.SystemMatch, call.Talkgroup.TalkgroupRef, trace.User.TalkgroupMatch)
+
+	granted = granted && trace.User.Granted
+	trace.Granted = granted || !trace.AuthRequired
+
+	for _, ref := range call.Patches {
+		if ref == call.Talkgroup.TalkgroupRef {
+			continue
+		}
+
+		patched := &Call{
+			Id:        call.Id,
+			System:    call.System,
+			Talkgroup: &Talkgroup{TalkgroupRef: ref},
+		}
+
+		if call.System.Talkgroups != nil {
+			if talkgroup, ok := call.System.Talkgroups.GetTalkgroupByRef(ref); ok {
+				patched.Talkgroup = talkgroup
+			}
+		}
+
+		accessible := controller.userHasAccess(user, patched)
+		trace.Patches = append(trace.Patches, AccessTracePatch{TalkgroupRef: ref, Accessible: accessible})
+		step("patched talkgroup %d accessible=%t (patches do not grant access to the primary talkgroup)", ref, accessible)
+	}
+
+	trace.BaseDelay = controller.Delayer.getSystemDelay(call)
+	trace.EffectiveDelay, trace.DelaySource = resolveDelay(user, controller.userGroup(user), call, controller.Options.DefaultSystemDelay)
+
+	step("effective delay %d minute(s) from %s settings", trace.EffectiveDelay, trace.DelaySource)
+
+	if trace.Granted {
+		step("access granted")
+	} else {
+		step("access denied")
+	}
+
+	return trace
+}
+
+func traceUserScope(user *User, call *Call) AccessTraceScope {
+	scope := AccessTraceScope{}
+
+	switch v := user.systemsData.(type) {
+	case nil:
+		scope.Format = "unrestricted"
+	case string:
+		if strings.TrimSpace(v) == "" || v == "*" {
+			scope.Format = "unrestricted"
+		} else {
+			scope.Format = "scoped"
+		}
+	default:
+		scope.Format = "scoped"
+	}
+
+	if scope.Format == "unrestricted" {
+		scope.SystemMatch = true
+		scope.TalkgroupMatch = true
+		scope.Granted = true
+		return scope
+	}
+
+	scope.Granted = user.HasAccess(call)
+	scope.TalkgroupMatch = scope.Granted
+
+	scope.SystemMatch = scope.Granted || userScopesSystem(user, call.System.SystemRef)
+
+	return scope
+}
+
+func userScopesSystem(user *User, systemRef uint) bool {
+	scopes, ok := user.systemsData.([]any)
+	if !ok {
+		return false
+	}
+
+	for _, scope := range scopes {
+		if m, ok := scope.(map[string]any); ok {
+			if id, ok := parseUintFromAny(m["id"]); ok && id == systemRef {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func newAccessTraceController() *Controller {
+	controller := &Controller{
+		Options:    NewOptions(),
+		UserGroups: NewUserGroups(),
+		Users:      NewUsers(),
+	}
+	controller.Options.UserRegistrationEnabled = true
+	controller.Delayer = NewDelayer(controller)
+	return controller
+}
+
+func newAccessTraceCall() *Call {
+	call := NewCall()
+	call.Id = 1
+	call.System = &System{Id: 1, SystemRef: 10, Delay: 2}
+	call.Talkgroup = &Talkgroup{Id: 1, TalkgroupRef: 100}
+	return call
+}
+
+func TestTraceCallAccessGranted(t *testing.T) {
+	controller := newAccessTraceController()
+
+	group := &UserGroup{Id: 1, Name: "Fire", SystemAccess: `[{"id":10,"talkgroups":[100]}]`, Delay: 5}
+	group.loadSystemAccess()
+	group.loadSystemDelays()
+	group.loadTalkgroupDelays()
+	controller.UserGroups.groups[group.Id] = group
+
+	user := &User{Id: 7, UserGroupId: group.Id}
+	user.loadSystemScopes()
+	user.loadDelayMaps()
+
+	trace := controller.TraceCallAccess(user, newAccessTraceCall())
+
+	if !trace.Granted {
+		t.Fatalf("expected access to be granted, steps: %v", trace.Steps)
+	}
+	if trace.Group == nil || trace.Group.Format != "scoped" || !trace.Group.TalkgroupMatch {
+		t.Errorf("expected scoped group talkgroup match, got %+v", trace.Group)
+	}
+	if trace.BaseDelay != 2 || trace.EffectiveDelay != 5 || trace.DelaySource != "group" {
+		t.Errorf("expected group delay 5 over system delay 2, got base=%d effective=%d source=%s", trace.BaseDelay, trace.EffectiveDelay, trace.DelaySource)
+	}
+}
+
+func TestTraceCallAccessDenied(t *testing.T) {
+	controller := newAccessTraceController()
+
+	user := &User{Id: 8, Systems: `[{"id":10,"talkgroups":[200]}]`}
+	user.loadSystemScopes()
+	user.loadDelayMaps()
+
+	call := newAccessTraceCall()
+	call.Patches = []uint{200}
+
+	trace := controller.TraceCallAccess(user, call)
+
+	if trace.Granted {
+		t.Fatalf("expected access to be denied, steps: %v", trace.Steps)
+	}
+	if !trace.User.SystemMatch || trace.User.TalkgroupMatch {
+		t.Errorf("expected system match without talkgroup match, got %+v", trace.User)
+	}
+	if len(trace.Patches) != 1 || !trace.Patches[0].Accessible {
+		t.Errorf("expected patched talkgroup 200 to be reported accessible, got %+v", trace.Patches)
+	}
+	if trace.DelaySource != "system" {
+		t.Errorf("expected system delay source, got %s", trace.DelaySource)
+	}
+}
@@ -212,6 +212,79 @@ func (admin *Admin) SystemHealthHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// TranscriptionQueueHandler returns the depth of the transcription queue and the calls being transcribed
+func (admin *Admin) TranscriptionQueueHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := map[string]any{
+		"enabled": admin.Controller.Options.TranscriptionConfig.Enabled,
+	}
+
+	if queue := admin.Controller.TranscriptionQueue; queue != nil {
+		response["queue"] = queue.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// TranscriptionRequeueHandler queues stored calls for transcription again, e.g. for a keyword backfill
+func (admin *Admin) TranscriptionRequeueHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var request struct {
+		CallIds []uint64 `json:"callIds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.CallIds) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "callIds are required"})
+		return
+	}
+
+	if !admin.Controller.Options.TranscriptionConfig.Enabled {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": errRequeueUnavailable.Error()})
+		return
+	}
+
+	requeued := 0
+	failed := map[string]string{}
+
+	for _, callId := range request.CallIds {
+		if err := admin.Controller.RequeueTranscription(callId); err != nil {
+			failed[strconv.FormatUint(callId, 10)] = err.Error()
+		} else {
+			requeued++
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"requeued": requeued,
+		"failed":   failed,
+	})
+}
+
 func (admin *Admin) TranscriptionFailuresHandler(w http.ResponseWriter, r *http.Request) {
 	t := admin.GetAuthorization(r)
 	if !admin.ValidateToken(t) {
@@ -559,6 +632,264 @@ func (admin *Admin) TranscriptionFailureThresholdHandler(w http.ResponseWriter,
 	}
 }
 
+// AccessTraceHandler explains why a user does or does not receive a call
+// (e.g., /api/admin/access-trace?callId=12345&userId=42)
+func (admin *Admin) AccessTraceHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	callId, err := strconv.ParseUint(r.URL.Query().Get("callId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid call ID format"})
+		return
+	}
+
+	userId, err := strconv.ParseUint(r.URL.Query().Get("userId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid user ID format"})
+		return
+	}
+
+	user := admin.Controller.Users.GetUserById(userId)
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user not found"})
+		return
+	}
+
+	// Read the call even when it is still delayed, the delay is part of the trace
+	call, err := admin.Controller.Calls.readCall(callId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("call not found: %v", err)})
+		return
+	}
+
+	trace := admin.Controller.TraceCallAccess(user, call)
+	trace.CurrentlyDelayed = admin.Controller.Delayer.IsCallDelayed(callId)
+
+	json.NewEncoder(w).Encode(trace)
+}
+
+// DemoHandler seeds (POST) or removes (DELETE) the demo data used to evaluate the server
+// (e.g., POST /api/admin/demo?force=true to seed a database which already has systems)
+func (admin *Admin) DemoHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	reload := func() error {
+		if err := admin.Controller.Tags.Read(admin.Controller.Database); err != nil {
+			return err
+		}
+		if err := admin.Controller.Groups.Read(admin.Controller.Database); err != nil {
+			return err
+		}
+		return admin.Controller.Systems.Read(admin.Controller.Database)
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		force := r.URL.Query().Get("force") == "true"
+
+		result, err := SeedDemo(admin.Controller.Database, force)
+		if err != nil {
+			if err == errDemoPresent || err == errDemoPopulated {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := reload(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		admin.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("demo data seeded: %d talkgroups, %d calls", result.Talkgroups, result.Calls))
+
+		go admin.Controller.EmitConfig()
+
+		json.NewEncoder(w).Encode(result)
+
+	case http.MethodDelete:
+		if err := RemoveDemo(admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := reload(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		admin.Controller.Logs.LogEvent(LogLevelInfo, "demo data removed")
+
+		go admin.Controller.EmitConfig()
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// VocabularyProfilesHandler manages the transcription vocabulary profiles, systems select one with their vocabularyProfileId
+func (admin *Admin) VocabularyProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	profiles := admin.Controller.VocabularyProfiles
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(profiles.GetAll())
+
+	case http.MethodPost, http.MethodPut:
+		profile := &VocabularyProfile{}
+		if err := json.NewDecoder(r.Body).Decode(profile); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+			return
+		}
+
+		var err error
+		if r.Method == http.MethodPost {
+			profile.Id = 0
+			err = profiles.Add(profile, admin.Controller.Database)
+		} else if profiles.Get(profile.Id) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "vocabulary profile not found"})
+			return
+		} else {
+			err = profiles.Update(profile, admin.Controller.Database)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(profile)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		if err != nil || profiles.Get(id) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "vocabulary profile not found"})
+			return
+		}
+
+		if err := profiles.Delete(id, admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// systems that used the profile have been reset to the default one
+		if err := admin.Controller.Systems.Read(admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// AlertTestWindowsHandler manages the test windows during which alerts are recorded without notification
+func (admin *Admin) AlertTestWindowsHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	windows := admin.Controller.AlertTestWindows
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(windows.GetAll())
+
+	case http.MethodPost:
+		window := &AlertTestWindow{}
+		if err := json.NewDecoder(r.Body).Decode(window); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+			return
+		}
+
+		if window.SystemId > 0 {
+			if _, ok := admin.Controller.Systems.GetSystemById(window.SystemId); !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "system not found"})
+				return
+			}
+		}
+
+		window.Id = 0
+		if err := windows.Add(window, admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		admin.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("alert test window %d scheduled, notifications suppressed from %s to %s", window.Id, time.UnixMilli(window.StartsAt).Format(time.RFC3339), time.UnixMilli(window.EndsAt).Format(time.RFC3339)))
+
+		json.NewEncoder(w).Encode(window)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		if err != nil || windows.Get(id) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "alert test window not found"})
+			return
+		}
+
+		if err := windows.Delete(id, admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // CallAudioHandler serves call audio for admin playback
 func (admin *Admin) CallAudioHandler(w http.ResponseWriter, r *http.Request) {
 	t := admin.GetAuthorization(r)
@@ -596,10 +927,14 @@ func (admin *Admin) CallAudioHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeCallAudio(w, call, admin.Controller.Options.MissingAudioResponse)
+}
+
+// writeCallAudio serves the audio of a call, or the configured error when it was archived
+func writeCallAudio(w http.ResponseWriter, call *Call, missingAudioResponse string) {
 	// Check if call has audio
-	if len(call.Audio) == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "call has no audio"})
+	if call.audioMissing() {
+		writeMissingAudio(w, missingAudioResponse)
 		return
 	}
 
@@ -612,7 +947,7 @@ func (admin *Admin) CallAudioHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", mimeType)
 	w.Header().Set("Content-Length", strconv.Itoa(len(call.Audio)))
 	w.Header().Set("Cache-Control", "private, max-age=3600")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"call-%d.%s\"", callId, getAudioExtension(mimeType)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"call-%d.%s\"", call.Id, getAudioExtension(mimeType)))
 
 	// Write audio data
 	w.Write(call.Audio)
@@ -663,6 +998,10 @@ func (admin *Admin) ToneImportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Dedupe {
+		result.dedupe()
+	}
+
 	response := ToneImportResponse{
 		Format:   strings.ToLower(strings.TrimSpace(req.Format)),
 		Count:    len(result.toneSets),
@@ -678,6 +1017,46 @@ func (admin *Admin) ToneImportHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ToneExportHandler writes the posted tone sets as a file in one of the tone import formats
+func (admin *Admin) ToneExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Format   string    `json:"format"`
+		ToneSets []ToneSet `json:"toneSets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	content, err := ExportToneSets(req.Format, req.ToneSets)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	filename, contentType := "tone-sets.cfg", "text/plain"
+	if ToneImportFormat(strings.ToLower(strings.TrimSpace(req.Format))) == ToneImportFormatCSV {
+		filename, contentType = "tone-sets.csv", "text/csv"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write([]byte(content))
+}
+
 func (admin *Admin) BroadcastConfig() {
 	if b, err := json.Marshal(admin.GetConfig()); err == nil {
 		for conn := range admin.Conns {
@@ -774,14 +1153,100 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 			// in the database is replaced with the imported data. Entities not in the import
 			// file are left unchanged (except for users and userGroups which are fully replaced).
 
-			// Check if this is a full import (destructive) or just a save (non-destructive)
-			isFullImport := r.Header.Get("X-Full-Import") == "true"
+			// Check if this is a full import (destructive) or just a save (non-destructive)
+			isFullImport := r.Header.Get("X-Full-Import") == "true"
+
+			m := map[string]any{}
+			err := json.NewDecoder(r.Body).Decode(&m)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			// Refuse refs their columns can't hold
+			if v, ok := m["systems"].([]any); ok {
+				if errs := configRefErrors(v); len(errs) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":  "ref out of range",
+						"errors": errs,
+					})
+					return
+				}
+			}
+
+			// Refuse talkgroup gains out of range
+			if v, ok := m["systems"].([]any); ok {
+				if errs := talkgroupGainErrors(NewSystems().FromMap(v).List); len(errs) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":  "talkgroup gain out of range",
+						"errors": errs,
+					})
+					return
+				}
+			}
+
+			// Refuse default groups and tags of auto populated talkgroups that don't exist
+			if v, ok := m["systems"].([]any); ok {
+				groups, tags := admin.Controller.Groups, admin.Controller.Tags
+				if g, ok := m["groups"].([]any); ok {
+					groups = NewGroups().FromMap(g)
+				}
+				if t, ok := m["tags"].([]any); ok {
+					tags = NewTags().FromMap(t)
+				}
+				if errs := autoPopulateDefaultErrors(NewSystems().FromMap(v).List, groups, tags); len(errs) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":  "default group or tag not found",
+						"errors": errs,
+					})
+					return
+				}
+			}
+
+			// Refuse display confidences out of range
+			if v, ok := m["systems"].([]any); ok {
+				if errs := displayConfidenceErrors(NewSystems().FromMap(v).List); len(errs) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":  "display confidence out of range",
+						"errors": errs,
+					})
+					return
+				}
+			}
+
+			// Refuse options of the wrong type or out of range
+			if v, ok := m["options"].(map[string]any); ok {
+				if errs := optionsMapErrors(v); len(errs) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":  "options out of range",
+						"errors": errs,
+					})
+					return
+				}
+			}
 
-			m := map[string]any{}
-			err := json.NewDecoder(r.Body).Decode(&m)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				return
+			// Refuse retention settings that would delete calls before they can be transcribed again, unless acknowledged
+			if v, ok := m["systems"].([]any); ok && r.Header.Get("X-Acknowledge-Retention") != "true" {
+				warnings := retranscriptionWarnings(admin.Controller.Systems.List, NewSystems().FromMap(v).List, admin.Controller.Options.TranscriptionConfig.Enabled)
+				if len(warnings) > 0 {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]any{
+						"error":    "retention settings would prevent transcribing calls again, resend with X-Acknowledge-Retention: true to apply them",
+						"warnings": warnings,
+					})
+					return
+				}
 			}
 
 			admin.mutex.Lock()
@@ -791,6 +1256,7 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 			switch v := m["apikeys"].(type) {
 			case []any:
+				before := auditApikeysSnapshot(admin.Controller.Apikeys.List)
 				admin.Controller.Apikeys.FromMap(v)
 				err = admin.Controller.Apikeys.Write(admin.Controller.Database)
 				if err != nil {
@@ -799,6 +1265,8 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 					err = admin.Controller.Apikeys.Read(admin.Controller.Database)
 					if err != nil {
 						logError(err)
+					} else if removed, added := auditChanges(before, auditApikeysSnapshot(admin.Controller.Apikeys.List)); removed != "" || added != "" {
+						admin.Controller.AuditLog.Record(r, 0, "apikeys.update", "apikeys", removed, added)
 					}
 				}
 			}
@@ -908,6 +1376,7 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 			switch v := m["systems"].(type) {
 			case []any:
+				before := auditSystemsSnapshot(admin.Controller.Systems.List)
 				admin.Controller.Systems.FromMap(v)
 				err = admin.Controller.Systems.Write(admin.Controller.Database)
 				if err != nil {
@@ -916,6 +1385,8 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 					err = admin.Controller.Systems.Read(admin.Controller.Database)
 					if err != nil {
 						logError(err)
+					} else if removed, added := auditChanges(before, auditSystemsSnapshot(admin.Controller.Systems.List)); removed != "" || added != "" {
+						admin.Controller.AuditLog.Record(r, 0, "systems.update", "systems", removed, added)
 					}
 				}
 			}
@@ -993,6 +1464,14 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 						existingGroup.CollectSalesTax = getBoolFromMap(groupMap, "collectSalesTax", false)
 						existingGroup.IsPublicRegistration = getBoolFromMap(groupMap, "isPublicRegistration", false)
 						existingGroup.AllowAddExistingUsers = getBoolFromMap(groupMap, "allowAddExistingUsers", false)
+						existingGroup.DefaultAlertPreferences = getStringFromMap(groupMap, "defaultAlertPreferences")
+						if _, ok := groupMap["systemAccessRemove"]; ok {
+							existingGroup.SystemAccessRemove = getStringFromMap(groupMap, "systemAccessRemove")
+						}
+						// Parents are set once all the groups are imported, they may not exist yet
+						if _, ok := groupMap["parentGroupId"]; ok {
+							existingGroup.ParentGroupId = 0
+						}
 						if createdAt, ok := groupMap["createdAt"].(float64); ok {
 							existingGroup.CreatedAt = int64(createdAt)
 						}
@@ -1008,21 +1487,23 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 					} else {
 						// Create new group
 						group := &UserGroup{
-							Name:                  name,
-							Description:           getStringFromMap(groupMap, "description"),
-							SystemAccess:          getStringFromMap(groupMap, "systemAccess"),
-							Delay:                 int(getFloat64FromMap(groupMap, "delay")),
-							SystemDelays:          getStringFromMap(groupMap, "systemDelays"),
-							TalkgroupDelays:       getStringFromMap(groupMap, "talkgroupDelays"),
-							ConnectionLimit:       uint(getFloat64FromMap(groupMap, "connectionLimit")),
-							MaxUsers:              uint(getFloat64FromMap(groupMap, "maxUsers")),
-							BillingEnabled:        getBoolFromMap(groupMap, "billingEnabled", false),
-							StripePriceId:         getStringFromMap(groupMap, "stripePriceId"),
-							PricingOptions:        getStringFromMap(groupMap, "pricingOptions"),
-							BillingMode:           getStringFromMap(groupMap, "billingMode"),
-							CollectSalesTax:       getBoolFromMap(groupMap, "collectSalesTax", false),
-							IsPublicRegistration:  getBoolFromMap(groupMap, "isPublicRegistration", false),
-							AllowAddExistingUsers: getBoolFromMap(groupMap, "allowAddExistingUsers", false),
+							Name:                    name,
+							Description:             getStringFromMap(groupMap, "description"),
+							SystemAccess:            getStringFromMap(groupMap, "systemAccess"),
+							Delay:                   int(getFloat64FromMap(groupMap, "delay")),
+							SystemDelays:            getStringFromMap(groupMap, "systemDelays"),
+							TalkgroupDelays:         getStringFromMap(groupMap, "talkgroupDelays"),
+							ConnectionLimit:         uint(getFloat64FromMap(groupMap, "connectionLimit")),
+							MaxUsers:                uint(getFloat64FromMap(groupMap, "maxUsers")),
+							BillingEnabled:          getBoolFromMap(groupMap, "billingEnabled", false),
+							StripePriceId:           getStringFromMap(groupMap, "stripePriceId"),
+							PricingOptions:          getStringFromMap(groupMap, "pricingOptions"),
+							BillingMode:             getStringFromMap(groupMap, "billingMode"),
+							CollectSalesTax:         getBoolFromMap(groupMap, "collectSalesTax", false),
+							IsPublicRegistration:    getBoolFromMap(groupMap, "isPublicRegistration", false),
+							AllowAddExistingUsers:   getBoolFromMap(groupMap, "allowAddExistingUsers", false),
+							DefaultAlertPreferences: getStringFromMap(groupMap, "defaultAlertPreferences"),
+							SystemAccessRemove:      getStringFromMap(groupMap, "systemAccessRemove"),
 						}
 						if createdAt, ok := groupMap["createdAt"].(float64); ok {
 							group.CreatedAt = int64(createdAt)
@@ -1081,6 +1562,32 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 							groupIdMap[importedGroupId] = actualGroup.Id
 						}
 					}
+
+					// Restore the parent groups now that every imported group has its actual ID
+					for _, groupData := range v {
+						groupMap, ok := groupData.(map[string]any)
+						if !ok {
+							continue
+						}
+
+						importedId, _ := groupMap["id"].(float64)
+						importedParentId := uint64(getFloat64FromMap(groupMap, "parentGroupId"))
+						if importedParentId == 0 {
+							continue
+						}
+
+						group := admin.Controller.UserGroups.Get(groupIdMap[uint64(importedId)])
+						parentId, ok := groupIdMap[importedParentId]
+						if group == nil || !ok {
+							continue
+						}
+
+						group.ParentGroupId = parentId
+						if err := admin.Controller.UserGroups.Update(group, admin.Controller.Database); err != nil {
+							group.ParentGroupId = 0
+							logError(fmt.Errorf("failed to set the parent group of imported user group %s: %v", group.Name, err))
+						}
+					}
 				}
 			}
 
@@ -1141,6 +1648,14 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 						}
 					}
 
+					// Normalize the zip code, one not matching the format is imported as is and flagged
+					zipCode := getStringFromMap(userMap, "zipCode")
+					if normalized, err := NormalizeZipCode(zipCode, admin.Controller.Options.ZipCodeFormat); err != nil {
+						logError(fmt.Errorf("user %s has an %v, imported as is", email, err))
+					} else {
+						zipCode = normalized
+					}
+
 					// Check if user already exists
 					existingUser := admin.Controller.Users.GetUserByEmail(email)
 
@@ -1149,7 +1664,7 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 						existingUser.Password = password // Use imported password hash directly
 						existingUser.FirstName = getStringFromMap(userMap, "firstName")
 						existingUser.LastName = getStringFromMap(userMap, "lastName")
-						existingUser.ZipCode = getStringFromMap(userMap, "zipCode")
+						existingUser.ZipCode = zipCode
 						existingUser.Verified = getBoolFromMap(userMap, "verified", false)
 						existingUser.UserGroupId = actualUserGroupId
 						existingUser.IsGroupAdmin = getBoolFromMap(userMap, "isGroupAdmin", false)
@@ -1194,7 +1709,7 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 							Password:             password, // Use imported password hash directly
 							FirstName:            getStringFromMap(userMap, "firstName"),
 							LastName:             getStringFromMap(userMap, "lastName"),
-							ZipCode:              getStringFromMap(userMap, "zipCode"),
+							ZipCode:              zipCode,
 							Verified:             getBoolFromMap(userMap, "verified", false),
 							UserGroupId:          actualUserGroupId,
 							IsGroupAdmin:         getBoolFromMap(userMap, "isGroupAdmin", false),
@@ -1265,6 +1780,7 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 					description := getStringFromMap(listMap, "description")
 					order := uint(getFloat64FromMap(listMap, "order"))
+					phonetic, _ := listMap["phonetic"].(bool)
 					createdAt := int64(getFloat64FromMap(listMap, "createdAt"))
 					if createdAt == 0 {
 						createdAt = time.Now().UnixMilli()
@@ -1284,18 +1800,22 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 					// Insert keyword list using parameterized queries
 					if admin.Controller.Database.Config.DbType == DbTypePostgresql {
-						query := `INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt") VALUES ($1, $2, $3, $4, $5) RETURNING "keywordListId"`
+						query := `INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt", "phonetic") VALUES ($1, $2, $3, $4, $5, $6) RETURNING "keywordListId"`
 						var listId uint64
-						if err := admin.Controller.Database.Sql.QueryRow(query, label, description, string(keywordsJson), order, createdAt).Scan(&listId); err != nil {
+						if err := admin.Controller.Database.Sql.QueryRow(query, label, description, string(keywordsJson), order, createdAt, phonetic).Scan(&listId); err != nil {
 							logError(fmt.Errorf("failed to import keyword list %s: %v", label, err))
 						}
 					} else {
-						query := `INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt") VALUES (?, ?, ?, ?, ?)`
-						if _, err := admin.Controller.Database.Sql.Exec(query, label, description, string(keywordsJson), order, createdAt); err != nil {
+						query := `INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt", "phonetic") VALUES (?, ?, ?, ?, ?, ?)`
+						if _, err := admin.Controller.Database.Sql.Exec(query, label, description, string(keywordsJson), order, createdAt, phonetic); err != nil {
 							logError(fmt.Errorf("failed to import keyword list %s: %v", label, err))
 						}
 					}
 				}
+
+				if err := admin.Controller.VocabularyProfiles.LoadKeywordLists(admin.Controller.Database); err != nil {
+					logError(fmt.Errorf("failed to reload keyword lists of vocabulary profiles: %v", err))
+				}
 			}
 
 			// Handle user alert preferences import
@@ -1357,19 +1877,30 @@ func (admin *Admin) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 						}
 					}
 
+					// Get exclusions array
+					exclusions := []string{}
+					if exclusionsData, ok := prefMap["exclusions"].([]any); ok {
+						for _, ex := range exclusionsData {
+							if e, ok := ex.(string); ok {
+								exclusions = append(exclusions, e)
+							}
+						}
+					}
+
 					keywordsJson, _ := json.Marshal(keywords)
 					keywordListIdsJson, _ := json.Marshal(keywordListIds)
 					toneSetIdsJson, _ := json.Marshal(toneSetIds)
+					exclusionsJson, _ := json.Marshal(exclusions)
 
 					// Insert user alert preference using parameterized queries
 					if admin.Controller.Database.Config.DbType == DbTypePostgresql {
-						query := `INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-						if _, err := admin.Controller.Database.Sql.Exec(query, userId, systemId, talkgroupId, alertEnabled, toneAlerts, keywordAlerts, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson)); err != nil {
+						query := `INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+						if _, err := admin.Controller.Database.Sql.Exec(query, userId, systemId, talkgroupId, alertEnabled, toneAlerts, keywordAlerts, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson), string(exclusionsJson)); err != nil {
 							logError(fmt.Errorf("failed to import user alert preference for userId=%d, systemId=%d, talkgroupId=%d: %v", userId, systemId, talkgroupId, err))
 						}
 					} else {
-						query := `INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-						if _, err := admin.Controller.Database.Sql.Exec(query, userId, systemId, talkgroupId, alertEnabled, toneAlerts, keywordAlerts, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson)); err != nil {
+						query := `INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+						if _, err := admin.Controller.Database.Sql.Exec(query, userId, systemId, talkgroupId, alertEnabled, toneAlerts, keywordAlerts, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson), string(exclusionsJson)); err != nil {
 							logError(fmt.Errorf("failed to import user alert preference for userId=%d, systemId=%d, talkgroupId=%d: %v", userId, systemId, talkgroupId, err))
 						}
 					}
@@ -1564,23 +2095,26 @@ func (admin *Admin) GetConfig() map[string]any {
 	userGroupList := make([]map[string]any, 0, len(userGroups))
 	for _, group := range userGroups {
 		userGroupList = append(userGroupList, map[string]any{
-			"id":                    group.Id,
-			"name":                  group.Name,
-			"description":           group.Description,
-			"systemAccess":          group.SystemAccess,
-			"delay":                 group.Delay,
-			"systemDelays":          group.SystemDelays,
-			"talkgroupDelays":       group.TalkgroupDelays,
-			"connectionLimit":       group.ConnectionLimit,
-			"maxUsers":              group.MaxUsers,
-			"billingEnabled":        group.BillingEnabled,
-			"stripePriceId":         group.StripePriceId,
-			"pricingOptions":        group.PricingOptions,
-			"billingMode":           group.BillingMode,
-			"collectSalesTax":       group.CollectSalesTax,
-			"isPublicRegistration":  group.IsPublicRegistration,
-			"allowAddExistingUsers": group.AllowAddExistingUsers,
-			"createdAt":             group.CreatedAt,
+			"id":                      group.Id,
+			"name":                    group.Name,
+			"description":             group.Description,
+			"systemAccess":            group.SystemAccess,
+			"delay":                   group.Delay,
+			"systemDelays":            group.SystemDelays,
+			"talkgroupDelays":         group.TalkgroupDelays,
+			"connectionLimit":         group.ConnectionLimit,
+			"maxUsers":                group.MaxUsers,
+			"billingEnabled":          group.BillingEnabled,
+			"stripePriceId":           group.StripePriceId,
+			"pricingOptions":          group.PricingOptions,
+			"billingMode":             group.BillingMode,
+			"collectSalesTax":         group.CollectSalesTax,
+			"isPublicRegistration":    group.IsPublicRegistration,
+			"allowAddExistingUsers":   group.AllowAddExistingUsers,
+			"defaultAlertPreferences": group.DefaultAlertPreferences,
+			"parentGroupId":           group.ParentGroupId,
+			"systemAccessRemove":      group.SystemAccessRemove,
+			"createdAt":               group.CreatedAt,
 		})
 	}
 
@@ -1602,7 +2136,7 @@ func (admin *Admin) GetConfig() map[string]any {
 
 	// Get all keyword lists for export
 	keywordListList := make([]map[string]any, 0)
-	query := `SELECT "keywordListId", "label", "description", "keywords", "order", "createdAt" FROM "keywordLists" ORDER BY "order" ASC, "createdAt" DESC`
+	query := `SELECT "keywordListId", "label", "description", "keywords", "order", "createdAt", "phonetic" FROM "keywordLists" ORDER BY "order" ASC, "createdAt" DESC`
 	rows, err := admin.Controller.Database.Sql.Query(query)
 	if err == nil {
 		defer rows.Close()
@@ -1614,9 +2148,10 @@ func (admin *Admin) GetConfig() map[string]any {
 				keywordsJson string
 				order        uint
 				createdAt    int64
+				phonetic     bool
 			)
 
-			if err := rows.Scan(&listId, &label, &description, &keywordsJson, &order, &createdAt); err != nil {
+			if err := rows.Scan(&listId, &label, &description, &keywordsJson, &order, &createdAt, &phonetic); err != nil {
 				continue
 			}
 
@@ -1632,13 +2167,14 @@ func (admin *Admin) GetConfig() map[string]any {
 				"keywords":    keywords,
 				"order":       order,
 				"createdAt":   createdAt,
+				"phonetic":    phonetic,
 			})
 		}
 	}
 
 	// Get all user alert preferences for export
 	userAlertPrefList := make([]map[string]any, 0)
-	alertQuery := `SELECT "userAlertPreferenceId", "userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds" FROM "userAlertPreferences" ORDER BY "userId" ASC`
+	alertQuery := `SELECT "userAlertPreferenceId", "userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions" FROM "userAlertPreferences" ORDER BY "userId" ASC`
 	alertRows, alertErr := admin.Controller.Database.Sql.Query(alertQuery)
 	if alertErr == nil {
 		defer alertRows.Close()
@@ -1654,9 +2190,10 @@ func (admin *Admin) GetConfig() map[string]any {
 				keywordsJson   string
 				keywordListIds string
 				toneSetIds     string
+				exclusionsJson string
 			)
 
-			if err := alertRows.Scan(&prefId, &userId, &systemId, &talkgroupId, &alertEnabled, &toneAlerts, &keywordAlerts, &keywordsJson, &keywordListIds, &toneSetIds); err != nil {
+			if err := alertRows.Scan(&prefId, &userId, &systemId, &talkgroupId, &alertEnabled, &toneAlerts, &keywordAlerts, &keywordsJson, &keywordListIds, &toneSetIds, &exclusionsJson); err != nil {
 				continue
 			}
 
@@ -1675,6 +2212,11 @@ func (admin *Admin) GetConfig() map[string]any {
 				json.Unmarshal([]byte(toneSetIds), &toneSetIdsParsed)
 			}
 
+			var exclusions []string
+			if exclusionsJson != "" && exclusionsJson != "[]" {
+				json.Unmarshal([]byte(exclusionsJson), &exclusions)
+			}
+
 			userAlertPrefList = append(userAlertPrefList, map[string]any{
 				"id":             prefId,
 				"userId":         userId,
@@ -1686,6 +2228,7 @@ func (admin *Admin) GetConfig() map[string]any {
 				"keywords":       keywords,
 				"keywordListIds": keywordListIdsParsed,
 				"toneSetIds":     toneSetIdsParsed,
+				"exclusions":     exclusions,
 			})
 		}
 	}
@@ -1809,14 +2352,14 @@ func (admin *Admin) LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 		if !ok {
 			// Record failed attempt
-			admin.Controller.LoginAttemptTracker.RecordFailedAttempt(remoteAddr)
+			admin.Controller.LoginAttemptTracker.RecordFailedAttempt(admin.Controller.LoginAttemptTracker.Addr(r))
 			admin.Controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("invalid login attempt for ip %v", remoteAddr))
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
 		// Login successful - reset failed attempts
-		admin.Controller.LoginAttemptTracker.RecordSuccess(remoteAddr)
+		admin.Controller.LoginAttemptTracker.RecordSuccess(admin.Controller.LoginAttemptTracker.Addr(r))
 
 		id, err := uuid.NewRandom()
 
@@ -3420,6 +3963,44 @@ func (admin *Admin) EmailTestHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// DownstreamTestHandler sends a test call to the downstream of the request, saved or not, and
+// returns the http status and the latency of the downstream
+func (admin *Admin) DownstreamTestHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Downstream   map[string]any `json:"downstream"`
+		SystemRef    uint           `json:"systemRef"`
+		TalkgroupRef uint           `json:"talkgroupRef"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Downstream == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	downstream := NewDownstream(admin.Controller).FromMap(request.Downstream)
+	if downstream.Url == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Downstream url is required"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(downstream.Test(request.SystemRef, request.TalkgroupRef))
+}
+
 // EmailLogoDeleteHandler deletes the email logo
 func (admin *Admin) EmailLogoDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	t := admin.GetAuthorization(r)
@@ -3945,6 +4526,8 @@ func (admin *Admin) UserDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		// Don't fail the request since database deletion succeeded
 	}
 
+	admin.Controller.AuditLog.Record(r, 0, "user.delete", auditUserTarget(user), auditUserGroupTarget(admin.Controller.UserGroups.Get(user.UserGroupId)), "")
+
 	// Sync config to file if enabled
 	admin.Controller.SyncConfigToFile()
 
@@ -4025,6 +4608,17 @@ func (admin *Admin) UserUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the zip code is only checked when it changes, legacy ones not matching the format staying editable
+	if request.ZipCode != user.ZipCode {
+		zipCode, err := NormalizeZipCode(request.ZipCode, admin.Controller.Options.ZipCodeFormat)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		request.ZipCode = zipCode
+	}
+
 	// Update user fields
 	user.Email = request.Email
 	user.FirstName = request.FirstName
@@ -4192,6 +4786,14 @@ func (admin *Admin) UserCreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	zipCode, err := NormalizeZipCode(request.ZipCode, admin.Controller.Options.ZipCodeFormat)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	request.ZipCode = zipCode
+
 	// Generate a unique PIN
 	pin, err := admin.Controller.Users.GenerateUniquePin(0)
 	if err != nil {
@@ -4285,7 +4887,11 @@ func (admin *Admin) UserCreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add user to database
-	if err := admin.Controller.Users.SaveNewUser(user, admin.Controller.Database); err != nil {
+	if err := admin.Controller.Users.SaveNewUser(user, admin.Controller.Database); errors.Is(err, errUserExists) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "User with this email already exists"})
+		return
+	} else if err != nil {
 		log.Printf("Failed to create user: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create user"})
@@ -4405,6 +5011,31 @@ func (admin *Admin) HallucinationSuggestionsHandler(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(suggestions)
 }
 
+// HallucinationStatsHandler returns the summary of the phrases tracked by the hallucination detector
+func (admin *Admin) HallucinationStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	stats, err := admin.Controller.HallucinationDetector.GetStats()
+	if err != nil {
+		log.Printf("Failed to get hallucination stats: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get stats"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 // HallucinationApproveHandler approves a suggested hallucination
 func (admin *Admin) HallucinationApproveHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -4436,6 +5067,60 @@ func (admin *Admin) HallucinationApproveHandler(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(map[string]string{"message": "Hallucination pattern approved and added to filter"})
 }
 
+// HallucinationPatternsHandler exports the hallucination patterns on GET and imports them on POST,
+// merging them with the current ones unless merge=false is given
+func (admin *Admin) HallucinationPatternsHandler(w http.ResponseWriter, r *http.Request) {
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		b, err := admin.Controller.HallucinationDetector.ExportPatterns()
+		if err != nil {
+			log.Printf("Failed to export hallucination patterns: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to export patterns"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="hallucination-patterns.json"`)
+		w.Write(b)
+
+	case http.MethodPost:
+		b, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request"})
+			return
+		}
+
+		merge := r.URL.Query().Get("merge") != "false"
+
+		if err := admin.Controller.HallucinationDetector.ImportPatterns(b, merge); err != nil {
+			log.Printf("Failed to import hallucination patterns: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// Sync config to file if enabled (since we updated hallucination patterns)
+		admin.Controller.SyncConfigToFile()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":  "Hallucination patterns imported",
+			"patterns": admin.Controller.Options.TranscriptionConfig.HallucinationPatterns,
+		})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // HallucinationRejectHandler rejects a suggested hallucination
 func (admin *Admin) HallucinationRejectHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -4463,3 +5148,150 @@ func (admin *Admin) HallucinationRejectHandler(w http.ResponseWriter, r *http.Re
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Suggestion rejected"})
 }
+
+// ToneLearningSuggestionsHandler returns the tone sets learned from the pages of talkgroups, pending review
+func (admin *Admin) ToneLearningSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	suggestions, err := admin.Controller.ToneLearner.GetPendingSuggestions()
+	if err != nil {
+		log.Printf("Failed to get tone set suggestions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get suggestions"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// ToneLearningApproveHandler adds a learned tone set to its talkgroup, optionally under a new label
+func (admin *Admin) ToneLearningApproveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Id    uint64 `json:"id"`
+		Label string `json:"label"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if err := admin.Controller.ToneLearner.ApproveSuggestion(req.Id, strings.TrimSpace(req.Label)); err != nil {
+		log.Printf("Failed to approve tone set suggestion %d: %v", req.Id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Sync config to file if enabled (since we updated the tone sets of a talkgroup)
+	admin.Controller.SyncConfigToFile()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tone set approved and added to the talkgroup"})
+}
+
+// ToneLearningWindowHandler starts learning tone sets from the pages of a talkgroup for a number of hours,
+// or stops learning when the hours are 0
+func (admin *Admin) ToneLearningWindowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		SystemId    uint64 `json:"systemId"`
+		TalkgroupId uint64 `json:"talkgroupId"`
+		Hours       int    `json:"hours"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Hours < 0 || req.Hours > toneLearningMaxHours {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid request, hours from 0 to %d", toneLearningMaxHours)})
+		return
+	}
+
+	until := time.Time{}
+	if req.Hours > 0 {
+		until = time.Now().Add(time.Duration(req.Hours) * time.Hour)
+	}
+
+	if err := admin.Controller.ToneLearner.SetLearningWindow(req.SystemId, req.TalkgroupId, until); err != nil {
+		log.Printf("Failed to set the tone learning window of talkgroup %d: %v", req.TalkgroupId, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Sync config to file if enabled (since we updated the tone learning window of a talkgroup)
+	admin.Controller.SyncConfigToFile()
+
+	var toneLearningUntil int64
+	if !until.IsZero() {
+		toneLearningUntil = until.UnixMilli()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"toneLearningUntil": toneLearningUntil})
+}
+
+// ToneLearningRejectHandler rejects a learned tone set, it won't be proposed again for its talkgroup
+func (admin *Admin) ToneLearningRejectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := admin.GetAuthorization(r)
+	if !admin.ValidateToken(token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Id uint64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if err := admin.Controller.ToneLearner.RejectSuggestion(req.Id); err != nil {
+		log.Printf("Failed to reject tone set suggestion %d: %v", req.Id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to reject suggestion"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Suggestion rejected"})
+}
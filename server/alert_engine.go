@@ -655,23 +655,32 @@ type AlertRecord struct {
 	ToneSetId         string `json:"toneSetId"`       // ID of the tone set that triggered this alert (empty for keyword-only alerts)
 	KeywordsMatched   string `json:"keywordsMatched"` // JSON array
 	TranscriptSnippet string `json:"transcriptSnippet"`
+	Suppressed        bool   `json:"suppressed"` // raised during a test window, recorded without notification
 	CreatedAt         int64  `json:"createdAt"`
 }
 
+// flagSuppressed marks the alert as suppressed when it is raised during a test window of its system
+func (engine *AlertEngine) flagSuppressed(alert *AlertRecord) {
+	alert.Suppressed = engine.controller.AlertTestWindows.Active(alert.SystemId, time.UnixMilli(alert.CreatedAt)) != nil
+}
+
 // createAlert creates an alert in the database
 func (engine *AlertEngine) createAlert(alert *AlertRecord) {
 	var query string
+
+	engine.flagSuppressed(alert)
+
 	if engine.controller.Database.Config.DbType == DbTypePostgresql {
-		query = `INSERT INTO "alerts" ("callId", "systemId", "talkgroupId", "alertType", "toneDetected", "toneSetId", "keywordsMatched", "transcriptSnippet", "createdAt") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "alertId"`
+		query = `INSERT INTO "alerts" ("callId", "systemId", "talkgroupId", "alertType", "toneDetected", "toneSetId", "keywordsMatched", "transcriptSnippet", "suppressed", "createdAt") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING "alertId"`
 		var alertId uint64
-		if err := engine.controller.Database.Sql.QueryRow(query, alert.CallId, alert.SystemId, alert.TalkgroupId, alert.AlertType, alert.ToneDetected, alert.ToneSetId, alert.KeywordsMatched, alert.TranscriptSnippet, alert.CreatedAt).Scan(&alertId); err != nil {
+		if err := engine.controller.Database.Sql.QueryRow(query, alert.CallId, alert.SystemId, alert.TalkgroupId, alert.AlertType, alert.ToneDetected, alert.ToneSetId, alert.KeywordsMatched, alert.TranscriptSnippet, alert.Suppressed, alert.CreatedAt).Scan(&alertId); err != nil {
 			engine.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to create alert: %v", err))
 			return
 		}
 		alert.AlertId = alertId
 	} else {
-		query = `INSERT INTO "alerts" ("callId", "systemId", "talkgroupId", "alertType", "toneDetected", "toneSetId", "keywordsMatched", "transcriptSnippet", "createdAt") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-		result, err := engine.controller.Database.Sql.Exec(query, alert.CallId, alert.SystemId, alert.TalkgroupId, alert.AlertType, alert.ToneDetected, alert.ToneSetId, alert.KeywordsMatched, alert.TranscriptSnippet, alert.CreatedAt)
+		query = `INSERT INTO "alerts" ("callId", "systemId", "talkgroupId", "alertType", "toneDetected", "toneSetId", "keywordsMatched", "transcriptSnippet", "suppressed", "createdAt") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		result, err := engine.controller.Database.Sql.Exec(query, alert.CallId, alert.SystemId, alert.TalkgroupId, alert.AlertType, alert.ToneDetected, alert.ToneSetId, alert.KeywordsMatched, alert.TranscriptSnippet, alert.Suppressed, alert.CreatedAt)
 		if err != nil {
 			engine.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to create alert: %v", err))
 			return
@@ -681,7 +690,11 @@ func (engine *AlertEngine) createAlert(alert *AlertRecord) {
 		}
 	}
 
-	engine.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("alert created: id=%d, call=%d, type=%s", alert.AlertId, alert.CallId, alert.AlertType))
+	if alert.Suppressed {
+		engine.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("alert created: id=%d, call=%d, type=%s, suppressed during test window", alert.AlertId, alert.CallId, alert.AlertType))
+	} else {
+		engine.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("alert created: id=%d, call=%d, type=%s", alert.AlertId, alert.CallId, alert.AlertType))
+	}
 
 	// Debug log
 	if engine.controller.DebugLogger != nil {
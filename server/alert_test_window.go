@@ -0,0 +1,158 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AlertTestWindow is a period, e.g. a weekly radio test, during which alerts are still detected
+// and recorded, flagged as suppressed, but no notification is sent for them
+type AlertTestWindow struct {
+	Id       uint64 `json:"id"`
+	Label    string `json:"label"`
+	SystemId uint64 `json:"systemId"` // 0 for every system
+	StartsAt int64  `json:"startsAt"` // unix milliseconds
+	EndsAt   int64  `json:"endsAt"`   // unix milliseconds
+}
+
+// Covers reports whether the window applies to the system at the given time
+func (window *AlertTestWindow) Covers(systemId uint64, at time.Time) bool {
+	if window.SystemId > 0 && window.SystemId != systemId {
+		return false
+	}
+
+	ms := at.UnixMilli()
+
+	return ms >= window.StartsAt && ms < window.EndsAt
+}
+
+type AlertTestWindows struct {
+	mutex   sync.RWMutex
+	windows map[uint64]*AlertTestWindow
+}
+
+func NewAlertTestWindows() *AlertTestWindows {
+	return &AlertTestWindows{
+		windows: make(map[uint64]*AlertTestWindow),
+	}
+}
+
+func (atw *AlertTestWindows) Load(db *Database) error {
+	rows, err := db.Sql.Query(`SELECT "alertTestWindowId", "label", "systemId", "startsAt", "endsAt" FROM "alertTestWindows"`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	windows := make(map[uint64]*AlertTestWindow)
+
+	for rows.Next() {
+		window := &AlertTestWindow{}
+		if err := rows.Scan(&window.Id, &window.Label, &window.SystemId, &window.StartsAt, &window.EndsAt); err != nil {
+			continue
+		}
+		windows[window.Id] = window
+	}
+
+	atw.mutex.Lock()
+	atw.windows = windows
+	atw.mutex.Unlock()
+
+	return rows.Err()
+}
+
+func (atw *AlertTestWindows) Get(id uint64) *AlertTestWindow {
+	atw.mutex.RLock()
+	defer atw.mutex.RUnlock()
+	return atw.windows[id]
+}
+
+func (atw *AlertTestWindows) GetAll() []*AlertTestWindow {
+	atw.mutex.RLock()
+	defer atw.mutex.RUnlock()
+
+	windows := make([]*AlertTestWindow, 0, len(atw.windows))
+	for _, window := range atw.windows {
+		windows = append(windows, window)
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].StartsAt < windows[j].StartsAt
+	})
+
+	return windows
+}
+
+// Active returns the window covering the system at the given time, if any
+func (atw *AlertTestWindows) Active(systemId uint64, at time.Time) *AlertTestWindow {
+	if atw == nil {
+		return nil
+	}
+
+	atw.mutex.RLock()
+	defer atw.mutex.RUnlock()
+
+	for _, window := range atw.windows {
+		if window.Covers(systemId, at) {
+			return window
+		}
+	}
+
+	return nil
+}
+
+func (atw *AlertTestWindows) Add(window *AlertTestWindow, db *Database) error {
+	window.Label = SanitizeLabel(window.Label)
+
+	if window.StartsAt <= 0 || window.EndsAt <= window.StartsAt {
+		return errors.New("the window must end after it starts")
+	}
+
+	if err := db.Sql.QueryRow(`INSERT INTO "alertTestWindows" ("label", "systemId", "startsAt", "endsAt") VALUES ($1, $2, $3, $4) RETURNING "alertTestWindowId"`, window.Label, window.SystemId, window.StartsAt, window.EndsAt).Scan(&window.Id); err != nil {
+		return err
+	}
+
+	atw.mutex.Lock()
+	atw.windows[window.Id] = window
+	atw.mutex.Unlock()
+
+	return nil
+}
+
+func (atw *AlertTestWindows) Delete(id uint64, db *Database) error {
+	if _, err := db.Sql.Exec(`DELETE FROM "alertTestWindows" WHERE "alertTestWindowId" = $1`, id); err != nil {
+		return err
+	}
+
+	atw.mutex.Lock()
+	delete(atw.windows, id)
+	atw.mutex.Unlock()
+
+	return nil
+}
+
+// alertsSuppressed reports whether notifications for the alerts of the call are held back by a test window
+func (controller *Controller) alertsSuppressed(call *Call, at time.Time) bool {
+	if call == nil || call.System == nil {
+		return false
+	}
+
+	return controller.AlertTestWindows.Active(call.System.Id, at) != nil
+}
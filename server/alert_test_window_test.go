@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestWindowController(windows ...*AlertTestWindow) *Controller {
+	controller := &Controller{AlertTestWindows: NewAlertTestWindows()}
+	for _, window := range windows {
+		controller.AlertTestWindows.windows[window.Id] = window
+	}
+	return controller
+}
+
+func TestAlertTestWindowCovers(t *testing.T) {
+	start := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+	window := &AlertTestWindow{Id: 1, SystemId: 7, StartsAt: start.UnixMilli(), EndsAt: start.Add(15 * time.Minute).UnixMilli()}
+
+	if !window.Covers(7, start.Add(5*time.Minute)) {
+		t.Error("window should cover its system while it is open")
+	}
+	if window.Covers(8, start.Add(5*time.Minute)) {
+		t.Error("window should not cover another system")
+	}
+	if window.Covers(7, start.Add(-time.Second)) || window.Covers(7, start.Add(15*time.Minute)) {
+		t.Error("window should not cover times outside its range")
+	}
+
+	window.SystemId = 0
+	if !window.Covers(8, start) {
+		t.Error("window without a system should cover every system")
+	}
+}
+
+func TestAlertsRecordedButNotDispatchedDuringTestWindow(t *testing.T) {
+	start := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+	controller := newTestWindowController(&AlertTestWindow{Id: 1, SystemId: 3, StartsAt: start.UnixMilli(), EndsAt: start.Add(10 * time.Minute).UnixMilli()})
+	engine := &AlertEngine{controller: controller}
+	call := &Call{System: &System{Id: 3}}
+
+	inside := &AlertRecord{SystemId: 3, CreatedAt: start.Add(time.Minute).UnixMilli()}
+	engine.flagSuppressed(inside)
+	if !inside.Suppressed {
+		t.Error("alert raised during the test window should be flagged suppressed")
+	}
+	if !controller.alertsSuppressed(call, start.Add(time.Minute)) {
+		t.Error("notifications should not be dispatched during the test window")
+	}
+
+	outside := &AlertRecord{SystemId: 3, CreatedAt: start.Add(time.Hour).UnixMilli()}
+	engine.flagSuppressed(outside)
+	if outside.Suppressed {
+		t.Error("alert raised after the test window should not be flagged")
+	}
+	if controller.alertsSuppressed(call, start.Add(time.Hour)) {
+		t.Error("notifications should be dispatched outside the test window")
+	}
+
+	if controller.alertsSuppressed(&Call{System: &System{Id: 4}}, start.Add(time.Minute)) {
+		t.Error("notifications of other systems should be dispatched")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -154,8 +155,9 @@ func (api *Api) CallUploadHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		var (
-			call = NewCall()
-			key  string
+			call     = NewCall()
+			checksum = r.Header.Get(audioChecksumHeader)
+			key      string
 		)
 
 		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
@@ -187,6 +189,8 @@ func (api *Api) CallUploadHandler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			switch p.FormName() {
+			case "audioChecksum":
+				checksum = string(b)
 			case "key":
 				key = string(b)
 			default:
@@ -194,6 +198,11 @@ func (api *Api) CallUploadHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if err := verifyAudioChecksum(call.Audio, checksum); err != nil {
+			api.exitWithError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Corrupted audio: %s", err.Error()))
+			return
+		}
+
 		// Check if this is a test connection (SDRTrunk sends key, system, test fields)
 		if len(call.Audio) == 0 && call.SystemId > 0 && call.TalkgroupId == 0 && call.Timestamp.IsZero() {
 			// This is likely a test connection from SDRTrunk
@@ -323,8 +332,9 @@ func (api *Api) TrunkRecorderCallUploadHandler(w http.ResponseWriter, r *http.Re
 	switch r.Method {
 	case http.MethodPost:
 		var (
-			call = NewCall()
-			key  string
+			call     = NewCall()
+			checksum = r.Header.Get(audioChecksumHeader)
+			key      string
 		)
 
 		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
@@ -358,6 +368,8 @@ func (api *Api) TrunkRecorderCallUploadHandler(w http.ResponseWriter, r *http.Re
 			}
 
 			switch p.FormName() {
+			case "audioChecksum":
+				checksum = string(b)
 			case "key":
 				key = string(b)
 			case "meta":
@@ -374,6 +386,11 @@ func (api *Api) TrunkRecorderCallUploadHandler(w http.ResponseWriter, r *http.Re
 			ParseMultipartContent(call, p, b)
 		}
 
+		if err := verifyAudioChecksum(call.Audio, checksum); err != nil {
+			api.exitWithError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Corrupted audio: %s", err.Error()))
+			return
+		}
+
 		if ok, err := call.IsValid(); ok {
 			api.HandleCall(key, call, w)
 
@@ -456,6 +473,13 @@ func (api *Api) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	zipCode, err := NormalizeZipCode(request.ZipCode, api.Controller.Options.ZipCodeFormat)
+	if err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.ZipCode = zipCode
+
 	// Turnstile verification (mobile apps and invitation-based registrations are exempt)
 	// Invitation codes are already validated via email, so CAPTCHA is redundant
 	if api.Controller.Options.TurnstileEnabled && request.InvitationCode == "" {
@@ -630,12 +654,10 @@ func (api *Api) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Check max users limit for the group
 	// This check is enforced regardless of registration code maxUses setting
 	// Even if a code has unlimited uses (maxUses = 0), the group's maxUsers limit still applies
-	if targetGroup.MaxUsers > 0 {
-		currentUserCount := api.Controller.UserGroups.GetUserCount(targetGroup.Id, api.Controller.Users)
-		if currentUserCount >= targetGroup.MaxUsers {
-			api.exitWithError(w, http.StatusForbidden, fmt.Sprintf("Group has reached maximum user limit of %d", targetGroup.MaxUsers))
-			return
-		}
+	// It is checked again when the user is saved, should another user have taken the last slot meanwhile
+	if !api.Controller.UserGroups.CanAcceptNewUser(targetGroup.Id, api.Controller.Users) {
+		api.exitWithError(w, http.StatusForbidden, groupFullError(targetGroup))
+		return
 	}
 
 	// Create new user
@@ -692,12 +714,23 @@ func (api *Api) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Save new user directly to database
-	if err := api.Controller.Users.SaveNewUser(user, api.Controller.Database); err != nil {
+	// Save new user directly to database, once sure the group still has room for them
+	if err := api.Controller.UserGroups.AdmitNewUser(targetGroup.Id, api.Controller.Users, func() error {
+		return api.Controller.Users.SaveNewUser(user, api.Controller.Database)
+	}); errors.Is(err, errGroupFull) {
+		api.exitWithError(w, http.StatusForbidden, groupFullError(targetGroup))
+		return
+	} else if errors.Is(err, errUserExists) {
+		api.exitWithError(w, http.StatusConflict, "User already exists")
+		return
+	} else if err != nil {
 		api.exitWithError(w, http.StatusInternalServerError, "Failed to save user")
 		return
 	}
 
+	// Give the new member the group's default alert preferences
+	api.applyGroupAlertDefaults(user)
+
 	// Sync config to file if enabled
 	api.Controller.SyncConfigToFile()
 
@@ -815,8 +848,9 @@ func (api *Api) UserLoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Normalize email to lowercase for case-insensitive login
 	request.Email = NormalizeEmail(request.Email)
 
-	// Get client IP for login attempt tracking
+	// Get client IP, and the address the login attempts count against, empty for the allowlisted peers
 	clientIP := GetRemoteAddr(r)
+	loginAddr := api.Controller.LoginAttemptTracker.Addr(r)
 
 	// Turnstile verification (mobile apps are exempt)
 	if api.Controller.Options.TurnstileEnabled {
@@ -831,11 +865,17 @@ func (api *Api) UserLoginHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The account may be blocked by attempts from other IPs
+	if api.Controller.LoginAttemptTracker.IsBlocked(loginAddr, request.Email) {
+		writeLoginBlocked(w, api.Controller.LoginAttemptTracker.GetRemainingBlockTime(loginAddr, request.Email), "Account")
+		return
+	}
+
 	// Find user
 	user := api.Controller.Users.GetUserByEmail(request.Email)
 	if user == nil {
-		// Record failed attempt
-		api.Controller.LoginAttemptTracker.RecordFailedAttempt(clientIP)
+		// Record failed attempt, against the IP alone as there is no account to block
+		api.Controller.LoginAttemptTracker.RecordFailedAttempt(loginAddr)
 		api.exitWithError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
@@ -843,13 +883,13 @@ func (api *Api) UserLoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Verify password
 	if !user.VerifyPassword(request.Password) {
 		// Record failed attempt
-		api.Controller.LoginAttemptTracker.RecordFailedAttempt(clientIP)
+		api.Controller.LoginAttemptTracker.RecordFailedAttempt(loginAddr, request.Email)
 		api.exitWithError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
 	// Login successful - reset failed attempts
-	api.Controller.LoginAttemptTracker.RecordSuccess(clientIP)
+	api.Controller.LoginAttemptTracker.RecordSuccess(loginAddr, request.Email)
 
 	// Allow login even if not verified - email verification is optional
 	if !user.Verified {
@@ -2072,6 +2112,13 @@ func (api *Api) handleCheckoutSessionCompleted(event stripe.Event) {
 		api.syncGroupAdminSubscriptionToAllUsers(user)
 	}
 
+	// Count the redemption of the coupon code typed at checkout
+	if code := session.Metadata["couponCode"]; code != "" {
+		if err := api.Controller.CouponCodes.Redeem(code, api.Controller.Database); err != nil {
+			log.Printf("Failed to redeem coupon code %s for user %s: %v", code, user.Email, err)
+		}
+	}
+
 	log.Printf("User %s subscription activated after checkout completion", user.Email)
 }
 
@@ -2087,6 +2134,7 @@ func (api *Api) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Requ
 		Email      string `json:"email"`
 		SuccessUrl string `json:"successUrl"`
 		CancelUrl  string `json:"cancelUrl"`
+		CouponCode string `json:"couponCode"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -2126,9 +2174,11 @@ func (api *Api) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Requ
 	// Validate that the requested price ID is one of the valid pricing options for this group
 	pricingOptions := group.GetPricingOptions()
 	validPriceId := false
+	var pricingOption PricingOption
 	for _, option := range pricingOptions {
 		if option.PriceId == priceId {
 			validPriceId = true
+			pricingOption = option
 			break
 		}
 	}
@@ -2140,6 +2190,17 @@ func (api *Api) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Requ
 
 	log.Printf("Using price ID %s for user %s (group: %s)", priceId, request.Email, group.Name)
 
+	// The coupon code typed by the user replaces the coupon of the pricing option
+	couponId, err := api.Controller.CouponCodes.CheckoutCoupon(pricingOption, request.CouponCode)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Create Stripe Checkout Session
 	params := &stripe.CheckoutSessionParams{
 		PaymentMethodTypes: stripe.StringSlice([]string{
@@ -2167,6 +2228,18 @@ func (api *Api) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
+	if couponId != "" {
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+			{Coupon: stripe.String(couponId)},
+		}
+		log.Printf("Applying coupon %s to the checkout of price %s", couponId, priceId)
+	}
+
+	// The coupon code is redeemed once the checkout completes
+	if code := normalizeCouponCode(request.CouponCode); code != "" {
+		params.AddMetadata("couponCode", code)
+	}
+
 	// Add trial period if configured for this pricing option
 	for _, option := range pricingOptions {
 		if option.PriceId == priceId && option.TrialDays > 0 {
@@ -2365,7 +2438,7 @@ func (api *Api) AlertsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Query all alerts (no userId filter) with system, talkgroup labels, call transcripts, and tone sequence
-		query := fmt.Sprintf(`SELECT a."alertId", a."callId", a."systemId", a."talkgroupId", a."alertType", a."toneDetected", a."toneSetId", a."keywordsMatched", a."transcriptSnippet", a."createdAt", s."label" as "systemLabel", s."systemRef" as "systemRef", t."label" as "talkgroupLabel", t."name" as "talkgroupName", c."transcript" as "callTranscript", c."transcriptionStatus" as "callTranscriptionStatus", c."toneSequence" as "callToneSequence", c."timestamp" as "callTimestamp" FROM "alerts" a LEFT JOIN "systems" s ON s."systemId" = a."systemId" LEFT JOIN "talkgroups" t ON t."talkgroupId" = a."talkgroupId" LEFT JOIN "calls" c ON c."callId" = a."callId" %s ORDER BY a."createdAt" DESC LIMIT %d`, whereClause, maxAlerts)
+		query := fmt.Sprintf(`SELECT a."alertId", a."callId", a."systemId", a."talkgroupId", a."alertType", a."toneDetected", a."toneSetId", a."keywordsMatched", a."transcriptSnippet", a."suppressed", a."createdAt", s."label" as "systemLabel", s."systemRef" as "systemRef", t."label" as "talkgroupLabel", t."name" as "talkgroupName", COALESCE(t."priority", 0) as "talkgroupPriority", c."transcript" as "callTranscript", c."transcriptionStatus" as "callTranscriptionStatus", c."toneSequence" as "callToneSequence", c."timestamp" as "callTimestamp" FROM "alerts" a LEFT JOIN "systems" s ON s."systemId" = a."systemId" LEFT JOIN "talkgroups" t ON t."talkgroupId" = a."talkgroupId" LEFT JOIN "calls" c ON c."callId" = a."callId" %s ORDER BY a."createdAt" DESC LIMIT %d`, whereClause, maxAlerts)
 		rows, err := api.Controller.Database.Sql.Query(query)
 		if err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query alerts: %v", err))
@@ -2386,18 +2459,20 @@ func (api *Api) AlertsHandler(w http.ResponseWriter, r *http.Request) {
 				toneSetId               string
 				keywordsMatched         string
 				transcriptSnippet       string
+				suppressed              bool
 				createdAt               int64
 				systemLabel             sql.NullString
 				systemRef               sql.NullInt64
 				talkgroupLabel          sql.NullString
 				talkgroupName           sql.NullString
+				talkgroupPriority       uint
 				callTranscript          sql.NullString
 				callTranscriptionStatus sql.NullString
 				callToneSequence        sql.NullString
 				callTimestamp           sql.NullInt64
 			)
 
-			if err := rows.Scan(&alertId, &callId, &systemId, &talkgroupId, &alertType, &toneDetected, &toneSetId, &keywordsMatched, &transcriptSnippet, &createdAt, &systemLabel, &systemRef, &talkgroupLabel, &talkgroupName, &callTranscript, &callTranscriptionStatus, &callToneSequence, &callTimestamp); err != nil {
+			if err := rows.Scan(&alertId, &callId, &systemId, &talkgroupId, &alertType, &toneDetected, &toneSetId, &keywordsMatched, &transcriptSnippet, &suppressed, &createdAt, &systemLabel, &systemRef, &talkgroupLabel, &talkgroupName, &talkgroupPriority, &callTranscript, &callTranscriptionStatus, &callToneSequence, &callTimestamp); err != nil {
 				continue
 			}
 
@@ -2479,6 +2554,11 @@ func (api *Api) AlertsHandler(w http.ResponseWriter, r *http.Request) {
 				"createdAt":         createdAt,
 			}
 
+			// Raised during a test window, notifications were held back
+			if suppressed {
+				alertMap["suppressed"] = true
+			}
+
 			if systemLabel.Valid {
 				alertMap["systemLabel"] = systemLabel.String
 			}
@@ -2488,6 +2568,9 @@ func (api *Api) AlertsHandler(w http.ResponseWriter, r *http.Request) {
 			if talkgroupName.Valid {
 				alertMap["talkgroupName"] = talkgroupName.String
 			}
+			if talkgroupPriority > 0 {
+				alertMap["priority"] = talkgroupPriority
+			}
 			if callTranscript.Valid {
 				alertMap["transcript"] = callTranscript.String
 			}
@@ -2815,6 +2898,9 @@ func (api *Api) AlertsHandler(w http.ResponseWriter, r *http.Request) {
 			alerts = append(alerts, group.alerts...)
 		}
 
+		// Alerts of priority talkgroups come first
+		sortAlertsByPriority(alerts)
+
 		if b, err := json.Marshal(alerts); err == nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.Write(b)
@@ -2937,6 +3023,83 @@ func (api *Api) TranscriptsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// IncidentHandler handles GET /api/incidents?id=, the calls of an incident ordered by time
+func (api *Api) IncidentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	client := api.getClient(r)
+	if client == nil || client.User == nil {
+		api.exitWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	incidentId, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || incidentId == 0 {
+		api.exitWithError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+
+	query := fmt.Sprintf(`SELECT c."callId", c."systemId", c."talkgroupId", c."transcript", c."timestamp" FROM "calls" c WHERE c."incidentId" = %d ORDER BY c."timestamp" ASC, c."callId" ASC`, incidentId)
+
+	rows, err := api.Controller.Database.Sql.Query(query)
+	if err != nil {
+		api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query incident: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	results := []map[string]any{}
+	for rows.Next() {
+		var (
+			callId        uint64
+			sysId         uint64
+			tgId          uint64
+			transcript    sql.NullString
+			callTimestamp int64
+		)
+
+		if err := rows.Scan(&callId, &sysId, &tgId, &transcript, &callTimestamp); err != nil {
+			continue
+		}
+
+		system, ok := api.Controller.Systems.GetSystemById(sysId)
+		if !ok {
+			continue
+		}
+		talkgroup, ok := system.Talkgroups.GetTalkgroupById(tgId)
+		if !ok {
+			continue
+		}
+
+		// only the calls the user may listen to
+		if !api.Controller.userHasAccess(client.User, &Call{System: system, Talkgroup: talkgroup}) {
+			continue
+		}
+
+		results = append(results, map[string]any{
+			"callId":         callId,
+			"incidentId":     incidentId,
+			"systemId":       system.SystemRef,
+			"systemLabel":    system.Label,
+			"talkgroupId":    talkgroup.TalkgroupRef,
+			"talkgroupLabel": talkgroup.Label,
+			"talkgroupName":  talkgroup.Name,
+			"transcript":     transcript.String,
+			"timestamp":      callTimestamp,
+		})
+	}
+
+	if b, err := json.Marshal(results); err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	} else {
+		api.exitWithError(w, http.StatusInternalServerError, "failed to marshal incident")
+	}
+}
+
 // AlertPreferencesHandler handles GET/PUT /api/alerts/preferences
 func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request) {
 	client := api.getClient(r)
@@ -2948,7 +3111,7 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 	switch r.Method {
 	case http.MethodGet:
 		// Get preferences with talkgroupRef (for frontend matching)
-		query := fmt.Sprintf(`SELECT p."userId", p."systemId", p."talkgroupId", p."alertEnabled", p."toneAlerts", p."keywordAlerts", p."keywords", p."keywordListIds", p."toneSetIds", t."talkgroupRef", s."systemRef" FROM "userAlertPreferences" p LEFT JOIN "talkgroups" t ON t."talkgroupId" = p."talkgroupId" LEFT JOIN "systems" s ON s."systemId" = p."systemId" WHERE p."userId" = %d`, client.User.Id)
+		query := fmt.Sprintf(`SELECT p."userId", p."systemId", p."talkgroupId", p."alertEnabled", p."toneAlerts", p."keywordAlerts", p."keywords", p."keywordListIds", p."toneSetIds", p."exclusions", t."talkgroupRef", s."systemRef" FROM "userAlertPreferences" p LEFT JOIN "talkgroups" t ON t."talkgroupId" = p."talkgroupId" LEFT JOIN "systems" s ON s."systemId" = p."systemId" WHERE p."userId" = %d`, client.User.Id)
 		rows, err := api.Controller.Database.Sql.Query(query)
 		if err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query preferences: %v", err))
@@ -2968,11 +3131,12 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 				keywordsJson   string
 				keywordListIds string
 				toneSetIdsJson string
+				exclusionsJson string
 				talkgroupRef   sql.NullInt32
 				systemRef      sql.NullInt32
 			)
 
-			if err := rows.Scan(&userId, &systemId, &talkgroupId, &alertEnabled, &toneAlerts, &keywordAlerts, &keywordsJson, &keywordListIds, &toneSetIdsJson, &talkgroupRef, &systemRef); err != nil {
+			if err := rows.Scan(&userId, &systemId, &talkgroupId, &alertEnabled, &toneAlerts, &keywordAlerts, &keywordsJson, &keywordListIds, &toneSetIdsJson, &exclusionsJson, &talkgroupRef, &systemRef); err != nil {
 				continue
 			}
 
@@ -2991,6 +3155,11 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 				json.Unmarshal([]byte(toneSetIdsJson), &toneSetIdsList)
 			}
 
+			exclusions := []string{}
+			if exclusionsJson != "" && exclusionsJson != "[]" {
+				json.Unmarshal([]byte(exclusionsJson), &exclusions)
+			}
+
 			prefMap := map[string]any{
 				"userId":         userId,
 				"systemId":       systemId,
@@ -3001,6 +3170,7 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 				"keywords":       keywords,
 				"keywordListIds": keywordListIdsList,
 				"toneSetIds":     toneSetIdsList,
+				"exclusions":     exclusions,
 			}
 
 			// Also include talkgroupRef and systemRef if available (for frontend matching)
@@ -3047,6 +3217,7 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 				keywords       []string
 				keywordListIds []uint64
 				toneSetIds     []string
+				exclusionsJson any // nil keeps the stored exclusions, for the clients not sending them
 			)
 
 			// Accept either systemId or systemRef field names
@@ -3101,6 +3272,16 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 					}
 				}
 			}
+			if v, ok := pref["exclusions"].([]any); ok {
+				exclusions := []string{}
+				for _, value := range v {
+					if exclusion, ok := value.(string); ok && strings.TrimSpace(exclusion) != "" {
+						exclusions = append(exclusions, strings.TrimSpace(exclusion))
+					}
+				}
+				b, _ := json.Marshal(exclusions)
+				exclusionsJson = string(b)
+			}
 
 			// Resolve systemId: prefer systemRef, fallback to systemId
 			systemId = 0
@@ -3164,9 +3345,9 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 			}
 
 			// Upsert preference using verified database talkgroupId
-			query := fmt.Sprintf(`INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds") VALUES (%d, %d, %d, %t, %t, %t, $1, $2, $3) ON CONFLICT ("userId", "systemId", "talkgroupId") DO UPDATE SET "alertEnabled" = %t, "toneAlerts" = %t, "keywordAlerts" = %t, "keywords" = $1, "keywordListIds" = $2, "toneSetIds" = $3`, client.User.Id, systemId, dbTalkgroupId, alertEnabled, toneAlerts, keywordAlerts, alertEnabled, toneAlerts, keywordAlerts)
+			query := fmt.Sprintf(`INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions") VALUES (%d, %d, %d, %t, %t, %t, $1, $2, $3, COALESCE($4::text, '[]')) ON CONFLICT ("userId", "systemId", "talkgroupId") DO UPDATE SET "alertEnabled" = %t, "toneAlerts" = %t, "keywordAlerts" = %t, "keywords" = $1, "keywordListIds" = $2, "toneSetIds" = $3, "exclusions" = COALESCE($4::text, "userAlertPreferences"."exclusions")`, client.User.Id, systemId, dbTalkgroupId, alertEnabled, toneAlerts, keywordAlerts, alertEnabled, toneAlerts, keywordAlerts)
 
-			if _, err := tx.Exec(query, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson)); err != nil {
+			if _, err := tx.Exec(query, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson), exclusionsJson); err != nil {
 				api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update preference: %v", err))
 				return
 			}
@@ -3185,6 +3366,60 @@ func (api *Api) AlertPreferencesHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// AlertPreferencesConfigHandler handles GET/PUT /api/alerts/preferences/config
+// GET exports the alert preferences and keyword lists of the user, PUT imports them
+func (api *Api) AlertPreferencesConfigHandler(w http.ResponseWriter, r *http.Request) {
+	client := api.getClient(r)
+	if client == nil || client.User == nil {
+		api.exitWithError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		config, err := api.Controller.ExportUserConfig(client.User.Id)
+		if err != nil {
+			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export preferences: %v", err))
+			return
+		}
+
+		if b, err := json.Marshal(config); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="alert-preferences.json"`)
+			w.Write(b)
+		} else {
+			api.exitWithError(w, http.StatusInternalServerError, "failed to marshal preferences")
+		}
+
+	case http.MethodPut, http.MethodPost:
+		b, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			api.exitWithError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+
+		result, err := api.Controller.ImportUserConfig(client.User.Id, b, api.isAdmin(client))
+		if err != nil {
+			api.exitWithError(w, http.StatusBadRequest, fmt.Sprintf("failed to import preferences: %v", err))
+			return
+		}
+
+		if len(result.CreatedKeywordLists) > 0 {
+			api.reloadPhraseHints()
+		}
+
+		if b, err := json.Marshal(result); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(b)
+		} else {
+			api.exitWithError(w, http.StatusInternalServerError, "failed to marshal import result")
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // KeywordListsHandler handles GET/POST /api/keyword-lists
 func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 	client := api.getClient(r)
@@ -3202,7 +3437,7 @@ func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// Get all keyword lists (admin can see all, users see available lists)
-		query := `SELECT "keywordListId", "label", "description", "keywords", "order", "createdAt" FROM "keywordLists" ORDER BY "order" ASC, "createdAt" DESC`
+		query := `SELECT "keywordListId", "label", "description", "keywords", "order", "createdAt", "phonetic" FROM "keywordLists" ORDER BY "order" ASC, "createdAt" DESC`
 		rows, err := api.Controller.Database.Sql.Query(query)
 		if err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query keyword lists: %v", err))
@@ -3219,9 +3454,10 @@ func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 				keywordsJson string
 				order        uint
 				createdAt    int64
+				phonetic     bool
 			)
 
-			if err := rows.Scan(&listId, &label, &description, &keywordsJson, &order, &createdAt); err != nil {
+			if err := rows.Scan(&listId, &label, &description, &keywordsJson, &order, &createdAt, &phonetic); err != nil {
 				continue
 			}
 
@@ -3237,6 +3473,7 @@ func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 				"keywords":    keywords,
 				"order":       order,
 				"createdAt":   createdAt,
+				"phonetic":    phonetic,
 			})
 		}
 
@@ -3265,13 +3502,14 @@ func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 			description string
 			keywords    []string
 			order       uint
+			phonetic    bool
 		)
 
 		if v, ok := list["label"].(string); ok {
-			label = v
+			label = SanitizeLabel(v)
 		}
 		if v, ok := list["description"].(string); ok {
-			description = v
+			description = SanitizeText(v, TextSanitizeStrip)
 		}
 		if v, ok := list["keywords"].([]any); ok {
 			for _, kw := range v {
@@ -3283,16 +3521,21 @@ func (api *Api) KeywordListsHandler(w http.ResponseWriter, r *http.Request) {
 		if v, ok := list["order"].(float64); ok {
 			order = uint(v)
 		}
+		if v, ok := list["phonetic"].(bool); ok {
+			phonetic = v
+		}
 
 		keywordsJson, _ := json.Marshal(keywords)
 
-		query := fmt.Sprintf(`INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt") VALUES ('%s', '%s', '%s', %d, %d) RETURNING "keywordListId"`, escapeQuotes(label), escapeQuotes(description), escapeQuotes(string(keywordsJson)), order, time.Now().UnixMilli())
+		query := fmt.Sprintf(`INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt", "phonetic") VALUES ('%s', '%s', '%s', %d, %d, %t) RETURNING "keywordListId"`, escapeQuotes(label), escapeQuotes(description), escapeQuotes(string(keywordsJson)), order, time.Now().UnixMilli(), phonetic)
 
 		var listId uint64
 		if err := api.Controller.Database.Sql.QueryRow(query).Scan(&listId); err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create keyword list: %v", err))
 			return
 		}
+		api.reloadPhraseHints()
+
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte(fmt.Sprintf(`{"id": %d, "success": true}`, listId)))
 
@@ -3341,13 +3584,14 @@ func (api *Api) KeywordListHandler(w http.ResponseWriter, r *http.Request) {
 			description string
 			keywords    []string
 			order       uint
+			phonetic    *bool
 		)
 
 		if v, ok := list["label"].(string); ok {
-			label = v
+			label = SanitizeLabel(v)
 		}
 		if v, ok := list["description"].(string); ok {
-			description = v
+			description = SanitizeText(v, TextSanitizeStrip)
 		}
 		if v, ok := list["keywords"].([]any); ok {
 			for _, kw := range v {
@@ -3359,16 +3603,27 @@ func (api *Api) KeywordListHandler(w http.ResponseWriter, r *http.Request) {
 		if v, ok := list["order"].(float64); ok {
 			order = uint(v)
 		}
+		if v, ok := list["phonetic"].(bool); ok {
+			phonetic = &v
+		}
 
 		keywordsJson, _ := json.Marshal(keywords)
 
-		query := fmt.Sprintf(`UPDATE "keywordLists" SET "label" = '%s', "description" = '%s', "keywords" = '%s', "order" = %d WHERE "keywordListId" = %d`, escapeQuotes(label), escapeQuotes(description), escapeQuotes(string(keywordsJson)), order, listId)
+		// the phonetic matching is kept as stored when the request doesn't have it
+		phoneticSet := ""
+		if phonetic != nil {
+			phoneticSet = fmt.Sprintf(`, "phonetic" = %t`, *phonetic)
+		}
+
+		query := fmt.Sprintf(`UPDATE "keywordLists" SET "label" = '%s', "description" = '%s', "keywords" = '%s', "order" = %d%s WHERE "keywordListId" = %d`, escapeQuotes(label), escapeQuotes(description), escapeQuotes(string(keywordsJson)), order, phoneticSet, listId)
 
 		if _, err := api.Controller.Database.Sql.Exec(query); err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update keyword list: %v", err))
 			return
 		}
 
+		api.reloadPhraseHints()
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"success": true}`))
 
@@ -3379,6 +3634,8 @@ func (api *Api) KeywordListHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		api.reloadPhraseHints()
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"success": true}`))
 
@@ -3387,6 +3644,27 @@ func (api *Api) KeywordListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// reloadPhraseHints refreshes the keywords vocabulary profiles take from the keyword lists
+func (api *Api) reloadPhraseHints() {
+	if err := api.Controller.VocabularyProfiles.LoadKeywordLists(api.Controller.Database); err != nil {
+		api.Controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to reload keyword lists of vocabulary profiles: %v", err))
+	}
+}
+
+// rateLimitKey identifies the user behind a request for per user rate limiting, falling back to its address
+func (api *Api) rateLimitKey(r *http.Request) string {
+	if client := api.getClient(r); client != nil {
+		if client.User != nil {
+			return fmt.Sprintf("user:%d", client.User.Id)
+		}
+		if client.IsAdmin {
+			return "admin"
+		}
+	}
+
+	return "ip:" + getRemoteAddr(r)
+}
+
 // getClient extracts client from request (helper for API handlers)
 func (api *Api) getClient(r *http.Request) *Client {
 	// Get PIN/token from query parameter or Authorization header
@@ -4314,8 +4592,9 @@ func (api *Api) GroupAdminLoginHandler(w http.ResponseWriter, r *http.Request) {
 	// Normalize email to lowercase for case-insensitive login
 	request.Email = NormalizeEmail(request.Email)
 
-	// Get client IP for login attempt tracking
+	// Get client IP, and the address the login attempts count against, empty for the allowlisted peers
 	clientIP := GetRemoteAddr(r)
+	loginAddr := api.Controller.LoginAttemptTracker.Addr(r)
 
 	// Turnstile verification (mobile apps are exempt)
 	if api.Controller.Options.TurnstileEnabled {
@@ -4330,16 +4609,29 @@ func (api *Api) GroupAdminLoginHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The account may be blocked by attempts from other IPs
+	if api.Controller.LoginAttemptTracker.IsBlocked(loginAddr, request.Email) {
+		writeLoginBlocked(w, api.Controller.LoginAttemptTracker.GetRemainingBlockTime(loginAddr, request.Email), "Account")
+		return
+	}
+
 	user := api.Controller.Users.GetUserByEmail(request.Email)
-	if user == nil || !user.VerifyPassword(request.Password) {
+	if user == nil {
+		// Record failed attempt, against the IP alone as there is no account to block
+		api.Controller.LoginAttemptTracker.RecordFailedAttempt(loginAddr)
+		api.exitWithError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	if !user.VerifyPassword(request.Password) {
 		// Record failed attempt
-		api.Controller.LoginAttemptTracker.RecordFailedAttempt(clientIP)
+		api.Controller.LoginAttemptTracker.RecordFailedAttempt(loginAddr, request.Email)
 		api.exitWithError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
 	// Login successful - reset failed attempts
-	api.Controller.LoginAttemptTracker.RecordSuccess(clientIP)
+	api.Controller.LoginAttemptTracker.RecordSuccess(loginAddr, request.Email)
 
 	// Allow login even if not verified - email verification is optional
 	if !user.Verified {
@@ -4370,9 +4662,10 @@ func (api *Api) GroupAdminLoginHandler(w http.ResponseWriter, r *http.Request) {
 			"pin":   user.Pin,
 		},
 		"group": map[string]interface{}{
-			"id":                    group.Id,
-			"name":                  group.Name,
-			"allowAddExistingUsers": group.AllowAddExistingUsers,
+			"id":                      group.Id,
+			"name":                    group.Name,
+			"allowAddExistingUsers":   group.AllowAddExistingUsers,
+			"defaultAlertPreferences": group.DefaultAlertPreferences,
 		},
 	})
 }
@@ -4443,11 +4736,12 @@ func (api *Api) GroupAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"users": groupUsers,
 		"group": map[string]interface{}{
-			"id":                    group.Id,
-			"name":                  group.Name,
-			"maxUsers":              group.MaxUsers,
-			"userCount":             currentUserCount,
-			"allowAddExistingUsers": group.AllowAddExistingUsers,
+			"id":                      group.Id,
+			"name":                    group.Name,
+			"maxUsers":                group.MaxUsers,
+			"userCount":               currentUserCount,
+			"allowAddExistingUsers":   group.AllowAddExistingUsers,
+			"defaultAlertPreferences": group.DefaultAlertPreferences,
 		},
 	})
 }
@@ -4614,19 +4908,18 @@ func (api *Api) GroupAdminAddUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	zipCode, err := NormalizeZipCode(request.ZipCode, api.Controller.Options.ZipCodeFormat)
+	if err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.ZipCode = zipCode
+
 	// Check if group has reached max users limit
-	if group.MaxUsers > 0 {
-		allUsers := api.Controller.Users.GetAllUsers()
-		currentUserCount := 0
-		for _, u := range allUsers {
-			if u.UserGroupId == group.Id {
-				currentUserCount++
-			}
-		}
-		if currentUserCount >= int(group.MaxUsers) {
-			api.exitWithError(w, http.StatusForbidden, fmt.Sprintf("Group has reached maximum user limit of %d", group.MaxUsers))
-			return
-		}
+	// It is checked again when the user is added, should another user have taken the last slot meanwhile
+	if !api.Controller.UserGroups.CanAcceptNewUser(group.Id, api.Controller.Users) {
+		api.exitWithError(w, http.StatusForbidden, groupFullError(group))
+		return
 	}
 
 	// Find user by email
@@ -4676,12 +4969,23 @@ func (api *Api) GroupAdminAddUserHandler(w http.ResponseWriter, r *http.Request)
 		}
 		user.Pin = pin
 
-		// Save new user
-		if err := api.Controller.Users.SaveNewUser(user, api.Controller.Database); err != nil {
+		// Save new user, once sure the group still has room for them
+		if err := api.Controller.UserGroups.AdmitNewUser(group.Id, api.Controller.Users, func() error {
+			return api.Controller.Users.SaveNewUser(user, api.Controller.Database)
+		}); errors.Is(err, errGroupFull) {
+			api.exitWithError(w, http.StatusForbidden, groupFullError(group))
+			return
+		} else if errors.Is(err, errUserExists) {
+			api.exitWithError(w, http.StatusConflict, "User with this email already exists")
+			return
+		} else if err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, "Failed to create user")
 			return
 		}
 
+		// Give the new member the group's default alert preferences
+		api.applyGroupAlertDefaults(user)
+
 		// Sync config to file if enabled
 		api.Controller.SyncConfigToFile()
 
@@ -4776,14 +5080,21 @@ func (api *Api) GroupAdminAddUserHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Add user to group
-	user.UserGroupId = group.Id
-	// Clear user's individual delay settings - they will use the group's delay settings
-	api.clearUserDelayValues(user)
-	// Sync user's connection limit with the group's connection limit
-	api.syncUserConnectionLimit(user)
-	api.Controller.Users.Update(user)
-	api.Controller.Users.Write(api.Controller.Database)
+	// Add user to group, once sure the group still has room for them
+	if err := api.Controller.UserGroups.AdmitNewUser(group.Id, api.Controller.Users, func() error {
+		user.UserGroupId = group.Id
+		// Clear user's individual delay settings - they will use the group's delay settings
+		api.clearUserDelayValues(user)
+		// Sync user's connection limit with the group's connection limit
+		api.syncUserConnectionLimit(user)
+		api.Controller.Users.Update(user)
+		api.Controller.Users.Write(api.Controller.Database)
+		return nil
+	}); errors.Is(err, errGroupFull) {
+		api.exitWithError(w, http.StatusForbidden, groupFullError(group))
+		return
+	}
+	api.applyGroupAlertDefaults(user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -4802,7 +5113,7 @@ func (api *Api) GroupAdminAddExistingUserHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_, group, err := api.getGroupAdminUser(r)
+	admin, group, err := api.getGroupAdminUser(r)
 	if err != nil {
 		api.exitWithError(w, http.StatusUnauthorized, err.Error())
 		return
@@ -4881,6 +5192,8 @@ func (api *Api) GroupAdminAddExistingUserHandler(w http.ResponseWriter, r *http.
 	api.clearUserDelayValues(user)
 	api.Controller.Users.Update(user)
 	api.Controller.Users.Write(api.Controller.Database)
+	api.applyGroupAlertDefaults(user)
+	api.Controller.AuditLog.RecordTransfer(admin.Id, user.Id, oldGroupId, group.Id, "group admin add existing user")
 
 	// If user was added to an admin-managed billing group, sync subscription status from admin
 	if group.BillingEnabled && group.BillingMode == "group_admin" && !user.IsGroupAdmin {
@@ -5083,6 +5396,15 @@ func (api *Api) syncUserConnectionLimit(user *User) {
 	}
 }
 
+// applyGroupAlertDefaults gives a user joining a group the group's default alert preferences
+func (api *Api) applyGroupAlertDefaults(user *User) {
+	if n, err := api.Controller.ApplyGroupAlertDefaults(user); err != nil {
+		log.Printf("Failed to apply group default alert preferences to user %s: %v", user.Email, err)
+	} else if n > 0 {
+		log.Printf("Applied %d group default alert preferences to user %s", n, user.Email)
+	}
+}
+
 // handleUserGroupBillingTransition handles Stripe subscription changes when moving users between groups
 func (api *Api) handleUserGroupBillingTransition(user *User, oldGroup *UserGroup, newGroup *UserGroup) error {
 	// Only handle if Stripe is enabled
@@ -5504,6 +5826,7 @@ func (api *Api) GroupAdminCodesHandler(w http.ResponseWriter, r *http.Request) {
 				"isOneTime":   code.IsOneTime,
 				"isActive":    code.IsActive,
 				"createdAt":   code.CreatedAt,
+				"lastUsedAt":  code.LastUsedAt,
 			})
 		}
 	}
@@ -5591,23 +5914,26 @@ func (api *Api) AdminGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	groupList := []map[string]interface{}{}
 	for _, group := range groups {
 		groupList = append(groupList, map[string]interface{}{
-			"id":                    group.Id,
-			"name":                  group.Name,
-			"description":           group.Description,
-			"systemAccess":          group.SystemAccess,
-			"delay":                 group.Delay,
-			"systemDelays":          group.SystemDelays,
-			"talkgroupDelays":       group.TalkgroupDelays,
-			"connectionLimit":       group.ConnectionLimit,
-			"maxUsers":              group.MaxUsers,
-			"billingEnabled":        group.BillingEnabled,
-			"stripePriceId":         group.StripePriceId,
-			"pricingOptions":        group.GetPricingOptions(),
-			"billingMode":           group.BillingMode,
-			"collectSalesTax":       group.CollectSalesTax,
-			"isPublicRegistration":  group.IsPublicRegistration,
-			"allowAddExistingUsers": group.AllowAddExistingUsers,
-			"createdAt":             group.CreatedAt,
+			"id":                      group.Id,
+			"name":                    group.Name,
+			"description":             group.Description,
+			"systemAccess":            group.SystemAccess,
+			"delay":                   group.Delay,
+			"systemDelays":            group.SystemDelays,
+			"talkgroupDelays":         group.TalkgroupDelays,
+			"connectionLimit":         group.ConnectionLimit,
+			"maxUsers":                group.MaxUsers,
+			"billingEnabled":          group.BillingEnabled,
+			"stripePriceId":           group.StripePriceId,
+			"pricingOptions":          group.GetPricingOptions(),
+			"billingMode":             group.BillingMode,
+			"collectSalesTax":         group.CollectSalesTax,
+			"isPublicRegistration":    group.IsPublicRegistration,
+			"allowAddExistingUsers":   group.AllowAddExistingUsers,
+			"defaultAlertPreferences": group.DefaultAlertPreferences,
+			"parentGroupId":           group.ParentGroupId,
+			"systemAccessRemove":      group.SystemAccessRemove,
+			"createdAt":               group.CreatedAt,
 		})
 	}
 
@@ -5636,21 +5962,24 @@ func (api *Api) AdminCreateGroupHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var request struct {
-		Name                  string          `json:"name"`
-		Description           string          `json:"description"`
-		SystemAccess          string          `json:"systemAccess"`
-		Delay                 int             `json:"delay"`
-		SystemDelays          string          `json:"systemDelays"`
-		TalkgroupDelays       string          `json:"talkgroupDelays"`
-		ConnectionLimit       uint            `json:"connectionLimit"`
-		MaxUsers              uint            `json:"maxUsers"`
-		BillingEnabled        bool            `json:"billingEnabled"`
-		StripePriceId         string          `json:"stripePriceId"`
-		PricingOptions        []PricingOption `json:"pricingOptions"`
-		BillingMode           string          `json:"billingMode"`
-		CollectSalesTax       bool            `json:"collectSalesTax"`
-		IsPublicRegistration  bool            `json:"isPublicRegistration"`
-		AllowAddExistingUsers bool            `json:"allowAddExistingUsers"`
+		Name                    string          `json:"name"`
+		Description             string          `json:"description"`
+		SystemAccess            string          `json:"systemAccess"`
+		Delay                   int             `json:"delay"`
+		SystemDelays            string          `json:"systemDelays"`
+		TalkgroupDelays         string          `json:"talkgroupDelays"`
+		ConnectionLimit         uint            `json:"connectionLimit"`
+		MaxUsers                uint            `json:"maxUsers"`
+		BillingEnabled          bool            `json:"billingEnabled"`
+		StripePriceId           string          `json:"stripePriceId"`
+		PricingOptions          []PricingOption `json:"pricingOptions"`
+		BillingMode             string          `json:"billingMode"`
+		CollectSalesTax         bool            `json:"collectSalesTax"`
+		IsPublicRegistration    bool            `json:"isPublicRegistration"`
+		AllowAddExistingUsers   bool            `json:"allowAddExistingUsers"`
+		DefaultAlertPreferences string          `json:"defaultAlertPreferences"`
+		ParentGroupId           uint64          `json:"parentGroupId"`
+		SystemAccessRemove      string          `json:"systemAccessRemove"`
 		// Group admin assignment
 		AssignExistingUserAsAdmin bool   `json:"assignExistingUserAsAdmin"`
 		GroupAdminUserId          uint64 `json:"groupAdminUserId"`
@@ -5688,6 +6017,7 @@ func (api *Api) AdminCreateGroupHandler(w http.ResponseWriter, r *http.Request)
 			api.exitWithError(w, http.StatusBadRequest, fmt.Sprintf("Pricing option %d is missing required fields", i+1))
 			return
 		}
+		request.PricingOptions[i].CouponId = strings.TrimSpace(opt.CouponId)
 	}
 
 	// Convert pricing options to JSON string
@@ -5716,22 +6046,35 @@ func (api *Api) AdminCreateGroupHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	group := &UserGroup{
-		Name:                  request.Name,
-		Description:           request.Description,
-		SystemAccess:          request.SystemAccess,
-		Delay:                 request.Delay,
-		SystemDelays:          request.SystemDelays,
-		TalkgroupDelays:       request.TalkgroupDelays,
-		ConnectionLimit:       request.ConnectionLimit,
-		MaxUsers:              request.MaxUsers,
-		BillingEnabled:        request.BillingEnabled,
-		StripePriceId:         request.StripePriceId,
-		PricingOptions:        pricingOptionsJSON,
-		BillingMode:           billingMode,
-		CollectSalesTax:       request.CollectSalesTax,
-		IsPublicRegistration:  request.IsPublicRegistration,
-		AllowAddExistingUsers: request.AllowAddExistingUsers,
-		CreatedAt:             time.Now().Unix(),
+		Name:                    request.Name,
+		Description:             request.Description,
+		SystemAccess:            request.SystemAccess,
+		Delay:                   request.Delay,
+		SystemDelays:            request.SystemDelays,
+		TalkgroupDelays:         request.TalkgroupDelays,
+		ConnectionLimit:         request.ConnectionLimit,
+		MaxUsers:                request.MaxUsers,
+		BillingEnabled:          request.BillingEnabled,
+		StripePriceId:           request.StripePriceId,
+		PricingOptions:          pricingOptionsJSON,
+		BillingMode:             billingMode,
+		CollectSalesTax:         request.CollectSalesTax,
+		IsPublicRegistration:    request.IsPublicRegistration,
+		AllowAddExistingUsers:   request.AllowAddExistingUsers,
+		DefaultAlertPreferences: request.DefaultAlertPreferences,
+		ParentGroupId:           request.ParentGroupId,
+		SystemAccessRemove:      request.SystemAccessRemove,
+		CreatedAt:               time.Now().Unix(),
+	}
+
+	if err := api.Controller.UserGroups.Link(group); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := group.ValidateDefaultAlertPreferences(); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	if err := api.Controller.UserGroups.Add(group, api.Controller.Database); err != nil {
@@ -5772,6 +6115,13 @@ func (api *Api) AdminCreateGroupHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		zipCode, err := NormalizeZipCode(request.NewGroupAdminZipCode, api.Controller.Options.ZipCodeFormat)
+		if err != nil {
+			api.exitWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		request.NewGroupAdminZipCode = zipCode
+
 		// Validate password strength
 		if err := ValidatePassword(request.NewGroupAdminPassword); err != nil {
 			api.exitWithError(w, http.StatusBadRequest, err.Error())
@@ -5817,7 +6167,10 @@ func (api *Api) AdminCreateGroupHandler(w http.ResponseWriter, r *http.Request)
 		user.Pin = pin
 
 		// Save new user
-		if err := api.Controller.Users.SaveNewUser(user, api.Controller.Database); err != nil {
+		if err := api.Controller.Users.SaveNewUser(user, api.Controller.Database); errors.Is(err, errUserExists) {
+			api.exitWithError(w, http.StatusConflict, "User with this email already exists")
+			return
+		} else if err != nil {
 			api.exitWithError(w, http.StatusInternalServerError, "Failed to create group admin user")
 			return
 		}
@@ -5910,6 +6263,8 @@ func (api *Api) AdminDeleteGroupHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	api.Controller.AuditLog.Record(r, 0, "userGroup.delete", auditUserGroupTarget(group), fmt.Sprintf("%s users=%d", auditUserGroupSummary(group), len(usersInGroup)), "")
+
 	// Wait 1 second to ensure database transaction visibility across connection pool
 	time.Sleep(1 * time.Second)
 
@@ -5949,22 +6304,25 @@ func (api *Api) AdminUpdateGroupHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var request struct {
-		Id                    uint64          `json:"id"`
-		Name                  string          `json:"name"`
-		Description           string          `json:"description"`
-		SystemAccess          string          `json:"systemAccess"`
-		Delay                 int             `json:"delay"`
-		SystemDelays          string          `json:"systemDelays"`
-		TalkgroupDelays       string          `json:"talkgroupDelays"`
-		ConnectionLimit       uint            `json:"connectionLimit"`
-		MaxUsers              uint            `json:"maxUsers"`
-		BillingEnabled        bool            `json:"billingEnabled"`
-		StripePriceId         string          `json:"stripePriceId"`
-		PricingOptions        []PricingOption `json:"pricingOptions"`
-		BillingMode           string          `json:"billingMode"`
-		CollectSalesTax       bool            `json:"collectSalesTax"`
-		IsPublicRegistration  bool            `json:"isPublicRegistration"`
-		AllowAddExistingUsers bool            `json:"allowAddExistingUsers"`
+		Id                      uint64                `json:"id"`
+		Name                    string                `json:"name"`
+		Description             string                `json:"description"`
+		SystemAccess            string                `json:"systemAccess"`
+		Delay                   int                   `json:"delay"`
+		SystemDelays            string                `json:"systemDelays"`
+		TalkgroupDelays         string                `json:"talkgroupDelays"`
+		ConnectionLimit         uint                  `json:"connectionLimit"`
+		MaxUsers                uint                  `json:"maxUsers"`
+		BillingEnabled          bool                  `json:"billingEnabled"`
+		StripePriceId           string                `json:"stripePriceId"`
+		PricingOptions          []PricingOptionUpdate `json:"pricingOptions"`
+		BillingMode             string                `json:"billingMode"`
+		CollectSalesTax         bool                  `json:"collectSalesTax"`
+		IsPublicRegistration    bool                  `json:"isPublicRegistration"`
+		AllowAddExistingUsers   bool                  `json:"allowAddExistingUsers"`
+		DefaultAlertPreferences string                `json:"defaultAlertPreferences"`
+		ParentGroupId           *uint64               `json:"parentGroupId"`
+		SystemAccessRemove      *string               `json:"systemAccessRemove"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -5978,18 +6336,44 @@ func (api *Api) AdminUpdateGroupHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The parent group, the removed system access and the coupons of the pricing options are kept when
+	// not in the request
+	pricingOptions := make([]PricingOption, len(request.PricingOptions))
+	for i, opt := range request.PricingOptions {
+		pricingOptions[i] = opt.Merge(group.GetPricingOptions())
+	}
+	parentGroupId := group.ParentGroupId
+	if request.ParentGroupId != nil {
+		parentGroupId = *request.ParentGroupId
+	}
+	systemAccessRemove := group.SystemAccessRemove
+	if request.SystemAccessRemove != nil {
+		systemAccessRemove = *request.SystemAccessRemove
+	}
+
+	// Validate the parent group and the default alert preferences against the requested system access
+	candidate := &UserGroup{Id: group.Id, Name: request.Name, SystemAccess: request.SystemAccess, DefaultAlertPreferences: request.DefaultAlertPreferences, ParentGroupId: parentGroupId, SystemAccessRemove: systemAccessRemove}
+	if err := api.Controller.UserGroups.Link(candidate); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := candidate.ValidateDefaultAlertPreferences(); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Validate: If billing is enabled, at least one pricing option is required
-	if request.BillingEnabled && len(request.PricingOptions) == 0 {
+	if request.BillingEnabled && len(pricingOptions) == 0 {
 		api.exitWithError(w, http.StatusBadRequest, "At least one pricing option is required when billing is enabled")
 		return
 	}
 
 	// Validate pricing options (max 3, all fields required)
-	if len(request.PricingOptions) > 3 {
+	if len(pricingOptions) > 3 {
 		api.exitWithError(w, http.StatusBadRequest, "Maximum 3 pricing options allowed")
 		return
 	}
-	for i, opt := range request.PricingOptions {
+	for i, opt := range pricingOptions {
 		if opt.PriceId == "" || opt.Label == "" || opt.Amount == "" {
 			api.exitWithError(w, http.StatusBadRequest, fmt.Sprintf("Pricing option %d is missing required fields", i+1))
 			return
@@ -5998,8 +6382,8 @@ func (api *Api) AdminUpdateGroupHandler(w http.ResponseWriter, r *http.Request)
 
 	// Convert pricing options to JSON string
 	pricingOptionsJSON := ""
-	if len(request.PricingOptions) > 0 {
-		if jsonBytes, err := json.Marshal(request.PricingOptions); err == nil {
+	if len(pricingOptions) > 0 {
+		if jsonBytes, err := json.Marshal(pricingOptions); err == nil {
 			pricingOptionsJSON = string(jsonBytes)
 		}
 	}
@@ -6016,6 +6400,7 @@ func (api *Api) AdminUpdateGroupHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	oldBillingEnabled := group.BillingEnabled
+	auditBefore := auditUserGroupSummary(group)
 
 	group.Name = request.Name
 	group.Description = request.Description
@@ -6034,12 +6419,19 @@ func (api *Api) AdminUpdateGroupHandler(w http.ResponseWriter, r *http.Request)
 	group.CollectSalesTax = request.CollectSalesTax
 	group.IsPublicRegistration = request.IsPublicRegistration
 	group.AllowAddExistingUsers = request.AllowAddExistingUsers
+	group.DefaultAlertPreferences = request.DefaultAlertPreferences
+	group.ParentGroupId = parentGroupId
+	group.SystemAccessRemove = systemAccessRemove
 
 	if err := api.Controller.UserGroups.Update(group, api.Controller.Database); err != nil {
 		api.exitWithError(w, http.StatusInternalServerError, "Failed to update group")
 		return
 	}
 
+	if auditAfter := auditUserGroupSummary(group); auditAfter != auditBefore {
+		api.Controller.AuditLog.Record(r, 0, "userGroup.update", auditUserGroupTarget(group), auditBefore, auditAfter)
+	}
+
 	// Reload groups from database to ensure consistency
 	api.Controller.UserGroups.Load(api.Controller.Database)
 
@@ -6340,6 +6732,7 @@ func (api *Api) AdminGroupCodesHandler(w http.ResponseWriter, r *http.Request) {
 				"isOneTime":   code.IsOneTime,
 				"isActive":    code.IsActive,
 				"createdAt":   code.CreatedAt,
+				"lastUsedAt":  code.LastUsedAt,
 			})
 		}
 	}
@@ -6901,6 +7294,10 @@ func (api *Api) AdminTransferUserHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	fromGroupId := targetUser.UserGroupId
+	fromGroup := api.Controller.UserGroups.Get(fromGroupId)
+	wasGroupAdmin := targetUser.IsGroupAdmin
+
 	// Transfer user (system admin can transfer directly, no approval needed)
 	// Remove group admin status if user was a group admin (security: admin status should not persist when moved to another group)
 	if targetUser.IsGroupAdmin {
@@ -6913,6 +7310,10 @@ func (api *Api) AdminTransferUserHandler(w http.ResponseWriter, r *http.Request)
 	api.syncUserConnectionLimit(targetUser)
 	api.Controller.Users.Update(targetUser)
 	api.Controller.Users.Write(api.Controller.Database)
+	api.applyGroupAlertDefaults(targetUser)
+
+	api.Controller.AuditLog.Record(r, 0, "user.transfer", auditUserTarget(targetUser), fmt.Sprintf("%s groupAdmin=%t", auditUserGroupTarget(fromGroup), wasGroupAdmin), auditUserGroupTarget(toGroup))
+	api.Controller.AuditLog.RecordTransfer(0, targetUser.Id, fromGroupId, toGroup.Id, "admin")
 
 	// Sync config to file if enabled
 	api.Controller.SyncConfigToFile()
@@ -6987,6 +7388,8 @@ func (api *Api) GroupAdminRequestTransferHandler(w http.ResponseWriter, r *http.
 		api.syncUserConnectionLimit(targetUser)
 		api.Controller.Users.Update(targetUser)
 		api.Controller.Users.Write(api.Controller.Database)
+		api.applyGroupAlertDefaults(targetUser)
+		api.Controller.AuditLog.RecordTransfer(user.Id, targetUser.Id, oldGroupId, toGroup.Id, "transfer request to public registration group")
 
 		// Sync config to file if enabled
 		api.Controller.SyncConfigToFile()
@@ -7054,6 +7457,8 @@ func (api *Api) GroupAdminRequestTransferHandler(w http.ResponseWriter, r *http.
 		api.syncUserConnectionLimit(targetUser)
 		api.Controller.Users.Update(targetUser)
 		api.Controller.Users.Write(api.Controller.Database)
+		api.applyGroupAlertDefaults(targetUser)
+		api.Controller.AuditLog.RecordTransfer(user.Id, targetUser.Id, oldGroupId, toGroup.Id, "transfer request to group without admin")
 
 		// Sync config to file if enabled
 		api.Controller.SyncConfigToFile()
@@ -7177,6 +7582,8 @@ func (api *Api) GroupAdminApproveTransferHandler(w http.ResponseWriter, r *http.
 		api.syncUserConnectionLimit(targetUser)
 		api.Controller.Users.Update(targetUser)
 		api.Controller.Users.Write(api.Controller.Database)
+		api.applyGroupAlertDefaults(targetUser)
+		api.Controller.AuditLog.RecordTransfer(user.Id, targetUser.Id, oldGroupId, group.Id, "transfer request")
 
 		// Sync config to file if enabled
 		api.Controller.SyncConfigToFile()
@@ -7208,6 +7615,16 @@ func (api *Api) GroupAdminApproveTransferHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	auditTarget := fmt.Sprintf("transfer %d of user %d", transferReq.Id, transferReq.UserId)
+	if targetUser := api.Controller.Users.GetUserById(transferReq.UserId); targetUser != nil {
+		auditTarget = fmt.Sprintf("transfer %d of %s", transferReq.Id, auditUserTarget(targetUser))
+	}
+	if request.Approve {
+		api.Controller.AuditLog.Record(r, user.Id, "transfer.approve", auditTarget, auditUserGroupTarget(api.Controller.UserGroups.Get(transferReq.FromGroupId)), auditUserGroupTarget(group))
+	} else {
+		api.Controller.AuditLog.Record(r, user.Id, "transfer.reject", auditTarget, "", "")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Transfer request updated",
@@ -7323,6 +7740,8 @@ func (api *Api) GroupAdminApproveTransferLinkHandler(w http.ResponseWriter, r *h
 	api.clearUserDelayValues(targetUser)
 	api.Controller.Users.Update(targetUser)
 	api.Controller.Users.Write(api.Controller.Database)
+	api.applyGroupAlertDefaults(targetUser)
+	api.Controller.AuditLog.RecordTransfer(0, targetUser.Id, oldGroupId, toGroup.Id, "transfer request email link")
 
 	// Sync config to file if enabled
 	api.Controller.SyncConfigToFile()
@@ -7346,6 +7765,9 @@ func (api *Api) GroupAdminApproveTransferLinkHandler(w http.ResponseWriter, r *h
 	transferReq.ApprovalTokenUsed = true // Mark token as used
 	api.Controller.TransferRequests.Update(transferReq, api.Controller.Database)
 
+	// approved by whoever holds the emailed link, without an account to attribute it to
+	api.Controller.AuditLog.Record(r, 0, "transfer.approve", fmt.Sprintf("transfer %d of %s via email link", transferReq.Id, auditUserTarget(targetUser)), auditUserGroupTarget(oldGroup), auditUserGroupTarget(toGroup))
+
 	api.sendTransferApprovalPage(w, true, "Transfer request approved successfully")
 }
 
@@ -7567,8 +7989,8 @@ func (api *Api) UserDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		// Register or update device token
 		var request struct {
-			Token    string `json:"token"`    // OneSignal player ID
-			Platform string `json:"platform"` // "ios" or "android"
+			Token    string `json:"token"`    // OneSignal player ID, or the subscription JSON of a browser
+			Platform string `json:"platform"` // "ios", "android" or "web"
 			Sound    string `json:"sound"`    // Notification sound preference
 		}
 
@@ -7582,7 +8004,16 @@ func (api *Api) UserDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if request.Platform != "ios" && request.Platform != "android" {
+		if request.Platform == deviceTokenPlatformWeb {
+			if !api.Controller.WebPush.Configured() {
+				api.exitWithError(w, http.StatusBadRequest, "Web push is not configured")
+				return
+			}
+			if _, err := parseWebPushSubscription(request.Token); err != nil {
+				api.exitWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		} else if request.Platform != "ios" && request.Platform != "android" {
 			request.Platform = "android" // Default
 		}
 
@@ -7590,31 +8021,10 @@ func (api *Api) UserDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 			request.Sound = "startup.wav" // Default
 		}
 
-		// Check if device token already exists for this user
-		existingToken := api.Controller.DeviceTokens.FindByUserAndToken(client.User.Id, request.Token)
-		if existingToken != nil {
-			// Update existing token
-			existingToken.Platform = request.Platform
-			existingToken.Sound = request.Sound
-			if err := api.Controller.DeviceTokens.Update(existingToken, api.Controller.Database); err != nil {
-				api.exitWithError(w, http.StatusInternalServerError, "Failed to update device token")
-				return
-			}
-		} else {
-			// Create new device token
-			deviceToken := &DeviceToken{
-				UserId:    client.User.Id,
-				Token:     request.Token,
-				Platform:  request.Platform,
-				Sound:     request.Sound,
-				CreatedAt: time.Now().Unix(),
-				LastUsed:  time.Now().Unix(),
-			}
-
-			if err := api.Controller.DeviceTokens.Add(deviceToken, api.Controller.Database); err != nil {
-				api.exitWithError(w, http.StatusInternalServerError, "Failed to register device token")
-				return
-			}
+		// Registered again, a device token of the user is updated
+		if _, err := api.Controller.DeviceTokens.Register(client.User.Id, request.Token, request.Platform, request.Sound, api.Controller.Database); err != nil {
+			api.exitWithError(w, http.StatusInternalServerError, "Failed to register device token")
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -7731,6 +8141,7 @@ func (api *Api) UserTransferToPublicHandler(w http.ResponseWriter, r *http.Reque
 	api.clearUserDelayValues(user)
 	api.Controller.Users.Update(user)
 	api.Controller.Users.Write(api.Controller.Database)
+	api.applyGroupAlertDefaults(user)
 
 	// Send email notifications asynchronously
 	go func() {
@@ -7878,6 +8289,28 @@ func (api *Api) RegistrationSettingsHandler(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
+// CapabilitiesHandler reports which optional features are configured so clients can adapt their UI
+func (api *Api) CapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.exitWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Controller.Capabilities())
+}
+
+// BrandingHandler returns the branding of the server, so white-label deployments are branded without rebuilding the web app
+func (api *Api) BrandingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.exitWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Controller.Branding())
+}
+
 // ValidateAccessCodeHandler validates a registration or invitation code before showing the form
 func (api *Api) ValidateAccessCodeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -0,0 +1,270 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	apnsProductionURL    = "https://api.push.apple.com"
+	apnsSandboxURL       = "https://api.sandbox.push.apple.com"
+	apnsTokenLifetime    = 50 * time.Minute // APNs refuses tokens older than an hour, and ones renewed more than every 20 minutes
+	apnsExpiration       = time.Hour        // alerts are stale past it, APNs drops them
+	apnsMaxMessageLength = 2048             // bytes of the message, the payload being bound to 4096 bytes
+)
+
+// Reasons of the refusals of APNs
+const (
+	apnsReasonBadDeviceToken       = "BadDeviceToken"       // the device token is deleted
+	apnsReasonUnregistered         = "Unregistered"         // the device token is deleted
+	apnsReasonExpiredProviderToken = "ExpiredProviderToken" // the authentication token is signed again
+)
+
+// apnsDeviceTokenPattern matches the hex device tokens of APNs, the other tokens of the ios devices
+// being OneSignal player ids pushed to by the relay server
+const apnsDeviceTokenPattern = `^[0-9a-fA-F]{64,200}$`
+
+var apnsDeviceTokenRegexp = regexp.MustCompile(apnsDeviceTokenPattern)
+
+func isAPNsDeviceToken(token string) bool {
+	return apnsDeviceTokenRegexp.MatchString(token)
+}
+
+// apnsConfigured tells whether the APNs authentication key is configured
+func apnsConfigured(options *Options) bool {
+	return options.APNsKeyId != "" && options.APNsTeamId != "" && options.APNsBundleId != "" && options.APNsKey != ""
+}
+
+// apnsSigningKey reads the .p8 authentication key of APNs
+func apnsSigningKey(p8 string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(p8))
+	if block == nil {
+		return nil, errors.New("invalid APNs key, expected the contents of the .p8 file")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs key: %v", err)
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid APNs key, expected an ES256 key")
+	}
+
+	return ecdsaKey, nil
+}
+
+// APNsSender delivers the alerts to the ios devices registered with an APNs device token,
+// authenticating with the token of the configured .p8 key
+type APNsSender struct {
+	controller *Controller
+	client     *http.Client
+	endpoint   string // the APNs server, the one of the configured environment when empty
+	now        func() time.Time
+
+	mutex         sync.Mutex
+	token         string
+	tokenIssuedAt time.Time
+	tokenOptions  string // the options the token was signed with, signed again once they change
+}
+
+func NewAPNsSender(controller *Controller) *APNsSender {
+	return &APNsSender{
+		controller: controller,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// Configured tells whether the alerts can be delivered with APNs
+func (sender *APNsSender) Configured() bool {
+	return sender != nil && apnsConfigured(sender.controller.Options)
+}
+
+func (sender *APNsSender) url() string {
+	if sender.endpoint != "" {
+		return sender.endpoint
+	}
+	if sender.controller.Options.APNsSandbox {
+		return apnsSandboxURL
+	}
+	return apnsProductionURL
+}
+
+// providerToken returns the authentication token of the requests, signed again once it nears its expiration
+func (sender *APNsSender) providerToken() (string, error) {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	options := sender.controller.Options
+	tokenOptions := options.APNsKeyId + "\x00" + options.APNsTeamId + "\x00" + options.APNsKey
+	now := sender.now()
+
+	if sender.token != "" && sender.tokenOptions == tokenOptions && now.Sub(sender.tokenIssuedAt) < apnsTokenLifetime {
+		return sender.token, nil
+	}
+
+	key, err := apnsSigningKey(options.APNsKey)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": options.APNsTeamId,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = options.APNsKeyId
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	sender.token = signed
+	sender.tokenIssuedAt = now
+	sender.tokenOptions = tokenOptions
+
+	return signed, nil
+}
+
+// resetProviderToken discards the authentication token APNs reported expired
+func (sender *APNsSender) resetProviderToken() {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	sender.token = ""
+}
+
+// Send posts the payload to the device, returning the http status and the reason of a refusal
+func (sender *APNsSender) Send(deviceToken string, payload []byte) (int, string, error) {
+	token, err := sender.providerToken()
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/3/device/%s", sender.url(), deviceToken), bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", sender.controller.Options.APNsBundleId)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-expiration", fmt.Sprint(sender.now().Add(apnsExpiration).Unix()))
+
+	resp, err := sender.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, "", nil
+	}
+
+	var refusal struct {
+		Reason string `json:"reason"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(body, &refusal)
+
+	if refusal.Reason == apnsReasonExpiredProviderToken {
+		sender.resetProviderToken()
+	}
+
+	return resp.StatusCode, refusal.Reason, nil
+}
+
+// SendToUser sends the notification to the ios devices of the user registered with an APNs device token,
+// deleting the tokens APNs refuses. It returns the number of devices the notification was delivered to.
+func (sender *APNsSender) SendToUser(userId uint64, title, subtitle, message string, data map[string]interface{}) int {
+	if !sender.Configured() {
+		return 0
+	}
+
+	controller := sender.controller
+	delivered := 0
+
+	for _, device := range controller.DeviceTokens.GetForUser(userId) {
+		if device.Platform != "ios" || !isAPNsDeviceToken(device.Token) {
+			continue
+		}
+
+		status, reason, err := sender.Send(device.Token, apnsPayload(title, subtitle, message, device.Sound, data))
+		switch {
+		case err != nil:
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("apns: failed to send to device %d of user %d: %v", device.Id, userId, err))
+		case reason == apnsReasonBadDeviceToken || reason == apnsReasonUnregistered:
+			controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("apns: removing device %d of user %d (%s)", device.Id, userId, reason))
+			if err := controller.DeviceTokens.Delete(device.Id, controller.Database); err != nil {
+				controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("apns: failed to remove device %d of user %d: %v", device.Id, userId, err))
+			}
+		case status != http.StatusOK:
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("apns: device %d of user %d refused (status %d, %s)", device.Id, userId, status, reason))
+		default:
+			delivered++
+			if err := controller.DeviceTokens.Touch(device.Token, controller.Database); err != nil {
+				controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("apns: failed to record the use of device %d of user %d: %v", device.Id, userId, err))
+			}
+		}
+	}
+
+	return delivered
+}
+
+// apnsPayload returns the notification of the alert, its data alongside the aps dictionary
+func apnsPayload(title, subtitle, message, sound string, data map[string]interface{}) []byte {
+	if sound == "" {
+		sound = "startup.wav"
+	}
+
+	alert := map[string]string{
+		"title": title,
+		"body":  truncatePushMessage(message, apnsMaxMessageLength),
+	}
+	if subtitle != "" {
+		alert["subtitle"] = subtitle
+	}
+
+	payload := map[string]interface{}{}
+	for key, value := range data {
+		payload[key] = value
+	}
+	payload["aps"] = map[string]interface{}{
+		"alert": alert,
+		"sound": sound,
+	}
+
+	b, _ := json.Marshal(payload)
+	return b
+}
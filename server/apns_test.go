@@ -0,0 +1,213 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// newAPNsKey returns a .p8 authentication key
+func newAPNsKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestIsAPNsDeviceToken(t *testing.T) {
+	for token, expected := range map[string]bool{
+		strings.Repeat("a1", 32):               true,
+		"4f1c2a9e-1b2c-4d3e-8f90-123456789abc": false, // OneSignal player id
+		strings.Repeat("z1", 32):               false,
+		"":                                     false,
+	} {
+		if isAPNsDeviceToken(token) != expected {
+			t.Errorf("%q: expected %v", token, expected)
+		}
+	}
+}
+
+func TestAPNsSendToUser(t *testing.T) {
+	key, p8 := newAPNsKey(t)
+
+	var (
+		requests = map[string]*http.Request{}
+		payloads = map[string]map[string]interface{}{}
+		mutex    = sync.Mutex{}
+	)
+
+	delivered, unregistered, bad := strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deviceToken := strings.TrimPrefix(r.URL.Path, "/3/device/")
+
+		payload := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mutex.Lock()
+		requests[deviceToken] = r
+		payloads[deviceToken] = payload
+		mutex.Unlock()
+
+		switch deviceToken {
+		case unregistered:
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered","timestamp":1700000000000}`))
+		case bad:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		}
+	}))
+	defer server.Close()
+
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs(), DeviceTokens: NewDeviceTokens()}
+	controller.Options.APNsKeyId = "ABC123DEFG"
+	controller.Options.APNsTeamId = "DEF123GHIJ"
+	controller.Options.APNsBundleId = "com.example.radio"
+	controller.Options.APNsKey = p8
+
+	sender := NewAPNsSender(controller)
+	sender.client = server.Client()
+	sender.endpoint = server.URL
+
+	for _, device := range []*DeviceToken{
+		{Id: 1, UserId: 5, Platform: "ios", Token: delivered, Sound: "chime.wav"},
+		{Id: 2, UserId: 5, Platform: "ios", Token: unregistered},
+		{Id: 3, UserId: 5, Platform: "ios", Token: bad},
+		{Id: 4, UserId: 5, Platform: "ios", Token: "4f1c2a9e-1b2c-4d3e-8f90-123456789abc"},
+		{Id: 5, UserId: 5, Platform: "android", Token: strings.Repeat("d", 64)},
+	} {
+		controller.DeviceTokens.tokens[device.Id] = device
+		controller.DeviceTokens.userTokens[device.UserId] = append(controller.DeviceTokens.userTokens[device.UserId], device)
+	}
+
+	if n := sender.SendToUser(5, "COUNTY FIRE / DISPATCH", "STATION 1", "STRUCTURE FIRE", map[string]interface{}{"callId": 7}); n != 1 {
+		t.Errorf("expected 1 delivery, got %d", n)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(requests) != 3 {
+		t.Fatalf("expected the APNs device tokens pushed to, got %d requests", len(requests))
+	}
+
+	r := requests[delivered]
+	if r.Header.Get("apns-topic") != "com.example.radio" || r.Header.Get("apns-push-type") != "alert" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+
+	token, err := jwt.Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "bearer "), func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("invalid provider token: %v", err)
+	}
+	if token.Header["kid"] != "ABC123DEFG" || token.Claims.(jwt.MapClaims)["iss"] != "DEF123GHIJ" {
+		t.Errorf("unexpected provider token %v %v", token.Header, token.Claims)
+	}
+
+	aps, _ := payloads[delivered]["aps"].(map[string]interface{})
+	alert, _ := aps["alert"].(map[string]interface{})
+	if alert["title"] != "COUNTY FIRE / DISPATCH" || alert["subtitle"] != "STATION 1" || alert["body"] != "STRUCTURE FIRE" || aps["sound"] != "chime.wav" || payloads[delivered]["callId"] != 7.0 {
+		t.Errorf("unexpected payload %v", payloads[delivered])
+	}
+
+	devices := controller.DeviceTokens.GetForUser(5)
+	if len(devices) != 3 || devices[0].Id != 1 || devices[1].Id != 4 || devices[2].Id != 5 {
+		t.Errorf("expected the refused devices removed, got %v", devices)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// the use of the delivered device is recorded
+	if len(d.queries) != 3 || !strings.Contains(d.queries[0], `SET "lastUsed"`) || d.args[0][1] != delivered {
+		t.Fatalf("expected the use of the device 1 recorded, got %v %v", d.queries, d.args)
+	}
+	if !strings.Contains(d.queries[1], `DELETE FROM "deviceTokens"`) || d.args[1][0] != int64(2) || d.args[2][0] != int64(3) {
+		t.Errorf("expected the devices 2 and 3 deleted, got %v %v", d.queries, d.args)
+	}
+}
+
+func TestAPNsProviderTokenCached(t *testing.T) {
+	_, p8 := newAPNsKey(t)
+
+	controller := &Controller{Options: NewOptions()}
+	controller.Options.APNsKeyId = "ABC123DEFG"
+	controller.Options.APNsTeamId = "DEF123GHIJ"
+	controller.Options.APNsKey = p8
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sender := NewAPNsSender(controller)
+	sender.now = func() time.Time { return now }
+
+	first, err := sender.providerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(apnsTokenLifetime - time.Minute)
+	if second, _ := sender.providerToken(); second != first {
+		t.Error("expected the token reused within its lifetime")
+	}
+
+	now = now.Add(time.Minute)
+	if third, _ := sender.providerToken(); third == first {
+		t.Error("expected the token signed again past its lifetime")
+	}
+
+	controller.Options.APNsKey = "not a key"
+	if _, err := sender.providerToken(); err == nil {
+		t.Error("expected an invalid key refused once the options change")
+	}
+}
+
+func TestOptionsKeepAPNsCredentials(t *testing.T) {
+	options := NewOptions()
+	options.APNsKey = "key"
+	options.APNsKeyId = "key-id"
+	options.APNsTeamId = "team-id"
+	options.APNsBundleId = "bundle-id"
+
+	options.FromMap(map[string]any{"apnsSandbox": true})
+
+	if options.APNsKey != "key" || options.APNsKeyId != "key-id" || options.APNsTeamId != "team-id" || options.APNsBundleId != "bundle-id" {
+		t.Errorf("expected the APNs credentials kept, got %q %q %q %q", options.APNsKey, options.APNsKeyId, options.APNsTeamId, options.APNsBundleId)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Responses served for a call whose metadata is stored without its audio
+const (
+	MissingAudioGone     = "gone"     // 410, the audio existed but was archived or pruned
+	MissingAudioNotFound = "notFound" // 404, for the clients expecting the status of a missing call
+)
+
+// transcriptionStatusArchived is the transcription status of a call skipped for its missing audio
+const transcriptionStatusArchived = "archived"
+
+// callAudioArchivedError is the error served for a call without its audio
+const callAudioArchivedError = "audio archived"
+
+// audioMissing tells whether the metadata of the call is all that is left, its audio being archived or pruned
+func (call *Call) audioMissing() bool {
+	return len(call.Audio) == 0
+}
+
+// missingAudioStatus returns the http status configured for a call without its audio
+func missingAudioStatus(response string) int {
+	if response == MissingAudioNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusGone
+}
+
+// writeMissingAudio serves the configured error of a call without its audio
+func writeMissingAudio(w http.ResponseWriter, response string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(missingAudioStatus(response))
+	json.NewEncoder(w).Encode(map[string]string{"error": callAudioArchivedError})
+}
+
+// markTranscriptionArchived records that the transcription of a call was skipped for its missing audio
+func (controller *Controller) markTranscriptionArchived(callId uint64) {
+	query := fmt.Sprintf(`UPDATE "calls" SET "transcriptionStatus" = '%s', "transcriptionFailureReason" = '' WHERE "callId" = %d`, transcriptionStatusArchived, callId)
+	if _, err := controller.Database.Sql.Exec(query); err != nil {
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to update transcription status for call %d: %v", callId, err))
+	}
+}
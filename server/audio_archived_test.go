@@ -0,0 +1,128 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newArchivedCall returns a call whose metadata is stored without its audio
+func newArchivedCall() *Call {
+	return &Call{
+		Id:            7,
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{Id: 1, SystemRef: 1},
+		Talkgroup:     &Talkgroup{Id: 2, TalkgroupRef: 100, ToneDetectionEnabled: true, ToneSets: []ToneSet{{Label: "Station 1"}}},
+		Timestamp:     time.Now(),
+	}
+}
+
+func TestWriteCallAudioArchived(t *testing.T) {
+	for response, status := range map[string]int{MissingAudioGone: http.StatusGone, MissingAudioNotFound: http.StatusNotFound, "": http.StatusGone} {
+		w := httptest.NewRecorder()
+		writeCallAudio(w, newArchivedCall(), response)
+
+		if w.Code != status || !strings.Contains(w.Body.String(), "audio archived") {
+			t.Errorf("%q: expected %d audio archived, got %d %s", response, status, w.Code, w.Body.String())
+		}
+	}
+
+	call := newArchivedCall()
+	call.Audio = []byte("audio")
+
+	w := httptest.NewRecorder()
+	writeCallAudio(w, call, MissingAudioGone)
+
+	if w.Code != http.StatusOK || w.Body.String() != "audio" || w.Header().Get("Content-Type") != "audio/mp4" {
+		t.Errorf("expected the audio served, got %d %q %s", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestSendPlaybackCallArchived(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+	client := &Client{Send: make(chan *Message, 1)}
+
+	controller.sendPlaybackCall(client, newArchivedCall(), nil)
+
+	msg := <-client.Send
+	if msg.Command != MessageCommandError || msg.Payload != "call 7 audio archived" {
+		t.Errorf("expected an audio archived error, got %s %v", msg.Command, msg.Payload)
+	}
+}
+
+func TestTranscriptionSkipsArchivedAudio(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs()}
+	controller.TranscriptionQueue = &TranscriptionQueue{
+		jobs:       make(chan TranscriptionJob, 1),
+		controller: controller,
+		running:    true,
+	}
+
+	controller.queueTranscriptionJobIfNeeded(newArchivedCall(), 0, nil, 0)
+
+	if len(controller.TranscriptionQueue.jobs) != 0 {
+		t.Error("expected no transcription job for a call without its audio")
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.queries) != 1 || !strings.Contains(d.queries[0], `"transcriptionStatus" = 'archived'`) || !strings.Contains(d.queries[0], `"callId" = 7`) {
+		t.Errorf("expected the call marked archived, got %v", d.queries)
+	}
+}
+
+func TestToneDetectionSkipsArchivedAudio(t *testing.T) {
+	// without a tone detector, detecting the tones of the call would panic
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+
+	controller.processToneDetection(newArchivedCall())
+}
+
+func TestDownstreamsSendSkipsArchivedAudio(t *testing.T) {
+	received := 0
+	mutex := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		received++
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{{Apikey: "any", Systems: "*", Url: server.URL, controller: controller}}
+
+	call := newArchivedCall()
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageCall)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if received != 0 {
+		t.Errorf("expected nothing forwarded for a call without its audio, got %d requests", received)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// audioChecksumHeader is the request header a recorder may send the checksum of the audio in,
+// instead of the audioChecksum field of the upload
+const audioChecksumHeader = "X-Audio-Checksum"
+
+// audioChecksumAlgorithms are the algorithms a checksum may be computed with, by name
+var audioChecksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// verifyAudioChecksum checks the audio of an upload against the checksum sent by the recorder,
+// as hex and prefixed by its algorithm, e.g. "sha256:9f86...", or bare and told by its length.
+// An empty checksum isn't verified.
+func verifyAudioChecksum(audio []byte, checksum string) error {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum == "" {
+		return nil
+	}
+
+	algorithm, digest := "", checksum
+	if i := strings.IndexAny(checksum, ":="); i >= 0 {
+		algorithm, digest = strings.ReplaceAll(checksum[:i], "-", ""), checksum[i+1:]
+	} else {
+		switch len(checksum) {
+		case hex.EncodedLen(md5.Size):
+			algorithm = "md5"
+		case hex.EncodedLen(sha1.Size):
+			algorithm = "sha1"
+		case hex.EncodedLen(sha256.Size):
+			algorithm = "sha256"
+		}
+	}
+
+	newHash, ok := audioChecksumAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported checksum %q, expected sha256, sha1 or md5", checksum)
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return fmt.Errorf("invalid %s checksum %q", algorithm, digest)
+	}
+
+	h := newHash()
+	h.Write(audio)
+
+	if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+		return fmt.Errorf("%s checksum mismatch, got %x for %d bytes of audio, expected %s", algorithm, actual, len(audio), digest)
+	}
+
+	return nil
+}
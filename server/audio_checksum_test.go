@@ -0,0 +1,108 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAudioChecksum(t *testing.T) {
+	audio := []byte("call audio")
+	sha := fmt.Sprintf("%x", sha256.Sum256(audio))
+	md := fmt.Sprintf("%x", md5.Sum(audio))
+
+	for _, checksum := range []string{"", sha, strings.ToUpper(sha), "sha256:" + sha, "SHA-256=" + sha, md, "md5:" + md} {
+		if err := verifyAudioChecksum(audio, checksum); err != nil {
+			t.Errorf("checksum %q: unexpected error %v", checksum, err)
+		}
+	}
+
+	for _, checksum := range []string{fmt.Sprintf("%x", sha256.Sum256(audio[:5])), "sha256:" + md, "crc32:0badcafe", "sha256:not hex", "abc"} {
+		if err := verifyAudioChecksum(audio, checksum); err == nil {
+			t.Errorf("checksum %q: expected an error", checksum)
+		}
+	}
+}
+
+func TestCallUploadHandlerChecksum(t *testing.T) {
+	audio := bytes.Repeat([]byte{1}, 100)
+	sha := fmt.Sprintf("%x", sha256.Sum256(audio))
+
+	upload := func(field string, header string) (*httptest.ResponseRecorder, *Controller) {
+		controller := &Controller{
+			Apikeys: NewApikeys(),
+			Ingest:  make(chan *Call, 1),
+			Logs:    NewLogs(),
+			Systems: NewSystems(),
+		}
+		controller.Apikeys.List = []*Apikey{{Key: "key", Systems: "*"}}
+
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("key", "key")
+		mw.WriteField("system", "1")
+		mw.WriteField("talkgroup", "2")
+		mw.WriteField("dateTime", "1700000000")
+		if field != "" {
+			mw.WriteField("audioChecksum", field)
+		}
+		w, _ := mw.CreateFormFile("audio", "call.wav")
+		w.Write(audio)
+		mw.Close()
+
+		r := httptest.NewRequest(http.MethodPost, "/api/call-upload", body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		if header != "" {
+			r.Header.Set(audioChecksumHeader, header)
+		}
+
+		rr := httptest.NewRecorder()
+		NewApi(controller).CallUploadHandler(rr, r)
+
+		return rr, controller
+	}
+
+	for name, test := range map[string]struct {
+		field, header string
+		accepted      bool
+	}{
+		"absent":            {accepted: true},
+		"matching field":    {field: "sha256:" + sha, accepted: true},
+		"matching header":   {header: sha, accepted: true},
+		"mismatched field":  {field: fmt.Sprintf("sha256:%x", sha256.Sum256(audio[:50]))},
+		"mismatched header": {header: fmt.Sprintf("%x", md5.Sum(audio[:50]))},
+	} {
+		rr, controller := upload(test.field, test.header)
+
+		if test.accepted {
+			if rr.Code != http.StatusOK || len(controller.Ingest) != 1 {
+				t.Errorf("%s: got %d %q, want the call ingested", name, rr.Code, rr.Body.String())
+			}
+		} else {
+			if rr.Code != http.StatusUnprocessableEntity || len(controller.Ingest) != 0 || !strings.Contains(rr.Body.String(), "checksum mismatch") {
+				t.Errorf("%s: got %d %q, want the call rejected", name, rr.Code, rr.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	// talkgroupGainMin and talkgroupGainMax bound the gain in dB of a talkgroup
+	talkgroupGainMin = -20.0
+	talkgroupGainMax = 20.0
+
+	// audioGainCacheSize is the number of leveled audios kept, recent calls being served the most
+	audioGainCacheSize = 256
+
+	audioGainProfileListener      = "listener"
+	audioGainProfileTranscription = "transcription"
+)
+
+func validTalkgroupGain(gain float64) bool {
+	return !math.IsNaN(gain) && gain >= talkgroupGainMin && gain <= talkgroupGainMax
+}
+
+// talkgroupGainErrors lists the talkgroups whose gain is out of range
+func talkgroupGainErrors(systems []*System) []string {
+	errs := []string{}
+
+	for _, system := range systems {
+		if system.Talkgroups == nil {
+			continue
+		}
+
+		for _, talkgroup := range system.Talkgroups.List {
+			if !validTalkgroupGain(talkgroup.Gain) {
+				errs = append(errs, fmt.Sprintf("system %s talkgroup %s: gain %v dB is out of the %v to %v dB range", system.Label, talkgroup.Label, talkgroup.Gain, talkgroupGainMin, talkgroupGainMax))
+			}
+		}
+	}
+
+	return errs
+}
+
+// applyWavGain scales the samples of a 16 bits pcm wav audio, clipping them at full scale. It returns
+// false for any other audio, which needs ffmpeg.
+func applyWavGain(audio []byte, gain float64) ([]byte, bool) {
+	if len(audio) < 12 || string(audio[0:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		return nil, false
+	}
+
+	pcm16 := false
+	factor := math.Pow(10, gain/20)
+
+	for offset := 12; offset+8 <= len(audio); {
+		id := string(audio[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(audio[offset+4 : offset+8]))
+		start := offset + 8
+
+		switch id {
+		case "fmt ":
+			if start+16 > len(audio) {
+				return nil, false
+			}
+			format := binary.LittleEndian.Uint16(audio[start : start+2])
+			bits := binary.LittleEndian.Uint16(audio[start+14 : start+16])
+			pcm16 = format == 1 && bits == 16
+
+		case "data":
+			if !pcm16 {
+				return nil, false
+			}
+
+			end := start + size
+			if end > len(audio) {
+				end = len(audio)
+			}
+
+			leveled := make([]byte, len(audio))
+			copy(leveled, audio)
+
+			for i := start; i+1 < end; i += 2 {
+				sample := float64(int16(binary.LittleEndian.Uint16(leveled[i:i+2]))) * factor
+				sample = math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(sample)))
+				binary.LittleEndian.PutUint16(leveled[i:i+2], uint16(int16(sample)))
+			}
+
+			return leveled, true
+		}
+
+		offset = start + size + size%2
+	}
+
+	return nil, false
+}
+
+// ApplyGain returns the audio with its volume changed by gain dB, in the same format
+func (ffmpeg *FFMpeg) ApplyGain(audio []byte, filename string, mime string, gain float64) ([]byte, error) {
+	if leveled, ok := applyWavGain(audio, gain); ok {
+		return leveled, nil
+	}
+
+	var args []string
+	for _, format := range []string{"m4a", "mp3", "wav"} {
+		if audioHasFormat(filename, mime, format) {
+			args = downstreamAudioFormats[format].args
+			break
+		}
+	}
+	if args == nil {
+		return nil, fmt.Errorf("unsupported audio format %s", mime)
+	}
+
+	if !ffmpeg.available {
+		return nil, errors.New("ffmpeg is not available")
+	}
+
+	cmd := exec.Command("ffmpeg", append([]string{"-i", "-", "-af", fmt.Sprintf("volume=%.1fdB", gain)}, append(args, "-")...)...)
+	cmd.Stdin = bytes.NewReader(audio)
+
+	stdout := bytes.NewBuffer([]byte(nil))
+	cmd.Stdout = stdout
+
+	stderr := bytes.NewBuffer([]byte(nil))
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// AudioGainCache keeps the leveled audio of the recent calls by call, profile and gain, so a call
+// played by many listeners is leveled once. The stored audio is never modified.
+type AudioGainCache struct {
+	apply   func(audio []byte, filename string, mime string, gain float64) ([]byte, error)
+	entries map[string][]byte
+	order   []string
+	pending map[string]*audioGainPending
+	mutex   sync.Mutex
+}
+
+// audioGainPending is an audio being leveled, waited for by the other gets of the same key
+type audioGainPending struct {
+	done    chan struct{}
+	leveled []byte
+	err     error
+}
+
+func NewAudioGainCache(apply func(audio []byte, filename string, mime string, gain float64) ([]byte, error)) *AudioGainCache {
+	return &AudioGainCache{
+		apply:   apply,
+		entries: map[string][]byte{},
+		order:   []string{},
+		pending: map[string]*audioGainPending{},
+	}
+}
+
+// Get returns the audio of the call leveled by gain dB for the given profile. The audio is leveled
+// outside the lock, the concurrent gets of the same call waiting for the first one.
+func (cache *AudioGainCache) Get(callId uint64, profile string, gain float64, audio []byte, filename string, mime string) ([]byte, error) {
+	// calls not stored yet have no id to be found again
+	if callId == 0 {
+		return cache.apply(audio, filename, mime, gain)
+	}
+
+	key := fmt.Sprintf("%d:%s:%g:%s", callId, profile, gain, mime)
+
+	cache.mutex.Lock()
+
+	if leveled, ok := cache.entries[key]; ok {
+		cache.mutex.Unlock()
+		return leveled, nil
+	}
+
+	if pending, ok := cache.pending[key]; ok {
+		cache.mutex.Unlock()
+		<-pending.done
+		return pending.leveled, pending.err
+	}
+
+	pending := &audioGainPending{done: make(chan struct{})}
+	cache.pending[key] = pending
+
+	cache.mutex.Unlock()
+
+	pending.leveled, pending.err = cache.apply(audio, filename, mime, gain)
+
+	cache.mutex.Lock()
+
+	delete(cache.pending, key)
+
+	if pending.err == nil {
+		cache.entries[key] = pending.leveled
+		cache.order = append(cache.order, key)
+
+		if len(cache.order) > audioGainCacheSize {
+			delete(cache.entries, cache.order[0])
+			cache.order = cache.order[1:]
+		}
+	}
+
+	cache.mutex.Unlock()
+
+	close(pending.done)
+
+	return pending.leveled, pending.err
+}
+
+// talkgroupGain returns the gain in dB to apply to the audio of the call, 0 for none
+func talkgroupGain(call *Call) float64 {
+	if call.Talkgroup == nil || !validTalkgroupGain(call.Talkgroup.Gain) {
+		return 0
+	}
+	return call.Talkgroup.Gain
+}
+
+// levelCallAudio sets the audio served to the listeners of the call, leveled by the gain of its talkgroup
+func (controller *Controller) levelCallAudio(call *Call) {
+	gain := talkgroupGain(call)
+	if gain == 0 || len(call.Audio) == 0 || len(call.listenerAudio) > 0 {
+		return
+	}
+
+	leveled, err := controller.AudioGains.Get(call.Id, audioGainProfileListener, gain, call.Audio, call.AudioFilename, call.AudioMime)
+	if err != nil {
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("audio gain of call %d: %v, serving the original audio", call.Id, err))
+		return
+	}
+
+	call.listenerAudio = leveled
+}
+
+// levelTranscriptionJob levels the audio of the transcription job by the gain of the talkgroup, when enabled
+func (controller *Controller) levelTranscriptionJob(job *TranscriptionJob, call *Call) {
+	gain := talkgroupGain(call)
+	if gain == 0 || !controller.Options.TranscriptionConfig.ApplyTalkgroupGain {
+		return
+	}
+
+	// the normalized audio has no file name of its own
+	filename := ""
+	if len(call.analysisAudio) == 0 {
+		filename = call.AudioFilename
+	}
+
+	leveled, err := controller.AudioGains.Get(call.Id, audioGainProfileTranscription, gain, job.Audio, filename, job.AudioMime)
+	if err != nil {
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("audio gain of call %d: %v, transcribing the original audio", call.Id, err))
+		return
+	}
+
+	job.Audio = leveled
+}
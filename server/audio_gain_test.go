@@ -0,0 +1,199 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+)
+
+// gainTestAudio returns a pcm16 wav whose samples are all set to sample
+func gainTestAudio(sample int16) []byte {
+	audio := demoAudio()
+	for i := 44; i+1 < len(audio); i += 2 {
+		binary.LittleEndian.PutUint16(audio[i:i+2], uint16(sample))
+	}
+	return audio
+}
+
+func gainTestSample(audio []byte) int16 {
+	return int16(binary.LittleEndian.Uint16(audio[44:46]))
+}
+
+func TestTalkgroupGainLevelsListenerAudio(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), FFMpeg: &FFMpeg{}}
+	controller.AudioGains = NewAudioGainCache(controller.FFMpeg.ApplyGain)
+
+	original := gainTestAudio(1000)
+
+	boosted := &Call{Id: 1, Audio: bytes.Clone(original), AudioMime: "audio/wav", Talkgroup: &Talkgroup{Gain: 6}}
+	controller.levelCallAudio(boosted)
+
+	if !bytes.Equal(boosted.Audio, original) {
+		t.Fatal("the stored audio was modified")
+	}
+
+	// +6 dB doubles the amplitude
+	if sample := gainTestSample(boosted.listenerAudio); sample != 1995 {
+		t.Errorf("expected a leveled sample of 1995, got %d", sample)
+	}
+
+	if !bytes.Equal(boosted.listenerAudio[:44], original[:44]) {
+		t.Error("the wav header was modified")
+	}
+
+	unchanged := &Call{Id: 2, Audio: bytes.Clone(original), AudioMime: "audio/wav", Talkgroup: &Talkgroup{}}
+	controller.levelCallAudio(unchanged)
+
+	if unchanged.listenerAudio != nil {
+		t.Error("a talkgroup without gain should not level the audio")
+	}
+
+	for _, c := range []struct {
+		call     *Call
+		expected []byte
+	}{
+		{boosted, boosted.listenerAudio},
+		{unchanged, original},
+	} {
+		b, err := json.Marshal(c.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m struct {
+			Audio struct {
+				Data []int `json:"data"`
+			} `json:"audio"`
+		}
+		if err = json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+
+		served := []byte{}
+		for _, v := range m.Audio.Data {
+			served = append(served, byte(v))
+		}
+
+		if !bytes.Equal(served, c.expected) {
+			t.Errorf("call %d: served audio does not match", c.call.Id)
+		}
+	}
+}
+
+func TestApplyWavGainClips(t *testing.T) {
+	leveled, ok := applyWavGain(gainTestAudio(20000), 20)
+	if !ok {
+		t.Fatal("expected a pcm16 wav to be leveled without ffmpeg")
+	}
+
+	if sample := gainTestSample(leveled); sample != 32767 {
+		t.Errorf("expected a clipped sample of 32767, got %d", sample)
+	}
+
+	if _, ok := applyWavGain([]byte("ID3 not a wav"), 6); ok {
+		t.Error("expected a non wav audio to be refused")
+	}
+}
+
+func TestTalkgroupGainRange(t *testing.T) {
+	systems := NewSystems().FromMap([]any{
+		map[string]any{"id": float64(1), "label": "County", "talkgroups": []any{
+			map[string]any{"id": float64(1), "label": "Fire", "gain": float64(-20)},
+			map[string]any{"id": float64(2), "label": "Police", "gain": float64(20)},
+			map[string]any{"id": float64(3), "label": "Ems", "gain": float64(24)},
+		}},
+	})
+
+	errs := talkgroupGainErrors(systems.List)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 gain out of range, got %v", errs)
+	}
+
+	if talkgroupGain(&Call{Talkgroup: &Talkgroup{Gain: 24}}) != 0 {
+		t.Error("a gain out of range should not be applied")
+	}
+}
+
+func TestAudioGainCacheAppliesOnce(t *testing.T) {
+	applied := 0
+	cache := NewAudioGainCache(func(audio []byte, filename string, mime string, gain float64) ([]byte, error) {
+		applied++
+		return audio, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		cache.Get(7, audioGainProfileListener, 6, []byte{1}, "", "audio/wav")
+	}
+	cache.Get(7, audioGainProfileTranscription, 6, []byte{1}, "", "audio/wav")
+
+	if applied != 2 {
+		t.Errorf("expected the gain to be applied once per profile, got %d", applied)
+	}
+
+	for i := 0; i < audioGainCacheSize+1; i++ {
+		cache.Get(uint64(100+i), audioGainProfileListener, 6, []byte{1}, "", "audio/wav")
+	}
+
+	if len(cache.entries) != audioGainCacheSize || len(cache.order) != audioGainCacheSize {
+		t.Errorf("expected the cache to hold %d entries, got %d", audioGainCacheSize, len(cache.entries))
+	}
+}
+
+func TestAudioGainCacheLevelsOutsideTheLock(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	var applied atomic.Int32
+	cache := NewAudioGainCache(func(audio []byte, filename string, mime string, gain float64) ([]byte, error) {
+		if audio[0] == 1 {
+			applied.Add(1)
+			started <- struct{}{}
+			<-release
+		}
+		return audio, nil
+	})
+
+	results := make(chan []byte, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			leveled, _ := cache.Get(1, audioGainProfileListener, 6, []byte{1}, "", "audio/wav")
+			results <- leveled
+		}()
+	}
+
+	<-started
+
+	// another call is leveled while the first one is still decoding
+	if leveled, err := cache.Get(2, audioGainProfileListener, 6, []byte{2}, "", "audio/wav"); err != nil || leveled[0] != 2 {
+		t.Fatalf("expected the other call leveled meanwhile, got %v %v", leveled, err)
+	}
+
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if leveled := <-results; len(leveled) != 1 || leveled[0] != 1 {
+			t.Errorf("expected the leveled audio shared, got %v", leveled)
+		}
+	}
+
+	if n := applied.Load(); n != 1 {
+		t.Errorf("expected the call leveled once, got %d", n)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// wavFormat reads the sample rate and the number of channels of a wav audio
+func wavFormat(audio []byte) (sampleRate uint, channels uint, ok bool) {
+	if len(audio) < 12 || string(audio[0:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		return 0, 0, false
+	}
+
+	for offset := 12; offset+8 <= len(audio); {
+		id := string(audio[offset : offset+4])
+		size := int64(binary.LittleEndian.Uint32(audio[offset+4 : offset+8]))
+
+		if id == "fmt " {
+			if offset+24 > len(audio) {
+				return 0, 0, false
+			}
+			channels = uint(binary.LittleEndian.Uint16(audio[offset+10 : offset+12]))
+			sampleRate = uint(binary.LittleEndian.Uint32(audio[offset+12 : offset+16]))
+			return sampleRate, channels, true
+		}
+
+		next := int64(offset) + 8 + size + size%2
+		if next > int64(len(audio)) {
+			break
+		}
+		offset = int(next)
+	}
+
+	return 0, 0, false
+}
+
+// audioConforms reports whether the audio is already a wav with the given format, other formats always need a pass through ffmpeg
+func audioConforms(audio []byte, sampleRate uint, channels uint) bool {
+	r, c, ok := wavFormat(audio)
+	return ok && r == sampleRate && c == channels
+}
+
+func normalizeArgs(sampleRate uint, channels uint) []string {
+	return []string{"-i", "-", "-ar", fmt.Sprint(sampleRate), "-ac", fmt.Sprint(channels), "-c:a", "pcm_s16le", "-f", "wav", "-"}
+}
+
+// Normalize resamples the call audio to a 16 bits pcm wav of the given sample rate and channels. When keepOriginal
+// is set, the call audio is left as received and the normalized copy only feeds transcription and tone detection.
+func (ffmpeg *FFMpeg) Normalize(call *Call, sampleRate uint, channels uint, keepOriginal bool) error {
+	if sampleRate == 0 || len(call.Audio) == 0 {
+		return nil
+	}
+
+	if channels == 0 {
+		channels = 1
+	}
+
+	if audioConforms(call.Audio, sampleRate, channels) {
+		return nil
+	}
+
+	if !ffmpeg.available {
+		if !ffmpeg.normalizeWarned {
+			ffmpeg.normalizeWarned = true
+
+			return errors.New("ffmpeg is not available, no audio normalization will be performed")
+		}
+		return nil
+	}
+
+	cmd := exec.Command("ffmpeg", normalizeArgs(sampleRate, channels)...)
+	cmd.Stdin = bytes.NewReader(call.Audio)
+
+	stdout := bytes.NewBuffer([]byte(nil))
+	cmd.Stdout = stdout
+
+	stderr := bytes.NewBuffer([]byte(nil))
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("audio normalization failed for %s: %v %s", call.AudioFilename, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if keepOriginal {
+		call.analysisAudio = stdout.Bytes()
+		call.analysisAudioMime = "audio/wav"
+
+	} else {
+		call.Audio = stdout.Bytes()
+		call.AudioFilename = fmt.Sprintf("%v.wav", strings.TrimSuffix(call.AudioFilename, path.Ext(call.AudioFilename)))
+		call.AudioMime = "audio/wav"
+	}
+
+	return nil
+}
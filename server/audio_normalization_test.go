@@ -0,0 +1,105 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testWav returns a tenth of a second of silent 16 bits pcm wav audio
+func testWav(sampleRate uint32, channels uint16) []byte {
+	dataSize := sampleRate / 10 * uint32(channels) * 2
+
+	b := &bytes.Buffer{}
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, 36+dataSize)
+	b.WriteString("WAVEfmt ")
+	binary.Write(b, binary.LittleEndian, uint32(16))
+	binary.Write(b, binary.LittleEndian, uint16(1))
+	binary.Write(b, binary.LittleEndian, channels)
+	binary.Write(b, binary.LittleEndian, sampleRate)
+	binary.Write(b, binary.LittleEndian, sampleRate*uint32(channels)*2)
+	binary.Write(b, binary.LittleEndian, channels*2)
+	binary.Write(b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(b, binary.LittleEndian, dataSize)
+	b.Write(make([]byte, dataSize))
+
+	return b.Bytes()
+}
+
+func TestWavFormat(t *testing.T) {
+	if rate, channels, ok := wavFormat(testWav(48000, 2)); !ok || rate != 48000 || channels != 2 {
+		t.Errorf("expected 48000 hz stereo, got %d hz %d channels ok=%t", rate, channels, ok)
+	}
+
+	if _, _, ok := wavFormat([]byte("ID3 not a wav file")); ok {
+		t.Error("non wav audio must not be recognized")
+	}
+
+	if audioConforms(testWav(48000, 2), 16000, 1) {
+		t.Error("48000 hz stereo must not conform to 16000 hz mono")
+	}
+	if !audioConforms(testWav(16000, 1), 16000, 1) {
+		t.Error("16000 hz mono must conform to 16000 hz mono")
+	}
+}
+
+func TestNormalizeConformantUntouched(t *testing.T) {
+	// conformant audio never reaches ffmpeg, whether it is available or not
+	ffmpeg := &FFMpeg{}
+
+	audio := testWav(16000, 1)
+	call := &Call{Audio: audio, AudioFilename: "call.wav", AudioMime: "audio/wav"}
+
+	if err := ffmpeg.Normalize(call, 16000, 1, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(call.Audio, audio) || call.AudioFilename != "call.wav" || call.analysisAudio != nil {
+		t.Error("conformant audio must be left untouched")
+	}
+}
+
+func TestNormalizeStereo48k(t *testing.T) {
+	ffmpeg := NewFFMpeg()
+	if !ffmpeg.available {
+		t.Skip("ffmpeg is not available")
+	}
+
+	audio := testWav(48000, 2)
+
+	call := &Call{Audio: audio, AudioFilename: "call.wav", AudioMime: "audio/wav"}
+	if err := ffmpeg.Normalize(call, 16000, 1, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(call.Audio, audio) {
+		t.Error("original audio must be kept")
+	}
+	if analysis, _ := call.audioForAnalysis(); !audioConforms(analysis, 16000, 1) {
+		t.Error("analysis audio must be 16000 hz mono")
+	}
+
+	call = &Call{Audio: audio, AudioFilename: "call.mp3", AudioMime: "audio/mpeg"}
+	if err := ffmpeg.Normalize(call, 16000, 1, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !audioConforms(call.Audio, 16000, 1) || call.AudioFilename != "call.wav" || call.AudioMime != "audio/wav" {
+		t.Errorf("audio must be replaced by 16000 hz mono wav, got %s %s", call.AudioFilename, call.AudioMime)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// sniffAudioMime returns the mime of the audio told by its first bytes, or an empty string when
+// the format isn't recognized
+func sniffAudioMime(audio []byte) string {
+	switch {
+	case len(audio) >= 12 && string(audio[0:4]) == "RIFF" && string(audio[8:12]) == "WAVE":
+		return "audio/wav"
+	case len(audio) >= 8 && string(audio[4:8]) == "ftyp":
+		return "audio/mp4"
+	case bytes.HasPrefix(audio, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(audio, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(audio, []byte("#!AMR")):
+		return "audio/amr"
+	case bytes.HasPrefix(audio, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return "audio/webm"
+	case bytes.HasPrefix(audio, []byte("ID3")):
+		return "audio/mpeg"
+	case len(audio) >= 2 && audio[0] == 0xff && audio[1]&0xe0 == 0xe0:
+		// Frame sync, ADTS AAC has a layer of 0 where MPEG audio has 1 to 3
+		if audio[1]&0x06 == 0 {
+			return "audio/aac"
+		}
+		return "audio/mpeg"
+	}
+
+	return ""
+}
+
+// canonicalAudioMime folds the aliases of an audio mime, e.g. audio/x-wav, into the one
+// sniffAudioMime returns
+func canonicalAudioMime(audioMime string) string {
+	audioMime = strings.ToLower(strings.TrimSpace(audioMime))
+	if i := strings.Index(audioMime, ";"); i >= 0 {
+		audioMime = strings.TrimSpace(audioMime[:i])
+	}
+
+	switch audioMime {
+	case "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		return "audio/wav"
+	case "audio/mp3", "audio/x-mp3", "audio/mpeg3", "audio/x-mpeg":
+		return "audio/mpeg"
+	case "audio/m4a", "audio/x-m4a", "video/mp4":
+		return "audio/mp4"
+	case "application/ogg", "audio/opus", "audio/vorbis":
+		return "audio/ogg"
+	case "audio/x-flac":
+		return "audio/flac"
+	case "audio/x-aac", "audio/aacp":
+		return "audio/aac"
+	}
+
+	return audioMime
+}
+
+// correctAudioMime replaces the declared mime of the call audio when it is missing or contradicts
+// the content, so that playback and transcription pick the right decoder. The declared mime is
+// kept when the content isn't recognized.
+func (controller *Controller) correctAudioMime(call *Call) {
+	if !controller.Options.AudioSniffing || len(call.Audio) == 0 {
+		return
+	}
+
+	sniffed := sniffAudioMime(call.Audio)
+	if sniffed == "" || canonicalAudioMime(call.AudioMime) == sniffed {
+		return
+	}
+
+	controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("newcall: file=%v audio mime corrected from %q to %q", call.AudioFilename, call.AudioMime, sniffed))
+
+	call.AudioMime = sniffed
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSniffAudioMime(t *testing.T) {
+	for name, test := range map[string]struct {
+		audio []byte
+		mime  string
+	}{
+		"wav":     {encodeWavPcm16([]int16{0, 1, 2}, 8000), "audio/wav"},
+		"m4a":     {append([]byte{0, 0, 0, 0x20}, []byte("ftypM4A ")...), "audio/mp4"},
+		"ogg":     {[]byte("OggS\x00\x02"), "audio/ogg"},
+		"mp3 id3": {[]byte("ID3\x04\x00"), "audio/mpeg"},
+		"mp3":     {[]byte{0xff, 0xfb, 0x90, 0x64}, "audio/mpeg"},
+		"adts":    {[]byte{0xff, 0xf1, 0x50, 0x80}, "audio/aac"},
+		"unknown": {[]byte("garbage"), ""},
+		"empty":   {nil, ""},
+	} {
+		if mime := sniffAudioMime(test.audio); mime != test.mime {
+			t.Errorf("%s: sniffed %q, want %q", name, mime, test.mime)
+		}
+	}
+}
+
+func TestCorrectAudioMime(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+	controller.Options.AudioSniffing = true
+	wav := encodeWavPcm16([]int16{0, 1, 2}, 8000)
+
+	for name, test := range map[string]struct {
+		audio    []byte
+		declared string
+		stored   string
+	}{
+		"empty mime":        {wav, "", "audio/wav"},
+		"wrong mime":        {wav, "audio/mpeg", "audio/wav"},
+		"alias":             {wav, "audio/x-wav", "audio/x-wav"},
+		"with parameters":   {wav, "audio/wav; codecs=1", "audio/wav; codecs=1"},
+		"inconclusive":      {[]byte("garbage"), "audio/mpeg", "audio/mpeg"},
+		"inconclusive none": {[]byte("garbage"), "", ""},
+	} {
+		call := &Call{Audio: test.audio, AudioMime: test.declared}
+		controller.correctAudioMime(call)
+
+		if call.AudioMime != test.stored {
+			t.Errorf("%s: stored %q, want %q", name, call.AudioMime, test.stored)
+		}
+	}
+
+	controller.Options.AudioSniffing = false
+	call := &Call{Audio: wav, AudioMime: "audio/mpeg"}
+	controller.correctAudioMime(call)
+	if call.AudioMime != "audio/mpeg" {
+		t.Errorf("the declared mime should be kept with sniffing off, got %q", call.AudioMime)
+	}
+}
+
+func TestCallUploadCorrectsAudioMime(t *testing.T) {
+	controller := &Controller{
+		Apikeys: NewApikeys(),
+		Ingest:  make(chan *Call, 1),
+		Logs:    NewLogs(),
+		Options: NewOptions(),
+		Systems: NewSystems(),
+	}
+	controller.Apikeys.List = []*Apikey{{Key: "key", Systems: "*"}}
+	controller.Options.AudioSniffing = true
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("key", "key")
+	mw.WriteField("system", "1")
+	mw.WriteField("talkgroup", "2")
+	mw.WriteField("dateTime", "1700000000")
+	w, _ := mw.CreateFormFile("audio", "call.mp3")
+	w.Write(encodeWavPcm16([]int16{0, 1, 2}, 8000))
+	mw.WriteField("audioType", "audio/mpeg")
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/call-upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	NewApi(controller).CallUploadHandler(rr, r)
+
+	if rr.Code != http.StatusOK || len(controller.Ingest) != 1 {
+		t.Fatalf("got %d %q, want the call ingested", rr.Code, rr.Body.String())
+	}
+
+	call := <-controller.Ingest
+	if call.AudioMime != "audio/mpeg" {
+		t.Fatalf("the upload should carry the declared mime, got %q", call.AudioMime)
+	}
+
+	controller.correctAudioMime(call)
+	if call.AudioMime != "audio/wav" {
+		t.Errorf("the wav audio should be stored as audio/wav, got %q", call.AudioMime)
+	}
+}
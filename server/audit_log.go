@@ -0,0 +1,508 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// auditSummaryMaxLength bounds the before and after summaries of an audit log entry
+	auditSummaryMaxLength = 4000
+
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+// AuditEntry is an admin action recorded in the audit log
+type AuditEntry struct {
+	Id          uint64 `json:"id"`
+	ActorUserId uint64 `json:"actorUserId"` // 0 for the admin password, otherwise the group admin
+	Action      string `json:"action"`      // e.g. "systems.update", "user.delete", "transfer.approve"
+	Target      string `json:"target"`
+	Before      string `json:"before,omitempty"`
+	After       string `json:"after,omitempty"`
+	Timestamp   int64  `json:"timestamp"` // unix milliseconds
+	Ip          string `json:"ip"`
+}
+
+// AuditLogFilter selects audit log entries, the zero values matching every entry
+type AuditLogFilter struct {
+	Action      string
+	ActorUserId *uint64
+	Target      string // case-insensitive substring of the target
+	Since       int64  // unix milliseconds
+	Until       int64  // unix milliseconds
+	Limit       uint   // defaultAuditLogLimit when 0, at most maxAuditLogLimit
+	Offset      uint
+}
+
+// AuditLog records the admin actions for review
+type AuditLog struct {
+	controller *Controller
+}
+
+func NewAuditLog(controller *Controller) *AuditLog {
+	return &AuditLog{controller: controller}
+}
+
+// Record writes an admin action to the audit log, with the ip of the request when given. Within a
+// request passing through AuditMiddleware, the entry describes the request and is written once it
+// succeeded.
+func (auditLog *AuditLog) Record(r *http.Request, actorUserId uint64, action string, target string, before string, after string) {
+	if auditLog == nil {
+		return
+	}
+
+	entry := &AuditEntry{
+		ActorUserId: actorUserId,
+		Action:      action,
+		Target:      target,
+		Before:      truncateAuditSummary(before),
+		After:       truncateAuditSummary(after),
+	}
+
+	if r != nil {
+		if request, ok := r.Context().Value(auditRequestKey{}).(*auditRequest); ok {
+			request.entries = append(request.entries, entry)
+			return
+		}
+	}
+
+	auditLog.write(r, entry)
+}
+
+func (auditLog *AuditLog) write(r *http.Request, entry *AuditEntry) {
+	if !auditLog.controller.Options.AuditLogEnabled {
+		return
+	}
+
+	entry.Timestamp = time.Now().UnixMilli()
+
+	if r != nil {
+		entry.Ip = GetRemoteAddr(r)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "auditLog" ("actorUserId", "action", "target", "before", "after", "timestamp", "ip") VALUES (%d, $1, $2, $3, $4, %d, $5)`, entry.ActorUserId, entry.Timestamp)
+	if _, err := auditLog.controller.Database.Sql.Exec(query, entry.Action, entry.Target, entry.Before, entry.After, entry.Ip); err != nil {
+		auditLog.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("audit log: %s %s: %v", entry.Action, entry.Target, err))
+	}
+}
+
+// auditRequestKey is the context key of the entries recorded while handling an audited request
+type auditRequestKey struct{}
+
+type auditRequest struct {
+	entries []*AuditEntry
+}
+
+// auditReadOnlyRoutes are the admin routes taking a POST without changing anything
+var auditReadOnlyRoutes = []string{
+	"/api/admin/logs",
+	"/api/admin/radioreference/",
+	"/api/admin/tone-export",
+}
+
+// auditedRequest tells if the request is an admin or group admin request which may change something
+func auditedRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/api/admin/") && !strings.HasPrefix(r.URL.Path, "/api/group-admin/") {
+		return false
+	}
+
+	for _, route := range auditReadOnlyRoutes {
+		if strings.HasPrefix(r.URL.Path, route) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// auditResponseWriter keeps the status of the response to audit only the successful requests
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// AuditMiddleware records every successful admin and group admin request which may change
+// something, described by the entries its handler recorded or otherwise by its method and path,
+// the actor resolving who made it
+func AuditMiddleware(auditLog *AuditLog, actor func(r *http.Request) uint64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auditLog == nil || !auditedRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			request := &auditRequest{}
+			rw := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), auditRequestKey{}, request)))
+
+			if rw.status >= http.StatusBadRequest {
+				return
+			}
+
+			if len(request.entries) == 0 {
+				request.entries = append(request.entries, &AuditEntry{
+					ActorUserId: actor(r),
+					Action:      r.Method + " " + r.URL.Path,
+					Target:      r.URL.Path,
+				})
+			}
+
+			for _, entry := range request.entries {
+				auditLog.write(r, entry)
+			}
+		})
+	}
+}
+
+// auditActor returns the group admin making a group admin request, 0 for the admin password
+func (api *Api) auditActor(r *http.Request) uint64 {
+	if !strings.HasPrefix(r.URL.Path, "/api/group-admin/") {
+		return 0
+	}
+
+	if user, _, err := api.getGroupAdminUser(r); err == nil && user != nil {
+		return user.Id
+	}
+
+	return 0
+}
+
+// Search returns the entries matching the filter, most recent first, and how many match in total
+func (auditLog *AuditLog) Search(filter AuditLogFilter) ([]*AuditEntry, uint64, error) {
+	var (
+		args  = []any{}
+		where = []string{}
+	)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Action != "" {
+		where = append(where, `"action" = `+arg(filter.Action))
+	}
+	if filter.ActorUserId != nil {
+		where = append(where, fmt.Sprintf(`"actorUserId" = %d`, *filter.ActorUserId))
+	}
+	if filter.Target != "" {
+		where = append(where, fmt.Sprintf(`strpos(lower("target"), lower(%s)) > 0`, arg(filter.Target)))
+	}
+	if filter.Since > 0 {
+		where = append(where, fmt.Sprintf(`"timestamp" >= %d`, filter.Since))
+	}
+	if filter.Until > 0 {
+		where = append(where, fmt.Sprintf(`"timestamp" <= %d`, filter.Until))
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var count uint64
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM "auditLog" %s`, clause)
+	if err := auditLog.controller.Database.Sql.QueryRow(query, args...).Scan(&count); err != nil {
+		return nil, 0, fmt.Errorf("%v in %s", err, query)
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultAuditLogLimit
+	} else if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	query = fmt.Sprintf(`SELECT "auditLogId", "actorUserId", "action", "target", "before", "after", "timestamp", "ip" FROM "auditLog" %s ORDER BY "timestamp" DESC, "auditLogId" DESC LIMIT %d OFFSET %d`, clause, limit, filter.Offset)
+	rows, err := auditLog.controller.Database.Sql.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		entry := &AuditEntry{}
+		if err := rows.Scan(&entry.Id, &entry.ActorUserId, &entry.Action, &entry.Target, &entry.Before, &entry.After, &entry.Timestamp, &entry.Ip); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, count, rows.Err()
+}
+
+// Prune deletes the entries older than the retention of the audit log, those of the user groups
+// included, and returns how many were deleted
+func (auditLog *AuditLog) Prune(now time.Time) (int64, error) {
+	days := auditLog.controller.Options.AuditLogRetentionDays
+	if days == 0 {
+		return 0, nil
+	}
+
+	var pruned int64
+
+	for _, table := range []string{"auditLog", "userGroupAuditLog"} {
+		query := fmt.Sprintf(`DELETE FROM "%s" WHERE "timestamp" < %d`, table, now.AddDate(0, 0, -int(days)).UnixMilli())
+		res, err := auditLog.controller.Database.Sql.Exec(query)
+		if err != nil {
+			return pruned, fmt.Errorf("%v in %s", err, query)
+		}
+
+		if n, err := res.RowsAffected(); err == nil {
+			pruned += n
+		}
+	}
+
+	return pruned, nil
+}
+
+// AuditLogHandler returns the audit log entries matching the action, actorUserId, target, since,
+// until, limit and offset query parameters
+func (admin *Admin) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	filter := AuditLogFilter{
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+	}
+
+	if s := query.Get("actorUserId"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid actorUserId"})
+			return
+		}
+		filter.ActorUserId = &id
+	}
+
+	for _, param := range []struct {
+		key   string
+		value *int64
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if s := query.Get(param.key); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || v < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid " + param.key})
+				return
+			}
+			*param.value = v
+		}
+	}
+
+	for _, param := range []struct {
+		key   string
+		value *uint
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if s := query.Get(param.key); s != "" {
+			v, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid " + param.key})
+				return
+			}
+			*param.value = uint(v)
+		}
+	}
+
+	entries, count, err := admin.Controller.AuditLog.Search(filter)
+	if err != nil {
+		admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("audit log search: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read the audit log"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"entries": entries, "count": count})
+}
+
+func truncateAuditSummary(summary string) string {
+	if len(summary) <= auditSummaryMaxLength {
+		return summary
+	}
+
+	// cut on a rune boundary
+	cut := auditSummaryMaxLength
+	for cut > 0 && !utf8RuneStart(summary[cut]) {
+		cut--
+	}
+
+	return summary[:cut] + "…"
+}
+
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// auditSnapshot maps the items of a configuration section to their description and fingerprint,
+// taken before and after a change to summarize it with auditChanges
+type auditSnapshot map[string]auditItem
+
+type auditItem struct {
+	description string
+	fingerprint string
+}
+
+func auditFingerprint(v any) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditSystemsSnapshot describes the systems and their talkgroups by ref
+func auditSystemsSnapshot(systems []*System) auditSnapshot {
+	snapshot := auditSnapshot{}
+
+	for _, system := range systems {
+		// the talkgroups and units are compared on their own
+		m := map[string]any{}
+		if b, err := json.Marshal(system); err == nil {
+			json.Unmarshal(b, &m)
+		}
+		delete(m, "talkgroups")
+		delete(m, "units")
+
+		snapshot[fmt.Sprintf("system:%d", system.SystemRef)] = auditItem{
+			description: fmt.Sprintf("system %d (%s)", system.SystemRef, system.Label),
+			fingerprint: auditFingerprint(m),
+		}
+
+		if system.Talkgroups == nil {
+			continue
+		}
+		for _, talkgroup := range system.Talkgroups.List {
+			snapshot[fmt.Sprintf("system:%d:talkgroup:%d", system.SystemRef, talkgroup.TalkgroupRef)] = auditItem{
+				description: fmt.Sprintf("system %d talkgroup %d (%s)", system.SystemRef, talkgroup.TalkgroupRef, talkgroup.Label),
+				fingerprint: auditFingerprint(talkgroup),
+			}
+		}
+	}
+
+	return snapshot
+}
+
+// auditApikeysSnapshot describes the api keys by id, a new key showing as a change of its own
+// without the key itself
+func auditApikeysSnapshot(apikeys []*Apikey) auditSnapshot {
+	snapshot := auditSnapshot{}
+
+	for _, apikey := range apikeys {
+		description := fmt.Sprintf("apikey %d (%s)", apikey.Id, apikey.Ident)
+
+		snapshot[fmt.Sprintf("apikey:%d", apikey.Id)] = auditItem{
+			description: description,
+			fingerprint: auditFingerprint([]any{apikey.Disabled, apikey.Ident, apikey.Order, apikey.Systems}),
+		}
+		snapshot[fmt.Sprintf("apikey:%d:key", apikey.Id)] = auditItem{
+			description: description + " key",
+			fingerprint: auditFingerprint(apikey.Key),
+		}
+	}
+
+	return snapshot
+}
+
+// auditChanges summarizes the items removed or changed as they were before, and the items added
+// or changed as they are after, empty when nothing changed
+func auditChanges(before auditSnapshot, after auditSnapshot) (string, string) {
+	keys := []string{}
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	removed, added := []string{}, []string{}
+
+	for _, key := range keys {
+		b, inBefore := before[key]
+		a, inAfter := after[key]
+
+		switch {
+		case !inAfter:
+			removed = append(removed, "removed "+b.description)
+		case !inBefore:
+			added = append(added, "added "+a.description)
+		case a.fingerprint != b.fingerprint:
+			removed = append(removed, "changed "+b.description)
+			added = append(added, "changed "+a.description)
+		}
+	}
+
+	return strings.Join(removed, "\n"), strings.Join(added, "\n")
+}
+
+// auditUserGroupSummary describes the access and billing settings of a user group
+func auditUserGroupSummary(group *UserGroup) string {
+	return fmt.Sprintf("name=%q billingEnabled=%t billingMode=%q pricingOptions=%s stripePriceId=%q collectSalesTax=%t maxUsers=%d connectionLimit=%d delay=%d parentGroupId=%d isPublicRegistration=%t systemAccess=%s systemAccessRemove=%s",
+		group.Name, group.BillingEnabled, group.BillingMode, group.PricingOptions, group.StripePriceId, group.CollectSalesTax, group.MaxUsers, group.ConnectionLimit, group.Delay, group.ParentGroupId, group.IsPublicRegistration, group.SystemAccess, group.SystemAccessRemove)
+}
+
+// auditUserTarget describes a user as the target of an admin action
+func auditUserTarget(user *User) string {
+	return fmt.Sprintf("user %d (%s)", user.Id, user.Email)
+}
+
+// auditUserGroupTarget describes a user group as the target or the state of an admin action
+func auditUserGroupTarget(group *UserGroup) string {
+	if group == nil {
+		return "no group"
+	}
+	return fmt.Sprintf("group %d (%s)", group.Id, group.Name)
+}
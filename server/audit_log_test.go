@@ -0,0 +1,214 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newAuditTestController(t *testing.T) (*Controller, *recordingDriver) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{
+		Database:   db,
+		Options:    NewOptions(),
+		Logs:       NewLogs(),
+		Users:      NewUsers(),
+		UserGroups: NewUserGroups(),
+	}
+	controller.Options.AuditLogEnabled = true
+	controller.Options.secret = "secret"
+	controller.Admin = NewAdmin(controller)
+	controller.AuditLog = NewAuditLog(controller)
+
+	return controller, d
+}
+
+// auditInserts returns the arguments of the audit log inserts, by their query
+func auditInserts(d *recordingDriver) (queries []string, args [][]driver.Value) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, query := range d.queries {
+		if strings.HasPrefix(query, `INSERT INTO "auditLog"`) {
+			queries = append(queries, query)
+			args = append(args, d.args[i])
+		}
+	}
+
+	return queries, args
+}
+
+func TestAuditLogUserDelete(t *testing.T) {
+	controller, d := newAuditTestController(t)
+	controller.Users.Add(&User{Id: 7, Email: "jane@example.com"})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{ID: "test"}).SignedString([]byte(controller.Options.secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.Admin.Tokens = append(controller.Admin.Tokens, token)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/admin/users/7", nil)
+	r.Header.Set("Authorization", token)
+	r.RemoteAddr = "203.0.113.5:4242"
+	w := httptest.NewRecorder()
+
+	controller.Admin.UserDeleteHandler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the user to be deleted, got %d %s", w.Code, w.Body.String())
+	}
+
+	queries, args := auditInserts(d)
+	if len(queries) != 1 {
+		t.Fatalf("expected one audit log entry, got %v", queries)
+	}
+	if !strings.Contains(queries[0], "VALUES (0,") {
+		t.Errorf("expected the admin password as the actor, got %s", queries[0])
+	}
+	if args[0][0] != "user.delete" || args[0][1] != "user 7 (jane@example.com)" || args[0][4] != "203.0.113.5" {
+		t.Errorf("unexpected audit log entry %v", args[0])
+	}
+}
+
+func TestAuditLogRecord(t *testing.T) {
+	controller, d := newAuditTestController(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/group-admin/transfer-requests/approve", nil)
+	r.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+	controller.AuditLog.Record(r, 42, "transfer.approve", "transfer 3 of user 7 (jane@example.com)", "group 1 (North)", "group 2 (South)")
+
+	queries, args := auditInserts(d)
+	if len(queries) != 1 {
+		t.Fatalf("expected one audit log entry, got %v", queries)
+	}
+	if !strings.Contains(queries[0], "VALUES (42,") {
+		t.Errorf("expected the group admin as the actor, got %s", queries[0])
+	}
+	want := []driver.Value{"transfer.approve", "transfer 3 of user 7 (jane@example.com)", "group 1 (North)", "group 2 (South)", "198.51.100.9"}
+	for i := range want {
+		if args[0][i] != want[i] {
+			t.Errorf("argument %d: expected %v, got %v", i, want[i], args[0][i])
+		}
+	}
+
+	controller.Options.AuditLogEnabled = false
+	controller.AuditLog.Record(r, 42, "transfer.reject", "transfer 4", "", "")
+
+	if queries, _ := auditInserts(d); len(queries) != 1 {
+		t.Errorf("expected nothing recorded with the audit log disabled, got %v", queries)
+	}
+
+	var auditLog *AuditLog
+	auditLog.Record(r, 0, "user.delete", "user 1", "", "")
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	controller, d := newAuditTestController(t)
+
+	actor := func(r *http.Request) uint64 { return 42 }
+
+	serve := func(method string, path string, handler http.HandlerFunc) {
+		r := httptest.NewRequest(method, path, nil)
+		AuditMiddleware(controller.AuditLog, actor)(handler).ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve(http.MethodPost, "/api/group-admin/generate-code", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	queries, args := auditInserts(d)
+	if len(queries) != 1 {
+		t.Fatalf("expected one audit log entry, got %v", queries)
+	}
+	if !strings.Contains(queries[0], "VALUES (42,") || args[0][0] != "POST /api/group-admin/generate-code" || args[0][1] != "/api/group-admin/generate-code" {
+		t.Errorf("expected the request to describe the entry, got %s %v", queries[0], args[0])
+	}
+
+	serve(http.MethodDelete, "/api/admin/users/7", func(w http.ResponseWriter, r *http.Request) {
+		controller.AuditLog.Record(r, 0, "user.delete", "user 7 (jane@example.com)", "", "")
+	})
+
+	queries, args = auditInserts(d)
+	if len(queries) != 2 {
+		t.Fatalf("expected the recorded entry alone, got %v", queries)
+	}
+	if !strings.Contains(queries[1], "VALUES (0,") || args[1][0] != "user.delete" {
+		t.Errorf("expected the recorded entry, got %s %v", queries[1], args[1])
+	}
+
+	serve(http.MethodPut, "/api/admin/config", func(w http.ResponseWriter, r *http.Request) {
+		controller.AuditLog.Record(r, 0, "config.update", "config", "", "")
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	serve(http.MethodGet, "/api/admin/users", func(w http.ResponseWriter, r *http.Request) {})
+	serve(http.MethodPost, "/api/admin/logs", func(w http.ResponseWriter, r *http.Request) {})
+	serve(http.MethodPost, "/api/user/login", func(w http.ResponseWriter, r *http.Request) {})
+
+	if queries, _ := auditInserts(d); len(queries) != 2 {
+		t.Errorf("expected failed, read-only and user requests not to be recorded, got %v", queries)
+	}
+}
+
+func TestAuditChanges(t *testing.T) {
+	newSystem := func(ref uint, label string, talkgroups ...*Talkgroup) *System {
+		system := NewSystem()
+		system.SystemRef = ref
+		system.Label = label
+		system.Talkgroups.List = talkgroups
+		return system
+	}
+
+	before := auditSystemsSnapshot([]*System{
+		newSystem(1, "County", &Talkgroup{TalkgroupRef: 100, Label: "Fire"}, &Talkgroup{TalkgroupRef: 200, Label: "EMS"}),
+		newSystem(2, "City"),
+	})
+	after := auditSystemsSnapshot([]*System{
+		newSystem(1, "County", &Talkgroup{TalkgroupRef: 100, Label: "Fire Dispatch"}),
+		newSystem(3, "State"),
+	})
+
+	removed, added := auditChanges(before, after)
+
+	if want := "changed system 1 talkgroup 100 (Fire)\nremoved system 1 talkgroup 200 (EMS)\nremoved system 2 (City)"; removed != want {
+		t.Errorf("expected removed\n%s\ngot\n%s", want, removed)
+	}
+	if want := "changed system 1 talkgroup 100 (Fire Dispatch)\nadded system 3 (State)"; added != want {
+		t.Errorf("expected added\n%s\ngot\n%s", want, added)
+	}
+
+	if removed, added := auditChanges(before, before); removed != "" || added != "" {
+		t.Errorf("expected no changes, got %q %q", removed, added)
+	}
+
+	rotated := auditApikeysSnapshot([]*Apikey{{Id: 1, Ident: "scanner", Key: "new-secret", Systems: "*"}})
+	removed, added = auditChanges(auditApikeysSnapshot([]*Apikey{{Id: 1, Ident: "scanner", Key: "old-secret", Systems: "*"}}), rotated)
+
+	if removed != "changed apikey 1 (scanner) key" || added != "changed apikey 1 (scanner) key" {
+		t.Errorf("expected the key rotation, got %q %q", removed, added)
+	}
+	if strings.Contains(removed+added, "secret") {
+		t.Errorf("expected the keys left out of the summary, got %q %q", removed, added)
+	}
+}
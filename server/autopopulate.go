@@ -0,0 +1,163 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+)
+
+// autoPopulateTalkgroup creates the talkgroup of a call received on an auto populated system. It
+// takes the group and the tag named by the call, or else the default group and tag of the system,
+// or else the "Unknown" group and the "Untagged" tag. Missing groups and tags are created.
+func (controller *Controller) autoPopulateTalkgroup(system *System, call *Call, talkgroupId uint) (*Talkgroup, error) {
+	var (
+		err   error
+		group *Group
+		ok    bool
+		tag   *Tag
+	)
+
+	defaultGroup, defaultTag := controller.autoPopulateDefaults(system)
+
+	groupLabels := []string{"Unknown"}
+	if len(call.Meta.TalkgroupGroups) > 0 {
+		groupLabels = call.Meta.TalkgroupGroups
+	}
+	groupLabel := groupLabels[0]
+
+	tagLabel := "Untagged"
+	if len(call.Meta.TalkgroupTag) > 0 {
+		tagLabel = call.Meta.TalkgroupTag
+	}
+
+	if len(call.Meta.TalkgroupGroups) == 0 && defaultGroup != nil {
+		group = defaultGroup
+
+	} else if group, ok = controller.Groups.GetGroupByLabel(groupLabel); !ok {
+		group = &Group{Label: groupLabel}
+
+		controller.Groups.List = append(controller.Groups.List, group)
+
+		if err = controller.Groups.Write(controller.Database); err != nil {
+			return nil, err
+		}
+
+		if err = controller.Groups.Read(controller.Database); err != nil {
+			return nil, err
+		}
+
+		// Sync config to file if enabled
+		controller.SyncConfigToFile()
+
+		if group, ok = controller.Groups.GetGroupByLabel(groupLabel); !ok {
+			return nil, fmt.Errorf("unable to get group %s", groupLabel)
+		}
+	}
+
+	if len(call.Meta.TalkgroupTag) == 0 && defaultTag != nil {
+		tag = defaultTag
+
+	} else if tag, ok = controller.Tags.GetTagByLabel(tagLabel); !ok {
+		tag = &Tag{Label: tagLabel}
+
+		controller.Tags.List = append(controller.Tags.List, tag)
+
+		if err = controller.Tags.Write(controller.Database); err != nil {
+			return nil, err
+		}
+
+		if err = controller.Tags.Read(controller.Database); err != nil {
+			return nil, err
+		}
+
+		// Sync config to file if enabled
+		controller.SyncConfigToFile()
+
+		if tag, ok = controller.Tags.GetTagByLabel(tagLabel); !ok {
+			return nil, fmt.Errorf("unable to get tag %s", tagLabel)
+		}
+	}
+
+	talkgroup := &Talkgroup{
+		GroupIds:     []uint64{group.Id},
+		Label:        fmt.Sprintf("%d", talkgroupId),
+		Name:         fmt.Sprintf("%d", talkgroupId),
+		TalkgroupRef: talkgroupId,
+		TagId:        tag.Id,
+	}
+
+	// Update label and name if provided (v6 style)
+	if len(call.Meta.TalkgroupLabel) > 0 {
+		talkgroup.Label = call.Meta.TalkgroupLabel
+	}
+
+	// Set Name: use TalkgroupName if available, otherwise fallback to Label
+	// This fixes SDR Trunk uploads that only send label/tgid but no name
+	if len(call.Meta.TalkgroupName) > 0 {
+		talkgroup.Name = call.Meta.TalkgroupName
+	} else {
+		talkgroup.Name = talkgroup.Label
+	}
+
+	system.Talkgroups.List = append(system.Talkgroups.List, talkgroup)
+
+	return talkgroup, nil
+}
+
+// autoPopulateDefaults returns the default group and tag of the system, nil when unset. Defaults
+// deleted since they were configured are ignored with a warning.
+func (controller *Controller) autoPopulateDefaults(system *System) (*Group, *Tag) {
+	var (
+		group *Group
+		tag   *Tag
+		ok    bool
+	)
+
+	if system.DefaultGroupId > 0 {
+		if group, ok = controller.Groups.GetGroupById(system.DefaultGroupId); !ok {
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("autopopulate: default group %d of system %s no longer exists", system.DefaultGroupId, system.Label))
+		}
+	}
+
+	if system.DefaultTagId > 0 {
+		if tag, ok = controller.Tags.GetTagById(system.DefaultTagId); !ok {
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("autopopulate: default tag %d of system %s no longer exists", system.DefaultTagId, system.Label))
+		}
+	}
+
+	return group, tag
+}
+
+// autoPopulateDefaultErrors lists the systems whose default group or tag doesn't exist
+func autoPopulateDefaultErrors(systems []*System, groups *Groups, tags *Tags) []string {
+	errs := []string{}
+
+	for _, system := range systems {
+		if system.DefaultGroupId > 0 {
+			if _, ok := groups.GetGroupById(system.DefaultGroupId); !ok {
+				errs = append(errs, fmt.Sprintf("system %s: default group %d does not exist", system.Label, system.DefaultGroupId))
+			}
+		}
+
+		if system.DefaultTagId > 0 {
+			if _, ok := tags.GetTagById(system.DefaultTagId); !ok {
+				errs = append(errs, fmt.Sprintf("system %s: default tag %d does not exist", system.Label, system.DefaultTagId))
+			}
+		}
+	}
+
+	return errs
+}
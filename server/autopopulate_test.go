@@ -0,0 +1,108 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func newAutoPopulateController() *Controller {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	controller.Groups.List = []*Group{{Id: 1, Label: "Unknown"}, {Id: 5, Label: "Fire"}, {Id: 6, Label: "Police"}}
+	controller.Tags.List = []*Tag{{Id: 1, Label: "Untagged"}, {Id: 9, Label: "Dispatch"}, {Id: 10, Label: "Tactical"}}
+
+	return controller
+}
+
+func TestAutoPopulateTalkgroupDefaults(t *testing.T) {
+	controller := newAutoPopulateController()
+
+	system := NewSystem()
+	system.Label = "County"
+	system.DefaultGroupId = 5
+	system.DefaultTagId = 9
+
+	talkgroup, err := controller.autoPopulateTalkgroup(system, &Call{Meta: CallMeta{TalkgroupLabel: "Fire Dispatch"}}, 1201)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(talkgroup.GroupIds, []uint64{5}) || talkgroup.TagId != 9 {
+		t.Errorf("expected the default group and tag of the system, got groups %v tag %d", talkgroup.GroupIds, talkgroup.TagId)
+	}
+	if talkgroup.TalkgroupRef != 1201 || talkgroup.Label != "Fire Dispatch" || talkgroup.Name != "Fire Dispatch" {
+		t.Errorf("unexpected talkgroup %+v", talkgroup)
+	}
+	if len(system.Talkgroups.List) != 1 || system.Talkgroups.List[0] != talkgroup {
+		t.Errorf("expected the talkgroup added to the system, got %v", system.Talkgroups.List)
+	}
+
+	// the group and tag named by the call win over the defaults
+	talkgroup, err = controller.autoPopulateTalkgroup(system, &Call{Meta: CallMeta{TalkgroupGroups: []string{"Police"}, TalkgroupTag: "Tactical"}}, 1202)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(talkgroup.GroupIds, []uint64{6}) || talkgroup.TagId != 10 {
+		t.Errorf("expected the group and tag of the call, got groups %v tag %d", talkgroup.GroupIds, talkgroup.TagId)
+	}
+
+	// defaults deleted since are ignored
+	system.DefaultGroupId = 50
+	system.DefaultTagId = 90
+	talkgroup, err = controller.autoPopulateTalkgroup(system, &Call{}, 1203)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(talkgroup.GroupIds, []uint64{1}) || talkgroup.TagId != 1 || talkgroup.Name != "1203" {
+		t.Errorf("expected the unknown group and untagged tag, got %+v", talkgroup)
+	}
+}
+
+func TestAutoPopulateDefaultErrors(t *testing.T) {
+	controller := newAutoPopulateController()
+
+	systems := []*System{
+		{Label: "Valid", DefaultGroupId: 5, DefaultTagId: 9},
+		{Label: "Unset"},
+		{Label: "Invalid", DefaultGroupId: 50, DefaultTagId: 90},
+	}
+
+	errs := autoPopulateDefaultErrors(systems, controller.Groups, controller.Tags)
+	if len(errs) != 2 {
+		t.Fatalf("expected the missing group and tag of the invalid system, got %v", errs)
+	}
+}
+
+func TestSystemAutoPopulateDefaultsFromMap(t *testing.T) {
+	system := NewSystem().FromMap(map[string]any{"id": float64(1), "label": "County", "defaultGroupId": float64(5), "defaultTagId": float64(9)})
+	if system.DefaultGroupId != 5 || system.DefaultTagId != 9 {
+		t.Fatalf("unexpected defaults %d %d", system.DefaultGroupId, system.DefaultTagId)
+	}
+
+	b, err := system.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := map[string]any{}
+	json.Unmarshal(b, &m)
+	if exported := NewSystem().FromMap(m); exported.DefaultGroupId != 5 || exported.DefaultTagId != 9 {
+		t.Errorf("expected the defaults exported, got %s", b)
+	}
+}
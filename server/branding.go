@@ -0,0 +1,151 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const defaultBrandingTitle = "Thinline Radio"
+
+var (
+	brandingColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	brandingTitlePattern = regexp.MustCompile(`(?is)<title>.*?</title>`)
+)
+
+// Branding is what a white-label deployment shows in place of the default title, logo and colors
+type Branding struct {
+	Title   string `json:"title"`
+	LogoUrl string `json:"logoUrl,omitempty"`
+	Color   string `json:"color,omitempty"`
+}
+
+// sanitizeBrandingUrl keeps the paths of this server and the http urls, anything else such as a
+// javascript: url is dropped
+func sanitizeBrandingUrl(s string) string {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		return s
+	}
+
+	if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return u.String()
+	}
+
+	return ""
+}
+
+// Branding returns the branding of the options, the logo defaulting to the email logo when one is uploaded
+func (controller *Controller) Branding() Branding {
+	options := controller.Options
+
+	branding := Branding{
+		Title:   strings.TrimSpace(SanitizeText(options.Branding, "strip")),
+		LogoUrl: sanitizeBrandingUrl(options.BrandingLogoUrl),
+	}
+
+	if branding.Title == "" {
+		branding.Title = defaultBrandingTitle
+	}
+
+	if branding.LogoUrl == "" && options.EmailLogoFilename != "" {
+		branding.LogoUrl = "/email-logo"
+	}
+
+	if brandingColorPattern.MatchString(options.BrandingColor) {
+		branding.Color = options.BrandingColor
+	}
+
+	return branding
+}
+
+// injectHead inserts the html at the end of the head of the page, or wherever it fits best
+func injectHead(html string, s string) string {
+	for _, tag := range []string{"</head>", "</HEAD>"} {
+		if strings.Contains(html, tag) {
+			return strings.Replace(html, tag, s+tag, 1)
+		}
+	}
+
+	for _, tag := range []string{"<head>", "<HEAD>"} {
+		if strings.Contains(html, tag) {
+			return strings.Replace(html, tag, tag+s, 1)
+		}
+	}
+
+	for _, tag := range []string{"</body>", "</BODY>"} {
+		if strings.Contains(html, tag) {
+			return strings.Replace(html, tag, s+tag, 1)
+		}
+	}
+
+	return s + html
+}
+
+// injectBranding sets the title and the theme color of the page, html escaped
+func injectBranding(html string, branding Branding) string {
+	title := fmt.Sprintf("<title>%s</title>", template.HTMLEscapeString(branding.Title))
+
+	if brandingTitlePattern.MatchString(html) {
+		html = brandingTitlePattern.ReplaceAllLiteralString(html, title)
+	} else {
+		html = injectHead(html, title)
+	}
+
+	if branding.Color != "" {
+		html = injectHead(html, fmt.Sprintf(`<meta name="theme-color" content="%s">`, template.HTMLEscapeString(branding.Color)))
+	}
+
+	return html
+}
+
+// initialConfigScript returns the script defining window.initialConfig. The config is json
+// encoded, which escapes <, > and &, so no value can close the script.
+func (controller *Controller) initialConfigScript() string {
+	options := controller.Options
+	branding := controller.Branding()
+
+	config := map[string]any{
+		"branding":        branding.Title,
+		"brandingColor":   branding.Color,
+		"brandingLogoUrl": branding.LogoUrl,
+		"email":           options.Email,
+		"options": map[string]any{
+			"userRegistrationEnabled": options.UserRegistrationEnabled,
+			"stripePaywallEnabled":    options.StripePaywallEnabled,
+			"stripePublishableKey":    options.StripePublishableKey,
+			"stripePriceId":           options.StripePriceId,
+			"baseUrl":                 options.BaseUrl,
+			"emailLogoFilename":       options.EmailLogoFilename,
+			"emailLogoBorderRadius":   options.EmailLogoBorderRadius,
+			"turnstileEnabled":        options.TurnstileEnabled,
+			"turnstileSiteKey":        options.TurnstileSiteKey,
+		},
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		b = []byte("{}")
+	}
+
+	return fmt.Sprintf("\n<script>\nwindow.initialConfig = %s;\n</script>", b)
+}
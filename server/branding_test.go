@@ -0,0 +1,87 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBrandingHandler(t *testing.T) {
+	controller := &Controller{Options: NewOptions()}
+	controller.Options.Branding = "County Scanner"
+	controller.Options.BrandingColor = "#1976d2"
+	controller.Options.BrandingLogoUrl = "https://example.com/logo.png"
+
+	w := httptest.NewRecorder()
+	NewApi(controller).BrandingHandler(w, httptest.NewRequest(http.MethodGet, "/api/branding", nil))
+
+	var branding Branding
+	if err := json.NewDecoder(w.Body).Decode(&branding); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Branding{Title: "County Scanner", LogoUrl: "https://example.com/logo.png", Color: "#1976d2"}
+	if branding != expected {
+		t.Errorf("expected %+v, got %+v", expected, branding)
+	}
+
+	// unset, the default title and the uploaded email logo
+	controller.Options = NewOptions()
+	controller.Options.EmailLogoFilename = "email-logo.png"
+
+	if branding = controller.Branding(); branding.Title != defaultBrandingTitle || branding.LogoUrl != "/email-logo" || branding.Color != "" {
+		t.Errorf("expected the defaults, got %+v", branding)
+	}
+}
+
+func TestBrandingEscaped(t *testing.T) {
+	controller := &Controller{Options: NewOptions()}
+	controller.Options.Branding = `</title></script><script>alert("title")</script>`
+	controller.Options.BrandingColor = `#fff" onload="alert(1)`
+	controller.Options.BrandingLogoUrl = "javascript:alert(1)"
+
+	branding := controller.Branding()
+
+	if branding.Color != "" || branding.LogoUrl != "" {
+		t.Errorf("expected the invalid color and logo to be dropped, got %+v", branding)
+	}
+
+	for _, logo := range []string{"//evil.example.com/logo.png", "data:image/svg+xml,<svg onload=alert(1)>"} {
+		if s := sanitizeBrandingUrl(logo); s != "" {
+			t.Errorf("expected %s to be dropped, got %s", logo, s)
+		}
+	}
+
+	html := "<html><head><title>Rdio Scanner</title></head><body></body></html>"
+	html = injectBranding(html, branding)
+	html = injectHead(html, controller.initialConfigScript())
+
+	if strings.Contains(html, "<script>alert") || strings.Contains(html, "</title></script>") {
+		t.Errorf("expected the branding to be escaped, got %s", html)
+	}
+
+	if !strings.Contains(html, "<title>&lt;/title&gt;&lt;/script&gt;") || strings.Count(html, "<title>") != 1 {
+		t.Errorf("expected a single escaped title, got %s", html)
+	}
+
+	if strings.Count(html, "</script>") != 1 {
+		t.Errorf("expected the config script to be closed once, got %s", html)
+	}
+}
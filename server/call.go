@@ -82,12 +82,31 @@ type Call struct {
 	Transcript    string
 	TranscriptConfidence float64
 	TranscriptionStatus string
+	IncidentId    uint64 // id of the first call of the incident, 0 when not part of one
+
+	// normalized copy of the audio for transcription and tone detection, see FFMpeg.Normalize
+	analysisAudio     []byte
+	analysisAudioMime string
+
+	// copy of the audio leveled by the gain of the talkgroup, served to listeners instead of the audio
+	listenerAudio []byte
+
+	// confidence under which listeners show the transcript as uncertain, see transcriptDisplayConfidence
+	displayConfidence float64
 
 	// Add back simple fields for compatibility with v6 uploads
 	SystemId    uint `json:"system"`
 	TalkgroupId uint `json:"talkgroup"`
 }
 
+// audioForAnalysis returns the normalized audio of the call when there is one, otherwise its audio
+func (call *Call) audioForAnalysis() ([]byte, string) {
+	if len(call.analysisAudio) > 0 {
+		return call.analysisAudio, call.analysisAudioMime
+	}
+	return call.Audio, call.AudioMime
+}
+
 func NewCall() *Call {
 	return &Call{
 		Frequencies: []CallFrequency{},
@@ -127,11 +146,26 @@ func (call *Call) IsValid() (ok bool, err error) {
 		err = errors.New("no talkgroup")
 	}
 
+	if !validRef(call.SystemId, refIntegerMax) {
+		ok = false
+		err = fmt.Errorf("system %d out of range", call.SystemId)
+	}
+
+	if !validRef(call.TalkgroupId, refIntegerMax) {
+		ok = false
+		err = fmt.Errorf("talkgroup %d out of range", call.TalkgroupId)
+	}
+
 	return ok, err
 }
 
 func (call *Call) MarshalJSON() ([]byte, error) {
-	audio := strings.ReplaceAll(fmt.Sprintf("%v", call.Audio), " ", ",")
+	data := call.Audio
+	if len(call.listenerAudio) > 0 {
+		data = call.listenerAudio
+	}
+
+	audio := strings.ReplaceAll(fmt.Sprintf("%v", data), " ", ",")
 
 	callMap := map[string]any{
 		"id": call.Id,
@@ -151,10 +185,18 @@ func (call *Call) MarshalJSON() ([]byte, error) {
 		callMap["toneSequence"] = call.ToneSequence
 	}
 
+	if call.IncidentId > 0 {
+		callMap["incidentId"] = call.IncidentId
+	}
+
 	if call.Transcript != "" {
 		callMap["transcript"] = call.Transcript
 		callMap["transcriptConfidence"] = call.TranscriptConfidence
 		callMap["transcriptionStatus"] = call.TranscriptionStatus
+
+		if call.displayConfidence > 0 {
+			callMap["transcriptDisplayConfidence"] = call.displayConfidence
+		}
 	}
 
 	if len(call.Frequencies) > 0 {
@@ -271,6 +313,18 @@ func (calls *Calls) CheckDuplicate(call *Call, msTimeFrame uint, db *Database) (
 }
 
 func (calls *Calls) GetCall(id uint64) (*Call, error) {
+	formatError := errorFormatter("calls", "getcall")
+
+	// Check if this call is currently delayed
+	if calls.controller.Delayer.IsCallDelayed(id) {
+		return nil, formatError(fmt.Errorf("call %d is currently delayed and not available for playback", id), "")
+	}
+
+	return calls.readCall(id)
+}
+
+// readCall reads a call from the database regardless of its delay status
+func (calls *Calls) readCall(id uint64) (*Call, error) {
 	var (
 		err       error
 		query     string
@@ -286,11 +340,6 @@ func (calls *Calls) GetCall(id uint64) (*Call, error) {
 
 	formatError := errorFormatter("calls", "getcall")
 
-	// Check if this call is currently delayed
-	if calls.controller.Delayer.IsCallDelayed(id) {
-		return nil, formatError(fmt.Errorf("call %d is currently delayed and not available for playback", id), "")
-	}
-
 	// Add timeout context to prevent indefinite blocking
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -302,10 +351,10 @@ func (calls *Calls) GetCall(id uint64) (*Call, error) {
 	call := Call{Id: id}
 
 	if calls.controller.Database.Config.DbType == DbTypePostgresql {
-		query = fmt.Sprintf(`SELECT c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", STRING_AGG(CAST(COALESCE(cpt."talkgroupRef", 0) AS text), ','), sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus" FROM "calls" AS c LEFT JOIN "callPatches" AS cp on cp."callId" = c."callId" LEFT JOIN "talkgroups" AS cpt ON cpt."talkgroupId" = cp."talkgroupId" LEFT JOIN "systems" AS sy ON sy."systemId" = c."systemId" LEFT JOIN "talkgroups" AS t ON t."talkgroupId" = c."talkgroupId" WHERE c."callId" = %d GROUP BY c."callId", c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus"`, id)
+		query = fmt.Sprintf(`SELECT c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", STRING_AGG(CAST(COALESCE(cpt."talkgroupRef", 0) AS text), ','), sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus", c."incidentId" FROM "calls" AS c LEFT JOIN "callPatches" AS cp on cp."callId" = c."callId" LEFT JOIN "talkgroups" AS cpt ON cpt."talkgroupId" = cp."talkgroupId" LEFT JOIN "systems" AS sy ON sy."systemId" = c."systemId" LEFT JOIN "talkgroups" AS t ON t."talkgroupId" = c."talkgroupId" WHERE c."callId" = %d GROUP BY c."callId", c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus", c."incidentId"`, id)
 
 	} else {
-		query = fmt.Sprintf(`SELECT c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", GROUP_CONCAT(COALESCE(cpt."talkgroupRef", 0)), sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus" FROM "calls" AS c LEFT JOIN "callPatches" AS cp on cp."callId" = c."callId" LEFT JOIN "talkgroups" AS cpt ON cpt."talkgroupId" = cp."talkgroupId" LEFT JOIN "systems" AS sy ON sy."systemId" = c."systemId" LEFT JOIN "talkgroups" AS t ON t."talkgroupId" = c."talkgroupId" WHERE c."callId" = %d GROUP BY c."callId", c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus"`, id)
+		query = fmt.Sprintf(`SELECT c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", GROUP_CONCAT(COALESCE(cpt."talkgroupRef", 0)), sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus", c."incidentId" FROM "calls" AS c LEFT JOIN "callPatches" AS cp on cp."callId" = c."callId" LEFT JOIN "talkgroups" AS cpt ON cpt."talkgroupId" = cp."talkgroupId" LEFT JOIN "systems" AS sy ON sy."systemId" = c."systemId" LEFT JOIN "talkgroups" AS t ON t."talkgroupId" = c."talkgroupId" WHERE c."callId" = %d GROUP BY c."callId", c."audio", c."audioFilename", c."audioMime", c."siteRef", c."timestamp", sy."systemId", t."talkgroupId", c."frequency", c."toneSequence", c."hasTones", c."transcript", c."transcriptConfidence", c."transcriptionStatus", c."incidentId"`, id)
 	}
 
 	var toneSequenceJson sql.NullString
@@ -313,7 +362,7 @@ func (calls *Calls) GetCall(id uint64) (*Call, error) {
 	var transcriptConfidence sql.NullFloat64
 	var transcriptionStatus sql.NullString
 	
-	if err = tx.QueryRow(query).Scan(&call.Audio, &call.AudioFilename, &call.AudioMime, &call.SiteRef, &timestamp, &patch, &systemId, &talkgroupId, &frequency, &toneSequenceJson, &call.HasTones, &transcript, &transcriptConfidence, &transcriptionStatus); err != nil && err != sql.ErrNoRows {
+	if err = tx.QueryRow(query).Scan(&call.Audio, &call.AudioFilename, &call.AudioMime, &call.SiteRef, &timestamp, &patch, &systemId, &talkgroupId, &frequency, &toneSequenceJson, &call.HasTones, &transcript, &transcriptConfidence, &transcriptionStatus, &call.IncidentId); err != nil && err != sql.ErrNoRows {
 		tx.Rollback()
 		return nil, formatError(err, query)
 	}
@@ -413,6 +462,57 @@ func (calls *Calls) Prune(db *Database, pruneDays uint) error {
 	return nil
 }
 
+// defaultEphemeralTtl is the retention in minutes of calls on an ephemeral talkgroup without its own TTL
+const defaultEphemeralTtl = 60
+
+// ephemeralPruneCutoffs returns, for every ephemeral talkgroup, the timestamp in milliseconds
+// before which its calls must be deleted
+func ephemeralPruneCutoffs(systems []*System, now time.Time) map[uint64]int64 {
+	cutoffs := map[uint64]int64{}
+
+	for _, system := range systems {
+		if system.Talkgroups == nil {
+			continue
+		}
+
+		for _, talkgroup := range system.Talkgroups.List {
+			if !talkgroup.Ephemeral || talkgroup.Id == 0 {
+				continue
+			}
+
+			ttl := talkgroup.EphemeralTtl
+			if ttl == 0 {
+				ttl = defaultEphemeralTtl
+			}
+
+			cutoffs[talkgroup.Id] = now.Add(-time.Duration(ttl) * time.Minute).UnixMilli()
+		}
+	}
+
+	return cutoffs
+}
+
+// PruneEphemeral deletes the calls of ephemeral talkgroups older than their TTL, regardless of pruneDays.
+// The audio is stored with the call row, related rows are removed by cascade.
+func (calls *Calls) PruneEphemeral(db *Database, systems []*System) (int64, error) {
+	var pruned int64
+
+	for talkgroupId, cutoff := range ephemeralPruneCutoffs(systems, time.Now()) {
+		query := fmt.Sprintf(`DELETE FROM "calls" WHERE "talkgroupId" = %d AND "timestamp" < %d`, talkgroupId, cutoff)
+
+		res, err := db.Sql.Exec(query)
+		if err != nil {
+			return pruned, fmt.Errorf("%s in %s", err, query)
+		}
+
+		if n, err := res.RowsAffected(); err == nil {
+			pruned += n
+		}
+	}
+
+	return pruned, nil
+}
+
 func (calls *Calls) Search(searchOptions *CallsSearchOptions, client *Client) (*CallsSearchResults, error) {
 	const (
 		ascOrder  = "ASC"
@@ -741,7 +841,7 @@ func (calls *Calls) WriteCall(call *Call, db *Database) (uint64, error) {
 		// Skip invalid unitRef values from Trunk Recorder (e.g., -1 which wraps to 18446744073709551615)
 		// Trunk Recorder sends -1 when radio ID is unknown or not determined
 		// PostgreSQL bigint max is 9223372036854775807, so wrapped values exceed this
-		if unit.UnitRef > 9223372036854775807 {
+		if !validRef(unit.UnitRef, refBigintMax) {
 			continue
 		}
 		query = fmt.Sprintf(`INSERT INTO "callUnits" ("callId", "offset", "unitRef") VALUES (%d, %f, %d)`, call.Id, unit.Offset, unit.UnitRef)
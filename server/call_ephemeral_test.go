@@ -0,0 +1,52 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEphemeralPruneCutoffs(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	talkgroups := NewTalkgroups()
+	talkgroups.List = []*Talkgroup{
+		{Id: 1, TalkgroupRef: 100},
+		{Id: 2, TalkgroupRef: 200, Ephemeral: true, EphemeralTtl: 15},
+		{Id: 3, TalkgroupRef: 300, Ephemeral: true},
+	}
+
+	systems := []*System{{Id: 1, SystemRef: 1, Talkgroups: talkgroups}}
+
+	cutoffs := ephemeralPruneCutoffs(systems, now)
+
+	if len(cutoffs) != 2 {
+		t.Fatalf("expected 2 ephemeral talkgroups, got %d", len(cutoffs))
+	}
+
+	if _, ok := cutoffs[1]; ok {
+		t.Errorf("non ephemeral talkgroup must not be pruned")
+	}
+
+	if cutoff := cutoffs[2]; cutoff != now.Add(-15*time.Minute).UnixMilli() {
+		t.Errorf("unexpected cutoff for ttl 15: %d", cutoff)
+	}
+
+	if cutoff := cutoffs[3]; cutoff != now.Add(-defaultEphemeralTtl*time.Minute).UnixMilli() {
+		t.Errorf("unexpected cutoff for default ttl: %d", cutoff)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	CallRateAnomalyFlood  = "call_rate_flood"
+	CallRateAnomalySilent = "call_rate_silent"
+)
+
+const (
+	// callRateWindow is the window compared against the baseline
+	callRateWindow = time.Hour
+	// callRateBaselineWindows is the number of windows preceding the current one forming the baseline
+	callRateBaselineWindows = 24
+	// callRateMinBaseline is the minimum calls per window in the baseline before a silence is reported
+	callRateMinBaseline = 4
+	// callRateMinFlood is the minimum calls in a window before a flood is reported
+	callRateMinFlood = 20
+)
+
+// CallRateMonitor detects systems whose call rate deviates sharply from their recent baseline,
+// a silent system usually means a dead recorder and a flooding one a stuck transmitter.
+type CallRateMonitor struct {
+	lastAlerts map[string]time.Time // key: "systemId:anomaly"
+	mutex      sync.Mutex
+}
+
+func NewCallRateMonitor() *CallRateMonitor {
+	return &CallRateMonitor{
+		lastAlerts: map[string]time.Time{},
+		mutex:      sync.Mutex{},
+	}
+}
+
+// callRateAnomaly compares the calls of the current window with the calls of the baseline windows
+// and returns the anomaly type, or an empty string when the rate is normal
+func callRateAnomaly(windowCalls uint, baselineCalls uint, sensitivity uint) string {
+	if sensitivity == 0 {
+		return ""
+	}
+
+	baseline := float64(baselineCalls) / callRateBaselineWindows
+	current := float64(windowCalls)
+
+	switch {
+	case baseline >= callRateMinBaseline && current*float64(sensitivity) < baseline:
+		return CallRateAnomalySilent
+	case windowCalls >= callRateMinFlood && current > baseline*float64(sensitivity):
+		return CallRateAnomalyFlood
+	}
+
+	return ""
+}
+
+// Evaluate returns the anomaly type of the call rate of the system, or an empty string when the
+// rate is normal or the alert is cooling down.
+func (monitor *CallRateMonitor) Evaluate(systemId uint64, windowCalls uint, baselineCalls uint, sensitivity uint, cooldown time.Duration, now time.Time) string {
+	anomaly := callRateAnomaly(windowCalls, baselineCalls, sensitivity)
+	if anomaly == "" {
+		return ""
+	}
+
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	key := fmt.Sprintf("%d:%s", systemId, anomaly)
+
+	if last, ok := monitor.lastAlerts[key]; ok && now.Sub(last) < cooldown {
+		return ""
+	}
+
+	monitor.lastAlerts[key] = now
+
+	return anomaly
+}
+
+// MonitorCallRateAnomalies counts the calls of every system for the last window and its baseline
+// and raises a system alert for each system with an anomalous rate
+func (controller *Controller) MonitorCallRateAnomalies() {
+	sensitivity := controller.Options.CallRateAnomalySensitivity
+	if sensitivity == 0 {
+		return
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-callRateWindow)
+	baselineStart := windowStart.Add(-callRateWindow * callRateBaselineWindows)
+
+	windowCounts, err := controller.countCallsBySystem(windowStart, now)
+	if err != nil {
+		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("failed to check call rates: %v", err))
+		return
+	}
+
+	baselineCounts, err := controller.countCallsBySystem(baselineStart, windowStart)
+	if err != nil {
+		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("failed to check call rates: %v", err))
+		return
+	}
+
+	cooldown := time.Duration(controller.Options.CallRateAnomalyCooldown) * time.Minute
+
+	// the alerts of the systems whose rate is back to normal are resolved
+	current := map[uint64]string{}
+	for _, system := range controller.Systems.List {
+		current[system.Id] = callRateAnomaly(windowCounts[system.Id], baselineCounts[system.Id], sensitivity)
+	}
+	for _, anomaly := range []string{CallRateAnomalySilent, CallRateAnomalyFlood} {
+		controller.ResolveSystemAlerts(anomaly, func(data *SystemAlertData) bool {
+			systemAnomaly, ok := current[data.SystemId]
+			return ok && systemAnomaly != anomaly
+		})
+	}
+
+	for _, system := range controller.Systems.List {
+		windowCalls := windowCounts[system.Id]
+		baselineCalls := baselineCounts[system.Id]
+
+		anomaly := controller.CallRateMonitor.Evaluate(system.Id, windowCalls, baselineCalls, sensitivity, cooldown, now)
+		if anomaly == "" {
+			continue
+		}
+
+		data := &SystemAlertData{
+			SystemId: system.Id,
+			Count:    int(windowCalls),
+		}
+
+		expected := float64(baselineCalls) / callRateBaselineWindows
+
+		switch anomaly {
+		case CallRateAnomalySilent:
+			controller.CreateSystemAlert(
+				anomaly,
+				"warning",
+				"System Gone Silent",
+				fmt.Sprintf("System '%s' received %d call(s) in the last hour, %.1f expected. Check the recorder feeding this system.", system.Label, windowCalls, expected),
+				data,
+				0, // System-generated
+			)
+		case CallRateAnomalyFlood:
+			controller.CreateSystemAlert(
+				anomaly,
+				"warning",
+				"System Call Flood",
+				fmt.Sprintf("System '%s' received %d call(s) in the last hour, %.1f expected. A transmitter may be stuck.", system.Label, windowCalls, expected),
+				data,
+				0, // System-generated
+			)
+		}
+	}
+}
+
+func (controller *Controller) countCallsBySystem(from time.Time, to time.Time) (map[uint64]uint, error) {
+	counts := map[uint64]uint{}
+
+	query := fmt.Sprintf(`SELECT "systemId", COUNT(*) FROM "calls" WHERE "timestamp" >= %d AND "timestamp" < %d GROUP BY "systemId"`, from.UnixMilli(), to.UnixMilli())
+
+	rows, err := controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s in %s", err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			count    uint
+			systemId uint64
+		)
+
+		if err := rows.Scan(&systemId, &count); err != nil {
+			continue
+		}
+
+		counts[systemId] = count
+	}
+
+	return counts, rows.Err()
+}
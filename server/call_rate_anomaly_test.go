@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallRateMonitorEvaluate(t *testing.T) {
+	monitor := NewCallRateMonitor()
+	now := time.Now()
+	cooldown := 6 * time.Hour
+
+	// baseline of 30 calls per hour over the previous 24 hours
+	baseline := uint(30 * callRateBaselineWindows)
+
+	if anomaly := monitor.Evaluate(1, 28, baseline, 4, cooldown, now); anomaly != "" {
+		t.Errorf("normal system raised %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(2, 0, baseline, 4, cooldown, now); anomaly != CallRateAnomalySilent {
+		t.Errorf("silent system raised %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(3, 500, baseline, 4, cooldown, now); anomaly != CallRateAnomalyFlood {
+		t.Errorf("flooding system raised %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(2, 0, baseline, 4, cooldown, now.Add(time.Hour)); anomaly != "" {
+		t.Errorf("alert raised during cooldown: %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(2, 0, baseline, 4, cooldown, now.Add(7*time.Hour)); anomaly != CallRateAnomalySilent {
+		t.Errorf("alert not raised after cooldown: %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(4, 0, 10, 4, cooldown, now); anomaly != "" {
+		t.Errorf("quiet system without baseline raised %q", anomaly)
+	}
+
+	if anomaly := monitor.Evaluate(5, 500, baseline, 0, cooldown, now); anomaly != "" {
+		t.Errorf("disabled detector raised %q", anomaly)
+	}
+}
+
+func TestCallRateAnomaly(t *testing.T) {
+	cases := []struct {
+		window, baseline, sensitivity uint
+		anomaly                       string
+	}{
+		{window: 0, baseline: 240, sensitivity: 4, anomaly: CallRateAnomalySilent},
+		{window: 10, baseline: 240, sensitivity: 4, anomaly: ""},
+		{window: 100, baseline: 240, sensitivity: 4, anomaly: CallRateAnomalyFlood},
+		{window: 0, baseline: 240, sensitivity: 0, anomaly: ""},
+	}
+
+	for _, c := range cases {
+		if anomaly := callRateAnomaly(c.window, c.baseline, c.sensitivity); anomaly != c.anomaly {
+			t.Errorf("%d calls for a baseline of %d: expected %q, got %q", c.window, c.baseline, c.anomaly, anomaly)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// callReassignBatchSize is the number of calls repointed per statement, so a talkgroup with years
+// of calls doesn't hold one huge update
+const callReassignBatchSize = 5000
+
+var (
+	errReassignSameTalkgroup = errors.New("source and target talkgroups are the same")
+	errReassignSourceMissing = errors.New("source system and talkgroup are required")
+	errReassignSystemMissing = errors.New("target system not found")
+	errReassignTargetMissing = errors.New("target talkgroup not found in the target system")
+)
+
+// callReassignment moves the calls of a talkgroup to another talkgroup, possibly of another system
+type callReassignment struct {
+	FromSystemId    uint64
+	FromTalkgroupId uint64
+	ToSystemId      uint64
+	ToTalkgroupId   uint64
+	ToSystemRef     uint
+	ToTalkgroupRef  uint
+}
+
+// planCallReassignment validates the target talkgroup against the configured systems and
+// resolves the refs the moved calls must carry
+func planCallReassignment(systems *Systems, fromSystemId uint64, fromTalkgroupId uint64, toSystemId uint64, toTalkgroupId uint64) (*callReassignment, error) {
+	if fromSystemId == 0 || fromTalkgroupId == 0 {
+		return nil, errReassignSourceMissing
+	}
+
+	if fromSystemId == toSystemId && fromTalkgroupId == toTalkgroupId {
+		return nil, errReassignSameTalkgroup
+	}
+
+	system, ok := systems.GetSystemById(toSystemId)
+	if !ok {
+		return nil, errReassignSystemMissing
+	}
+
+	talkgroup, ok := system.Talkgroups.GetTalkgroupById(toTalkgroupId)
+	if !ok {
+		return nil, errReassignTargetMissing
+	}
+
+	return &callReassignment{
+		FromSystemId:    fromSystemId,
+		FromTalkgroupId: fromTalkgroupId,
+		ToSystemId:      system.Id,
+		ToTalkgroupId:   talkgroup.Id,
+		ToSystemRef:     system.SystemRef,
+		ToTalkgroupRef:  talkgroup.TalkgroupRef,
+	}, nil
+}
+
+// batchQuery repoints up to limit calls of the source talkgroup, ids and refs together
+func (reassignment *callReassignment) batchQuery(limit int) string {
+	return fmt.Sprintf(`UPDATE "calls" SET "systemId" = %d, "talkgroupId" = %d, "systemRef" = %d, "talkgroupRef" = %d WHERE "callId" IN (SELECT "callId" FROM "calls" WHERE "systemId" = %d AND "talkgroupId" = %d LIMIT %d)`,
+		reassignment.ToSystemId, reassignment.ToTalkgroupId, reassignment.ToSystemRef, reassignment.ToTalkgroupRef, reassignment.FromSystemId, reassignment.FromTalkgroupId, limit)
+}
+
+// relatedQueries repoints the rows that copy the talkgroup of a call, so they stay consistent with it
+func (reassignment *callReassignment) relatedQueries() []string {
+	return []string{
+		fmt.Sprintf(`UPDATE "alerts" SET "systemId" = %d, "talkgroupId" = %d WHERE "systemId" = %d AND "talkgroupId" = %d`,
+			reassignment.ToSystemId, reassignment.ToTalkgroupId, reassignment.FromSystemId, reassignment.FromTalkgroupId),
+		fmt.Sprintf(`UPDATE "callPatches" SET "talkgroupId" = %d WHERE "talkgroupId" = %d`,
+			reassignment.ToTalkgroupId, reassignment.FromTalkgroupId),
+	}
+}
+
+// execute runs the reassignment within the transaction and returns the number of calls moved
+func (reassignment *callReassignment) execute(tx sqlExecer, batchSize int) (int64, error) {
+	var moved int64
+
+	query := reassignment.batchQuery(batchSize)
+
+	for {
+		res, err := tx.Exec(query)
+		if err != nil {
+			return moved, fmt.Errorf("%s in %s", err, query)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return moved, err
+		}
+
+		moved += n
+
+		if n < int64(batchSize) {
+			break
+		}
+	}
+
+	for _, query := range reassignment.relatedQueries() {
+		if _, err := tx.Exec(query); err != nil {
+			return moved, fmt.Errorf("%s in %s", err, query)
+		}
+	}
+
+	return moved, nil
+}
+
+// ReassignCalls moves all the calls of a talkgroup to another talkgroup, e.g. after fixing a wrong
+// talkgroup ref or merging systems. Either every call is moved or none is.
+func (controller *Controller) ReassignCalls(fromSystemId uint64, fromTalkgroupId uint64, toSystemId uint64, toTalkgroupId uint64) (int64, error) {
+	reassignment, err := planCallReassignment(controller.Systems, fromSystemId, fromTalkgroupId, toSystemId, toTalkgroupId)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := controller.Database.Sql.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	moved, err := reassignment.execute(tx, callReassignBatchSize)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("reassigned %d calls from system %d talkgroup %d to system %d talkgroup %d", moved, fromSystemId, fromTalkgroupId, toSystemId, toTalkgroupId))
+
+	return moved, nil
+}
+
+// CallsReassignHandler moves the calls of a talkgroup to another talkgroup
+func (admin *Admin) CallsReassignHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var request struct {
+		FromSystemId    uint64 `json:"fromSystemId"`
+		FromTalkgroupId uint64 `json:"fromTalkgroupId"`
+		ToSystemId      uint64 `json:"toSystemId"`
+		ToTalkgroupId   uint64 `json:"toTalkgroupId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	moved, err := admin.Controller.ReassignCalls(request.FromSystemId, request.FromTalkgroupId, request.ToSystemId, request.ToTalkgroupId)
+
+	switch {
+	case errors.Is(err, errReassignSameTalkgroup), errors.Is(err, errReassignSourceMissing), errors.Is(err, errReassignSystemMissing), errors.Is(err, errReassignTargetMissing):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	admin.Controller.AuditLog.Record(r, 0, "calls.reassign", fmt.Sprintf("system %d talkgroup %d", request.FromSystemId, request.FromTalkgroupId), "", fmt.Sprintf("moved %d calls to system %d talkgroup %d", moved, request.ToSystemId, request.ToTalkgroupId))
+
+	json.NewEncoder(w).Encode(map[string]any{"moved": moved})
+}
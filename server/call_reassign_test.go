@@ -0,0 +1,125 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+type fakeReassignCall struct {
+	systemId     uint64
+	talkgroupId  uint64
+	systemRef    uint
+	talkgroupRef uint
+}
+
+// fakeReassignStore applies the reassignment queries to in-memory calls
+type fakeReassignStore struct {
+	reassignment *callReassignment
+	batchSize    int
+	calls        []*fakeReassignCall
+	batches      int
+	related      int
+}
+
+func (store *fakeReassignStore) Exec(query string, args ...any) (sql.Result, error) {
+	r := store.reassignment
+
+	if query == r.batchQuery(store.batchSize) {
+		store.batches++
+
+		moved := 0
+		for _, call := range store.calls {
+			if moved == store.batchSize {
+				break
+			}
+			if call.systemId == r.FromSystemId && call.talkgroupId == r.FromTalkgroupId {
+				call.systemId, call.talkgroupId = r.ToSystemId, r.ToTalkgroupId
+				call.systemRef, call.talkgroupRef = r.ToSystemRef, r.ToTalkgroupRef
+				moved++
+			}
+		}
+
+		return fakeResult(moved), nil
+	}
+
+	for _, related := range r.relatedQueries() {
+		if query == related {
+			store.related++
+			return fakeResult(0), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected query %s", query)
+}
+
+func TestReassignCalls(t *testing.T) {
+	systems := NewSystems().FromMap([]any{
+		map[string]any{"id": float64(1), "label": "Old", "systemRef": float64(10), "talkgroups": []any{
+			map[string]any{"id": float64(11), "label": "Wrong", "talkgroupRef": float64(100)},
+		}},
+		map[string]any{"id": float64(2), "label": "New", "systemRef": float64(20), "talkgroups": []any{
+			map[string]any{"id": float64(21), "label": "Right", "talkgroupRef": float64(200)},
+		}},
+	})
+
+	if _, err := planCallReassignment(systems, 1, 11, 1, 11); err != errReassignSameTalkgroup {
+		t.Errorf("expected same talkgroup error, got %v", err)
+	}
+	if _, err := planCallReassignment(systems, 1, 11, 3, 21); err != errReassignSystemMissing {
+		t.Errorf("expected missing system error, got %v", err)
+	}
+	if _, err := planCallReassignment(systems, 1, 11, 1, 21); err != errReassignTargetMissing {
+		t.Errorf("expected the target talkgroup to be looked up in the target system, got %v", err)
+	}
+
+	reassignment, err := planCallReassignment(systems, 1, 11, 2, 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &fakeReassignStore{reassignment: reassignment, batchSize: 3}
+	for i := 0; i < 7; i++ {
+		store.calls = append(store.calls, &fakeReassignCall{systemId: 1, talkgroupId: 11, systemRef: 10, talkgroupRef: 100})
+	}
+	other := &fakeReassignCall{systemId: 1, talkgroupId: 12, systemRef: 10, talkgroupRef: 101}
+	store.calls = append(store.calls, other)
+
+	moved, err := reassignment.execute(store, store.batchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if moved != 7 || store.batches != 3 {
+		t.Errorf("expected 7 calls moved in 3 batches, got %d in %d", moved, store.batches)
+	}
+
+	if store.related != 2 {
+		t.Errorf("expected alerts and patches to be repointed, got %d queries", store.related)
+	}
+
+	for _, call := range store.calls[:7] {
+		if call.systemId != 2 || call.talkgroupId != 21 || call.systemRef != 20 || call.talkgroupRef != 200 {
+			t.Errorf("call not moved consistently: %+v", call)
+		}
+	}
+
+	if other.systemId != 1 || other.talkgroupId != 12 || other.talkgroupRef != 101 {
+		t.Errorf("call of another talkgroup must not move: %+v", other)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+// Capabilities reports which optional subsystems are configured on this server,
+// so clients can hide the features that would not work
+type Capabilities struct {
+	Transcription TranscriptionCapability `json:"transcription"`
+	ToneDetection bool                    `json:"toneDetection"`
+	Push          bool                    `json:"push"`
+	WebPush       string                  `json:"webPush,omitempty"` // the VAPID public key to subscribe with
+	Billing       bool                    `json:"billing"`
+	Email         bool                    `json:"email"`
+	Registration  RegistrationCapability  `json:"registration"`
+	Turnstile     bool                    `json:"turnstile"`
+}
+
+type TranscriptionCapability struct {
+	Enabled   bool   `json:"enabled"`
+	Available bool   `json:"available"`
+	Provider  string `json:"provider,omitempty"`
+}
+
+type RegistrationCapability struct {
+	User   bool `json:"user"`
+	Public bool `json:"public"`
+}
+
+func (controller *Controller) Capabilities() *Capabilities {
+	options := controller.Options
+
+	capabilities := &Capabilities{
+		Push:      options.RelayServerAPIKey != "" || apnsConfigured(options),
+		Billing:   options.StripePaywallEnabled && options.StripeSecretKey != "" && options.StripePublishableKey != "",
+		Email:     options.EmailServiceEnabled,
+		Turnstile: options.TurnstileEnabled && options.TurnstileSiteKey != "",
+		Registration: RegistrationCapability{
+			User:   options.UserRegistrationEnabled,
+			Public: options.PublicRegistrationEnabled,
+		},
+	}
+
+	if webPushConfigured(options) {
+		capabilities.WebPush = options.WebPushVapidPublicKey
+	}
+
+	if options.TranscriptionConfig.Enabled {
+		capabilities.Transcription.Enabled = true
+		capabilities.Transcription.Provider = options.TranscriptionConfig.Provider
+
+		if queue := controller.TranscriptionQueue; queue != nil && queue.provider != nil {
+			capabilities.Transcription.Available = queue.provider.IsAvailable()
+		}
+	}
+
+	if controller.Systems != nil {
+		controller.Systems.mutex.RLock()
+		for _, system := range controller.Systems.List {
+			if system.Talkgroups == nil {
+				continue
+			}
+			for _, talkgroup := range system.Talkgroups.List {
+				if talkgroup.ToneDetectionEnabled {
+					capabilities.ToneDetection = true
+					break
+				}
+			}
+			if capabilities.ToneDetection {
+				break
+			}
+		}
+		controller.Systems.mutex.RUnlock()
+	}
+
+	return capabilities
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeTranscriptionProvider struct {
+	available bool
+}
+
+func (p *fakeTranscriptionProvider) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	return &TranscriptionResult{}, nil
+}
+
+func (p *fakeTranscriptionProvider) IsAvailable() bool { return p.available }
+
+func (p *fakeTranscriptionProvider) GetName() string { return "fake" }
+
+func (p *fakeTranscriptionProvider) GetSupportedLanguages() []string { return []string{"en"} }
+
+func TestCapabilitiesFollowConfiguration(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Systems: NewSystems()}
+
+	capabilities := controller.Capabilities()
+	if capabilities.Push || capabilities.Billing || capabilities.Transcription.Enabled || capabilities.ToneDetection {
+		t.Fatalf("expected nothing enabled by default, got %+v", capabilities)
+	}
+
+	controller.Options.RelayServerAPIKey = "key"
+	if !controller.Capabilities().Push {
+		t.Error("expected push once the relay server key is set")
+	}
+
+	controller.Options.StripePaywallEnabled = true
+	if controller.Capabilities().Billing {
+		t.Error("expected billing to stay off without stripe keys")
+	}
+	controller.Options.StripeSecretKey = "sk"
+	controller.Options.StripePublishableKey = "pk"
+	if !controller.Capabilities().Billing {
+		t.Error("expected billing once stripe is configured")
+	}
+
+	controller.Options.TranscriptionConfig.Enabled = true
+	controller.Options.TranscriptionConfig.Provider = "azure"
+	provider := &fakeTranscriptionProvider{}
+	controller.TranscriptionQueue = &TranscriptionQueue{provider: provider}
+	if c := controller.Capabilities().Transcription; !c.Enabled || c.Available || c.Provider != "azure" {
+		t.Errorf("expected transcription enabled but unavailable, got %+v", c)
+	}
+	provider.available = true
+	if !controller.Capabilities().Transcription.Available {
+		t.Error("expected transcription available once the provider is")
+	}
+
+	talkgroups := NewTalkgroups()
+	talkgroups.List = []*Talkgroup{{Id: 1}}
+	controller.Systems.List = []*System{{Id: 1, Talkgroups: talkgroups}}
+	if controller.Capabilities().ToneDetection {
+		t.Error("expected no tone detection without enabled talkgroups")
+	}
+	talkgroups.List[0].ToneDetectionEnabled = true
+	if !controller.Capabilities().ToneDetection {
+		t.Error("expected tone detection once a talkgroup enables it")
+	}
+}
+
+func TestCapabilitiesHandler(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Systems: NewSystems()}
+	controller.Options.RelayServerAPIKey = "key"
+	api := &Api{Controller: controller}
+
+	w := httptest.NewRecorder()
+	api.CapabilitiesHandler(w, httptest.NewRequest(http.MethodGet, "/api/capabilities", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	capabilities := &Capabilities{}
+	if err := json.Unmarshal(w.Body.Bytes(), capabilities); err != nil {
+		t.Fatal(err)
+	}
+	if !capabilities.Push {
+		t.Error("expected push in the response")
+	}
+}
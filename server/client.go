@@ -249,6 +249,11 @@ func (client *Client) SendConfig(groups *Groups, options *Options, systems *Syst
 		"time12hFormat":      options.Time12hFormat,
 	}
 
+	// Include the listeners of the talkgroups the client can see
+	if options.ShowListenersCount && client.Controller != nil && client.Controller.ListenerCounts != nil {
+		payload["listeners"] = client.Controller.ListenerCounts.Summary(listenerFilter(client.SystemsMap))
+	}
+
 	// Include user settings if user is authenticated
 	if client.User != nil && client.User.Settings != "" {
 		var userSettings map[string]interface{}
@@ -31,6 +31,7 @@ import (
 
 const (
 	COMMAND_ARG            = "cmd"
+	COMMAND_ARG_FORCE      = "+force"
 	COMMAND_ARG_IN         = "+in"
 	COMMAND_ARG_OUT        = "+out"
 	COMMAND_ARG_PASSWORD   = "+password"
@@ -39,9 +40,12 @@ const (
 	COMMAND_ADMIN_PASSWORD = "admin-password"
 	COMMAND_CONFIG_GET     = "config-get"
 	COMMAND_CONFIG_SET     = "config-set"
+	COMMAND_DEMO_REMOVE    = "demo-remove"
+	COMMAND_DEMO_SEED      = "demo-seed"
 	COMMAND_HELP           = "help"
 	COMMAND_LOGIN          = "login"
 	COMMAND_LOGOUT         = "logout"
+	COMMAND_VAPID_KEYS     = "vapid-keys"
 
 	COMMAND_DEF_PASSWORD = "admin"
 	COMMAND_DEF_URL      = "http://localhost:3000/"
@@ -50,6 +54,7 @@ const (
 type Command struct {
 	app       string
 	command   string
+	force     bool
 	in        string
 	out       string
 	password  string
@@ -90,6 +95,9 @@ func (command *Command) Do(action string) {
 
 	for i < len(os.Args) {
 		switch os.Args[i] {
+		case COMMAND_ARG_FORCE:
+			command.force = true
+
 		case COMMAND_ARG_IN:
 			command.in = readVal()
 
@@ -131,6 +139,12 @@ func (command *Command) Do(action string) {
 	case COMMAND_CONFIG_SET:
 		command.configSet()
 
+	case COMMAND_DEMO_REMOVE:
+		command.demoRemove()
+
+	case COMMAND_DEMO_SEED:
+		command.demoSeed()
+
 	case COMMAND_LOGIN:
 		command.login()
 
@@ -140,6 +154,9 @@ func (command *Command) Do(action string) {
 	case COMMAND_ADMIN_PASSWORD:
 		command.adminPassword()
 
+	case COMMAND_VAPID_KEYS:
+		command.vapidKeys()
+
 	default:
 		command.printUsage()
 	}
@@ -164,6 +181,10 @@ func (command *Command) printUsage() {
 	fmt.Printf("    %-11s %s%s -%s %s %s <file.json>\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_CONFIG_GET, COMMAND_ARG_OUT)
 	fmt.Printf("  %-11s – Set server's configuration.\n\n", COMMAND_CONFIG_SET)
 	fmt.Printf("    %-11s %s%s -%s %s %s <file.json>\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_CONFIG_SET, COMMAND_ARG_IN)
+	fmt.Printf("  %-11s – Populate the server with demo data for evaluation.\n\n", COMMAND_DEMO_SEED)
+	fmt.Printf("    %-11s %s%s -%s %s [%s]\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_DEMO_SEED, COMMAND_ARG_FORCE)
+	fmt.Printf("  %-11s – Remove the demo data.\n\n", COMMAND_DEMO_REMOVE)
+	fmt.Printf("    %-11s %s%s -%s %s\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_DEMO_REMOVE)
 	fmt.Printf("  %-11s – Login to server.\n\n", COMMAND_LOGIN)
 	if runtime.GOOS != "windows" {
 		fmt.Printf("    %-11s $ RDIO_ADMIN_PASSWORD=<password> ./%s -%s %s\n", "", command.app, COMMAND_ARG, COMMAND_LOGIN)
@@ -171,6 +192,8 @@ func (command *Command) printUsage() {
 	fmt.Printf("    %-11s %s%s -%s %s %s <password>\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_LOGIN, COMMAND_ARG_PASSWORD)
 	fmt.Printf("  %-11s – Logout from server.\n\n", COMMAND_LOGOUT)
 	fmt.Printf("    %-11s %s%s -%s %s\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_LOGOUT)
+	fmt.Printf("  %-11s – Generate the VAPID keys of the web push options.\n\n", COMMAND_VAPID_KEYS)
+	fmt.Printf("    %-11s %s%s -%s %s\n\n", "", prompt, command.app, COMMAND_ARG, COMMAND_VAPID_KEYS)
 	fmt.Printf("Global Options:\n\n")
 	fmt.Printf("  %-11s – Session token keystore. Default is `.%s.token`.\n", COMMAND_ARG_TOKEN, command.app)
 	fmt.Printf("  %-11s – Server remote address. Default is `%s`.\n\n", COMMAND_ARG_URL, COMMAND_DEF_URL)
@@ -256,6 +279,53 @@ func (command *Command) configSet() {
 	}
 }
 
+func (command *Command) demoSeed() {
+	url := "/api/admin/demo"
+	if command.force {
+		url = url + "?force=true"
+	}
+
+	if res, err := command.submit(http.MethodPost, url, nil, true); err == nil {
+		switch res.StatusCode {
+		case http.StatusOK:
+			if data, err := command.readBody(res.Body); err == nil {
+				switch v := data.(type) {
+				case map[string]any:
+					fmt.Printf("Demo data seeded: %v talkgroups, %v calls.\n", v["talkgroups"], v["calls"])
+				default:
+					command.exitWithError(errors.New("invalid response"))
+				}
+			} else {
+				command.exitWithError(err)
+			}
+		case http.StatusConflict:
+			if data, err := command.readBody(res.Body); err == nil {
+				switch v := data.(type) {
+				case map[string]any:
+					command.exitWithError(fmt.Sprintf("%v", v["error"]))
+				}
+			}
+			command.exitWithError(res.Status)
+		default:
+			command.exitWithError(res.Status)
+		}
+	} else {
+		command.exitWithError(err)
+	}
+}
+
+func (command *Command) demoRemove() {
+	if res, err := command.submit(http.MethodDelete, "/api/admin/demo", nil, true); err == nil {
+		if res.StatusCode == http.StatusOK {
+			fmt.Println("Demo data removed.")
+		} else {
+			command.exitWithError(res.Status)
+		}
+	} else {
+		command.exitWithError(err)
+	}
+}
+
 func (command *Command) login() {
 	if body, err := command.writeBody(map[string]any{"password": command.password}); err == nil {
 		if res, err := command.submit(http.MethodPost, "/api/admin/login", body, false); err == nil {
@@ -309,6 +379,16 @@ func (command *Command) logout() {
 	}
 }
 
+func (command *Command) vapidKeys() {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		command.exitWithError(err)
+	}
+
+	fmt.Printf("webPushVapidPublicKey:  %s\n", publicKey)
+	fmt.Printf("webPushVapidPrivateKey: %s\n", privateKey)
+}
+
 func (c *Command) readBody(body io.ReadCloser) (data any, err error) {
 	err = json.NewDecoder(body).Decode(&data)
 	return data, err
@@ -0,0 +1,242 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressionMiddleware compresses JSON responses of at least minSize bytes with gzip or deflate,
+// according to the Accept-Encoding header of the request. WebSocket upgrades, responses already
+// encoded and non JSON content (audio, html, images) are passed through untouched.
+// A minSize of 0 disables compression.
+func CompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if minSize <= 0 || isWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        minSize,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding returns the preferred supported encoding, gzip over deflate, or an empty string
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		accepted[name] = q > 0
+	}
+
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+func isCompressibleContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "application/json") || strings.Contains(contentType, "+json")
+}
+
+// compressResponseWriter buffers the beginning of the response until it knows
+// whether it is worth compressing, then either streams it compressed or as is.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	buffer      bytes.Buffer
+	compressor  io.WriteCloser
+	decided     bool
+	encoding    string
+	minSize     int
+	passthrough bool
+	status      int
+	wroteHeader bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+
+	// bodyless or already encoded responses are never compressed
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified || cw.Header().Get("Content-Encoding") != "" {
+		cw.startPassthrough()
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.decided {
+		if cw.passthrough {
+			return cw.ResponseWriter.Write(b)
+		}
+		return cw.compressor.Write(b)
+	}
+
+	if cw.Header().Get("Content-Type") == "" {
+		cw.Header().Set("Content-Type", http.DetectContentType(b))
+	}
+
+	if !isCompressibleContentType(cw.Header().Get("Content-Type")) {
+		cw.startPassthrough()
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buffer.Write(b)
+
+	if cw.buffer.Len() >= cw.minSize {
+		if err := cw.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Close flushes whatever is buffered and terminates the compressed stream
+func (cw *compressResponseWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// nothing was written by the handler
+			return nil
+		}
+		cw.startPassthrough()
+	}
+
+	if cw.compressor != nil {
+		return cw.compressor.Close()
+	}
+
+	return nil
+}
+
+// Flush implements http.Flusher, it forces the compression decision
+func (cw *compressResponseWriter) Flush() {
+	if !cw.decided && cw.wroteHeader {
+		if cw.buffer.Len() > 0 && isCompressibleContentType(cw.Header().Get("Content-Type")) {
+			cw.startCompression()
+		} else {
+			cw.startPassthrough()
+		}
+	}
+
+	if f, ok := cw.compressor.(interface{ Flush() error }); ok && cw.compressor != nil {
+		f.Flush()
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker to support WebSocket connections
+func (cw *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (cw *compressResponseWriter) startPassthrough() {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	cw.passthrough = true
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.buffer.Len() > 0 {
+		cw.ResponseWriter.Write(cw.buffer.Bytes())
+		cw.buffer.Reset()
+	}
+}
+
+func (cw *compressResponseWriter) startCompression() error {
+	cw.decided = true
+
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+
+	switch cw.encoding {
+	case "gzip":
+		cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+	default:
+		// the deflate content coding is the zlib format, not raw deflate (RFC 9110 section 8.4.1.2)
+		cw.compressor = zlib.NewWriter(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	_, err := cw.compressor.Write(cw.buffer.Bytes())
+	cw.buffer.Reset()
+
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveCompressed(handler http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	h := SecurityHeadersMiddleware(CompressionMiddleware(1024)(handler))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/calls", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCompressionLargeJson(t *testing.T) {
+	body := `{"calls":[` + strings.Repeat(`{"id":1,"transcript":"engine 5 responding"},`, 100) + `{}]}`
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+
+	w := serveCompressed(handler, "gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("security headers missing on compressed response")
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(gr); string(b) != body {
+		t.Errorf("gzip body mismatch")
+	}
+
+	w = serveCompressed(handler, "gzip;q=0, deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("expected a zlib stream for deflate: %v", err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != body {
+		t.Errorf("deflate body mismatch")
+	}
+
+	w = serveCompressed(handler, "")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+		t.Errorf("response must not be compressed without Accept-Encoding")
+	}
+}
+
+func TestCompressionSkipped(t *testing.T) {
+	small := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}
+
+	w := serveCompressed(small, "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("small response must not be compressed")
+	}
+
+	audio := bytes.Repeat([]byte{0xff, 0xf3}, 4096)
+	mp3 := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(audio)
+	}
+
+	w = serveCompressed(mp3, "gzip")
+	if w.Header().Get("Content-Encoding") != "" || !bytes.Equal(w.Body.Bytes(), audio) {
+		t.Errorf("audio response must not be compressed")
+	}
+
+	wrapped := false
+	ws := func(w http.ResponseWriter, r *http.Request) {
+		_, wrapped = w.(*compressResponseWriter)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Upgrade", "websocket")
+	CompressionMiddleware(1024)(http.HandlerFunc(ws)).ServeHTTP(httptest.NewRecorder(), r)
+	if wrapped {
+		t.Errorf("websocket upgrade must receive the original response writer")
+	}
+}
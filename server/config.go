@@ -24,33 +24,64 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"gopkg.in/ini.v1"
 )
 
 const (
 	DbTypePostgresql string = "postgresql"
+
+	defaultCompressionMinSize  uint = 1024
+	defaultMigrationBackupKeep uint = 5
 )
 
+// stringListFlag is a command line flag given once per value
+type stringListFlag []string
+
+func (list *stringListFlag) String() string {
+	if list == nil {
+		return ""
+	}
+	return strings.Join(*list, " ")
+}
+
+func (list *stringListFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
 type Config struct {
-	BaseDir              string
-	ConfigFile           string
-	DbType               string
-	DbHost               string
-	DbPort               uint
-	DbName               string
-	DbUsername           string
-	DbPassword           string
-	Listen               string
-	SslAutoCert          string
-	SslCaCertFile        string
-	SslCaKeyFile         string
-	SslCertFile          string
-	SslKeyFile           string
-	SslListen            string
-	EnableDebugLog       bool
-	daemon               *Daemon
-	newAdminPassword     string
+	BaseDir                 string
+	CompressionMinSize      uint
+	ConfigFile              string
+	DbType                  string
+	DbHost                  string
+	DbPort                  uint
+	DbName                  string
+	DbUsername              string
+	DbPassword              string
+	Listen                  string
+	LookupCache             bool
+	SslAutoCert             string
+	SslCaCertFile           string
+	SslCaKeyFile            string
+	SslCertFile             string
+	SslKeyFile              string
+	SslListen               string
+	EnableDebugLog          bool
+	AccessCodeUsers         bool
+	LegacyAccessCodes       bool
+	MigrationBackup         bool
+	MigrationBackupRequired bool
+	MigrationBackupDir      string
+	MigrationBackupCommand  string
+	MigrationBackupArgs     []string
+	MigrationBackupKeep     uint
+	MaxDirwatches           uint
+	RateLimitAllowlist      string
+	daemon                  *Daemon
+	newAdminPassword        string
 }
 
 func NewConfig() *Config {
@@ -85,16 +116,28 @@ func NewConfig() *Config {
 		}
 	}
 
+	flag.BoolVar(&config.AccessCodeUsers, "access_code_users", false, "create a user with the access code as pin when converting legacy access codes to user groups")
+	flag.BoolVar(&config.LegacyAccessCodes, "legacy_access_codes", false, "keep the legacy access codes instead of converting them to user groups")
 	flag.StringVar(&config.BaseDir, "base_dir", config.BaseDir, "base directory where all data will be written")
+	flag.UintVar(&config.CompressionMinSize, "compression_min_size", defaultCompressionMinSize, "minimum size in bytes of json responses to compress (0 to disable)")
 	flag.StringVar(&config.DbHost, "db_host", defaultDbHost, "database host ip or hostname")
 	flag.StringVar(&config.DbName, "db_name", "", "database name")
 	flag.StringVar(&config.DbPassword, "db_pass", "", "database password")
 	flag.UintVar(&config.DbPort, "db_port", defaultDbPortPostgreSql, "database host port")
+	flag.UintVar(&config.MaxDirwatches, "dirwatch_max", 0, "maximum number of concurrent dirwatches (0 for unlimited)")
 	flag.StringVar(&config.DbType, "db_type", defaultDbType, "database type (postgresql)")
 	flag.StringVar(&config.DbUsername, "db_user", "", "database user name")
 	flag.StringVar(&config.ConfigFile, "config", defaultConfigFile, "server config file")
 	flag.StringVar(&config.Listen, "listen", defaultListen, "listening address")
+	flag.BoolVar(&config.LookupCache, "lookup_cache", true, "serve system, talkgroup, tag and group lookups from lock-free snapshots")
+	flag.BoolVar(&config.MigrationBackup, "migration_backup", false, "back up the database before running migrations")
+	flag.Var((*stringListFlag)(&config.MigrationBackupArgs), "migration_backup_args", "argument of the backup command, repeated for each argument, {file} is replaced by the backup path (defaults to pg_dump arguments)")
+	flag.StringVar(&config.MigrationBackupCommand, "migration_backup_command", defaultMigrationBackupCommand, "database backup command")
+	flag.StringVar(&config.MigrationBackupDir, "migration_backup_dir", "backups", "directory where the pre-migration backups are written")
+	flag.UintVar(&config.MigrationBackupKeep, "migration_backup_keep", defaultMigrationBackupKeep, "number of pre-migration backups kept, the older ones being removed (0 to keep them all)")
+	flag.BoolVar(&config.MigrationBackupRequired, "migration_backup_required", false, "abort the migrations when the pre-migration backup fails")
 	flag.StringVar(&config.newAdminPassword, "admin_password", "", "change admin password")
+	flag.StringVar(&config.RateLimitAllowlist, "rate_limit_allowlist", "", "comma separated ips and cidr ranges exempt from the rate limits and login blocks, matched against the connection address, never the forwarded headers")
 	flag.StringVar(&config.SslAutoCert, "ssl_auto_cert", "", "domain name for Let's Encrypt automatic certificate")
 	flag.StringVar(&config.SslCertFile, "ssl_cert_file", "", "ssl PEM formated certificate")
 	flag.StringVar(&config.SslKeyFile, "ssl_key_file", "", "ssl PEM formated key")
@@ -121,6 +164,10 @@ func NewConfig() *Config {
 
 	default:
 		if cfg, err := ini.Load(config.GetConfigFilePath()); err == nil {
+			if v, err := cfg.Section("").Key("compression_min_size").Uint(); err == nil {
+				config.CompressionMinSize = v
+			}
+
 			if v := cfg.Section("").Key("db_host").String(); len(v) > 0 {
 				config.DbHost = v
 			}
@@ -145,10 +192,46 @@ func NewConfig() *Config {
 				config.DbUsername = v
 			}
 
+			if v, err := cfg.Section("").Key("dirwatch_max").Uint(); err == nil {
+				config.MaxDirwatches = v
+			}
+
 			if v := cfg.Section("").Key("listen").String(); len(v) > 0 {
 				config.Listen = v
 			}
 
+			if v, err := cfg.Section("").Key("lookup_cache").Bool(); err == nil {
+				config.LookupCache = v
+			}
+
+			if v, err := cfg.Section("").Key("migration_backup").Bool(); err == nil {
+				config.MigrationBackup = v
+			}
+
+			if v := cfg.Section("").Key("migration_backup_args").Strings(","); len(v) > 0 {
+				config.MigrationBackupArgs = v
+			}
+
+			if v := cfg.Section("").Key("migration_backup_command").String(); len(v) > 0 {
+				config.MigrationBackupCommand = v
+			}
+
+			if v := cfg.Section("").Key("migration_backup_dir").String(); len(v) > 0 {
+				config.MigrationBackupDir = v
+			}
+
+			if v, err := cfg.Section("").Key("migration_backup_keep").Uint(); err == nil {
+				config.MigrationBackupKeep = v
+			}
+
+			if v, err := cfg.Section("").Key("migration_backup_required").Bool(); err == nil {
+				config.MigrationBackupRequired = v
+			}
+
+			if v := cfg.Section("").Key("rate_limit_allowlist").String(); len(v) > 0 {
+				config.RateLimitAllowlist = v
+			}
+
 			if v := cfg.Section("").Key("ssl_auto_cert").String(); len(v) > 0 {
 				config.SslAutoCert = v
 			}
@@ -165,6 +248,14 @@ func NewConfig() *Config {
 				config.SslListen = v
 			}
 
+			if v, err := cfg.Section("").Key("access_code_users").Bool(); err == nil {
+				config.AccessCodeUsers = v
+			}
+
+			if v, err := cfg.Section("").Key("legacy_access_codes").Bool(); err == nil {
+				config.LegacyAccessCodes = v
+			}
+
 			// Read enable_debug_log option (defaults to false)
 			if v, err := cfg.Section("").Key("enable_debug_log").Bool(); err == nil {
 				config.EnableDebugLog = v
@@ -233,6 +324,10 @@ func (config *Config) isBaseDirWritable() bool {
 func (config *Config) saveConfig() error {
 	ini := []string{}
 
+	if config.CompressionMinSize != defaultCompressionMinSize {
+		ini = append(ini, fmt.Sprintf("compression_min_size = %d", config.CompressionMinSize))
+	}
+
 	if config.DbHost != "" {
 		ini = append(ini, fmt.Sprintf("db_host = %s", config.DbHost))
 	}
@@ -257,10 +352,50 @@ func (config *Config) saveConfig() error {
 		ini = append(ini, fmt.Sprintf("db_user = %s", config.DbUsername))
 	}
 
+	if config.MaxDirwatches > 0 {
+		ini = append(ini, fmt.Sprintf("dirwatch_max = %d", config.MaxDirwatches))
+	}
+
 	if config.Listen != "" {
 		ini = append(ini, fmt.Sprintf("listen = %s", config.Listen))
 	}
 
+	if !config.LookupCache {
+		ini = append(ini, "lookup_cache = false")
+	}
+
+	if config.MigrationBackup {
+		ini = append(ini, "migration_backup = true")
+	}
+
+	if len(config.MigrationBackupArgs) > 0 {
+		args := make([]string, len(config.MigrationBackupArgs))
+		for i, arg := range config.MigrationBackupArgs {
+			args[i] = strings.NewReplacer(`\`, `\\`, ",", `\,`).Replace(arg)
+		}
+		ini = append(ini, fmt.Sprintf("migration_backup_args = %s", strings.Join(args, ",")))
+	}
+
+	if config.MigrationBackupCommand != "" && config.MigrationBackupCommand != defaultMigrationBackupCommand {
+		ini = append(ini, fmt.Sprintf("migration_backup_command = %s", config.MigrationBackupCommand))
+	}
+
+	if config.MigrationBackupDir != "" && config.MigrationBackupDir != "backups" {
+		ini = append(ini, fmt.Sprintf("migration_backup_dir = %s", config.MigrationBackupDir))
+	}
+
+	if config.MigrationBackupKeep != defaultMigrationBackupKeep {
+		ini = append(ini, fmt.Sprintf("migration_backup_keep = %d", config.MigrationBackupKeep))
+	}
+
+	if config.MigrationBackupRequired {
+		ini = append(ini, "migration_backup_required = true")
+	}
+
+	if config.RateLimitAllowlist != "" {
+		ini = append(ini, fmt.Sprintf("rate_limit_allowlist = %s", config.RateLimitAllowlist))
+	}
+
 	if config.SslAutoCert != "" {
 		ini = append(ini, fmt.Sprintf("ssl_auto_cert = %s", config.SslAutoCert))
 	}
@@ -281,6 +416,14 @@ func (config *Config) saveConfig() error {
 		ini = append(ini, "enable_debug_log = true")
 	}
 
+	if config.AccessCodeUsers {
+		ini = append(ini, "access_code_users = true")
+	}
+
+	if config.LegacyAccessCodes {
+		ini = append(ini, "legacy_access_codes = true")
+	}
+
 	file, err := os.Create(config.GetConfigFilePath())
 	if err != nil {
 		return err
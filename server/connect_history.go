@@ -0,0 +1,133 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// connectHistoryMaxMinutes bounds how far back the calls replayed to a client go, beyond its delay
+	connectHistoryMaxMinutes = 120
+
+	// connectHistoryCallsLimit bounds the calls replayed to a client, whatever the options
+	connectHistoryCallsLimit = 1000
+)
+
+// connectHistoryMinutes returns the minutes of calls replayed to a client turning its livefeed on.
+// The flag of the livefeed message overrides the livefeedBacklogMinutes setting of the user, which
+// overrides the connectHistoryMinutes option.
+func connectHistoryMinutes(options *Options, user *User, flag any) uint {
+	minutes := options.ConnectHistoryMinutes
+
+	if user != nil && user.Settings != "" {
+		var settings map[string]any
+		if err := json.Unmarshal([]byte(user.Settings), &settings); err == nil {
+			if backlog, ok := settings["livefeedBacklogMinutes"].(float64); ok && backlog > 0 {
+				minutes = uint(backlog)
+			}
+		}
+	}
+
+	switch v := flag.(type) {
+	case float64:
+		if v >= 0 {
+			minutes = uint(v)
+		}
+	}
+
+	if minutes > connectHistoryMaxMinutes {
+		return connectHistoryMaxMinutes
+	}
+
+	return minutes
+}
+
+// connectHistoryLimit returns how many calls are replayed at most to a client turning its livefeed on
+func connectHistoryLimit(options *Options) uint {
+	if options.ConnectHistoryMaxCalls == 0 || options.ConnectHistoryMaxCalls > connectHistoryCallsLimit {
+		return connectHistoryCallsLimit
+	}
+	return options.ConnectHistoryMaxCalls
+}
+
+// connectHistoryCutoff returns the time from which calls are replayed. Calls within the delay of the
+// client are always sent, as they haven't been played live yet, and the history goes back from the
+// start of the delay. Without delay nor history there is nothing to replay.
+func connectHistoryCutoff(now time.Time, delay uint, minutes uint) (time.Time, bool) {
+	if delay == 0 && minutes == 0 {
+		return time.Time{}, false
+	}
+
+	return now.Add(-time.Duration(delay+minutes) * time.Minute), true
+}
+
+// loadConnectHistory returns the calls stored since the cutoff, the oldest first
+func (controller *Controller) loadConnectHistory(cutoff time.Time, limit uint) ([]*Call, error) {
+	query := `SELECT c."callId" FROM "calls" AS c WHERE c."timestamp" >= $1 ORDER BY c."timestamp" DESC LIMIT $2`
+
+	rows, err := controller.Database.Sql.Query(query, cutoff.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	callIds := []uint64{}
+	for rows.Next() {
+		var callId uint64
+		if err = rows.Scan(&callId); err == nil {
+			callIds = append(callIds, callId)
+		}
+	}
+
+	calls := []*Call{}
+	for i := len(callIds) - 1; i >= 0; i-- {
+		if call, err := controller.Calls.GetCall(callIds[i]); err == nil && call != nil {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls, nil
+}
+
+// replayConnectHistory sends the calls the client may listen to, in order, before the live calls
+func (controller *Controller) replayConnectHistory(client *Client, calls []*Call) {
+	for _, call := range calls {
+		if controller.requiresUserAuth() {
+			if client.User == nil || !controller.userHasAccess(client.User, call) {
+				continue
+			}
+		}
+
+		if !client.Livefeed.IsEnabled(call) {
+			continue
+		}
+
+		controller.levelCallAudio(call)
+		controller.resolveDisplayConfidence(call)
+
+		msg := &Message{Command: MessageCommandCall, Payload: call}
+		// Use non-blocking send for safety, with small delay to preserve order
+		select {
+		case client.Send <- msg:
+			// Small delay to ensure chronological order is preserved
+			time.Sleep(1 * time.Millisecond)
+		default:
+			// Channel full or client disconnected, skip to avoid blocking
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectHistoryReplay(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	system := &System{Id: 1, SystemRef: 10}
+	fire := &Talkgroup{Id: 1, TalkgroupRef: 100}
+	police := &Talkgroup{Id: 2, TalkgroupRef: 200}
+
+	stored := []*Call{
+		{Id: 1, System: system, Talkgroup: fire, Timestamp: now.Add(-30 * time.Minute)},
+		{Id: 2, System: system, Talkgroup: fire, Timestamp: now.Add(-4 * time.Minute)},
+		{Id: 3, System: system, Talkgroup: police, Timestamp: now.Add(-3 * time.Minute)},
+		{Id: 4, System: system, Talkgroup: fire, Timestamp: now.Add(-1 * time.Minute)},
+	}
+
+	// load mimics the calls query, the most recent calls within the limit, the oldest first
+	load := func(cutoff time.Time, limit uint) ([]*Call, error) {
+		calls := []*Call{}
+		for _, call := range stored {
+			if !call.Timestamp.Before(cutoff) {
+				calls = append(calls, call)
+			}
+		}
+		if len(calls) > int(limit) {
+			calls = calls[len(calls)-int(limit):]
+		}
+		return calls, nil
+	}
+
+	connect := func(controller *Controller, flag any) []uint64 {
+		client := &Client{Livefeed: NewLivefeed(), Send: make(chan *Message, 10)}
+		client.Livefeed.FromMap(map[string]any{"10": map[string]any{"100": true}})
+
+		controller.sendConnectHistory(client, flag, now, load)
+		close(client.Send)
+
+		ids := []uint64{}
+		for msg := range client.Send {
+			ids = append(ids, msg.Payload.(*Call).Id)
+		}
+		return ids
+	}
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+
+	if ids := connect(controller, nil); len(ids) != 0 {
+		t.Errorf("history off: expected no replayed calls, got %v", ids)
+	}
+
+	controller.Options.ConnectHistoryMinutes = 5
+
+	if ids := connect(controller, nil); len(ids) != 2 || ids[0] != 2 || ids[1] != 4 {
+		t.Errorf("history on: expected calls [2 4] of the enabled talkgroup, got %v", ids)
+	}
+
+	// the client may ask for only new calls
+	if ids := connect(controller, float64(0)); len(ids) != 0 {
+		t.Errorf("history off for the client: expected no replayed calls, got %v", ids)
+	}
+
+	// the replay is bounded by count and by age
+	controller.Options.ConnectHistoryMaxCalls = 1
+
+	if ids := connect(controller, float64(24*60)); len(ids) != 1 || ids[0] != 4 {
+		t.Errorf("bounded history: expected the most recent call only, got %v", ids)
+	}
+
+	if minutes := connectHistoryMinutes(controller.Options, nil, float64(24*60)); minutes != connectHistoryMaxMinutes {
+		t.Errorf("expected the history to be capped at %d minutes, got %d", connectHistoryMaxMinutes, minutes)
+	}
+}
@@ -19,7 +19,6 @@ package main
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -41,7 +40,10 @@ type Controller struct {
 	Admin                 *Admin
 	Api                   *Api
 	Apikeys               *Apikeys
+	AudioGains            *AudioGainCache
 	Calls                 *Calls
+	CallRateMonitor       *CallRateMonitor
+	SystemAlertWebhook    *SystemAlertWebhook
 	Clients               *Clients
 	Config                *Config
 	Database              *Database
@@ -50,6 +52,9 @@ type Controller struct {
 	Downstreams           *Downstreams
 	FFMpeg                *FFMpeg
 	Groups                *Groups
+	Incidents             *IncidentGrouper
+	KeywordCooldowns      *KeywordCooldowns
+	ListenerCounts        *ListenerCounts
 	Logs                  *Logs
 	Options               *Options
 	Scheduler             *Scheduler
@@ -57,7 +62,10 @@ type Controller struct {
 	Tags                  *Tags
 	Users                 *Users
 	UserGroups            *UserGroups
+	VocabularyProfiles    *VocabularyProfiles
+	AlertTestWindows      *AlertTestWindows
 	RegistrationCodes     *RegistrationCodes
+	CouponCodes           *CouponCodes
 	TransferRequests      *TransferRequests
 	DeviceTokens          *DeviceTokens
 	EmailService          *EmailService
@@ -66,6 +74,11 @@ type Controller struct {
 	KeywordMatcher        *KeywordMatcher
 	AlertEngine           *AlertEngine
 	HallucinationDetector *HallucinationDetector
+	ToneLearner           *ToneLearner
+	AuditLog              *AuditLog
+	TranscriptionUsage    *TranscriptionUsage
+	WebPush               *WebPushSender
+	APNs                  *APNsSender
 	Register              chan *Client
 	Unregister            chan *Client
 	Ingest                chan *Call
@@ -95,6 +108,8 @@ type Controller struct {
 
 	// Rate limiting
 	RateLimiter         *RateLimiter
+	HeavyRateLimiter    *RateLimiter      // per user, for search and bulk listing endpoints
+	RouteRateLimiter    *RouteRateLimiter // per path prefix, RateLimiter for the other paths
 	LoginAttemptTracker *LoginAttemptTracker
 
 	// Debug logging for tones/keywords
@@ -119,6 +134,8 @@ const (
 )
 
 func NewController(config *Config) *Controller {
+	lookupCacheDisabled.Store(!config.LookupCache)
+
 	controller := &Controller{
 		Clients:           NewClients(),
 		Config:            config,
@@ -140,11 +157,20 @@ func NewController(config *Config) *Controller {
 
 	controller.Admin = NewAdmin(controller)
 	controller.Api = NewApi(controller)
+	controller.AudioGains = NewAudioGainCache(controller.FFMpeg.ApplyGain)
 	controller.Calls = NewCalls(controller)
+	controller.CallRateMonitor = NewCallRateMonitor()
+	controller.SystemAlertWebhook = NewSystemAlertWebhook()
 	controller.Database = NewDatabase(config)
 	controller.Users = NewUsers()
 	controller.UserGroups = NewUserGroups()
+	controller.VocabularyProfiles = NewVocabularyProfiles()
+	controller.AlertTestWindows = NewAlertTestWindows()
+	controller.Incidents = NewIncidentGrouper()
+	controller.KeywordCooldowns = NewKeywordCooldowns()
+	controller.ListenerCounts = NewListenerCounts()
 	controller.RegistrationCodes = NewRegistrationCodes()
+	controller.CouponCodes = NewCouponCodes()
 	controller.TransferRequests = NewTransferRequests()
 	controller.DeviceTokens = NewDeviceTokens()
 	controller.EmailService = NewEmailService(controller)
@@ -171,13 +197,38 @@ func NewController(config *Config) *Controller {
 	controller.KeywordMatcher = NewKeywordMatcher()
 	controller.AlertEngine = NewAlertEngine(controller)
 	controller.HallucinationDetector = NewHallucinationDetector(controller)
+	controller.ToneLearner = NewToneLearner(controller)
+	controller.AuditLog = NewAuditLog(controller)
+	controller.UserGroups.auditLog = controller.AuditLog
+	controller.TranscriptionUsage = NewTranscriptionUsage(controller)
+	controller.WebPush = NewWebPushSender(controller)
+	controller.APNs = NewAPNsSender(controller)
 
 	// Initialize rate limiting
 	// General rate limiter: 1000 requests per minute per IP
 	controller.RateLimiter = NewRateLimiter(1000, 1*time.Minute)
+	// Heavy endpoints rate limiter: transcript search and alert listing per user
+	controller.HeavyRateLimiter = NewRateLimiter(heavyEndpointMaxRequests, 1*time.Minute)
+	// Route rate limiter: stricter limits for the logins, the general one elsewhere
+	controller.RouteRateLimiter = NewRouteRateLimiter(controller.RateLimiter)
+	loginRateLimiter := NewRateLimiter(loginMaxRequests, 1*time.Minute)
+	for _, prefix := range []string{"/api/admin/login", "/api/user/login", "/api/group-admin/login"} {
+		controller.RouteRateLimiter.Add(prefix, loginRateLimiter)
+	}
 	// Login attempt tracker: 6 failed attempts = 15 minute block
 	controller.LoginAttemptTracker = NewLoginAttemptTracker(6, 15*time.Minute)
 
+	// Addresses exempt from the limits above, the heavy endpoints being limited per user
+	if config.RateLimitAllowlist != "" {
+		allowlist, err := NewIPAllowlist(config.RateLimitAllowlist)
+		if err != nil {
+			log.Printf("rate limit allowlist: %v", err)
+		}
+		controller.RateLimiter.SetAllowlist(allowlist)
+		loginRateLimiter.SetAllowlist(allowlist)
+		controller.LoginAttemptTracker.SetAllowlist(allowlist)
+	}
+
 	// Initialize transcription queue (if transcription is enabled in options)
 	// This will be initialized after Options.Read() in Start()
 
@@ -185,6 +236,10 @@ func NewController(config *Config) *Controller {
 }
 
 func (controller *Controller) EmitCall(call *Call) {
+	// Level the audio served to listeners before the goroutines share the call
+	controller.levelCallAudio(call)
+	controller.resolveDisplayConfidence(call)
+
 	// If call is already marked as delayed (system-wide delay),
 	// it's already been processed - just emit it
 	if call.Delayed {
@@ -236,17 +291,11 @@ func (controller *Controller) EmitConfig() {
 func (controller *Controller) IngestCall(call *Call) {
 	var (
 		err         error
-		group       *Group
-		groupId     uint64
-		groupLabel  string
 		id          uint64
 		ok          bool
 		populated   bool
 		system      *System
 		systemId    uint
-		tag         *Tag
-		tagId       uint64
-		tagLabel    string
 		talkgroup   *Talkgroup
 		talkgroupId uint
 	)
@@ -273,6 +322,12 @@ func (controller *Controller) IngestCall(call *Call) {
 		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("controller.ingestcall: %v", err.Error()))
 	}
 
+	if dropped := call.dropInvalidUnitRefs(); len(dropped) > 0 {
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("controller.ingestcall: unit refs %v out of range ignored", dropped))
+	}
+
+	controller.correctAudioMime(call)
+
 	// Get system ID from call (v6 style - simple uint)
 	if call.SystemId > 0 {
 		systemId = call.SystemId
@@ -375,96 +430,10 @@ func (controller *Controller) IngestCall(call *Call) {
 		if system != nil && talkgroup == nil && talkgroupId > 0 {
 			populated = true
 
-			groupLabels := []string{"Unknown"}
-			if len(call.Meta.TalkgroupGroups) > 0 {
-				groupLabels = call.Meta.TalkgroupGroups
-			}
-			groupLabel = groupLabels[0]
-
-			tagLabel = "Untagged"
-			if len(call.Meta.TalkgroupTag) > 0 {
-				tagLabel = call.Meta.TalkgroupTag
-			}
-
-			if group, ok = controller.Groups.GetGroupByLabel(groupLabel); !ok {
-				group = &Group{Label: groupLabel}
-
-				controller.Groups.List = append(controller.Groups.List, group)
-
-				if err = controller.Groups.Write(controller.Database); err != nil {
-					logError(err)
-					return
-				}
-
-				if err = controller.Groups.Read(controller.Database); err != nil {
-					logError(err)
-					return
-				}
-
-				// Sync config to file if enabled
-				controller.SyncConfigToFile()
-
-				if group, ok = controller.Groups.GetGroupByLabel(groupLabel); !ok {
-					logError(fmt.Errorf("unable to get group %s", groupLabel))
-					return
-				}
-			}
-
-			groupId = group.Id
-
-			if tag, ok = controller.Tags.GetTagByLabel(tagLabel); !ok {
-				tag = &Tag{Label: tagLabel}
-
-				controller.Tags.List = append(controller.Tags.List, tag)
-
-				if err = controller.Tags.Write(controller.Database); err != nil {
-					logError(err)
-					return
-				}
-
-				if err = controller.Tags.Read(controller.Database); err != nil {
-					logError(err)
-					return
-				}
-
-				// Sync config to file if enabled
-				controller.SyncConfigToFile()
-
-				if tag, ok = controller.Tags.GetTagByLabel(tagLabel); !ok {
-					logError(fmt.Errorf("unable to get tag %s", tagLabel))
-					return
-				}
-			}
-
-			tagId = tag.Id
-
-			talkgroup = &Talkgroup{
-				GroupIds:     []uint64{groupId},
-				Label:        fmt.Sprintf("%d", talkgroupId),
-				Name:         fmt.Sprintf("%d", talkgroupId),
-				TalkgroupRef: talkgroupId,
-				TagId:        tagId,
-			}
-
-			// Update label and name if provided (v6 style)
-			if len(call.Meta.TalkgroupLabel) > 0 && talkgroup.Label != call.Meta.TalkgroupLabel {
-				populated = true
-				talkgroup.Label = call.Meta.TalkgroupLabel
-			}
-
-			// Set Name: use TalkgroupName if available, otherwise fallback to Label
-			// This fixes SDR Trunk uploads that only send label/tgid but no name
-			if len(call.Meta.TalkgroupName) > 0 {
-				populated = true
-				talkgroup.Name = call.Meta.TalkgroupName
-			} else {
-				// When TalkgroupName is not available, use Label as fallback
-				// instead of leaving it as the talkgroup ID number
-				populated = true
-				talkgroup.Name = talkgroup.Label
+			if talkgroup, err = controller.autoPopulateTalkgroup(system, call, talkgroupId); err != nil {
+				logError(err)
+				return
 			}
-
-			system.Talkgroups.List = append(system.Talkgroups.List, talkgroup)
 		}
 
 		// Handle units (v6 style)
@@ -484,6 +453,10 @@ func (controller *Controller) IngestCall(call *Call) {
 			units := NewUnits()
 			if len(call.Meta.UnitRefs) > 0 {
 				for i, unitRef := range call.Meta.UnitRefs {
+					// the units table holds integer refs, larger ones stay on the call only
+					if !validRef(unitRef, refIntegerMax) {
+						continue
+					}
 					if len(call.Meta.UnitLabels)-1 >= i {
 						if len(call.Meta.UnitLabels[i]) > 0 {
 							units.Add(unitRef, call.Meta.UnitLabels[i])
@@ -639,7 +612,7 @@ func (controller *Controller) IngestCall(call *Call) {
 		return
 	}
 
-	if !controller.Options.DisableDuplicateDetection {
+	if controller.checksDuplicate(call) {
 		if dup, err := controller.Calls.CheckDuplicate(call, controller.Options.DuplicateDetectionTimeFrame, controller.Database); err == nil {
 			if dup {
 				logCall(call, LogLevelWarn, "duplicate call rejected")
@@ -651,6 +624,10 @@ func (controller *Controller) IngestCall(call *Call) {
 		}
 	}
 
+	if err := controller.FFMpeg.Normalize(call, controller.Options.AudioNormalizeSampleRate, controller.Options.AudioNormalizeChannels, controller.Options.AudioNormalizeKeepOriginal); err != nil {
+		controller.Logs.LogEvent(LogLevelWarn, err.Error())
+	}
+
 	if err := controller.FFMpeg.Convert(call, controller.Systems, controller.Tags, controller.Options.AudioConversion); err != nil {
 		controller.Logs.LogEvent(LogLevelWarn, err.Error())
 	}
@@ -687,11 +664,14 @@ func (controller *Controller) IngestCall(call *Call) {
 			}
 		}
 
+		// Link the call to the recent calls of the same incident
+		controller.groupIncident(call)
+
 		// IMMEDIATE: Emit call to clients (users can play NOW - zero delay)
 		controller.EmitCall(call)
 
-		// Check if tone detection is enabled for this talkgroup
-		shouldDetectTones := call.Talkgroup != nil && call.Talkgroup.ToneDetectionEnabled && len(call.Talkgroup.ToneSets) > 0
+		// Check if tone detection is enabled for this talkgroup, or tone sets are learned from its pages
+		shouldDetectTones := call.Talkgroup != nil && ((call.Talkgroup.ToneDetectionEnabled && len(call.Talkgroup.ToneSets) > 0) || call.Talkgroup.learningTones(time.Now()))
 
 		if shouldDetectTones {
 			// SEQUENTIAL: Process tone detection FIRST (fast, 100-500ms typically)
@@ -700,6 +680,9 @@ func (controller *Controller) IngestCall(call *Call) {
 			// 2. Skip transcription if mostly tones (saves API costs)
 			controller.processToneDetection(call)
 
+			// Send it to the downstreams waiting for its tones
+			go controller.Downstreams.SendToned(controller, call)
+
 			// After tone detection, queue transcription with tone-aware decision
 			go controller.queueTranscriptionIfNeeded(call)
 		} else {
@@ -728,11 +711,15 @@ func (controller *Controller) processToneDetection(call *Call) {
 		return
 	}
 
-	if !call.Talkgroup.ToneDetectionEnabled {
+	learning := call.Talkgroup.learningTones(time.Now())
+	detecting := call.Talkgroup.ToneDetectionEnabled && len(call.Talkgroup.ToneSets) > 0
+
+	if !learning && !detecting {
 		return
 	}
 
-	if len(call.Talkgroup.ToneSets) == 0 {
+	if call.audioMissing() {
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("tone detection skipped for call %d, %s", call.Id, callAudioArchivedError))
 		return
 	}
 
@@ -744,7 +731,8 @@ func (controller *Controller) processToneDetection(call *Call) {
 	}
 
 	// Fast tone detection (100-500ms typically)
-	toneSequence, err := controller.ToneDetector.Detect(call.Audio, call.AudioMime, call.Talkgroup.ToneSets)
+	audio, audioMime := call.audioForAnalysis()
+	toneSequence, err := controller.ToneDetector.Detect(audio, audioMime, call.Talkgroup.ToneSets)
 	if err != nil {
 		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("tone detection failed for call %d: %v", call.Id, err))
 		return
@@ -755,6 +743,14 @@ func (controller *Controller) processToneDetection(call *Call) {
 		return
 	}
 
+	if learning {
+		go controller.ToneLearner.Observe(call, toneSequence.measured)
+	}
+
+	if !detecting {
+		return
+	}
+
 	call.ToneSequence = toneSequence
 	call.HasTones = len(toneSequence.Tones) > 0
 
@@ -1914,7 +1910,7 @@ func (controller *Controller) queueTranscriptionIfNeeded(call *Call) {
 			}
 
 			// Both duration and alert checks passed, queue transcription
-			controller.queueTranscriptionJobIfNeeded(call, priority, localReasons)
+			controller.queueTranscriptionJobIfNeeded(call, priority, localReasons, audioDuration)
 		}()
 		return // Exit early, goroutine will handle queueing
 	}
@@ -1940,24 +1936,22 @@ func (controller *Controller) queueTranscriptionIfNeeded(call *Call) {
 	}
 
 	if needsTranscription {
-		controller.queueTranscriptionJobIfNeeded(call, priority, reasons)
+		controller.queueTranscriptionJobIfNeeded(call, priority, reasons, 0)
 	}
 }
 
 // queueTranscriptionJobIfNeeded is a helper to queue a transcription job
 // Extracted to allow async duration checking without duplicating queue logic
-func (controller *Controller) queueTranscriptionJobIfNeeded(call *Call, priority int, reasons []string) {
+// duration is the one measured by the duration check, 0 when it didn't run
+func (controller *Controller) queueTranscriptionJobIfNeeded(call *Call, priority int, reasons []string, duration float64) {
 	queue := controller.TranscriptionQueue
 	if queue != nil {
-		queue.QueueJob(TranscriptionJob{
-			CallId:      call.Id,
-			Audio:       call.Audio,
-			AudioMime:   call.AudioMime,
-			SystemId:    call.System.Id,
-			TalkgroupId: call.Talkgroup.Id,
-			Priority:    priority,
-			Reasons:     reasons,
-		})
+		if err := queue.Submit(call, priority, reasons, duration); err == errRequeueAudioMissing {
+			controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription skipped for call %d, %s", call.Id, callAudioArchivedError))
+			controller.markTranscriptionArchived(call.Id)
+		} else if err != nil && err != errTranscriptionQueueFull {
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("cannot queue transcription of call %d: %v", call.Id, err))
+		}
 	} else {
 		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription queue became unavailable while processing call %d", call.Id))
 	}
@@ -2102,12 +2096,25 @@ func (controller *Controller) ProcessMessageCommandCall(client *Client, message
 		}
 	}
 
-	msg := &Message{Command: MessageCommandCall, Payload: call, Flag: message.Flag}
+	controller.sendPlaybackCall(client, call, message.Flag)
+	return nil
+}
+
+// sendPlaybackCall sends a call requested for playback to the client, or an error when its audio was archived
+func (controller *Controller) sendPlaybackCall(client *Client, call *Call, flag any) {
+	msg := &Message{Command: MessageCommandError, Payload: fmt.Sprintf("call %d %s", call.Id, callAudioArchivedError)}
+
+	if !call.audioMissing() {
+		controller.levelCallAudio(call)
+		controller.resolveDisplayConfidence(call)
+
+		msg = &Message{Command: MessageCommandCall, Payload: call, Flag: flag}
+	}
+
 	select {
 	case client.Send <- msg:
 	default:
 	}
-	return nil
 }
 
 func (controller *Controller) ProcessMessageCommandListCall(client *Client, message *Message) error {
@@ -2128,21 +2135,30 @@ func (controller *Controller) ProcessMessageCommandListCall(client *Client, mess
 }
 
 func (controller *Controller) ProcessMessageCommandLivefeedMap(client *Client, message *Message) {
+	wasOff := client.Livefeed.IsAllOff()
+
 	client.Livefeed.FromMap(message.Payload)
+	controller.ListenerCounts.Set(client, client.Livefeed.Selection())
+
 	msg := &Message{Command: MessageCommandLivefeedMap, Payload: !client.Livefeed.IsAllOff()}
 	select {
 	case client.Send <- msg:
 	default:
 	}
 
-	// Send available calls to newly connected clients (as if there was no delay)
-	if !client.Livefeed.IsAllOff() {
-		go controller.sendAvailableCallsToClient(client)
+	// Send the recent calls to clients turning their livefeed on, not on each talkgroup toggled
+	if wasOff && !client.Livefeed.IsAllOff() {
+		go controller.sendAvailableCallsToClient(client, message.Flag)
 	}
 }
 
-// sendAvailableCallsToClient sends calls that are currently available to a newly connected client
-func (controller *Controller) sendAvailableCallsToClient(client *Client) {
+// sendAvailableCallsToClient sends the calls of the delay window and of the connect history to a
+// client turning its livefeed on. The flag of the livefeed message may override the history minutes.
+func (controller *Controller) sendAvailableCallsToClient(client *Client, flag any) {
+	controller.sendConnectHistory(client, flag, time.Now(), controller.loadConnectHistory)
+}
+
+func (controller *Controller) sendConnectHistory(client *Client, flag any, now time.Time, load func(cutoff time.Time, limit uint) ([]*Call, error)) {
 	if controller.requiresUserAuth() && client.User == nil {
 		return
 	}
@@ -2163,91 +2179,19 @@ func (controller *Controller) sendAvailableCallsToClient(client *Client) {
 		}
 	}
 
-	// Get backlog setting from user preferences (available to all users)
-	var backlogMinutes uint = 0
-	if client.User != nil && client.User.Settings != "" {
-		var settings map[string]any
-		if err := json.Unmarshal([]byte(client.User.Settings), &settings); err == nil {
-			if backlog, ok := settings["livefeedBacklogMinutes"].(float64); ok {
-				backlogMinutes = uint(backlog)
-			}
-		}
-	}
-
-	var cutoffTime time.Time
-	if defaultDelay == 0 {
-		// No delay - if backlog is requested, use it; otherwise return early
-		if backlogMinutes == 0 {
-			// No backlog requested - return early, only send new calls going forward
-			return
-		}
-		// User requested backlog - use it as cutoff time from current time
-		cutoffTime = time.Now().Add(-time.Duration(backlogMinutes) * time.Minute)
-	} else {
-		// User has a delay - backlog is measured from the start of the delay period
-		// Example: 5 min delay, 2 min backlog, current time 11:00 PM
-		// Delay period starts at 10:55 PM, backlog goes back 2 min from there = 10:53 PM
-		// System/talkgroup delays are always respected and can't be overridden
-		delayStartTime := time.Now().Add(-time.Duration(defaultDelay) * time.Minute)
-
-		if backlogMinutes > 0 {
-			// Subtract backlog from the delay start time
-			cutoffTime = delayStartTime.Add(-time.Duration(backlogMinutes) * time.Minute)
-		} else {
-			// No backlog setting - start from delay period start only
-			cutoffTime = delayStartTime
-		}
+	cutoffTime, ok := connectHistoryCutoff(now, defaultDelay, connectHistoryMinutes(controller.Options, client.User, flag))
+	if !ok {
+		// No delay and no history - only send new calls going forward
+		return
 	}
 
-	var (
-		rows *sql.Rows
-		err  error
-	)
-
-	query := `SELECT c."callId", c."timestamp" FROM "calls" AS c WHERE c."timestamp" >= $1 ORDER BY c."timestamp" ASC LIMIT 1000`
-
-	if rows, err = controller.Database.Sql.Query(query, cutoffTime.UnixMilli()); err != nil {
+	calls, err := load(cutoffTime, connectHistoryLimit(controller.Options))
+	if err != nil {
 		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("sendAvailableCallsToClient query failed: %v", err))
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var (
-			callId    uint64
-			timestamp int64
-		)
-
-		if err = rows.Scan(&callId, &timestamp); err != nil {
-			continue
-		}
 
-		call, err := controller.Calls.GetCall(callId)
-		if err != nil || call == nil {
-			continue
-		}
-
-		if controller.requiresUserAuth() {
-			if client.User == nil || !controller.userHasAccess(client.User, call) {
-				continue
-			}
-		}
-
-		// For delayed feed catchup: send all calls from the delayed window
-		// The cutoff time was already calculated based on user's delay
-		// So all calls in the query result should be sent (no additional delay checks)
-		if client.Livefeed.IsEnabled(call) {
-			msg := &Message{Command: MessageCommandCall, Payload: call}
-			// Use non-blocking send for safety, with small delay to preserve order
-			select {
-			case client.Send <- msg:
-				// Small delay to ensure chronological order is preserved
-				time.Sleep(1 * time.Millisecond)
-			default:
-				// Channel full or client disconnected, skip to avoid blocking
-			}
-		}
-	}
+	controller.replayConnectHistory(client, calls)
 }
 
 func (controller *Controller) requiresUserAuth() bool {
@@ -2448,6 +2392,9 @@ func (controller *Controller) Start() error {
 	if err := controller.Delayer.Start(); err != nil {
 		return err
 	}
+	if err := controller.Downstreams.Start(); err != nil {
+		return err
+	}
 	if err := controller.Scheduler.Start(); err != nil {
 		return err
 	}
@@ -2522,6 +2469,7 @@ func (controller *Controller) Start() error {
 
 			case client := <-controller.Unregister:
 				controller.Clients.Remove(client)
+				controller.ListenerCounts.Remove(client)
 				emitClientsCount()
 
 			case <-ctx.Done():
@@ -2557,7 +2505,7 @@ func (controller *Controller) RestartTranscriptionQueue() {
 func (controller *Controller) readAllData() error {
 	// Read all data in parallel for better performance
 	var wg sync.WaitGroup
-	errChan := make(chan error, 10)
+	errChan := make(chan error, 15)
 
 	readFunc := func(fn func() error, name string) {
 		defer wg.Done()
@@ -2566,7 +2514,7 @@ func (controller *Controller) readAllData() error {
 		}
 	}
 
-	wg.Add(12)
+	wg.Add(15)
 	go readFunc(func() error { return controller.Apikeys.Read(controller.Database) }, "apikeys")
 	go readFunc(func() error { return controller.Dirwatches.Read(controller.Database) }, "dirwatches")
 	go readFunc(func() error { return controller.Downstreams.Read(controller.Database) }, "downstreams")
@@ -2579,8 +2527,11 @@ func (controller *Controller) readAllData() error {
 	go readFunc(func() error { return controller.Users.Read(controller.Database) }, "users")
 	go readFunc(func() error { return controller.UserGroups.Load(controller.Database) }, "userGroups")
 	go readFunc(func() error { return controller.RegistrationCodes.Load(controller.Database) }, "registrationCodes")
+	go readFunc(func() error { return controller.CouponCodes.Load(controller.Database) }, "couponCodes")
 	go readFunc(func() error { return controller.TransferRequests.Load(controller.Database) }, "transferRequests")
 	go readFunc(func() error { return controller.DeviceTokens.Load(controller.Database) }, "deviceTokens")
+	go readFunc(func() error { return controller.VocabularyProfiles.Load(controller.Database) }, "vocabularyProfiles")
+	go readFunc(func() error { return controller.AlertTestWindows.Load(controller.Database) }, "alertTestWindows")
 
 	// Wait for all reads to complete
 	wg.Wait()
@@ -2632,24 +2583,15 @@ func (controller *Controller) userHasAccess(user *User, call *Call) bool {
 
 // Helper method to get effective delay for a user (uses group settings if available)
 func (controller *Controller) userEffectiveDelay(user *User, call *Call, defaultDelay uint) uint {
-	if user == nil || call == nil || call.System == nil || call.Talkgroup == nil {
-		return defaultDelay
-	}
+	return ResolveDelay(user, controller.userGroup(user), call, defaultDelay)
+}
 
-	// Check group delays first if user has a group
-	if user.UserGroupId > 0 {
-		group := controller.UserGroups.Get(user.UserGroupId)
-		if group != nil {
-			groupDelay := group.EffectiveDelay(call, defaultDelay)
-			// If group has a delay, use it (group settings override user settings)
-			if groupDelay != defaultDelay || group.Delay > 0 || len(group.systemDelaysMap) > 0 || len(group.talkgroupDelaysMap) > 0 {
-				return groupDelay
-			}
-		}
+// userGroup returns the group of the user, nil when the user has none
+func (controller *Controller) userGroup(user *User) *UserGroup {
+	if user == nil || user.UserGroupId == 0 || controller.UserGroups == nil {
+		return nil
 	}
-
-	// Fall back to user-level delays
-	return user.EffectiveDelay(call, defaultDelay)
+	return controller.UserGroups.Get(user.UserGroupId)
 }
 
 // Helper method to get effective connection limit for a user (uses group settings if available)
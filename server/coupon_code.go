@@ -0,0 +1,330 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// couponCodeRegexp matches the promo codes the users type at checkout
+var couponCodeRegexp = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// CouponCode is a promo code of the paid groups, applying a Stripe coupon to the checkout
+type CouponCode struct {
+	Id             uint64 `json:"id"`
+	Code           string `json:"code"` // upper case, matched case insensitively
+	StripeCouponId string `json:"stripeCouponId"`
+	MaxRedemptions int    `json:"maxRedemptions"` // 0 = unlimited
+	Redemptions    int    `json:"redemptions"`
+	ExpiresAt      int64  `json:"expiresAt"` // unix seconds, 0 = never
+	CreatedAt      int64  `json:"createdAt"`
+}
+
+type CouponCodes struct {
+	mutex sync.RWMutex
+	codes map[string]*CouponCode
+}
+
+func NewCouponCodes() *CouponCodes {
+	return &CouponCodes{
+		codes: make(map[string]*CouponCode),
+	}
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (ccs *CouponCodes) Load(db *Database) error {
+	ccs.mutex.Lock()
+	defer ccs.mutex.Unlock()
+
+	rows, err := db.Sql.Query(`SELECT "couponCodeId", "code", "stripeCouponId", "maxRedemptions", "redemptions", "expiresAt", "createdAt" FROM "couponCodes"`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ccs.codes = make(map[string]*CouponCode)
+
+	for rows.Next() {
+		coupon := &CouponCode{}
+		if err := rows.Scan(&coupon.Id, &coupon.Code, &coupon.StripeCouponId, &coupon.MaxRedemptions, &coupon.Redemptions, &coupon.ExpiresAt, &coupon.CreatedAt); err != nil {
+			log.Printf("Error loading coupon code: %v", err)
+			continue
+		}
+
+		ccs.codes[normalizeCouponCode(coupon.Code)] = coupon
+	}
+
+	return rows.Err()
+}
+
+func (ccs *CouponCodes) GetByCode(code string) *CouponCode {
+	ccs.mutex.RLock()
+	defer ccs.mutex.RUnlock()
+	return ccs.codes[normalizeCouponCode(code)]
+}
+
+func (ccs *CouponCodes) Get(id uint64) *CouponCode {
+	ccs.mutex.RLock()
+	defer ccs.mutex.RUnlock()
+	for _, coupon := range ccs.codes {
+		if coupon.Id == id {
+			return coupon
+		}
+	}
+	return nil
+}
+
+func (ccs *CouponCodes) GetAll() []*CouponCode {
+	ccs.mutex.RLock()
+	defer ccs.mutex.RUnlock()
+	codes := make([]*CouponCode, 0, len(ccs.codes))
+	for _, coupon := range ccs.codes {
+		codes = append(codes, coupon)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// ValidateCoupon returns the coupon of the code typed by a user, unless it expired or was redeemed its maximum times
+func (ccs *CouponCodes) ValidateCoupon(code string) (*CouponCode, error) {
+	coupon := ccs.GetByCode(code)
+	if coupon == nil {
+		return nil, fmt.Errorf("invalid coupon code")
+	}
+
+	if coupon.ExpiresAt > 0 && time.Now().Unix() > coupon.ExpiresAt {
+		return nil, fmt.Errorf("coupon code has expired")
+	}
+
+	if coupon.MaxRedemptions > 0 && coupon.Redemptions >= coupon.MaxRedemptions {
+		return nil, fmt.Errorf("coupon code has reached maximum redemptions")
+	}
+
+	return coupon, nil
+}
+
+// Redeem counts a redemption of the code, refused once the maximum is reached even by concurrent checkouts
+func (ccs *CouponCodes) Redeem(code string, db *Database) error {
+	coupon, err := ccs.ValidateCoupon(code)
+	if err != nil {
+		return err
+	}
+
+	var redemptions int
+	err = db.Sql.QueryRow(
+		`UPDATE "couponCodes" SET "redemptions" = "redemptions" + 1 WHERE "couponCodeId" = $1 AND ("maxRedemptions" = 0 OR "redemptions" < "maxRedemptions") RETURNING "redemptions"`,
+		coupon.Id,
+	).Scan(&redemptions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("coupon code has reached maximum redemptions")
+	} else if err != nil {
+		return err
+	}
+
+	redeemed := *coupon
+	redeemed.Redemptions = redemptions
+
+	ccs.mutex.Lock()
+	ccs.codes[normalizeCouponCode(coupon.Code)] = &redeemed
+	ccs.mutex.Unlock()
+
+	return nil
+}
+
+// validateCouponCode checks the coupon an admin adds
+func validateCouponCode(coupon *CouponCode) error {
+	if !couponCodeRegexp.MatchString(coupon.Code) {
+		return fmt.Errorf("coupon code must be 3 to 32 letters, digits, dashes or underscores")
+	}
+
+	if strings.TrimSpace(coupon.StripeCouponId) == "" {
+		return fmt.Errorf("stripe coupon id is required")
+	}
+
+	if coupon.MaxRedemptions < 0 {
+		return fmt.Errorf("max redemptions can't be negative")
+	}
+
+	return nil
+}
+
+func (ccs *CouponCodes) Add(coupon *CouponCode, db *Database) error {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	coupon.StripeCouponId = strings.TrimSpace(coupon.StripeCouponId)
+
+	if err := validateCouponCode(coupon); err != nil {
+		return err
+	}
+
+	if coupon.CreatedAt == 0 {
+		coupon.CreatedAt = time.Now().Unix()
+	}
+
+	var id int64
+	err := db.Sql.QueryRow(
+		`INSERT INTO "couponCodes" ("code", "stripeCouponId", "maxRedemptions", "redemptions", "expiresAt", "createdAt") VALUES ($1, $2, $3, $4, $5, $6) RETURNING "couponCodeId"`,
+		coupon.Code, coupon.StripeCouponId, coupon.MaxRedemptions, coupon.Redemptions, coupon.ExpiresAt, coupon.CreatedAt,
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("coupon code %s already exists", coupon.Code)
+	} else if err != nil {
+		return err
+	}
+
+	coupon.Id = uint64(id)
+
+	ccs.mutex.Lock()
+	ccs.codes[coupon.Code] = coupon
+	ccs.mutex.Unlock()
+
+	return nil
+}
+
+func (ccs *CouponCodes) Delete(id uint64, db *Database) error {
+	ccs.mutex.Lock()
+	defer ccs.mutex.Unlock()
+
+	if _, err := db.Sql.Exec(`DELETE FROM "couponCodes" WHERE "couponCodeId" = $1`, id); err != nil {
+		return err
+	}
+
+	for code, coupon := range ccs.codes {
+		if coupon.Id == id {
+			delete(ccs.codes, code)
+			break
+		}
+	}
+
+	return nil
+}
+
+// CheckoutCoupon returns the Stripe coupon of the checkout of the pricing option, the one of the code
+// typed by the user when any, otherwise the one of the pricing option
+func (ccs *CouponCodes) CheckoutCoupon(option PricingOption, code string) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return option.CouponId, nil
+	}
+
+	coupon, err := ccs.ValidateCoupon(code)
+	if err != nil {
+		return "", err
+	}
+
+	return coupon.StripeCouponId, nil
+}
+
+// CouponCodesHandler lists, adds and deletes the coupon codes of the paid groups
+func (admin *Admin) CouponCodesHandler(w http.ResponseWriter, r *http.Request) {
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	coupons := admin.Controller.CouponCodes
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(coupons.GetAll())
+
+	case http.MethodPost:
+		coupon := &CouponCode{}
+		if err := json.NewDecoder(r.Body).Decode(coupon); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+			return
+		}
+
+		coupon.Id = 0
+		coupon.Redemptions = 0
+		coupon.CreatedAt = 0
+		if err := coupons.Add(coupon, admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		admin.Controller.AuditLog.Record(r, 0, "coupon.add", "coupon "+coupon.Code, "", fmt.Sprintf("stripeCouponId=%q maxRedemptions=%d expiresAt=%d", coupon.StripeCouponId, coupon.MaxRedemptions, coupon.ExpiresAt))
+
+		json.NewEncoder(w).Encode(coupon)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		coupon := coupons.Get(id)
+		if err != nil || coupon == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "coupon code not found"})
+			return
+		}
+
+		if err := coupons.Delete(id, admin.Controller.Database); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		admin.Controller.AuditLog.Record(r, 0, "coupon.delete", "coupon "+coupon.Code, "", "")
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ValidateCouponHandler checks the coupon code a user types before the checkout
+func (api *Api) ValidateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		api.exitWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	coupon, err := api.Controller.CouponCodes.ValidateCoupon(request.Code)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"valid": true, "code": coupon.Code})
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestCouponCodes(coupons ...*CouponCode) *CouponCodes {
+	ccs := NewCouponCodes()
+	for _, coupon := range coupons {
+		ccs.codes[coupon.Code] = coupon
+	}
+	return ccs
+}
+
+func TestValidateCoupon(t *testing.T) {
+	now := time.Now().Unix()
+
+	ccs := newTestCouponCodes(
+		&CouponCode{Id: 1, Code: "SPRING25", StripeCouponId: "spring"},
+		&CouponCode{Id: 2, Code: "EXPIRED", StripeCouponId: "old", ExpiresAt: now - 60},
+		&CouponCode{Id: 3, Code: "FIRST10", StripeCouponId: "first", MaxRedemptions: 10, Redemptions: 10},
+		&CouponCode{Id: 4, Code: "LATER", StripeCouponId: "later", ExpiresAt: now + 3600, MaxRedemptions: 5, Redemptions: 4},
+	)
+
+	for code, valid := range map[string]bool{
+		" spring25 ": true,
+		"EXPIRED":    false,
+		"FIRST10":    false,
+		"LATER":      true,
+		"UNKNOWN":    false,
+	} {
+		if _, err := ccs.ValidateCoupon(code); (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got %v", code, valid, err)
+		}
+	}
+}
+
+func TestRedeemCoupon(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	ccs := newTestCouponCodes(&CouponCode{Id: 4, Code: "LATER", StripeCouponId: "later", MaxRedemptions: 5, Redemptions: 4})
+
+	d.rows = [][]driver.Value{{int64(5)}}
+	if err := ccs.Redeem("later", db); err != nil {
+		t.Fatal(err)
+	}
+
+	if coupon := ccs.GetByCode("LATER"); coupon.Redemptions != 5 {
+		t.Errorf("expected 5 redemptions, got %d", coupon.Redemptions)
+	}
+
+	if _, err := ccs.ValidateCoupon("LATER"); err == nil {
+		t.Error("expected the coupon redeemed its maximum times refused")
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.queries) != 1 || !strings.Contains(d.queries[0], `"redemptions" < "maxRedemptions"`) {
+		t.Errorf("expected the redemption guarded by the maximum, got %v", d.queries)
+	}
+}
+
+func TestRedeemCouponExhaustedConcurrently(t *testing.T) {
+	db, _ := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	ccs := newTestCouponCodes(&CouponCode{Id: 4, Code: "LATER", StripeCouponId: "later", MaxRedemptions: 5, Redemptions: 4})
+
+	// another checkout redeemed the last one, the update matches no row
+	if err := ccs.Redeem("LATER", db); err == nil || !strings.Contains(err.Error(), "maximum redemptions") {
+		t.Errorf("expected the redemption refused, got %v", err)
+	}
+}
+
+func TestAddCouponValidation(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	ccs := NewCouponCodes()
+
+	for _, coupon := range []*CouponCode{
+		{Code: "no spaces", StripeCouponId: "spring"},
+		{Code: "AB", StripeCouponId: "spring"},
+		{Code: "SPRING25"},
+		{Code: "SPRING25", StripeCouponId: "spring", MaxRedemptions: -1},
+	} {
+		if err := ccs.Add(coupon, db); err == nil {
+			t.Errorf("expected %+v refused", coupon)
+		}
+	}
+
+	d.rows = [][]driver.Value{{int64(7)}}
+	coupon := &CouponCode{Code: "spring-25", StripeCouponId: " spring "}
+	if err := ccs.Add(coupon, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if coupon.Id != 7 || coupon.Code != "SPRING-25" || coupon.StripeCouponId != "spring" || ccs.GetByCode("Spring-25") != coupon {
+		t.Errorf("unexpected coupon %+v", coupon)
+	}
+}
+
+func TestCheckoutCoupon(t *testing.T) {
+	ccs := newTestCouponCodes(&CouponCode{Id: 1, Code: "SPRING25", StripeCouponId: "spring"})
+	option := PricingOption{PriceId: "price_1", Label: "Monthly", Amount: "$10/month", CouponId: "launch"}
+
+	for code, expected := range map[string]string{"": "launch", "spring25": "spring"} {
+		if coupon, err := ccs.CheckoutCoupon(option, code); err != nil || coupon != expected {
+			t.Errorf("%q: expected %s, got %s %v", code, expected, coupon, err)
+		}
+	}
+
+	if _, err := ccs.CheckoutCoupon(option, "UNKNOWN"); err == nil {
+		t.Error("expected an invalid code refused rather than ignored")
+	}
+}
+
+func TestPricingOptionUpdateKeepsCoupon(t *testing.T) {
+	current := []PricingOption{{PriceId: "price_1", CouponId: "launch"}, {PriceId: "price_2", CouponId: "yearly"}}
+
+	var updates []PricingOptionUpdate
+	if err := json.Unmarshal([]byte(`[{"priceId":"price_1","label":"Monthly"},{"priceId":"price_2","couponId":""},{"priceId":"price_3","couponId":" new "}]`), &updates); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []string{"launch", "", "new"} {
+		if option := updates[i].Merge(current); option.CouponId != expected {
+			t.Errorf("option %d: expected coupon %q, got %q", i, expected, option.CouponId)
+		}
+	}
+
+	if option := updates[0].Merge(current); option.Label != "Monthly" {
+		t.Errorf("expected the other fields of the update, got %+v", option)
+	}
+}
+
+func TestCouponCodesHandler(t *testing.T) {
+	controller, d := newAuditTestController(t)
+	controller.CouponCodes = newTestCouponCodes(&CouponCode{Id: 1, Code: "SPRING25", StripeCouponId: "spring"})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{ID: "test"}).SignedString([]byte(controller.Options.secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.Admin.Tokens = append(controller.Admin.Tokens, token)
+
+	serve := func(method string, target string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.Admin.CouponCodesHandler(w, r)
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/api/admin/coupon-codes", `{"code":"no spaces","stripeCouponId":"x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid code refused, got %d", w.Code)
+	}
+
+	d.rows = [][]driver.Value{{int64(2)}}
+	if w := serve(http.MethodPost, "/api/admin/coupon-codes", `{"code":"fall10","stripeCouponId":"fall","maxRedemptions":10,"redemptions":99}`); w.Code != http.StatusOK {
+		t.Fatalf("expected the coupon added, got %d %s", w.Code, w.Body.String())
+	}
+	if coupon := controller.CouponCodes.GetByCode("FALL10"); coupon == nil || coupon.Id != 2 || coupon.Redemptions != 0 {
+		t.Errorf("unexpected coupon %+v", coupon)
+	}
+
+	w := serve(http.MethodGet, "/api/admin/coupon-codes", "")
+	coupons := []*CouponCode{}
+	if err := json.Unmarshal(w.Body.Bytes(), &coupons); err != nil || len(coupons) != 2 || coupons[0].Code != "FALL10" {
+		t.Errorf("unexpected coupons %s", w.Body.String())
+	}
+
+	if w := serve(http.MethodDelete, "/api/admin/coupon-codes?id=9", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown coupon not found, got %d", w.Code)
+	}
+	if w := serve(http.MethodDelete, "/api/admin/coupon-codes?id=1", ""); w.Code != http.StatusOK || controller.CouponCodes.GetByCode("SPRING25") != nil {
+		t.Errorf("expected the coupon deleted, got %d", w.Code)
+	}
+}
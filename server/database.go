@@ -31,6 +31,9 @@ import (
 type Database struct {
 	Config *Config
 	Sql    *sql.DB
+
+	// migrated records the migrations which ran successfully at this start
+	migrated map[string]bool
 }
 
 func NewDatabase(config *Config) *Database {
@@ -62,7 +65,7 @@ func NewDatabase(config *Config) *Database {
 
 	log.Printf("Database connection pool configured: %d max connections for %d CPU cores", maxConns, runtime.NumCPU())
 
-	if err = database.migrate(); err != nil {
+	if err = database.migrateWithBackup(execMigrationBackup, database.migrate); err != nil {
 		log.Printf("FATAL: Database migration failed: %v", err)
 		log.Printf("The database schema must be up to date for the server to run. Please fix the migration error and try again.")
 		os.Exit(1)
@@ -80,6 +83,93 @@ func NewDatabase(config *Config) *Database {
 	return database
 }
 
+// markers recorded in rdioScannerMeta by the migrations run once
+const (
+	migrationOptimizeSearchPerformance = "20250101000000-optimize-search-performance"
+	migrationCallUnitsIndex            = "20250127000000-callunits-callid-index"
+	migrationTagsGroupsUniqueLabels    = "20251215000000-tags-groups-unique-labels"
+	migrationRemoveAlertTones          = "20251219000000-remove-alert-tones"
+	migrationRemoveLedColors           = "20251219000001-remove-led-colors"
+	migrationFixUserTimestamps         = "20251228000000-fix-user-timestamps"
+)
+
+// databaseMigration is a step of the database migration, run in order by Database.migrate
+type databaseMigration struct {
+	name        string
+	description string
+	// marker is the name recorded in rdioScannerMeta by the migrations run once, empty for the
+	// idempotent ones checking the schema at every start
+	marker string
+	// legacy lists the tables the migration converts then drops, the migration being pending while they exist
+	legacy []string
+	run    func(db *Database) error
+}
+
+// databaseMigrations lists the migrations in the order they run
+var databaseMigrations = []databaseMigration{
+	{name: "groups", description: "Migrate groups table", legacy: []string{"rdioScannerGroups"}, run: migrateGroups},
+	{name: "tags", description: "Migrate tags table", legacy: []string{"rdioScannerTags"}, run: migrateTags},
+	{name: "systems", description: "Migrate systems table", legacy: []string{"rdioScannerSystems"}, run: migrateSystems},
+	{name: "talkgroups", description: "Migrate talkgroups table", legacy: []string{"rdioScannerTalkgroups"}, run: migrateTalkgroups},
+	{name: "units", description: "Migrate units table", legacy: []string{"rdioScannerUnits"}, run: migrateUnits},
+	{name: "options", description: "Migrate options table", legacy: []string{"rdioScannerConfigs"}, run: migrateOptions},
+	{name: "meta", description: "Prepare the rdioScannerMeta table tracking the migrations", run: migrateMeta},
+	{name: "logs", description: "Migrate logs table", legacy: []string{"rdioScannerLogs"}, run: migrateLogs},
+	{name: "downstreams", description: "Migrate downstreams table", legacy: []string{"rdioScannerDownstreams"}, run: migrateDownstreams},
+	{name: "dirwatches", description: "Migrate dirwatches table", legacy: []string{"RdioScannerDirWatches"}, run: migrateDirwatches},
+	{name: "calls", description: "Migrate calls table", legacy: []string{"rdioScannerCalls"}, run: migrateCalls},
+	{name: "callsRefs", description: "Migrate calls system and talkgroup references", run: migrateCallsRefs},
+	{name: "apikeys", description: "Migrate apikeys table", legacy: []string{"rdioScannerApiKeys"}, run: migrateApikeys},
+	{name: "users", description: "Migrate users table", run: migrateUsers},
+	{name: "userPins", description: "Add pin columns to users table", run: migrateUserPins},
+	{name: "toneDetection", description: "Add tone detection columns to talkgroups and calls tables", run: migrateToneDetection},
+	{name: "alerts", description: "Migrate alerts table", run: migrateAlerts},
+	{name: "alertPreferences", description: "Add toneSetIds column to userAlertPreferences table", run: migrateAlertPreferences},
+	{name: "userGroupsMaxUsers", description: "Migrate userGroups maxUsers column", run: migrateUserGroupsMaxUsers},
+	{name: "systemAdmins", description: "Migrate system admins and system alerts", run: migrateSystemAdmins},
+	{name: "registrationCodesCreatedBy", description: "Migrate registrationCodes createdBy to be nullable", run: migrateRegistrationCodesCreatedBy},
+	{name: "userInvitationsInvitedBy", description: "Migrate userInvitations invitedBy to be nullable", run: migrateUserInvitationsInvitedBy},
+	{name: "tagsGroupsUniqueLabels", description: "Migrate tags and groups to have unique labels", marker: migrationTagsGroupsUniqueLabels, run: func(db *Database) error { return migrateTagsGroupsUniqueLabels(db, false) }},
+	{name: "userGroupsAllowAddExistingUsers", description: "Migrate userGroups allowAddExistingUsers column", run: migrateUserGroupsAllowAddExistingUsers},
+	{name: "userGroupsBillingFields", description: "Migrate userGroups billing fields (stripePriceId, billingMode)", run: migrateUserGroupsBillingFields},
+	{name: "userGroupsPricingOptions", description: "Migrate userGroups pricingOptions column", run: migrateUserGroupsPricingOptions},
+	{name: "userGroupsCollectSalesTax", description: "Migrate userGroups collectSalesTax column", run: migrateUserGroupsCollectSalesTax},
+	{name: "userAccountExpiresAt", description: "Migrate users accountExpiresAt column", run: migrateUserAccountExpiresAt},
+	{name: "transferRequestsApprovalTokens", description: "Migrate transferRequests approval token columns", run: migrateTransferRequestsApprovalTokens},
+	{name: "callsPerformanceIndexes", description: "Migrate calls performance indexes (matching v6 migration20250101000000)", marker: migrationOptimizeSearchPerformance, run: migrateCallsPerformanceIndexes},
+	{name: "callUnitsIndex", description: "Migrate callUnits index for fast search performance", marker: migrationCallUnitsIndex, run: migrateCallUnitsIndex},
+	{name: "removeAlertTones", description: "Remove alert tone columns", marker: migrationRemoveAlertTones, run: migrateRemoveAlertTones},
+	{name: "removeLedColors", description: "Remove LED color columns", marker: migrationRemoveLedColors, run: migrateRemoveLedColors},
+	{name: "fixUserTimestamps", description: "Fix invalid user timestamps (empty strings or 0 values)", marker: migrationFixUserTimestamps, run: migrateFixUserTimestamps},
+	{name: "downstreamsName", description: "Add name column to downstreams table", run: migrateDownstreamsName},
+	{name: "downstreamsThrottle", description: "Add rate limiting columns to downstreams table", run: migrateDownstreamsThrottle},
+	{name: "tagsColor", description: "Add color field to tags", run: migrateTagsColor},
+	{name: "talkgroupsEphemeral", description: "Add short retention columns to talkgroups table", run: migrateTalkgroupsEphemeral},
+	{name: "userGroupsDefaultAlertPreferences", description: "Add default alert preferences template to userGroups table", run: migrateUserGroupsDefaultAlertPreferences},
+	{name: "systemsVocabularyProfile", description: "Add transcription vocabulary profile to systems table", run: migrateSystemsVocabularyProfile},
+	{name: "talkgroupsPriority", description: "Add priority column to talkgroups table", run: migrateTalkgroupsPriority},
+	{name: "alertsSuppressed", description: "Add suppressed flag to alerts raised during a test window", run: migrateAlertsSuppressed},
+	{name: "downstreamsAudioFormat", description: "Add audio format column to downstreams table", run: migrateDownstreamsAudioFormat},
+	{name: "downstreamsRetry", description: "Add retry columns to downstreams table", run: migrateDownstreamsRetry},
+	{name: "talkgroupsGain", description: "Add audio gain column to talkgroups table", run: migrateTalkgroupsGain},
+	{name: "downstreamsTimeout", description: "Add timeout column to downstreams table", run: migrateDownstreamsTimeout},
+	{name: "downstreamsHeaders", description: "Add custom headers column to downstreams table", run: migrateDownstreamsHeaders},
+	{name: "downstreamsProtocol", description: "Add protocol column to downstreams table", run: migrateDownstreamsProtocol},
+	{name: "downstreamQueue", description: "Add the table of calls waiting to be sent again to downstreams", run: migrateDownstreamQueue},
+	{name: "downstreamsFilters", description: "Add tones and keywords filter columns to downstreams table", run: migrateDownstreamsFilters},
+	{name: "userGroupsInheritance", description: "Add system access inheritance columns to userGroups table", run: migrateUserGroupsInheritance},
+	{name: "registrationCodesLastUsedAt", description: "Add lastUsedAt column to registrationCodes table", run: migrateRegistrationCodesLastUsedAt},
+	{name: "vocabularyProfilesKeywordLists", description: "Add keywordListIds column to vocabularyProfiles table", run: migrateVocabularyProfilesKeywordLists},
+	{name: "systemsAutoPopulateDefaults", description: "Add default group and tag columns of auto populated talkgroups to systems table", run: migrateSystemsAutoPopulateDefaults},
+	{name: "displayConfidence", description: "Add transcript display confidence columns to systems and talkgroups tables", run: migrateDisplayConfidence},
+	{name: "talkgroupsToneLearning", description: "Add tone learning window column to talkgroups table", run: migrateTalkgroupsToneLearning},
+	{name: "downstreamsMaxCallAge", description: "Add max call age column to downstreams table", run: migrateDownstreamsMaxCallAge},
+	{name: "keywordListsPhonetic", description: "Add phonetic matching toggle to keywordLists table", run: migrateKeywordListsPhonetic},
+	{name: "userAlertPreferencesExclusions", description: "Add exclusion keywords column to userAlertPreferences table", run: migrateUserAlertPreferencesExclusions},
+	{name: "accessesToUserGroups", description: "Convert legacy access codes to user groups, then drop the accesses table", marker: accessConversionMigration, legacy: []string{"accesses"}, run: migrateAccessesToUserGroups},
+	{name: "fixAutoIncrementSequences", description: "Fix auto-increment sequences to prevent duplicate key errors", run: fixAutoIncrementSequences},
+}
+
 func (db *Database) migrate() error {
 	var schema []string
 
@@ -111,173 +201,12 @@ func (db *Database) migrate() error {
 		return formatError(err, "")
 	}
 
-	if err := migrateGroups(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateTags(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateSystems(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateTalkgroups(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateUnits(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateOptions(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateMeta(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateLogs(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateDownstreams(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateDirwatches(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateCalls(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateCallsRefs(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateApikeys(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate users table
-	if err := migrateUsers(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateUserPins(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate alert-related tables and columns
-	if err := migrateToneDetection(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateAlerts(db); err != nil {
-		return formatError(err, "")
-	}
-
-	if err := migrateAlertPreferences(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userGroups maxUsers column
-	if err := migrateUserGroupsMaxUsers(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate system admins and system alerts
-	if err := migrateSystemAdmins(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate registrationCodes createdBy to be nullable
-	if err := migrateRegistrationCodesCreatedBy(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userInvitations invitedBy to be nullable
-	if err := migrateUserInvitationsInvitedBy(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate tags and groups to have unique labels
-	if err := migrateTagsGroupsUniqueLabels(db, false); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userGroups allowAddExistingUsers column
-	if err := migrateUserGroupsAllowAddExistingUsers(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userGroups billing fields (stripePriceId, billingMode)
-	if err := migrateUserGroupsBillingFields(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userGroups pricingOptions column
-	if err := migrateUserGroupsPricingOptions(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate userGroups collectSalesTax column
-	if err := migrateUserGroupsCollectSalesTax(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate users accountExpiresAt column
-	if err := migrateUserAccountExpiresAt(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate transferRequests approval token columns
-	if err := migrateTransferRequestsApprovalTokens(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate calls performance indexes (matching v6 migration20250101000000)
-	if err := migrateCallsPerformanceIndexes(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Migrate callUnits index for fast search performance
-	if err := migrateCallUnitsIndex(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Remove alert tone columns
-	if err := migrateRemoveAlertTones(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Remove LED color columns
-	if err := migrateRemoveLedColors(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Fix invalid user timestamps (empty strings or 0 values)
-	if err := migrateFixUserTimestamps(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Add name column to downstreams table
-	if err := migrateDownstreamsName(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Add color field to tags
-	if err := migrateTagsColor(db); err != nil {
-		return formatError(err, "")
-	}
-
-	// Fix auto-increment sequences to prevent duplicate key errors
-	if err := fixAutoIncrementSequences(db); err != nil {
-		return formatError(err, "")
+	db.migrated = map[string]bool{}
+	for _, migration := range databaseMigrations {
+		if err := migration.run(db); err != nil {
+			return formatError(err, "")
+		}
+		db.migrated[migration.name] = true
 	}
 
 	return nil
@@ -298,6 +227,24 @@ func (db *Database) seed() error {
 	return nil
 }
 
+// execSavepoint runs the query within a savepoint of the transaction. When it fails, the savepoint
+// is rolled back so the transaction remains usable, e.g. to fetch the row behind a unique violation.
+func execSavepoint(tx *sql.Tx, query string, args ...any) error {
+	if _, err := tx.Exec(`SAVEPOINT "execSavepoint"`); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		if _, rollbackErr := tx.Exec(`ROLLBACK TO SAVEPOINT "execSavepoint"`); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+
+	_, err := tx.Exec(`RELEASE SAVEPOINT "execSavepoint"`)
+	return err
+}
+
 func escapeQuotes(s string) string {
 	return strings.ReplaceAll(s, "'", "''")
 }
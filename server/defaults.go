@@ -47,12 +47,22 @@ type DefaultDownstream struct {
 type DefaultOptions struct {
 	autoPopulate                bool
 	audioConversion             uint
+	audioNormalizeChannels      uint
+	audioNormalizeKeepOriginal  bool
+	audioNormalizeSampleRate    uint
 	branding                    string
+	brandingColor               string
+	brandingLogoUrl             string
 	defaultSystemDelay          uint
 	dimmerDelay                 uint
 	disableDuplicateDetection   bool
 	duplicateDetectionTimeFrame uint
 	email                       string
+	incidentGrouping            bool
+	incidentGroupingKeywords    string
+	incidentGroupingPatches     bool
+	incidentGroupingUnits       bool
+	incidentGroupingWindow      uint
 	keypadBeeps                 string
 	maxClients                  uint
 	playbackGoesLive            bool
@@ -95,6 +105,27 @@ type DefaultOptions struct {
 	transcriptionConfig         DefaultTranscriptionConfig
 	transcriptionFailureThreshold uint
 	toneDetectionIssueThreshold uint
+	callRateAnomalySensitivity  uint
+	callRateAnomalyCooldown     uint
+	keywordAlertCooldown        uint
+	orphanSweepInterval         uint
+	registrationRetentionDays   uint
+	deviceTokenRetentionDays    uint
+	auditLogEnabled             bool
+	auditLogRetentionDays       uint
+	zipCodeFormat               string
+	missingAudioResponse        string
+	audioSniffing               bool
+	delayedMaxEntries           uint
+	delayedOverflowPolicy       string
+	systemAlertWebhookUrl       string
+	systemAlertWebhookSecret    string
+	systemAlertWebhookSeverity  string
+	downstreamHttp2             bool
+	downstreamMaxIdleConns      uint
+	downstreamIdleConnTimeout   uint
+	connectHistoryMinutes       uint
+	connectHistoryMaxCalls      uint
 	alertRetentionDays          uint
 	adminLocalhostOnly          bool
 	configSyncEnabled           bool
@@ -111,9 +142,13 @@ type DefaultTranscriptionConfig struct {
 	googleAPIKey     string
 	googleCredentials string
 	assemblyAIKey    string
+	awsRegion        string
+	awsBucket        string
 	language         string
 	prompt           string
 	workerPoolSize   int
+	hallucinationRetentionDays int
+	hallucinationDecayDays     int
 }
 
 var defaults = Defaults{
@@ -145,12 +180,22 @@ var defaults = Defaults{
 	options: DefaultOptions{
 		autoPopulate:                true,
 		audioConversion:             0,
+		audioNormalizeChannels:      1,
+		audioNormalizeKeepOriginal:  true,
+		audioNormalizeSampleRate:    0, // disabled, 16000 suits every transcription provider
 		branding:                    "",
+		brandingColor:               "",
+		brandingLogoUrl:             "",
 		defaultSystemDelay:          0,
 		dimmerDelay:                 30000,
 		disableDuplicateDetection:   false,
 		duplicateDetectionTimeFrame: 1000,
 		email:                       "",
+		incidentGrouping:            false,
+		incidentGroupingKeywords:    "",
+		incidentGroupingPatches:     true,
+		incidentGroupingUnits:       true,
+		incidentGroupingWindow:      600,
 		keypadBeeps:                 "uniden",
 		maxClients:                  100,
 		playbackGoesLive:            false,
@@ -200,12 +245,37 @@ var defaults = Defaults{
 			googleAPIKey:   "",
 			googleCredentials: "",
 			assemblyAIKey:  "",
+			awsRegion:      "us-east-1",
+			awsBucket:      "",
 			language:       "en",       // English by default
 			prompt:         "",         // No default prompt
 			workerPoolSize: 3,          // Conservative default
+			hallucinationRetentionDays: 90, // days before pending suspected phrases no longer seen are deleted
+			hallucinationDecayDays:     30, // days before the rejected count of a phrase no longer seen is halved
 		},
 		transcriptionFailureThreshold: 10,
 		toneDetectionIssueThreshold: 5,
+		callRateAnomalySensitivity: 4,   // alert when the rate is 4x above or below the baseline
+		callRateAnomalyCooldown:    360, // minutes between alerts of the same type for a system
+		keywordAlertCooldown:       0,   // minutes before a keyword alerts a user again, off by default
+		orphanSweepInterval:        24,  // hours between sweeps of rows left without their call
+		registrationRetentionDays:  90,  // days spent registration codes and invitations are kept for auditing
+		deviceTokenRetentionDays:   0,   // days unused device tokens are kept, off by default as the relay server doesn't report their use
+		auditLogEnabled:            true,
+		auditLogRetentionDays:      365, // days admin actions are kept in the audit log
+		zipCodeFormat:              ZipCodeFormatAny,
+		missingAudioResponse:       MissingAudioGone,
+		audioSniffing:              true,
+		delayedMaxEntries:          5000, // calls waiting for their delay at most
+		delayedOverflowPolicy:      DelayedOverflowDropOldest,
+		systemAlertWebhookUrl:      "",
+		systemAlertWebhookSecret:   "",
+		systemAlertWebhookSeverity: "warning", // info alerts are not sent to the webhook
+		downstreamHttp2:            true,
+		downstreamMaxIdleConns:     10, // idle connections kept open to each downstream host
+		downstreamIdleConnTimeout:  90, // seconds before an idle downstream connection is closed
+		connectHistoryMinutes:      0,   // only new calls, as before the option existed
+		connectHistoryMaxCalls:     connectHistoryCallsLimit, // calls replayed at most to a client turning its livefeed on
 		alertRetentionDays: 5,
 		adminLocalhostOnly: false, // Default to false for backwards compatibility
 		configSyncEnabled:  false,
@@ -23,17 +23,30 @@ import (
 	"time"
 )
 
+const (
+	DelayedOverflowDropOldest = "drop-oldest"
+	DelayedOverflowRelease    = "release-immediately"
+)
+
 type Delayer struct {
 	controller *Controller
 	mutex      sync.Mutex
-	timers     map[uint64]time.Timer
+	delayed    map[uint64]*delayedCall
+	overflowed uint // calls dropped or released since the cap was reached
+}
+
+// delayedCall is a call waiting for its delay, released at the timestamp
+type delayedCall struct {
+	call      *Call
+	timer     *time.Timer
+	timestamp time.Time
 }
 
 func NewDelayer(controller *Controller) *Delayer {
 	return &Delayer{
 		controller: controller,
 		mutex:      sync.Mutex{},
-		timers:     make(map[uint64]time.Timer),
+		delayed:    make(map[uint64]*delayedCall),
 	}
 }
 
@@ -81,18 +94,34 @@ func (delayer *Delayer) Delay(call *Call) {
 	}
 
 	if delay > 0 {
-		call.Delayed = true
-
 		timestamp := call.Timestamp.Add(time.Duration(delay) * time.Minute)
 		remaining := time.Until(timestamp)
 
+		evicted, release := delayer.admit(call, timestamp)
+
+		if evicted != nil {
+			// dropped calls are not sent live, they stay available for playback
+			if err := delayer.pop(evicted); err != nil {
+				logError(err)
+			}
+			evicted.Delayed = false
+		}
+
+		if release {
+			go delayer.controller.Downstreams.Send(delayer.controller, call)
+			go delayer.controller.Clients.EmitCall(delayer.controller, call)
+			return
+		}
+
+		call.Delayed = true
+
 		if err := delayer.push(call, timestamp); err == nil {
-			delayer.timers[call.Id] = *time.AfterFunc(remaining, func() {
+			timer := time.AfterFunc(remaining, func() {
 				if err := delayer.pop(call); err != nil {
 					logError(err)
 				}
 
-				delete(delayer.timers, call.Id)
+				delayer.forget(call.Id)
 
 				// Clear the global delayed flag so individual client delays can be checked
 				call.Delayed = false
@@ -102,7 +131,17 @@ func (delayer *Delayer) Delay(call *Call) {
 				go delayer.controller.Clients.EmitCall(delayer.controller, call)
 			})
 
+			delayer.mutex.Lock()
+			if entry, ok := delayer.delayed[call.Id]; ok {
+				entry.timer = timer
+			} else {
+				// evicted while being pushed
+				timer.Stop()
+			}
+			delayer.mutex.Unlock()
+
 		} else {
+			delayer.forget(call.Id)
 			logError(err)
 		}
 
@@ -243,34 +282,161 @@ func (delayer *Delayer) Start() error {
 	return nil
 }
 
+// admit tracks a call delayed until the timestamp. Once the cap of delayed calls is reached, it
+// either evicts the call released the soonest to make room, which is returned, or reports that the
+// call must be released without delay.
+func (delayer *Delayer) admit(call *Call, timestamp time.Time) (evicted *Call, release bool) {
+	delayer.mutex.Lock()
+	defer delayer.mutex.Unlock()
+
+	max := delayer.controller.Options.DelayedMaxEntries
+	policy := delayer.controller.Options.DelayedOverflowPolicy
+
+	if max > 0 && uint(len(delayer.delayed)) >= max {
+		if delayer.overflowed == 0 {
+			delayer.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("delayer: %d delayed calls reached, applying the %s overflow policy", max, policy))
+		}
+		delayer.overflowed++
+
+		if policy == DelayedOverflowRelease {
+			return nil, true
+		}
+
+		var oldest *delayedCall
+		for _, entry := range delayer.delayed {
+			if oldest == nil || entry.timestamp.Before(oldest.timestamp) || (entry.timestamp.Equal(oldest.timestamp) && entry.call.Id < oldest.call.Id) {
+				oldest = entry
+			}
+		}
+
+		if oldest != nil {
+			if oldest.timer != nil {
+				oldest.timer.Stop()
+			}
+			delete(delayer.delayed, oldest.call.Id)
+			evicted = oldest.call
+		}
+	}
+
+	delayer.delayed[call.Id] = &delayedCall{call: call, timestamp: timestamp}
+
+	return evicted, false
+}
+
+// forget stops tracking a released call, logging the overflowed calls once well under the cap
+func (delayer *Delayer) forget(callId uint64) {
+	delayer.mutex.Lock()
+	defer delayer.mutex.Unlock()
+
+	delete(delayer.delayed, callId)
+
+	max := delayer.controller.Options.DelayedMaxEntries
+
+	if delayer.overflowed > 0 && (max == 0 || uint(len(delayer.delayed)) < max-max/10) {
+		delayer.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("delayer: back under %d delayed calls, %d call(s) overflowed", delayer.controller.Options.DelayedMaxEntries, delayer.overflowed))
+		delayer.overflowed = 0
+	}
+}
+
 func (delayer *Delayer) getEffectiveDelayForClient(call *Call, client *Client) uint {
 	if call == nil || call.System == nil || call.Talkgroup == nil {
 		return 0
 	}
 
-	baseDelay := delayer.getSystemDelay(call)
-	if client != nil && client.User != nil {
-		// Use controller method to properly check group delays
-		return delayer.controller.userEffectiveDelay(client.User, call, baseDelay)
+	var user *User
+	if client != nil {
+		user = client.User
 	}
 
-	return baseDelay
+	return delayer.controller.userEffectiveDelay(user, call, delayer.controller.Options.DefaultSystemDelay)
 }
 
+// getSystemDelay returns the delay of the call regardless of the listener
 func (delayer *Delayer) getSystemDelay(call *Call) uint {
-	// Check talkgroup delay first (highest priority)
-	// Note: All delays are in MINUTES and affect live audio streaming to clients
+	return ResolveDelay(nil, nil, call, delayer.controller.Options.DefaultSystemDelay)
+}
+
+// Sources of a resolved delay, as reported by the access trace
+const (
+	delaySourcePriority  = "priority"
+	delaySourceGroup     = "group"
+	delaySourceUser      = "user"
+	delaySourceTalkgroup = "talkgroup"
+	delaySourceSystem    = "system"
+	delaySourceDefault   = "default"
+)
+
+// ResolveDelay returns the delay in minutes of the call for the user in the group, either nil.
+// Priority talkgroups are never delayed. A group setting any delay decides the delays of its
+// users for every call: its delay of the talkgroup, of the system, of the group, else the delay
+// of the talkgroup, of the system and finally systemDefault, the user delays being ignored.
+// Otherwise the user delay of the talkgroup, of the system and of the user wins over the same
+// fallbacks. A delay of 0 is not set and inherits the next one.
+func ResolveDelay(user *User, group *UserGroup, call *Call, systemDefault uint) uint {
+	delay, _ := resolveDelay(user, group, call, systemDefault)
+	return delay
+}
+
+// resolveDelay is ResolveDelay, also returning the source of the delay
+func resolveDelay(user *User, group *UserGroup, call *Call, systemDefault uint) (uint, string) {
+	if call == nil || call.System == nil || call.Talkgroup == nil {
+		return systemDefault, delaySourceDefault
+	}
+
+	if isPriorityCall(call) {
+		return 0, delaySourcePriority
+	}
+
+	if group.hasDelays() {
+		if delay := delayOverride(group.talkgroupDelaysMap, group.systemDelaysMap, group.Delay, call); delay > 0 {
+			return delay, delaySourceGroup
+		}
+		delay, _ := callDelay(call, systemDefault)
+		return delay, delaySourceGroup
+	}
+
+	if user != nil {
+		if delay := delayOverride(user.talkgroupDelaysMap, user.systemDelaysMap, user.Delay, call); delay > 0 {
+			return delay, delaySourceUser
+		}
+	}
+
+	return callDelay(call, systemDefault)
+}
+
+// callDelay returns the delay of the talkgroup of the call, else of its system, else systemDefault
+func callDelay(call *Call, systemDefault uint) (uint, string) {
 	if call.Talkgroup.Delay > 0 {
-		return call.Talkgroup.Delay
+		return call.Talkgroup.Delay, delaySourceTalkgroup
 	}
 
-	// Check system delay second (medium priority)
 	if call.System.Delay > 0 {
-		return call.System.Delay
+		return call.System.Delay, delaySourceSystem
+	}
+
+	return systemDefault, delaySourceDefault
+}
+
+// delayOverride returns the delay set for the talkgroup of the call, else for its system, else
+// delay, 0 when none is set
+func delayOverride(talkgroupDelays map[string]uint, systemDelays map[uint64]uint, delay int, call *Call) uint {
+	if call == nil || call.System == nil || call.Talkgroup == nil {
+		return 0
+	}
+
+	if d := talkgroupDelays[fmt.Sprintf("%d:%d", call.System.SystemRef, call.Talkgroup.TalkgroupRef)]; d > 0 {
+		return d
+	}
+
+	if d := systemDelays[uint64(call.System.SystemRef)]; d > 0 {
+		return d
+	}
+
+	if delay > 0 {
+		return uint(delay)
 	}
 
-	// Use default system delay as fallback (lowest priority)
-	return delayer.controller.Options.DefaultSystemDelay
+	return 0
 }
 
 func (delayer *Delayer) getTimestamp(call *Call) time.Time {
@@ -0,0 +1,146 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayerOverflow(t *testing.T) {
+	now := time.Now()
+
+	newDelayer := func(policy string) *Delayer {
+		controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+		controller.Options.DelayedMaxEntries = 2
+		controller.Options.DelayedOverflowPolicy = policy
+		return NewDelayer(controller)
+	}
+
+	t.Run("drop oldest", func(t *testing.T) {
+		delayer := newDelayer(DelayedOverflowDropOldest)
+
+		for i, minutes := range []int{10, 5} {
+			if evicted, release := delayer.admit(&Call{Id: uint64(i + 1)}, now.Add(time.Duration(minutes)*time.Minute)); evicted != nil || release {
+				t.Fatalf("call %d: expected to be delayed under the cap", i+1)
+			}
+		}
+
+		// the call released the soonest makes room
+		evicted, release := delayer.admit(&Call{Id: 3}, now.Add(15*time.Minute))
+		if release || evicted == nil || evicted.Id != 2 {
+			t.Fatalf("expected call 2 evicted, got %+v %v", evicted, release)
+		}
+		if _, ok := delayer.delayed[2]; ok || len(delayer.delayed) != 2 || delayer.overflowed != 1 {
+			t.Errorf("unexpected delayed calls %v, %d overflowed", delayer.delayed, delayer.overflowed)
+		}
+
+		delayer.forget(1)
+		if delayer.overflowed != 0 {
+			t.Errorf("expected the overflow reset once under the cap, got %d", delayer.overflowed)
+		}
+	})
+
+	t.Run("release immediately", func(t *testing.T) {
+		delayer := newDelayer(DelayedOverflowRelease)
+
+		delayer.admit(&Call{Id: 1}, now.Add(10*time.Minute))
+		delayer.admit(&Call{Id: 2}, now.Add(5*time.Minute))
+
+		evicted, release := delayer.admit(&Call{Id: 3}, now.Add(15*time.Minute))
+		if !release || evicted != nil {
+			t.Fatalf("expected call 3 released, got %+v %v", evicted, release)
+		}
+		if _, ok := delayer.delayed[3]; ok || len(delayer.delayed) != 2 {
+			t.Errorf("expected the released call not to be tracked, got %v", delayer.delayed)
+		}
+	})
+
+	t.Run("no cap", func(t *testing.T) {
+		delayer := newDelayer(DelayedOverflowDropOldest)
+		delayer.controller.Options.DelayedMaxEntries = 0
+
+		for i := 1; i <= 10; i++ {
+			if evicted, release := delayer.admit(&Call{Id: uint64(i)}, now); evicted != nil || release {
+				t.Fatalf("call %d: expected no overflow without a cap", i)
+			}
+		}
+	})
+}
+
+func TestResolveDelay(t *testing.T) {
+	call := func(talkgroupDelay uint, systemDelay uint, priority uint) *Call {
+		return &Call{
+			System:    &System{Id: 1, SystemRef: 1, Delay: systemDelay},
+			Talkgroup: &Talkgroup{Id: 1, TalkgroupRef: 100, Delay: talkgroupDelay, Priority: priority},
+		}
+	}
+
+	for _, test := range []struct {
+		name   string
+		user   *User
+		group  *UserGroup
+		call   *Call
+		delay  uint
+		source string
+	}{
+		{"default", nil, nil, call(0, 0, 0), 5, delaySourceDefault},
+		{"system", nil, nil, call(0, 3, 0), 3, delaySourceSystem},
+		{"talkgroup over system", nil, nil, call(10, 3, 0), 10, delaySourceTalkgroup},
+		{"priority never delayed", &User{Delay: 7}, &UserGroup{Delay: 9}, call(10, 3, 1), 0, delaySourcePriority},
+		{"user without delays inherits the talkgroup", &User{}, nil, call(10, 3, 0), 10, delaySourceTalkgroup},
+		{"user delay over talkgroup", &User{Delay: 7}, nil, call(10, 3, 0), 7, delaySourceUser},
+		{"user system over user delay", &User{Delay: 7, systemDelaysMap: map[uint64]uint{1: 4}}, nil, call(10, 3, 0), 4, delaySourceUser},
+		{"user talkgroup over user system", &User{systemDelaysMap: map[uint64]uint{1: 4}, talkgroupDelaysMap: map[string]uint{"1:100": 2}}, nil, call(0, 0, 0), 2, delaySourceUser},
+		{"user zero talkgroup inherits user system", &User{systemDelaysMap: map[uint64]uint{1: 4}, talkgroupDelaysMap: map[string]uint{"1:100": 0}}, nil, call(0, 0, 0), 4, delaySourceUser},
+		{"user negative delay inherits", &User{Delay: -1}, nil, call(0, 0, 0), 5, delaySourceDefault},
+		{"user other system inherits", &User{systemDelaysMap: map[uint64]uint{2: 4}}, nil, call(0, 3, 0), 3, delaySourceSystem},
+		{"group delay over user talkgroup", &User{talkgroupDelaysMap: map[string]uint{"1:100": 2}}, &UserGroup{Delay: 9}, call(0, 0, 0), 9, delaySourceGroup},
+		{"group system over group delay", nil, &UserGroup{Delay: 9, systemDelaysMap: map[uint64]uint{1: 6}}, call(0, 0, 0), 6, delaySourceGroup},
+		{"group talkgroup over group system", nil, &UserGroup{systemDelaysMap: map[uint64]uint{1: 6}, talkgroupDelaysMap: map[string]uint{"1:100": 1}}, call(0, 0, 0), 1, delaySourceGroup},
+		{"group other system hides the user delays", &User{Delay: 7}, &UserGroup{systemDelaysMap: map[uint64]uint{2: 8}}, call(10, 3, 0), 10, delaySourceGroup},
+		{"group zero talkgroup inherits the talkgroup", &User{}, &UserGroup{talkgroupDelaysMap: map[string]uint{"1:100": 0}}, call(10, 3, 0), 10, delaySourceGroup},
+		{"group without delays inherits the user", &User{Delay: 7}, &UserGroup{}, call(0, 0, 0), 7, delaySourceUser},
+		{"group without delays inherits the default", nil, &UserGroup{}, call(0, 0, 0), 5, delaySourceDefault},
+		{"call without talkgroup", &User{Delay: 7}, nil, &Call{System: &System{}}, 5, delaySourceDefault},
+	} {
+		delay, source := resolveDelay(test.user, test.group, test.call, 5)
+		if delay != test.delay || source != test.source {
+			t.Errorf("%s: expected %d from %s, got %d from %s", test.name, test.delay, test.source, delay, source)
+		}
+		if delay := ResolveDelay(test.user, test.group, test.call, 5); delay != test.delay {
+			t.Errorf("%s: expected ResolveDelay %d, got %d", test.name, test.delay, delay)
+		}
+	}
+}
+
+func TestResolveDelayAgrees(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), UserGroups: NewUserGroups()}
+	controller.Options.DefaultSystemDelay = 5
+	controller.UserGroups.groups[3] = &UserGroup{Id: 3, systemDelaysMap: map[uint64]uint{2: 8}}
+	delayer := NewDelayer(controller)
+
+	call := &Call{System: &System{Id: 1, SystemRef: 1, Delay: 3}, Talkgroup: &Talkgroup{Id: 1, TalkgroupRef: 100, Delay: 10}}
+
+	for _, user := range []*User{nil, {}, {Delay: 7}, {UserGroupId: 3}, {UserGroupId: 3, Delay: 7}} {
+		delayed := delayer.getEffectiveDelayForClient(call, &Client{User: user})
+		effective := controller.userEffectiveDelay(user, call, controller.Options.DefaultSystemDelay)
+
+		if delayed != effective {
+			t.Errorf("user %+v: the delayer delays %d minutes, others %d", user, delayed, effective)
+		}
+	}
+}
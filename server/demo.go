@@ -0,0 +1,194 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// demoLabel marks every system, tag and group created by the demo seeding so they can be removed in one action
+const demoLabel = "ThinLine Demo"
+
+var (
+	errDemoPresent   = errors.New("demo data is already present")
+	errDemoPopulated = errors.New("database already contains systems or calls, use force to seed anyway")
+)
+
+type DemoSeedResult struct {
+	SystemId   uint64 `json:"systemId"`
+	Talkgroups int    `json:"talkgroups"`
+	Calls      int    `json:"calls"`
+}
+
+type demoTalkgroup struct {
+	ref         uint
+	label       string
+	name        string
+	transcripts []string
+}
+
+var demoTalkgroups = []demoTalkgroup{
+	{ref: 1001, label: "FD DISP", name: "Fire Dispatch", transcripts: []string{
+		"ENGINE 5 LADDER 2 RESPOND TO A STRUCTURE FIRE 100 MAIN STREET",
+		"ENGINE 5 ON SCENE SMOKE SHOWING FROM THE SECOND FLOOR",
+		"ALL UNITS FIRE IS KNOCKED DOWN CHECKING FOR EXTENSION",
+	}},
+	{ref: 1002, label: "EMS DISP", name: "EMS Dispatch", transcripts: []string{
+		"MEDIC 3 RESPOND TO 42 OAK AVENUE FOR A FALL",
+		"MEDIC 3 TRANSPORTING ONE PATIENT TO GENERAL HOSPITAL",
+	}},
+	{ref: 1003, label: "PD DISP", name: "Police Dispatch", transcripts: []string{
+		"UNIT 12 TRAFFIC STOP AT 5TH AND ELM",
+		"UNIT 12 CLEAR RETURNING TO SERVICE",
+	}},
+}
+
+// demoAudio returns one second of silent 8 kHz 16 bits mono wav audio, used as placeholder for the demo calls
+func demoAudio() []byte {
+	return encodeWavPcm16(make([]int16, 8000), 8000)
+}
+
+// checkDemoSeed refuses to seed over existing demo data, or over a populated database unless forced
+func checkDemoSeed(systems uint, calls uint, demoPresent bool, force bool) error {
+	switch {
+	case demoPresent:
+		return errDemoPresent
+	case (systems > 0 || calls > 0) && !force:
+		return errDemoPopulated
+	default:
+		return nil
+	}
+}
+
+// SeedDemo inserts a sample system with its talkgroups, tag, group and a few synthetic calls
+func SeedDemo(db *Database, force bool) (*DemoSeedResult, error) {
+	var (
+		calls       uint
+		demoPresent uint
+		query       string
+		systemRef   uint
+		systems     uint
+	)
+
+	formatError := errorFormatter("demo", "seed")
+
+	query = fmt.Sprintf(`SELECT (SELECT COUNT(*) FROM "systems"), (SELECT COUNT(*) FROM "calls"), (SELECT COUNT(*) FROM "systems" WHERE "label" = '%s')`, escapeQuotes(demoLabel))
+	if err := db.Sql.QueryRow(query).Scan(&systems, &calls, &demoPresent); err != nil {
+		return nil, formatError(err, query)
+	}
+
+	if err := checkDemoSeed(systems, calls, demoPresent > 0, force); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT COALESCE(MAX("systemRef"), 0) + 1 FROM "systems"`
+	if err := db.Sql.QueryRow(query).Scan(&systemRef); err != nil {
+		return nil, formatError(err, query)
+	}
+
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return nil, formatError(err, "")
+	}
+	defer tx.Rollback()
+
+	result := &DemoSeedResult{}
+
+	var groupId, tagId uint64
+
+	query = fmt.Sprintf(`INSERT INTO "tags" ("label") VALUES ('%s') RETURNING "tagId"`, escapeQuotes(demoLabel))
+	if err := tx.QueryRow(query).Scan(&tagId); err != nil {
+		return nil, formatError(err, query)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO "groups" ("label") VALUES ('%s') RETURNING "groupId"`, escapeQuotes(demoLabel))
+	if err := tx.QueryRow(query).Scan(&groupId); err != nil {
+		return nil, formatError(err, query)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO "systems" ("label", "systemRef") VALUES ('%s', %d) RETURNING "systemId"`, escapeQuotes(demoLabel), systemRef)
+	if err := tx.QueryRow(query).Scan(&result.SystemId); err != nil {
+		return nil, formatError(err, query)
+	}
+
+	audio := demoAudio()
+	now := time.Now()
+
+	for i, talkgroup := range demoTalkgroups {
+		var talkgroupId uint64
+
+		query = fmt.Sprintf(`INSERT INTO "talkgroups" ("label", "name", "order", "systemId", "tagId", "talkgroupRef") VALUES ('%s', '%s', %d, %d, %d, %d) RETURNING "talkgroupId"`, escapeQuotes(talkgroup.label), escapeQuotes(talkgroup.name), i+1, result.SystemId, tagId, talkgroup.ref)
+		if err := tx.QueryRow(query).Scan(&talkgroupId); err != nil {
+			return nil, formatError(err, query)
+		}
+
+		query = fmt.Sprintf(`INSERT INTO "talkgroupGroups" ("groupId", "talkgroupId") VALUES (%d, %d)`, groupId, talkgroupId)
+		if _, err := tx.Exec(query); err != nil {
+			return nil, formatError(err, query)
+		}
+
+		result.Talkgroups++
+
+		for j, transcript := range talkgroup.transcripts {
+			timestamp := now.Add(-time.Duration(len(talkgroup.transcripts)-j) * 5 * time.Minute).Add(-time.Duration(i) * time.Minute)
+
+			query = fmt.Sprintf(`INSERT INTO "calls" ("audio", "audioFilename", "audioMime", "systemId", "talkgroupId", "systemRef", "talkgroupRef", "timestamp", "transcript", "transcriptConfidence", "transcriptionStatus") VALUES ($1, '%s', 'audio/wav', %d, %d, %d, %d, %d, $2, 1, 'completed')`, fmt.Sprintf("demo-%d-%d.wav", talkgroup.ref, timestamp.Unix()), result.SystemId, talkgroupId, systemRef, talkgroup.ref, timestamp.UnixMilli())
+			if _, err := tx.Exec(query, audio, transcript); err != nil {
+				return nil, formatError(err, query)
+			}
+
+			result.Calls++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, formatError(err, "")
+	}
+
+	return result, nil
+}
+
+// RemoveDemo deletes everything created by SeedDemo, the calls and talkgroups go with the system
+func RemoveDemo(db *Database) error {
+	formatError := errorFormatter("demo", "remove")
+
+	queries := []string{
+		fmt.Sprintf(`DELETE FROM "systems" WHERE "label" = '%s'`, escapeQuotes(demoLabel)),
+		// only when no other talkgroup uses them, deleting a tag cascades to its talkgroups
+		fmt.Sprintf(`DELETE FROM "tags" WHERE "label" = '%s' AND NOT EXISTS (SELECT 1 FROM "talkgroups" WHERE "talkgroups"."tagId" = "tags"."tagId")`, escapeQuotes(demoLabel)),
+		fmt.Sprintf(`DELETE FROM "groups" WHERE "label" = '%s' AND NOT EXISTS (SELECT 1 FROM "talkgroupGroups" WHERE "talkgroupGroups"."groupId" = "groups"."groupId")`, escapeQuotes(demoLabel)),
+	}
+
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return formatError(err, "")
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return formatError(err, query)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return formatError(err, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCheckDemoSeed(t *testing.T) {
+	if err := checkDemoSeed(0, 0, false, false); err != nil {
+		t.Errorf("seeding an empty database must be allowed, got %v", err)
+	}
+
+	if err := checkDemoSeed(2, 0, false, false); err != errDemoPopulated {
+		t.Errorf("seeding a database with systems must be refused, got %v", err)
+	}
+
+	if err := checkDemoSeed(0, 150, false, false); err != errDemoPopulated {
+		t.Errorf("seeding a database with calls must be refused, got %v", err)
+	}
+
+	if err := checkDemoSeed(2, 150, false, true); err != nil {
+		t.Errorf("forced seeding must be allowed, got %v", err)
+	}
+
+	if err := checkDemoSeed(1, 7, true, true); err != errDemoPresent {
+		t.Errorf("seeding twice must be refused even when forced, got %v", err)
+	}
+}
+
+func TestDemoData(t *testing.T) {
+	audio := demoAudio()
+
+	if string(audio[0:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		t.Fatalf("demo audio is not a wav file")
+	}
+
+	if size := binary.LittleEndian.Uint32(audio[4:8]); int(size) != len(audio)-8 {
+		t.Errorf("riff size %d does not match audio length %d", size, len(audio))
+	}
+
+	refs := map[uint]bool{}
+	calls := 0
+
+	for _, talkgroup := range demoTalkgroups {
+		if refs[talkgroup.ref] {
+			t.Errorf("duplicate demo talkgroup ref %d", talkgroup.ref)
+		}
+		refs[talkgroup.ref] = true
+		calls += len(talkgroup.transcripts)
+	}
+
+	if len(refs) == 0 || calls == 0 {
+		t.Errorf("demo data must contain talkgroups and calls")
+	}
+}
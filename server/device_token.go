@@ -22,11 +22,14 @@ import (
 	"time"
 )
 
+// deviceTokenTouchInterval is how often the use of a token is recorded at most
+const deviceTokenTouchInterval = time.Hour
+
 type DeviceToken struct {
 	Id        uint64
 	UserId    uint64
-	Token     string // OneSignal player ID
-	Platform  string // "ios" or "android"
+	Token     string // OneSignal player ID, APNs device token, or the subscription JSON of the "web" platform
+	Platform  string // "ios", "android" or "web"
 	Sound     string // Notification sound preference
 	CreatedAt int64
 	LastUsed  int64
@@ -166,21 +169,12 @@ func (dt *DeviceTokens) Delete(id uint64, db *Database) error {
 		return err
 	}
 
-	delete(dt.tokens, id)
-
-	// Remove from userTokens map
-	userTokens := dt.userTokens[token.UserId]
-	for i, t := range userTokens {
-		if t.Id == id {
-			dt.userTokens[token.UserId] = append(userTokens[:i], userTokens[i+1:]...)
-			break
-		}
-	}
+	dt.forget(id)
 
 	return nil
 }
 
-func (dt *DeviceTokens) GetByUser(userId uint64) []*DeviceToken {
+func (dt *DeviceTokens) GetForUser(userId uint64) []*DeviceToken {
 	dt.mutex.RLock()
 	defer dt.mutex.RUnlock()
 
@@ -207,3 +201,143 @@ func (dt *DeviceTokens) FindByUserAndToken(userId uint64, token string) *DeviceT
 	return nil
 }
 
+
+// Register adds the token of the user, or updates its platform and sound when the user already registered it
+func (dt *DeviceTokens) Register(userId uint64, token, platform, sound string, db *Database) (*DeviceToken, error) {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+
+	device := &DeviceToken{
+		UserId:   userId,
+		Token:    token,
+		Platform: platform,
+		Sound:    sound,
+		LastUsed: time.Now().Unix(),
+	}
+
+	var id int64
+	err := db.Sql.QueryRow(
+		`INSERT INTO "deviceTokens" ("userId", "token", "platform", "sound", "createdAt", "lastUsed") VALUES ($1, $2, $3, $4, $5, $5) ON CONFLICT ("userId", "token") DO UPDATE SET "platform" = EXCLUDED."platform", "sound" = EXCLUDED."sound", "lastUsed" = EXCLUDED."lastUsed" RETURNING "deviceTokenId", "createdAt"`,
+		userId, token, platform, sound, device.LastUsed,
+	).Scan(&id, &device.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	device.Id = uint64(id)
+	dt.store(device)
+
+	return device, nil
+}
+
+// Remove deletes the token from the devices of every user, and returns how many were deleted
+func (dt *DeviceTokens) Remove(token string, db *Database) (int64, error) {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+
+	return dt.deleteReturning(db, `DELETE FROM "deviceTokens" WHERE "token" = $1 RETURNING "deviceTokenId"`, token)
+}
+
+// Touch records that the token was just used, at most once per deviceTokenTouchInterval
+func (dt *DeviceTokens) Touch(token string, db *Database) error {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+
+	now := time.Now().Unix()
+
+	devices := []*DeviceToken{}
+	for _, device := range dt.tokens {
+		if device.Token == token && now-device.LastUsed >= int64(deviceTokenTouchInterval.Seconds()) {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	if _, err := db.Sql.Exec(`UPDATE "deviceTokens" SET "lastUsed" = $1 WHERE "token" = $2`, now, token); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		touched := *device
+		touched.LastUsed = now
+		dt.store(&touched)
+	}
+
+	return nil
+}
+
+// PruneStale deletes the web and APNs tokens not used for maxAge, those of the dead installs, and returns how many were deleted.
+// The relay tokens are never touched by a send, so they are left to the relay server.
+func (dt *DeviceTokens) PruneStale(maxAge time.Duration, db *Database) (int64, error) {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+
+	cutoff := time.Now().Add(-maxAge).Unix()
+
+	// Tokens registered before lastUsed was recorded only have their creation date
+	return dt.deleteReturning(db, `DELETE FROM "deviceTokens" WHERE GREATEST("lastUsed", "createdAt") < $1 AND ("platform" = 'web' OR ("platform" = 'ios' AND "token" ~ $2)) RETURNING "deviceTokenId"`, cutoff, apnsDeviceTokenPattern)
+}
+
+// deleteReturning runs a delete returning the ids of the deleted tokens, and forgets them. The mutex must be held.
+func (dt *DeviceTokens) deleteReturning(db *Database, query string, args ...any) (int64, error) {
+	rows, err := db.Sql.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var deleted int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return deleted, err
+		}
+		dt.forget(uint64(id))
+		deleted++
+	}
+
+	return deleted, rows.Err()
+}
+
+// store adds the token, or replaces the one with its id in place. The mutex must be held.
+func (dt *DeviceTokens) store(device *DeviceToken) {
+	if previous, ok := dt.tokens[device.Id]; ok && previous.UserId == device.UserId {
+		dt.tokens[device.Id] = device
+		for i, t := range dt.userTokens[device.UserId] {
+			if t.Id == device.Id {
+				dt.userTokens[device.UserId][i] = device
+			}
+		}
+		return
+	} else if ok {
+		dt.forget(device.Id)
+	}
+
+	dt.tokens[device.Id] = device
+	dt.userTokens[device.UserId] = append(dt.userTokens[device.UserId], device)
+}
+
+// forget removes the token from the maps. The mutex must be held.
+func (dt *DeviceTokens) forget(id uint64) {
+	token, ok := dt.tokens[id]
+	if !ok {
+		return
+	}
+
+	delete(dt.tokens, id)
+
+	userTokens := []*DeviceToken{}
+	for _, t := range dt.userTokens[token.UserId] {
+		if t.Id != id {
+			userTokens = append(userTokens, t)
+		}
+	}
+
+	if len(userTokens) == 0 {
+		delete(dt.userTokens, token.UserId)
+	} else {
+		dt.userTokens[token.UserId] = userTokens
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDeviceTokens returns the device tokens holding the devices, without loading them
+func newDeviceTokens(devices ...*DeviceToken) *DeviceTokens {
+	dt := NewDeviceTokens()
+	for _, device := range devices {
+		dt.store(device)
+	}
+	return dt
+}
+
+func TestDeviceTokensRegister(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	dt := NewDeviceTokens()
+
+	// the upsert returns the id and the creation date of the token
+	d.rows = [][]driver.Value{{int64(9), int64(100)}}
+	device, err := dt.Register(5, "player-id", "ios", "startup.wav", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Id != 9 || device.CreatedAt != 100 || device.LastUsed == 0 {
+		t.Errorf("unexpected device %+v", device)
+	}
+
+	// registered again, the token keeps its id
+	d.rows = [][]driver.Value{{int64(9), int64(100)}}
+	if _, err := dt.Register(5, "player-id", "ios", "chime.wav", db); err != nil {
+		t.Fatal(err)
+	}
+
+	devices := dt.GetForUser(5)
+	if len(devices) != 1 || devices[0].Sound != "chime.wav" {
+		t.Errorf("expected the device updated, got %v", devices)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !strings.Contains(d.queries[0], `ON CONFLICT ("userId", "token") DO UPDATE`) {
+		t.Errorf("expected an upsert, got %s", d.queries[0])
+	}
+}
+
+func TestDeviceTokensRemove(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	dt := newDeviceTokens(
+		&DeviceToken{Id: 1, UserId: 5, Token: "reinstalled"},
+		&DeviceToken{Id: 2, UserId: 6, Token: "reinstalled"},
+		&DeviceToken{Id: 3, UserId: 5, Token: "other"},
+	)
+
+	d.rows = [][]driver.Value{{int64(1)}, {int64(2)}}
+	removed, err := dt.Remove("reinstalled", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if removed != 2 {
+		t.Errorf("expected the token removed from both users, got %d", removed)
+	}
+	if devices := dt.GetForUser(5); len(devices) != 1 || devices[0].Id != 3 {
+		t.Errorf("expected the other device of the user kept, got %v", devices)
+	}
+	if devices := dt.GetForUser(6); len(devices) != 0 {
+		t.Errorf("expected no device left to the other user, got %v", devices)
+	}
+}
+
+func TestDeviceTokensTouch(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	now := time.Now().Unix()
+	dt := newDeviceTokens(
+		&DeviceToken{Id: 1, UserId: 5, Token: "recent", LastUsed: now - 60},
+		&DeviceToken{Id: 2, UserId: 5, Token: "old", LastUsed: now - 7200},
+	)
+
+	for _, token := range []string{"recent", "old"} {
+		if err := dt.Touch(token, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if devices := dt.GetForUser(5); devices[0].Id != 1 || devices[0].LastUsed != now-60 || devices[1].Id != 2 || devices[1].LastUsed < now {
+		t.Errorf("expected only the old token touched, got %+v %+v", devices[0], devices[1])
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.queries) != 1 || d.args[0][1] != "old" {
+		t.Errorf("expected the use recorded at most hourly, got %v %v", d.queries, d.args)
+	}
+}
+
+func TestDeviceTokensPruneStale(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	dt := newDeviceTokens(
+		&DeviceToken{Id: 1, UserId: 5, Token: "dead"},
+		&DeviceToken{Id: 2, UserId: 5, Token: "alive", LastUsed: time.Now().Unix()},
+	)
+
+	d.rows = [][]driver.Value{{int64(1)}}
+	pruned, err := dt.PruneStale(30*24*time.Hour, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devices := dt.GetForUser(5); pruned != 1 || len(devices) != 1 || devices[0].Id != 2 {
+		t.Errorf("expected the dead install pruned, got %d %v", pruned, devices)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour).Unix()
+	if got := d.args[0][0].(int64); got < cutoff-5 || got > cutoff {
+		t.Errorf("expected the cutoff %d, got %d", cutoff, got)
+	}
+
+	if !strings.Contains(d.queries[0], `"platform" = 'web'`) || d.args[0][1] != apnsDeviceTokenPattern {
+		t.Errorf("expected only the web and APNs tokens pruned, got %s %v", d.queries[0], d.args[0])
+	}
+}
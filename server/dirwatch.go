@@ -21,7 +21,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"math"
 	"mime"
 	"os"
 	"path"
@@ -32,8 +31,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/fsnotify/fsnotify"
 )
 
 const (
@@ -59,9 +56,6 @@ type Dirwatch struct {
 	TalkgroupId uint64
 	controller  *Controller
 	dirs        map[string]bool
-	mutex       sync.Mutex
-	timers      map[string]*time.Timer
-	watcher     *fsnotify.Watcher
 }
 
 func NewDirwatch() *Dirwatch {
@@ -70,8 +64,6 @@ func NewDirwatch() *Dirwatch {
 		DeleteAfter: defaults.dirwatch.deleteAfter,
 		Kind:        defaults.dirwatch.kind,
 		dirs:        map[string]bool{},
-		mutex:       sync.Mutex{},
-		timers:      map[string]*time.Timer{},
 	}
 }
 
@@ -743,191 +735,100 @@ func (dirwatch *Dirwatch) parseMask(call *Call) {
 	}
 }
 
+// Start registers the dirwatch with the shared dispatcher of its controller
 func (dirwatch *Dirwatch) Start(controller *Controller) error {
-	var (
-		delay = time.Duration(math.Max(float64(dirwatch.Delay), 2000)) * time.Millisecond
-		err   error
-	)
-
 	if dirwatch.Disabled {
 		return nil
 	}
 
-	if dirwatch.watcher != nil {
-		return errors.New("dirwatch.start: already started")
-	}
-
 	dirwatch.controller = controller
-	dirwatch.dirs = map[string]bool{}
 
-	if dirwatch.watcher, err = fsnotify.NewWatcher(); err != nil {
+	dispatcher := controller.Dirwatches.dispatcher
+
+	if err := dispatcher.Add(dirwatch); err != nil {
 		return err
 	}
 
-	go func() {
-		logError := func(err error) {
-			controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatch.watcher: %v", err.Error()))
-		}
-
-		newTimer := func(eventName string) *time.Timer {
-			return time.AfterFunc(delay, func() {
-				dirwatch.mutex.Lock()
-				defer dirwatch.mutex.Unlock()
-
-				delete(dirwatch.timers, eventName)
-
-				if _, err := os.Stat(eventName); err == nil {
-					dirwatch.Ingest(eventName)
-				}
-			})
-		}
-
-		defer func() {
-			switch v := recover().(type) {
-			case error:
-				controller.Logs.LogEvent(LogLevelError, v.Error())
-			}
-
-			dirwatch.mutex.Lock()
-			defer dirwatch.mutex.Unlock()
-
-			for e, t := range dirwatch.timers {
-				t.Stop()
-				delete(dirwatch.timers, e)
-			}
-
-			if dirwatch.watcher != nil {
-				dirwatch.Start(controller)
-			}
-		}()
-
-		for {
-			if dirwatch.watcher == nil {
-				return
-			}
-
-			select {
-			case event, ok := <-dirwatch.watcher.Events:
-				if !ok {
-					return
-				}
-
-				switch event.Op {
-				case fsnotify.Create:
-					if dirwatch.isDir(event.Name) {
-						if err := dirwatch.walkDir(event.Name); err != nil {
-							logError(err)
-						}
-
-					} else {
-						dirwatch.mutex.Lock()
-						if dirwatch.timers[event.Name] != nil {
-							dirwatch.timers[event.Name].Stop()
-						}
-						dirwatch.timers[event.Name] = newTimer(event.Name)
-						dirwatch.mutex.Unlock()
-					}
-
-				case fsnotify.Remove:
-					if dirwatch.dirs[event.Name] {
-						if err := dirwatch.watcher.Remove(event.Name); err == nil {
-							delete(dirwatch.dirs, event.Name)
-						} else {
-							logError(err)
-						}
-					}
-
-				case fsnotify.Write:
-					dirwatch.mutex.Lock()
-					if dirwatch.timers[event.Name] != nil {
-						dirwatch.timers[event.Name].Stop()
-					}
-					dirwatch.timers[event.Name] = newTimer(event.Name)
-					dirwatch.mutex.Unlock()
+	if dirwatch.DeleteAfter {
+		go func() {
+			defer func() {
+				switch v := recover().(type) {
+				case error:
+					controller.Logs.LogEvent(LogLevelError, v.Error())
 				}
+			}()
 
-			case err, ok := <-dirwatch.watcher.Errors:
-				if ok {
-					logError(err)
+			// Files already present are ingested through the debouncer like new ones
+			if err := filepath.WalkDir(dirwatch.Directory, func(p string, entry fs.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					dispatcher.Schedule(p)
 				}
-
-				return
-			}
-		}
-	}()
-
-	go func() {
-		defer func() {
-			switch v := recover().(type) {
-			case error:
-				controller.Logs.LogEvent(LogLevelError, v.Error())
+				return err
+			}); err != nil {
+				controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatch.walkdir: %s", err.Error()))
 			}
 		}()
-
-		time.Sleep(delay)
-
-		if err := fs.WalkDir(os.DirFS(dirwatch.Directory), ".", func(p string, _ fs.DirEntry, err error) error {
-			fp := filepath.Join(dirwatch.Directory, p)
-
-			if dirwatch.isDir(fp) {
-				dirwatch.dirs[fp] = true
-				dirwatch.watcher.Add(fp)
-
-			} else if dirwatch.DeleteAfter {
-				dirwatch.Ingest(fp)
-			}
-
-			return err
-		}); err != nil {
-			controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatch.walkdir: %s", err.Error()))
-		}
-	}()
+	}
 
 	return nil
 }
 
+// Stop unregisters the dirwatch from the shared dispatcher
 func (dirwatch *Dirwatch) Stop() {
-	if dirwatch.watcher != nil {
-		w := dirwatch.watcher
-		dirwatch.watcher = nil
-		w.Close()
+	if dirwatch.controller != nil {
+		dirwatch.controller.Dirwatches.dispatcher.Remove(dirwatch)
 	}
 }
 
-func (dirwatch *Dirwatch) isDir(d string) bool {
-	if fi, err := os.Stat(d); err == nil {
-		if fi.IsDir() {
-			return true
-		}
+// contains reports whether p is inside the directory tree of the dirwatch
+func (dirwatch *Dirwatch) contains(p string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dirwatch.Directory), filepath.Clean(p))
+	if err != nil {
+		return false
 	}
 
-	return false
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
 }
 
-func (dirwatch *Dirwatch) walkDir(d string) error {
-	dfs := os.DirFS(d)
+// accepts reports whether a file should be routed to the dirwatch for ingestion
+func (dirwatch *Dirwatch) accepts(p string) bool {
+	if dirwatch.Disabled || !dirwatch.contains(p) {
+		return false
+	}
+
+	var ext string
 
-	return fs.WalkDir(dfs, ".", func(p string, _ fs.DirEntry, err error) error {
-		fp := filepath.Join(d, p)
-		if dirwatch.isDir(fp) {
-			if !dirwatch.dirs[fp] {
-				dirwatch.dirs[fp] = true
-				dirwatch.watcher.Add(fp)
-			}
+	switch dirwatch.Kind {
+	case DirwatchTypeTrunkRecorder:
+		ext = ".json"
+	case DirwatchTypeSdrTrunk:
+		ext = ".mp3"
+	case DirwatchTypeDSDPlus:
+		ext = ".mp3"
+		if len(dirwatch.Extension) > 0 {
+			ext = fmt.Sprintf(".%s", dirwatch.Extension)
 		}
-		return err
-	})
+	default:
+		ext = ".wav"
+		if len(dirwatch.Extension) > 0 {
+			ext = fmt.Sprintf(".%s", dirwatch.Extension)
+		}
+	}
+
+	return strings.EqualFold(path.Ext(p), ext)
 }
 
 type Dirwatches struct {
-	List  []*Dirwatch
-	mutex sync.Mutex
+	List       []*Dirwatch
+	dispatcher *DirwatchDispatcher
+	mutex      sync.Mutex
 }
 
 func NewDirwatches() *Dirwatches {
 	return &Dirwatches{
-		List:  []*Dirwatch{},
-		mutex: sync.Mutex{},
+		List:       []*Dirwatch{},
+		dispatcher: NewDirwatchDispatcher(),
+		mutex:      sync.Mutex{},
 	}
 }
 
@@ -995,6 +896,13 @@ func (dirwatches *Dirwatches) Read(db *Database) error {
 }
 
 func (dirwatches *Dirwatches) Start(controller *Controller) {
+	dirwatches.dispatcher.MaxDirwatches = controller.Config.MaxDirwatches
+
+	if err := dirwatches.dispatcher.Start(controller); err != nil {
+		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatches.start: %s", err.Error()))
+		return
+	}
+
 	for i := range dirwatches.List {
 		if err := dirwatches.List[i].Start(controller); err != nil {
 			controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatches.start: %s", err.Error()))
@@ -1003,9 +911,7 @@ func (dirwatches *Dirwatches) Start(controller *Controller) {
 }
 
 func (dirwatches *Dirwatches) Stop() {
-	for i := range dirwatches.List {
-		dirwatches.List[i].Stop()
-	}
+	dirwatches.dispatcher.Stop()
 	dirwatches.List = []*Dirwatch{}
 }
 
@@ -0,0 +1,366 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DirwatchDispatcher owns a single fsnotify watcher shared by all dirwatches.
+// Directories watched by several dirwatches are only watched once, and file
+// events are debounced per path so a file is routed once to every matching
+// dirwatch after it stops growing.
+type DirwatchDispatcher struct {
+	MaxDirwatches uint
+	controller    *Controller
+	dirs          map[string]int // watched directory -> number of dirwatches referencing it
+	dirwatches    []*Dirwatch
+	minDelay      time.Duration
+	ingest        func(dirwatch *Dirwatch, p string)
+	logError      func(err error)
+	mutex         sync.Mutex
+	pending       map[string]*dirwatchPendingFile
+	watcher       *fsnotify.Watcher
+	watch         func(dir string) error
+	unwatch       func(dir string) error
+}
+
+type dirwatchPendingFile struct {
+	modTime time.Time
+	size    int64
+	timer   *time.Timer
+}
+
+func NewDirwatchDispatcher() *DirwatchDispatcher {
+	dispatcher := &DirwatchDispatcher{
+		dirs:       map[string]int{},
+		dirwatches: []*Dirwatch{},
+		minDelay:   2 * time.Second,
+		mutex:      sync.Mutex{},
+		pending:    map[string]*dirwatchPendingFile{},
+	}
+
+	dispatcher.ingest = func(dirwatch *Dirwatch, p string) {
+		dirwatch.Ingest(p)
+	}
+
+	dispatcher.logError = func(err error) {
+		if dispatcher.controller != nil {
+			dispatcher.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("dirwatch.watcher: %v", err.Error()))
+		}
+	}
+
+	dispatcher.watch = func(dir string) error {
+		if dispatcher.watcher == nil {
+			return nil
+		}
+		return dispatcher.watcher.Add(dir)
+	}
+
+	dispatcher.unwatch = func(dir string) error {
+		if dispatcher.watcher == nil {
+			return nil
+		}
+		return dispatcher.watcher.Remove(dir)
+	}
+
+	return dispatcher
+}
+
+// Add registers a dirwatch and watches its directory tree, sharing directories already watched
+func (dispatcher *DirwatchDispatcher) Add(dirwatch *Dirwatch) error {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if dispatcher.MaxDirwatches > 0 && uint(len(dispatcher.dirwatches)) >= dispatcher.MaxDirwatches {
+		return fmt.Errorf("maximum of %d concurrent dirwatches reached, %s not watched", dispatcher.MaxDirwatches, dirwatch.Directory)
+	}
+
+	for _, d := range dispatcher.dirwatches {
+		if d == dirwatch {
+			return fmt.Errorf("dirwatch %s already started", dirwatch.Directory)
+		}
+	}
+
+	dirwatch.dirs = map[string]bool{}
+	dispatcher.dirwatches = append(dispatcher.dirwatches, dirwatch)
+
+	return dispatcher.addTree(dirwatch, dirwatch.Directory)
+}
+
+// Remove unregisters a dirwatch, releasing the directories no other dirwatch needs
+func (dispatcher *DirwatchDispatcher) Remove(dirwatch *Dirwatch) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for i, d := range dispatcher.dirwatches {
+		if d == dirwatch {
+			dispatcher.dirwatches = append(dispatcher.dirwatches[:i], dispatcher.dirwatches[i+1:]...)
+			break
+		}
+	}
+
+	for dir := range dirwatch.dirs {
+		dispatcher.releaseDir(dir)
+	}
+
+	dirwatch.dirs = map[string]bool{}
+}
+
+// Start opens the shared watcher and begins dispatching its events
+func (dispatcher *DirwatchDispatcher) Start(controller *Controller) error {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	dispatcher.controller = controller
+
+	if dispatcher.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dispatcher.watcher = watcher
+
+	go func() {
+		defer func() {
+			switch v := recover().(type) {
+			case error:
+				dispatcher.logError(v)
+			}
+		}()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				dispatcher.Notify(event.Name, event.Op)
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				dispatcher.logError(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the shared watcher and cancels every pending file
+func (dispatcher *DirwatchDispatcher) Stop() {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for p, pending := range dispatcher.pending {
+		pending.timer.Stop()
+		delete(dispatcher.pending, p)
+	}
+
+	for _, dirwatch := range dispatcher.dirwatches {
+		dirwatch.dirs = map[string]bool{}
+	}
+
+	dispatcher.dirwatches = []*Dirwatch{}
+	dispatcher.dirs = map[string]int{}
+
+	if dispatcher.watcher != nil {
+		w := dispatcher.watcher
+		dispatcher.watcher = nil
+		w.Close()
+	}
+}
+
+// Notify handles a file system event for path p
+func (dispatcher *DirwatchDispatcher) Notify(p string, op fsnotify.Op) {
+	switch {
+	case op&fsnotify.Create != 0:
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			dispatcher.mutex.Lock()
+			for _, dirwatch := range dispatcher.dirwatches {
+				if dirwatch.contains(p) {
+					if err := dispatcher.addTree(dirwatch, p); err != nil {
+						dispatcher.logError(err)
+					}
+				}
+			}
+			dispatcher.mutex.Unlock()
+			return
+		}
+		dispatcher.Schedule(p)
+
+	case op&fsnotify.Write != 0:
+		dispatcher.Schedule(p)
+
+	case op&fsnotify.Remove != 0:
+		dispatcher.mutex.Lock()
+		if _, ok := dispatcher.dirs[p]; ok {
+			for _, dirwatch := range dispatcher.dirwatches {
+				if dirwatch.dirs[p] {
+					delete(dirwatch.dirs, p)
+					dispatcher.releaseDir(p)
+				}
+			}
+		}
+		if pending := dispatcher.pending[p]; pending != nil {
+			pending.timer.Stop()
+			delete(dispatcher.pending, p)
+		}
+		dispatcher.mutex.Unlock()
+	}
+}
+
+// Schedule (re)arms the debounce timer of a file, whatever the number of dirwatches interested in it
+func (dispatcher *DirwatchDispatcher) Schedule(p string) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	delay := dispatcher.delayFor(p)
+	if delay == 0 {
+		return
+	}
+
+	pending := dispatcher.pending[p]
+	if pending == nil {
+		pending = &dirwatchPendingFile{}
+		dispatcher.pending[p] = pending
+	} else {
+		pending.timer.Stop()
+	}
+
+	if fi, err := os.Stat(p); err == nil {
+		pending.modTime = fi.ModTime()
+		pending.size = fi.Size()
+	}
+
+	pending.timer = time.AfterFunc(delay, func() {
+		dispatcher.settle(p, delay)
+	})
+}
+
+// settle ingests a file once its size and modification time stopped changing
+func (dispatcher *DirwatchDispatcher) settle(p string, delay time.Duration) {
+	dispatcher.mutex.Lock()
+
+	pending := dispatcher.pending[p]
+	if pending == nil {
+		dispatcher.mutex.Unlock()
+		return
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		delete(dispatcher.pending, p)
+		dispatcher.mutex.Unlock()
+		return
+	}
+
+	if fi.Size() != pending.size || !fi.ModTime().Equal(pending.modTime) {
+		// still being written, check again later
+		pending.modTime = fi.ModTime()
+		pending.size = fi.Size()
+		pending.timer = time.AfterFunc(delay, func() {
+			dispatcher.settle(p, delay)
+		})
+		dispatcher.mutex.Unlock()
+		return
+	}
+
+	delete(dispatcher.pending, p)
+
+	targets := []*Dirwatch{}
+	for _, dirwatch := range dispatcher.dirwatches {
+		if dirwatch.accepts(p) {
+			targets = append(targets, dirwatch)
+		}
+	}
+
+	dispatcher.mutex.Unlock()
+
+	for _, dirwatch := range targets {
+		if _, err := os.Stat(p); err != nil {
+			// removed by a previous dirwatch with deleteAfter
+			break
+		}
+		dispatcher.ingest(dirwatch, p)
+	}
+}
+
+// delayFor returns the longest debounce delay among the dirwatches accepting p, 0 if none does
+func (dispatcher *DirwatchDispatcher) delayFor(p string) time.Duration {
+	var delay time.Duration
+
+	for _, dirwatch := range dispatcher.dirwatches {
+		if dirwatch.accepts(p) {
+			d := time.Duration(dirwatch.Delay) * time.Millisecond
+			if d < dispatcher.minDelay {
+				d = dispatcher.minDelay
+			}
+			if d > delay {
+				delay = d
+			}
+		}
+	}
+
+	return delay
+}
+
+func (dispatcher *DirwatchDispatcher) addTree(dirwatch *Dirwatch, root string) error {
+	return fs.WalkDir(os.DirFS(root), ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			fp := filepath.Join(root, p)
+			if !dirwatch.dirs[fp] {
+				dirwatch.dirs[fp] = true
+				if dispatcher.dirs[fp] == 0 {
+					if err := dispatcher.watch(fp); err != nil {
+						return err
+					}
+				}
+				dispatcher.dirs[fp]++
+			}
+		}
+
+		return nil
+	})
+}
+
+func (dispatcher *DirwatchDispatcher) releaseDir(dir string) {
+	if dispatcher.dirs[dir] <= 1 {
+		delete(dispatcher.dirs, dir)
+		if err := dispatcher.unwatch(dir); err != nil {
+			dispatcher.logError(err)
+		}
+	} else {
+		dispatcher.dirs[dir]--
+	}
+}
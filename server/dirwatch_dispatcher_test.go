@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dirwatchIngestRecorder struct {
+	mutex sync.Mutex
+	calls map[*Dirwatch][]string
+	sizes []int64
+}
+
+func newTestDirwatchDispatcher(recorder *dirwatchIngestRecorder) *DirwatchDispatcher {
+	dispatcher := NewDirwatchDispatcher()
+	dispatcher.minDelay = 50 * time.Millisecond
+	dispatcher.logError = func(err error) {}
+	dispatcher.ingest = func(dirwatch *Dirwatch, p string) {
+		recorder.mutex.Lock()
+		defer recorder.mutex.Unlock()
+		recorder.calls[dirwatch] = append(recorder.calls[dirwatch], p)
+		if fi, err := os.Stat(p); err == nil {
+			recorder.sizes = append(recorder.sizes, fi.Size())
+		}
+	}
+	return dispatcher
+}
+
+func TestDirwatchDispatcherDebounceWaitsForWriteComplete(t *testing.T) {
+	dir := t.TempDir()
+	recorder := &dirwatchIngestRecorder{calls: map[*Dirwatch][]string{}}
+	dispatcher := newTestDirwatchDispatcher(recorder)
+
+	dirwatch := NewDirwatch()
+	dirwatch.Directory = dir
+	dirwatch.Delay = 0
+
+	if err := dispatcher.Add(dirwatch); err != nil {
+		t.Fatal(err)
+	}
+
+	p := filepath.Join(dir, "call.wav")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Schedule(p)
+
+	// keep writing without new events, as a slow recorder would
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		f.Write(make([]byte, 1024))
+	}
+	f.Close()
+
+	time.Sleep(300 * time.Millisecond)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if len(recorder.calls[dirwatch]) != 1 {
+		t.Fatalf("expected file to be ingested once, got %d", len(recorder.calls[dirwatch]))
+	}
+	if recorder.sizes[0] != 4096 {
+		t.Errorf("expected complete file of 4096 bytes to be ingested, got %d", recorder.sizes[0])
+	}
+}
+
+func TestDirwatchDispatcherSharedPathDedupe(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &dirwatchIngestRecorder{calls: map[*Dirwatch][]string{}}
+	dispatcher := newTestDirwatchDispatcher(recorder)
+
+	watched := map[string]int{}
+	dispatcher.watch = func(d string) error {
+		watched[d]++
+		return nil
+	}
+
+	first := NewDirwatch()
+	first.Directory = dir
+	second := NewDirwatch()
+	second.Directory = dir
+	other := NewDirwatch()
+	other.Directory = dir
+	other.Kind = DirwatchTypeSdrTrunk
+
+	for _, dirwatch := range []*Dirwatch{first, second, other} {
+		dirwatch.Delay = 0
+		if err := dispatcher.Add(dirwatch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for d, n := range watched {
+		if n != 1 {
+			t.Errorf("expected %s to be watched once, got %d", d, n)
+		}
+	}
+	if len(watched) != 2 {
+		t.Errorf("expected 2 watched directories, got %d", len(watched))
+	}
+
+	p := filepath.Join(dir, "sub", "call.wav")
+	if err := os.WriteFile(p, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// duplicate events for the same file collapse into a single ingestion per dirwatch
+	for i := 0; i < 5; i++ {
+		dispatcher.Schedule(p)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if len(recorder.calls[first]) != 1 || len(recorder.calls[second]) != 1 {
+		t.Errorf("expected one ingestion per matching dirwatch, got %d and %d", len(recorder.calls[first]), len(recorder.calls[second]))
+	}
+	if len(recorder.calls[other]) != 0 {
+		t.Errorf("expected sdr-trunk dirwatch not to receive wav files, got %d", len(recorder.calls[other]))
+	}
+}
+
+func TestDirwatchDispatcherMaxDirwatches(t *testing.T) {
+	dispatcher := newTestDirwatchDispatcher(&dirwatchIngestRecorder{calls: map[*Dirwatch][]string{}})
+	dispatcher.MaxDirwatches = 1
+
+	first := NewDirwatch()
+	first.Directory = t.TempDir()
+	second := NewDirwatch()
+	second.Directory = t.TempDir()
+
+	if err := dispatcher.Add(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Add(second); err == nil {
+		t.Error("expected second dirwatch to exceed the maximum")
+	}
+}
@@ -17,10 +17,12 @@ package main
 
 import (
 	"bytes"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -31,20 +33,83 @@ import (
 	"time"
 )
 
+const (
+	DOWNSTREAM_THROTTLE_QUEUE = "queue"
+	DOWNSTREAM_THROTTLE_DROP  = "drop"
+
+	DOWNSTREAM_PROTOCOL_V6 = "v6"
+	DOWNSTREAM_PROTOCOL_V7 = "v7"
+
+	// downstreamThrottleMaxQueued bounds how many calls may wait for a
+	// throttled downstream before further calls are dropped
+	downstreamThrottleMaxQueued = 100
+
+	// downstreamMaxRetries bounds the retries of a call refused by a downstream
+	downstreamMaxRetries = 5
+
+	// downstreamMaxTimeout bounds how long a send may take, in seconds
+	downstreamMaxTimeout = 600
+
+	defaultDownstreamMaxRetries   = 3
+	defaultDownstreamRetryBackoff = 1000 // milliseconds
+	defaultDownstreamTimeout      = 30   // seconds
+)
+
 type Downstream struct {
-	Id         uint64
-	Apikey     string
-	Disabled   bool
-	Name       string
-	Order      uint
-	Systems    any
-	Url        string
-	controller *Controller
+	Id              uint64
+	Apikey          string
+	AudioFormat     string // empty to send the audio as stored, otherwise one of downstreamAudioFormats
+	Disabled        bool
+	Headers         map[string]string // extra headers of the upload request, e.g. for an api gateway
+	MaxCallAge      uint              // seconds, older calls are not sent, e.g. during a backfill, 0 to send them all
+	MaxPerMinute    uint
+	MaxRetries      uint // retries of a call after a network or server error, at most downstreamMaxRetries
+	MinInterval     uint // milliseconds
+	Name            string
+	Order           uint
+	Protocol        string   // field names of the upload, "v6" for any receiver or "v7" for this server
+	RequireKeywords []string // when set, only the calls whose transcript has one of them are sent
+	RequireTones    bool     // only the calls with detected tones are sent
+	RetryBackoff    uint     // milliseconds before the first retry, doubled for each following one
+	Systems         any
+	ThrottleMode    string // "queue" or "drop"
+	TimeoutSeconds  uint   // 0 for defaultDownstreamTimeout, at most downstreamMaxTimeout
+	Url             string
+	controller      *Controller
+	throttle        *downstreamThrottle
+	transport       downstreamTransport
+}
+
+// downstreamTransport holds the client reused for every call sent to a downstream,
+// so connections to busy receivers are kept alive between calls
+type downstreamTransport struct {
+	mutex    sync.Mutex
+	client   *http.Client
+	settings downstreamTransportSettings
+}
+
+type downstreamTransportSettings struct {
+	http2           bool
+	idleConnTimeout uint // seconds
+	maxIdleConns    uint // per host
+	timeout         uint // seconds
+}
+
+// downstreamThrottle tracks the send slots reserved for a downstream, kept by the downstream of
+// the same id when the downstreams are reloaded
+type downstreamThrottle struct {
+	mutex  sync.Mutex
+	last   time.Time
+	queued int
+	slots  []time.Time
 }
 
 func NewDownstream(controller *Controller) *Downstream {
 	return &Downstream{
-		controller: controller,
+		MaxRetries:   defaultDownstreamMaxRetries,
+		RetryBackoff: defaultDownstreamRetryBackoff,
+		controller:   controller,
+		throttle:     &downstreamThrottle{},
 	}
 }
 
@@ -61,11 +126,57 @@ func (downstream *Downstream) FromMap(m map[string]any) *Downstream {
 		downstream.Apikey = v
 	}
 
+	switch v := m["audioFormat"].(type) {
+	case string:
+		if _, ok := downstreamAudioFormats[strings.ToLower(v)]; ok {
+			downstream.AudioFormat = strings.ToLower(v)
+		} else {
+			downstream.AudioFormat = ""
+		}
+	}
+
 	switch v := m["disabled"].(type) {
 	case bool:
 		downstream.Disabled = v
 	}
 
+	switch v := m["headers"].(type) {
+	case map[string]any:
+		downstream.Headers = map[string]string{}
+		for name, value := range v {
+			switch value := value.(type) {
+			case string:
+				if name = strings.TrimSpace(name); name != "" {
+					downstream.Headers[name] = value
+				}
+			}
+		}
+	}
+
+	switch v := m["maxCallAge"].(type) {
+	case float64:
+		if v > 0 {
+			downstream.MaxCallAge = uint(v)
+		} else {
+			downstream.MaxCallAge = 0
+		}
+	}
+
+	switch v := m["maxPerMinute"].(type) {
+	case float64:
+		downstream.MaxPerMinute = uint(v)
+	}
+
+	switch v := m["maxRetries"].(type) {
+	case float64:
+		downstream.MaxRetries = uint(v)
+	}
+
+	switch v := m["minInterval"].(type) {
+	case float64:
+		downstream.MinInterval = uint(v)
+	}
+
 	switch v := m["name"].(type) {
 	case string:
 		downstream.Name = v
@@ -76,8 +187,57 @@ func (downstream *Downstream) FromMap(m map[string]any) *Downstream {
 		downstream.Order = uint(v)
 	}
 
+	switch v := m["protocol"].(type) {
+	case string:
+		if strings.ToLower(v) == DOWNSTREAM_PROTOCOL_V7 {
+			downstream.Protocol = DOWNSTREAM_PROTOCOL_V7
+		} else {
+			downstream.Protocol = DOWNSTREAM_PROTOCOL_V6
+		}
+	}
+
+	switch v := m["requireKeywords"].(type) {
+	case []any:
+		downstream.RequireKeywords = []string{}
+		for _, keyword := range v {
+			switch keyword := keyword.(type) {
+			case string:
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					downstream.RequireKeywords = append(downstream.RequireKeywords, keyword)
+				}
+			}
+		}
+	}
+
+	switch v := m["requireTones"].(type) {
+	case bool:
+		downstream.RequireTones = v
+	}
+
+	switch v := m["retryBackoff"].(type) {
+	case float64:
+		downstream.RetryBackoff = uint(v)
+	}
+
 	downstream.Systems = m["systems"]
 
+	switch v := m["throttleMode"].(type) {
+	case string:
+		downstream.ThrottleMode = v
+	}
+
+	switch v := m["timeoutSeconds"].(type) {
+	case float64:
+		switch {
+		case v < 0:
+			downstream.TimeoutSeconds = 0
+		case v > downstreamMaxTimeout:
+			downstream.TimeoutSeconds = downstreamMaxTimeout
+		default:
+			downstream.TimeoutSeconds = uint(v)
+		}
+	}
+
 	switch v := m["url"].(type) {
 	case string:
 		downstream.Url = v
@@ -86,19 +246,89 @@ func (downstream *Downstream) FromMap(m map[string]any) *Downstream {
 	return downstream
 }
 
+// keepSettings restores the settings of the previous downstream missing from the map it was read from,
+// the admin saving the downstreams without the settings it doesn't know of
+func (downstream *Downstream) keepSettings(previous *Downstream, m map[string]any) {
+	if _, ok := m["audioFormat"]; !ok {
+		downstream.AudioFormat = previous.AudioFormat
+	}
+	if _, ok := m["headers"]; !ok {
+		downstream.Headers = previous.Headers
+	}
+	if _, ok := m["maxCallAge"]; !ok {
+		downstream.MaxCallAge = previous.MaxCallAge
+	}
+	if _, ok := m["maxPerMinute"]; !ok {
+		downstream.MaxPerMinute = previous.MaxPerMinute
+	}
+	if _, ok := m["maxRetries"]; !ok {
+		downstream.MaxRetries = previous.MaxRetries
+	}
+	if _, ok := m["minInterval"]; !ok {
+		downstream.MinInterval = previous.MinInterval
+	}
+	if _, ok := m["protocol"]; !ok {
+		downstream.Protocol = previous.Protocol
+	}
+	if _, ok := m["requireKeywords"]; !ok {
+		downstream.RequireKeywords = previous.RequireKeywords
+	}
+	if _, ok := m["requireTones"]; !ok {
+		downstream.RequireTones = previous.RequireTones
+	}
+	if _, ok := m["retryBackoff"]; !ok {
+		downstream.RetryBackoff = previous.RetryBackoff
+	}
+	if _, ok := m["throttleMode"]; !ok {
+		downstream.ThrottleMode = previous.ThrottleMode
+	}
+	if _, ok := m["timeoutSeconds"]; !ok {
+		downstream.TimeoutSeconds = previous.TimeoutSeconds
+	}
+}
+
 func (downstream *Downstream) HasAccess(call *Call) bool {
 	if downstream.Disabled {
 		return false
 	}
 
-	switch v := downstream.Systems.(type) {
+	return systemsFilterMatches(downstream.Systems, call.System.SystemRef, call.Talkgroup.TalkgroupRef)
+}
+
+// TooOld reports whether the call is older than the downstream takes, at the given time
+func (downstream *Downstream) TooOld(call *Call, now time.Time) bool {
+	if downstream.MaxCallAge == 0 || call.Timestamp.IsZero() {
+		return false
+	}
+
+	return now.Sub(call.Timestamp) > time.Duration(downstream.MaxCallAge)*time.Second
+}
+
+// Matches reports whether the call has the tones and one of the keywords the downstream requires,
+// the keywords being matched by the keyword matcher of the controller
+func (downstream *Downstream) Matches(call *Call) bool {
+	if downstream.RequireTones && !call.HasTones {
+		return false
+	}
+
+	if len(downstream.RequireKeywords) > 0 && len(downstream.controller.KeywordMatcher.MatchKeywords(call.Transcript, downstream.RequireKeywords, nil)) == 0 {
+		return false
+	}
+
+	return true
+}
+
+// systemsFilterMatches reports whether a talkgroup is selected by a systems filter, either "*" or a
+// list of systems by ref, each with "*" or the list of its talkgroup refs
+func systemsFilterMatches(filter any, systemRef uint, talkgroupRef uint) bool {
+	switch v := filter.(type) {
 	case []any:
 		for _, f := range v {
 			switch v := f.(type) {
 			case map[string]any:
 				switch id := v["id"].(type) {
 				case float64:
-					if id == float64(call.System.SystemRef) {
+					if id == float64(systemRef) {
 						switch tg := v["talkgroups"].(type) {
 						case string:
 							if tg == "*" {
@@ -108,7 +338,7 @@ func (downstream *Downstream) HasAccess(call *Call) bool {
 							for _, f := range tg {
 								switch tg := f.(type) {
 								case float64:
-									if tg == float64(call.Talkgroup.TalkgroupRef) {
+									if tg == float64(talkgroupRef) {
 										return true
 									}
 								}
@@ -131,26 +361,60 @@ func (downstream *Downstream) HasAccess(call *Call) bool {
 
 func (downstream *Downstream) MarshalJSON() ([]byte, error) {
 	m := map[string]any{
-		"id":       downstream.Id,
-		"apikey":   downstream.Apikey,
-		"disabled": downstream.Disabled,
-		"name":     downstream.Name,
-		"systems":  downstream.Systems,
-		"url":      downstream.Url,
+		"id":             downstream.Id,
+		"apikey":         downstream.Apikey,
+		"disabled":       downstream.Disabled,
+		"maxRetries":     downstream.MaxRetries,
+		"name":           downstream.Name,
+		"protocol":       downstream.protocol(),
+		"retryBackoff":   downstream.RetryBackoff,
+		"systems":        downstream.Systems,
+		"timeoutSeconds": downstream.timeout(),
+		"url":            downstream.Url,
 	}
 
 	if downstream.Order > 0 {
 		m["order"] = downstream.Order
 	}
 
+	if downstream.MaxCallAge > 0 {
+		m["maxCallAge"] = downstream.MaxCallAge
+	}
+
+	if downstream.MaxPerMinute > 0 {
+		m["maxPerMinute"] = downstream.MaxPerMinute
+	}
+
+	if downstream.MinInterval > 0 {
+		m["minInterval"] = downstream.MinInterval
+	}
+
+	if downstream.ThrottleMode != "" {
+		m["throttleMode"] = downstream.ThrottleMode
+	}
+
+	if len(downstream.RequireKeywords) > 0 {
+		m["requireKeywords"] = downstream.RequireKeywords
+	}
+
+	if downstream.RequireTones {
+		m["requireTones"] = downstream.RequireTones
+	}
+
+	if downstream.AudioFormat != "" {
+		m["audioFormat"] = downstream.AudioFormat
+	}
+
+	if len(downstream.Headers) > 0 {
+		m["headers"] = downstream.Headers
+	}
+
 	return json.Marshal(m)
 }
 
-func (downstream *Downstream) Send(call *Call) error {
-	var buf = bytes.Buffer{}
-
+func (downstream *Downstream) Send(call *Call, audio *downstreamAudioFile) error {
 	formatError := func(err error) error {
-		return fmt.Errorf("downstream.send: %s", err.Error())
+		return fmt.Errorf("downstream.send: %w", err)
 	}
 
 	if downstream.controller == nil {
@@ -161,40 +425,104 @@ func (downstream *Downstream) Send(call *Call) error {
 		return nil
 	}
 
+	res, err := downstream.upload(call, audio)
+	if err != nil {
+		return formatError(err)
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return formatError(&downstreamRetryableError{fmt.Errorf("bad status: %s", res.Status)})
+	} else if res.StatusCode != http.StatusOK {
+		return formatError(fmt.Errorf("bad status: %s", res.Status))
+	}
+
+	return nil
+}
+
+// upload posts the call to the call upload api of the downstream and returns the response, its
+// body already drained. Network errors are retryable.
+func (downstream *Downstream) upload(call *Call, audio *downstreamAudioFile) (*http.Response, error) {
+	var buf = bytes.Buffer{}
+
 	mw := multipart.NewWriter(&buf)
 
-	if w, err := mw.CreateFormFile("audio", call.AudioFilename); err == nil {
-		if _, err = w.Write(call.Audio); err != nil {
-			return formatError(err)
+	if w, err := mw.CreateFormFile("audio", audio.filename); err == nil {
+		if _, err = w.Write(audio.audio); err != nil {
+			return nil, err
 		}
 	} else {
-		return formatError(err)
+		return nil, err
+	}
+
+	if downstream.protocol() == DOWNSTREAM_PROTOCOL_V7 {
+		if err := downstream.writeV7Fields(mw, call, audio); err != nil {
+			return nil, err
+		}
+	} else if err := downstream.writeV6Fields(mw, call, audio); err != nil {
+		return nil, err
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
 	}
 
+	u, err := url.Parse(downstream.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = path.Join(u.Path, "/api/call-upload")
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range downstream.Headers {
+		req.Header.Set(name, value)
+	}
+
+	// set last, the multipart boundary must not be overridden
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	res, err := downstream.httpClient().Do(req)
+	if err != nil {
+		return nil, &downstreamRetryableError{err}
+	}
+
+	// drain the body so the connection goes back to the idle pool
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	return res, nil
+}
+
+// writeV6Fields writes the fields of the call with the v6 field names, for universal compatibility
+func (downstream *Downstream) writeV6Fields(mw *multipart.Writer, call *Call, audio *downstreamAudioFile) error {
 	// Use v6 field names for universal compatibility (v7 parser accepts both)
 	if w, err := mw.CreateFormField("audioName"); err == nil {
-		if _, err = w.Write([]byte(call.AudioFilename)); err != nil {
-			return formatError(err)
+		if _, err = w.Write([]byte(audio.filename)); err != nil {
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	if w, err := mw.CreateFormField("audioType"); err == nil {
-		if _, err = w.Write([]byte(call.AudioMime)); err != nil {
-			return formatError(err)
+		if _, err = w.Write([]byte(audio.mime)); err != nil {
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// pre v7 comptability
 	if w, err := mw.CreateFormField("dateTime"); err == nil {
 		if _, err = w.Write([]byte(call.Timestamp.Format(time.RFC3339))); err != nil {
-			return formatError(err)
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// Only send frequencies if there are valid ones (matching v6 behavior)
@@ -218,32 +546,32 @@ func (downstream *Downstream) Send(call *Call) error {
 		if w, err := mw.CreateFormField("frequencies"); err == nil {
 			if b, err := json.Marshal(validFreqs); err == nil {
 				if _, err = w.Write(b); err != nil {
-					return formatError(err)
+					return err
 				}
 			} else {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
 	if call.Frequency > 0 {
 		if w, err := mw.CreateFormField("frequency"); err == nil {
 			if _, err = w.Write([]byte(fmt.Sprintf("%d", call.Frequency))); err != nil {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
 	if w, err := mw.CreateFormField("key"); err == nil {
 		if _, err = w.Write([]byte(downstream.Apikey)); err != nil {
-			return formatError(err)
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// Only send patches if there are any (matching v6 behavior)
@@ -251,41 +579,41 @@ func (downstream *Downstream) Send(call *Call) error {
 		if w, err := mw.CreateFormField("patches"); err == nil {
 			if b, err := json.Marshal(call.Patches); err == nil {
 				if _, err = w.Write(b); err != nil {
-					return formatError(err)
+					return err
 				}
 			} else {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
 	if w, err := mw.CreateFormField("system"); err == nil {
 		if _, err = w.Write([]byte(fmt.Sprintf("%v", call.System.SystemRef))); err != nil {
-			return formatError(err)
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// Only send systemLabel if not empty (matching v6 switch behavior)
 	if call.System.Label != "" {
 		if w, err := mw.CreateFormField("systemLabel"); err == nil {
 			if _, err = w.Write([]byte(call.System.Label)); err != nil {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
 	if w, err := mw.CreateFormField("talkgroup"); err == nil {
 		if _, err = w.Write([]byte(fmt.Sprintf("%v", call.Talkgroup.TalkgroupRef))); err != nil {
-			return formatError(err)
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// v6 compatibility - only send talkgroupGroup if not empty (matching v6 switch behavior)
@@ -299,10 +627,10 @@ func (downstream *Downstream) Send(call *Call) error {
 	if talkgroupGroup != "" {
 		if w, err := mw.CreateFormField("talkgroupGroup"); err == nil {
 			if _, err = w.Write([]byte(talkgroupGroup)); err != nil {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
@@ -310,10 +638,10 @@ func (downstream *Downstream) Send(call *Call) error {
 	if call.Talkgroup.Label != "" {
 		if w, err := mw.CreateFormField("talkgroupLabel"); err == nil {
 			if _, err = w.Write([]byte(call.Talkgroup.Label)); err != nil {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
@@ -321,10 +649,10 @@ func (downstream *Downstream) Send(call *Call) error {
 	if call.Talkgroup.Name != "" {
 		if w, err := mw.CreateFormField("talkgroupName"); err == nil {
 			if _, err = w.Write([]byte(call.Talkgroup.Name)); err != nil {
-				return formatError(err)
+				return err
 			}
 		} else {
-			return formatError(err)
+			return err
 		}
 	}
 
@@ -333,20 +661,20 @@ func (downstream *Downstream) Send(call *Call) error {
 		if tag.Label != "" {
 			if w, err := mw.CreateFormField("talkgroupTag"); err == nil {
 				if _, err = w.Write([]byte(tag.Label)); err != nil {
-					return formatError(err)
+					return err
 				}
 			} else {
-				return formatError(err)
+				return err
 			}
 		}
 	}
 
 	if w, err := mw.CreateFormField("timestamp"); err == nil {
 		if _, err = w.Write([]byte(fmt.Sprintf("%d", call.Timestamp.UnixMilli()))); err != nil {
-			return formatError(err)
+			return err
 		}
 	} else {
-		return formatError(err)
+		return err
 	}
 
 	// DON'T send units field - v6 doesn't understand it
@@ -376,10 +704,10 @@ func (downstream *Downstream) Send(call *Call) error {
 		if firstValidUnit != nil {
 			if w, err := mw.CreateFormField("source"); err == nil {
 				if _, err = w.Write([]byte(fmt.Sprintf("%d", firstValidUnit.UnitRef))); err != nil {
-					return formatError(err)
+					return err
 				}
 			} else {
-				return formatError(err)
+				return err
 			}
 		}
 
@@ -398,43 +726,352 @@ func (downstream *Downstream) Send(call *Call) error {
 			if w, err := mw.CreateFormField("sources"); err == nil {
 				if b, err := json.Marshal(sources); err == nil {
 					if _, err = w.Write(b); err != nil {
-						return formatError(err)
+						return err
 					}
 				} else {
-					return formatError(err)
+					return err
 				}
 			} else {
-				return formatError(err)
+				return err
 			}
 		}
 	}
 	// If no valid units, DON'T send source/sources at all - let v6 store them as nil
 
-	if err := mw.Close(); err != nil {
-		return formatError(err)
+	return nil
+}
+
+// writeV7Fields writes the fields of the call with the native field names, so a receiver running
+// this server gets every unit and the frequencies as they are. The frequency field would replace
+// the frequencies on the receiver, so it is only sent in their absence.
+func (downstream *Downstream) writeV7Fields(mw *multipart.Writer, call *Call, audio *downstreamAudioFile) error {
+	field := func(name string, value string) error {
+		return mw.WriteField(name, value)
 	}
 
-	if u, err := url.Parse(downstream.Url); err == nil {
-		u.Path = path.Join(u.Path, "/api/call-upload")
+	jsonField := func(name string, value any) error {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return field(name, string(b))
+	}
 
-		c := http.Client{Timeout: 30 * time.Second}
+	if err := field("audioFilename", audio.filename); err != nil {
+		return err
+	}
 
-		if res, err := c.Post(u.String(), mw.FormDataContentType(), &buf); err == nil {
-			if res.StatusCode != http.StatusOK {
-				return formatError(fmt.Errorf("bad status: %s", res.Status))
-			}
+	if err := field("audioMime", audio.mime); err != nil {
+		return err
+	}
 
-		} else {
-			return formatError(err)
+	if len(call.Frequencies) > 0 {
+		frequencies := []map[string]any{}
+		for _, freq := range call.Frequencies {
+			frequencies = append(frequencies, map[string]any{
+				"dbm":        freq.Dbm,
+				"errorCount": freq.Errors,
+				"freq":       freq.Frequency,
+				"pos":        freq.Offset,
+				"spikeCount": freq.Spikes,
+			})
+		}
+		if err := jsonField("frequencies", frequencies); err != nil {
+			return err
 		}
+	} else if call.Frequency > 0 {
+		if err := field("frequency", fmt.Sprintf("%d", call.Frequency)); err != nil {
+			return err
+		}
+	}
 
-	} else {
-		return formatError(err)
+	if err := field("key", downstream.Apikey); err != nil {
+		return err
+	}
+
+	if len(call.Patches) > 0 {
+		if err := jsonField("patches", call.Patches); err != nil {
+			return err
+		}
+	}
+
+	if call.SiteRef > 0 {
+		if err := field("site", fmt.Sprintf("%d", call.SiteRef)); err != nil {
+			return err
+		}
+	}
+
+	if err := field("system", fmt.Sprintf("%d", call.System.SystemRef)); err != nil {
+		return err
+	}
+
+	if call.System.Label != "" {
+		if err := field("systemLabel", call.System.Label); err != nil {
+			return err
+		}
+	}
+
+	if err := field("talkgroup", fmt.Sprintf("%d", call.Talkgroup.TalkgroupRef)); err != nil {
+		return err
+	}
+
+	labels := []string{}
+	for _, id := range call.Talkgroup.GroupIds {
+		if group, ok := downstream.controller.Groups.GetGroupById(id); ok {
+			labels = append(labels, group.Label)
+		}
+	}
+	if len(labels) > 0 {
+		if err := field("talkgroupGroups", strings.Join(labels, ",")); err != nil {
+			return err
+		}
+	}
+
+	if call.Talkgroup.Label != "" {
+		if err := field("talkgroupLabel", call.Talkgroup.Label); err != nil {
+			return err
+		}
+	}
+
+	if call.Talkgroup.Name != "" {
+		if err := field("talkgroupName", call.Talkgroup.Name); err != nil {
+			return err
+		}
+	}
+
+	if tag, ok := downstream.controller.Tags.GetTagById(call.Talkgroup.TagId); ok && tag.Label != "" {
+		if err := field("talkgroupTag", tag.Label); err != nil {
+			return err
+		}
+	}
+
+	if err := field("timestamp", fmt.Sprintf("%d", call.Timestamp.UnixMilli())); err != nil {
+		return err
+	}
+
+	if len(call.Units) > 0 {
+		units := []map[string]any{}
+		for _, unit := range call.Units {
+			units = append(units, map[string]any{
+				"offset":  unit.Offset,
+				"unitRef": unit.UnitRef,
+			})
+		}
+		if err := jsonField("units", units); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// downstreamRetryableError is a send failure worth retrying, a network error or a server error
+type downstreamRetryableError struct {
+	err error
+}
+
+func (e *downstreamRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *downstreamRetryableError) Unwrap() error {
+	return e.err
+}
+
+func isDownstreamRetryable(err error) bool {
+	var retryable *downstreamRetryableError
+	return errors.As(err, &retryable)
+}
+
+// retryDelay returns the wait before the given retry, starting at 1, with an exponential backoff
+func (downstream *Downstream) retryDelay(retry uint) time.Duration {
+	backoff := downstream.RetryBackoff
+	if backoff == 0 {
+		backoff = defaultDownstreamRetryBackoff
+	}
+
+	return time.Duration(backoff) * time.Millisecond << (retry - 1)
+}
+
+// retries returns how many times a refused call is sent again
+func (downstream *Downstream) retries() uint {
+	if downstream.MaxRetries > downstreamMaxRetries {
+		return downstreamMaxRetries
+	}
+	return downstream.MaxRetries
+}
+
+// protocol returns the field names the calls are sent with, v6 unless v7 is set
+func (downstream *Downstream) protocol() string {
+	if downstream.Protocol == DOWNSTREAM_PROTOCOL_V7 {
+		return DOWNSTREAM_PROTOCOL_V7
+	}
+	return DOWNSTREAM_PROTOCOL_V6
+}
+
+// timeout returns how many seconds a send may take before it is abandoned
+func (downstream *Downstream) timeout() uint {
+	switch {
+	case downstream.TimeoutSeconds == 0:
+		return defaultDownstreamTimeout
+	case downstream.TimeoutSeconds > downstreamMaxTimeout:
+		return downstreamMaxTimeout
+	}
+	return downstream.TimeoutSeconds
+}
+
+// retry sends the call again with an exponential backoff until it is accepted, the
+// downstream refuses it for good or the retries are exhausted, and returns the last error
+func (downstream *Downstream) retry(call *Call, audio *downstreamAudioFile, logEvent func(logLevel string, message string)) error {
+	var err error
+
+	retries := downstream.retries()
+
+	for retry := uint(1); retry <= retries; retry++ {
+		time.Sleep(downstream.retryDelay(retry))
+
+		logEvent(LogLevelInfo, fmt.Sprintf("retry attempt %d of %d", retry, retries))
+
+		if err = downstream.Send(call, audio); err == nil {
+			logEvent(LogLevelInfo, fmt.Sprintf("success after %d retries", retry))
+			return nil
+		}
+
+		if !isDownstreamRetryable(err) {
+			break
+		}
+	}
+
+	logEvent(LogLevelError, fmt.Sprintf("%v, giving up after retrying", err))
+
+	return err
+}
+
+// httpClient returns the client of the downstream, rebuilt when the transport options change
+func (downstream *Downstream) httpClient() *http.Client {
+	settings := downstreamTransportSettings{
+		http2:           defaults.options.downstreamHttp2,
+		idleConnTimeout: defaults.options.downstreamIdleConnTimeout,
+		maxIdleConns:    defaults.options.downstreamMaxIdleConns,
+		timeout:         downstream.timeout(),
+	}
+
+	if downstream.controller != nil && downstream.controller.Options != nil {
+		settings = downstreamTransportSettings{
+			http2:           downstream.controller.Options.DownstreamHttp2,
+			idleConnTimeout: downstream.controller.Options.DownstreamIdleConnTimeout,
+			maxIdleConns:    downstream.controller.Options.DownstreamMaxIdleConns,
+			timeout:         downstream.timeout(),
+		}
+	}
+
+	t := &downstream.transport
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.client == nil || t.settings != settings {
+		if t.client != nil {
+			t.client.CloseIdleConnections()
+		}
+		t.client = newDownstreamClient(settings)
+		t.settings = settings
+	}
+
+	return t.client
+}
+
+// newDownstreamClient builds a client with the given transport settings, zero values keep the go defaults
+func newDownstreamClient(settings downstreamTransportSettings) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.ForceAttemptHTTP2 = settings.http2
+	if !settings.http2 {
+		// a non nil empty map is how net/http is told not to negotiate http/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if settings.maxIdleConns > 0 {
+		transport.MaxIdleConnsPerHost = int(settings.maxIdleConns)
+		if transport.MaxIdleConns < transport.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = transport.MaxIdleConnsPerHost
+		}
+	}
+
+	if settings.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(settings.idleConnTimeout) * time.Second
+	}
+
+	timeout := settings.timeout
+	if timeout == 0 {
+		timeout = defaultDownstreamTimeout
+	}
+
+	return &http.Client{Timeout: time.Duration(timeout) * time.Second, Transport: transport}
+}
+
+// Reserve books a send slot for a call arriving at now. It returns how long
+// the caller must wait before sending, or false when the call must be dropped
+// because the downstream is throttled and its policy (or queue) forbids waiting.
+func (downstream *Downstream) Reserve(now time.Time) (time.Duration, bool) {
+	if downstream.MinInterval == 0 && downstream.MaxPerMinute == 0 {
+		return 0, true
+	}
+
+	t := downstream.throttle
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	slot := now
+
+	if downstream.MinInterval > 0 && !t.last.IsZero() {
+		if next := t.last.Add(time.Duration(downstream.MinInterval) * time.Millisecond); next.After(slot) {
+			slot = next
+		}
+	}
+
+	if downstream.MaxPerMinute > 0 {
+		// forget slots that can no longer affect the window of this call
+		for len(t.slots) > 0 && !t.slots[0].After(now.Add(-time.Minute)) {
+			t.slots = t.slots[1:]
+		}
+
+		if n := len(t.slots); n >= int(downstream.MaxPerMinute) {
+			if next := t.slots[n-int(downstream.MaxPerMinute)].Add(time.Minute); next.After(slot) {
+				slot = next
+			}
+		}
+	}
+
+	wait := slot.Sub(now)
+
+	if wait > 0 {
+		if downstream.ThrottleMode == DOWNSTREAM_THROTTLE_DROP || t.queued >= downstreamThrottleMaxQueued {
+			return 0, false
+		}
+		t.queued++
+	}
+
+	t.last = slot
+
+	if downstream.MaxPerMinute > 0 {
+		t.slots = append(t.slots, slot)
+	}
+
+	return wait, true
+}
+
+// release frees a queued slot previously granted by Reserve with a wait
+func (downstream *Downstream) release() {
+	downstream.throttle.mutex.Lock()
+	defer downstream.throttle.mutex.Unlock()
+
+	if downstream.throttle.queued > 0 {
+		downstream.throttle.queued--
+	}
+}
+
 type Downstreams struct {
 	List       []*Downstream
 	controller *Controller
@@ -453,12 +1090,21 @@ func (downstreams *Downstreams) FromMap(f []any) *Downstreams {
 	downstreams.mutex.Lock()
 	defer downstreams.mutex.Unlock()
 
+	previous := map[uint64]*Downstream{}
+	for _, downstream := range downstreams.List {
+		previous[downstream.Id] = downstream
+	}
+
 	downstreams.List = []*Downstream{}
 
 	for _, r := range f {
 		switch m := r.(type) {
 		case map[string]any:
 			downstream := NewDownstream(downstreams.controller).FromMap(m)
+			if p, ok := previous[downstream.Id]; ok && downstream.Id > 0 {
+				downstream.keepSettings(p, m)
+				downstream.throttle = p.throttle
+			}
 			downstreams.List = append(downstreams.List, downstream)
 		}
 	}
@@ -476,23 +1122,30 @@ func (downstreams *Downstreams) Read(db *Database) error {
 	downstreams.mutex.Lock()
 	defer downstreams.mutex.Unlock()
 
+	throttles := map[uint64]*downstreamThrottle{}
+	for _, downstream := range downstreams.List {
+		throttles[downstream.Id] = downstream.throttle
+	}
+
 	downstreams.List = []*Downstream{}
 
 	formatError := downstreams.errorFormatter("read")
 
-	query = `SELECT "downstreamId", "apikey", "disabled", "name", "order", "systems", "url" FROM "downstreams"`
+	query = `SELECT "downstreamId", "apikey", "audioFormat", "disabled", "headers", "maxCallAge", "maxPerMinute", "maxRetries", "minInterval", "name", "order", "protocol", "requireKeywords", "requireTones", "retryBackoff", "systems", "throttleMode", "timeoutSeconds", "url" FROM "downstreams"`
 	if rows, err = db.Sql.Query(query); err != nil {
 		return formatError(err, query)
 	}
 
 	for rows.Next() {
 		var (
-			downstream = NewDownstream(downstreams.controller)
-			name       sql.NullString
-			systems    string
+			downstream      = NewDownstream(downstreams.controller)
+			headers         string
+			name            sql.NullString
+			requireKeywords string
+			systems         string
 		)
 
-		if err = rows.Scan(&downstream.Id, &downstream.Apikey, &downstream.Disabled, &name, &downstream.Order, &systems, &downstream.Url); err != nil {
+		if err = rows.Scan(&downstream.Id, &downstream.Apikey, &downstream.AudioFormat, &downstream.Disabled, &headers, &downstream.MaxCallAge, &downstream.MaxPerMinute, &downstream.MaxRetries, &downstream.MinInterval, &name, &downstream.Order, &downstream.Protocol, &requireKeywords, &downstream.RequireTones, &downstream.RetryBackoff, &systems, &downstream.ThrottleMode, &downstream.TimeoutSeconds, &downstream.Url); err != nil {
 			break
 		}
 
@@ -500,6 +1153,18 @@ func (downstreams *Downstreams) Read(db *Database) error {
 			downstream.Name = name.String
 		}
 
+		if throttle, ok := throttles[downstream.Id]; ok {
+			downstream.throttle = throttle
+		}
+
+		if len(headers) > 0 {
+			json.Unmarshal([]byte(headers), &downstream.Headers)
+		}
+
+		if len(requireKeywords) > 0 {
+			json.Unmarshal([]byte(requireKeywords), &downstream.RequireKeywords)
+		}
+
 		if len(systems) > 0 {
 			json.Unmarshal([]byte(systems), &downstream.Systems)
 		}
@@ -520,19 +1185,119 @@ func (downstreams *Downstreams) Read(db *Database) error {
 	return nil
 }
 
+// downstreamStage is the point of the processing of a call at which a downstream is sent the call,
+// once what it requires of the call is known
+type downstreamStage int
+
+const (
+	downstreamStageCall       downstreamStage = iota // as soon as the call is written
+	downstreamStageTones                             // once its tones are detected
+	downstreamStageTranscript                        // once its transcript is known
+)
+
+// stage returns the stage at which the downstream is sent the calls
+func (downstream *Downstream) stage() downstreamStage {
+	switch {
+	case len(downstream.RequireKeywords) > 0:
+		return downstreamStageTranscript
+	case downstream.RequireTones:
+		return downstreamStageTones
+	default:
+		return downstreamStageCall
+	}
+}
+
 func (downstreams *Downstreams) Send(controller *Controller, call *Call) {
+	downstreams.send(controller, call, newDownstreamAudio(call, controller.FFMpeg.Transcode), downstreamStageCall)
+}
+
+// SendToned sends a call to the downstreams requiring tones but no keywords, once its tones are detected.
+// These downstreams are skipped when the call is first sent, its tones being yet to come.
+func (downstreams *Downstreams) SendToned(controller *Controller, call *Call) {
+	if call.System == nil || call.Talkgroup == nil {
+		return
+	}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, controller.FFMpeg.Transcode), downstreamStageTones)
+}
+
+// SendTranscribed sends a call to the downstreams requiring keywords, once its transcript is known.
+// These downstreams are skipped when the call is first sent, its transcript being yet to come.
+func (downstreams *Downstreams) SendTranscribed(controller *Controller, call *Call) {
+	if call.System == nil || call.Talkgroup == nil {
+		return
+	}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, controller.FFMpeg.Transcode), downstreamStageTranscript)
+}
+
+func (downstreams *Downstreams) send(controller *Controller, call *Call, audio *downstreamAudio, stage downstreamStage) {
+	// Nothing to forward once the audio is archived, rather than sending an empty file
+	if call.audioMissing() {
+		if len(downstreams.List) > 0 {
+			controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("downstream: system=%d talkgroup=%d call %d skipped, %s", call.System.SystemRef, call.Talkgroup.TalkgroupRef, call.Id, callAudioArchivedError))
+		}
+		return
+	}
+
 	for _, downstream := range downstreams.List {
 		logEvent := func(logLevel string, message string) {
 			controller.Logs.LogEvent(logLevel, fmt.Sprintf("downstream: system=%d talkgroup=%d file=%s to %s %s", call.System.SystemRef, call.Talkgroup.TalkgroupRef, call.AudioFilename, downstream.Url, message))
 		}
 
-		if downstream.HasAccess(call) {
-			if err := downstream.Send(call); err == nil {
+		if !downstream.HasAccess(call) {
+			continue
+		}
+
+		if downstream.stage() != stage || !downstream.Matches(call) {
+			continue
+		}
+
+		if downstream.TooOld(call, time.Now()) {
+			logEvent(LogLevelInfo, fmt.Sprintf("skipped, call older than %ds", downstream.MaxCallAge))
+			continue
+		}
+
+		wait, ok := downstream.Reserve(time.Now())
+		if !ok {
+			logEvent(LogLevelWarn, "dropped, rate limit exceeded")
+			continue
+		}
+
+		send := func(downstream *Downstream, wait time.Duration) {
+			if wait > 0 {
+				time.Sleep(wait)
+				downstream.release()
+			}
+
+			file, err := audio.forFormat(downstream.AudioFormat)
+			if err != nil {
+				logEvent(LogLevelWarn, fmt.Sprintf("%v, sending the original audio", err))
+			}
+
+			if err := downstream.Send(call, file); err == nil {
 				logEvent(LogLevelInfo, "success")
+			} else if isDownstreamRetryable(err) && downstream.retries() > 0 {
+				logEvent(LogLevelWarn, fmt.Sprintf("%v, retrying in %v", err, downstream.retryDelay(1)))
+				go func() {
+					if err := downstream.retry(call, file, logEvent); isDownstreamRetryable(err) {
+						downstreams.enqueue(call, downstream, logEvent)
+					}
+				}()
+			} else if isDownstreamRetryable(err) {
+				logEvent(LogLevelError, err.Error())
+				downstreams.enqueue(call, downstream, logEvent)
 			} else {
 				logEvent(LogLevelError, err.Error())
 			}
 		}
+
+		if wait > 0 {
+			logEvent(LogLevelInfo, fmt.Sprintf("queued for %v, rate limit exceeded", wait.Round(time.Millisecond)))
+			go send(downstream, wait)
+		} else {
+			send(downstream, 0)
+		}
 	}
 }
 
@@ -597,10 +1362,24 @@ func (downstreams *Downstreams) Write(db *Database) error {
 
 	for _, downstream := range downstreams.List {
 		var (
-			count   uint
-			systems string
+			count           uint
+			headers         string
+			requireKeywords string
+			systems         string
 		)
 
+		if len(downstream.Headers) > 0 {
+			if b, err := json.Marshal(downstream.Headers); err == nil {
+				headers = string(b)
+			}
+		}
+
+		if len(downstream.RequireKeywords) > 0 {
+			if b, err := json.Marshal(downstream.RequireKeywords); err == nil {
+				requireKeywords = string(b)
+			}
+		}
+
 		if downstream.Systems != nil {
 			if b, err := json.Marshal(downstream.Systems); err == nil {
 				systems = string(b)
@@ -617,17 +1396,23 @@ func (downstreams *Downstreams) Write(db *Database) error {
 		if count == 0 {
 			if downstream.Id > 0 {
 				// Preserve the explicit ID when inserting
-				query = fmt.Sprintf(`INSERT INTO "downstreams" ("downstreamId", "apikey", "disabled", "name", "order", "systems", "url") VALUES (%d, '%s', %t, '%s', %d, '%s', '%s')`, downstream.Id, escapeQuotes(downstream.Apikey), downstream.Disabled, escapeQuotes(downstream.Name), downstream.Order, systems, escapeQuotes(downstream.Url))
+				query = fmt.Sprintf(`INSERT INTO "downstreams" ("downstreamId", "apikey", "audioFormat", "disabled", "headers", "maxCallAge", "maxPerMinute", "maxRetries", "minInterval", "name", "order", "protocol", "requireKeywords", "requireTones", "retryBackoff", "systems", "throttleMode", "timeoutSeconds", "url") VALUES (%d, '%s', '%s', %t, '%s', %d, %d, %d, %d, '%s', %d, '%s', '%s', %t, %d, '%s', '%s', %d, '%s')`, downstream.Id, escapeQuotes(downstream.Apikey), escapeQuotes(downstream.AudioFormat), downstream.Disabled, escapeQuotes(headers), downstream.MaxCallAge, downstream.MaxPerMinute, downstream.MaxRetries, downstream.MinInterval, escapeQuotes(downstream.Name), downstream.Order, downstream.protocol(), escapeQuotes(requireKeywords), downstream.RequireTones, downstream.RetryBackoff, systems, escapeQuotes(downstream.ThrottleMode), downstream.TimeoutSeconds, escapeQuotes(downstream.Url))
 			} else {
 				// Let database assign auto-increment ID
-				query = fmt.Sprintf(`INSERT INTO "downstreams" ("apikey", "disabled", "name", "order", "systems", "url") VALUES ('%s', %t, '%s', %d, '%s', '%s')`, escapeQuotes(downstream.Apikey), downstream.Disabled, escapeQuotes(downstream.Name), downstream.Order, systems, escapeQuotes(downstream.Url))
+				query = fmt.Sprintf(`INSERT INTO "downstreams" ("apikey", "audioFormat", "disabled", "headers", "maxCallAge", "maxPerMinute", "maxRetries", "minInterval", "name", "order", "protocol", "requireKeywords", "requireTones", "retryBackoff", "systems", "throttleMode", "timeoutSeconds", "url") VALUES ('%s', '%s', %t, '%s', %d, %d, %d, %d, '%s', %d, '%s', '%s', %t, %d, '%s', '%s', %d, '%s')`, escapeQuotes(downstream.Apikey), escapeQuotes(downstream.AudioFormat), downstream.Disabled, escapeQuotes(headers), downstream.MaxCallAge, downstream.MaxPerMinute, downstream.MaxRetries, downstream.MinInterval, escapeQuotes(downstream.Name), downstream.Order, downstream.protocol(), escapeQuotes(requireKeywords), downstream.RequireTones, downstream.RetryBackoff, systems, escapeQuotes(downstream.ThrottleMode), downstream.TimeoutSeconds, escapeQuotes(downstream.Url))
 			}
-			if _, err = tx.Exec(query); err != nil {
+			err = execSavepoint(tx, query)
+			if isUniqueViolation(err) && downstream.Id > 0 {
+				// Downstream with this ID was inserted in the meantime, update it instead
+				count = 1
+				err = nil
+			} else if err != nil {
 				break
 			}
+		}
 
-		} else {
-			query = fmt.Sprintf(`UPDATE "downstreams" SET "apikey" = '%s', "disabled" = %t, "name" = '%s', "order" = %d, "systems" = '%s', "url" = '%s' WHERE "downstreamId" = %d`, escapeQuotes(downstream.Apikey), downstream.Disabled, escapeQuotes(downstream.Name), downstream.Order, systems, escapeQuotes(downstream.Url), downstream.Id)
+		if count > 0 {
+			query = fmt.Sprintf(`UPDATE "downstreams" SET "apikey" = '%s', "audioFormat" = '%s', "disabled" = %t, "headers" = '%s', "maxCallAge" = %d, "maxPerMinute" = %d, "maxRetries" = %d, "minInterval" = %d, "name" = '%s', "order" = %d, "protocol" = '%s', "requireKeywords" = '%s', "requireTones" = %t, "retryBackoff" = %d, "systems" = '%s', "throttleMode" = '%s', "timeoutSeconds" = %d, "url" = '%s' WHERE "downstreamId" = %d`, escapeQuotes(downstream.Apikey), escapeQuotes(downstream.AudioFormat), downstream.Disabled, escapeQuotes(headers), downstream.MaxCallAge, downstream.MaxPerMinute, downstream.MaxRetries, downstream.MinInterval, escapeQuotes(downstream.Name), downstream.Order, downstream.protocol(), escapeQuotes(requireKeywords), downstream.RequireTones, downstream.RetryBackoff, systems, escapeQuotes(downstream.ThrottleMode), downstream.TimeoutSeconds, escapeQuotes(downstream.Url), downstream.Id)
 			if _, err = tx.Exec(query); err != nil {
 				break
 			}
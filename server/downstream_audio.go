@@ -0,0 +1,147 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+)
+
+type downstreamAudioFormat struct {
+	mimes []string // first one is sent with transcoded audio
+	args  []string
+}
+
+// downstreamAudioFormats are the audio formats a downstream may require, by file extension
+var downstreamAudioFormats = map[string]downstreamAudioFormat{
+	"m4a": {mimes: []string{"audio/mp4", "audio/m4a", "audio/x-m4a"}, args: []string{"-c:a", "aac", "-b:a", "32k", "-movflags", "frag_keyframe+empty_moov", "-f", "ipod"}},
+	"mp3": {mimes: []string{"audio/mpeg", "audio/mp3"}, args: []string{"-c:a", "libmp3lame", "-b:a", "32k", "-f", "mp3"}},
+	"wav": {mimes: []string{"audio/wav", "audio/x-wav", "audio/wave"}, args: []string{"-c:a", "pcm_s16le", "-f", "wav"}},
+}
+
+// audioHasFormat reports whether the audio, known by its filename and mime type, is already in the given format
+func audioHasFormat(filename string, mime string, format string) bool {
+	f, ok := downstreamAudioFormats[format]
+	if !ok {
+		return true
+	}
+
+	if strings.EqualFold(strings.TrimPrefix(path.Ext(filename), "."), format) {
+		return true
+	}
+
+	for _, m := range f.mimes {
+		if strings.EqualFold(mime, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transcode converts the audio to one of the downstreamAudioFormats
+func (ffmpeg *FFMpeg) Transcode(audio []byte, format string) ([]byte, error) {
+	f, ok := downstreamAudioFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio format %s", format)
+	}
+
+	if !ffmpeg.available {
+		return nil, errors.New("ffmpeg is not available")
+	}
+
+	cmd := exec.Command("ffmpeg", append([]string{"-i", "-"}, append(f.args, "-")...)...)
+	cmd.Stdin = bytes.NewReader(audio)
+
+	stdout := bytes.NewBuffer([]byte(nil))
+	cmd.Stdout = stdout
+
+	stderr := bytes.NewBuffer([]byte(nil))
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// downstreamAudio is the audio of a call as sent to the downstreams, transcoded at most once per format
+// whatever the number of downstreams requiring it. The call audio itself is never modified.
+type downstreamAudio struct {
+	call       *Call
+	mutex      sync.Mutex
+	transcode  func(audio []byte, format string) ([]byte, error)
+	transcoded map[string]*downstreamAudioFile
+	failed     map[string]error
+}
+
+type downstreamAudioFile struct {
+	audio    []byte
+	filename string
+	mime     string
+}
+
+func newDownstreamAudio(call *Call, transcode func(audio []byte, format string) ([]byte, error)) *downstreamAudio {
+	return &downstreamAudio{
+		call:       call,
+		transcode:  transcode,
+		transcoded: map[string]*downstreamAudioFile{},
+		failed:     map[string]error{},
+	}
+}
+
+// forFormat returns the call audio in the given format. When the transcoding fails, the original audio
+// is returned along with the error.
+func (da *downstreamAudio) forFormat(format string) (*downstreamAudioFile, error) {
+	original := &downstreamAudioFile{audio: da.call.Audio, filename: da.call.AudioFilename, mime: da.call.AudioMime}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || audioHasFormat(da.call.AudioFilename, da.call.AudioMime, format) {
+		return original, nil
+	}
+
+	da.mutex.Lock()
+	defer da.mutex.Unlock()
+
+	if file, ok := da.transcoded[format]; ok {
+		return file, nil
+	}
+	if err, ok := da.failed[format]; ok {
+		return original, err
+	}
+
+	audio, err := da.transcode(da.call.Audio, format)
+	if err != nil {
+		err = fmt.Errorf("audio transcoding to %s failed: %v", format, err)
+		da.failed[format] = err
+		return original, err
+	}
+
+	file := &downstreamAudioFile{
+		audio:    audio,
+		filename: fmt.Sprintf("%v.%s", strings.TrimSuffix(da.call.AudioFilename, path.Ext(da.call.AudioFilename)), format),
+		mime:     downstreamAudioFormats[format].mimes[0],
+	}
+	da.transcoded[format] = file
+
+	return file, nil
+}
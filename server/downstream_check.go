@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultDownstreamTestSystemRef and defaultDownstreamTestTalkgroupRef are the refs of the test
+	// call when none are given, out of the way of the usual system and talkgroup refs
+	defaultDownstreamTestSystemRef    = 9999
+	defaultDownstreamTestTalkgroupRef = 9999
+)
+
+// DownstreamTestResult is the outcome of a test call sent to a downstream
+type DownstreamTestResult struct {
+	Success    bool   `json:"success"`
+	Status     int    `json:"status,omitempty"`
+	StatusText string `json:"statusText,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// newDownstreamTestCall returns a synthetic call of one second of silence for the given system and talkgroup
+func newDownstreamTestCall(systemRef uint, talkgroupRef uint, now time.Time) *Call {
+	if systemRef == 0 {
+		systemRef = defaultDownstreamTestSystemRef
+	}
+
+	if talkgroupRef == 0 {
+		talkgroupRef = defaultDownstreamTestTalkgroupRef
+	}
+
+	return &Call{
+		Audio:         demoAudio(),
+		AudioFilename: "downstream-test.wav",
+		AudioMime:     "audio/wav",
+		System:        &System{SystemRef: systemRef, Label: "Downstream Test"},
+		Talkgroup:     &Talkgroup{TalkgroupRef: talkgroupRef, Label: "TEST", Name: "Downstream Test"},
+		Timestamp:     now,
+	}
+}
+
+// Test sends a synthetic call to the downstream the way Send does, even when it is disabled, and
+// reports the http status and the round trip latency. Nothing is stored.
+func (downstream *Downstream) Test(systemRef uint, talkgroupRef uint) DownstreamTestResult {
+	result := DownstreamTestResult{}
+
+	if downstream.controller == nil {
+		result.Error = "no controller available"
+		return result
+	}
+
+	call := newDownstreamTestCall(systemRef, talkgroupRef, time.Now())
+
+	var transcode func(audio []byte, format string) ([]byte, error)
+	if downstream.controller.FFMpeg != nil {
+		transcode = downstream.controller.FFMpeg.Transcode
+	}
+
+	audio, _ := newDownstreamAudio(call, transcode).forFormat(downstream.AudioFormat)
+
+	start := time.Now()
+	res, err := downstream.upload(call, audio)
+	result.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = res.StatusCode
+	result.StatusText = res.Status
+	result.Success = res.StatusCode == http.StatusOK
+
+	return result
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// downstreamQueueInterval is how often the queue is checked for calls due to be sent again
+	downstreamQueueInterval = 30 * time.Second
+
+	// downstreamQueueBatch bounds the calls sent again per check
+	downstreamQueueBatch = 100
+
+	// downstreamQueueMaxAttempts bounds the attempts of a queued call, about a day with the backoff below
+	downstreamQueueMaxAttempts = 30
+
+	downstreamQueueMinBackoff = time.Minute
+	downstreamQueueMaxBackoff = time.Hour
+)
+
+// downstreamQueueEntry is a call a downstream couldn't receive, persisted so it survives restarts
+type downstreamQueueEntry struct {
+	Id           uint64
+	CallId       uint64
+	DownstreamId uint64
+	Attempts     uint
+}
+
+// downstreamQueueOutcome is what becomes of an entry once tried, deleted or tried again later
+type downstreamQueueOutcome struct {
+	entry         downstreamQueueEntry
+	delete        bool
+	nextAttemptAt time.Time
+}
+
+// downstreamQueueBackoff returns the wait after the given number of failed attempts, doubled each time
+func downstreamQueueBackoff(attempts uint) time.Duration {
+	backoff := downstreamQueueMinBackoff
+	for i := uint(1); i < attempts && backoff < downstreamQueueMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > downstreamQueueMaxBackoff {
+		backoff = downstreamQueueMaxBackoff
+	}
+	return backoff
+}
+
+// enqueue persists a call the downstream couldn't receive, to be sent again by the queue worker
+func (downstreams *Downstreams) enqueue(call *Call, downstream *Downstream, logEvent func(logLevel string, message string)) {
+	if call.Id == 0 || downstream.Id == 0 {
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(downstreamQueueBackoff(1)).UnixMilli()
+
+	query := fmt.Sprintf(`INSERT INTO "downstreamQueue" ("callId", "downstreamId", "attempts", "nextAttemptAt") VALUES (%d, %d, 0, %d) ON CONFLICT ("callId", "downstreamId") DO NOTHING`, call.Id, downstream.Id, nextAttemptAt)
+	if _, err := downstreams.controller.Database.Sql.Exec(query); err != nil {
+		logEvent(LogLevelError, fmt.Sprintf("not queued: %v in %s", err, query))
+		return
+	}
+
+	logEvent(LogLevelWarn, fmt.Sprintf("queued, next attempt in %v", downstreamQueueBackoff(1)))
+}
+
+// Start resumes sending the calls queued before a restart, then checks the queue periodically
+func (downstreams *Downstreams) Start() error {
+	go func() {
+		downstreams.processQueue()
+
+		ticker := time.NewTicker(downstreamQueueInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			downstreams.processQueue()
+		}
+	}()
+
+	return nil
+}
+
+// processQueue sends the queued calls that are due and records the outcomes
+func (downstreams *Downstreams) processQueue() {
+	controller := downstreams.controller
+
+	logError := func(err error) {
+		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("downstreams.queue: %v", err))
+	}
+
+	query := fmt.Sprintf(`SELECT "downstreamQueueId", "callId", "downstreamId", "attempts" FROM "downstreamQueue" WHERE "nextAttemptAt" <= %d ORDER BY "nextAttemptAt" LIMIT %d`, time.Now().UnixMilli(), downstreamQueueBatch)
+
+	rows, err := controller.Database.Sql.Query(query)
+	if err != nil {
+		logError(fmt.Errorf("%v in %s", err, query))
+		return
+	}
+
+	entries := []downstreamQueueEntry{}
+	for rows.Next() {
+		entry := downstreamQueueEntry{}
+		if err = rows.Scan(&entry.Id, &entry.CallId, &entry.DownstreamId, &entry.Attempts); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	rows.Close()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, outcome := range downstreams.drainQueue(entries, time.Now(), controller.Calls.GetCall, controller.FFMpeg.Transcode) {
+		if outcome.delete {
+			query = fmt.Sprintf(`DELETE FROM "downstreamQueue" WHERE "downstreamQueueId" = %d`, outcome.entry.Id)
+		} else {
+			query = fmt.Sprintf(`UPDATE "downstreamQueue" SET "attempts" = %d, "nextAttemptAt" = %d WHERE "downstreamQueueId" = %d`, outcome.entry.Attempts, outcome.nextAttemptAt.UnixMilli(), outcome.entry.Id)
+		}
+
+		if _, err = controller.Database.Sql.Exec(query); err != nil {
+			logError(fmt.Errorf("%v in %s", err, query))
+		}
+	}
+}
+
+// drainQueue sends the queued calls again. Entries are deleted once sent, refused for good, out of
+// attempts or when their downstream no longer takes or matches the call, otherwise they are tried again later.
+func (downstreams *Downstreams) drainQueue(entries []downstreamQueueEntry, now time.Time, getCall func(callId uint64) (*Call, error), transcode func(audio []byte, format string) ([]byte, error)) []downstreamQueueOutcome {
+	outcomes := []downstreamQueueOutcome{}
+
+	byId := map[uint64]*Downstream{}
+	downstreams.mutex.Lock()
+	for _, downstream := range downstreams.List {
+		byId[downstream.Id] = downstream
+	}
+	downstreams.mutex.Unlock()
+
+	for _, entry := range entries {
+		outcome := downstreamQueueOutcome{entry: entry, delete: true}
+
+		downstream, ok := byId[entry.DownstreamId]
+		if !ok {
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		call, err := getCall(entry.CallId)
+		if err != nil || call == nil || call.System == nil || call.Talkgroup == nil || !downstream.HasAccess(call) || !downstream.Matches(call) {
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		logEvent := func(logLevel string, message string) {
+			downstreams.controller.Logs.LogEvent(logLevel, fmt.Sprintf("downstream: system=%d talkgroup=%d file=%s to %s %s", call.System.SystemRef, call.Talkgroup.TalkgroupRef, call.AudioFilename, downstream.Url, message))
+		}
+
+		if downstream.TooOld(call, now) {
+			logEvent(LogLevelInfo, fmt.Sprintf("removed from the queue, call older than %ds", downstream.MaxCallAge))
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		file, _ := newDownstreamAudio(call, transcode).forFormat(downstream.AudioFormat)
+
+		outcome.entry.Attempts++
+
+		switch err = downstream.Send(call, file); {
+		case err == nil:
+			logEvent(LogLevelInfo, fmt.Sprintf("success from the queue after %d attempts", outcome.entry.Attempts))
+
+		case !isDownstreamRetryable(err):
+			logEvent(LogLevelError, fmt.Sprintf("%v, removed from the queue", err))
+
+		case outcome.entry.Attempts >= downstreamQueueMaxAttempts:
+			logEvent(LogLevelError, fmt.Sprintf("%v, giving up after %d attempts from the queue", err, outcome.entry.Attempts))
+
+		default:
+			outcome.delete = false
+			outcome.nextAttemptAt = now.Add(downstreamQueueBackoff(outcome.entry.Attempts + 1))
+		}
+
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDownstreamQueueBackoff(t *testing.T) {
+	cases := []struct {
+		attempts uint
+		backoff  time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, time.Hour},
+		{downstreamQueueMaxAttempts, time.Hour},
+	}
+
+	for _, c := range cases {
+		if backoff := downstreamQueueBackoff(c.attempts); backoff != c.backoff {
+			t.Errorf("attempts %d: expected %v, got %v", c.attempts, c.backoff, backoff)
+		}
+	}
+}
+
+func TestDownstreamsDrainQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("key") {
+		case "down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "rejected":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{
+		{Id: 1, Apikey: "up", Systems: "*", Url: server.URL, controller: controller},
+		{Id: 2, Apikey: "down", Systems: "*", Url: server.URL, controller: controller},
+		{Id: 3, Apikey: "rejected", Systems: "*", Url: server.URL, controller: controller},
+		{Id: 5, Apikey: "recent", MaxCallAge: 60, Systems: "*", Url: server.URL, controller: controller},
+	}
+
+	// call 12 is an hour old
+	getCall := func(callId uint64) (*Call, error) {
+		if callId != 10 && callId != 12 {
+			return nil, errors.New("no such call")
+		}
+		timestamp := time.Now()
+		if callId == 12 {
+			timestamp = timestamp.Add(-time.Hour)
+		}
+		return &Call{
+			Id:            callId,
+			Audio:         []byte("audio"),
+			AudioFilename: "call.m4a",
+			AudioMime:     "audio/mp4",
+			System:        &System{SystemRef: 1},
+			Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+			Timestamp:     timestamp,
+		}, nil
+	}
+
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		entry    downstreamQueueEntry
+		delete   bool
+		attempts uint
+	}{
+		{"sent", downstreamQueueEntry{Id: 1, CallId: 10, DownstreamId: 1}, true, 1},
+		{"still down", downstreamQueueEntry{Id: 2, CallId: 10, DownstreamId: 2, Attempts: 2}, false, 3},
+		{"out of attempts", downstreamQueueEntry{Id: 3, CallId: 10, DownstreamId: 2, Attempts: downstreamQueueMaxAttempts - 1}, true, downstreamQueueMaxAttempts},
+		{"refused", downstreamQueueEntry{Id: 4, CallId: 10, DownstreamId: 3}, true, 1},
+		{"downstream removed", downstreamQueueEntry{Id: 5, CallId: 10, DownstreamId: 4}, true, 0},
+		{"call pruned", downstreamQueueEntry{Id: 6, CallId: 11, DownstreamId: 1}, true, 0},
+		{"call too old", downstreamQueueEntry{Id: 7, CallId: 12, DownstreamId: 5}, true, 0},
+		{"call recent enough", downstreamQueueEntry{Id: 8, CallId: 10, DownstreamId: 5}, true, 1},
+	}
+
+	entries := []downstreamQueueEntry{}
+	for _, c := range cases {
+		entries = append(entries, c.entry)
+	}
+
+	outcomes := downstreams.drainQueue(entries, now, getCall, nil)
+	if len(outcomes) != len(cases) {
+		t.Fatalf("expected %d outcomes, got %d", len(cases), len(outcomes))
+	}
+
+	for i, c := range cases {
+		outcome := outcomes[i]
+
+		if outcome.delete != c.delete {
+			t.Errorf("%s: expected delete %v, got %v", c.name, c.delete, outcome.delete)
+		}
+		if outcome.entry.Attempts != c.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", c.name, c.attempts, outcome.entry.Attempts)
+		}
+		if !c.delete && !outcome.nextAttemptAt.Equal(now.Add(downstreamQueueBackoff(c.attempts+1))) {
+			t.Errorf("%s: expected the next attempt to back off, got %v", c.name, outcome.nextAttemptAt.Sub(now))
+		}
+	}
+}
@@ -0,0 +1,722 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownstreamReserveMinInterval(t *testing.T) {
+	downstream := NewDownstream(nil)
+	downstream.MinInterval = 1000
+	downstream.ThrottleMode = DOWNSTREAM_THROTTLE_DROP
+	now := time.Now()
+
+	if _, ok := downstream.Reserve(now); !ok {
+		t.Fatal("first call should pass")
+	}
+
+	if _, ok := downstream.Reserve(now.Add(100 * time.Millisecond)); ok {
+		t.Error("burst call should be dropped")
+	}
+
+	if wait, ok := downstream.Reserve(now.Add(1500 * time.Millisecond)); !ok || wait != 0 {
+		t.Errorf("trickle call should pass immediately, got wait=%v ok=%t", wait, ok)
+	}
+}
+
+func TestDownstreamReserveMaxPerMinuteQueue(t *testing.T) {
+	downstream := NewDownstream(nil)
+	downstream.MaxPerMinute = 3
+	downstream.ThrottleMode = DOWNSTREAM_THROTTLE_QUEUE
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if wait, ok := downstream.Reserve(now); !ok || wait != 0 {
+			t.Fatalf("call %d should pass immediately, got wait=%v ok=%t", i, wait, ok)
+		}
+	}
+
+	wait, ok := downstream.Reserve(now)
+	if !ok {
+		t.Fatal("burst call should be queued, not dropped")
+	}
+	if wait != time.Minute {
+		t.Errorf("expected queued call to wait one minute, got %v", wait)
+	}
+}
+
+func TestDownstreamReserveMaxPerMinuteExpiry(t *testing.T) {
+	downstream := NewDownstream(nil)
+	downstream.MaxPerMinute = 2
+	downstream.ThrottleMode = DOWNSTREAM_THROTTLE_DROP
+	now := time.Now()
+
+	downstream.Reserve(now)
+	downstream.Reserve(now.Add(30 * time.Second))
+
+	if _, ok := downstream.Reserve(now.Add(59 * time.Second)); ok {
+		t.Error("call within the minute should be dropped")
+	}
+
+	if wait, ok := downstream.Reserve(now.Add(61 * time.Second)); !ok || wait != 0 {
+		t.Errorf("call past the minute of the first slot should pass, got wait=%v ok=%t", wait, ok)
+	}
+	if len(downstream.throttle.slots) != 2 {
+		t.Errorf("expected the expired slot forgotten, got %d slots", len(downstream.throttle.slots))
+	}
+}
+
+func TestDownstreamReserveRelease(t *testing.T) {
+	downstream := NewDownstream(nil)
+	downstream.MinInterval = 1000
+	downstream.ThrottleMode = DOWNSTREAM_THROTTLE_QUEUE
+	now := time.Now()
+
+	downstream.Reserve(now)
+
+	for i := 0; i < downstreamThrottleMaxQueued; i++ {
+		if _, ok := downstream.Reserve(now); !ok {
+			t.Fatalf("call %d should be queued", i)
+		}
+	}
+
+	if _, ok := downstream.Reserve(now); ok {
+		t.Fatal("call past the full queue should be dropped")
+	}
+
+	downstream.release()
+
+	if wait, ok := downstream.Reserve(now); !ok || wait <= 0 {
+		t.Errorf("released slot should queue the next call, got wait=%v ok=%t", wait, ok)
+	}
+}
+
+func TestDownstreamsReloadKeepsThrottle(t *testing.T) {
+	downstreams := NewDownstreams(nil)
+	downstreams.FromMap([]any{map[string]any{"id": float64(1), "maxPerMinute": float64(1), "throttleMode": DOWNSTREAM_THROTTLE_DROP}})
+
+	now := time.Now()
+	downstreams.List[0].Reserve(now)
+
+	downstreams.FromMap([]any{map[string]any{"id": float64(1), "maxPerMinute": float64(1), "throttleMode": DOWNSTREAM_THROTTLE_DROP}})
+
+	if _, ok := downstreams.List[0].Reserve(now); ok {
+		t.Error("the throttle should be kept across an admin save")
+	}
+
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	d.rows = [][]driver.Value{{int64(1), "", "", false, "", int64(0), int64(1), int64(0), int64(0), nil, int64(0), "", "", false, int64(0), "", DOWNSTREAM_THROTTLE_DROP, int64(0), ""}}
+
+	if err := downstreams.Read(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := downstreams.List[0].Reserve(now); ok {
+		t.Error("the throttle should be kept across a reload from the database")
+	}
+}
+
+func TestDownstreamReserveUnthrottled(t *testing.T) {
+	downstream := NewDownstream(nil)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		if wait, ok := downstream.Reserve(now); !ok || wait != 0 {
+			t.Fatalf("unthrottled downstream should never wait, got wait=%v ok=%t", wait, ok)
+		}
+	}
+}
+
+func TestDownstreamClientTransportSettings(t *testing.T) {
+	options := NewOptions()
+	options.DownstreamHttp2 = true
+	options.DownstreamMaxIdleConns = 32
+	options.DownstreamIdleConnTimeout = 45
+
+	downstream := NewDownstream(&Controller{Options: options})
+
+	client := downstream.httpClient()
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport, got %T", client.Transport)
+	}
+
+	if !transport.ForceAttemptHTTP2 {
+		t.Error("http/2 should be attempted")
+	}
+	if transport.MaxIdleConnsPerHost != 32 {
+		t.Errorf("expected 32 idle connections per host, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 45*time.Second {
+		t.Errorf("expected a 45s idle timeout, got %v", transport.IdleConnTimeout)
+	}
+
+	if downstream.httpClient() != client {
+		t.Error("the client should be reused while the options are unchanged")
+	}
+
+	options.DownstreamHttp2 = false
+
+	client = downstream.httpClient()
+	transport = client.Transport.(*http.Transport)
+
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("http/2 should be disabled once the option is turned off")
+	}
+}
+
+func TestDownstreamTimeout(t *testing.T) {
+	cases := []struct {
+		value    any
+		expected time.Duration
+	}{
+		{nil, 30 * time.Second},
+		{float64(0), 30 * time.Second},
+		{float64(120), 120 * time.Second},
+		{float64(3600), 600 * time.Second},
+	}
+
+	for _, c := range cases {
+		downstream := NewDownstream(&Controller{Options: NewOptions()}).FromMap(map[string]any{"timeoutSeconds": c.value})
+
+		if timeout := downstream.httpClient().Timeout; timeout != c.expected {
+			t.Errorf("timeoutSeconds %v: expected %v, got %v", c.value, c.expected, timeout)
+		}
+	}
+
+	downstream := NewDownstream(nil)
+	client := downstream.httpClient()
+
+	downstream.TimeoutSeconds = 90
+	if downstream.httpClient() == client || downstream.httpClient().Timeout != 90*time.Second {
+		t.Error("the client should be rebuilt once the timeout changes")
+	}
+}
+
+func TestDownstreamsSendAudioFormat(t *testing.T) {
+	received := map[string][3]string{}
+	mutex := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("audio")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		audio, _ := io.ReadAll(file)
+
+		mutex.Lock()
+		received[r.FormValue("key")] = [3]string{string(audio), header.Filename, r.FormValue("audioType")}
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{
+		{Apikey: "any", Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "wav", AudioFormat: "wav", Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "m4a", AudioFormat: "m4a", Systems: "*", Url: server.URL, controller: controller},
+	}
+
+	call := &Call{
+		Audio:         []byte("m4a audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.Now(),
+	}
+
+	transcodes := 0
+	downstreams.send(controller, call, newDownstreamAudio(call, func(audio []byte, format string) ([]byte, error) {
+		transcodes++
+		return []byte(format + " audio"), nil
+	}), downstreamStageCall)
+
+	if got := received["any"]; got != [3]string{"m4a audio", "call.m4a", "audio/mp4"} {
+		t.Errorf("any format downstream should get the original audio, got %v", got)
+	}
+	if got := received["wav"]; got != [3]string{"wav audio", "call.wav", "audio/wav"} {
+		t.Errorf("wav downstream should get transcoded audio, got %v", got)
+	}
+	if got := received["m4a"]; got != [3]string{"m4a audio", "call.m4a", "audio/mp4"} {
+		t.Errorf("downstream requiring the stored format should get the original audio, got %v", got)
+	}
+	if transcodes != 1 {
+		t.Errorf("expected a single transcoding, got %d", transcodes)
+	}
+	if string(call.Audio) != "m4a audio" || call.AudioFilename != "call.m4a" {
+		t.Error("the call audio should be left untouched")
+	}
+
+	failing := newDownstreamAudio(call, func(audio []byte, format string) ([]byte, error) {
+		return nil, errors.New("no encoder")
+	})
+	if file, err := failing.forFormat("wav"); err == nil || string(file.audio) != "m4a audio" {
+		t.Error("a failed transcoding should fall back to the original audio")
+	}
+}
+
+func TestDownstreamSendHeaders(t *testing.T) {
+	var received http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := r.FormFile("audio"); err != nil || r.FormValue("key") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	call := &Call{
+		Audio:         []byte("m4a audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.Now(),
+	}
+
+	audio, _ := newDownstreamAudio(call, nil).forFormat("")
+
+	downstream := NewDownstream(controller).FromMap(map[string]any{
+		"apikey": "secret",
+		"url":    server.URL,
+		"headers": map[string]any{
+			"Authorization": "Bearer gateway-token",
+			"X-Api-Source":  "thinline",
+			"Content-Type":  "text/plain",
+			" ":             "ignored",
+		},
+	})
+
+	if len(downstream.Headers) != 3 {
+		t.Fatalf("expected 3 headers, got %v", downstream.Headers)
+	}
+
+	if err := downstream.Send(call, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("Authorization") != "Bearer gateway-token" || received.Get("X-Api-Source") != "thinline" {
+		t.Errorf("custom headers not sent, got %v", received)
+	}
+
+	if !strings.HasPrefix(received.Get("Content-Type"), "multipart/form-data") {
+		t.Errorf("the multipart content type should not be overridden, got %s", received.Get("Content-Type"))
+	}
+
+	// without headers the request is sent as before
+	downstream = NewDownstream(controller).FromMap(map[string]any{"apikey": "secret", "url": server.URL})
+
+	if err := downstream.Send(call, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("Authorization") != "" || received.Get("X-Api-Source") != "" {
+		t.Errorf("unexpected headers %v", received)
+	}
+}
+
+func TestDownstreamsSendFilters(t *testing.T) {
+	received := []string{}
+	mutex := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		received = append(received, r.FormValue("key"))
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags(), KeywordMatcher: NewKeywordMatcher()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{
+		{Apikey: "any", Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "tones", RequireTones: true, Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "fire", RequireKeywords: []string{"fire", "smoke"}, Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "flood", RequireKeywords: []string{"flood"}, Systems: "*", Url: server.URL, controller: controller},
+	}
+
+	call := &Call{
+		Audio:         []byte("audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.Now(),
+	}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageCall)
+
+	if strings.Join(received, " ") != "any" {
+		t.Errorf("sent to %v, want [any] before its tones and transcript", received)
+	}
+
+	received = []string{}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageTones)
+
+	if len(received) != 0 {
+		t.Errorf("sent to %v, want none without tones", received)
+	}
+
+	received = []string{}
+	call.HasTones = true
+	call.Transcript = "Smoke showing from the second floor"
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageTones)
+
+	if strings.Join(received, " ") != "tones" {
+		t.Errorf("sent to %v, want [tones] once its tones are detected", received)
+	}
+
+	received = []string{}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageTranscript)
+
+	if strings.Join(received, " ") != "fire" {
+		t.Errorf("sent to %v, want [fire] once transcribed", received)
+	}
+}
+
+func TestDownstreamRetryDelay(t *testing.T) {
+	downstream := NewDownstream(nil)
+
+	for retry, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if delay := downstream.retryDelay(uint(retry + 1)); delay != expected {
+			t.Errorf("retry %d: expected %v, got %v", retry+1, expected, delay)
+		}
+	}
+
+	downstream.MaxRetries = 10
+	if retries := downstream.retries(); retries != downstreamMaxRetries {
+		t.Errorf("expected retries to be capped at %d, got %d", downstreamMaxRetries, retries)
+	}
+}
+
+func TestDownstreamsSendRetries(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		attempts = map[string]int{}
+		done     = make(chan string, 2)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.FormValue("key")
+
+		mutex.Lock()
+		attempts[key]++
+		n := attempts[key]
+		mutex.Unlock()
+
+		switch {
+		case key == "rejected":
+			w.WriteHeader(http.StatusUnauthorized)
+			done <- key
+		case n < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			done <- key
+		}
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{
+		{Apikey: "flaky", MaxRetries: 3, RetryBackoff: 1, Systems: "*", Url: server.URL, controller: controller},
+		{Apikey: "rejected", MaxRetries: 3, RetryBackoff: 1, Systems: "*", Url: server.URL, controller: controller},
+	}
+
+	call := &Call{
+		Audio:         []byte("audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.Now(),
+	}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageCall)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the retries")
+		}
+	}
+
+	// give a wrongly retried rejection the time to show up
+	time.Sleep(50 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if attempts["flaky"] != 3 {
+		t.Errorf("expected the server errors to be retried until accepted, got %d attempts", attempts["flaky"])
+	}
+	if attempts["rejected"] != 1 {
+		t.Errorf("expected a client error not to be retried, got %d attempts", attempts["rejected"])
+	}
+}
+
+func TestDownstreamSendProtocol(t *testing.T) {
+	var (
+		fields   []string
+		received *Call
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fields = []string{}
+		received = NewCall()
+
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(p)
+			fields = append(fields, p.FormName())
+			ParseMultipartContent(received, p, b)
+		}
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	call := &Call{
+		Audio:         []byte("m4a audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		Frequency:     854412500,
+		Frequencies:   []CallFrequency{{Dbm: 5, Errors: 2, Frequency: 854412500, Offset: 0.5, Spikes: 1}},
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.UnixMilli(1760000000000),
+		Units:         []CallUnit{{UnitRef: 0}, {Offset: 1.5, UnitRef: 4001}, {Offset: 3, UnitRef: 4002}},
+	}
+
+	audio, _ := newDownstreamAudio(call, nil).forFormat("")
+
+	has := func(name string) bool {
+		for _, field := range fields {
+			if field == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// v6 by default, units are reduced to the sources with a ref
+	downstream := NewDownstream(controller).FromMap(map[string]any{"apikey": "secret", "url": server.URL})
+
+	if downstream.protocol() != DOWNSTREAM_PROTOCOL_V6 {
+		t.Fatalf("expected the v6 protocol by default, got %s", downstream.protocol())
+	}
+
+	if err := downstream.Send(call, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	if !has("audioName") || !has("sources") || has("units") {
+		t.Errorf("expected the v6 field names, got %v", fields)
+	}
+	if len(received.Units) != 3 {
+		t.Errorf("expected the first source and the sources, got %v", received.Units)
+	}
+
+	downstream = NewDownstream(controller).FromMap(map[string]any{"apikey": "secret", "protocol": "V7", "url": server.URL})
+
+	if downstream.protocol() != DOWNSTREAM_PROTOCOL_V7 {
+		t.Fatalf("expected the v7 protocol, got %s", downstream.protocol())
+	}
+
+	if err := downstream.Send(call, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	if !has("audioFilename") || !has("units") || has("audioName") || has("sources") || has("frequency") {
+		t.Errorf("expected the native field names, got %v", fields)
+	}
+
+	if len(received.Units) != len(call.Units) {
+		t.Fatalf("expected every unit, got %v", received.Units)
+	}
+	for i, unit := range call.Units {
+		if received.Units[i] != unit {
+			t.Errorf("expected unit %v, got %v", unit, received.Units[i])
+		}
+	}
+
+	if len(received.Frequencies) != 1 || received.Frequencies[0] != call.Frequencies[0] || received.Frequency != call.Frequency {
+		t.Errorf("expected the exact frequencies, got %d %v", received.Frequency, received.Frequencies)
+	}
+
+	if !received.Timestamp.Equal(call.Timestamp) || received.AudioMime != call.AudioMime {
+		t.Errorf("expected the timestamp and mime, got %v %s", received.Timestamp, received.AudioMime)
+	}
+}
+
+func TestDownstreamTest(t *testing.T) {
+	var received *Call
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/call-upload" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		received = NewCall()
+		key := ""
+
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(p)
+			if p.FormName() == "key" {
+				key = string(b)
+			}
+			ParseMultipartContent(received, p, b)
+		}
+
+		if key != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	// a disabled downstream is tested all the same
+	downstream := NewDownstream(controller).FromMap(map[string]any{"apikey": "secret", "disabled": true, "url": server.URL})
+
+	result := downstream.Test(0, 0)
+
+	if !result.Success || result.Status != http.StatusOK || result.Error != "" || result.LatencyMs < 0 {
+		t.Fatalf("expected the test call to be accepted, got %+v", result)
+	}
+
+	if received.SystemId != defaultDownstreamTestSystemRef || received.TalkgroupId != defaultDownstreamTestTalkgroupRef {
+		t.Errorf("expected the default refs, got system %d talkgroup %d", received.SystemId, received.TalkgroupId)
+	}
+
+	if ok, err := received.IsValid(); !ok || string(received.Audio[0:4]) != "RIFF" {
+		t.Errorf("expected a valid call of wav audio, got %v", err)
+	}
+
+	downstream.Test(12, 34)
+
+	if received.SystemId != 12 || received.TalkgroupId != 34 {
+		t.Errorf("expected the given refs, got system %d talkgroup %d", received.SystemId, received.TalkgroupId)
+	}
+
+	downstream = NewDownstream(controller).FromMap(map[string]any{"apikey": "wrong", "url": server.URL})
+
+	if result = downstream.Test(0, 0); result.Success || result.Status != http.StatusUnauthorized {
+		t.Errorf("expected the refusal to be reported, got %+v", result)
+	}
+
+	downstream = NewDownstream(controller).FromMap(map[string]any{"apikey": "secret", "url": "http://127.0.0.1:1"})
+
+	if result = downstream.Test(0, 0); result.Success || result.Status != 0 || result.Error == "" {
+		t.Errorf("expected the connection error to be reported, got %+v", result)
+	}
+}
+
+func TestDownstreamsSendMaxCallAge(t *testing.T) {
+	received := []string{}
+	mutex := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		received = append(received, r.FormValue("key"))
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs(), Groups: NewGroups(), Tags: NewTags()}
+
+	downstreams := NewDownstreams(controller)
+	downstreams.List = []*Downstream{
+		{Apikey: "any", Systems: "*", Url: server.URL, controller: controller},
+		NewDownstream(controller).FromMap(map[string]any{"apikey": "recent", "maxCallAge": float64(300), "systems": "*", "url": server.URL}),
+	}
+
+	if downstreams.List[1].MaxCallAge != 300 {
+		t.Fatalf("expected a max call age of 300s, got %d", downstreams.List[1].MaxCallAge)
+	}
+
+	call := &Call{
+		Audio:         []byte("audio"),
+		AudioFilename: "call.m4a",
+		AudioMime:     "audio/mp4",
+		System:        &System{SystemRef: 1},
+		Talkgroup:     &Talkgroup{TalkgroupRef: 2},
+		Timestamp:     time.Now().Add(-time.Minute),
+	}
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageCall)
+
+	if strings.Join(received, " ") != "any recent" {
+		t.Errorf("sent to %v, want [any recent] for a fresh call", received)
+	}
+
+	received = []string{}
+	call.Timestamp = time.Now().Add(-time.Hour)
+
+	downstreams.send(controller, call, newDownstreamAudio(call, nil), downstreamStageCall)
+
+	if strings.Join(received, " ") != "any" {
+		t.Errorf("sent to %v, want [any] for a call older than the max age", received)
+	}
+}
+
+func TestDownstreamsFromMapKeepsSettings(t *testing.T) {
+	downstreams := NewDownstreams(nil)
+	downstreams.FromMap([]any{map[string]any{"id": float64(1), "url": "https://a.example.com", "audioFormat": "mp3", "minInterval": float64(500), "protocol": "v7", "headers": map[string]any{"X-Key": "secret"}}})
+
+	// saved by an admin unaware of the settings
+	downstreams.FromMap([]any{
+		map[string]any{"id": float64(1), "url": "https://b.example.com"},
+		map[string]any{"url": "https://c.example.com"},
+	})
+
+	downstream := downstreams.List[0]
+	if downstream.Url != "https://b.example.com" || downstream.AudioFormat != "mp3" || downstream.MinInterval != 500 || downstream.Protocol != DOWNSTREAM_PROTOCOL_V7 || downstream.Headers["X-Key"] != "secret" {
+		t.Errorf("expected the settings kept, got %+v", downstream)
+	}
+	if downstreams.List[1].AudioFormat != "" || downstreams.List[1].MinInterval != 0 {
+		t.Errorf("expected a new downstream without settings, got %+v", downstreams.List[1])
+	}
+}
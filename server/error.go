@@ -21,6 +21,11 @@ import (
 	"fmt"
 )
 
+const (
+	mysqlDuplicateEntry     = 1062
+	sqlStateUniqueViolation = "23505"
+)
+
 func errorFormatter(section string, label string) func(err error, query string) error {
 	return func(err error, query string) error {
 		s := fmt.Sprintf("%s.%s: %s", section, label, err.Error())
@@ -32,3 +37,23 @@ func errorFormatter(section string, label string) func(err error, query string)
 		return errors.New(s)
 	}
 }
+
+// isUniqueViolation reports whether err, or an error it wraps, is a unique constraint violation.
+// The postgresql drivers expose the sqlstate of the error, the mysql driver its error number as
+// the prefix of the message.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+
+	if errors.As(err, &state) {
+		return state.SQLState() == sqlStateUniqueViolation
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		var number uint
+		if _, scanErr := fmt.Sscanf(err.Error(), "Error %d", &number); scanErr == nil {
+			return number == mysqlDuplicateEntry
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeMySQLError mimics the error of the go-sql-driver/mysql driver
+type fakeMySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *fakeMySQLError) Error() string {
+	return fmt.Sprintf("Error %d (23000): %s", e.Number, e.Message)
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("connection refused"), false},
+		{"postgres unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "tags_label_key"}, true},
+		{"postgres foreign key violation", &pgconn.PgError{Code: "23503"}, false},
+		{"wrapped postgres unique violation", fmt.Errorf("tags.write: %w", &pgconn.PgError{Code: "23505"}), true},
+		{"mysql duplicate entry", &fakeMySQLError{Number: 1062, Message: "Duplicate entry 'Fire' for key 'label'"}, true},
+		{"mysql foreign key violation", &fakeMySQLError{Number: 1452, Message: "Cannot add or update a child row"}, false},
+		{"wrapped mysql duplicate entry", fmt.Errorf("groups.write: %w", &fakeMySQLError{Number: 1062, Message: "Duplicate entry"}), true},
+		{"message only mention", errors.New("duplicate key value violates unique constraint"), false},
+	}
+
+	for _, test := range tests {
+		if got := isUniqueViolation(test.err); got != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, got)
+		}
+	}
+}
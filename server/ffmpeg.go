@@ -27,9 +27,10 @@ import (
 )
 
 type FFMpeg struct {
-	available bool
-	version43 bool
-	warned    bool
+	available       bool
+	version43       bool
+	warned          bool
+	normalizeWarned bool
 }
 
 func NewFFMpeg() *FFMpeg {
@@ -72,6 +72,7 @@ func (group *Group) MarshalJSON() ([]byte, error) {
 type Groups struct {
 	List  []*Group
 	mutex sync.RWMutex
+	byId  lookupCache[uint64, Group]
 }
 
 func NewGroups() *Groups {
@@ -84,6 +85,7 @@ func NewGroups() *Groups {
 func (groups *Groups) FromMap(f []any) *Groups {
 	groups.mutex.Lock()
 	defer groups.mutex.Unlock()
+	defer groups.rebuildLookups()
 
 	groups.List = []*Group{}
 
@@ -99,6 +101,10 @@ func (groups *Groups) FromMap(f []any) *Groups {
 }
 
 func (groups *Groups) GetGroupById(id uint64) (group *Group, ok bool) {
+	if group, ok := groups.byId.get(id); ok {
+		return group, true
+	}
+
 	groups.mutex.RLock()
 	defer groups.mutex.RUnlock()
 
@@ -244,6 +250,7 @@ func (groups *Groups) Read(db *Database) error {
 
 	groups.mutex.Lock()
 	defer groups.mutex.Unlock()
+	defer groups.rebuildLookups()
 
 	groups.List = []*Group{}
 
@@ -277,6 +284,11 @@ func (groups *Groups) Read(db *Database) error {
 	return nil
 }
 
+// rebuildLookups publishes a new lookup snapshot of the list, caller must hold the lock.
+func (groups *Groups) rebuildLookups() {
+	groups.byId.rebuild(groups.List, func(group *Group) uint64 { return group.Id })
+}
+
 func (groups *Groups) Write(db *Database) error {
 	var (
 		err      error
@@ -340,6 +352,8 @@ func (groups *Groups) Write(db *Database) error {
 		var count uint
 		var existingId uint64
 
+		group.Label = SanitizeLabel(group.Label)
+
 		if group.Id > 0 {
 			query = fmt.Sprintf(`SELECT COUNT(*) FROM "groups" WHERE "groupId" = %d`, group.Id)
 			if err = tx.QueryRow(query).Scan(&count); err != nil {
@@ -368,11 +382,20 @@ func (groups *Groups) Write(db *Database) error {
 				// Let database assign auto-increment ID
 				query = fmt.Sprintf(`INSERT INTO "groups" ("label", "order") VALUES ('%s', %d)`, escapeQuotes(group.Label), group.Order)
 			}
-			if _, err = tx.Exec(query); err != nil {
+			err = execSavepoint(tx, query)
+			if isUniqueViolation(err) {
+				// Group with this label was inserted in the meantime, update it instead
+				query = fmt.Sprintf(`SELECT "groupId" FROM "groups" WHERE "label" = '%s' LIMIT 1`, escapeQuotes(group.Label))
+				if err = tx.QueryRow(query).Scan(&group.Id); err != nil {
+					break
+				}
+				count = 1
+			} else if err != nil {
 				break
 			}
+		}
 
-		} else {
+		if count > 0 {
 			query = fmt.Sprintf(`UPDATE "groups" SET "label" = '%s', "order" = %d where "groupId" = %d`, escapeQuotes(group.Label), group.Order, group.Id)
 			if _, err = tx.Exec(query); err != nil {
 				break
@@ -0,0 +1,110 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ApplyGroupAlertDefaults materializes the default alert preferences of the user's group into
+// the user's own alert preferences. Talkgroups the user already has preferences for are left untouched.
+func (controller *Controller) ApplyGroupAlertDefaults(user *User) (int, error) {
+	if user == nil || user.UserGroupId == 0 {
+		return 0, nil
+	}
+
+	group := controller.UserGroups.Get(user.UserGroupId)
+	if group == nil || len(group.GetDefaultAlertPreferences()) == 0 {
+		return 0, nil
+	}
+
+	existing, err := controller.readUserAlertPreferences(user.Id)
+	if err != nil {
+		return 0, err
+	}
+
+	lists, err := controller.readKeywordLists()
+	if err != nil {
+		return 0, err
+	}
+
+	preferences := controller.groupAlertDefaults(user, group, existing, lists)
+	if len(preferences) == 0 {
+		return 0, nil
+	}
+
+	tx, err := controller.Database.Sql.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	applied := 0
+
+	for _, preference := range preferences {
+		keywordsJson, _ := json.Marshal(nonNilStrings(preference.Keywords))
+		keywordListIdsJson, _ := json.Marshal(preference.KeywordListIds)
+		toneSetIdsJson, _ := json.Marshal(nonNilStrings(preference.ToneSetIds))
+
+		query := fmt.Sprintf(`INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds") VALUES (%d, %d, %d, %t, %t, %t, $1, $2, $3) ON CONFLICT ("userId", "systemId", "talkgroupId") DO NOTHING`, user.Id, preference.SystemId, preference.TalkgroupId, preference.AlertEnabled, preference.ToneAlerts, preference.KeywordAlerts)
+
+		res, err := tx.Exec(query, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson))
+		if err != nil {
+			return 0, fmt.Errorf("%s in %s", err, query)
+		}
+
+		if n, err := res.RowsAffected(); err == nil {
+			applied += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return applied, nil
+}
+
+// groupAlertDefaults resolves the group template for a user, keeping only the talkgroups the group
+// can access and for which the user has no preference yet
+func (controller *Controller) groupAlertDefaults(user *User, group *UserGroup, existing []userAlertPreference, lists []keywordList) []userAlertPreference {
+	template := &UserConfig{Preferences: []UserConfigPreference{}}
+	for _, p := range group.GetDefaultAlertPreferences() {
+		if group.HasTalkgroupAccess(uint64(p.SystemRef), p.TalkgroupRef) {
+			template.Preferences = append(template.Preferences, p)
+		}
+	}
+
+	resolved, _ := controller.resolveUserConfig(user, template, lists)
+
+	present := map[string]bool{}
+	for _, preference := range existing {
+		present[fmt.Sprintf("%d:%d", preference.SystemId, preference.TalkgroupId)] = true
+	}
+
+	preferences := []userAlertPreference{}
+	for _, preference := range resolved {
+		key := fmt.Sprintf("%d:%d", preference.SystemId, preference.TalkgroupId)
+		if present[key] {
+			continue
+		}
+		present[key] = true
+		preferences = append(preferences, preference)
+	}
+
+	return preferences
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newGroupAlertDefaultsGroup(systemAccess string, template string) *UserGroup {
+	group := &UserGroup{Id: 7, SystemAccess: systemAccess, DefaultAlertPreferences: template}
+	group.loadSystemAccess()
+	group.loadDefaultAlertPreferences()
+	return group
+}
+
+func TestGroupAlertDefaultsNewMember(t *testing.T) {
+	controller := newUserConfigController(1, 11, 12)
+
+	group := newGroupAlertDefaultsGroup(`[{"id":10,"talkgroups":"*"}]`, `[
+		{"systemRef":10,"talkgroupRef":100,"alertEnabled":true,"keywordAlerts":true,"keywords":["mayday"],"keywordLists":["Fire"]},
+		{"systemRef":10,"talkgroupRef":101,"alertEnabled":true,"toneAlerts":true}
+	]`)
+	controller.UserGroups.groups[group.Id] = group
+
+	user := &User{Id: 1, UserGroupId: group.Id}
+	user.loadSystemScopes()
+
+	lists := []keywordList{{Id: 3, Label: "Fire"}}
+
+	preferences := controller.groupAlertDefaults(user, group, []userAlertPreference{}, lists)
+
+	expected := []userAlertPreference{
+		{SystemId: 1, TalkgroupId: 11, AlertEnabled: true, KeywordAlerts: true, Keywords: []string{"mayday"}, KeywordListIds: []uint64{3}, ToneSetIds: []string{}},
+		{SystemId: 1, TalkgroupId: 12, AlertEnabled: true, ToneAlerts: true, Keywords: []string{}, KeywordListIds: []uint64{}, ToneSetIds: []string{}},
+	}
+	if !reflect.DeepEqual(preferences, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, preferences)
+	}
+}
+
+func TestGroupAlertDefaultsKeepsExistingPreferences(t *testing.T) {
+	controller := newUserConfigController(1, 11, 12)
+
+	group := newGroupAlertDefaultsGroup("", `[
+		{"systemRef":10,"talkgroupRef":100,"alertEnabled":true},
+		{"systemRef":10,"talkgroupRef":101,"alertEnabled":true}
+	]`)
+	controller.UserGroups.groups[group.Id] = group
+
+	user := &User{Id: 1, UserGroupId: group.Id}
+	user.loadSystemScopes()
+
+	existing := []userAlertPreference{{SystemId: 1, TalkgroupId: 11, AlertEnabled: false}}
+
+	preferences := controller.groupAlertDefaults(user, group, existing, nil)
+	if len(preferences) != 1 || preferences[0].TalkgroupId != 12 {
+		t.Fatalf("expected only talkgroup 12 to be applied, got %+v", preferences)
+	}
+}
+
+func TestValidateDefaultAlertPreferences(t *testing.T) {
+	restricted := `[{"id":10,"talkgroups":[100]}]`
+
+	cases := []struct {
+		name     string
+		access   string
+		template string
+		valid    bool
+	}{
+		{name: "empty", access: restricted, template: "", valid: true},
+		{name: "accessible", access: restricted, template: `[{"systemRef":10,"talkgroupRef":100}]`, valid: true},
+		{name: "talkgroup outside access", access: restricted, template: `[{"systemRef":10,"talkgroupRef":101}]`, valid: false},
+		{name: "system outside access", access: restricted, template: `[{"systemRef":20,"talkgroupRef":100}]`, valid: false},
+		{name: "invalid json", access: restricted, template: `{`, valid: false},
+		{name: "unrestricted group", access: "", template: `[{"systemRef":20,"talkgroupRef":100}]`, valid: true},
+	}
+
+	for _, c := range cases {
+		group := &UserGroup{SystemAccess: c.access, DefaultAlertPreferences: c.template}
+		if err := group.ValidateDefaultAlertPreferences(); (err == nil) != c.valid {
+			t.Errorf("%s: expected valid=%t, got %v", c.name, c.valid, err)
+		}
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -44,6 +46,7 @@ type SuspectedHallucination struct {
 type HallucinationDetector struct {
 	controller *Controller
 	mutex      sync.Mutex
+	rejected   atomic.Uint64 // transcripts rejected as made of hallucination patterns only
 }
 
 // NewHallucinationDetector creates a new hallucination detector
@@ -53,7 +56,7 @@ func NewHallucinationDetector(controller *Controller) *HallucinationDetector {
 	}
 }
 
-// Emergency vocabulary that should never be flagged
+// Emergency vocabulary that should never be flagged, extended by the emergencyVocabulary of the transcription config
 var emergencyVocabulary = []string{
 	"station", "engine", "truck", "unit", "medic", "ambulance",
 	"fire", "ems", "rescue", "squad", "chief", "captain",
@@ -128,12 +131,28 @@ func (hd *HallucinationDetector) TrackPhrase(transcript string, wasAccepted bool
 	}
 }
 
-// containsEmergencyVocabulary checks if phrase contains any emergency vocabulary
+// TrackRejectedTranscript counts a transcript rejected as made of hallucination patterns only and tracks it as rejected
+func (hd *HallucinationDetector) TrackRejectedTranscript(transcript string, systemId uint64) uint64 {
+	count := hd.rejected.Add(1)
+
+	hd.TrackPhrase(transcript, false, systemId)
+
+	return count
+}
+
+// RejectedTranscripts returns the number of transcripts rejected as made of hallucination patterns only
+func (hd *HallucinationDetector) RejectedTranscripts() uint64 {
+	return hd.rejected.Load()
+}
+
+// containsEmergencyVocabulary checks if phrase contains any built-in or configured emergency vocabulary
 func (hd *HallucinationDetector) containsEmergencyVocabulary(phrase string) bool {
 	phraseUpper := strings.ToUpper(phrase)
-	for _, word := range emergencyVocabulary {
-		if strings.Contains(phraseUpper, strings.ToUpper(word)) {
-			return true
+	for _, vocabulary := range [][]string{emergencyVocabulary, hd.controller.Options.TranscriptionConfig.EmergencyVocabulary} {
+		for _, word := range vocabulary {
+			if word = strings.TrimSpace(word); word != "" && strings.Contains(phraseUpper, strings.ToUpper(word)) {
+				return true
+			}
 		}
 	}
 	return false
@@ -151,41 +170,44 @@ func (hd *HallucinationDetector) containsSystemId(systemIds []uint64, systemId u
 
 // calculateConfidenceScore calculates how confident we are that this is a hallucination
 func (hd *HallucinationDetector) calculateConfidenceScore(sh *SuspectedHallucination) float64 {
-	score := 0.0
+	daysSinceFirst := float64(time.Now().UnixMilli()-sh.FirstSeenAt) / (1000.0 * 60 * 60 * 24)
 
-	// If it ever appeared in accepted calls, confidence drops dramatically
-	if sh.AcceptedCount > 0 {
-		return 0.0
-	}
+	return confidenceScore(sh.RejectedCount, sh.AcceptedCount, len(sh.SystemIds), daysSinceFirst)
+}
 
-	// Rejected count score (up to 5 points)
-	if sh.RejectedCount >= 10 {
-		score += 5.0
-	} else if sh.RejectedCount >= 5 {
-		score += 3.0
-	} else if sh.RejectedCount >= 3 {
-		score += 1.0
-	}
+// confidenceTier is the score of a count reaching its threshold
+type confidenceTier struct {
+	threshold int
+	score     float64
+}
 
-	// Multiple systems score (up to 3 points)
-	if len(sh.SystemIds) >= 3 {
-		score += 3.0
-	} else if len(sh.SystemIds) >= 2 {
-		score += 2.0
-	} else {
-		score += 1.0
+// the tiers of the confidence score from the highest threshold, which also caps the counts grouped by GetStats
+var (
+	rejectedConfidenceTiers = []confidenceTier{{10, 5.0}, {5, 3.0}, {3, 1.0}} // up to 5 points
+	systemsConfidenceTiers  = []confidenceTier{{3, 3.0}, {2, 2.0}, {0, 1.0}}  // up to 3 points
+	daysConfidenceTiers     = []confidenceTier{{7, 2.0}, {3, 1.0}}            // up to 2 points, phrases appearing over longer time periods being more suspicious
+)
+
+func confidenceTierScore(tiers []confidenceTier, count float64) float64 {
+	for _, tier := range tiers {
+		if count >= float64(tier.threshold) {
+			return tier.score
+		}
 	}
+	return 0
+}
 
-	// Time window score (up to 2 points)
-	// Phrases that appear over longer time periods are more suspicious
-	daysSinceFirst := float64(time.Now().UnixMilli()-sh.FirstSeenAt) / (1000.0 * 60 * 60 * 24)
-	if daysSinceFirst >= 7 {
-		score += 2.0
-	} else if daysSinceFirst >= 3 {
-		score += 1.0
+// confidenceScore scores a phrase from its rejected and accepted counts, the number of systems it was
+// seen on and the days since it was first seen
+func confidenceScore(rejectedCount int, acceptedCount int, systemCount int, days float64) float64 {
+	// If it ever appeared in accepted calls, confidence drops dramatically
+	if acceptedCount > 0 {
+		return 0.0
 	}
 
-	return score
+	return confidenceTierScore(rejectedConfidenceTiers, float64(rejectedCount)) +
+		confidenceTierScore(systemsConfidenceTiers, float64(systemCount)) +
+		confidenceTierScore(daysConfidenceTiers, days)
 }
 
 // shouldAutoAdd determines if a phrase should be automatically added to the filter
@@ -249,7 +271,62 @@ func (hd *HallucinationDetector) autoAddPattern(sh *SuspectedHallucination) {
 		sh.Phrase, sh.RejectedCount, len(sh.SystemIds), sh.ConfidenceScore))
 }
 
-// getOrCreatePhrase gets an existing phrase or creates a new one
+// defaultHallucinationSimilarityThreshold collapses variations like "THANK YOU FOR WATCHING" and
+// "THANKS FOR WATCHING", which are 0.82 similar
+const defaultHallucinationSimilarityThreshold = 0.8
+
+// hallucinationSimilarityThreshold returns the configured similarity ratio of variations of a phrase
+func (hd *HallucinationDetector) hallucinationSimilarityThreshold() float64 {
+	if threshold := hd.controller.Options.TranscriptionConfig.HallucinationSimilarityThreshold; threshold > 0 && threshold <= 1 {
+		return threshold
+	}
+	return defaultHallucinationSimilarityThreshold
+}
+
+// phraseSimilarity returns 1 minus the levenshtein distance of the phrases relative to the longest one
+func phraseSimilarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	// single row levenshtein distance
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		diagonal := row[0]
+		row[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			above := row[j]
+			row[j] = diagonal + cost
+			if above+1 < row[j] {
+				row[j] = above + 1
+			}
+			if row[j-1]+1 < row[j] {
+				row[j] = row[j-1] + 1
+			}
+			diagonal = above
+		}
+	}
+
+	return 1 - float64(row[len(rb)])/float64(longest)
+}
+
+// getOrCreatePhrase gets an existing phrase, or a tracked variation of it, or creates a new one
 func (hd *HallucinationDetector) getOrCreatePhrase(phrase string, systemId uint64) (*SuspectedHallucination, error) {
 	// Try to get existing
 	existing, err := hd.getPhrase(phrase)
@@ -257,6 +334,15 @@ func (hd *HallucinationDetector) getOrCreatePhrase(phrase string, systemId uint6
 		return existing, nil
 	}
 
+	// Then the most similar tracked phrase
+	if threshold := hd.hallucinationSimilarityThreshold(); threshold < 1 {
+		if similar, err := hd.getSimilarPhrase(phrase, threshold); err != nil {
+			hd.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to look for similar phrases: %v", err))
+		} else if similar != nil {
+			return similar, nil
+		}
+	}
+
 	// Create new
 	now := time.Now().UnixMilli()
 	sh := &SuspectedHallucination{
@@ -318,6 +404,60 @@ func (hd *HallucinationDetector) getPhrase(phrase string) (*SuspectedHallucinati
 	return &sh, nil
 }
 
+// getSimilarPhrase retrieves the tracked phrase most similar to the phrase, nil when none reaches
+// the threshold. Only phrases whose length allows reaching it are compared.
+func (hd *HallucinationDetector) getSimilarPhrase(phrase string, threshold float64) (*SuspectedHallucination, error) {
+	length := float64(len([]rune(phrase)))
+
+	query := fmt.Sprintf(`SELECT "id", "phrase", "rejectedCount", "acceptedCount", "firstSeenAt", "lastSeenAt", "systemIds", "status", "autoAdded", "createdAt", "updatedAt" FROM "suspectedHallucinations" WHERE length("phrase") BETWEEN %d AND %d`,
+		int(math.Ceil(length*threshold)), int(math.Floor(length/threshold)))
+
+	rows, err := hd.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		best           *SuspectedHallucination
+		bestSimilarity float64
+	)
+
+	for rows.Next() {
+		var sh SuspectedHallucination
+		var systemIdsJson string
+
+		if err := rows.Scan(&sh.Id, &sh.Phrase, &sh.RejectedCount, &sh.AcceptedCount,
+			&sh.FirstSeenAt, &sh.LastSeenAt, &systemIdsJson, &sh.Status,
+			&sh.AutoAdded, &sh.CreatedAt, &sh.UpdatedAt); err != nil {
+			continue
+		}
+
+		similarity := phraseSimilarity(phrase, sh.Phrase)
+		if similarity < threshold || (best != nil && similarity <= bestSimilarity) {
+			continue
+		}
+
+		// Parse system IDs
+		if systemIdsJson != "" {
+			json.Unmarshal([]byte(systemIdsJson), &sh.SystemIds)
+		}
+
+		best, bestSimilarity = &sh, similarity
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if best != nil {
+		// Calculate confidence score
+		best.ConfidenceScore = hd.calculateConfidenceScore(best)
+	}
+
+	return best, nil
+}
+
 // savePhrase saves a phrase to the database
 func (hd *HallucinationDetector) savePhrase(sh *SuspectedHallucination) error {
 	systemIdsJson, _ := json.Marshal(sh.SystemIds)
@@ -433,3 +573,273 @@ func (hd *HallucinationDetector) RejectHallucination(id uint64) error {
 	return err
 }
 
+// HallucinationStats summarizes the phrases tracked by the hallucination detector
+type HallucinationStats struct {
+	TotalPhrases             int                       `json:"totalPhrases"`
+	ByStatus                 map[string]int            `json:"byStatus"` // "pending", "approved", "rejected", "auto_added"
+	TopRejected              []*SuspectedHallucination `json:"topRejected"`
+	AutoAddedLastWeek        int                       `json:"autoAddedLastWeek"`
+	AveragePendingConfidence float64                   `json:"averagePendingConfidence"`
+}
+
+// hallucinationStatsTopCount is the number of most rejected phrases in the stats
+const hallucinationStatsTopCount = 10
+
+// GetStats returns the counts of the tracked phrases by status, the most rejected ones, the ones auto-added
+// in the last 7 days and the average confidence score of the pending suggestions
+func (hd *HallucinationDetector) GetStats() (*HallucinationStats, error) {
+	stats := &HallucinationStats{
+		ByStatus:    map[string]int{"pending": 0, "approved": 0, "rejected": 0, "auto_added": 0},
+		TopRejected: []*SuspectedHallucination{},
+	}
+
+	now := time.Now()
+
+	query := fmt.Sprintf(`SELECT "status", COUNT(*), COUNT(*) FILTER (WHERE "autoAdded" AND "updatedAt" >= %d) FROM "suspectedHallucinations" GROUP BY "status"`, now.AddDate(0, 0, -7).UnixMilli())
+	rows, err := hd.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	for rows.Next() {
+		var (
+			status    string
+			count     int
+			autoAdded int
+		)
+		if err := rows.Scan(&status, &count, &autoAdded); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.ByStatus[status] = count
+		stats.TotalPhrases += count
+		stats.AutoAddedLastWeek += autoAdded
+	}
+	rows.Close()
+
+	// the pending phrases are grouped by the inputs of their confidence score, capped where the score stops changing
+	query = fmt.Sprintf(`SELECT LEAST("rejectedCount", %d), LEAST("acceptedCount", 1), LEAST(CASE WHEN "systemIds" LIKE '[%%' THEN json_array_length("systemIds"::json) ELSE 0 END, %d), LEAST(GREATEST((%d - "firstSeenAt") / 86400000, 0), %d), COUNT(*) FROM "suspectedHallucinations" WHERE "status" = 'pending' GROUP BY 1, 2, 3, 4`, rejectedConfidenceTiers[0].threshold, systemsConfidenceTiers[0].threshold, now.UnixMilli(), daysConfidenceTiers[0].threshold)
+	rows, err = hd.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	var (
+		pending int
+		score   float64
+	)
+	for rows.Next() {
+		var rejectedCount, acceptedCount, systemCount, days, count int
+		if err := rows.Scan(&rejectedCount, &acceptedCount, &systemCount, &days, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		pending += count
+		score += confidenceScore(rejectedCount, acceptedCount, systemCount, float64(days)) * float64(count)
+	}
+	rows.Close()
+	if pending > 0 {
+		stats.AveragePendingConfidence = score / float64(pending)
+	}
+
+	query = fmt.Sprintf(`SELECT "id", "phrase", "rejectedCount", "acceptedCount", "firstSeenAt", "lastSeenAt", "systemIds", "status", "autoAdded", "createdAt", "updatedAt" FROM "suspectedHallucinations" ORDER BY "rejectedCount" DESC, "lastSeenAt" DESC LIMIT %d`, hallucinationStatsTopCount)
+	rows, err = hd.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sh SuspectedHallucination
+		var systemIdsJson string
+
+		if err := rows.Scan(&sh.Id, &sh.Phrase, &sh.RejectedCount, &sh.AcceptedCount,
+			&sh.FirstSeenAt, &sh.LastSeenAt, &systemIdsJson, &sh.Status,
+			&sh.AutoAdded, &sh.CreatedAt, &sh.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		if systemIdsJson != "" {
+			json.Unmarshal([]byte(systemIdsJson), &sh.SystemIds)
+		}
+
+		sh.ConfidenceScore = hd.calculateConfidenceScore(&sh)
+
+		stats.TopRejected = append(stats.TopRejected, &sh)
+	}
+
+	return stats, rows.Err()
+}
+
+// hallucinationPruneCutoffs returns the time before which pending suspected phrases are deleted and the
+// one before which their rejected count decays, zero when disabled
+func hallucinationPruneCutoffs(config TranscriptionConfig, now time.Time) (retention time.Time, decay time.Time) {
+	if config.HallucinationRetentionDays > 0 {
+		retention = now.AddDate(0, 0, -config.HallucinationRetentionDays)
+	}
+	if config.HallucinationDecayDays > 0 {
+		decay = now.AddDate(0, 0, -config.HallucinationDecayDays)
+	}
+	return retention, decay
+}
+
+// Prune deletes the pending suspected phrases not seen within the retention and halves the rejected count
+// of the phrases not seen within the decay period, so that stale phrases can become legitimate vocabulary
+func (hd *HallucinationDetector) Prune(now time.Time) error {
+	retention, decay := hallucinationPruneCutoffs(hd.controller.Options.TranscriptionConfig, now)
+	if retention.IsZero() && decay.IsZero() {
+		return nil
+	}
+
+	hd.mutex.Lock()
+	defer hd.mutex.Unlock()
+
+	if !retention.IsZero() {
+		query := fmt.Sprintf(`DELETE FROM "suspectedHallucinations" WHERE "status" = 'pending' AND "lastSeenAt" < %d`, retention.UnixMilli())
+		res, err := hd.controller.Database.Sql.Exec(query)
+		if err != nil {
+			return fmt.Errorf("%v in %s", err, query)
+		}
+		if count, err := res.RowsAffected(); err == nil && count > 0 {
+			hd.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("deleted %d suspected hallucination(s) not seen for %d days", count, hd.controller.Options.TranscriptionConfig.HallucinationRetentionDays))
+		}
+	}
+
+	// updatedAt marks the last decay, so a phrase decays once per period while it is not seen
+	if !decay.IsZero() {
+		query := fmt.Sprintf(`UPDATE "suspectedHallucinations" SET "rejectedCount" = "rejectedCount" / 2, "updatedAt" = %d WHERE "rejectedCount" > 0 AND "lastSeenAt" < %d AND "updatedAt" < %d`, now.UnixMilli(), decay.UnixMilli(), decay.UnixMilli())
+		res, err := hd.controller.Database.Sql.Exec(query)
+		if err != nil {
+			return fmt.Errorf("%v in %s", err, query)
+		}
+		if count, err := res.RowsAffected(); err == nil && count > 0 {
+			hd.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("halved the rejected count of %d suspected hallucination(s) not seen for %d days", count, hd.controller.Options.TranscriptionConfig.HallucinationDecayDays))
+		}
+	}
+
+	return nil
+}
+
+const hallucinationPatternsVersion = 1
+
+// HallucinationPatternsExport is the portable list of hallucination filters, shared between instances
+type HallucinationPatternsExport struct {
+	Version  int      `json:"version"`
+	Patterns []string `json:"patterns"` // hallucinationPatterns of the transcription config
+	Approved []string `json:"approved"` // approved suspected hallucinations
+}
+
+// ExportPatterns serializes the hallucination patterns and the approved suspected hallucinations to JSON
+func (hd *HallucinationDetector) ExportPatterns() ([]byte, error) {
+	hd.mutex.Lock()
+	defer hd.mutex.Unlock()
+
+	export := HallucinationPatternsExport{
+		Version:  hallucinationPatternsVersion,
+		Patterns: append([]string{}, hd.controller.Options.TranscriptionConfig.HallucinationPatterns...),
+		Approved: []string{},
+	}
+
+	rows, err := hd.controller.Database.Sql.Query(`SELECT "phrase" FROM "suspectedHallucinations" WHERE "status" = 'approved' ORDER BY "phrase"`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var phrase string
+		if err := rows.Scan(&phrase); err != nil {
+			return nil, err
+		}
+		export.Approved = append(export.Approved, phrase)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(export)
+}
+
+// ImportPatterns replaces the hallucination patterns with the exported ones, or merges them with the
+// current ones. Imported phrases are tracked as approved suspected hallucinations.
+func (hd *HallucinationDetector) ImportPatterns(data []byte, merge bool) error {
+	imported, err := parseHallucinationPatterns(data)
+	if err != nil {
+		return err
+	}
+
+	hd.mutex.Lock()
+	defer hd.mutex.Unlock()
+
+	var current []string
+	if merge {
+		current = hd.controller.Options.TranscriptionConfig.HallucinationPatterns
+	}
+	hd.controller.Options.TranscriptionConfig.HallucinationPatterns = mergeHallucinationPatterns(current, imported)
+
+	if err := hd.controller.Options.Write(hd.controller.Database); err != nil {
+		return err
+	}
+
+	for _, phrase := range imported {
+		if err := hd.approvePhrase(phrase); err != nil {
+			hd.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to approve imported hallucination %q: %v", phrase, err))
+		}
+	}
+
+	mode := "merged"
+	if !merge {
+		mode = "replaced"
+	}
+	hd.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("%s hallucination patterns with %d imported ones", mode, len(imported)))
+
+	return nil
+}
+
+// approvePhrase marks a tracked phrase as approved, inserting it when it is not tracked yet
+func (hd *HallucinationDetector) approvePhrase(phrase string) error {
+	now := time.Now().UnixMilli()
+
+	res, err := hd.controller.Database.Sql.Exec(fmt.Sprintf(`UPDATE "suspectedHallucinations" SET "status" = 'approved', "updatedAt" = %d WHERE UPPER("phrase") = UPPER($1) AND "status" NOT IN ('approved', 'auto_added')`, now), phrase)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil && count > 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "suspectedHallucinations" ("phrase", "rejectedCount", "acceptedCount", "firstSeenAt", "lastSeenAt", "systemIds", "status", "autoAdded", "createdAt", "updatedAt") SELECT $1::text, 0, 0, %d, %d, '[]', 'approved', false, %d, %d WHERE NOT EXISTS (SELECT 1 FROM "suspectedHallucinations" WHERE UPPER("phrase") = UPPER($1::text))`,
+		now, now, now, now)
+	_, err = hd.controller.Database.Sql.Exec(query, phrase)
+	return err
+}
+
+// parseHallucinationPatterns returns the phrases of an export, de-duplicated case-insensitively
+func parseHallucinationPatterns(data []byte) ([]string, error) {
+	var export HallucinationPatternsExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid hallucination patterns: %v", err)
+	}
+
+	if export.Version == 0 || export.Version > hallucinationPatternsVersion {
+		return nil, fmt.Errorf("unsupported hallucination patterns version %d", export.Version)
+	}
+
+	return mergeHallucinationPatterns(export.Patterns, export.Approved), nil
+}
+
+// mergeHallucinationPatterns appends the patterns missing from the current ones, ignoring case and blanks
+func mergeHallucinationPatterns(current []string, patterns []string) []string {
+	merged := []string{}
+	seen := map[string]bool{}
+
+	for _, pattern := range append(append([]string{}, current...), patterns...) {
+		pattern = strings.TrimSpace(pattern)
+		key := strings.ToUpper(pattern)
+		if pattern == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, pattern)
+	}
+
+	return merged
+}
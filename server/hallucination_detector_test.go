@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRejectsHallucinationOnlyTranscripts(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+	controller.Options.TranscriptionConfig.HallucinationPatterns = []string{"THANKS FOR WATCHING", "PLEASE SUBSCRIBE"}
+	controller.Options.TranscriptionConfig.HallucinationDetectionMode = "off"
+	controller.HallucinationDetector = NewHallucinationDetector(controller)
+	queue := &TranscriptionQueue{controller: controller}
+
+	transcript := "Thanks for watching. Please subscribe"
+	cleaned, hadHallucinations := controller.cleanTranscript(transcript, 1)
+
+	if !hadHallucinations || !isHallucinationOnly(transcript, cleaned) {
+		t.Fatalf("transcript should be made of hallucinations only, cleaned to %q", cleaned)
+	}
+
+	if queue.rejectsHallucination(TranscriptionJob{CallId: 1, SystemId: 1}, transcript, cleaned) {
+		t.Error("transcripts should not be rejected while the policy is disabled")
+	}
+
+	events := make(chan TranscriptionWebhookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event TranscriptionWebhookEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer server.Close()
+
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	controller.Database = db
+	controller.Systems = NewSystems()
+	controller.Options.TranscriptionConfig.RejectHallucinations = true
+	controller.Options.TranscriptionConfig.WebhookURL = server.URL
+
+	if !queue.rejectsHallucination(TranscriptionJob{CallId: 1, SystemId: 1}, transcript, cleaned) {
+		t.Fatal("transcript should be rejected once the policy is enabled")
+	}
+
+	select {
+	case event := <-events:
+		if event.CallId != 1 || event.Status != "hallucination" || event.Transcript != "" {
+			t.Errorf("unexpected webhook event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook should be notified of the rejected call")
+	}
+
+	if len(d.queries) != 1 || !strings.Contains(d.queries[0], `"transcriptionStatus" = 'hallucination'`) {
+		t.Errorf("expected the call marked as hallucination, got %v", d.queries)
+	}
+
+	controller.HallucinationDetector = NewHallucinationDetector(controller)
+
+	voice := "ENGINE 5 RESPONDING thanks for watching"
+	if cleaned, _ := controller.cleanTranscript(voice, 2); isHallucinationOnly(voice, cleaned) {
+		t.Error("transcript with voice left after cleaning should be kept")
+	}
+	if isHallucinationOnly("", "") {
+		t.Error("empty transcript is not a hallucination")
+	}
+
+	if count := controller.HallucinationDetector.TrackRejectedTranscript(transcript, 1); count != 1 {
+		t.Errorf("expected the first rejected transcript to be counted, got %d", count)
+	}
+	controller.HallucinationDetector.TrackRejectedTranscript(transcript, 1)
+	if count := controller.HallucinationDetector.RejectedTranscripts(); count != 2 {
+		t.Errorf("expected 2 rejected transcripts, got %d", count)
+	}
+}
+
+func TestEmergencyVocabularyConfigured(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+	detector := NewHallucinationDetector(controller)
+
+	if !detector.containsEmergencyVocabulary("ENGINE 5 RESPONDING") {
+		t.Error("expected the built-in vocabulary to be protected")
+	}
+	if detector.containsEmergencyVocabulary("LIFEFLIGHT LAUNCHING") {
+		t.Error("expected no protection without configured vocabulary")
+	}
+
+	options := NewOptions()
+	options.FromMap(map[string]any{"transcriptionConfig": map[string]any{"emergencyVocabulary": []any{" LifeFlight ", "", "STAR"}}})
+	controller.Options.TranscriptionConfig.EmergencyVocabulary = options.TranscriptionConfig.EmergencyVocabulary
+
+	for _, phrase := range []string{"LIFEFLIGHT LAUNCHING", "star 2 airborne", "ENGINE 5 RESPONDING"} {
+		if !detector.containsEmergencyVocabulary(phrase) {
+			t.Errorf("expected %q to be protected", phrase)
+		}
+	}
+	if detector.containsEmergencyVocabulary("THANKS FOR WATCHING") {
+		t.Error("expected a hallucination not to be protected")
+	}
+}
+
+func TestPhraseSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b    string
+		similar bool
+	}{
+		{"THANK YOU FOR WATCHING", "THANKS FOR WATCHING", true},
+		{"THANKS FOR WATCHING", "THANKS FOR WATCHING!", true},
+		{"PLEASE SUBSCRIBE", "PLEASE SUBSCRIBE TO MY CHANNEL", false},
+		{"THANKS FOR WATCHING", "PLEASE SUBSCRIBE", false},
+	}
+
+	for _, c := range cases {
+		if similar := phraseSimilarity(c.a, c.b) >= defaultHallucinationSimilarityThreshold; similar != c.similar {
+			t.Errorf("%q and %q: expected similar %v, got %.2f", c.a, c.b, c.similar, phraseSimilarity(c.a, c.b))
+		}
+	}
+
+	if similarity := phraseSimilarity("KITTEN", "SITTING"); fmt.Sprintf("%.4f", similarity) != "0.5714" {
+		t.Errorf("expected a distance of 3, got a similarity of %v", similarity)
+	}
+	if phraseSimilarity("", "") != 1 || fmt.Sprintf("%.4f", phraseSimilarity("ÉTÉ", "ETE")) != "0.3333" {
+		t.Error("expected similarities counted in runes")
+	}
+
+	detector := NewHallucinationDetector(&Controller{Options: NewOptions()})
+	if threshold := detector.hallucinationSimilarityThreshold(); threshold != defaultHallucinationSimilarityThreshold {
+		t.Errorf("expected the default threshold, got %v", threshold)
+	}
+	detector.controller.Options.TranscriptionConfig.HallucinationSimilarityThreshold = 0.9
+	if threshold := detector.hallucinationSimilarityThreshold(); threshold != 0.9 {
+		t.Errorf("expected the configured threshold, got %v", threshold)
+	}
+}
+
+func TestParseHallucinationPatterns(t *testing.T) {
+	patterns, err := parseHallucinationPatterns([]byte(`{"version":1,"patterns":["THANKS FOR WATCHING"," please subscribe "],"approved":["thanks for watching","SUBTITLES BY"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(patterns) != "[THANKS FOR WATCHING please subscribe SUBTITLES BY]" {
+		t.Errorf("expected patterns de-duplicated case-insensitively, got %q", patterns)
+	}
+
+	for _, data := range []string{`not json`, `{"patterns":["A"]}`, `{"version":2,"patterns":["A"]}`} {
+		if _, err := parseHallucinationPatterns([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+
+	merged := mergeHallucinationPatterns([]string{"PLEASE SUBSCRIBE", ""}, []string{"Please Subscribe", "SUBTITLES BY"})
+	if fmt.Sprint(merged) != "[PLEASE SUBSCRIBE SUBTITLES BY]" {
+		t.Errorf("expected current patterns kept first, got %q", merged)
+	}
+}
+
+func TestHallucinationPruneCutoffs(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	retention, decay := hallucinationPruneCutoffs(TranscriptionConfig{HallucinationRetentionDays: 90, HallucinationDecayDays: 30}, now)
+	if !retention.Equal(now.AddDate(0, 0, -90)) || !decay.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("got retention %v and decay %v", retention, decay)
+	}
+
+	retention, decay = hallucinationPruneCutoffs(TranscriptionConfig{}, now)
+	if !retention.IsZero() || !decay.IsZero() {
+		t.Errorf("expected zero days to disable pruning, got retention %v and decay %v", retention, decay)
+	}
+
+	detector := NewHallucinationDetector(&Controller{Options: NewOptions()})
+	if err := detector.Prune(now); err != nil {
+		t.Errorf("expected a disabled prune to leave the database alone, got %v", err)
+	}
+
+	options := NewOptions()
+	options.TranscriptionConfig.HallucinationRetentionDays = 90
+	options.FromMap(map[string]any{"transcriptionConfig": map[string]any{"hallucinationRetentionDays": float64(0), "hallucinationDecayDays": float64(7)}})
+	if options.TranscriptionConfig.HallucinationRetentionDays != 0 || options.TranscriptionConfig.HallucinationDecayDays != 7 {
+		t.Errorf("got retention %d and decay %d days", options.TranscriptionConfig.HallucinationRetentionDays, options.TranscriptionConfig.HallucinationDecayDays)
+	}
+}
+
+func TestGroupedConfidenceScore(t *testing.T) {
+	now := time.Now()
+	detector := NewHallucinationDetector(&Controller{Options: NewOptions()})
+
+	phrases := []struct {
+		phrase  SuspectedHallucination
+		buckets [4]int // rejected count, accepted count, system count and days, capped as in GetStats
+	}{
+		{SuspectedHallucination{RejectedCount: 25, SystemIds: []uint64{1, 2, 3, 4, 5}, FirstSeenAt: now.AddDate(0, 0, -30).UnixMilli()}, [4]int{10, 0, 3, 7}},
+		{SuspectedHallucination{RejectedCount: 4, SystemIds: []uint64{1, 2}, FirstSeenAt: now.AddDate(0, 0, -4).UnixMilli()}, [4]int{4, 0, 2, 4}},
+		{SuspectedHallucination{RejectedCount: 1, SystemIds: []uint64{1}, FirstSeenAt: now.UnixMilli()}, [4]int{1, 0, 1, 0}},
+		{SuspectedHallucination{RejectedCount: 12, AcceptedCount: 3, SystemIds: []uint64{1, 2, 3}, FirstSeenAt: now.AddDate(0, 0, -9).UnixMilli()}, [4]int{10, 1, 3, 7}},
+	}
+
+	for _, p := range phrases {
+		expected := detector.calculateConfidenceScore(&p.phrase)
+		if score := confidenceScore(p.buckets[0], p.buckets[1], p.buckets[2], float64(p.buckets[3])); score != expected {
+			t.Errorf("buckets %v scored %.1f, expected %.1f as the phrase", p.buckets, score, expected)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IncidentRules tell which calls belong to the same incident
+type IncidentRules struct {
+	Window   time.Duration
+	Units    bool     // calls of a system sharing a unit
+	Patches  bool     // calls of a system sharing a patched talkgroup
+	Keywords []string // calls whose transcripts mention the same keyword, across systems
+}
+
+func newIncidentRules(options *Options) IncidentRules {
+	rules := IncidentRules{
+		Window:  time.Duration(options.IncidentGroupingWindow) * time.Second,
+		Units:   options.IncidentGroupingUnits,
+		Patches: options.IncidentGroupingPatches,
+	}
+
+	for _, keyword := range strings.Split(options.IncidentGroupingKeywords, ",") {
+		if keyword = strings.ToUpper(strings.TrimSpace(keyword)); keyword != "" {
+			rules.Keywords = append(rules.Keywords, keyword)
+		}
+	}
+
+	return rules
+}
+
+type incidentCall struct {
+	callId       uint64
+	incidentId   uint64
+	systemId     uint64
+	talkgroupRef uint
+	timestamp    time.Time
+	units        []uint
+	patches      []uint
+	keywords     []string
+}
+
+func newIncidentCall(call *Call) *incidentCall {
+	entry := &incidentCall{
+		callId:    call.Id,
+		timestamp: call.Timestamp,
+		patches:   call.Patches,
+	}
+
+	if call.System != nil {
+		entry.systemId = call.System.Id
+	}
+
+	if call.Talkgroup != nil {
+		entry.talkgroupRef = call.Talkgroup.TalkgroupRef
+	}
+
+	for _, unit := range call.Units {
+		if unit.UnitRef > 0 {
+			entry.units = append(entry.units, unit.UnitRef)
+		}
+	}
+
+	return entry
+}
+
+func (entry *incidentCall) relatedTo(other *incidentCall, rules IncidentRules) bool {
+	if rules.Keywords != nil && sharesString(entry.keywords, other.keywords) {
+		return true
+	}
+
+	if entry.systemId != other.systemId {
+		return false
+	}
+
+	if rules.Units && sharesUint(entry.units, other.units) {
+		return true
+	}
+
+	if rules.Patches {
+		if sharesUint(append([]uint{entry.talkgroupRef}, entry.patches...), other.patches) || sharesUint(entry.patches, []uint{other.talkgroupRef}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sharesUint(a []uint, b []uint) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x > 0 && x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sharesString(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IncidentGrouper keeps the recent calls to link each new one to those of the same incident.
+// An incident is identified by the id of its first call.
+type IncidentGrouper struct {
+	mutex sync.Mutex
+	calls []*incidentCall
+}
+
+func NewIncidentGrouper() *IncidentGrouper {
+	return &IncidentGrouper{}
+}
+
+// Assign records the call and returns its incident id, 0 when unrelated to any recent call, along
+// with the ids of the calls, itself included, which were not part of the incident until now
+func (grouper *IncidentGrouper) Assign(entry *incidentCall, rules IncidentRules) (uint64, []uint64) {
+	grouper.mutex.Lock()
+	defer grouper.mutex.Unlock()
+
+	calls := grouper.calls[:0]
+	for _, c := range grouper.calls {
+		if entry.timestamp.Sub(c.timestamp) <= rules.Window*2 {
+			calls = append(calls, c)
+		}
+	}
+	grouper.calls = append(calls, entry)
+
+	return grouper.assign(entry, rules)
+}
+
+// AssignKeywords sets the incident keywords mentioned by a recent call once transcribed, then
+// assigns its incident as Assign does
+func (grouper *IncidentGrouper) AssignKeywords(callId uint64, keywords []string, rules IncidentRules) (uint64, []uint64) {
+	grouper.mutex.Lock()
+	defer grouper.mutex.Unlock()
+
+	for _, c := range grouper.calls {
+		if c.callId == callId {
+			c.keywords = keywords
+			return grouper.assign(c, rules)
+		}
+	}
+
+	return 0, nil
+}
+
+func (grouper *IncidentGrouper) assign(current *incidentCall, rules IncidentRules) (uint64, []uint64) {
+	related := []*incidentCall{}
+	for _, c := range grouper.calls {
+		if c == current {
+			continue
+		}
+		delta := current.timestamp.Sub(c.timestamp)
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta <= rules.Window && current.relatedTo(c, rules) {
+			related = append(related, c)
+		}
+	}
+
+	if len(related) == 0 {
+		return current.incidentId, nil
+	}
+
+	incidentId := current.incidentId
+	for _, c := range related {
+		if c.incidentId > 0 && (incidentId == 0 || c.incidentId < incidentId) {
+			incidentId = c.incidentId
+		}
+	}
+	if incidentId == 0 {
+		incidentId = current.callId
+		for _, c := range related {
+			if c.callId < incidentId {
+				incidentId = c.callId
+			}
+		}
+	}
+
+	linked := []uint64{}
+	if current.incidentId != incidentId {
+		current.incidentId = incidentId
+		linked = append(linked, current.callId)
+	}
+	for _, c := range related {
+		if c.incidentId == 0 {
+			c.incidentId = incidentId
+			linked = append(linked, c.callId)
+		}
+	}
+
+	return incidentId, linked
+}
+
+// groupIncident links the call to the recent calls of the same incident
+func (controller *Controller) groupIncident(call *Call) {
+	if !controller.Options.IncidentGrouping || controller.Incidents == nil {
+		return
+	}
+
+	incidentId, linked := controller.Incidents.Assign(newIncidentCall(call), newIncidentRules(controller.Options))
+	call.IncidentId = incidentId
+
+	controller.writeIncident(incidentId, linked)
+}
+
+// groupIncidentByTranscript links the call to the recent calls mentioning the same incident keywords
+func (controller *Controller) groupIncidentByTranscript(callId uint64, transcript string) {
+	if !controller.Options.IncidentGrouping || controller.Incidents == nil {
+		return
+	}
+
+	rules := newIncidentRules(controller.Options)
+
+	keywords := []string{}
+	transcript = strings.ToUpper(transcript)
+	for _, keyword := range rules.Keywords {
+		if strings.Contains(transcript, keyword) {
+			keywords = append(keywords, keyword)
+		}
+	}
+	if len(keywords) == 0 {
+		return
+	}
+
+	incidentId, linked := controller.Incidents.AssignKeywords(callId, keywords, rules)
+
+	controller.writeIncident(incidentId, linked)
+}
+
+func (controller *Controller) writeIncident(incidentId uint64, callIds []uint64) {
+	if incidentId == 0 || len(callIds) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(callIds)
+	if err != nil {
+		return
+	}
+	in := strings.ReplaceAll(strings.ReplaceAll(string(b), "[", "("), "]", ")")
+
+	query := fmt.Sprintf(`UPDATE "calls" SET "incidentId" = %d WHERE "callId" IN %s`, incidentId, in)
+	if _, err := controller.Database.Sql.Exec(query); err != nil {
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to link calls %v to incident %d: %v", callIds, incidentId, err))
+	}
+}
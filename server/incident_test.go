@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncidentGrouperAssign(t *testing.T) {
+	rules := IncidentRules{Window: 10 * time.Minute, Units: true, Patches: true, Keywords: []string{"STRUCTURE FIRE"}}
+	grouper := NewIncidentGrouper()
+	start := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+
+	call := func(id uint64, system uint64, talkgroup uint, at time.Duration, units []uint, patches []uint) *incidentCall {
+		return &incidentCall{callId: id, systemId: system, talkgroupRef: talkgroup, timestamp: start.Add(at), units: units, patches: patches}
+	}
+
+	if id, _ := grouper.Assign(call(1, 1, 100, 0, []uint{501}, nil), rules); id != 0 {
+		t.Fatalf("first call should not be part of an incident, got %d", id)
+	}
+
+	id, linked := grouper.Assign(call(2, 1, 200, time.Minute, []uint{501, 502}, nil), rules)
+	if id != 1 || len(linked) != 2 {
+		t.Fatalf("call sharing a unit should start the incident of the first call, got %d linking %v", id, linked)
+	}
+
+	if id, _ := grouper.Assign(call(3, 1, 300, 2*time.Minute, nil, []uint{200}), rules); id != 1 {
+		t.Errorf("call patched with a talkgroup of the incident should join it, got %d", id)
+	}
+
+	if id, _ := grouper.Assign(call(4, 1, 400, 3*time.Minute, []uint{999}, nil), rules); id != 0 {
+		t.Errorf("unrelated call should not join the incident, got %d", id)
+	}
+
+	if id, _ := grouper.Assign(call(5, 2, 100, 3*time.Minute, []uint{501}, nil), rules); id != 0 {
+		t.Errorf("call of another system sharing a unit ref should not join the incident, got %d", id)
+	}
+
+	if id, _ := grouper.AssignKeywords(5, []string{"STRUCTURE FIRE"}, rules); id != 0 {
+		t.Errorf("keyword mentioned by a single call should not form an incident, got %d", id)
+	}
+	id, linked = grouper.AssignKeywords(4, []string{"STRUCTURE FIRE"}, rules)
+	if id != 4 || len(linked) != 2 {
+		t.Errorf("calls mentioning the same incident keyword should share an incident, got %d linking %v", id, linked)
+	}
+
+	if id, _ := grouper.Assign(call(6, 1, 600, 30*time.Minute, []uint{501}, nil), rules); id != 0 {
+		t.Errorf("call outside the window should not join the incident, got %d", id)
+	}
+}
+
+func TestIncidentGrouperRulesDisabled(t *testing.T) {
+	rules := IncidentRules{Window: 10 * time.Minute}
+	grouper := NewIncidentGrouper()
+	now := time.Now()
+
+	grouper.Assign(&incidentCall{callId: 1, systemId: 1, talkgroupRef: 100, timestamp: now, units: []uint{501}}, rules)
+
+	if id, _ := grouper.Assign(&incidentCall{callId: 2, systemId: 1, talkgroupRef: 200, timestamp: now, units: []uint{501}, patches: []uint{100}}, rules); id != 0 {
+		t.Errorf("calls should not be grouped when the rules are disabled, got %d", id)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// orphanTables are the tables whose rows belong to a call. Their foreign keys cascade on delete,
+// but rows imported or inserted outside the normal path can still point at calls that are gone.
+var orphanTables = []string{
+	"alerts",
+	"callPatches",
+	"callUnits",
+	"delayed",
+	"downstreamQueue",
+	"keywordMatches",
+	"transcriptions",
+}
+
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+type OrphanSweepResult struct {
+	Table   string
+	Deleted int64
+}
+
+func orphanSweepQuery(table string) string {
+	return fmt.Sprintf(`DELETE FROM "%s" WHERE NOT EXISTS (SELECT 1 FROM "calls" WHERE "calls"."callId" = "%s"."callId")`, table, table)
+}
+
+// orphanSweepDue tells if the sweep should run, an interval of 0 disables it
+func orphanSweepDue(last time.Time, now time.Time, interval uint) bool {
+	if interval == 0 {
+		return false
+	}
+	return last.IsZero() || now.Sub(last) >= time.Duration(interval)*time.Hour
+}
+
+// SweepOrphans deletes the rows of orphanTables whose call no longer exists
+func SweepOrphans(db sqlExecer) ([]OrphanSweepResult, error) {
+	results := []OrphanSweepResult{}
+
+	for _, table := range orphanTables {
+		query := orphanSweepQuery(table)
+
+		res, err := db.Exec(query)
+		if err != nil {
+			return results, fmt.Errorf("%s in %s", err, query)
+		}
+
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return results, err
+		}
+
+		if deleted > 0 {
+			results = append(results, OrphanSweepResult{Table: table, Deleted: deleted})
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeCallStore keeps the callId of the rows of each table and applies the orphan sweep queries
+type fakeCallStore struct {
+	calls map[uint64]bool
+	rows  map[string][]uint64
+}
+
+func (store *fakeCallStore) Exec(query string, args ...any) (sql.Result, error) {
+	for table, callIds := range store.rows {
+		if query != orphanSweepQuery(table) {
+			continue
+		}
+
+		kept := []uint64{}
+		for _, callId := range callIds {
+			if store.calls[callId] {
+				kept = append(kept, callId)
+			}
+		}
+		store.rows[table] = kept
+
+		return fakeResult(len(callIds) - len(kept)), nil
+	}
+
+	return nil, fmt.Errorf("unexpected query %s", query)
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 0, nil }
+
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestSweepOrphans(t *testing.T) {
+	store := &fakeCallStore{
+		calls: map[uint64]bool{1: true, 2: true},
+		rows:  map[string][]uint64{},
+	}
+	for _, table := range orphanTables {
+		store.rows[table] = []uint64{1, 2}
+	}
+	store.rows["callUnits"] = []uint64{1, 9, 9, 2}
+	store.rows["transcriptions"] = []uint64{7, 2}
+
+	results, err := SweepOrphans(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []OrphanSweepResult{{Table: "callUnits", Deleted: 2}, {Table: "transcriptions", Deleted: 1}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, results)
+	}
+
+	for _, table := range orphanTables {
+		valid := []uint64{1, 2}
+		if table == "transcriptions" {
+			valid = []uint64{2}
+		}
+		if !reflect.DeepEqual(store.rows[table], valid) {
+			t.Errorf("%s: expected %v to remain, got %v", table, valid, store.rows[table])
+		}
+	}
+}
+
+func TestOrphanSweepDue(t *testing.T) {
+	now := time.Now()
+
+	if orphanSweepDue(time.Time{}, now, 0) {
+		t.Error("expected an interval of 0 to disable the sweep")
+	}
+	if !orphanSweepDue(time.Time{}, now, 24) {
+		t.Error("expected the first sweep to be due")
+	}
+	if orphanSweepDue(now.Add(-23*time.Hour), now, 24) {
+		t.Error("expected the sweep not to be due before the interval")
+	}
+	if !orphanSweepDue(now.Add(-24*time.Hour), now, 24) {
+		t.Error("expected the sweep to be due after the interval")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeywordCooldowns keeps a keyword recurring in every transmission of a busy incident from
+// alerting a user again and again. The matches are still recorded, only the alerts are held back.
+type KeywordCooldowns struct {
+	lastAlerts map[string]time.Time // key: "userId:keyword"
+	mutex      sync.Mutex
+}
+
+func NewKeywordCooldowns() *KeywordCooldowns {
+	return &KeywordCooldowns{
+		lastAlerts: map[string]time.Time{},
+		mutex:      sync.Mutex{},
+	}
+}
+
+// Filter returns the matches of keywords the user wasn't alerted of within the cooldown, and
+// records them as alerted. Keywords are compared regardless of case, a zero cooldown keeps them all.
+func (cooldowns *KeywordCooldowns) Filter(userId uint64, matches []KeywordMatch, cooldown time.Duration, now time.Time) []KeywordMatch {
+	if cooldown <= 0 {
+		return matches
+	}
+
+	cooldowns.mutex.Lock()
+	defer cooldowns.mutex.Unlock()
+
+	for key, last := range cooldowns.lastAlerts {
+		if now.Sub(last) >= cooldown {
+			delete(cooldowns.lastAlerts, key)
+		}
+	}
+
+	alerted := map[string]bool{}
+	filtered := []KeywordMatch{}
+
+	for _, match := range matches {
+		key := fmt.Sprintf("%d:%s", userId, strings.ToLower(strings.TrimSpace(match.Keyword)))
+
+		if _, ok := cooldowns.lastAlerts[key]; ok && !alerted[key] {
+			continue
+		}
+
+		alerted[key] = true
+		filtered = append(filtered, match)
+	}
+
+	for key := range alerted {
+		cooldowns.lastAlerts[key] = now
+	}
+
+	return filtered
+}
+
+// keywordPushBatch is the users of a call pushed the same keywords
+type keywordPushBatch struct {
+	keywords []string
+	userIds  []uint64
+}
+
+// addKeywordPush adds the user to the batch of the keywords the user is alerted of, so a push
+// names only the keywords past the cooldown of its users
+func addKeywordPush(batches []*keywordPushBatch, userId uint64, matches []KeywordMatch) []*keywordPushBatch {
+	keywords := make([]string, len(matches))
+	for i, match := range matches {
+		keywords[i] = match.Keyword
+	}
+
+	for _, batch := range batches {
+		if slices.Equal(batch.keywords, keywords) {
+			batch.userIds = append(batch.userIds, userId)
+			return batches
+		}
+	}
+
+	return append(batches, &keywordPushBatch{keywords: keywords, userIds: []uint64{userId}})
+}
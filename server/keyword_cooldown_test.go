@@ -0,0 +1,102 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func keywordsOf(matches []KeywordMatch) []string {
+	keywords := []string{}
+	for _, match := range matches {
+		keywords = append(keywords, match.Keyword)
+	}
+	return keywords
+}
+
+func TestKeywordCooldownsFilter(t *testing.T) {
+	cooldowns := NewKeywordCooldowns()
+	cooldown := 10 * time.Minute
+	now := time.Now()
+
+	fire := []KeywordMatch{{Keyword: "FIRE"}}
+
+	if got := cooldowns.Filter(1, fire, cooldown, now); len(got) != 1 {
+		t.Fatalf("first match filtered out, got %v", keywordsOf(got))
+	}
+
+	if got := cooldowns.Filter(1, []KeywordMatch{{Keyword: "fire"}}, cooldown, now.Add(5*time.Minute)); len(got) != 0 {
+		t.Errorf("match within the cooldown should be held back, got %v", keywordsOf(got))
+	}
+
+	if got := cooldowns.Filter(2, fire, cooldown, now.Add(5*time.Minute)); len(got) != 1 {
+		t.Errorf("another user should still be alerted, got %v", keywordsOf(got))
+	}
+
+	got := cooldowns.Filter(1, []KeywordMatch{{Keyword: "FIRE"}, {Keyword: "smoke"}}, cooldown, now.Add(6*time.Minute))
+	if len(got) != 1 || got[0].Keyword != "smoke" {
+		t.Errorf("only the keyword not cooling down should alert, got %v", keywordsOf(got))
+	}
+
+	if got := cooldowns.Filter(1, fire, cooldown, now.Add(10*time.Minute)); len(got) != 1 {
+		t.Errorf("match after the cooldown should alert again, got %v", keywordsOf(got))
+	}
+}
+
+func TestKeywordCooldownsFilterDisabled(t *testing.T) {
+	cooldowns := NewKeywordCooldowns()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if got := cooldowns.Filter(1, []KeywordMatch{{Keyword: "FIRE"}}, 0, now); len(got) != 1 {
+			t.Fatalf("a zero cooldown should keep every match, got %v", keywordsOf(got))
+		}
+	}
+}
+
+func TestKeywordCooldownsFilterRepeatedInCall(t *testing.T) {
+	cooldowns := NewKeywordCooldowns()
+
+	got := cooldowns.Filter(1, []KeywordMatch{{Keyword: "fire", Position: 0}, {Keyword: "fire", Position: 40}}, time.Minute, time.Now())
+	if len(got) != 2 {
+		t.Errorf("every match of a keyword in the alerting call should be kept, got %v", keywordsOf(got))
+	}
+}
+
+func TestAddKeywordPushFilteredKeywords(t *testing.T) {
+	cooldowns := NewKeywordCooldowns()
+	now := time.Now()
+	matches := []KeywordMatch{{Keyword: "fire"}, {Keyword: "smoke"}}
+
+	// user 1 was just alerted of fire
+	cooldowns.Filter(1, []KeywordMatch{{Keyword: "fire"}}, 10*time.Minute, now)
+
+	var batches []*keywordPushBatch
+	for _, userId := range []uint64{1, 2, 3} {
+		batches = addKeywordPush(batches, userId, cooldowns.Filter(userId, matches, 10*time.Minute, now))
+	}
+
+	if len(batches) != 2 {
+		t.Fatalf("expected a push per set of keywords, got %d", len(batches))
+	}
+	if len(batches[0].keywords) != 1 || batches[0].keywords[0] != "smoke" || len(batches[0].userIds) != 1 || batches[0].userIds[0] != 1 {
+		t.Errorf("expected user 1 pushed smoke only, got %v %v", batches[0].keywords, batches[0].userIds)
+	}
+	if len(batches[1].keywords) != 2 || len(batches[1].userIds) != 2 {
+		t.Errorf("expected users 2 and 3 pushed both keywords, got %v %v", batches[1].keywords, batches[1].userIds)
+	}
+}
@@ -16,10 +16,22 @@
 package main
 
 import (
+	"container/list"
+	"fmt"
+	"log"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
+// maxKeywordRegexLength bounds the size of the regex keywords. Go regexps run in linear time, without
+// backtracking, so the size of the pattern is what bounds the cost of matching it.
+const maxKeywordRegexLength = 256
+
+// maxKeywordRegexps bounds the number of compiled regex keywords cached, the least recently used being evicted
+const maxKeywordRegexps = 1024
+
 // KeywordMatch represents a matched keyword in a transcript
 type KeywordMatch struct {
 	Keyword  string
@@ -32,6 +44,21 @@ type KeywordMatch struct {
 // KeywordMatcher handles keyword matching in transcripts
 type KeywordMatcher struct {
 	contextChars int // Number of characters to include on each side of match
+
+	// PhoneticEnabled also matches the words of the transcript sounding like the keywords, see MatchPhoneticKeywords
+	PhoneticEnabled bool
+
+	// regexps caches the compiled regex keywords, nil for the invalid ones, regexpsUsed ordering them
+	// from the most recently used
+	regexps      map[string]*list.Element
+	regexpsUsed  *list.List
+	regexpsMutex sync.Mutex
+}
+
+// keywordRegexpEntry is a compiled regex keyword of the cache
+type keywordRegexpEntry struct {
+	keyword string
+	re      *regexp.Regexp
 }
 
 // NewKeywordMatcher creates a new keyword matcher
@@ -43,7 +70,19 @@ func NewKeywordMatcher() *KeywordMatcher {
 
 // MatchKeywords matches keywords against a transcript (case-insensitive, whole-word only)
 // Transcript should already be in ALL CAPS
-func (matcher *KeywordMatcher) MatchKeywords(transcript string, keywords []string) []KeywordMatch {
+// Nothing matches when the transcript holds one of the exclusions, e.g. TEST for "THIS IS A TEST"
+func (matcher *KeywordMatcher) MatchKeywords(transcript string, keywords []string, exclusions []string) []KeywordMatch {
+	return matcher.matchKeywords(transcript, keywords, exclusions, matcher.PhoneticEnabled)
+}
+
+// MatchPhoneticKeywords matches keywords against a transcript like MatchKeywords, and also the words of the
+// transcript with the same Metaphone codes, for the proper nouns misheard by the speech to text, e.g.
+// MERIDIAN in "MARIDIAN AVENUE". Keyword words shorter than 4 letters still match exactly.
+func (matcher *KeywordMatcher) MatchPhoneticKeywords(transcript string, keywords []string, exclusions []string) []KeywordMatch {
+	return matcher.matchKeywords(transcript, keywords, exclusions, true)
+}
+
+func (matcher *KeywordMatcher) matchKeywords(transcript string, keywords []string, exclusions []string, phonetic bool) []KeywordMatch {
 	matches := []KeywordMatch{}
 	
 	if transcript == "" || len(keywords) == 0 {
@@ -52,11 +91,28 @@ func (matcher *KeywordMatcher) MatchKeywords(transcript string, keywords []strin
 	
 	// Ensure transcript is uppercase
 	transcriptUpper := strings.ToUpper(transcript)
+
+	if matcher.excluded(transcriptUpper, exclusions) {
+		return matches
+	}
 	
 	for _, keyword := range keywords {
 		if keyword == "" {
 			continue
 		}
+
+		if isRegexKeyword(keyword) {
+			if re := matcher.keywordRegexp(keyword); re != nil {
+				for _, match := range re.FindAllStringIndex(transcriptUpper, -1) {
+					matches = append(matches, KeywordMatch{
+						Keyword:  keyword,
+						Context:  matcher.extractContext(transcript, match[0], match[1]-match[0]),
+						Position: match[0],
+					})
+				}
+			}
+			continue
+		}
 		
 		// Convert keyword to uppercase for case-insensitive matching
 		keywordUpper := strings.ToUpper(strings.TrimSpace(keyword))
@@ -112,10 +168,147 @@ func (matcher *KeywordMatcher) MatchKeywords(transcript string, keywords []strin
 			})
 		}
 	}
+
+	if phonetic {
+		matches = append(matches, matcher.matchPhonetic(transcript, transcriptUpper, keywords, matches)...)
+	}
 	
 	return matches
 }
 
+// matchPhonetic matches the words of the transcript sounding like the keywords, but for the exact matches
+func (matcher *KeywordMatcher) matchPhonetic(transcript string, transcriptUpper string, keywords []string, exact []KeywordMatch) []KeywordMatch {
+	matches := []KeywordMatch{}
+
+	matched := map[string]bool{}
+	for _, match := range exact {
+		matched[fmt.Sprintf("%s:%d", match.Keyword, match.Position)] = true
+	}
+
+	words := keywordWordRegexp.FindAllStringIndex(transcriptUpper, -1)
+	codes := make([]string, len(words))
+	for i, word := range words {
+		codes[i] = metaphone(transcriptUpper[word[0]:word[1]])
+	}
+
+	for _, keyword := range keywords {
+		if isRegexKeyword(keyword) {
+			continue
+		}
+
+		keywordWords := keywordWordRegexp.FindAllString(strings.ToUpper(keyword), -1)
+		if len(keywordWords) == 0 {
+			continue
+		}
+
+		keywordCodes := make([]string, len(keywordWords))
+		for i, word := range keywordWords {
+			if len(word) >= minPhoneticKeywordLength {
+				keywordCodes[i] = metaphone(word)
+			}
+		}
+
+		for i := 0; i+len(keywordWords) <= len(words); i++ {
+			sounds := true
+			for j, word := range keywordWords {
+				token := transcriptUpper[words[i+j][0]:words[i+j][1]]
+				if keywordCodes[j] == "" {
+					sounds = token == word
+				} else {
+					sounds = codes[i+j] == keywordCodes[j]
+				}
+				if !sounds {
+					break
+				}
+			}
+			if !sounds {
+				continue
+			}
+
+			start, end := words[i][0], words[i+len(keywordWords)-1][1]
+			if matched[fmt.Sprintf("%s:%d", keyword, start)] {
+				continue
+			}
+
+			matches = append(matches, KeywordMatch{
+				Keyword:  keyword,
+				Context:  matcher.extractContext(transcript, start, end-start),
+				Position: start,
+			})
+		}
+	}
+
+	return matches
+}
+
+// excluded tells whether the uppercase transcript holds one of the exclusions as whole words
+func (matcher *KeywordMatcher) excluded(transcriptUpper string, exclusions []string) bool {
+	for _, exclusion := range exclusions {
+		if isRegexKeyword(exclusion) {
+			if re := matcher.keywordRegexp(exclusion); re != nil && re.MatchString(transcriptUpper) {
+				return true
+			}
+			continue
+		}
+
+		exclusionUpper := strings.ToUpper(strings.TrimSpace(exclusion))
+		if exclusionUpper == "" {
+			continue
+		}
+
+		if re, err := regexp.Compile(`\b` + regexp.QuoteMeta(exclusionUpper) + `\b`); err == nil && re.MatchString(transcriptUpper) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRegexKeyword tells whether the keyword is a regex, wrapped in slashes as in /UNIT \d+ DOWN/
+func isRegexKeyword(keyword string) bool {
+	keyword = strings.TrimSpace(keyword)
+	return len(keyword) > 2 && strings.HasPrefix(keyword, "/") && strings.HasSuffix(keyword, "/")
+}
+
+// keywordRegexp returns the case-insensitive regexp of a regex keyword, compiled once while it stays among
+// the maxKeywordRegexps most recently used, or nil when the keyword is too long or invalid
+func (matcher *KeywordMatcher) keywordRegexp(keyword string) *regexp.Regexp {
+	matcher.regexpsMutex.Lock()
+	defer matcher.regexpsMutex.Unlock()
+
+	if element, ok := matcher.regexps[keyword]; ok {
+		matcher.regexpsUsed.MoveToFront(element)
+		return element.Value.(*keywordRegexpEntry).re
+	}
+
+	if matcher.regexps == nil {
+		matcher.regexps = map[string]*list.Element{}
+		matcher.regexpsUsed = list.New()
+	}
+
+	pattern := strings.TrimSpace(keyword)
+	pattern = pattern[1 : len(pattern)-1]
+
+	var re *regexp.Regexp
+	if len(pattern) > maxKeywordRegexLength {
+		log.Printf("keyword matcher: regex keyword %s skipped, longer than %d characters", keyword, maxKeywordRegexLength)
+	} else if compiled, err := regexp.Compile("(?i)" + pattern); err != nil {
+		log.Printf("keyword matcher: invalid regex keyword %s skipped: %v", keyword, err)
+	} else {
+		re = compiled
+	}
+
+	matcher.regexps[keyword] = matcher.regexpsUsed.PushFront(&keywordRegexpEntry{keyword: keyword, re: re})
+
+	if matcher.regexpsUsed.Len() > maxKeywordRegexps {
+		oldest := matcher.regexpsUsed.Back()
+		matcher.regexpsUsed.Remove(oldest)
+		delete(matcher.regexps, oldest.Value.(*keywordRegexpEntry).keyword)
+	}
+
+	return re
+}
+
 // isWholeWord checks if a substring at the given position is a whole word
 // (not preceded or followed by alphanumeric characters)
 func (matcher *KeywordMatcher) isWholeWord(text string, pos int, length int) bool {
@@ -163,3 +356,112 @@ func (matcher *KeywordMatcher) extractContext(transcript string, position int, k
 	return context
 }
 
+
+// keywordWordRegexp matches the words of an uppercase transcript, as delimited by isWholeWord
+var keywordWordRegexp = regexp.MustCompile(`[A-Z0-9]+`)
+
+// keywordTermOccurrence is a whole-word occurrence of a proximity term, in bytes and in words
+type keywordTermOccurrence struct {
+	term      int
+	start     int
+	end       int
+	startWord int
+	endWord   int
+}
+
+// MatchProximity matches the terms appearing together in a transcript (case-insensitive, whole-word only),
+// with at most maxGap other words between them, e.g. "officer" and "down" in "OFFICER IS DOWN" with a
+// maxGap of 1. Terms may hold several words. Each match spans the region of the terms and is named by them all.
+func (matcher *KeywordMatcher) MatchProximity(transcript string, terms []string, maxGap int) []KeywordMatch {
+	matches := []KeywordMatch{}
+
+	if transcript == "" || len(terms) == 0 {
+		return matches
+	}
+
+	if maxGap < 0 {
+		maxGap = 0
+	}
+
+	transcriptUpper := strings.ToUpper(transcript)
+
+	words := keywordWordRegexp.FindAllStringIndex(transcriptUpper, -1)
+
+	// wordAt returns the index of the word holding, or following, the byte position
+	wordAt := func(pos int) int {
+		return sort.Search(len(words), func(i int) bool { return words[i][1] > pos })
+	}
+
+	keywords := []string{}
+	occurrences := []keywordTermOccurrence{}
+
+	for _, term := range terms {
+		termUpper := strings.ToUpper(strings.TrimSpace(term))
+		if termUpper == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(`\b` + regexp.QuoteMeta(termUpper) + `\b`)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range re.FindAllStringIndex(transcriptUpper, -1) {
+			occurrences = append(occurrences, keywordTermOccurrence{
+				term:      len(keywords),
+				start:     match[0],
+				end:       match[1],
+				startWord: wordAt(match[0]),
+				endWord:   wordAt(match[1] - 1),
+			})
+		}
+
+		keywords = append(keywords, term)
+	}
+
+	if len(keywords) == 0 {
+		return matches
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].start < occurrences[j].start })
+
+	for i := 0; i < len(occurrences); i++ {
+		found := map[int]bool{}
+		covered := map[int]bool{}
+		end, endWord := 0, 0
+
+		for j := i; j < len(occurrences); j++ {
+			occurrence := occurrences[j]
+
+			found[occurrence.term] = true
+			for word := occurrence.startWord; word <= occurrence.endWord; word++ {
+				covered[word] = true
+			}
+			if occurrence.end > end {
+				end, endWord = occurrence.end, occurrence.endWord
+			}
+
+			// the words of the region not covered by a term only grow as the region extends
+			gap := endWord - occurrences[i].startWord + 1 - len(covered)
+			if gap > maxGap {
+				break
+			}
+
+			if len(found) == len(keywords) {
+				start := occurrences[i].start
+
+				matches = append(matches, KeywordMatch{
+					Keyword:  strings.Join(keywords, ", "),
+					Context:  matcher.extractContext(transcript, start, end-start),
+					Position: start,
+				})
+
+				// matches don't overlap
+				i = j
+				break
+			}
+		}
+	}
+
+	return matches
+}
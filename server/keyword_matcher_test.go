@@ -0,0 +1,144 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMatchProximity(t *testing.T) {
+	matcher := NewKeywordMatcher()
+
+	for _, test := range []struct {
+		transcript string
+		terms      []string
+		maxGap     int
+		want       []string
+	}{
+		{"Engine 5, officer is down at Main", []string{"officer", "down"}, 1, []string{"officer is down"}},
+		{"Engine 5, officer is now down at Main", []string{"officer", "down"}, 1, nil},
+		{"Engine 5, officer is now down at Main", []string{"officer", "down"}, 2, []string{"officer is now down"}},
+		{"down, repeat, officer down", []string{"officer", "down"}, 0, []string{"officer down"}},
+		{"officers down", []string{"officer", "down"}, 3, nil},
+		{"shots fired, one officer hit", []string{"shots fired", "officer"}, 1, []string{"shots fired, one officer"}},
+		{"officer down then officer down", []string{"Officer", "DOWN"}, 0, []string{"officer down", "officer down"}},
+		{"officer down", []string{"officer", "", "down"}, 0, []string{"officer down"}},
+		{"officer down", []string{"officer", "rescue"}, 5, nil},
+		{"officer down", nil, 5, nil},
+	} {
+		matches := matcher.MatchProximity(test.transcript, test.terms, test.maxGap)
+
+		if len(matches) != len(test.want) {
+			t.Errorf("%q %v within %d: expected %d matches, got %+v", test.transcript, test.terms, test.maxGap, len(test.want), matches)
+			continue
+		}
+		for i, match := range matches {
+			if region := test.transcript[match.Position : match.Position+len(test.want[i])]; region != test.want[i] {
+				t.Errorf("%q %v: expected the match at %q, got %q", test.transcript, test.terms, test.want[i], region)
+			}
+		}
+	}
+}
+
+func TestMatchProximityContext(t *testing.T) {
+	matcher := &KeywordMatcher{contextChars: 4}
+
+	matches := matcher.MatchProximity("units respond, officer is down at Main Street", []string{"officer", "down"}, 2)
+	if len(matches) != 1 {
+		t.Fatalf("expected a match, got %+v", matches)
+	}
+
+	if matches[0].Context != "...nd, officer is down at ..." || matches[0].Keyword != "officer, down" {
+		t.Errorf("expected the context around the region, got %q named %q", matches[0].Context, matches[0].Keyword)
+	}
+}
+
+func TestMatchKeywordsExclusions(t *testing.T) {
+	matcher := NewKeywordMatcher()
+
+	for _, test := range []struct {
+		transcript string
+		exclusions []string
+		want       int
+	}{
+		{"THIS IS A TEST OF THE STRUCTURE FIRE PAGING", []string{"test"}, 0},
+		{"STRUCTURE FIRE AT THE TESTING FACILITY", []string{"test"}, 1},
+		{"STRUCTURE FIRE, FIRE DRILL CANCELLED", []string{"", "fire drill"}, 0},
+		{"STRUCTURE FIRE ON MAIN", nil, 1},
+	} {
+		if matches := matcher.MatchKeywords(test.transcript, []string{"structure fire"}, test.exclusions); len(matches) != test.want {
+			t.Errorf("%q excluding %v: expected %d matches, got %+v", test.transcript, test.exclusions, test.want, matches)
+		}
+	}
+
+	if matches := matcher.MatchPhoneticKeywords("MARIDIAN AVENUE, THIS IS A TEST", []string{"meridian"}, []string{"test"}); len(matches) != 0 {
+		t.Errorf("expected the phonetic matches excluded too, got %+v", matches)
+	}
+}
+
+func TestMatchRegexKeywords(t *testing.T) {
+	matcher := NewKeywordMatcher()
+	transcript := "Unit 12 down at Main, unit 7 down at Elm"
+
+	matches := matcher.MatchKeywords(transcript, []string{`/unit \d+ down/`}, nil)
+	if len(matches) != 2 || matches[0].Position != 0 || matches[1].Position != 22 || matches[1].Context != transcript {
+		t.Fatalf("expected both units matched, got %+v", matches)
+	}
+
+	// invalid and oversized regexes are skipped, the other keywords still matching
+	oversized := "/" + strings.Repeat("A", maxKeywordRegexLength+1) + "/"
+	matches = matcher.MatchKeywords(transcript, []string{"/unit (\\d+ down/", oversized, "elm"}, nil)
+	if len(matches) != 1 || matches[0].Keyword != "elm" {
+		t.Errorf("expected the invalid regexes skipped, got %+v", matches)
+	}
+
+	if invalid, ok := matcher.regexps["/unit (\\d+ down/"]; len(matcher.regexps) != 3 || !ok || invalid.Value.(*keywordRegexpEntry).re != nil {
+		t.Errorf("expected the regexes compiled once and the invalid ones cached as such, got %v", matcher.regexps)
+	}
+
+	if matches := matcher.MatchKeywords(transcript, []string{"down"}, []string{`/unit 7\b/`}); len(matches) != 0 {
+		t.Errorf("expected the regex exclusion to suppress the matches, got %+v", matches)
+	}
+
+	// a lone slash is a literal keyword
+	if isRegexKeyword("/") || isRegexKeyword("//") || !isRegexKeyword(" /a/ ") {
+		t.Error("expected only the keywords wrapped in slashes as regexes")
+	}
+}
+
+func TestKeywordRegexpCacheBounded(t *testing.T) {
+	matcher := NewKeywordMatcher()
+
+	for i := 0; i < maxKeywordRegexps+10; i++ {
+		if matcher.keywordRegexp(fmt.Sprintf("/unit %d/", i)) == nil {
+			t.Fatalf("expected regex %d compiled", i)
+		}
+		// the first regex stays the most recently used
+		matcher.keywordRegexp("/unit 0/")
+	}
+
+	if len(matcher.regexps) != maxKeywordRegexps || matcher.regexpsUsed.Len() != maxKeywordRegexps {
+		t.Fatalf("expected the cache bounded to %d regexes, got %d", maxKeywordRegexps, len(matcher.regexps))
+	}
+	if _, ok := matcher.regexps["/unit 0/"]; !ok {
+		t.Error("expected the most recently used regex kept")
+	}
+	if _, ok := matcher.regexps["/unit 1/"]; ok {
+		t.Error("expected the least recently used regex evicted")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import "strings"
+
+// minPhoneticKeywordLength is the length under which a keyword word only matches exactly, the codes of the
+// short words colliding too often, e.g. TO and TEA
+const minPhoneticKeywordLength = 4
+
+// isMetaphoneVowel tells whether the letter is a vowel
+func isMetaphoneVowel(c byte) bool {
+	return c == 'A' || c == 'E' || c == 'I' || c == 'O' || c == 'U'
+}
+
+// metaphone returns the Metaphone code of an uppercase word, its letters other than A to Z ignored. Words
+// which sound alike share their code, e.g. MERIDIAN and MARIDIAN.
+func metaphone(word string) string {
+	letters := make([]byte, 0, len(word))
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		// adjacent duplicate letters sound once, except C
+		if n := len(letters); n > 0 && letters[n-1] == c && c != 'C' {
+			continue
+		}
+		letters = append(letters, c)
+	}
+
+	if len(letters) == 0 {
+		return ""
+	}
+
+	w := string(letters)
+
+	switch {
+	case strings.HasPrefix(w, "AE"), strings.HasPrefix(w, "GN"), strings.HasPrefix(w, "KN"), strings.HasPrefix(w, "PN"), strings.HasPrefix(w, "WR"):
+		w = w[1:]
+	case strings.HasPrefix(w, "X"):
+		w = "S" + w[1:]
+	case strings.HasPrefix(w, "WH"):
+		w = "W" + w[2:]
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+
+	code := strings.Builder{}
+
+	for i := 0; i < len(w); i++ {
+		c := w[i]
+		prev, next := at(i-1), at(i+1)
+
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				code.WriteByte(c)
+			}
+
+		case 'B':
+			// silent in a final MB, as in PLUMB
+			if !(prev == 'M' && i == len(w)-1) {
+				code.WriteByte('B')
+			}
+
+		case 'C':
+			switch {
+			case next == 'I' && at(i+2) == 'A':
+				code.WriteByte('X')
+			case next == 'H':
+				if prev == 'S' {
+					code.WriteByte('K')
+				} else {
+					code.WriteByte('X')
+				}
+				i++
+			case next == 'I' || next == 'E' || next == 'Y':
+				if prev != 'S' {
+					code.WriteByte('S')
+				}
+			default:
+				code.WriteByte('K')
+			}
+
+		case 'D':
+			if next == 'G' && (at(i+2) == 'E' || at(i+2) == 'I' || at(i+2) == 'Y') {
+				code.WriteByte('J')
+				i++
+			} else {
+				code.WriteByte('T')
+			}
+
+		case 'G':
+			switch {
+			case next == 'H' && i+2 < len(w) && !isMetaphoneVowel(at(i+2)):
+				// silent, as in NIGHT
+			case next == 'H' && i+2 >= len(w):
+				// silent, as in HIGH
+			case next == 'N' && (i+2 == len(w) || (at(i+2) == 'E' && at(i+3) == 'D' && i+4 == len(w))):
+				// silent, as in SIGN and SIGNED
+			case next == 'I' || next == 'E' || next == 'Y':
+				code.WriteByte('J')
+			default:
+				code.WriteByte('K')
+			}
+
+		case 'H':
+			// sounded before a vowel, unless modifying the previous letter or after a vowel
+			if isMetaphoneVowel(next) && !strings.ContainsRune("CSPTG", rune(prev)) && !isMetaphoneVowel(prev) {
+				code.WriteByte('H')
+			}
+
+		case 'K':
+			if prev != 'C' {
+				code.WriteByte('K')
+			}
+
+		case 'P':
+			if next == 'H' {
+				code.WriteByte('F')
+				i++
+			} else {
+				code.WriteByte('P')
+			}
+
+		case 'Q':
+			code.WriteByte('K')
+
+		case 'S':
+			switch {
+			case next == 'H':
+				code.WriteByte('X')
+				i++
+			case next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				code.WriteByte('X')
+			default:
+				code.WriteByte('S')
+			}
+
+		case 'T':
+			switch {
+			case next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				code.WriteByte('X')
+			case next == 'H':
+				code.WriteByte('0')
+				i++
+			case next == 'C' && at(i+2) == 'H':
+				// silent, as in WATCH
+			default:
+				code.WriteByte('T')
+			}
+
+		case 'V':
+			code.WriteByte('F')
+
+		case 'W', 'Y':
+			if isMetaphoneVowel(next) {
+				code.WriteByte(c)
+			}
+
+		case 'X':
+			code.WriteString("KS")
+
+		case 'Z':
+			code.WriteByte('S')
+
+		default:
+			// F, J, L, M, N, R
+			code.WriteByte(c)
+		}
+	}
+
+	return code.String()
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+)
+
+func TestMetaphone(t *testing.T) {
+	for _, words := range [][]string{
+		{"MERIDIAN", "MARIDIAN", "MERIDEAN"},
+		{"PHILLIPS", "FILIPS"},
+		{"KNIGHT", "NITE"},
+		{"SMITH", "SMYTH"},
+		{"CATHERINE", "KATHERINE", "KATHRYN"},
+	} {
+		for _, word := range words[1:] {
+			if metaphone(word) != metaphone(words[0]) {
+				t.Errorf("expected %s like %s, got %s and %s", word, words[0], metaphone(word), metaphone(words[0]))
+			}
+		}
+	}
+
+	for _, pair := range [][2]string{{"MERIDIAN", "MADISON"}, {"ENGINE", "AMBULANCE"}} {
+		if metaphone(pair[0]) == metaphone(pair[1]) {
+			t.Errorf("expected %s unlike %s, both %s", pair[0], pair[1], metaphone(pair[0]))
+		}
+	}
+
+	if metaphone("") != "" || metaphone("123") != "" {
+		t.Error("expected no code without letters")
+	}
+}
+
+func TestMatchPhoneticKeywords(t *testing.T) {
+	matcher := NewKeywordMatcher()
+	transcript := "ENGINE 5 RESPOND TO 100 MARIDIAN AVENUE, MERIDIAN AND TOE STREET"
+
+	// exact by default
+	if matches := matcher.MatchKeywords(transcript, []string{"Meridian"}, nil); len(matches) != 1 || matches[0].Position != 41 {
+		t.Errorf("expected the exact match only, got %+v", matches)
+	}
+
+	matches := matcher.MatchPhoneticKeywords(transcript, []string{"Meridian", "Meridian Avenue", "tow"}, nil)
+	positions := map[string][]int{}
+	for _, match := range matches {
+		positions[match.Keyword] = append(positions[match.Keyword], match.Position)
+	}
+
+	if len(positions["Meridian"]) != 2 || positions["Meridian"][0] != 41 || positions["Meridian"][1] != 24 {
+		t.Errorf("expected the exact then the phonetic match of Meridian, got %v", positions["Meridian"])
+	}
+	if len(positions["Meridian Avenue"]) != 1 || positions["Meridian Avenue"][0] != 24 {
+		t.Errorf("expected the phonetic match of Meridian Avenue, got %v", positions["Meridian Avenue"])
+	}
+	if len(positions["tow"]) != 0 {
+		t.Errorf("expected the short keywords matched exactly, got %v", positions["tow"])
+	}
+
+	matcher.PhoneticEnabled = true
+	if matches := matcher.MatchKeywords(transcript, []string{"Meridian"}, nil); len(matches) != 2 {
+		t.Errorf("expected the phonetic match once enabled, got %+v", matches)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"sync"
+)
+
+// listenerKey is a talkgroup a client listens to, by system and talkgroup refs
+type listenerKey struct {
+	system    uint
+	talkgroup uint
+}
+
+// ListenerCounts counts the clients listening to each talkgroup, from the livefeed selection
+// of every client. A client only moves the counts of the talkgroups its selection changes.
+type ListenerCounts struct {
+	talkgroups map[listenerKey]int
+	clients    map[*Client][]listenerKey
+	mutex      sync.RWMutex
+}
+
+func NewListenerCounts() *ListenerCounts {
+	return &ListenerCounts{
+		talkgroups: map[listenerKey]int{},
+		clients:    map[*Client][]listenerKey{},
+	}
+}
+
+// Set replaces the talkgroups the client listens to
+func (counts *ListenerCounts) Set(client *Client, selection []listenerKey) {
+	counts.mutex.Lock()
+	defer counts.mutex.Unlock()
+
+	for _, key := range counts.clients[client] {
+		if counts.talkgroups[key] <= 1 {
+			delete(counts.talkgroups, key)
+		} else {
+			counts.talkgroups[key]--
+		}
+	}
+
+	if len(selection) == 0 {
+		delete(counts.clients, client)
+		return
+	}
+
+	for _, key := range selection {
+		counts.talkgroups[key]++
+	}
+
+	counts.clients[client] = selection
+}
+
+// Remove forgets a disconnected client
+func (counts *ListenerCounts) Remove(client *Client) {
+	counts.Set(client, nil)
+}
+
+// Talkgroup returns the number of clients listening to the talkgroup
+func (counts *ListenerCounts) Talkgroup(systemRef uint, talkgroupRef uint) int {
+	counts.mutex.RLock()
+	defer counts.mutex.RUnlock()
+
+	return counts.talkgroups[listenerKey{system: systemRef, talkgroup: talkgroupRef}]
+}
+
+// Total returns the number of clients listening to at least one talkgroup
+func (counts *ListenerCounts) Total() int {
+	counts.mutex.RLock()
+	defer counts.mutex.RUnlock()
+
+	return len(counts.clients)
+}
+
+// Summary returns the total and the counts by system and talkgroup refs of the talkgroups
+// the filter lets through, all of them for a nil filter
+func (counts *ListenerCounts) Summary(filter func(systemRef uint, talkgroupRef uint) bool) map[string]any {
+	counts.mutex.RLock()
+	defer counts.mutex.RUnlock()
+
+	systems := map[uint]map[uint]int{}
+	for key, count := range counts.talkgroups {
+		if filter != nil && !filter(key.system, key.talkgroup) {
+			continue
+		}
+		if systems[key.system] == nil {
+			systems[key.system] = map[uint]int{}
+		}
+		systems[key.system][key.talkgroup] = count
+	}
+
+	return map[string]any{
+		"total":   len(counts.clients),
+		"systems": systems,
+	}
+}
+
+// listenerFilter lets through the talkgroups of the scoped systems sent to a client
+func listenerFilter(systemsMap SystemsMap) func(systemRef uint, talkgroupRef uint) bool {
+	scope := map[listenerKey]bool{}
+
+	for _, systemMap := range systemsMap {
+		systemRef, ok := systemMap["id"].(uint)
+		if !ok {
+			continue
+		}
+		talkgroups, _ := systemMap["talkgroups"].(TalkgroupsMap)
+		for _, talkgroupMap := range talkgroups {
+			if talkgroupRef, ok := talkgroupMap["id"].(uint); ok {
+				scope[listenerKey{system: systemRef, talkgroup: talkgroupRef}] = true
+			}
+		}
+	}
+
+	return func(systemRef uint, talkgroupRef uint) bool {
+		return scope[listenerKey{system: systemRef, talkgroup: talkgroupRef}]
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+)
+
+func TestListenerCountsFollowSelections(t *testing.T) {
+	counts := NewListenerCounts()
+	a, b := &Client{Livefeed: NewLivefeed()}, &Client{Livefeed: NewLivefeed()}
+
+	a.Livefeed.FromMap(map[string]any{"10": map[string]any{"100": true, "101": true}})
+	counts.Set(a, a.Livefeed.Selection())
+
+	b.Livefeed.FromMap(map[string]any{"10": map[string]any{"100": true, "101": false}})
+	counts.Set(b, b.Livefeed.Selection())
+
+	if n := counts.Talkgroup(10, 100); n != 2 {
+		t.Errorf("talkgroup 100 has %d listeners, want 2", n)
+	}
+	if n := counts.Talkgroup(10, 101); n != 1 {
+		t.Errorf("talkgroup 101 has %d listeners, want 1", n)
+	}
+	if n := counts.Total(); n != 2 {
+		t.Errorf("total is %d, want 2", n)
+	}
+
+	// a unsubscribes from talkgroup 100
+	a.Livefeed.FromMap(map[string]any{"10": map[string]any{"100": false, "101": true}})
+	counts.Set(a, a.Livefeed.Selection())
+
+	if n := counts.Talkgroup(10, 100); n != 1 {
+		t.Errorf("talkgroup 100 has %d listeners after unsubscribing, want 1", n)
+	}
+
+	// b turns its livefeed off, a disconnects
+	b.Livefeed.FromMap(map[string]any{})
+	counts.Set(b, b.Livefeed.Selection())
+	counts.Remove(a)
+
+	if counts.Talkgroup(10, 100) != 0 || counts.Talkgroup(10, 101) != 0 || counts.Total() != 0 {
+		t.Errorf("expected no listeners left, got %v", counts.Summary(nil))
+	}
+	if len(counts.talkgroups) != 0 {
+		t.Errorf("talkgroups without listeners should be forgotten, got %v", counts.talkgroups)
+	}
+}
+
+func TestListenerCountsInConfig(t *testing.T) {
+	talkgroups := NewTalkgroups()
+	talkgroups.List = append(talkgroups.List, &Talkgroup{Id: 1, TalkgroupRef: 100, Label: "FD", TagId: 1})
+
+	controller := &Controller{
+		Options:        NewOptions(),
+		Systems:        NewSystems(),
+		Groups:         NewGroups(),
+		Tags:           NewTags(),
+		UserGroups:     NewUserGroups(),
+		ListenerCounts: NewListenerCounts(),
+	}
+	controller.Tags.List = []*Tag{{Id: 1, Label: "Fire"}}
+	controller.Systems.List = []*System{{Id: 1, SystemRef: 10, Label: "County", Talkgroups: talkgroups, Units: NewUnits()}}
+
+	listener := &Client{Controller: controller, Livefeed: NewLivefeed()}
+	controller.ListenerCounts.Set(listener, []listenerKey{{system: 10, talkgroup: 100}, {system: 20, talkgroup: 200}})
+
+	config := func() map[string]any {
+		client := &Client{Controller: controller, Send: make(chan *Message, 1)}
+		client.SendConfig(controller.Groups, controller.Options, controller.Systems, controller.Tags)
+		return (<-client.Send).Payload.(map[string]any)
+	}
+
+	controller.Options.ShowListenersCount = false
+	if _, ok := config()["listeners"]; ok {
+		t.Error("listeners should not be sent while showListenersCount is off")
+	}
+
+	controller.Options.ShowListenersCount = true
+	listeners, ok := config()["listeners"].(map[string]any)
+	if !ok {
+		t.Fatal("listeners should be sent while showListenersCount is on")
+	}
+
+	systems := listeners["systems"].(map[uint]map[uint]int)
+	if listeners["total"] != 1 || systems[10][100] != 1 {
+		t.Errorf("unexpected listeners %v", listeners)
+	}
+	if _, ok := systems[20]; ok {
+		t.Error("talkgroups outside the scope of the client should not be sent")
+	}
+}
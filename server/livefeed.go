@@ -82,6 +82,23 @@ func (livefeed *Livefeed) IsAllOff() bool {
 	return true
 }
 
+// Selection returns the talkgroups turned on
+func (livefeed *Livefeed) Selection() []listenerKey {
+	livefeed.mutex.Lock()
+	defer livefeed.mutex.Unlock()
+
+	selection := []listenerKey{}
+	for sys, tgs := range livefeed.Matrix {
+		for tg, on := range tgs {
+			if on {
+				selection = append(selection, listenerKey{system: sys, talkgroup: tg})
+			}
+		}
+	}
+
+	return selection
+}
+
 func (livefeed *Livefeed) IsEnabled(call *Call) bool {
 	livefeed.mutex.Lock()
 	defer livefeed.mutex.Unlock()
@@ -0,0 +1,61 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import "sync/atomic"
+
+// lookupCacheDisabled turns off the lookup snapshots, making every lookup scan the list under
+// the collection mutex as before. Set from the lookup_cache configuration option.
+var lookupCacheDisabled atomic.Bool
+
+// lookupCache is a copy-on-write index over a systems, talkgroups, tags or groups list. The map
+// is never modified once published; a reload builds a new one and swaps the pointer, so readers
+// on hot paths (access checks, downstreams, delayer) never wait on the collection mutex.
+//
+// A miss is not authoritative: entries appended to the list outside of a reload are only found
+// by the caller's fallback scan until the next rebuild.
+type lookupCache[K comparable, T any] struct {
+	snapshot atomic.Pointer[map[K]*T]
+}
+
+func (cache *lookupCache[K, T]) get(key K) (*T, bool) {
+	if lookupCacheDisabled.Load() {
+		return nil, false
+	}
+
+	snapshot := cache.snapshot.Load()
+	if snapshot == nil {
+		return nil, false
+	}
+
+	item, ok := (*snapshot)[key]
+	return item, ok
+}
+
+// rebuild publishes a new snapshot of the list. The first item for a key wins, matching the
+// order in which the fallback scan would find it.
+func (cache *lookupCache[K, T]) rebuild(list []*T, key func(*T) K) {
+	snapshot := make(map[K]*T, len(list))
+
+	for _, item := range list {
+		k := key(item)
+		if _, ok := snapshot[k]; !ok {
+			snapshot[k] = item
+		}
+	}
+
+	cache.snapshot.Store(&snapshot)
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func lookupTestSystems(count int, label string) []any {
+	list := []any{}
+
+	for i := 1; i <= count; i++ {
+		talkgroups := []any{}
+		for j := 1; j <= 50; j++ {
+			talkgroups = append(talkgroups, map[string]any{
+				"id":           float64(i*1000 + j),
+				"label":        fmt.Sprintf("%s %d", label, j),
+				"talkgroupRef": float64(j),
+			})
+		}
+
+		list = append(list, map[string]any{
+			"id":         float64(i),
+			"label":      fmt.Sprintf("%s %d", label, i),
+			"systemRef":  float64(i),
+			"talkgroups": talkgroups,
+		})
+	}
+
+	return list
+}
+
+func TestLookupCacheReload(t *testing.T) {
+	systems := NewSystems().FromMap(lookupTestSystems(3, "before"))
+
+	system, ok := systems.GetSystemById(2)
+	if !ok || system.Label != "before 2" {
+		t.Fatalf("expected system 2 before reload, got %v", system)
+	}
+
+	if talkgroup, ok := system.Talkgroups.GetTalkgroupByRef(7); !ok || talkgroup.Id != 2007 {
+		t.Fatalf("expected talkgroup 2007 by ref, got %v", talkgroup)
+	}
+
+	systems.FromMap(lookupTestSystems(2, "after"))
+
+	if _, ok := systems.GetSystemById(3); ok {
+		t.Fatal("expected system 3 to be gone after reload")
+	}
+
+	if system, ok := systems.GetSystemByRef(2); !ok || system.Label != "after 2" {
+		t.Fatalf("expected reloaded system 2, got %v", system)
+	}
+
+	// systems appended outside of a reload, as auto populate does, are still found
+	systems.List = append(systems.List, &System{Id: 9, SystemRef: 9, Label: "appended", Talkgroups: NewTalkgroups()})
+
+	if system, ok := systems.GetSystemById(9); !ok || system.Label != "appended" {
+		t.Fatalf("expected appended system, got %v", system)
+	}
+
+	tags := NewTags().FromMap([]any{map[string]any{"id": float64(1), "label": "Fire"}})
+	tags.FromMap([]any{map[string]any{"id": float64(1), "label": "EMS"}})
+	if tag, ok := tags.GetTagById(1); !ok || tag.Label != "EMS" {
+		t.Fatalf("expected reloaded tag, got %v", tag)
+	}
+
+	groups := NewGroups().FromMap([]any{map[string]any{"id": float64(4), "label": "County"}})
+	if group, ok := groups.GetGroupById(4); !ok || group.Label != "County" {
+		t.Fatalf("expected group 4, got %v", group)
+	}
+}
+
+func TestLookupCacheConcurrentReload(t *testing.T) {
+	systems := NewSystems().FromMap(lookupTestSystems(5, "system"))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				system, ok := systems.GetSystemById(1)
+				if !ok || system.Id != 1 {
+					t.Error("expected system 1 during reload")
+					return
+				}
+				if _, ok := system.Talkgroups.GetTalkgroupById(1001); !ok {
+					t.Error("expected talkgroup 1001 during reload")
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		systems.FromMap(lookupTestSystems(5, "system"))
+	}
+
+	wg.Wait()
+}
+
+func benchmarkLookups(b *testing.B, disabled bool) {
+	lookupCacheDisabled.Store(disabled)
+	defer lookupCacheDisabled.Store(false)
+
+	systems := NewSystems().FromMap(lookupTestSystems(20, "system"))
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if system, ok := systems.GetSystemById(uint64(i%20 + 1)); ok {
+				system.Talkgroups.GetTalkgroupByRef(uint(i%50 + 1))
+			}
+		}
+	})
+}
+
+func BenchmarkLookupCache(b *testing.B) {
+	benchmarkLookups(b, false)
+}
+
+func BenchmarkLookupLocked(b *testing.B) {
+	benchmarkLookups(b, true)
+}
@@ -169,9 +169,14 @@ func main() {
 		})
 	}
 
-	// Apply general rate limiting to all routes
+	// Apply rate limiting to all routes, by the limit of their path prefix or the general one
 	rateLimitWrapper := func(handler http.Handler) http.Handler {
-		return RateLimitMiddleware(controller.RateLimiter)(handler)
+		return RouteRateLimitMiddleware(controller.RouteRateLimiter)(handler)
+	}
+
+	// Apply per user rate limiting to expensive search and listing routes, on top of the general one
+	heavyRateLimitWrapper := func(handler http.Handler) http.Handler {
+		return KeyedRateLimitMiddleware(controller.HeavyRateLimiter, controller.Api.rateLimitKey)(handler)
 	}
 
 	// Apply security headers to all routes
@@ -179,9 +184,19 @@ func main() {
 		return SecurityHeadersMiddleware(handler)
 	}
 
-	// Helper to wrap handlers with recovery, rate limiting, and security headers
+	// Compress large JSON responses for clients accepting gzip or deflate
+	compressionWrapper := func(handler http.Handler) http.Handler {
+		return CompressionMiddleware(int(config.CompressionMinSize))(handler)
+	}
+
+	// Record every admin and group admin change in the audit log
+	auditWrapper := func(handler http.Handler) http.Handler {
+		return AuditMiddleware(controller.AuditLog, controller.Api.auditActor)(handler)
+	}
+
+	// Helper to wrap handlers with recovery, rate limiting, compression, security headers and auditing
 	wrapHandler := func(handler http.Handler) http.Handler {
-		return securityHeadersWrapper(rateLimitWrapper(recoveryMiddleware(handler)))
+		return securityHeadersWrapper(compressionWrapper(rateLimitWrapper(recoveryMiddleware(auditWrapper(handler)))))
 	}
 
 	if h, err := os.Hostname(); err == nil {
@@ -217,18 +232,32 @@ func main() {
 	http.HandleFunc("/api/admin/systemhealth", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.SystemHealthHandler)).ServeHTTP)
 
 	http.HandleFunc("/api/admin/transcription-failures", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.TranscriptionFailuresHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/transcription-queue", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.TranscriptionQueueHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/transcription-requeue", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.TranscriptionRequeueHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/transcription-failure-threshold", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.TranscriptionFailureThresholdHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/tone-detection-issue-threshold", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneDetectionIssueThresholdHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/alert-retention-days", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.AlertRetentionDaysHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/access-trace", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.AccessTraceHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/demo", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.DemoHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/vocabulary-profiles", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.VocabularyProfilesHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/alert-test-windows", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.AlertTestWindowsHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/call-audio/", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.CallAudioHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/calls-reassign", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.CallsReassignHandler)).ServeHTTP)
 
 	http.HandleFunc("/api/admin/tone-import", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneImportHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/tone-export", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneExportHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/tone-learning/suggestions", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneLearningSuggestionsHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/tone-learning/approve", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneLearningApproveHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/tone-learning/reject", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneLearningRejectHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/tone-learning/window", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ToneLearningWindowHandler)).ServeHTTP)
 
 	http.HandleFunc("/api/admin/config", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.ConfigHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/email-logo", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.EmailLogoUploadHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/email-logo/delete", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.EmailLogoDeleteHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/email-test", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.EmailTestHandler)).ServeHTTP)
 
+	http.HandleFunc("/api/admin/downstream-test", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.DownstreamTestHandler)).ServeHTTP)
+
 	http.HandleFunc("/api/admin/stripe-sync", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.StripeSyncHandler)).ServeHTTP)
 
 	// Serve email logo file - register before root handler to ensure it's handled
@@ -295,6 +324,14 @@ func main() {
 	http.HandleFunc("/api/admin/hallucinations/suggestions", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.HallucinationSuggestionsHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/hallucinations/approve", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.HallucinationApproveHandler)).ServeHTTP)
 	http.HandleFunc("/api/admin/hallucinations/reject", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.HallucinationRejectHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/hallucinations/patterns", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.HallucinationPatternsHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/hallucinations/stats", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.HallucinationStatsHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/migrations", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.MigrationStatusHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/audit-log", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.AuditLogHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/user-group-audit-log", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.UserGroupAuditLogHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/registration-qr-code", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.RegistrationQRCodeHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/transcription-usage", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.TranscriptionUsageHandler)).ServeHTTP)
+	http.HandleFunc("/api/admin/coupon-codes", wrapHandler(controller.Admin.requireLocalhost(controller.Admin.CouponCodesHandler)).ServeHTTP)
 
 	// User registration and authentication routes
 	http.HandleFunc("/api/user/register", wrapHandler(http.HandlerFunc(controller.Api.UserRegisterHandler)).ServeHTTP)
@@ -307,6 +344,8 @@ func main() {
 	http.HandleFunc("/api/public-registration-info", wrapHandler(http.HandlerFunc(controller.Api.PublicRegistrationInfoHandler)).ServeHTTP)
 	http.HandleFunc("/api/public-registration-channels", wrapHandler(http.HandlerFunc(controller.Api.PublicRegistrationChannelsHandler)).ServeHTTP)
 	http.HandleFunc("/api/registration-settings", wrapHandler(http.HandlerFunc(controller.Api.RegistrationSettingsHandler)).ServeHTTP)
+	http.HandleFunc("/api/capabilities", wrapHandler(http.HandlerFunc(controller.Api.CapabilitiesHandler)).ServeHTTP)
+	http.HandleFunc("/api/branding", wrapHandler(http.HandlerFunc(controller.Api.BrandingHandler)).ServeHTTP)
 	http.HandleFunc("/api/user/validate-access-code", wrapHandler(http.HandlerFunc(controller.Api.ValidateAccessCodeHandler)).ServeHTTP)
 	http.HandleFunc("/api/user/verify", wrapHandler(http.HandlerFunc(controller.Api.UserVerifyHandler)).ServeHTTP)
 	http.HandleFunc("/api/user/resend-verification", wrapHandler(http.HandlerFunc(controller.Api.UserResendVerificationHandler)).ServeHTTP)
@@ -370,9 +409,11 @@ func main() {
 	})).ServeHTTP)
 
 	// Alert routes
-	http.HandleFunc("/api/alerts", wrapHandler(http.HandlerFunc(controller.Api.AlertsHandler)).ServeHTTP)
+	http.HandleFunc("/api/alerts", wrapHandler(heavyRateLimitWrapper(http.HandlerFunc(controller.Api.AlertsHandler))).ServeHTTP)
 	http.HandleFunc("/api/alerts/preferences", wrapHandler(http.HandlerFunc(controller.Api.AlertPreferencesHandler)).ServeHTTP)
-	http.HandleFunc("/api/transcripts", wrapHandler(http.HandlerFunc(controller.Api.TranscriptsHandler)).ServeHTTP)
+	http.HandleFunc("/api/alerts/preferences/config", wrapHandler(http.HandlerFunc(controller.Api.AlertPreferencesConfigHandler)).ServeHTTP)
+	http.HandleFunc("/api/transcripts", wrapHandler(heavyRateLimitWrapper(http.HandlerFunc(controller.Api.TranscriptsHandler))).ServeHTTP)
+	http.HandleFunc("/api/incidents", wrapHandler(heavyRateLimitWrapper(http.HandlerFunc(controller.Api.IncidentHandler))).ServeHTTP)
 	http.HandleFunc("/api/keyword-lists", wrapHandler(http.HandlerFunc(controller.Api.KeywordListsHandler)).ServeHTTP)
 
 	// System alert routes (system admins only)
@@ -396,6 +437,7 @@ func main() {
 
 	// Stripe checkout session route
 	http.HandleFunc("/api/stripe/create-checkout-session", wrapHandler(http.HandlerFunc(controller.Api.CreateCheckoutSessionHandler)).ServeHTTP)
+	http.HandleFunc("/api/stripe/validate-coupon", wrapHandler(http.HandlerFunc(controller.Api.ValidateCouponHandler)).ServeHTTP)
 
 	// Account management routes
 	http.HandleFunc("/api/account", wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -413,6 +455,7 @@ func main() {
 	http.HandleFunc("/api/account/password/verify-code", wrapHandler(http.HandlerFunc(controller.Api.AccountVerifyPasswordChangeCodeHandler)).ServeHTTP)
 	http.HandleFunc("/api/account/password", wrapHandler(http.HandlerFunc(controller.Api.AccountUpdatePasswordHandler)).ServeHTTP)
 	http.HandleFunc("/api/billing/portal", wrapHandler(http.HandlerFunc(controller.Api.BillingPortalSessionHandler)).ServeHTTP)
+	http.HandleFunc("/api/billing/preview-plan-change", wrapHandler(http.HandlerFunc(controller.Api.PlanChangePreviewHandler)).ServeHTTP)
 
 	// Log that routes have been registered
 	log.Printf("All HTTP routes registered successfully")
@@ -670,61 +713,9 @@ func main() {
 					baseUrl := fmt.Sprintf("%s://%s/", scheme, host)
 					html = strings.Replace(html, `<base href="./">`, fmt.Sprintf(`<base href="%s">`, baseUrl), 1)
 
-				// Get initial config data
-				branding := controller.Options.Branding
-				if branding == "" {
-					branding = "Thinline Radio"
-				}
-				email := controller.Options.Email
-
-					// Inject config into HTML
-					configScript := fmt.Sprintf(`
-<script>
-window.initialConfig = {
-	"branding": %q,
-	"email": %q,
-	"options": {
-		"userRegistrationEnabled": %t,
-		"stripePaywallEnabled": %t,
-		"stripePublishableKey": %q,
-		"stripePriceId": %q,
-		"baseUrl": %q,
-		"emailLogoFilename": %q,
-		"emailLogoBorderRadius": %q,
-		"turnstileEnabled": %t,
-		"turnstileSiteKey": %q
-	}
-};
-</script>`, branding, email, controller.Options.UserRegistrationEnabled, controller.Options.StripePaywallEnabled, controller.Options.StripePublishableKey, controller.Options.StripePriceId, controller.Options.BaseUrl, controller.Options.EmailLogoFilename, controller.Options.EmailLogoBorderRadius, controller.Options.TurnstileEnabled, controller.Options.TurnstileSiteKey)
-
-					// Try multiple insertion points for the config script
-					injected := false
-					// Try to insert config script before the closing </head> tag
-					if strings.Contains(html, "</head>") {
-						html = strings.Replace(html, "</head>", configScript+"</head>", 1)
-						injected = true
-					} else if strings.Contains(html, "</HEAD>") { // Case-insensitive check
-						html = strings.Replace(html, "</HEAD>", configScript+"</HEAD>", 1)
-						injected = true
-					} else if strings.Contains(html, "<head>") {
-						// Insert after <head> tag
-						html = strings.Replace(html, "<head>", "<head>"+configScript, 1)
-						injected = true
-					} else if strings.Contains(html, "<HEAD>") {
-						html = strings.Replace(html, "<HEAD>", "<HEAD>"+configScript, 1)
-						injected = true
-					} else if strings.Contains(html, "</body>") {
-						html = strings.Replace(html, "</body>", configScript+"</body>", 1)
-						injected = true
-					} else if strings.Contains(html, "</BODY>") { // Case-insensitive check
-						html = strings.Replace(html, "</BODY>", configScript+"</BODY>", 1)
-						injected = true
-					}
-
-					if !injected {
-						// Last resort: prepend to HTML
-						html = configScript + html
-					}
+					// Inject the branding and the initial config
+					html = injectBranding(html, controller.Branding())
+					html = injectHead(html, controller.initialConfigScript())
 
 					w.Header().Set("Content-Type", "text/html")
 					w.Write([]byte(html))
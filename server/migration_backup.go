@@ -0,0 +1,236 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultMigrationBackupCommand = "pg_dump"
+
+// migrationBackupExecutor runs the backup command with the extra environment variables
+type migrationBackupExecutor func(name string, args []string, env []string) error
+
+func execMigrationBackup(name string, args []string, env []string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	stderr := bytes.NewBuffer([]byte(nil))
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// migrationBackupLayout is the layout of the time in the snapshot file names
+const migrationBackupLayout = "20060102-150405"
+
+// migrationBackupFile returns the path of the snapshot taken at the given time
+func (config *Config) migrationBackupFile(at time.Time) string {
+	return filepath.Join(config.migrationBackupDir(), fmt.Sprintf("%s-%s.dump", config.DbName, at.Format(migrationBackupLayout)))
+}
+
+func (config *Config) migrationBackupDir() string {
+	dir := config.MigrationBackupDir
+	if dir == "" {
+		dir = "backups"
+	}
+
+	return config.GetPath(dir)
+}
+
+// migrationBackupArgs returns the arguments of the backup command writing the snapshot to file. The
+// configured arguments replace the default pg_dump ones, with {file} substituted by the snapshot path.
+func (config *Config) migrationBackupArgs(file string) []string {
+	if len(config.MigrationBackupArgs) > 0 {
+		args := make([]string, len(config.MigrationBackupArgs))
+		for i, arg := range config.MigrationBackupArgs {
+			args[i] = strings.ReplaceAll(arg, "{file}", file)
+		}
+		return args
+	}
+
+	return []string{
+		"--host", config.DbHost,
+		"--port", fmt.Sprint(config.DbPort),
+		"--username", config.DbUsername,
+		"--format", "custom",
+		"--file", file,
+		config.DbName,
+	}
+}
+
+// pruneMigrationBackups removes the snapshots of the database but the keep most recent ones, none when keep is 0
+func (config *Config) pruneMigrationBackups(keep uint) error {
+	if keep == 0 {
+		return nil
+	}
+
+	dir := config.migrationBackupDir()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, config.DbName+"-") || !strings.HasSuffix(name, ".dump") {
+			continue
+		}
+		if _, err := time.Parse(migrationBackupLayout, strings.TrimSuffix(strings.TrimPrefix(name, config.DbName+"-"), ".dump")); err != nil {
+			continue
+		}
+		files = append(files, name)
+	}
+
+	// the time layout sorts the snapshots oldest first
+	sort.Strings(files)
+
+	for len(files) > int(keep) {
+		if err := os.Remove(filepath.Join(dir, files[0])); err != nil {
+			return err
+		}
+		log.Printf("removed the pre-migration backup %s", files[0])
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// existingTables returns the tables of the current schema
+func (db *Database) existingTables() (map[string]bool, error) {
+	tables := map[string]bool{}
+
+	query := `SELECT "tablename" FROM "pg_tables" WHERE "schemaname" = current_schema()`
+	rows, err := db.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables[table] = true
+	}
+
+	return tables, rows.Err()
+}
+
+// migrationsPending reports whether the migrations have something to change: a migration run once and not yet
+// recorded, or a legacy table still to convert. The schema checks run at every start only add what is missing and
+// don't count, and an empty database has nothing to back up.
+func (db *Database) migrationsPending() (bool, error) {
+	tables, err := db.existingTables()
+	if err != nil {
+		return false, err
+	}
+
+	if len(tables) == 0 {
+		return false, nil
+	}
+
+	if !tables["rdioScannerMeta"] {
+		return true, nil
+	}
+
+	status, err := db.migrationStatus(tables)
+	if err != nil {
+		return false, err
+	}
+
+	for i, migration := range databaseMigrations {
+		if !status[i].Applied && (status[i].Tracked || len(migration.legacy) > 0) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// backupBeforeMigration takes a snapshot of the database when migration_backup is enabled and a migration is
+// pending, then removes the snapshots beyond migration_backup_keep. A failed snapshot only aborts the migrations
+// when migration_backup_required is set.
+func (db *Database) backupBeforeMigration(executor migrationBackupExecutor) error {
+	config := db.Config
+
+	if config == nil || !config.MigrationBackup {
+		return nil
+	}
+
+	fail := func(err error) error {
+		if config.MigrationBackupRequired {
+			return fmt.Errorf("pre-migration backup failed: %v", err)
+		}
+		log.Printf("WARNING: pre-migration backup failed, migrating anyway: %v", err)
+		return nil
+	}
+
+	// when the pending migrations can't be read, the snapshot is taken anyway
+	if pending, err := db.migrationsPending(); err != nil {
+		log.Printf("WARNING: unable to check the pending migrations, backing up anyway: %v", err)
+	} else if !pending {
+		return nil
+	}
+
+	file := config.migrationBackupFile(time.Now())
+
+	if err := os.MkdirAll(filepath.Dir(file), 0770); err != nil {
+		return fail(err)
+	}
+
+	command := config.MigrationBackupCommand
+	if command == "" {
+		command = defaultMigrationBackupCommand
+	}
+
+	log.Printf("backing up database %s to %s before migration", config.DbName, file)
+
+	if err := executor(command, config.migrationBackupArgs(file), []string{"PGPASSWORD=" + config.DbPassword}); err != nil {
+		os.Remove(file)
+		return fail(err)
+	}
+
+	if err := config.pruneMigrationBackups(config.MigrationBackupKeep); err != nil {
+		log.Printf("WARNING: unable to remove the old pre-migration backups: %v", err)
+	}
+
+	return nil
+}
+
+// migrateWithBackup takes the pre-migration backup, when enabled, then runs the migrations
+func (db *Database) migrateWithBackup(executor migrationBackupExecutor, migrate func() error) error {
+	if err := db.backupBeforeMigration(executor); err != nil {
+		return err
+	}
+
+	return migrate()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrateWithBackup(t *testing.T) {
+	config := &Config{BaseDir: t.TempDir(), DbType: DbTypePostgresql, DbHost: "localhost", DbPort: 5432, DbName: "rdio", DbUsername: "rdio", DbPassword: "secret", MigrationBackup: true}
+	db, d := newRecordingDatabase(t, config)
+
+	// a legacy table is still to convert
+	d.onQuery = func(query string) {
+		d.rows = [][]driver.Value{{"rdioScannerGroups"}}
+	}
+
+	steps := []string{}
+	var backupArgs, backupEnv []string
+
+	executor := func(name string, args []string, env []string) error {
+		steps = append(steps, name)
+		backupArgs, backupEnv = args, env
+		return nil
+	}
+	migrate := func() error {
+		steps = append(steps, "migrate")
+		return nil
+	}
+
+	if err := db.migrateWithBackup(executor, migrate); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(steps, ",") != "pg_dump,migrate" {
+		t.Fatalf("backup should be taken before migrating, got %v", steps)
+	}
+	if !strings.Contains(strings.Join(backupArgs, " "), "--file "+config.GetPath("backups")) {
+		t.Errorf("backup should be written to the backup directory, got %v", backupArgs)
+	}
+	if len(backupEnv) != 1 || backupEnv[0] != "PGPASSWORD=secret" {
+		t.Errorf("database password should be passed in the environment, got %v", backupEnv)
+	}
+
+	failing := func(name string, args []string, env []string) error {
+		steps = append(steps, name)
+		return errors.New("pg_dump: not found")
+	}
+
+	steps = nil
+	if err := db.migrateWithBackup(failing, migrate); err != nil {
+		t.Errorf("optional backup failure should not abort the migrations, got %v", err)
+	}
+
+	steps = nil
+	config.MigrationBackupRequired = true
+	if err := db.migrateWithBackup(failing, migrate); err == nil || len(steps) != 1 {
+		t.Errorf("required backup failure should abort the migrations, got %v after %v", err, steps)
+	}
+
+	steps = nil
+	config.MigrationBackup = false
+	if err := db.migrateWithBackup(failing, migrate); err != nil || strings.Join(steps, ",") != "migrate" {
+		t.Errorf("disabled backup should not be taken, got %v after %v", err, steps)
+	}
+}
+
+func TestMigrateWithBackupNothingPending(t *testing.T) {
+	config := &Config{BaseDir: t.TempDir(), DbType: DbTypePostgresql, DbName: "rdio", MigrationBackup: true}
+	db, d := newRecordingDatabase(t, config)
+
+	markers := [][]driver.Value{}
+	for _, migration := range databaseMigrations {
+		if migration.marker != "" {
+			markers = append(markers, []driver.Value{migration.marker, nil})
+		}
+	}
+
+	d.onQuery = func(query string) {
+		if strings.Contains(query, "pg_tables") {
+			d.rows = [][]driver.Value{{"rdioScannerMeta"}, {"calls"}, {"systems"}}
+		} else {
+			d.rows = markers
+		}
+	}
+
+	steps := []string{}
+	executor := func(name string, args []string, env []string) error {
+		steps = append(steps, name)
+		return nil
+	}
+	migrate := func() error {
+		steps = append(steps, "migrate")
+		return nil
+	}
+
+	if err := db.migrateWithBackup(executor, migrate); err != nil || strings.Join(steps, ",") != "migrate" {
+		t.Errorf("backup should be skipped when no migration is pending, got %v after %v", err, steps)
+	}
+
+	markers = markers[1:]
+	steps = nil
+	if err := db.migrateWithBackup(executor, migrate); err != nil || strings.Join(steps, ",") != "pg_dump,migrate" {
+		t.Errorf("backup should be taken when a migration is not yet applied, got %v after %v", err, steps)
+	}
+
+	d.onQuery = func(query string) {
+		d.rows = nil
+	}
+	steps = nil
+	if err := db.migrateWithBackup(executor, migrate); err != nil || strings.Join(steps, ",") != "migrate" {
+		t.Errorf("backup of an empty database should be skipped, got %v after %v", err, steps)
+	}
+}
+
+func TestPruneMigrationBackups(t *testing.T) {
+	config := &Config{BaseDir: t.TempDir(), DbName: "rdio"}
+
+	dir := config.migrationBackupDir()
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if err := os.WriteFile(config.migrationBackupFile(at.Add(time.Duration(i)*time.Hour)), nil, 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"other-20250301-120000.dump", "rdio-notes.dump"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := config.pruneMigrationBackups(2); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	if got := strings.Join(names, ","); got != "other-20250301-120000.dump,rdio-20250301-140000.dump,rdio-20250301-150000.dump,rdio-notes.dump" {
+		t.Errorf("expected the 2 most recent backups of the database kept, got %s", got)
+	}
+}
+
+func TestMigrationBackupArgs(t *testing.T) {
+	config := &Config{MigrationBackupArgs: []string{"-Fc", "--file={file}", "--exclude-table=audio archive", "rdio"}}
+
+	args := config.migrationBackupArgs("/tmp/rdio.dump")
+	if got := strings.Join(args, "|"); got != "-Fc|--file=/tmp/rdio.dump|--exclude-table=audio archive|rdio" {
+		t.Errorf("unexpected backup arguments %q", got)
+	}
+	if config.MigrationBackupArgs[1] != "--file={file}" {
+		t.Errorf("configured arguments should be left untouched, got %v", config.MigrationBackupArgs)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// MigrationInfo is the status of a database migration
+type MigrationInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Marker      string `json:"marker,omitempty"`
+	Tracked     bool   `json:"tracked"`             // false for the migrations run at every start
+	Applied     bool   `json:"applied"`             // for the ones run at every start, once their legacy tables are gone or they ran at this start
+	AppliedAt   int64  `json:"appliedAt,omitempty"` // unix milliseconds, unknown for the migrations recorded before it was
+}
+
+// MigrationStatus returns the known migrations in the order they run, and whether and when they were applied
+func (db *Database) MigrationStatus() ([]MigrationInfo, error) {
+	tables, err := db.existingTables()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.migrationStatus(tables)
+}
+
+// migrationStatus returns the status of the migrations given the existing tables
+func (db *Database) migrationStatus(tables map[string]bool) ([]MigrationInfo, error) {
+	applied := map[string]sql.NullInt64{}
+
+	query := `SELECT "name", "appliedAt" FROM "rdioScannerMeta"`
+	rows, err := db.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name      string
+			appliedAt sql.NullInt64
+		)
+		if err := rows.Scan(&name, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[name] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := []MigrationInfo{}
+	for _, migration := range databaseMigrations {
+		info := MigrationInfo{
+			Name:        migration.name,
+			Description: migration.description,
+			Marker:      migration.marker,
+			Tracked:     migration.marker != "",
+		}
+
+		switch {
+		case info.Tracked:
+			if appliedAt, ok := applied[migration.marker]; ok {
+				info.Applied = true
+				if appliedAt.Valid {
+					info.AppliedAt = appliedAt.Int64
+				}
+			}
+
+		case len(migration.legacy) > 0:
+			info.Applied = true
+			for _, table := range migration.legacy {
+				if tables[table] {
+					info.Applied = false
+				}
+			}
+
+		default:
+			info.Applied = db.migrated[migration.name]
+		}
+
+		status = append(status, info)
+	}
+
+	return status, nil
+}
+
+// MigrationStatusHandler returns the status of the database migrations
+func (admin *Admin) MigrationStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	status, err := admin.Controller.Database.MigrationStatus()
+	if err != nil {
+		admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("migration status: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read the migration status"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"migrations": status})
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestMigrationStatus(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	d.onQuery = func(query string) {
+		if strings.Contains(query, "pg_tables") {
+			d.rows = [][]driver.Value{{"rdioScannerMeta"}, {"rdioScannerTags"}, {"tags"}}
+			return
+		}
+		d.rows = [][]driver.Value{
+			{migrationTagsGroupsUniqueLabels, int64(1765800000000)},
+			{accessConversionMigration, nil}, // recorded before the time was
+			{"20210101000000-unknown", nil},
+		}
+	}
+	db.migrated = map[string]bool{"userPins": true}
+
+	status, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(status) != len(databaseMigrations) {
+		t.Fatalf("expected %d migrations, got %d", len(databaseMigrations), len(status))
+	}
+	if status[0].Name != "groups" || status[len(status)-1].Name != "fixAutoIncrementSequences" {
+		t.Errorf("expected the migrations in the order they run, got %s first and %s last", status[0].Name, status[len(status)-1].Name)
+	}
+
+	byName := map[string]MigrationInfo{}
+	for _, info := range status {
+		if _, ok := byName[info.Name]; ok {
+			t.Errorf("duplicate migration %s", info.Name)
+		}
+		byName[info.Name] = info
+	}
+
+	for name, want := range map[string]MigrationInfo{
+		"groups":                 {Tracked: false, Applied: true},
+		"tags":                   {Tracked: false, Applied: false}, // its legacy table is still there
+		"userPins":               {Tracked: false, Applied: true},
+		"users":                  {Tracked: false, Applied: false}, // not run at this start
+		"tagsGroupsUniqueLabels": {Tracked: true, Applied: true, AppliedAt: 1765800000000},
+		"accessesToUserGroups":   {Tracked: true, Applied: true},
+		"removeLedColors":        {Tracked: true, Applied: false},
+	} {
+		got := byName[name]
+		if got.Tracked != want.Tracked || got.Applied != want.Applied || got.AppliedAt != want.AppliedAt {
+			t.Errorf("%s: expected tracked=%t applied=%t at %d, got %+v", name, want.Tracked, want.Applied, want.AppliedAt, got)
+		}
+	}
+}
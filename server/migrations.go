@@ -121,6 +121,7 @@ func migrateApikeys(db *Database) error {
 
 func migrateCalls(db *Database) error {
 	var (
+		args  []any
 		err   error
 		query string
 		rows  *sql.Rows
@@ -207,7 +208,7 @@ func migrateCalls(db *Database) error {
 		}
 
 		if audioFilename.Valid {
-			call.AudioFilename = escapeQuotes(audioFilename.String)
+			call.AudioFilename = audioFilename.String
 		}
 
 		if audioMime.Valid {
@@ -255,9 +256,9 @@ func migrateCalls(db *Database) error {
 			frequencyValue = int64(frequency.Int32)
 		}
 
-		query = fmt.Sprintf(`INSERT INTO "calls" ("callId", "audio", "audioFilename", "audioMime", "siteRef", "systemId", "talkgroupId", "timestamp", "frequency") VALUES (%d, $1, '%s', '%s', 0, %d, %d, %d, %d)`, call.Id, call.AudioFilename, call.AudioMime, systems[systemRef.Int32], talkgroups[systemRef.Int32][talkgroupRef.Int32], timestamp, frequencyValue)
+		query, args = migrationCallInsert(call, systems[systemRef.Int32], talkgroups[systemRef.Int32][talkgroupRef.Int32], timestamp, frequencyValue)
 
-		if _, err = tx.Exec(query, call.Audio); err == nil {
+		if _, err = tx.Exec(query, args...); err == nil {
 			if patches.Valid && len(patches.String) > 0 {
 				var f any
 				if err = json.Unmarshal([]byte(patches.String), &f); err == nil {
@@ -385,8 +386,15 @@ func migrateCallsRefs(db *Database) error {
 	return nil
 }
 
+// migrationCallInsert returns the insert of a legacy call, its audio and strings passed as arguments
+func migrationCallInsert(call *Call, systemId int32, talkgroupId int32, timestamp int64, frequency int64) (string, []any) {
+	query := fmt.Sprintf(`INSERT INTO "calls" ("callId", "audio", "audioFilename", "audioMime", "siteRef", "systemId", "talkgroupId", "timestamp", "frequency") VALUES (%d, $1, $2, $3, 0, %d, %d, %d, %d)`, call.Id, systemId, talkgroupId, timestamp, frequency)
+	return query, []any{call.Audio, call.AudioFilename, call.AudioMime}
+}
+
 func migrateDirwatches(db *Database) error {
 	var (
+		args  []any
 		err   error
 		query string
 		rows  *sql.Rows
@@ -476,7 +484,7 @@ func migrateDirwatches(db *Database) error {
 		}
 
 		if directory.Valid && len(directory.String) > 0 {
-			dirwatch.Directory = escapeQuotes(directory.String)
+			dirwatch.Directory = directory.String
 		} else {
 			continue
 		}
@@ -486,7 +494,7 @@ func migrateDirwatches(db *Database) error {
 		}
 
 		if extension.Valid {
-			dirwatch.Extension = escapeQuotes(extension.String)
+			dirwatch.Extension = extension.String
 		}
 
 		if frequency.Valid {
@@ -494,7 +502,7 @@ func migrateDirwatches(db *Database) error {
 		}
 
 		if mask.Valid && len(mask.String) > 0 {
-			dirwatch.Mask = escapeQuotes(mask.String)
+			dirwatch.Mask = mask.String
 		}
 
 		if kind.Valid && len(kind.String) > 0 {
@@ -517,8 +525,8 @@ func migrateDirwatches(db *Database) error {
 			refTalkgroup = nil
 		}
 
-		query = fmt.Sprintf(`INSERT INTO "dirwatches" ("dirwatchId", "delay", "deleteAfter", "directory", "disabled", "extension", "frequency", "mask", "order", "systemId", "talkgroupId", "type") VALUES (%d, %d, %t, '%s', %t, '%s', %d, '%s', %d, %d, %d, '%s')`, dirwatch.Id, dirwatch.Delay, dirwatch.DeleteAfter, dirwatch.Directory, dirwatch.Disabled, dirwatch.Extension, dirwatch.Frequency, dirwatch.Mask, dirwatch.Order, refSystem, refTalkgroup, dirwatch.Kind)
-		if _, err = tx.Exec(query); err != nil {
+		query, args = migrationDirwatchInsert(dirwatch, refSystem, refTalkgroup)
+		if _, err = tx.Exec(query, args...); err != nil {
 			log.Println(formatError(err, query))
 		}
 	}
@@ -538,6 +546,13 @@ func migrateDirwatches(db *Database) error {
 	return nil
 }
 
+// migrationDirwatchInsert returns the insert of a legacy dirwatch, its strings and references passed as
+// arguments, the references being nil for none
+func migrationDirwatchInsert(dirwatch *Dirwatch, systemId any, talkgroupId any) (string, []any) {
+	query := fmt.Sprintf(`INSERT INTO "dirwatches" ("dirwatchId", "delay", "deleteAfter", "directory", "disabled", "extension", "frequency", "mask", "order", "systemId", "talkgroupId", "type") VALUES (%d, %d, %t, $1, %t, $2, %d, $3, %d, $4, $5, $6)`, dirwatch.Id, dirwatch.Delay, dirwatch.DeleteAfter, dirwatch.Disabled, dirwatch.Frequency, dirwatch.Order)
+	return query, []any{dirwatch.Directory, dirwatch.Extension, dirwatch.Mask, systemId, talkgroupId, dirwatch.Kind}
+}
+
 func migrateDownstreams(db *Database) error {
 	var (
 		err   error
@@ -711,6 +726,7 @@ func migrateGroups(db *Database) error {
 
 func migrateLogs(db *Database) error {
 	var (
+		args  []any
 		err   error
 		query string
 		rows  *sql.Rows
@@ -768,13 +784,13 @@ func migrateLogs(db *Database) error {
 		}
 
 		if message.Valid && len(message.String) > 0 {
-			l.Message = escapeQuotes(message.String)
+			l.Message = message.String
 		} else {
 			continue
 		}
 
-		query = fmt.Sprintf(`INSERT INTO "logs" ("logId", "level", "message", "timestamp") VALUES (%d, '%s', '%s', %d)`, l.Id, l.Level, l.Message, timestamp)
-		if _, err = tx.Exec(query); err != nil {
+		query, args = migrationLogInsert(l, timestamp)
+		if _, err = tx.Exec(query, args...); err != nil {
 			log.Println(formatError(err, query))
 		}
 	}
@@ -794,6 +810,12 @@ func migrateLogs(db *Database) error {
 	return nil
 }
 
+// migrationLogInsert returns the insert of a legacy log, its strings passed as arguments
+func migrationLogInsert(l *Log, timestamp int64) (string, []any) {
+	query := fmt.Sprintf(`INSERT INTO "logs" ("logId", "level", "message", "timestamp") VALUES (%d, $1, $2, %d)`, l.Id, timestamp)
+	return query, []any{l.Level, l.Message}
+}
+
 func migrateMeta(db *Database) error {
 	// Prepare migration table (v6 style) - don't drop it, we need it for tracking migrations
 	_, err := prepareMigration(db)
@@ -825,7 +847,21 @@ func prepareMigration(db *Database) (bool, error) {
 		}
 	}
 
-	return verbose, err
+	if err != nil {
+		return verbose, err
+	}
+
+	// Record when the migrations are applied from now on, the earlier ones being left without a time
+	for _, query = range []string{
+		`ALTER TABLE "rdioScannerMeta" ADD COLUMN IF NOT EXISTS "appliedAt" bigint`,
+		`ALTER TABLE "rdioScannerMeta" ALTER COLUMN "appliedAt" SET DEFAULT (extract(epoch from now()) * 1000)::bigint`,
+	} {
+		if _, err = db.Sql.Exec(query); err != nil {
+			return verbose, err
+		}
+	}
+
+	return verbose, nil
 }
 
 // migrateWithSchema runs a migration with schema changes, tracking it in rdioScannerMeta (v6 style)
@@ -1237,6 +1273,7 @@ func migrateTags(db *Database) error {
 
 func migrateTalkgroups(db *Database) error {
 	var (
+		args  []any
 		err   error
 		query string
 		rows  *sql.Rows
@@ -1312,11 +1349,11 @@ func migrateTalkgroups(db *Database) error {
 		}
 
 		if label.Valid {
-			talkgroup.Label = escapeQuotes(label.String)
+			talkgroup.Label = label.String
 		}
 
 		if name.Valid {
-			talkgroup.Name = escapeQuotes(name.String)
+			talkgroup.Name = name.String
 		}
 
 		if order.Valid {
@@ -1335,8 +1372,8 @@ func migrateTalkgroups(db *Database) error {
 			talkgroup.TagId = uint64(tagId.Int64)
 		}
 
-		query = fmt.Sprintf(`INSERT INTO "talkgroups" ("talkgroupId", "frequency", "label", "name", "order", "systemId", "tagId", "talkgroupRef") VALUES (%d, %d, '%s', '%s', %d, %d, %d, %d)`, talkgroup.Id, talkgroup.Frequency, talkgroup.Label, talkgroup.Name, talkgroup.Order, systems[systemId.Int64], talkgroup.TagId, talkgroup.TalkgroupRef)
-		if _, err = tx.Exec(query); err == nil {
+		query, args = migrationTalkgroupInsert(talkgroup, systems[systemId.Int64])
+		if _, err = tx.Exec(query, args...); err == nil {
 			query = fmt.Sprintf(`INSERT INTO "talkgroupGroups" ("groupId", "talkgroupId") VALUES (%d, %d)`, talkgroup.GroupIds[0], talkgroup.Id)
 			if _, err = tx.Exec(query); err != nil {
 				log.Println(formatError(err, query))
@@ -1362,6 +1399,12 @@ func migrateTalkgroups(db *Database) error {
 	return nil
 }
 
+// migrationTalkgroupInsert returns the insert of a legacy talkgroup, its strings passed as arguments
+func migrationTalkgroupInsert(talkgroup *Talkgroup, systemId int64) (string, []any) {
+	query := fmt.Sprintf(`INSERT INTO "talkgroups" ("talkgroupId", "frequency", "label", "name", "order", "systemId", "tagId", "talkgroupRef") VALUES (%d, %d, $1, $2, %d, %d, %d, %d)`, talkgroup.Id, talkgroup.Frequency, talkgroup.Order, systemId, talkgroup.TagId, talkgroup.TalkgroupRef)
+	return query, []any{talkgroup.Label, talkgroup.Name}
+}
+
 func migrateUnits(db *Database) error {
 	var (
 		err   error
@@ -1566,11 +1609,6 @@ func migrateUserPins(db *Database) error {
 		}
 	}
 
-	// Drop legacy accesses table if it still exists
-	if _, err := db.Sql.Exec(`DROP TABLE IF EXISTS "accesses"`); err != nil {
-		log.Printf("DEBUG: Unable to drop legacy accesses table: %v", err)
-	}
-
 	// Load existing pins to make sure we don't duplicate values
 	existingPins := map[string]struct{}{}
 	rows, err := db.Sql.Query(`SELECT "pin" FROM "users" WHERE "pin" IS NOT NULL AND "pin" <> ''`)
@@ -1882,7 +1920,7 @@ func migrateCallsPerformanceIndexes(db *Database) error {
 		`CREATE INDEX IF NOT EXISTS "calls_system_talkgroup_timestamp_idx" ON "calls" ("systemId", "talkgroupId", "timestamp")`,
 	}
 
-	return db.migrateWithSchema("20250101000000-optimize-search-performance", queries, verbose)
+	return db.migrateWithSchema(migrationOptimizeSearchPerformance, queries, verbose)
 }
 
 // migrateCallUnitsIndex adds index on callUnits table for fast lookup by callId
@@ -1893,7 +1931,7 @@ func migrateCallUnitsIndex(db *Database) error {
 	queries := []string{
 		`CREATE INDEX IF NOT EXISTS "callUnits_callId_idx" ON "callUnits" ("callId", "offset")`,
 	}
-	return db.migrateWithSchema("20250127000000-callunits-callid-index", queries, true)
+	return db.migrateWithSchema(migrationCallUnitsIndex, queries, true)
 }
 
 // migrateTagsGroupsUniqueLabels adds unique constraints on the label column for tags and groups tables
@@ -1908,9 +1946,9 @@ func migrateTagsGroupsUniqueLabels(db *Database, verbose bool) error {
 	formatError := errorFormatter("migration", "migrateTagsGroupsUniqueLabels")
 
 	// Check if migration has already been applied
-	query = `SELECT COUNT(*) FROM "rdioScannerMeta" WHERE "name" = '20251215000000-tags-groups-unique-labels'`
+	query = `SELECT COUNT(*) FROM "rdioScannerMeta" WHERE "name" = $1`
 
-	if err = db.Sql.QueryRow(query).Scan(&count); err != nil {
+	if err = db.Sql.QueryRow(query, migrationTagsGroupsUniqueLabels).Scan(&count); err != nil {
 		return formatError(err, query)
 	}
 
@@ -1919,7 +1957,7 @@ func migrateTagsGroupsUniqueLabels(db *Database, verbose bool) error {
 	}
 
 	if verbose {
-		log.Printf("running database migration 20251215000000-tags-groups-unique-labels")
+		log.Printf("running database migration %s", migrationTagsGroupsUniqueLabels)
 	}
 
 	// Clean up duplicate tags - keep the first occurrence of each label
@@ -1944,7 +1982,7 @@ func migrateTagsGroupsUniqueLabels(db *Database, verbose bool) error {
 		`CREATE UNIQUE INDEX IF NOT EXISTS "groups_label_unique" ON "groups" ("label")`,
 	}
 
-	return db.migrateWithSchema("20251215000000-tags-groups-unique-labels", queries, verbose)
+	return db.migrateWithSchema(migrationTagsGroupsUniqueLabels, queries, verbose)
 }
 
 // Migration to remove alert tone columns from systems, talkgroups, tags, and groups
@@ -1953,7 +1991,7 @@ func migrateRemoveAlertTones(db *Database) error {
 
 	// Check if migration already ran
 	var count int
-	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "migrations" WHERE "id" = '20251219000000-remove-alert-tones'`).Scan(&count); err == nil && count > 0 {
+	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "migrations" WHERE "id" = $1`, migrationRemoveAlertTones).Scan(&count); err == nil && count > 0 {
 		return nil
 	}
 
@@ -1972,13 +2010,13 @@ func migrateRemoveAlertTones(db *Database) error {
 
 	if len(queries) == 0 {
 		// All columns already removed, just record migration
-		if _, err := db.Sql.Exec(`INSERT INTO "migrations" ("id") VALUES ('20251219000000-remove-alert-tones')`); err != nil {
+		if _, err := db.Sql.Exec(`INSERT INTO "migrations" ("id") VALUES ($1)`, migrationRemoveAlertTones); err != nil {
 			return formatError(err, "recording migration")
 		}
 		return nil
 	}
 
-	return db.migrateWithSchema("20251219000000-remove-alert-tones", queries, verbose)
+	return db.migrateWithSchema(migrationRemoveAlertTones, queries, verbose)
 }
 
 // Migration to remove led columns from systems, talkgroups, tags, and groups
@@ -1987,7 +2025,7 @@ func migrateRemoveLedColors(db *Database) error {
 
 	// Check if migration already ran
 	var count int
-	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "migrations" WHERE "id" = '20251219000001-remove-led-colors'`).Scan(&count); err == nil && count > 0 {
+	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "migrations" WHERE "id" = $1`, migrationRemoveLedColors).Scan(&count); err == nil && count > 0 {
 		return nil
 	}
 
@@ -2005,13 +2043,13 @@ func migrateRemoveLedColors(db *Database) error {
 	}
 
 	if len(queries) == 0 {
-		if _, err := db.Sql.Exec(`INSERT INTO "migrations" ("id") VALUES ('20251219000001-remove-led-colors')`); err != nil {
+		if _, err := db.Sql.Exec(`INSERT INTO "migrations" ("id") VALUES ($1)`, migrationRemoveLedColors); err != nil {
 			return formatError(err, "recording migration")
 		}
 		return nil
 	}
 
-	return db.migrateWithSchema("20251219000001-remove-led-colors", queries, verbose)
+	return db.migrateWithSchema(migrationRemoveLedColors, queries, verbose)
 }
 
 // Migration to fix invalid user timestamps (empty strings or invalid values)
@@ -2020,7 +2058,7 @@ func migrateFixUserTimestamps(db *Database) error {
 
 	// Check if migration already ran
 	var count int
-	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "rdioScannerMeta" WHERE "name" = '20251228000000-fix-user-timestamps'`).Scan(&count); err != nil {
+	if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "rdioScannerMeta" WHERE "name" = $1`, migrationFixUserTimestamps).Scan(&count); err != nil {
 		return formatError(err, "checking migration status")
 	}
 
@@ -2028,7 +2066,7 @@ func migrateFixUserTimestamps(db *Database) error {
 		return nil // Already migrated
 	}
 
-	log.Printf("running database migration 20251228000000-fix-user-timestamps")
+	log.Printf("running database migration %s", migrationFixUserTimestamps)
 
 	// Fix users with empty or invalid createdAt timestamps
 	// Set to current time if empty, invalid, or 0
@@ -2047,8 +2085,8 @@ func migrateFixUserTimestamps(db *Database) error {
 	}
 
 	// Record migration as completed
-	query = `INSERT INTO "rdioScannerMeta" ("name") VALUES ('20251228000000-fix-user-timestamps')`
-	if _, err := db.Sql.Exec(query); err != nil {
+	query = `INSERT INTO "rdioScannerMeta" ("name") VALUES ($1)`
+	if _, err := db.Sql.Exec(query, migrationFixUserTimestamps); err != nil {
 		return formatError(err, "recording migration")
 	}
 
@@ -2135,6 +2173,21 @@ func migrateDownstreamsName(db *Database) error {
 	return nil
 }
 
+// migrateDownstreamsThrottle adds rate limiting columns to downstreams table
+func migrateDownstreamsThrottle(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "maxPerMinute" integer NOT NULL DEFAULT 0`,
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "minInterval" integer NOT NULL DEFAULT 0`,
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "throttleMode" text NOT NULL DEFAULT 'queue'`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
 func migrateTagsColor(db *Database) error {
 	query := `ALTER TABLE "tags" ADD COLUMN IF NOT EXISTS "color" text NOT NULL DEFAULT ''`
 	if _, err := db.Sql.Exec(query); err != nil {
@@ -2142,3 +2195,228 @@ func migrateTagsColor(db *Database) error {
 	}
 	return nil
 }
+
+// migrateTalkgroupsEphemeral adds short retention columns to talkgroups table
+func migrateTalkgroupsEphemeral(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "ephemeral" boolean NOT NULL DEFAULT false`,
+		`ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "ephemeralTtl" integer NOT NULL DEFAULT 0`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+// migrateUserGroupsDefaultAlertPreferences adds defaultAlertPreferences column to userGroups table
+func migrateUserGroupsDefaultAlertPreferences(db *Database) error {
+	query := `ALTER TABLE "userGroups" ADD COLUMN IF NOT EXISTS "defaultAlertPreferences" text NOT NULL DEFAULT ''`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateSystemsVocabularyProfile adds vocabularyProfileId column to systems table
+func migrateSystemsVocabularyProfile(db *Database) error {
+	query := `ALTER TABLE "systems" ADD COLUMN IF NOT EXISTS "vocabularyProfileId" bigint NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateTalkgroupsPriority(db *Database) error {
+	query := `ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "priority" integer NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateAlertsSuppressed(db *Database) error {
+	query := `ALTER TABLE "alerts" ADD COLUMN IF NOT EXISTS "suppressed" boolean NOT NULL DEFAULT false`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateDownstreamsAudioFormat(db *Database) error {
+	query := `ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "audioFormat" text NOT NULL DEFAULT ''`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateDownstreamsRetry adds retry columns to downstreams table
+func migrateDownstreamsRetry(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "maxRetries" integer NOT NULL DEFAULT 3`,
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "retryBackoff" integer NOT NULL DEFAULT 1000`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+func migrateTalkgroupsGain(db *Database) error {
+	query := `ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "gain" double precision NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateDownstreamsTimeout(db *Database) error {
+	query := `ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "timeoutSeconds" integer NOT NULL DEFAULT 30`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateDownstreamsHeaders(db *Database) error {
+	query := `ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "headers" text NOT NULL DEFAULT ''`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateDownstreamQueue(db *Database) error {
+	query := `CREATE TABLE IF NOT EXISTS "downstreamQueue" (
+    "downstreamQueueId" bigserial NOT NULL PRIMARY KEY,
+    "callId" bigint NOT NULL,
+    "downstreamId" bigint NOT NULL,
+    "attempts" integer NOT NULL DEFAULT 0,
+    "nextAttemptAt" bigint NOT NULL DEFAULT 0,
+    CONSTRAINT "downstreamQueue_callId_downstreamId" UNIQUE ("callId", "downstreamId"),
+    CONSTRAINT "downstreamQueue_callId" FOREIGN KEY ("callId") REFERENCES "calls" ("callId") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "downstreamQueue_downstreamId" FOREIGN KEY ("downstreamId") REFERENCES "downstreams" ("downstreamId") ON DELETE CASCADE ON UPDATE CASCADE
+  )`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateDownstreamsProtocol(db *Database) error {
+	query := `ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "protocol" text NOT NULL DEFAULT 'v6'`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateDownstreamsFilters adds the tones and keywords filter columns to downstreams table
+func migrateDownstreamsFilters(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "requireKeywords" text NOT NULL DEFAULT ''`,
+		`ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "requireTones" boolean NOT NULL DEFAULT false`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+// migrateUserGroupsInheritance adds the parent group and the removed system access columns to userGroups table
+func migrateUserGroupsInheritance(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "userGroups" ADD COLUMN IF NOT EXISTS "parentGroupId" bigint NOT NULL DEFAULT 0`,
+		`ALTER TABLE "userGroups" ADD COLUMN IF NOT EXISTS "systemAccessRemove" text NOT NULL DEFAULT ''`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+// migrateRegistrationCodesLastUsedAt adds lastUsedAt column to registrationCodes table
+func migrateRegistrationCodesLastUsedAt(db *Database) error {
+	query := `ALTER TABLE "registrationCodes" ADD COLUMN IF NOT EXISTS "lastUsedAt" bigint NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateVocabularyProfilesKeywordLists(db *Database) error {
+	query := `ALTER TABLE "vocabularyProfiles" ADD COLUMN IF NOT EXISTS "keywordListIds" text NOT NULL DEFAULT '[]'`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+func migrateSystemsAutoPopulateDefaults(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "systems" ADD COLUMN IF NOT EXISTS "defaultGroupId" bigint NOT NULL DEFAULT 0`,
+		`ALTER TABLE "systems" ADD COLUMN IF NOT EXISTS "defaultTagId" bigint NOT NULL DEFAULT 0`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+func migrateDisplayConfidence(db *Database) error {
+	queries := []string{
+		`ALTER TABLE "systems" ADD COLUMN IF NOT EXISTS "displayConfidence" double precision NOT NULL DEFAULT 0`,
+		`ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "displayConfidence" double precision NOT NULL DEFAULT 0`,
+	}
+	for _, query := range queries {
+		if _, err := db.Sql.Exec(query); err != nil {
+			log.Printf("migration note: %v", err)
+		}
+	}
+	return nil
+}
+
+func migrateTalkgroupsToneLearning(db *Database) error {
+	query := `ALTER TABLE "talkgroups" ADD COLUMN IF NOT EXISTS "toneLearningUntil" bigint NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateDownstreamsMaxCallAge adds the max call age column to downstreams table
+func migrateDownstreamsMaxCallAge(db *Database) error {
+	query := `ALTER TABLE "downstreams" ADD COLUMN IF NOT EXISTS "maxCallAge" integer NOT NULL DEFAULT 0`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateKeywordListsPhonetic adds the phonetic matching toggle column to keywordLists table
+func migrateKeywordListsPhonetic(db *Database) error {
+	query := `ALTER TABLE "keywordLists" ADD COLUMN IF NOT EXISTS "phonetic" boolean NOT NULL DEFAULT false`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
+
+// migrateUserAlertPreferencesExclusions adds the exclusion keywords column to userAlertPreferences table
+func migrateUserAlertPreferencesExclusions(db *Database) error {
+	query := `ALTER TABLE "userAlertPreferences" ADD COLUMN IF NOT EXISTS "exclusions" text NOT NULL DEFAULT '[]'`
+	if _, err := db.Sql.Exec(query); err != nil {
+		log.Printf("migration note: %v", err)
+	}
+	return nil
+}
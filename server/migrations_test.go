@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// hostileLabel is a legacy value which broke out of, or injected into, the quoted literals of the migrations
+const hostileLabel = `O'Brien \ Fire'); DROP TABLE "calls"; --`
+
+func TestMigrationInsertsParameterized(t *testing.T) {
+	for name, insert := range map[string]func() (string, []any){
+		"calls": func() (string, []any) {
+			return migrationCallInsert(&Call{Id: 1, Audio: []byte("audio"), AudioFilename: hostileLabel, AudioMime: hostileLabel}, 2, 3, 1700000000000, 0)
+		},
+		"dirwatches": func() (string, []any) {
+			return migrationDirwatchInsert(&Dirwatch{Id: 1, Directory: hostileLabel, Extension: hostileLabel, Mask: hostileLabel, Kind: hostileLabel}, nil, nil)
+		},
+		"logs": func() (string, []any) {
+			return migrationLogInsert(&Log{Id: 1, Level: hostileLabel, Message: hostileLabel}, 1700000000000)
+		},
+		"talkgroups": func() (string, []any) {
+			return migrationTalkgroupInsert(&Talkgroup{Id: 1, Label: hostileLabel, Name: hostileLabel}, 2)
+		},
+	} {
+		query, args := insert()
+
+		if strings.Contains(query, "O'Brien") || strings.Contains(query, `\`) {
+			t.Errorf("%s: expected no legacy value in the query, got %s", name, query)
+		}
+		if strings.Contains(query, "%!") {
+			t.Errorf("%s: expected a well formed query, got %s", name, query)
+		}
+
+		if !strings.Contains(query, fmt.Sprintf("$%d)", len(args))) && !strings.Contains(query, fmt.Sprintf("$%d,", len(args))) {
+			t.Errorf("%s: expected %d placeholders in %s", name, len(args), query)
+		}
+
+		found := false
+		for _, arg := range args {
+			if arg == hostileLabel {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected the legacy value passed unchanged, got %v", name, args)
+		}
+	}
+}
+
+func TestMigrationDirwatchInsertReferences(t *testing.T) {
+	query, args := migrationDirwatchInsert(&Dirwatch{Id: 1}, nil, nil)
+	if args[3] != nil || args[4] != nil {
+		t.Errorf("expected null system and talkgroup references, got %v in %s", args, query)
+	}
+
+	_, args = migrationDirwatchInsert(&Dirwatch{Id: 1}, int32(2), int32(3))
+	if args[3] != int32(2) || args[4] != int32(3) {
+		t.Errorf("expected the system and talkgroup references, got %v", args)
+	}
+}
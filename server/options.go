@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 
 	"golang.org/x/crypto/bcrypt"
@@ -29,13 +30,23 @@ import (
 
 type Options struct {
 	AudioConversion             uint   `json:"audioConversion"`
+	AudioNormalizeChannels      uint   `json:"audioNormalizeChannels"`
+	AudioNormalizeKeepOriginal  bool   `json:"audioNormalizeKeepOriginal"` // normalized audio only feeds transcription and tone detection
+	AudioNormalizeSampleRate    uint   `json:"audioNormalizeSampleRate"`   // hz, 0 disables the normalization
 	AutoPopulate                bool   `json:"autoPopulate"`
 	Branding                    string `json:"branding"`
+	BrandingColor               string `json:"brandingColor"`   // theme color of the web app, e.g. "#1976d2"
+	BrandingLogoUrl             string `json:"brandingLogoUrl"` // logo of the web app, e.g. "/email-logo" or an https url
 	DefaultSystemDelay          uint   `json:"defaultSystemDelay"`
 	DimmerDelay                 uint   `json:"dimmerDelay"`
 	DisableDuplicateDetection   bool   `json:"disableDuplicateDetection"`
 	DuplicateDetectionTimeFrame uint   `json:"duplicateDetectionTimeFrame"`
 	Email                       string `json:"email"`
+	IncidentGrouping            bool   `json:"incidentGrouping"`
+	IncidentGroupingKeywords    string `json:"incidentGroupingKeywords"` // comma separated
+	IncidentGroupingPatches     bool   `json:"incidentGroupingPatches"`
+	IncidentGroupingUnits       bool   `json:"incidentGroupingUnits"`
+	IncidentGroupingWindow      uint   `json:"incidentGroupingWindow"` // seconds
 	KeypadBeeps                 string `json:"keypadBeeps"`
 	MaxClients                  uint   `json:"maxClients"`
 	PlaybackGoesLive            bool   `json:"playbackGoesLive"`
@@ -85,9 +96,38 @@ type Options struct {
 	TranscriptionConfig         TranscriptionConfig `json:"transcriptionConfig"`
 	TranscriptionFailureThreshold uint            `json:"transcriptionFailureThreshold"`
 	ToneDetectionIssueThreshold uint            `json:"toneDetectionIssueThreshold"`
+	CallRateAnomalySensitivity  uint              `json:"callRateAnomalySensitivity"` // 0 disables the detector
+	CallRateAnomalyCooldown     uint              `json:"callRateAnomalyCooldown"`    // minutes
+	KeywordAlertCooldown        uint              `json:"keywordAlertCooldown"`       // minutes before a keyword alerts a user again, 0 disables the cooldown
+	OrphanSweepInterval         uint              `json:"orphanSweepInterval"`        // hours, 0 disables the sweep
+	RegistrationRetentionDays   uint              `json:"registrationRetentionDays"`  // days spent registration codes and invitations are kept, 0 keeps them forever
+	DeviceTokenRetentionDays    uint              `json:"deviceTokenRetentionDays"`   // days device tokens are kept unused, 0 keeps them forever
+	AuditLogEnabled             bool              `json:"auditLogEnabled"`            // record the admin actions in the audit log, the user group audit log being always on
+	AuditLogRetentionDays       uint              `json:"auditLogRetentionDays"`      // days audit log entries are kept, 0 keeps them forever
+	ZipCodeFormat               string            `json:"zipCodeFormat"`              // "any", "us" or "ca" zip codes accepted when users are saved
+	MissingAudioResponse        string            `json:"missingAudioResponse"`       // "gone" (410) or "notFound" (404) served for a call whose audio was archived or pruned
+	AudioSniffing               bool              `json:"audioSniffing"`              // correct the declared audio mime of ingested calls from their content
+	DelayedMaxEntries           uint              `json:"delayedMaxEntries"`          // calls waiting for their delay at most, 0 for no limit
+	DelayedOverflowPolicy       string            `json:"delayedOverflowPolicy"`      // "drop-oldest" or "release-immediately" once delayedMaxEntries is reached
+	SystemAlertWebhookUrl       string            `json:"systemAlertWebhookUrl"`      // URL notified when a system alert is raised or resolved, empty disables the webhook
+	SystemAlertWebhookSecret    string            `json:"systemAlertWebhookSecret"`   // HMAC-SHA256 key signing the webhook body, empty leaves it unsigned
+	SystemAlertWebhookSeverity  string            `json:"systemAlertWebhookSeverity"` // least severe alert sent to the webhook
+	DownstreamHttp2             bool              `json:"downstreamHttp2"`
+	DownstreamMaxIdleConns      uint              `json:"downstreamMaxIdleConns"`     // idle connections kept per downstream host
+	DownstreamIdleConnTimeout   uint              `json:"downstreamIdleConnTimeout"`  // seconds
+	ConnectHistoryMinutes       uint              `json:"connectHistoryMinutes"`      // calls replayed to a client turning its livefeed on, 0 for only new calls
+	ConnectHistoryMaxCalls      uint              `json:"connectHistoryMaxCalls"`     // at most connectHistoryCallsLimit
 	AlertRetentionDays          uint              `json:"alertRetentionDays"`
 	RelayServerURL              string            `json:"relayServerURL"`
 	RelayServerAPIKey           string            `json:"relayServerAPIKey"`
+	WebPushVapidPublicKey       string            `json:"webPushVapidPublicKey"`  // base64url P-256 public key, handed to the browsers subscribing
+	WebPushVapidPrivateKey      string            `json:"webPushVapidPrivateKey"` // base64url P-256 private key signing the web push requests
+	WebPushVapidSubject         string            `json:"webPushVapidSubject"`    // mailto: or https: contact of the push services
+	APNsKeyId                   string            `json:"apnsKeyId"`              // id of the APNs authentication key
+	APNsTeamId                  string            `json:"apnsTeamId"`             // Apple developer team id
+	APNsBundleId                string            `json:"apnsBundleId"`           // bundle id of the iOS app, the topic of the notifications
+	APNsKey                     string            `json:"apnsKey"`                // contents of the .p8 authentication key
+	APNsSandbox                 bool              `json:"apnsSandbox"`            // push to the development environment of APNs
 	RadioReferenceAPIKey        string            `json:"radioReferenceAPIKey"`
 	AdminLocalhostOnly          bool              `json:"adminLocalhostOnly"`
 	ConfigSyncEnabled           bool              `json:"configSyncEnabled"`
@@ -105,21 +145,45 @@ type Options struct {
 // TranscriptionConfig contains configuration for transcription
 type TranscriptionConfig struct {
 	Enabled                      bool     `json:"enabled"`
-	Provider                     string   `json:"provider"`                     // "whisper-api", "azure", "google", "assemblyai"
+	Provider                     string   `json:"provider"`                     // "whisper-api", "whisper-local", "azure", "google", "assemblyai", "aws"
+	FallbackProviders            []string `json:"fallbackProviders"`            // Providers tried in order when the previous one fails or returns no transcript
 	Language                     string   `json:"language"`                     // "en", "auto"
 	Prompt                       string   `json:"prompt"`                       // Custom prompt for Whisper to guide transcription (e.g., terminology, formatting)
 	WorkerPoolSize               int      `json:"workerPoolSize"`
+	MaxInFlightMB                int      `json:"maxInFlightMB"`                // Memory budget in MB of the audio being transcribed at once (default: 256)
+	QueueSize                    int      `json:"queueSize"`                    // Calls waiting for a worker, more are dropped until the queue drains (default: 100)
+	AvailabilityCacheSeconds     int      `json:"availabilityCacheSeconds"`     // Seconds the availability of a provider is trusted before it is checked again (default: 30)
+	SanitizeMode                 string   `json:"sanitizeMode"`                 // "strip" (default) or "replace" invalid UTF-8 and control characters in transcripts
 	MinCallDuration              float64  `json:"minCallDuration"`              // Minimum call duration in seconds to transcribe (default: 0 = transcribe all)
+	MaxCallAgeDays               int      `json:"maxCallAgeDays"`               // Calls older than this many days are skipped instead of transcribed (default: 0 = no limit)
+	ApplyTalkgroupGain           bool     `json:"applyTalkgroupGain"`           // Level the audio by the gain of its talkgroup before transcription (default: false)
+	DisplayConfidence            float64  `json:"displayConfidence"`            // Transcripts under this confidence are shown as uncertain, unless their talkgroup or system sets one (default: 0.6)
+	ChunkSeconds                 map[string]float64 `json:"chunkSeconds,omitempty"` // Longest audio sent at once per provider, longer calls are split at silences (0 = whole call, default: 55 for google and azure)
+	CostPerMinute                map[string]float64 `json:"costPerMinute,omitempty"` // Cost of a minute of audio per provider, accrued per system and month in the transcription usage (default: none)
 	WhisperAPIURL                string   `json:"whisperAPIURL"`                // Base URL for external Whisper API server (e.g., "http://localhost:8000") or OpenAI API URL
 	WhisperAPIKey                string   `json:"whisperAPIKey"`                // Optional API key for external Whisper API server or OpenAI API key
+	WhisperLocalURL              string   `json:"whisperLocalURL"`              // Base URL of a Whisper server on the local network (e.g., faster-whisper)
+	WhisperLocalKey              string   `json:"whisperLocalKey"`              // Optional API key of the local Whisper server
 	AzureKey                     string   `json:"azureKey"`                     // Azure Speech Services subscription key
 	AzureRegion                  string   `json:"azureRegion"`                  // Azure Speech Services region (e.g., "eastus", "westus2")
 	GoogleAPIKey                 string   `json:"googleAPIKey"`                 // Google Cloud Speech-to-Text API key
 	GoogleCredentials            string   `json:"googleCredentials"`            // Google Cloud service account JSON credentials (alternative to API key)
 	AssemblyAIKey                string   `json:"assemblyAIKey"`                // AssemblyAI API key
+	AWSRegion                    string   `json:"awsRegion"`                    // Amazon Transcribe region (e.g., "us-east-1")
+	AWSBucket                    string   `json:"awsBucket"`                    // S3 bucket in the same region the audio is uploaded to for Amazon Transcribe
+	AWSAccessKeyId               string   `json:"awsAccessKeyId"`               // AWS access key (default: AWS_ACCESS_KEY_ID environment variable)
+	AWSSecretAccessKey           string   `json:"awsSecretAccessKey"`           // AWS secret key (default: AWS_SECRET_ACCESS_KEY environment variable)
 	HallucinationPatterns        []string `json:"hallucinationPatterns"`        // Patterns to remove from transcripts (Whisper hallucinations)
 	HallucinationDetectionMode   string   `json:"hallucinationDetectionMode"`   // "off", "manual", "auto"
 	HallucinationMinOccurrences  int      `json:"hallucinationMinOccurrences"`  // Minimum times a phrase must appear in rejected calls before flagging (default: 5)
+	HallucinationSimilarityThreshold float64 `json:"hallucinationSimilarityThreshold"` // Similarity ratio (0-1) above which a phrase counts as a tracked one (default: 0.8, 1 = exact phrases only)
+	HallucinationRetentionDays   int      `json:"hallucinationRetentionDays"`   // Pending suspected phrases not seen for this many days are deleted (default: 90, 0 = never)
+	HallucinationDecayDays       int      `json:"hallucinationDecayDays"`       // Suspected phrases not seen for this many days have their rejected count halved, once per period (default: 30, 0 = never)
+	EmergencyVocabulary          []string `json:"emergencyVocabulary"`          // Terms protecting phrases from hallucination detection, in addition to the built-in ones
+	RejectHallucinations         bool     `json:"rejectHallucinations"`         // Mark calls whose transcript only holds hallucination patterns, without storing it or raising alerts
+	WebhookURL                   string   `json:"webhookURL"`                   // URL notified when the transcription of a call completes or fails (empty = disabled)
+	WebhookSecret                string   `json:"webhookSecret"`                // HMAC-SHA256 key signing the webhook body (empty = unsigned)
+	WebhookSystems               any      `json:"webhookSystems"`               // Systems and talkgroups notified, same format as downstreams (empty = all)
 }
 
 const (
@@ -150,6 +214,9 @@ func (options *Options) FromMap(m map[string]any) *Options {
 	options.mutex.Lock()
 	defer options.mutex.Unlock()
 
+	// out of range values are clamped as Read does, the admin config save refusing them beforehand
+	m = validOptionsMap(m)
+
 	switch v := m["audioConversion"].(type) {
 	case float64:
 		options.AudioConversion = uint(v)
@@ -157,6 +224,21 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		options.AudioConversion = defaults.options.audioConversion
 	}
 
+	switch v := m["audioNormalizeChannels"].(type) {
+	case float64:
+		options.AudioNormalizeChannels = uint(v)
+	}
+
+	switch v := m["audioNormalizeKeepOriginal"].(type) {
+	case bool:
+		options.AudioNormalizeKeepOriginal = v
+	}
+
+	switch v := m["audioNormalizeSampleRate"].(type) {
+	case float64:
+		options.AudioNormalizeSampleRate = uint(v)
+	}
+
 	switch v := m["autoPopulate"].(type) {
 	case bool:
 		options.AutoPopulate = v
@@ -180,6 +262,16 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		options.Branding = v
 	}
 
+	switch v := m["brandingColor"].(type) {
+	case string:
+		options.BrandingColor = v
+	}
+
+	switch v := m["brandingLogoUrl"].(type) {
+	case string:
+		options.BrandingLogoUrl = v
+	}
+
 	switch v := m["dimmerDelay"].(type) {
 	case float64:
 		options.DimmerDelay = uint(v)
@@ -206,6 +298,31 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		options.Email = v
 	}
 
+	switch v := m["incidentGrouping"].(type) {
+	case bool:
+		options.IncidentGrouping = v
+	}
+
+	switch v := m["incidentGroupingKeywords"].(type) {
+	case string:
+		options.IncidentGroupingKeywords = v
+	}
+
+	switch v := m["incidentGroupingPatches"].(type) {
+	case bool:
+		options.IncidentGroupingPatches = v
+	}
+
+	switch v := m["incidentGroupingUnits"].(type) {
+	case bool:
+		options.IncidentGroupingUnits = v
+	}
+
+	switch v := m["incidentGroupingWindow"].(type) {
+	case float64:
+		options.IncidentGroupingWindow = uint(v)
+	}
+
 	switch v := m["keypadBeeps"].(type) {
 	case string:
 		options.KeypadBeeps = v
@@ -514,6 +631,161 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		options.ToneDetectionIssueThreshold = defaults.options.toneDetectionIssueThreshold
 	}
 
+	switch v := m["callRateAnomalySensitivity"].(type) {
+	case float64:
+		options.CallRateAnomalySensitivity = uint(v)
+	case int:
+		options.CallRateAnomalySensitivity = uint(v)
+	case int64:
+		options.CallRateAnomalySensitivity = uint(v)
+	}
+
+	switch v := m["callRateAnomalyCooldown"].(type) {
+	case float64:
+		options.CallRateAnomalyCooldown = uint(v)
+	case int:
+		options.CallRateAnomalyCooldown = uint(v)
+	case int64:
+		options.CallRateAnomalyCooldown = uint(v)
+	}
+
+	switch v := m["keywordAlertCooldown"].(type) {
+	case float64:
+		options.KeywordAlertCooldown = uint(v)
+	case int:
+		options.KeywordAlertCooldown = uint(v)
+	case int64:
+		options.KeywordAlertCooldown = uint(v)
+	}
+
+	switch v := m["orphanSweepInterval"].(type) {
+	case float64:
+		options.OrphanSweepInterval = uint(v)
+	case int:
+		options.OrphanSweepInterval = uint(v)
+	case int64:
+		options.OrphanSweepInterval = uint(v)
+	}
+
+	switch v := m["registrationRetentionDays"].(type) {
+	case float64:
+		options.RegistrationRetentionDays = uint(v)
+	case int:
+		options.RegistrationRetentionDays = uint(v)
+	case int64:
+		options.RegistrationRetentionDays = uint(v)
+	}
+
+	switch v := m["deviceTokenRetentionDays"].(type) {
+	case float64:
+		options.DeviceTokenRetentionDays = uint(v)
+	case int:
+		options.DeviceTokenRetentionDays = uint(v)
+	case int64:
+		options.DeviceTokenRetentionDays = uint(v)
+	}
+
+	switch v := m["auditLogEnabled"].(type) {
+	case bool:
+		options.AuditLogEnabled = v
+	}
+
+	switch v := m["auditLogRetentionDays"].(type) {
+	case float64:
+		options.AuditLogRetentionDays = uint(v)
+	case int:
+		options.AuditLogRetentionDays = uint(v)
+	case int64:
+		options.AuditLogRetentionDays = uint(v)
+	}
+
+	switch v := m["zipCodeFormat"].(type) {
+	case string:
+		options.ZipCodeFormat = v
+	}
+
+	switch v := m["missingAudioResponse"].(type) {
+	case string:
+		options.MissingAudioResponse = v
+	}
+
+	switch v := m["audioSniffing"].(type) {
+	case bool:
+		options.AudioSniffing = v
+	}
+
+	switch v := m["delayedMaxEntries"].(type) {
+	case float64:
+		options.DelayedMaxEntries = uint(v)
+	case int:
+		options.DelayedMaxEntries = uint(v)
+	case int64:
+		options.DelayedMaxEntries = uint(v)
+	}
+
+	switch v := m["delayedOverflowPolicy"].(type) {
+	case string:
+		options.DelayedOverflowPolicy = v
+	}
+
+	switch v := m["systemAlertWebhookUrl"].(type) {
+	case string:
+		options.SystemAlertWebhookUrl = v
+	}
+
+	switch v := m["systemAlertWebhookSecret"].(type) {
+	case string:
+		options.SystemAlertWebhookSecret = v
+	}
+
+	switch v := m["systemAlertWebhookSeverity"].(type) {
+	case string:
+		options.SystemAlertWebhookSeverity = v
+	}
+
+	switch v := m["downstreamHttp2"].(type) {
+	case bool:
+		options.DownstreamHttp2 = v
+	default:
+		options.DownstreamHttp2 = defaults.options.downstreamHttp2
+	}
+
+	switch v := m["downstreamMaxIdleConns"].(type) {
+	case float64:
+		options.DownstreamMaxIdleConns = uint(v)
+	case int:
+		options.DownstreamMaxIdleConns = uint(v)
+	case int64:
+		options.DownstreamMaxIdleConns = uint(v)
+	}
+
+	switch v := m["downstreamIdleConnTimeout"].(type) {
+	case float64:
+		options.DownstreamIdleConnTimeout = uint(v)
+	case int:
+		options.DownstreamIdleConnTimeout = uint(v)
+	case int64:
+		options.DownstreamIdleConnTimeout = uint(v)
+	}
+
+	switch v := m["connectHistoryMinutes"].(type) {
+	case float64:
+		options.ConnectHistoryMinutes = uint(v)
+	case int:
+		options.ConnectHistoryMinutes = uint(v)
+	case int64:
+		options.ConnectHistoryMinutes = uint(v)
+	}
+
+	switch v := m["connectHistoryMaxCalls"].(type) {
+	case float64:
+		options.ConnectHistoryMaxCalls = uint(v)
+	case int:
+		options.ConnectHistoryMaxCalls = uint(v)
+	case int64:
+		options.ConnectHistoryMaxCalls = uint(v)
+	}
+
 	switch v := m["relayServerURL"].(type) {
 	case string:
 		options.RelayServerURL = v
@@ -528,6 +800,48 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		options.RelayServerAPIKey = ""
 	}
 
+	// the VAPID keys are kept when absent from the map, a new key pair invalidating every subscription
+	switch v := m["webPushVapidPublicKey"].(type) {
+	case string:
+		options.WebPushVapidPublicKey = v
+	}
+
+	switch v := m["webPushVapidPrivateKey"].(type) {
+	case string:
+		options.WebPushVapidPrivateKey = v
+	}
+
+	switch v := m["webPushVapidSubject"].(type) {
+	case string:
+		options.WebPushVapidSubject = v
+	}
+
+	// the APNs credentials are kept when absent from the map, the key being only readable once
+	switch v := m["apnsKeyId"].(type) {
+	case string:
+		options.APNsKeyId = v
+	}
+
+	switch v := m["apnsTeamId"].(type) {
+	case string:
+		options.APNsTeamId = v
+	}
+
+	switch v := m["apnsBundleId"].(type) {
+	case string:
+		options.APNsBundleId = v
+	}
+
+	switch v := m["apnsKey"].(type) {
+	case string:
+		options.APNsKey = v
+	}
+
+	switch v := m["apnsSandbox"].(type) {
+	case bool:
+		options.APNsSandbox = v
+	}
+
 	switch v := m["radioReferenceAPIKey"].(type) {
 	case string:
 		options.RadioReferenceAPIKey = v
@@ -588,6 +902,14 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		if v, ok := tc["provider"].(string); ok && v != "" {
 			options.TranscriptionConfig.Provider = v
 		}
+		if v, ok := tc["fallbackProviders"].([]any); ok {
+			options.TranscriptionConfig.FallbackProviders = []string{}
+			for _, name := range v {
+				if name, ok := name.(string); ok && strings.TrimSpace(name) != "" {
+					options.TranscriptionConfig.FallbackProviders = append(options.TranscriptionConfig.FallbackProviders, strings.TrimSpace(name))
+				}
+			}
+		}
 		if v, ok := tc["language"].(string); ok && v != "" {
 			options.TranscriptionConfig.Language = v
 		}
@@ -597,15 +919,36 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		if v, ok := tc["workerPoolSize"].(float64); ok && v > 0 {
 			options.TranscriptionConfig.WorkerPoolSize = int(v)
 		}
+		if v, ok := tc["maxInFlightMB"].(float64); ok && v >= 0 {
+			options.TranscriptionConfig.MaxInFlightMB = int(v)
+		}
+		if v, ok := tc["queueSize"].(float64); ok && v >= 0 {
+			options.TranscriptionConfig.QueueSize = int(v)
+		}
+		if v, ok := tc["availabilityCacheSeconds"].(float64); ok && v >= 0 {
+			options.TranscriptionConfig.AvailabilityCacheSeconds = int(v)
+		}
+		if v, ok := tc["sanitizeMode"].(string); ok {
+			options.TranscriptionConfig.SanitizeMode = v
+		}
 		if v, ok := tc["minCallDuration"].(float64); ok {
 			options.TranscriptionConfig.MinCallDuration = v
 		}
+		if v, ok := tc["maxCallAgeDays"].(float64); ok {
+			options.TranscriptionConfig.MaxCallAgeDays = int(v)
+		}
 		if v, ok := tc["whisperAPIURL"].(string); ok {
 			options.TranscriptionConfig.WhisperAPIURL = v
 		}
 		if v, ok := tc["whisperAPIKey"].(string); ok {
 			options.TranscriptionConfig.WhisperAPIKey = v
 		}
+		if v, ok := tc["whisperLocalURL"].(string); ok {
+			options.TranscriptionConfig.WhisperLocalURL = v
+		}
+		if v, ok := tc["whisperLocalKey"].(string); ok {
+			options.TranscriptionConfig.WhisperLocalKey = v
+		}
 		if v, ok := tc["azureKey"].(string); ok {
 			options.TranscriptionConfig.AzureKey = v
 		}
@@ -621,6 +964,18 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		if v, ok := tc["assemblyAIKey"].(string); ok {
 			options.TranscriptionConfig.AssemblyAIKey = v
 		}
+		if v, ok := tc["awsRegion"].(string); ok {
+			options.TranscriptionConfig.AWSRegion = v
+		}
+		if v, ok := tc["awsBucket"].(string); ok {
+			options.TranscriptionConfig.AWSBucket = v
+		}
+		if v, ok := tc["awsAccessKeyId"].(string); ok {
+			options.TranscriptionConfig.AWSAccessKeyId = v
+		}
+		if v, ok := tc["awsSecretAccessKey"].(string); ok {
+			options.TranscriptionConfig.AWSSecretAccessKey = v
+		}
 		if v, ok := tc["hallucinationPatterns"].([]interface{}); ok {
 			patterns := make([]string, 0, len(v))
 			for _, p := range v {
@@ -636,6 +991,59 @@ func (options *Options) FromMap(m map[string]any) *Options {
 		if v, ok := tc["hallucinationMinOccurrences"].(float64); ok {
 			options.TranscriptionConfig.HallucinationMinOccurrences = int(v)
 		}
+		if v, ok := tc["hallucinationSimilarityThreshold"].(float64); ok && v >= 0 && v <= 1 {
+			options.TranscriptionConfig.HallucinationSimilarityThreshold = v
+		}
+		if v, ok := tc["hallucinationRetentionDays"].(float64); ok && v >= 0 {
+			options.TranscriptionConfig.HallucinationRetentionDays = int(v)
+		}
+		if v, ok := tc["hallucinationDecayDays"].(float64); ok && v >= 0 {
+			options.TranscriptionConfig.HallucinationDecayDays = int(v)
+		}
+		if v, ok := tc["emergencyVocabulary"].([]any); ok {
+			options.TranscriptionConfig.EmergencyVocabulary = []string{}
+			for _, word := range v {
+				if word, ok := word.(string); ok && strings.TrimSpace(word) != "" {
+					options.TranscriptionConfig.EmergencyVocabulary = append(options.TranscriptionConfig.EmergencyVocabulary, strings.TrimSpace(word))
+				}
+			}
+		}
+		if v, ok := tc["webhookURL"].(string); ok {
+			options.TranscriptionConfig.WebhookURL = v
+		}
+		if v, ok := tc["webhookSecret"].(string); ok {
+			options.TranscriptionConfig.WebhookSecret = v
+		}
+		if v, ok := tc["webhookSystems"]; ok {
+			options.TranscriptionConfig.WebhookSystems = v
+		}
+		if v, ok := tc["rejectHallucinations"].(bool); ok {
+			options.TranscriptionConfig.RejectHallucinations = v
+		}
+		if v, ok := tc["applyTalkgroupGain"].(bool); ok {
+			options.TranscriptionConfig.ApplyTalkgroupGain = v
+		}
+		if v, ok := tc["displayConfidence"].(float64); ok && validDisplayConfidence(v) {
+			options.TranscriptionConfig.DisplayConfidence = v
+		}
+		if v, ok := tc["chunkSeconds"].(map[string]any); ok {
+			chunkSeconds := map[string]float64{}
+			for provider, seconds := range v {
+				if seconds, ok := seconds.(float64); ok && seconds >= 0 {
+					chunkSeconds[provider] = seconds
+				}
+			}
+			options.TranscriptionConfig.ChunkSeconds = chunkSeconds
+		}
+		if v, ok := tc["costPerMinute"].(map[string]any); ok {
+			costPerMinute := map[string]float64{}
+			for provider, cost := range v {
+				if cost, ok := cost.(float64); ok && cost >= 0 {
+					costPerMinute[provider] = cost
+				}
+			}
+			options.TranscriptionConfig.CostPerMinute = costPerMinute
+		}
 	}
 
 	return options
@@ -661,13 +1069,23 @@ func (options *Options) Read(db *Database) error {
 	options.adminPassword = string(defaultPassword)
 	options.adminPasswordNeedChange = defaults.adminPasswordNeedChange
 	options.AudioConversion = defaults.options.audioConversion
+	options.AudioNormalizeChannels = defaults.options.audioNormalizeChannels
+	options.AudioNormalizeKeepOriginal = defaults.options.audioNormalizeKeepOriginal
+	options.AudioNormalizeSampleRate = defaults.options.audioNormalizeSampleRate
 	options.AutoPopulate = defaults.options.autoPopulate
 	options.Branding = defaults.options.branding
+	options.BrandingColor = defaults.options.brandingColor
+	options.BrandingLogoUrl = defaults.options.brandingLogoUrl
 	options.DefaultSystemDelay = defaults.options.defaultSystemDelay
 	options.DimmerDelay = defaults.options.dimmerDelay
 	options.DisableDuplicateDetection = defaults.options.disableDuplicateDetection
 	options.DuplicateDetectionTimeFrame = defaults.options.duplicateDetectionTimeFrame
 	options.Email = defaults.options.email
+	options.IncidentGrouping = defaults.options.incidentGrouping
+	options.IncidentGroupingKeywords = defaults.options.incidentGroupingKeywords
+	options.IncidentGroupingPatches = defaults.options.incidentGroupingPatches
+	options.IncidentGroupingUnits = defaults.options.incidentGroupingUnits
+	options.IncidentGroupingWindow = defaults.options.incidentGroupingWindow
 	options.KeypadBeeps = defaults.options.keypadBeeps
 	options.MaxClients = defaults.options.maxClients
 	options.PlaybackGoesLive = defaults.options.playbackGoesLive
@@ -678,9 +1096,32 @@ func (options *Options) Read(db *Database) error {
 	options.AlertRetentionDays = defaults.options.alertRetentionDays
 	options.TranscriptionFailureThreshold = defaults.options.transcriptionFailureThreshold
 	options.ToneDetectionIssueThreshold = defaults.options.toneDetectionIssueThreshold
+	options.CallRateAnomalySensitivity = defaults.options.callRateAnomalySensitivity
+	options.CallRateAnomalyCooldown = defaults.options.callRateAnomalyCooldown
+	options.KeywordAlertCooldown = defaults.options.keywordAlertCooldown
+	options.OrphanSweepInterval = defaults.options.orphanSweepInterval
+	options.RegistrationRetentionDays = defaults.options.registrationRetentionDays
+	options.DeviceTokenRetentionDays = defaults.options.deviceTokenRetentionDays
+	options.AuditLogEnabled = defaults.options.auditLogEnabled
+	options.AuditLogRetentionDays = defaults.options.auditLogRetentionDays
+	options.ZipCodeFormat = defaults.options.zipCodeFormat
+	options.MissingAudioResponse = defaults.options.missingAudioResponse
+	options.AudioSniffing = defaults.options.audioSniffing
+	options.DelayedMaxEntries = defaults.options.delayedMaxEntries
+	options.DelayedOverflowPolicy = defaults.options.delayedOverflowPolicy
+	options.SystemAlertWebhookUrl = defaults.options.systemAlertWebhookUrl
+	options.SystemAlertWebhookSecret = defaults.options.systemAlertWebhookSecret
+	options.SystemAlertWebhookSeverity = defaults.options.systemAlertWebhookSeverity
+	options.DownstreamHttp2 = defaults.options.downstreamHttp2
+	options.DownstreamMaxIdleConns = defaults.options.downstreamMaxIdleConns
+	options.DownstreamIdleConnTimeout = defaults.options.downstreamIdleConnTimeout
+	options.ConnectHistoryMinutes = defaults.options.connectHistoryMinutes
+	options.ConnectHistoryMaxCalls = defaults.options.connectHistoryMaxCalls
 	options.AdminLocalhostOnly = defaults.options.adminLocalhostOnly
 	options.ConfigSyncEnabled = defaults.options.configSyncEnabled
 	options.ConfigSyncPath = defaults.options.configSyncPath
+	options.TranscriptionConfig.HallucinationRetentionDays = defaults.options.transcriptionConfig.hallucinationRetentionDays
+	options.TranscriptionConfig.HallucinationDecayDays = defaults.options.transcriptionConfig.hallucinationDecayDays
 	
 	// Initialize Radio Reference credentials with defaults, but they will be overridden by database values
 	options.RadioReferenceEnabled = defaults.options.radioReferenceEnabled
@@ -703,6 +1144,16 @@ func (options *Options) Read(db *Database) error {
 			continue
 		}
 
+		// Clamp or ignore the values a bad row would break the server with
+		validated, correction, ok := validateOption(key.String, value.String)
+		if correction != "" {
+			log.Printf("options: %s", correction)
+		}
+		if !ok {
+			continue
+		}
+		value.String = validated
+
 		switch key.String {
 		case "adminPassword":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
@@ -725,6 +1176,27 @@ func (options *Options) Read(db *Database) error {
 					options.AudioConversion = uint(v)
 				}
 			}
+		case "audioNormalizeChannels":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.AudioNormalizeChannels = uint(v)
+				}
+			}
+		case "audioNormalizeKeepOriginal":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.AudioNormalizeKeepOriginal = v
+				}
+			}
+		case "audioNormalizeSampleRate":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.AudioNormalizeSampleRate = uint(v)
+				}
+			}
 		case "autoPopulate":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
 				switch v := f.(type) {
@@ -739,6 +1211,20 @@ func (options *Options) Read(db *Database) error {
 					options.Branding = v
 				}
 			}
+		case "brandingColor":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.BrandingColor = v
+				}
+			}
+		case "brandingLogoUrl":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.BrandingLogoUrl = v
+				}
+			}
 		case "defaultSystemDelay":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
 				switch v := f.(type) {
@@ -774,6 +1260,41 @@ func (options *Options) Read(db *Database) error {
 					options.Email = v
 				}
 			}
+		case "incidentGrouping":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.IncidentGrouping = v
+				}
+			}
+		case "incidentGroupingKeywords":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.IncidentGroupingKeywords = v
+				}
+			}
+		case "incidentGroupingPatches":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.IncidentGroupingPatches = v
+				}
+			}
+		case "incidentGroupingUnits":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.IncidentGroupingUnits = v
+				}
+			}
+		case "incidentGroupingWindow":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.IncidentGroupingWindow = uint(v)
+				}
+			}
 		case "keypadBeeps":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
 				switch v := f.(type) {
@@ -1043,7 +1564,11 @@ func (options *Options) Read(db *Database) error {
 				}
 			}
 		case "transcriptionConfig":
-			var cfg TranscriptionConfig
+			// settings missing from the stored config keep their defaults
+			cfg := TranscriptionConfig{
+				HallucinationRetentionDays: defaults.options.transcriptionConfig.hallucinationRetentionDays,
+				HallucinationDecayDays:     defaults.options.transcriptionConfig.hallucinationDecayDays,
+			}
 			if err := json.Unmarshal([]byte(value.String), &cfg); err == nil {
 				options.TranscriptionConfig = cfg
 			}
@@ -1068,6 +1593,153 @@ func (options *Options) Read(db *Database) error {
 					options.ToneDetectionIssueThreshold = uint(v)
 				}
 			}
+		case "callRateAnomalySensitivity":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.CallRateAnomalySensitivity = uint(v)
+				}
+			}
+		case "callRateAnomalyCooldown":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.CallRateAnomalyCooldown = uint(v)
+				}
+			}
+		case "keywordAlertCooldown":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.KeywordAlertCooldown = uint(v)
+				}
+			}
+		case "orphanSweepInterval":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.OrphanSweepInterval = uint(v)
+				}
+			}
+		case "registrationRetentionDays":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.RegistrationRetentionDays = uint(v)
+				}
+			}
+		case "deviceTokenRetentionDays":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.DeviceTokenRetentionDays = uint(v)
+				}
+			}
+		case "auditLogEnabled":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.AuditLogEnabled = v
+				}
+			}
+		case "auditLogRetentionDays":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.AuditLogRetentionDays = uint(v)
+				}
+			}
+		case "zipCodeFormat":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.ZipCodeFormat = v
+				}
+			}
+		case "missingAudioResponse":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.MissingAudioResponse = v
+				}
+			}
+		case "audioSniffing":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.AudioSniffing = v
+				}
+			}
+		case "delayedMaxEntries":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.DelayedMaxEntries = uint(v)
+				}
+			}
+		case "delayedOverflowPolicy":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.DelayedOverflowPolicy = v
+				}
+			}
+		case "systemAlertWebhookUrl":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.SystemAlertWebhookUrl = v
+				}
+			}
+		case "systemAlertWebhookSecret":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.SystemAlertWebhookSecret = v
+				}
+			}
+		case "systemAlertWebhookSeverity":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.SystemAlertWebhookSeverity = v
+				}
+			}
+		case "downstreamHttp2":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.DownstreamHttp2 = v
+				}
+			}
+		case "downstreamMaxIdleConns":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.DownstreamMaxIdleConns = uint(v)
+				}
+			}
+		case "downstreamIdleConnTimeout":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.DownstreamIdleConnTimeout = uint(v)
+				}
+			}
+		case "connectHistoryMinutes":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.ConnectHistoryMinutes = uint(v)
+				}
+			}
+		case "connectHistoryMaxCalls":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case float64:
+					options.ConnectHistoryMaxCalls = uint(v)
+				}
+			}
 		case "relayServerURL":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
 				switch v := f.(type) {
@@ -1082,6 +1754,62 @@ func (options *Options) Read(db *Database) error {
 					options.RelayServerAPIKey = v
 				}
 			}
+		case "webPushVapidPublicKey":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.WebPushVapidPublicKey = v
+				}
+			}
+		case "webPushVapidPrivateKey":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.WebPushVapidPrivateKey = v
+				}
+			}
+		case "webPushVapidSubject":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.WebPushVapidSubject = v
+				}
+			}
+		case "apnsKeyId":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.APNsKeyId = v
+				}
+			}
+		case "apnsTeamId":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.APNsTeamId = v
+				}
+			}
+		case "apnsBundleId":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.APNsBundleId = v
+				}
+			}
+		case "apnsKey":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case string:
+					options.APNsKey = v
+				}
+			}
+		case "apnsSandbox":
+			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
+				switch v := f.(type) {
+				case bool:
+					options.APNsSandbox = v
+				}
+			}
 		case "radioReferenceAPIKey":
 			if err = json.Unmarshal([]byte(value.String), &f); err == nil {
 				switch v := f.(type) {
@@ -1177,13 +1905,23 @@ func (options *Options) Write(db *Database) error {
 	set("adminPassword", options.adminPassword)
 	set("adminPasswordNeedChange", options.adminPasswordNeedChange)
 	set("audioConversion", options.AudioConversion)
+	set("audioNormalizeChannels", options.AudioNormalizeChannels)
+	set("audioNormalizeKeepOriginal", options.AudioNormalizeKeepOriginal)
+	set("audioNormalizeSampleRate", options.AudioNormalizeSampleRate)
 	set("autoPopulate", options.AutoPopulate)
 	set("branding", options.Branding)
+	set("brandingColor", options.BrandingColor)
+	set("brandingLogoUrl", options.BrandingLogoUrl)
 	set("defaultSystemDelay", options.DefaultSystemDelay)
 	set("dimmerDelay", options.DimmerDelay)
 	set("disableDuplicateDetection", options.DisableDuplicateDetection)
 	set("duplicateDetectionTimeFrame", options.DuplicateDetectionTimeFrame)
 	set("email", options.Email)
+	set("incidentGrouping", options.IncidentGrouping)
+	set("incidentGroupingKeywords", options.IncidentGroupingKeywords)
+	set("incidentGroupingPatches", options.IncidentGroupingPatches)
+	set("incidentGroupingUnits", options.IncidentGroupingUnits)
+	set("incidentGroupingWindow", options.IncidentGroupingWindow)
 	set("keypadBeeps", options.KeypadBeeps)
 	set("maxClients", options.MaxClients)
 	set("playbackGoesLive", options.PlaybackGoesLive)
@@ -1227,8 +1965,37 @@ func (options *Options) Write(db *Database) error {
 	set("alertRetentionDays", options.AlertRetentionDays)
 	set("transcriptionFailureThreshold", options.TranscriptionFailureThreshold)
 	set("toneDetectionIssueThreshold", options.ToneDetectionIssueThreshold)
+	set("callRateAnomalySensitivity", options.CallRateAnomalySensitivity)
+	set("callRateAnomalyCooldown", options.CallRateAnomalyCooldown)
+	set("keywordAlertCooldown", options.KeywordAlertCooldown)
+	set("orphanSweepInterval", options.OrphanSweepInterval)
+	set("registrationRetentionDays", options.RegistrationRetentionDays)
+	set("deviceTokenRetentionDays", options.DeviceTokenRetentionDays)
+	set("auditLogEnabled", options.AuditLogEnabled)
+	set("auditLogRetentionDays", options.AuditLogRetentionDays)
+	set("zipCodeFormat", options.ZipCodeFormat)
+	set("missingAudioResponse", options.MissingAudioResponse)
+	set("audioSniffing", options.AudioSniffing)
+	set("delayedMaxEntries", options.DelayedMaxEntries)
+	set("delayedOverflowPolicy", options.DelayedOverflowPolicy)
+	set("systemAlertWebhookUrl", options.SystemAlertWebhookUrl)
+	set("systemAlertWebhookSecret", options.SystemAlertWebhookSecret)
+	set("systemAlertWebhookSeverity", options.SystemAlertWebhookSeverity)
+	set("downstreamHttp2", options.DownstreamHttp2)
+	set("downstreamMaxIdleConns", options.DownstreamMaxIdleConns)
+	set("downstreamIdleConnTimeout", options.DownstreamIdleConnTimeout)
+	set("connectHistoryMinutes", options.ConnectHistoryMinutes)
+	set("connectHistoryMaxCalls", options.ConnectHistoryMaxCalls)
 	set("relayServerURL", options.RelayServerURL)
 	set("relayServerAPIKey", options.RelayServerAPIKey)
+	set("webPushVapidPublicKey", options.WebPushVapidPublicKey)
+	set("webPushVapidPrivateKey", options.WebPushVapidPrivateKey)
+	set("webPushVapidSubject", options.WebPushVapidSubject)
+	set("apnsKeyId", options.APNsKeyId)
+	set("apnsTeamId", options.APNsTeamId)
+	set("apnsBundleId", options.APNsBundleId)
+	set("apnsKey", options.APNsKey)
+	set("apnsSandbox", options.APNsSandbox)
 	set("radioReferenceAPIKey", options.RadioReferenceAPIKey)
 	set("adminLocalhostOnly", options.AdminLocalhostOnly)
 	set("configSyncEnabled", options.ConfigSyncEnabled)
@@ -0,0 +1,212 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// optionRange bounds the value of a numeric option
+type optionRange struct {
+	min float64
+	max float64
+}
+
+// optionRanges bounds the numeric options whose extremes would break the server, the other numeric
+// options only have to fit in 32 bits and unsigned ones can't be negative
+var optionRanges = map[string]optionRange{
+	"audioConversion":               {AUDIO_CONVERSION_DISABLED, AUDIO_CONVERSION_ENABLED_LOUD_NORM},
+	"audioNormalizeChannels":        {0, 2},
+	"audioNormalizeSampleRate":      {0, 192000},
+	"auditLogRetentionDays":         {0, 36500},
+	"callRateAnomalySensitivity":    {0, 100},
+	"connectHistoryMaxCalls":        {0, 10000},
+	"connectHistoryMinutes":         {0, 1440}, // a day
+	"defaultSystemDelay":            {0, 1440}, // minutes
+	"delayedMaxEntries":             {0, 1000000},
+	"dimmerDelay":                   {0, 3600000}, // an hour in milliseconds
+	"downstreamIdleConnTimeout":     {0, 3600},    // seconds
+	"downstreamMaxIdleConns":        {0, 1000},
+	"duplicateDetectionTimeFrame":   {0, 600000}, // ten minutes in milliseconds
+	"emailSmtpPort":                 {1, 65535},
+	"incidentGroupingWindow":        {0, 86400}, // a day in seconds
+	"maxClients":                    {1, 100000},
+	"orphanSweepInterval":           {0, 8760}, // a year in hours
+	"pruneDays":                     {0, 36500},
+	"transcriptionFailureThreshold": {1, 10000},
+	"toneDetectionIssueThreshold":   {1, 10000},
+}
+
+// optionChoices lists the values accepted by the options read as keywords
+var optionChoices = map[string][]string{
+	"delayedOverflowPolicy":      {DelayedOverflowDropOldest, DelayedOverflowRelease},
+	"emailProvider":              {"", "sendgrid", "mailgun", "smtp"},
+	"missingAudioResponse":       {MissingAudioGone, MissingAudioNotFound},
+	"systemAlertWebhookSeverity": {"info", "warning", "error", "critical"},
+	"zipCodeFormat":              {ZipCodeFormatAny, ZipCodeFormatUS, ZipCodeFormatCA},
+}
+
+// optionKinds maps the keys of the options rows to the kind of their field
+var optionKinds = func() map[string]reflect.Kind {
+	kinds := map[string]reflect.Kind{}
+
+	t := reflect.TypeOf(Options{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+
+		switch kind := field.Type.Kind(); kind {
+		case reflect.Bool, reflect.String, reflect.Int, reflect.Uint:
+			kinds[key] = kind
+		}
+	}
+
+	return kinds
+}()
+
+// validateOption checks the json value of an options row against the type and range of its option.
+// It returns the value to read, clamped when out of range, and the correction made to log. The row is
+// ignored when ok is false, leaving the option to its default.
+func validateOption(key string, raw string) (value string, correction string, ok bool) {
+	if _, found := optionKinds[key]; !found {
+		return raw, "", true
+	}
+
+	var f any
+	if err := json.Unmarshal([]byte(raw), &f); err != nil {
+		return "", fmt.Sprintf("option %s has a malformed value %q, using the default", key, raw), false
+	}
+
+	v, outOfRange, err := optionValue(key, f)
+	if err != nil {
+		return "", fmt.Sprintf("%v, using the default", err), false
+	}
+
+	if n, isNumber := v.(float64); isNumber {
+		if outOfRange != "" {
+			correction = fmt.Sprintf("%s, clamped to %v", outOfRange, n)
+		} else if _, isString := f.(string); isString {
+			correction = fmt.Sprintf("option %s was stored as the string %s, read as %v", key, raw, n)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), correction, true
+	}
+
+	return raw, "", true
+}
+
+// optionValue checks the decoded json value of an option against the type and range of its option. It
+// returns the value, numbers read from strings and clamped to their range, why it was clamped, or an
+// error when the value can't be read.
+func optionValue(key string, f any) (value any, outOfRange string, err error) {
+	kind, found := optionKinds[key]
+	if !found {
+		return f, "", nil
+	}
+
+	switch kind {
+	case reflect.Bool:
+		if _, ok := f.(bool); !ok {
+			return nil, "", fmt.Errorf("option %s expects a boolean, not %v", key, f)
+		}
+
+	case reflect.String:
+		s, ok := f.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("option %s expects a string, not %v", key, f)
+		}
+		if choices, found := optionChoices[key]; found && !slices.Contains(choices, s) {
+			return nil, "", fmt.Errorf("option %s has an unknown value %q", key, s)
+		}
+
+	case reflect.Int, reflect.Uint:
+		var n float64
+
+		switch v := f.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+				return nil, "", fmt.Errorf("option %s expects a number, not %q", key, v)
+			}
+			n = parsed
+		default:
+			return nil, "", fmt.Errorf("option %s expects a number, not %v", key, f)
+		}
+
+		r, found := optionRanges[key]
+		if !found {
+			if kind == reflect.Uint {
+				r = optionRange{0, math.MaxUint32}
+			} else {
+				r = optionRange{math.MinInt32, math.MaxInt32}
+			}
+		}
+
+		if clamped := math.Max(r.min, math.Min(r.max, n)); clamped != n {
+			outOfRange = fmt.Sprintf("option %s value %v is out of range [%v, %v]", key, n, r.min, r.max)
+			n = clamped
+		}
+
+		return n, outOfRange, nil
+	}
+
+	return f, "", nil
+}
+
+// optionsMapErrors returns why the options of the map can't be saved as they are, values of the wrong
+// type or out of range. Null values are left to FromMap as absent ones.
+func optionsMapErrors(m map[string]any) []string {
+	errs := []string{}
+
+	for key, f := range m {
+		if f == nil {
+			continue
+		}
+		if _, outOfRange, err := optionValue(key, f); err != nil {
+			errs = append(errs, err.Error())
+		} else if outOfRange != "" {
+			errs = append(errs, outOfRange)
+		}
+	}
+
+	slices.Sort(errs)
+
+	return errs
+}
+
+// validOptionsMap returns the options of the map read as Read does, numbers clamped to their range and
+// values of the wrong type left out
+func validOptionsMap(m map[string]any) map[string]any {
+	valid := make(map[string]any, len(m))
+
+	for key, f := range m {
+		if v, _, err := optionValue(key, f); err == nil {
+			valid[key] = v
+		}
+	}
+
+	return valid
+}
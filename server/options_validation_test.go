@@ -0,0 +1,116 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateOption(t *testing.T) {
+	for _, test := range []struct {
+		key, raw  string
+		value     string
+		ok        bool
+		corrected bool
+	}{
+		{key: "dimmerDelay", raw: "5000", value: "5000", ok: true},
+		{key: "dimmerDelay", raw: "-1", value: "0", ok: true, corrected: true},
+		{key: "dimmerDelay", raw: "1e12", value: "3600000", ok: true, corrected: true},
+		{key: "maxClients", raw: "0", value: "1", ok: true, corrected: true},
+		{key: "maxClients", raw: `"250"`, value: "250", ok: true, corrected: true},
+		{key: "maxClients", raw: `"many"`, corrected: true},
+		{key: "pruneDays", raw: "{not json", corrected: true},
+		{key: "pruneDays", raw: "true", corrected: true},
+		{key: "duplicateDetectionTimeFrame", raw: "99999999", value: "600000", ok: true, corrected: true},
+		{key: "keywordAlertCooldown", raw: "-30", value: "0", ok: true, corrected: true},
+		{key: "emailSmtpPort", raw: "70000", value: "65535", ok: true, corrected: true},
+		{key: "autoPopulate", raw: `"yes"`, corrected: true},
+		{key: "autoPopulate", raw: "false", value: "false", ok: true},
+		{key: "branding", raw: "42", corrected: true},
+		{key: "delayedOverflowPolicy", raw: `"drop-newest"`, corrected: true},
+		{key: "delayedOverflowPolicy", raw: `"release-immediately"`, value: `"release-immediately"`, ok: true},
+		{key: "transcriptionConfig", raw: `{"enabled":true}`, value: `{"enabled":true}`, ok: true},
+		{key: "adminPassword", raw: `"hash"`, value: `"hash"`, ok: true},
+	} {
+		value, correction, ok := validateOption(test.key, test.raw)
+
+		if ok != test.ok || (ok && value != test.value) {
+			t.Errorf("%s %s: got %q %v, want %q %v", test.key, test.raw, value, ok, test.value, test.ok)
+		}
+		if (correction != "") != test.corrected {
+			t.Errorf("%s %s: unexpected correction %q", test.key, test.raw, correction)
+		}
+	}
+}
+
+func TestOptionRangesCoverDefaults(t *testing.T) {
+	for key, value := range map[string]uint{
+		"dimmerDelay":                 defaults.options.dimmerDelay,
+		"duplicateDetectionTimeFrame": defaults.options.duplicateDetectionTimeFrame,
+		"maxClients":                  defaults.options.maxClients,
+		"pruneDays":                   defaults.options.pruneDays,
+		"delayedMaxEntries":           defaults.options.delayedMaxEntries,
+		"callRateAnomalySensitivity":  defaults.options.callRateAnomalySensitivity,
+		"orphanSweepInterval":         defaults.options.orphanSweepInterval,
+	} {
+		if kind, found := optionKinds[key]; !found || kind.String() != "uint" {
+			t.Errorf("%s: expected an unsigned option, got %v", key, kind)
+		}
+		if r := optionRanges[key]; float64(value) < r.min || float64(value) > r.max {
+			t.Errorf("%s: default %d out of range [%v, %v]", key, value, r.min, r.max)
+		}
+	}
+}
+
+func TestOptionsFromMapKeepsAbsent(t *testing.T) {
+	options := NewOptions()
+	options.ZipCodeFormat = "us"
+	options.MissingAudioResponse = "notFound"
+	options.DeviceTokenRetentionDays = 30
+	options.BrandingColor = "#ff0000"
+
+	// saved by an admin unaware of the options
+	options.FromMap(map[string]any{"maxClients": float64(50)})
+
+	if options.ZipCodeFormat != "us" || options.MissingAudioResponse != "notFound" || options.DeviceTokenRetentionDays != 30 || options.BrandingColor != "#ff0000" {
+		t.Errorf("expected the options kept, got %q %q %d %q", options.ZipCodeFormat, options.MissingAudioResponse, options.DeviceTokenRetentionDays, options.BrandingColor)
+	}
+}
+
+func TestOptionsMapErrors(t *testing.T) {
+	errs := optionsMapErrors(map[string]any{
+		"maxClients":          float64(0),
+		"pruneDays":           "30",
+		"autoPopulate":        "yes",
+		"zipCodeFormat":       nil,
+		"emailSmtpPort":       float64(587),
+		"branding":            "ThinLine",
+		"transcriptionConfig": map[string]any{"enabled": true},
+	})
+
+	if len(errs) != 2 || !strings.Contains(errs[0], "autoPopulate") || !strings.Contains(errs[1], "maxClients") {
+		t.Errorf("expected maxClients out of range and autoPopulate of the wrong type, got %v", errs)
+	}
+
+	options := NewOptions()
+	options.MaxClients = 200
+	options.FromMap(map[string]any{"maxClients": float64(0), "pruneDays": "30"})
+
+	if options.MaxClients != 1 || options.PruneDays != 30 {
+		t.Errorf("expected the values read as Read does, got maxClients %d pruneDays %d", options.MaxClients, options.PruneDays)
+	}
+}
@@ -420,7 +420,11 @@ func ParseMultipartContent(call *Call, p *multipart.Part, b []byte) {
 					unit := CallUnit{}
 					switch v := f.(type) {
 					case map[string]any:
-						switch s := v["id"].(type) {
+						id := v["id"]
+						if unitRef, ok := v["unitRef"]; ok {
+							id = unitRef
+						}
+						switch s := id.(type) {
 						case float64:
 							// Include all unit values, even 0, to match v6 behavior
 							unit.UnitRef = uint(s)
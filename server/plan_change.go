@@ -0,0 +1,207 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/invoice"
+	"github.com/stripe/stripe-go/v76/subscription"
+)
+
+// errInvalidPlanChange is returned for a plan change the user cannot make, as opposed to a failure of Stripe
+var errInvalidPlanChange = errors.New("invalid plan change")
+
+// PlanChangePreview is what switching the subscription of a user to another pricing option of
+// their group would charge, the amounts being in the smallest unit of the currency
+type PlanChangePreview struct {
+	PriceId         string `json:"priceId"`
+	Label           string `json:"label"`
+	Amount          string `json:"amount"` // display amount of the pricing option
+	Currency        string `json:"currency"`
+	ProratedAmount  int64  `json:"proratedAmount"`  // net of the prorations, negative when the unused time is worth more
+	CreditApplied   int64  `json:"creditApplied"`   // unused time of the current plan and customer balance deducted
+	AmountDue       int64  `json:"amountDue"`       // charged by the next invoice
+	NextBillingDate int64  `json:"nextBillingDate"` // unix seconds the next invoice is charged
+}
+
+// planChangeOption returns the pricing option of the group of the user for the price, checking
+// that the user has a subscription to change
+func (api *Api) planChangeOption(user *User, newPriceId string) (*PricingOption, error) {
+	if user == nil {
+		return nil, fmt.Errorf("%w: user not found", errInvalidPlanChange)
+	}
+
+	if user.StripeCustomerId == "" || user.StripeSubscriptionId == "" {
+		return nil, fmt.Errorf("%w: no subscription to change", errInvalidPlanChange)
+	}
+
+	group := api.Controller.UserGroups.Get(user.UserGroupId)
+	if group == nil || !group.BillingEnabled {
+		return nil, fmt.Errorf("%w: user is not in a billing-enabled group", errInvalidPlanChange)
+	}
+
+	newPriceId = strings.TrimSpace(newPriceId)
+	for _, option := range group.GetPricingOptions() {
+		if newPriceId != "" && option.PriceId == newPriceId {
+			return &option, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: invalid price ID for this group", errInvalidPlanChange)
+}
+
+// PreviewPlanChange returns what switching the subscription of the user to the price of another
+// pricing option of their group would charge now, with the prorations of the current period,
+// without changing the subscription
+func (api *Api) PreviewPlanChange(user *User, newPriceId string) (*PlanChangePreview, error) {
+	option, err := api.planChangeOption(user, newPriceId)
+	if err != nil {
+		return nil, err
+	}
+
+	stripe.Key = api.Controller.Options.StripeSecretKey
+	if stripe.Key == "" {
+		return nil, errors.New("stripe not configured")
+	}
+
+	sub, err := subscription.Get(user.StripeSubscriptionId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription %s: %v", user.StripeSubscriptionId, err)
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return nil, fmt.Errorf("%w: subscription has no item", errInvalidPlanChange)
+	}
+
+	item := sub.Items.Data[0]
+	if item.Price != nil && item.Price.ID == option.PriceId {
+		return nil, fmt.Errorf("%w: already subscribed to this plan", errInvalidPlanChange)
+	}
+
+	params := &stripe.InvoiceUpcomingParams{
+		Customer:     stripe.String(user.StripeCustomerId),
+		Subscription: stripe.String(sub.ID),
+		SubscriptionItems: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    stripe.String(item.ID),
+				Price: stripe.String(option.PriceId),
+			},
+		},
+		SubscriptionProrationBehavior: stripe.String("create_prorations"),
+		SubscriptionProrationDate:     stripe.Int64(time.Now().Unix()),
+	}
+
+	upcoming, err := invoice.Upcoming(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preview the upcoming invoice of subscription %s: %v", sub.ID, err)
+	}
+
+	return newPlanChangePreview(option, upcoming, sub.CurrentPeriodEnd), nil
+}
+
+// newPlanChangePreview sums up the upcoming invoice of a plan change, charged at the end of the
+// current period of the subscription unless the invoice has a payment attempt of its own
+func newPlanChangePreview(option *PricingOption, upcoming *stripe.Invoice, currentPeriodEnd int64) *PlanChangePreview {
+	preview := &PlanChangePreview{
+		PriceId:         option.PriceId,
+		Label:           option.Label,
+		Amount:          option.Amount,
+		Currency:        string(upcoming.Currency),
+		AmountDue:       upcoming.AmountDue,
+		NextBillingDate: upcoming.NextPaymentAttempt,
+	}
+
+	if preview.NextBillingDate == 0 {
+		preview.NextBillingDate = currentPeriodEnd
+	}
+
+	if upcoming.Lines != nil {
+		for _, line := range upcoming.Lines.Data {
+			if !line.Proration {
+				continue
+			}
+			preview.ProratedAmount += line.Amount
+			if line.Amount < 0 {
+				preview.CreditApplied -= line.Amount
+			}
+		}
+	}
+
+	// a credit balance is negative, applied as it goes back up towards 0
+	if applied := upcoming.EndingBalance - upcoming.StartingBalance; upcoming.StartingBalance < 0 && applied > 0 {
+		preview.CreditApplied += applied
+	}
+
+	return preview
+}
+
+// PlanChangePreviewHandler returns what switching to the pricing option of the priceId would
+// charge the user of the PIN
+func (api *Api) PlanChangePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		api.exitWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get PIN from query parameter or Authorization header
+	pin := r.URL.Query().Get("pin")
+	if pin == "" {
+		authHeader := r.Header.Get("Authorization")
+		if strings.HasPrefix(authHeader, "Bearer ") {
+			pin = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+	}
+
+	if pin == "" {
+		api.exitWithError(w, http.StatusUnauthorized, "PIN required")
+		return
+	}
+
+	user := api.Controller.Users.GetUserByPin(pin)
+	if user == nil {
+		api.exitWithError(w, http.StatusUnauthorized, "Invalid PIN")
+		return
+	}
+
+	var request struct {
+		PriceId string `json:"priceId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		api.exitWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	preview, err := api.PreviewPlanChange(user, request.PriceId)
+	if errors.Is(err, errInvalidPlanChange) {
+		api.exitWithError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("Error previewing plan change for user %s: %v", user.Email, err)
+		api.exitWithError(w, http.StatusInternalServerError, "Failed to preview the plan change")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stripe/stripe-go/v76"
+)
+
+func TestPlanChangeOption(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), UserGroups: NewUserGroups()}
+	api := &Api{Controller: controller}
+
+	paid := &UserGroup{Id: 1, BillingEnabled: true, PricingOptions: `[{"priceId":"price_monthly","label":"Monthly"},{"priceId":"price_yearly","label":"Yearly","amount":"$100/year"}]`}
+	paid.loadPricingOptions()
+	controller.UserGroups.groups[1] = paid
+	controller.UserGroups.groups[2] = &UserGroup{Id: 2}
+
+	subscribed := &User{UserGroupId: 1, StripeCustomerId: "cus_1", StripeSubscriptionId: "sub_1"}
+
+	option, err := api.planChangeOption(subscribed, " price_yearly ")
+	if err != nil || option.PriceId != "price_yearly" || option.Label != "Yearly" {
+		t.Errorf("expected the yearly option, got %+v %v", option, err)
+	}
+
+	for name, test := range map[string]struct {
+		user    *User
+		priceId string
+	}{
+		"no user":         {nil, "price_yearly"},
+		"no subscription": {&User{UserGroupId: 1, StripeCustomerId: "cus_1"}, "price_yearly"},
+		"free group":      {&User{UserGroupId: 2, StripeCustomerId: "cus_1", StripeSubscriptionId: "sub_1"}, "price_yearly"},
+		"other price":     {subscribed, "price_other"},
+		"no price":        {subscribed, ""},
+	} {
+		if _, err := api.planChangeOption(test.user, test.priceId); !errors.Is(err, errInvalidPlanChange) {
+			t.Errorf("%s: expected an invalid plan change, got %v", name, err)
+		}
+	}
+}
+
+func TestNewPlanChangePreview(t *testing.T) {
+	option := &PricingOption{PriceId: "price_yearly", Label: "Yearly", Amount: "$100/year"}
+
+	upcoming := &stripe.Invoice{
+		Currency:           stripe.CurrencyUSD,
+		AmountDue:          9000,
+		NextPaymentAttempt: 1767225600,
+		StartingBalance:    -500,
+		EndingBalance:      0,
+		Lines: &stripe.InvoiceLineItemList{Data: []*stripe.InvoiceLineItem{
+			{Amount: -500, Proration: true},  // unused time of the monthly plan
+			{Amount: 10000, Proration: true}, // remaining time of the yearly plan
+			{Amount: 10000},                  // next period, not prorated
+		}},
+	}
+
+	preview := newPlanChangePreview(option, upcoming, 1769904000)
+
+	if preview.PriceId != "price_yearly" || preview.Label != "Yearly" || preview.Currency != "usd" {
+		t.Errorf("expected the yearly option in usd, got %+v", preview)
+	}
+	if preview.ProratedAmount != 9500 || preview.CreditApplied != 1000 || preview.AmountDue != 9000 || preview.NextBillingDate != 1767225600 {
+		t.Errorf("expected 9500 prorated, 1000 credited, 9000 due on 1767225600, got %+v", preview)
+	}
+
+	// without a payment attempt, the invoice is charged at the end of the current period, not when it was previewed
+	preview = newPlanChangePreview(option, &stripe.Invoice{Created: 1767225600}, 1769904000)
+	if preview.NextBillingDate != 1769904000 || preview.ProratedAmount != 0 || preview.CreditApplied != 0 {
+		t.Errorf("expected the end of the current period and no proration, got %+v", preview)
+	}
+}
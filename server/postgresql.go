@@ -29,10 +29,22 @@ var PostgresqlSchema = []string{
 	`CREATE TABLE IF NOT EXISTS "downstreams" (
     "downstreamId" bigserial NOT NULL PRIMARY KEY,
     "apikey" text NOT NULL,
+    "audioFormat" text NOT NULL DEFAULT '',
     "disabled" boolean NOT NULL DEFAULT false,
+    "headers" text NOT NULL DEFAULT '',
+    "maxCallAge" integer NOT NULL DEFAULT 0,
+    "maxPerMinute" integer NOT NULL DEFAULT 0,
+    "maxRetries" integer NOT NULL DEFAULT 3,
+    "minInterval" integer NOT NULL DEFAULT 0,
     "name" text NOT NULL DEFAULT '',
     "order" integer NOT NULL DEFAULT 0,
+    "protocol" text NOT NULL DEFAULT 'v6',
+    "requireKeywords" text NOT NULL DEFAULT '',
+    "requireTones" boolean NOT NULL DEFAULT false,
+    "retryBackoff" integer NOT NULL DEFAULT 1000,
     "systems" text NOT NULL DEFAULT '',
+    "throttleMode" text NOT NULL DEFAULT 'queue',
+    "timeoutSeconds" integer NOT NULL DEFAULT 30,
     "url" text NOT NULL
   );`,
 
@@ -52,11 +64,23 @@ var PostgresqlSchema = []string{
     "systemId" bigserial NOT NULL PRIMARY KEY,
     "autoPopulate" boolean NOT NULL DEFAULT false,
     "blacklists" text NOT NULL DEFAULT '',
+    "defaultGroupId" bigint NOT NULL DEFAULT 0,
+    "defaultTagId" bigint NOT NULL DEFAULT 0,
     "delay" integer NOT NULL DEFAULT 0,
+    "displayConfidence" double precision NOT NULL DEFAULT 0,
     "label" text NOT NULL,
     "order" integer NOT NULL DEFAULT 0,
     "systemRef" integer NOT NULL,
-    "type" text NOT NULL DEFAULT ''
+    "type" text NOT NULL DEFAULT '',
+    "vocabularyProfileId" bigint NOT NULL DEFAULT 0
+  );`,
+
+	`CREATE TABLE IF NOT EXISTS "vocabularyProfiles" (
+    "vocabularyProfileId" bigserial NOT NULL PRIMARY KEY,
+    "name" text NOT NULL UNIQUE,
+    "phrases" text NOT NULL DEFAULT '[]',
+    "keywordListIds" text NOT NULL DEFAULT '[]',
+    "isDefault" boolean NOT NULL DEFAULT false
   );`,
 
 	`CREATE TABLE IF NOT EXISTS "sites" (
@@ -71,15 +95,21 @@ var PostgresqlSchema = []string{
 	`CREATE TABLE IF NOT EXISTS "talkgroups" (
     "talkgroupId" bigserial NOT NULL PRIMARY KEY,
     "delay" integer NOT NULL DEFAULT 0,
+    "ephemeral" boolean NOT NULL DEFAULT false,
+    "ephemeralTtl" integer NOT NULL DEFAULT 0,
     "frequency" integer NOT NULL DEFAULT 0,
     "label" text NOT NULL,
     "name" text NOT NULL,
     "order" integer NOT NULL DEFAULT 0,
+    "priority" integer NOT NULL DEFAULT 0,
+    "gain" double precision NOT NULL DEFAULT 0,
+    "displayConfidence" double precision NOT NULL DEFAULT 0,
     "systemId" bigint NOT NULL,
     "tagId" bigint NOT NULL,
     "talkgroupRef" integer NOT NULL,
     "type" TEXT NOT NULL DEFAULT '',
     "toneDetectionEnabled" boolean NOT NULL DEFAULT false,
+    "toneLearningUntil" bigint NOT NULL DEFAULT 0,
     "toneSets" text NOT NULL DEFAULT '[]',
     CONSTRAINT "talkgroups_systemId_fkey" FOREIGN KEY ("systemId") REFERENCES "systems" ("systemId") ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT "talkgroups_tagId_fkey" FOREIGN KEY ("tagId") REFERENCES "tags" ("tagId") ON DELETE CASCADE ON UPDATE CASCADE
@@ -117,9 +147,11 @@ var PostgresqlSchema = []string{
 	`ALTER TABLE "calls" ADD COLUMN IF NOT EXISTS "transcriptConfidence" real NOT NULL DEFAULT 0;`,
 	`ALTER TABLE "calls" ADD COLUMN IF NOT EXISTS "transcriptionStatus" text NOT NULL DEFAULT 'pending';`,
 	`ALTER TABLE "calls" ADD COLUMN IF NOT EXISTS "transcriptionFailureReason" text NOT NULL DEFAULT '';`,
+	`ALTER TABLE "calls" ADD COLUMN IF NOT EXISTS "incidentId" bigint NOT NULL DEFAULT 0;`,
 	`CREATE INDEX IF NOT EXISTS "calls_refs_idx" ON "calls" ("systemRef","talkgroupRef","timestamp");`,
 	`CREATE INDEX IF NOT EXISTS "calls_tones_idx" ON "calls" ("hasTones","timestamp");`,
 	`CREATE INDEX IF NOT EXISTS "calls_transcript_idx" ON "calls" ("transcriptionStatus","timestamp");`,
+	`CREATE INDEX IF NOT EXISTS "calls_incident_idx" ON "calls" ("incidentId","timestamp");`,
 	`DROP TABLE IF EXISTS "callFrequencies";`,
 
 	`CREATE TABLE IF NOT EXISTS "callPatches" (
@@ -151,6 +183,17 @@ var PostgresqlSchema = []string{
     CONSTRAINT "delayed_callId" FOREIGN KEY ("callId") REFERENCES "calls" ("callId") ON DELETE CASCADE ON UPDATE CASCADE
   );`,
 
+	`CREATE TABLE IF NOT EXISTS "downstreamQueue" (
+    "downstreamQueueId" bigserial NOT NULL PRIMARY KEY,
+    "callId" bigint NOT NULL,
+    "downstreamId" bigint NOT NULL,
+    "attempts" integer NOT NULL DEFAULT 0,
+    "nextAttemptAt" bigint NOT NULL DEFAULT 0,
+    CONSTRAINT "downstreamQueue_callId_downstreamId" UNIQUE ("callId", "downstreamId"),
+    CONSTRAINT "downstreamQueue_callId" FOREIGN KEY ("callId") REFERENCES "calls" ("callId") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "downstreamQueue_downstreamId" FOREIGN KEY ("downstreamId") REFERENCES "downstreams" ("downstreamId") ON DELETE CASCADE ON UPDATE CASCADE
+  );`,
+
 	`CREATE TABLE IF NOT EXISTS "dirwatches" (
     "dirwatchId" bigserial NOT NULL PRIMARY KEY,
     "delay" integer NOT NULL DEFAULT 0,
@@ -241,6 +284,7 @@ var PostgresqlSchema = []string{
     "keywords" text NOT NULL DEFAULT '[]',
     "keywordListIds" text NOT NULL DEFAULT '[]',
     "toneSetIds" text NOT NULL DEFAULT '[]',
+    "exclusions" text NOT NULL DEFAULT '[]',
     CONSTRAINT "userAlertPreferences_userId_fkey" FOREIGN KEY ("userId") REFERENCES "users" ("userId") ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT "userAlertPreferences_systemId_fkey" FOREIGN KEY ("systemId") REFERENCES "systems" ("systemId") ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT "userAlertPreferences_talkgroupId_fkey" FOREIGN KEY ("talkgroupId") REFERENCES "talkgroups" ("talkgroupId") ON DELETE CASCADE ON UPDATE CASCADE,
@@ -253,7 +297,8 @@ var PostgresqlSchema = []string{
     "description" text NOT NULL DEFAULT '',
     "keywords" text NOT NULL DEFAULT '[]',
     "order" integer NOT NULL DEFAULT 0,
-    "createdAt" bigint NOT NULL DEFAULT 0
+    "createdAt" bigint NOT NULL DEFAULT 0,
+    "phonetic" boolean NOT NULL DEFAULT false
   );`,
 
 	`CREATE TABLE IF NOT EXISTS "alerts" (
@@ -266,6 +311,7 @@ var PostgresqlSchema = []string{
     "toneSetId" text NOT NULL DEFAULT '',
     "keywordsMatched" text NOT NULL DEFAULT '[]',
     "transcriptSnippet" text NOT NULL DEFAULT '',
+    "suppressed" boolean NOT NULL DEFAULT false,
     "createdAt" bigint NOT NULL,
     CONSTRAINT "alerts_callId_fkey" FOREIGN KEY ("callId") REFERENCES "calls" ("callId") ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT "alerts_systemId_fkey" FOREIGN KEY ("systemId") REFERENCES "systems" ("systemId") ON DELETE CASCADE ON UPDATE CASCADE,
@@ -275,6 +321,14 @@ var PostgresqlSchema = []string{
 	`CREATE INDEX IF NOT EXISTS "alerts_created_idx" ON "alerts" ("createdAt");`,
 	`CREATE INDEX IF NOT EXISTS "alerts_call_idx" ON "alerts" ("callId");`,
 
+	`CREATE TABLE IF NOT EXISTS "alertTestWindows" (
+    "alertTestWindowId" bigserial NOT NULL PRIMARY KEY,
+    "label" text NOT NULL DEFAULT '',
+    "systemId" bigint NOT NULL DEFAULT 0,
+    "startsAt" bigint NOT NULL,
+    "endsAt" bigint NOT NULL
+  );`,
+
 	`CREATE TABLE IF NOT EXISTS "transcriptions" (
     "transcriptionId" bigserial NOT NULL PRIMARY KEY,
     "callId" bigint NOT NULL,
@@ -320,6 +374,9 @@ var PostgresqlSchema = []string{
     "collectSalesTax" boolean NOT NULL DEFAULT false,
     "isPublicRegistration" boolean NOT NULL DEFAULT false,
     "allowAddExistingUsers" boolean NOT NULL DEFAULT false,
+    "defaultAlertPreferences" text NOT NULL DEFAULT '',
+    "parentGroupId" bigint NOT NULL DEFAULT 0,
+    "systemAccessRemove" text NOT NULL DEFAULT '',
     "createdAt" bigint NOT NULL DEFAULT 0
   );`,
 
@@ -334,6 +391,7 @@ var PostgresqlSchema = []string{
     "isOneTime" boolean NOT NULL DEFAULT false,
     "isActive" boolean NOT NULL DEFAULT true,
     "createdAt" bigint NOT NULL DEFAULT 0,
+    "lastUsedAt" bigint NOT NULL DEFAULT 0,
     CONSTRAINT "registrationCodes_userGroupId_fkey" FOREIGN KEY ("userGroupId") REFERENCES "userGroups" ("userGroupId") ON DELETE CASCADE ON UPDATE CASCADE,
     CONSTRAINT "registrationCodes_createdBy_fkey" FOREIGN KEY ("createdBy") REFERENCES "users" ("userId") ON DELETE CASCADE ON UPDATE CASCADE
   );`,
@@ -382,4 +440,61 @@ var PostgresqlSchema = []string{
     CONSTRAINT "deviceTokens_userId_fkey" FOREIGN KEY ("userId") REFERENCES "users" ("userId") ON DELETE CASCADE ON UPDATE CASCADE,
     UNIQUE ("userId", "token")
   );`,
+
+	`CREATE TABLE IF NOT EXISTS "toneSetSuggestions" (
+    "toneSetSuggestionId" bigserial NOT NULL PRIMARY KEY,
+    "systemId" bigint NOT NULL,
+    "talkgroupId" bigint NOT NULL,
+    "toneSet" text NOT NULL,
+    "occurrences" integer NOT NULL DEFAULT 1,
+    "firstSeenAt" bigint NOT NULL DEFAULT 0,
+    "lastSeenAt" bigint NOT NULL DEFAULT 0,
+    "status" text NOT NULL DEFAULT 'pending',
+    CONSTRAINT "toneSetSuggestions_talkgroupId_fkey" FOREIGN KEY ("talkgroupId") REFERENCES "talkgroups" ("talkgroupId") ON DELETE CASCADE ON UPDATE CASCADE
+  );`,
+
+	`CREATE TABLE IF NOT EXISTS "auditLog" (
+    "auditLogId" bigserial NOT NULL PRIMARY KEY,
+    "actorUserId" bigint NOT NULL DEFAULT 0,
+    "action" text NOT NULL,
+    "target" text NOT NULL DEFAULT '',
+    "before" text NOT NULL DEFAULT '',
+    "after" text NOT NULL DEFAULT '',
+    "timestamp" bigint NOT NULL,
+    "ip" text NOT NULL DEFAULT ''
+  );`,
+
+	`CREATE INDEX IF NOT EXISTS "auditLog_timestamp_idx" ON "auditLog" ("timestamp");`,
+
+	`CREATE TABLE IF NOT EXISTS "transcriptionUsage" (
+    "systemId" bigint NOT NULL,
+    "month" text NOT NULL,
+    "provider" text NOT NULL,
+    "transcriptions" bigint NOT NULL DEFAULT 0,
+    "seconds" double precision NOT NULL DEFAULT 0,
+    "cost" double precision NOT NULL DEFAULT 0,
+    PRIMARY KEY ("systemId", "month", "provider")
+  );`,
+
+	`CREATE TABLE IF NOT EXISTS "couponCodes" (
+    "couponCodeId" bigserial NOT NULL PRIMARY KEY,
+    "code" text NOT NULL UNIQUE,
+    "stripeCouponId" text NOT NULL,
+    "maxRedemptions" integer NOT NULL DEFAULT 0,
+    "redemptions" integer NOT NULL DEFAULT 0,
+    "expiresAt" bigint NOT NULL DEFAULT 0,
+    "createdAt" bigint NOT NULL DEFAULT 0
+  );`,
+
+	`CREATE TABLE IF NOT EXISTS "userGroupAuditLog" (
+    "userGroupAuditLogId" bigserial NOT NULL PRIMARY KEY,
+    "timestamp" bigint NOT NULL,
+    "actorUserId" bigint NOT NULL DEFAULT 0,
+    "targetUserId" bigint NOT NULL DEFAULT 0,
+    "groupId" bigint NOT NULL DEFAULT 0,
+    "action" text NOT NULL,
+    "details" text NOT NULL DEFAULT '{}'
+  );`,
+
+	`CREATE INDEX IF NOT EXISTS "userGroupAuditLog_groupId_timestamp_idx" ON "userGroupAuditLog" ("groupId", "timestamp");`,
 }
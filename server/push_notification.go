@@ -23,15 +23,26 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-// sendPushNotification sends a push notification to the relay server
+// pushConfigured tells whether the relay server (URL is hardcoded), web push or APNs is configured
+func (controller *Controller) pushConfigured() bool {
+	return controller.Options.RelayServerAPIKey != "" || controller.WebPush.Configured() || controller.APNs.Configured()
+}
+
+// sendPushNotification sends a push notification to the relay server, with web push to the browsers
+// and with APNs to the ios devices registered with an APNs device token
 func (controller *Controller) sendPushNotification(userId uint64, alertType string, call *Call, systemLabel, talkgroupLabel string, toneSetName string, keywords []string) {
-	// Check if relay server API key is configured (URL is hardcoded)
-	if controller.Options.RelayServerAPIKey == "" {
+	if !controller.pushConfigured() {
 		return // Push notifications not configured
 	}
 
+	// Alerts raised during a test window are recorded but not pushed
+	if controller.alertsSuppressed(call, time.Now()) {
+		return
+	}
+
 	// Get user
 	user := controller.Users.GetUserById(userId)
 	if user == nil {
@@ -99,7 +110,7 @@ func (controller *Controller) sendPushNotification(userId uint64, alertType stri
 	}
 
 	// Get user's device tokens
-	deviceTokens := controller.DeviceTokens.GetByUser(userId)
+	deviceTokens := controller.DeviceTokens.GetForUser(userId)
 	controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: retrieved %d device token(s) for user %d", len(deviceTokens), userId))
 	if len(deviceTokens) == 0 {
 		return // No devices registered
@@ -177,9 +188,21 @@ func (controller *Controller) sendPushNotification(userId uint64, alertType stri
 	// Group devices by platform and sound preference
 	androidDevices := []string{}
 	iosDevices := []string{}
+	webDevices := 0
+	apnsDevices := 0
 	defaultSound := "startup.wav"
 
 	for _, device := range deviceTokens {
+		if device.Platform == deviceTokenPlatformWeb {
+			// Browsers are pushed to directly, not through the relay server
+			webDevices++
+			continue
+		}
+		if device.Platform == "ios" && controller.APNs.Configured() && isAPNsDeviceToken(device.Token) {
+			// So are the ios devices registered with an APNs device token
+			apnsDevices++
+			continue
+		}
 		if device.Platform == "ios" {
 			iosDevices = append(iosDevices, device.Token)
 		} else {
@@ -191,7 +214,7 @@ func (controller *Controller) sendPushNotification(userId uint64, alertType stri
 		}
 	}
 
-	controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: grouped devices for user %d - Android: %d, iOS: %d", userId, len(androidDevices), len(iosDevices)))
+	controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: grouped devices for user %d - Android: %d, iOS: %d, APNs: %d, web: %d", userId, len(androidDevices), len(iosDevices), apnsDevices, webDevices))
 
 	// Build subtitle for tone alerts
 	subtitle := ""
@@ -206,6 +229,23 @@ func (controller *Controller) sendPushNotification(userId uint64, alertType stri
 		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: alertType is '%s', no subtitle needed", alertType))
 	}
 
+	// Send to web devices
+	if webDevices > 0 && controller.WebPush.Configured() {
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: sending to %d web device(s) for user %d", webDevices, userId))
+		go controller.WebPush.SendToUser(userId, title, subtitle, message, pushNotificationData(call, systemLabel, talkgroupLabel))
+	}
+
+	// Send to APNs devices
+	if apnsDevices > 0 {
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: sending to %d APNs device(s) for user %d", apnsDevices, userId))
+		go controller.APNs.SendToUser(userId, title, subtitle, message, pushNotificationData(call, systemLabel, talkgroupLabel))
+	}
+
+	// The other devices are pushed to by the relay server
+	if controller.Options.RelayServerAPIKey == "" {
+		return
+	}
+
 	// Send to Android devices
 	if len(androidDevices) > 0 {
 		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: sending to %d Android device(s) for user %d", len(androidDevices), userId))
@@ -232,30 +272,7 @@ func (controller *Controller) sendNotificationBatch(playerIDs []string, title, s
 	}
 
 	// Build payload data (keep existing structure)
-	data := map[string]interface{}{}
-
-	if call != nil {
-		data["callId"] = call.Id
-		if call.System != nil {
-			data["systemId"] = call.System.Id
-			if systemLabel == "" {
-				systemLabel = call.System.Label
-			}
-		}
-		if call.Talkgroup != nil {
-			data["talkgroupId"] = call.Talkgroup.Id
-			if talkgroupLabel == "" {
-				talkgroupLabel = call.Talkgroup.Label
-			}
-		}
-	}
-
-	if systemLabel != "" {
-		data["systemLabel"] = systemLabel
-	}
-	if talkgroupLabel != "" {
-		data["talkgroupLabel"] = talkgroupLabel
-	}
+	data := pushNotificationData(call, systemLabel, talkgroupLabel)
 
 	// Build request payload
 	payload := map[string]interface{}{
@@ -332,21 +349,11 @@ func (controller *Controller) sendNotificationBatch(playerIDs []string, title, s
 	if len(response.InvalidPlayerIDs) > 0 {
 		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("push notification: removing %d invalid OneSignal ID(s) from user accounts: %v", len(response.InvalidPlayerIDs), response.InvalidPlayerIDs))
 		for _, invalidPlayerID := range response.InvalidPlayerIDs {
-			// Find and remove the device token with this OneSignal ID
-			allUsers := controller.Users.GetAllUsers()
-			for _, user := range allUsers {
-				deviceTokens := controller.DeviceTokens.GetByUser(user.Id)
-				for _, deviceToken := range deviceTokens {
-					if deviceToken.Token == invalidPlayerID {
-						controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: removing invalid OneSignal ID %s from user %d", invalidPlayerID, user.Id))
-						if err := controller.DeviceTokens.Delete(deviceToken.Id, controller.Database); err != nil {
-							controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("push notification: failed to remove invalid OneSignal ID %s from user %d: %v", invalidPlayerID, user.Id, err))
-						} else {
-							controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: successfully removed invalid OneSignal ID %s from user %d", invalidPlayerID, user.Id))
-						}
-						break // Found and removed, move to next invalid ID
-					}
-				}
+			// Remove the device tokens with this OneSignal ID, of every user
+			if removed, err := controller.DeviceTokens.Remove(invalidPlayerID, controller.Database); err != nil {
+				controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("push notification: failed to remove invalid OneSignal ID %s: %v", invalidPlayerID, err))
+			} else if removed > 0 {
+				controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification: removed invalid OneSignal ID %s from %d device(s)", invalidPlayerID, removed))
 			}
 		}
 	}
@@ -364,14 +371,63 @@ func (controller *Controller) sendNotificationBatch(playerIDs []string, title, s
 	}
 }
 
+// truncatePushMessage cuts the message to at most max bytes, on a character boundary
+func truncatePushMessage(message string, max int) string {
+	if len(message) <= max {
+		return message
+	}
+
+	message = message[:max]
+	for !utf8.ValidString(message) {
+		message = message[:len(message)-1]
+	}
+
+	return message + "…"
+}
+
+// pushNotificationData returns the data of the notification the clients open the call with
+func pushNotificationData(call *Call, systemLabel, talkgroupLabel string) map[string]interface{} {
+	data := map[string]interface{}{}
+
+	if call != nil {
+		data["callId"] = call.Id
+		if call.System != nil {
+			data["systemId"] = call.System.Id
+			if systemLabel == "" {
+				systemLabel = call.System.Label
+			}
+		}
+		if call.Talkgroup != nil {
+			data["talkgroupId"] = call.Talkgroup.Id
+			if talkgroupLabel == "" {
+				talkgroupLabel = call.Talkgroup.Label
+			}
+		}
+	}
+
+	if systemLabel != "" {
+		data["systemLabel"] = systemLabel
+	}
+	if talkgroupLabel != "" {
+		data["talkgroupLabel"] = talkgroupLabel
+	}
+
+	return data
+}
+
 // sendBatchedPushNotification sends push notifications to multiple users in a single batch
 // Groups device tokens by platform and sound preference, then sends batched notifications
+// Web and APNs devices are pushed to per user, without the relay server
 func (controller *Controller) sendBatchedPushNotification(userIds []uint64, alertType string, call *Call, systemLabel, talkgroupLabel string, toneSetName string, keywords []string) {
-	// Check if relay server API key is configured (URL is hardcoded)
-	if controller.Options.RelayServerAPIKey == "" {
+	if !controller.pushConfigured() {
 		return // Push notifications not configured
 	}
 
+	// Alerts raised during a test window are recorded but not pushed
+	if controller.alertsSuppressed(call, time.Now()) {
+		return
+	}
+
 	// Build notification title and message (same for all users)
 	// Title: System name / Channel name
 	title := ""
@@ -439,6 +495,8 @@ func (controller *Controller) sendBatchedPushNotification(userIds []uint64, aler
 	// Collect all device tokens from all users, grouped by platform and sound
 	// Key: "platform:sound" -> []playerIDs
 	deviceGroups := make(map[string][]string)
+	webUsers := []uint64{}
+	apnsUsers := []uint64{}
 
 	for _, userId := range userIds {
 		// Get user
@@ -505,7 +563,7 @@ func (controller *Controller) sendBatchedPushNotification(userIds []uint64, aler
 		}
 
 		// Get user's device tokens
-		deviceTokens := controller.DeviceTokens.GetByUser(userId)
+		deviceTokens := controller.DeviceTokens.GetForUser(userId)
 		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification (batched): retrieved %d device token(s) for user %d", len(deviceTokens), userId))
 		if len(deviceTokens) == 0 {
 			continue // No devices registered
@@ -517,7 +575,17 @@ func (controller *Controller) sendBatchedPushNotification(userIds []uint64, aler
 		}
 
 		// Group devices by platform and sound
+		webDevices := 0
+		apnsDevices := 0
 		for _, device := range deviceTokens {
+			if device.Platform == deviceTokenPlatformWeb {
+				webDevices++
+				continue
+			}
+			if device.Platform == "ios" && controller.APNs.Configured() && isAPNsDeviceToken(device.Token) {
+				apnsDevices++
+				continue
+			}
 			sound := device.Sound
 			if sound == "" {
 				sound = "startup.wav"
@@ -525,6 +593,47 @@ func (controller *Controller) sendBatchedPushNotification(userIds []uint64, aler
 			key := fmt.Sprintf("%s:%s", device.Platform, sound)
 			deviceGroups[key] = append(deviceGroups[key], device.Token)
 		}
+		if webDevices > 0 {
+			webUsers = append(webUsers, userId)
+		}
+		if apnsDevices > 0 {
+			apnsUsers = append(apnsUsers, userId)
+		}
+	}
+
+	// Build subtitle for tone alerts
+	subtitle := ""
+	if alertType == "tone" || alertType == "tone+keyword" {
+		if toneSetName != "" {
+			subtitle = strings.ToUpper(toneSetName)
+		}
+	}
+
+	// Send to the web devices of each user
+	if len(webUsers) > 0 && controller.WebPush.Configured() {
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification (batched): sending to the web devices of %d user(s)", len(webUsers)))
+		data := pushNotificationData(call, systemLabel, talkgroupLabel)
+		go func(ids []uint64) {
+			for _, userId := range ids {
+				controller.WebPush.SendToUser(userId, title, subtitle, message, data)
+			}
+		}(webUsers)
+	}
+
+	// Send to the APNs devices of each user
+	if len(apnsUsers) > 0 {
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification (batched): sending to the APNs devices of %d user(s)", len(apnsUsers)))
+		data := pushNotificationData(call, systemLabel, talkgroupLabel)
+		go func(ids []uint64) {
+			for _, userId := range ids {
+				controller.APNs.SendToUser(userId, title, subtitle, message, data)
+			}
+		}(apnsUsers)
+	}
+
+	// The other devices are pushed to by the relay server
+	if controller.Options.RelayServerAPIKey == "" {
+		return
 	}
 
 	// Send batched notifications for each platform/sound combination
@@ -542,14 +651,6 @@ func (controller *Controller) sendBatchedPushNotification(userIds []uint64, aler
 		platform := parts[0]
 		sound := parts[1]
 
-		// Build subtitle for tone alerts
-		subtitle := ""
-		if alertType == "tone" || alertType == "tone+keyword" {
-			if toneSetName != "" {
-				subtitle = strings.ToUpper(toneSetName)
-			}
-		}
-
 		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("push notification (batched): sending batch with %d player ID(s) for %s platform, sound: %s", len(playerIDs), platform, sound))
 		// Send batch notification in goroutine to ensure independent execution
 		// Each batch is sent independently, so failures in one don't affect others
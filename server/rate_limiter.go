@@ -18,33 +18,61 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 )
 
+// heavyEndpointMaxRequests is the number of search and bulk listing requests a user may make per minute
+const heavyEndpointMaxRequests = 30
+
+// loginMaxRequests is the number of login requests an address may make per minute, failed or not
+const loginMaxRequests = 10
+
+// RateLimitMode is the algorithm a RateLimiter counts the requests with
+type RateLimitMode int
+
+const (
+	// RateLimitFixedWindow allows maxRequests per window, the count resetting when the window expires,
+	// so that up to twice as many requests can pass around the reset
+	RateLimitFixedWindow RateLimitMode = iota
+	// RateLimitTokenBucket allows bursts of maxRequests, each request consuming a token refilled
+	// continuously at the rate
+	RateLimitTokenBucket
+)
+
 // RateLimiter provides general rate limiting to prevent DDoS attacks
 type RateLimiter struct {
 	requests map[string]*rateLimitEntry
 	mutex    sync.RWMutex
-	// Maximum requests per IP per window
+	mode     RateLimitMode
+	// Maximum requests per IP per window, the bucket size in token bucket mode
 	maxRequests int
-	// Time window for rate limiting
+	// Time window for rate limiting, the time to refill an empty bucket in token bucket mode
 	windowDuration time.Duration
+	// Tokens refilled per second in token bucket mode
+	rate float64
 	// Cleanup interval for old entries
 	cleanupInterval time.Duration
+	// Addresses never limited
+	allowlist *IPAllowlist
+	now       func() time.Time
 }
 
 type rateLimitEntry struct {
 	count     int
+	tokens    float64
 	firstSeen time.Time
 	lastSeen  time.Time
 }
 
-// LoginAttemptTracker tracks failed login attempts and blocks IPs after threshold
+// LoginAttemptTracker tracks failed login attempts and blocks IPs after threshold, and the accounts
+// after the same threshold whatever the IPs the attempts come from
 type LoginAttemptTracker struct {
-	attempts map[string]*loginAttemptEntry
+	attempts map[string]*loginAttemptEntry // by IP
+	accounts map[string]*loginAttemptEntry // by lowercased email
 	mutex    sync.RWMutex
 	// Maximum failed attempts before blocking
 	maxAttempts int
@@ -52,6 +80,74 @@ type LoginAttemptTracker struct {
 	blockDuration time.Duration
 	// Cleanup interval for old entries
 	cleanupInterval time.Duration
+	// Addresses never blocked
+	allowlist *IPAllowlist
+}
+
+// IPAllowlist holds the addresses exempt from the rate limits and login blocks, e.g. the monitoring
+// probes and the dispatch workstations
+type IPAllowlist struct {
+	networks []*net.IPNet
+}
+
+// NewIPAllowlist parses a comma separated list of ips and cidr ranges (e.g., "10.0.0.0/8, 192.0.2.10").
+// The invalid entries are left out of the allowlist and reported in the error.
+func NewIPAllowlist(entries string) (*IPAllowlist, error) {
+	allowlist := &IPAllowlist{networks: []*net.IPNet{}}
+	invalid := []string{}
+
+	for _, entry := range strings.Split(entries, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				allowlist.networks = append(allowlist.networks, network)
+			} else {
+				invalid = append(invalid, entry)
+			}
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			invalid = append(invalid, entry)
+			continue
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		allowlist.networks = append(allowlist.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+
+	if len(invalid) > 0 {
+		return allowlist, fmt.Errorf("invalid allowlist entries %s", strings.Join(invalid, ", "))
+	}
+
+	return allowlist, nil
+}
+
+// Contains tells whether the address, as returned by getPeerAddr, is allowlisted
+func (allowlist *IPAllowlist) Contains(addr string) bool {
+	if allowlist == nil || len(allowlist.networks) == 0 {
+		return false
+	}
+
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range allowlist.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
 }
 
 type loginAttemptEntry struct {
@@ -66,9 +162,11 @@ type loginAttemptEntry struct {
 func NewRateLimiter(maxRequests int, windowDuration time.Duration) *RateLimiter {
 	rl := &RateLimiter{
 		requests:        make(map[string]*rateLimitEntry),
+		mode:            RateLimitFixedWindow,
 		maxRequests:     maxRequests,
 		windowDuration:  windowDuration,
 		cleanupInterval: windowDuration * 2, // Clean up entries older than 2 windows
+		now:             time.Now,
 	}
 
 	// Start cleanup goroutine
@@ -77,12 +175,62 @@ func NewRateLimiter(maxRequests int, windowDuration time.Duration) *RateLimiter
 	return rl
 }
 
+// NewTokenBucketRateLimiter creates a new rate limiter refilling the tokens continuously
+// rate: tokens refilled per second (e.g., 1000.0 / 60 for 1000 requests per minute)
+// burst: maximum tokens, the requests an IP can make at once (e.g., 100)
+func NewTokenBucketRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	// Time to refill an empty bucket, after which an idle entry is the same as a new one
+	windowDuration := time.Second
+	if rate > 0 {
+		windowDuration = time.Duration(float64(burst) / rate * float64(time.Second))
+	}
+
+	rl := &RateLimiter{
+		requests:        make(map[string]*rateLimitEntry),
+		mode:            RateLimitTokenBucket,
+		maxRequests:     burst,
+		windowDuration:  windowDuration,
+		rate:            rate,
+		cleanupInterval: windowDuration * 2, // Clean up entries idle for twice a full refill
+		now:             time.Now,
+	}
+
+	// Start cleanup goroutine
+	go rl.cleanup()
+
+	return rl
+}
+
+// SetAllowlist exempts the addresses of the allowlist from the limit
+func (rl *RateLimiter) SetAllowlist(allowlist *IPAllowlist) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.allowlist = allowlist
+}
+
+// Exempts tells whether the request comes from an allowlisted peer, never limited
+func (rl *RateLimiter) Exempts(r *http.Request) bool {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	return rl.allowlist.Contains(getPeerAddr(r))
+}
+
 // Allow checks if a request from the given IP should be allowed
 func (rl *RateLimiter) Allow(ip string) bool {
+	if rl.mode == RateLimitTokenBucket {
+		return rl.allowTokenBucket(ip)
+	}
+
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
-	now := time.Now()
+	now := rl.now()
 	entry, exists := rl.requests[ip]
 
 	if !exists {
@@ -115,6 +263,74 @@ func (rl *RateLimiter) Allow(ip string) bool {
 	return true
 }
 
+// allowTokenBucket refills the bucket of the given IP for the time elapsed since its last request and consumes a token
+func (rl *RateLimiter) allowTokenBucket(ip string) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	entry, exists := rl.requests[ip]
+
+	if !exists {
+		// First request from this IP, with a full bucket
+		entry = &rateLimitEntry{
+			tokens:    float64(rl.maxRequests),
+			firstSeen: now,
+			lastSeen:  now,
+		}
+		rl.requests[ip] = entry
+	} else {
+		entry.tokens = rl.refilledTokens(entry, now)
+		entry.lastSeen = now
+	}
+
+	if entry.tokens < 1 {
+		return false
+	}
+
+	entry.tokens--
+	return true
+}
+
+// refilledTokens returns the tokens of the entry at now, refilled at the rate up to the bucket size
+func (rl *RateLimiter) refilledTokens(entry *rateLimitEntry, now time.Time) float64 {
+	tokens := entry.tokens + now.Sub(entry.lastSeen).Seconds()*rl.rate
+	if tokens > float64(rl.maxRequests) {
+		tokens = float64(rl.maxRequests)
+	}
+	return tokens
+}
+
+// RetryAfter returns how long the given key must wait before its next request is allowed
+func (rl *RateLimiter) RetryAfter(key string) time.Duration {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	entry, exists := rl.requests[key]
+
+	if rl.mode == RateLimitTokenBucket {
+		if !exists || rl.rate <= 0 {
+			return 0
+		}
+		missing := 1 - rl.refilledTokens(entry, rl.now())
+		if missing <= 0 {
+			return 0
+		}
+		return time.Duration(missing / rl.rate * float64(time.Second))
+	}
+
+	if !exists || entry.count < rl.maxRequests {
+		return 0
+	}
+
+	remaining := entry.firstSeen.Add(rl.windowDuration).Sub(rl.now())
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
 // cleanup removes old entries to prevent memory leaks
 func (rl *RateLimiter) cleanup() {
 	ticker := time.NewTicker(rl.cleanupInterval)
@@ -122,7 +338,7 @@ func (rl *RateLimiter) cleanup() {
 
 	for range ticker.C {
 		rl.mutex.Lock()
-		now := time.Now()
+		now := rl.now()
 		for ip, entry := range rl.requests {
 			if now.Sub(entry.lastSeen) > rl.cleanupInterval {
 				delete(rl.requests, ip)
@@ -132,12 +348,52 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 
+// RouteRateLimiter selects a rate limiter by the path of the request, so that each route can have a limit
+// of its own, e.g. stricter for the logins than for the audio, each limiter counting its own requests
+type RouteRateLimiter struct {
+	routes       map[string]*RateLimiter
+	defaultLimit *RateLimiter
+	mutex        sync.RWMutex
+}
+
+// NewRouteRateLimiter creates a route rate limiter falling back to defaultLimit for the paths without a route
+func NewRouteRateLimiter(defaultLimit *RateLimiter) *RouteRateLimiter {
+	return &RouteRateLimiter{
+		routes:       make(map[string]*RateLimiter),
+		defaultLimit: defaultLimit,
+	}
+}
+
+// Add limits the paths starting with prefix (e.g., "/api/user/login") with limiter
+func (rrl *RouteRateLimiter) Add(prefix string, limiter *RateLimiter) {
+	rrl.mutex.Lock()
+	defer rrl.mutex.Unlock()
+
+	rrl.routes[prefix] = limiter
+}
+
+// Limiter returns the limiter of the longest prefix of path, or the default limiter when none matches
+func (rrl *RouteRateLimiter) Limiter(path string) *RateLimiter {
+	rrl.mutex.RLock()
+	defer rrl.mutex.RUnlock()
+
+	limiter, longest := rrl.defaultLimit, -1
+	for prefix, l := range rrl.routes {
+		if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+			limiter, longest = l, len(prefix)
+		}
+	}
+
+	return limiter
+}
+
 // NewLoginAttemptTracker creates a new login attempt tracker
-// maxAttempts: maximum failed attempts before blocking (e.g., 6)
-// blockDuration: duration to block IP after max attempts (e.g., 15 minutes)
+// maxAttempts: maximum failed attempts before blocking (e.g., 6), from an IP or on an account
+// blockDuration: duration to block IP or account after max attempts (e.g., 15 minutes)
 func NewLoginAttemptTracker(maxAttempts int, blockDuration time.Duration) *LoginAttemptTracker {
 	lat := &LoginAttemptTracker{
 		attempts:        make(map[string]*loginAttemptEntry),
+		accounts:        make(map[string]*loginAttemptEntry),
 		maxAttempts:     maxAttempts,
 		blockDuration:   blockDuration,
 		cleanupInterval: blockDuration * 2, // Clean up entries older than 2 block durations
@@ -149,47 +405,106 @@ func NewLoginAttemptTracker(maxAttempts int, blockDuration time.Duration) *Login
 	return lat
 }
 
-// RecordFailedAttempt records a failed login attempt for the given IP
-func (lat *LoginAttemptTracker) RecordFailedAttempt(ip string) {
+// loginAccount returns the key of the account given to the tracker, empty for the IP-only calls
+func loginAccount(email []string) string {
+	if len(email) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[0]))
+}
+
+// SetAllowlist exempts the addresses of the allowlist from the login blocks
+func (lat *LoginAttemptTracker) SetAllowlist(allowlist *IPAllowlist) {
+	lat.mutex.Lock()
+	defer lat.mutex.Unlock()
+
+	lat.allowlist = allowlist
+}
+
+// Addr returns the address the login attempts of the request count against, or an empty address when
+// the request comes from an allowlisted peer, its attempts then counting against the account alone
+func (lat *LoginAttemptTracker) Addr(r *http.Request) string {
+	lat.mutex.RLock()
+	defer lat.mutex.RUnlock()
+
+	if lat.allowlist.Contains(getPeerAddr(r)) {
+		return ""
+	}
+
+	return getRemoteAddr(r)
+}
+
+// RecordFailedAttempt records a failed login attempt for the given IP, as returned by Addr, and for
+// the account of the given email when there is one, which must be of an existing user
+func (lat *LoginAttemptTracker) RecordFailedAttempt(ip string, email ...string) {
 	lat.mutex.Lock()
 	defer lat.mutex.Unlock()
 
 	now := time.Now()
-	entry, exists := lat.attempts[ip]
+
+	if ip != "" {
+		lat.recordFailedAttempt(lat.attempts, ip, now)
+	}
+
+	if account := loginAccount(email); account != "" {
+		lat.recordFailedAttempt(lat.accounts, account, now)
+	}
+}
+
+func (lat *LoginAttemptTracker) recordFailedAttempt(entries map[string]*loginAttemptEntry, key string, now time.Time) {
+	entry, exists := entries[key]
 
 	if !exists {
 		entry = &loginAttemptEntry{
 			failedAttempts: 0,
 			lastAttempt:     now,
 		}
-		lat.attempts[ip] = entry
+		entries[key] = entry
 	}
 
 	entry.failedAttempts++
 	entry.lastAttempt = now
 
-	// If threshold reached, block the IP
+	// If threshold reached, block the IP or account
 	if entry.failedAttempts >= lat.maxAttempts {
 		blockedUntil := now.Add(lat.blockDuration)
 		entry.blockedUntil = &blockedUntil
 	}
 }
 
-// RecordSuccess resets failed attempts for a successful login
-func (lat *LoginAttemptTracker) RecordSuccess(ip string) {
+// RecordSuccess resets failed attempts for a successful login, of the IP and of the account of the given email
+func (lat *LoginAttemptTracker) RecordSuccess(ip string, email ...string) {
 	lat.mutex.Lock()
 	defer lat.mutex.Unlock()
 
 	// Reset attempts on successful login
 	delete(lat.attempts, ip)
+
+	if account := loginAccount(email); account != "" {
+		delete(lat.accounts, account)
+	}
 }
 
-// IsBlocked checks if the IP is currently blocked
-func (lat *LoginAttemptTracker) IsBlocked(ip string) bool {
-	lat.mutex.RLock()
-	defer lat.mutex.RUnlock()
+// IsBlocked checks if the IP is currently blocked, or the account of the given email when there is one
+func (lat *LoginAttemptTracker) IsBlocked(ip string, email ...string) bool {
+	lat.mutex.Lock()
+	defer lat.mutex.Unlock()
 
-	entry, exists := lat.attempts[ip]
+	now := time.Now()
+
+	if ip != "" && lat.isBlocked(lat.attempts, ip, now) {
+		return true
+	}
+
+	if account := loginAccount(email); account != "" && lat.isBlocked(lat.accounts, account, now) {
+		return true
+	}
+
+	return false
+}
+
+func (lat *LoginAttemptTracker) isBlocked(entries map[string]*loginAttemptEntry, key string, now time.Time) bool {
+	entry, exists := entries[key]
 	if !exists {
 		return false
 	}
@@ -199,7 +514,7 @@ func (lat *LoginAttemptTracker) IsBlocked(ip string) bool {
 	}
 
 	// Check if block has expired
-	if time.Now().After(*entry.blockedUntil) {
+	if now.After(*entry.blockedUntil) {
 		// Block expired, but keep entry for tracking
 		entry.blockedUntil = nil
 		entry.failedAttempts = 0
@@ -209,13 +524,25 @@ func (lat *LoginAttemptTracker) IsBlocked(ip string) bool {
 	return true
 }
 
-// GetRemainingBlockTime returns the remaining block time for an IP, or 0 if not blocked
-func (lat *LoginAttemptTracker) GetRemainingBlockTime(ip string) time.Duration {
+// GetRemainingBlockTime returns the remaining block time for an IP, or for the account of the given
+// email when it is blocked longer, or 0 if not blocked
+func (lat *LoginAttemptTracker) GetRemainingBlockTime(ip string, email ...string) time.Duration {
 	lat.mutex.RLock()
 	defer lat.mutex.RUnlock()
 
-	entry, exists := lat.attempts[ip]
-	if !exists || entry.blockedUntil == nil {
+	remaining := remainingBlockTime(lat.attempts[ip])
+
+	if account := loginAccount(email); account != "" {
+		if r := remainingBlockTime(lat.accounts[account]); r > remaining {
+			remaining = r
+		}
+	}
+
+	return remaining
+}
+
+func remainingBlockTime(entry *loginAttemptEntry) time.Duration {
+	if entry == nil || entry.blockedUntil == nil {
 		return 0
 	}
 
@@ -235,18 +562,32 @@ func (lat *LoginAttemptTracker) cleanup() {
 	for range ticker.C {
 		lat.mutex.Lock()
 		now := time.Now()
-		for ip, entry := range lat.attempts {
-			// Remove if block expired and no recent activity
-			if entry.blockedUntil != nil && now.After(*entry.blockedUntil) {
-				if now.Sub(entry.lastAttempt) > lat.cleanupInterval {
-					delete(lat.attempts, ip)
-				}
+		lat.removeIdle(now)
+		lat.mutex.Unlock()
+	}
+}
+
+// removeIdle removes the entries without attempt for longer than the cleanup interval, blocked or not,
+// the blocks lasting less than the interval
+func (lat *LoginAttemptTracker) removeIdle(now time.Time) {
+	for _, entries := range []map[string]*loginAttemptEntry{lat.attempts, lat.accounts} {
+		for key, entry := range entries {
+			if now.Sub(entry.lastAttempt) > lat.cleanupInterval {
+				delete(entries, key)
 			}
 		}
-		lat.mutex.Unlock()
 	}
 }
 
+// getPeerAddr returns the address of the peer of the connection, which unlike the forwarded headers
+// read by getRemoteAddr the client cannot choose
+func getPeerAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
 // getRemoteAddr extracts the remote IP address from the request
 func getRemoteAddr(r *http.Request) string {
 	// Check X-Forwarded-For header first (for reverse proxies)
@@ -275,11 +616,68 @@ func getRemoteAddr(r *http.Request) string {
 func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getRemoteAddr(r)
+			if limitByIp(limiter, w, r) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RouteRateLimitMiddleware rate limits each request with the limiter of the longest path prefix it matches
+func RouteRateLimitMiddleware(routes *RouteRateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limitByIp(routes.Limiter(r.URL.Path), w, r) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitByIp answers 429 and returns true when the limiter throttles the address of the request
+func limitByIp(limiter *RateLimiter, w http.ResponseWriter, r *http.Request) bool {
+	ip := getRemoteAddr(r)
+
+	if limiter.Exempts(r) || limiter.Allow(ip) {
+		return false
+	}
+
+	retryAfter := limiter.windowDuration
+	if limiter.mode == RateLimitTokenBucket {
+		retryAfter = limiter.RetryAfter(ip)
+	}
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": "Too many requests. Please try again later.",
+	})
+	return true
+}
+
+// KeyedRateLimitMiddleware rate limits requests by the key returned for each request, e.g. the user
+// behind it, so a single user cannot monopolize expensive endpoints from several addresses
+func KeyedRateLimitMiddleware(limiter *RateLimiter, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+
+			if !limiter.Allow(k) {
+				retryAfter := limiter.RetryAfter(k)
+				if retryAfter < time.Second {
+					retryAfter = time.Second
+				}
 
-			if !limiter.Allow(ip) {
 				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", limiter.windowDuration.Seconds()))
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
 				w.WriteHeader(http.StatusTooManyRequests)
 				json.NewEncoder(w).Encode(map[string]string{
 					"error": "Too many requests. Please try again later.",
@@ -297,23 +695,10 @@ func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
 func LoginAttemptMiddleware(tracker *LoginAttemptTracker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getRemoteAddr(r)
+			ip := tracker.Addr(r)
 
 			if tracker.IsBlocked(ip) {
-				remaining := tracker.GetRemainingBlockTime(ip)
-				remainingSeconds := int(remaining.Seconds())
-				
-				// Return JSON error with redirect URL for client to handle
-				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", remaining.Seconds()))
-				w.WriteHeader(http.StatusTooManyRequests)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"error":       "Too many failed login attempts. IP address temporarily blocked.",
-					"blocked":     true,
-					"redirectTo":  fmt.Sprintf("/login-blocked?seconds=%d", remainingSeconds),
-					"retryAfter":  remainingSeconds,
-					"blockedUntil": remaining.String(),
-				})
+				writeLoginBlocked(w, tracker.GetRemainingBlockTime(ip), "IP address")
 				return
 			}
 
@@ -322,3 +707,20 @@ func LoginAttemptMiddleware(tracker *LoginAttemptTracker) func(http.Handler) htt
 	}
 }
 
+// writeLoginBlocked returns the JSON error of a blocked login, the subject being the IP address or the account
+func writeLoginBlocked(w http.ResponseWriter, remaining time.Duration, subject string) {
+	remainingSeconds := int(remaining.Seconds())
+
+	// Return JSON error with redirect URL for client to handle
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", fmt.Sprintf("%.0f", remaining.Seconds()))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":       fmt.Sprintf("Too many failed login attempts. %s temporarily blocked.", subject),
+		"blocked":     true,
+		"redirectTo":  fmt.Sprintf("/login-blocked?seconds=%d", remainingSeconds),
+		"retryAfter":  remainingSeconds,
+		"blockedUntil": remaining.String(),
+	})
+}
+
@@ -0,0 +1,340 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHeavyEndpointRateLimitPerUser(t *testing.T) {
+	controller := &Controller{Users: NewUsers(), HeavyRateLimiter: NewRateLimiter(2, time.Minute)}
+	for id, pin := range map[uint64]string{1: "PIN-ONE", 2: "PIN-TWO"} {
+		user := &User{Id: id, Pin: pin}
+		controller.Users.users[id] = user
+		controller.Users.pins[pin] = user
+	}
+	api := &Api{Controller: controller}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	heavy := KeyedRateLimitMiddleware(controller.HeavyRateLimiter, api.rateLimitKey)(ok)
+	light := ok
+
+	request := func(handler http.Handler, pin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/transcripts", nil)
+		r.RemoteAddr = "192.0.2.1:5000" // every user behind the same address
+		r.Header.Set("Authorization", "Bearer "+pin)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request(heavy, "PIN-ONE"); w.Code != http.StatusOK {
+			t.Fatalf("request %d should be allowed, got %d", i, w.Code)
+		}
+	}
+
+	w := request(heavy, "PIN-ONE")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third heavy request should be throttled, got %d", w.Code)
+	}
+	if seconds, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || seconds < 1 || seconds > 60 {
+		t.Errorf("unexpected Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	if w := request(heavy, "PIN-TWO"); w.Code != http.StatusOK {
+		t.Errorf("another user behind the same address should not be throttled, got %d", w.Code)
+	}
+
+	if w := request(light, "PIN-ONE"); w.Code != http.StatusOK {
+		t.Errorf("light endpoints should not be throttled, got %d", w.Code)
+	}
+}
+
+func TestTokenBucketRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewTokenBucketRateLimiter(2, 3) // 2 requests per second, bursts of 3
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("192.0.2.1") {
+			t.Fatalf("request %d of the burst should be allowed", i)
+		}
+	}
+	if limiter.Allow("192.0.2.1") {
+		t.Fatal("request past the burst should be throttled")
+	}
+	if retryAfter := limiter.RetryAfter("192.0.2.1"); retryAfter != 500*time.Millisecond {
+		t.Errorf("expected to retry after a token is refilled, got %v", retryAfter)
+	}
+	if !limiter.Allow("192.0.2.2") {
+		t.Error("another address should have its own bucket")
+	}
+
+	// refilled continuously, not at a window boundary
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.Allow("192.0.2.1") {
+		t.Fatal("a refilled token should be allowed")
+	}
+	if limiter.Allow("192.0.2.1") {
+		t.Fatal("only one token should have been refilled")
+	}
+
+	// never refilled past the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("192.0.2.1") {
+			t.Fatalf("request %d of the refilled burst should be allowed", i)
+		}
+	}
+	if limiter.Allow("192.0.2.1") {
+		t.Error("the bucket should hold the burst at most")
+	}
+}
+
+func TestFixedWindowRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow("192.0.2.1") {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if limiter.Allow("192.0.2.1") {
+		t.Fatal("request past the limit should be throttled")
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if !limiter.Allow("192.0.2.1") {
+		t.Error("the window should reset once expired")
+	}
+}
+
+func TestRouteRateLimitMiddleware(t *testing.T) {
+	routes := NewRouteRateLimiter(NewRateLimiter(3, time.Minute))
+	routes.Add("/api/user/login", NewRateLimiter(1, time.Minute))
+	routes.Add("/api/call", NewRateLimiter(2, time.Minute))
+	routes.Add("/api/call-upload", NewRateLimiter(4, time.Minute))
+
+	handler := RouteRateLimitMiddleware(routes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	allowed := func(path string) int {
+		n := 0
+		for i := 0; i < 5; i++ {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.RemoteAddr = "192.0.2.1:5000"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code == http.StatusOK {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, route := range []struct {
+		path    string
+		allowed int
+	}{
+		{"/api/user/login", 1},
+		{"/api/call/12", 2},
+		{"/api/call-upload", 4}, // the longest prefix wins
+		{"/api/alerts", 3},      // the default limit
+		{"/api/transcripts", 0}, // the default limit already spent by the same address
+	} {
+		if n := allowed(route.path); n != route.allowed {
+			t.Errorf("%s: expected %d requests allowed, got %d", route.path, route.allowed, n)
+		}
+	}
+}
+
+func TestIPAllowlist(t *testing.T) {
+	allowlist, err := NewIPAllowlist("192.0.2.10, 10.0.0.0/8,2001:db8::/32, ::1, not-an-ip, 10.0.0.0/33")
+	if err == nil || err.Error() != "invalid allowlist entries not-an-ip, 10.0.0.0/33" {
+		t.Errorf("expected the invalid entries reported, got %v", err)
+	}
+
+	for addr, want := range map[string]bool{
+		"192.0.2.10":    true,
+		"192.0.2.11":    false,
+		"10.1.2.3":      true,
+		"11.0.0.1":      false,
+		"2001:db8::42":  true,
+		"[2001:db8::1]": true, // as left by getRemoteAddr
+		"[::1]":         true,
+		"2001:db9::1":   false,
+		"user:7":        false,
+	} {
+		if got := allowlist.Contains(addr); got != want {
+			t.Errorf("%s: expected allowlisted %t, got %t", addr, want, got)
+		}
+	}
+
+	var none *IPAllowlist
+	if none.Contains("192.0.2.10") {
+		t.Error("expected nothing allowlisted without an allowlist")
+	}
+}
+
+func TestRateLimiterAllowlist(t *testing.T) {
+	allowlist, err := NewIPAllowlist("192.0.2.10,198.51.100.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := NewRateLimiter(1, time.Minute)
+	limiter.SetAllowlist(allowlist)
+
+	tracker := NewLoginAttemptTracker(1, time.Minute)
+	tracker.SetAllowlist(allowlist)
+
+	handler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, peer := range []string{"192.0.2.10", "198.51.100.77", "203.0.113.1"} {
+		allowlisted := peer != "203.0.113.1"
+
+		for i := 0; i < 3; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = peer + ":5000"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if allowed, want := w.Code == http.StatusOK, allowlisted || i == 0; allowed != want {
+				t.Errorf("%s: expected request %d allowed %t, got %t", peer, i, want, allowed)
+			}
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/user/login", nil)
+		r.RemoteAddr = peer + ":5000"
+		tracker.RecordFailedAttempt(tracker.Addr(r))
+		if blocked := tracker.IsBlocked(tracker.Addr(r)); blocked == allowlisted {
+			t.Errorf("%s: expected blocked %t after a failed login, got %t", peer, !allowlisted, blocked)
+		}
+	}
+}
+
+func TestRateLimiterAllowlistSpoofedHeaders(t *testing.T) {
+	allowlist, err := NewIPAllowlist("192.0.2.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := NewRateLimiter(1, time.Minute)
+	limiter.SetAllowlist(allowlist)
+
+	tracker := NewLoginAttemptTracker(1, time.Minute)
+	tracker.SetAllowlist(allowlist)
+
+	handler := LoginAttemptMiddleware(tracker)(RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracker.RecordFailedAttempt(tracker.Addr(r), "jane@example.com")
+		w.WriteHeader(http.StatusUnauthorized)
+	})))
+
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/login", nil)
+		r.RemoteAddr = "203.0.113.1:5000"
+		r.Header.Set(header, "192.0.2.10")
+
+		if tracker.Addr(r) == "" {
+			t.Errorf("%s: expected the login attempts of a spoofed address counted", header)
+		}
+
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if i == 1 && w.Code != http.StatusTooManyRequests {
+				t.Errorf("%s: expected a spoofed allowlisted address limited, got %d", header, w.Code)
+			}
+		}
+
+		tracker.RecordSuccess("192.0.2.10", "jane@example.com")
+	}
+}
+
+func TestLoginAttemptTrackerPerAccount(t *testing.T) {
+	tracker := NewLoginAttemptTracker(3, time.Minute)
+
+	// an attacker rotating IPs blocks the account, not the IPs
+	for i := 0; i < 3; i++ {
+		tracker.RecordFailedAttempt("203.0.113."+strconv.Itoa(i), "Jane@Example.com")
+	}
+	if !tracker.IsBlocked("198.51.100.1", "jane@example.com") {
+		t.Error("expected the account blocked from any IP")
+	}
+	if tracker.IsBlocked("203.0.113.0") || tracker.IsBlocked("198.51.100.1", "john@example.com") {
+		t.Error("expected the IPs and the other accounts not blocked")
+	}
+	if remaining := tracker.GetRemainingBlockTime("198.51.100.1", "jane@example.com"); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("unexpected remaining block time %v", remaining)
+	}
+
+	// the typos of several users behind one NAT block the IP, whatever the accounts
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		tracker.RecordFailedAttempt("192.0.2.1", email)
+	}
+	if !tracker.IsBlocked("192.0.2.1") || !tracker.IsBlocked("192.0.2.1", "d@example.com") {
+		t.Error("expected the IP blocked")
+	}
+	if tracker.IsBlocked("192.0.2.2", "a@example.com") {
+		t.Error("expected the accounts not blocked under their threshold")
+	}
+
+	// a successful login resets both
+	tracker.RecordFailedAttempt("192.0.2.3", "e@example.com")
+	tracker.RecordFailedAttempt("192.0.2.3", "e@example.com")
+	tracker.RecordSuccess("192.0.2.3", "e@example.com")
+	tracker.RecordFailedAttempt("192.0.2.3", "e@example.com")
+	if tracker.IsBlocked("192.0.2.3", "e@example.com") {
+		t.Error("expected the attempts reset by the successful login")
+	}
+}
+
+func TestLoginAttemptTrackerRemoveIdle(t *testing.T) {
+	tracker := NewLoginAttemptTracker(3, time.Minute)
+
+	tracker.RecordFailedAttempt("192.0.2.1", "jane@example.com")
+	for i := 0; i < 3; i++ {
+		tracker.RecordFailedAttempt("192.0.2.2", "john@example.com")
+	}
+
+	now := time.Now()
+
+	tracker.mutex.Lock()
+	tracker.removeIdle(now.Add(time.Minute))
+	if len(tracker.attempts) != 2 || len(tracker.accounts) != 2 {
+		t.Errorf("expected the recent entries kept, got %d ips and %d accounts", len(tracker.attempts), len(tracker.accounts))
+	}
+
+	// never blocked or not, the entries idle longer than the cleanup interval are removed
+	tracker.removeIdle(now.Add(tracker.cleanupInterval + time.Second))
+	if len(tracker.attempts) != 0 || len(tracker.accounts) != 0 {
+		t.Errorf("expected the idle entries removed, got %d ips and %d accounts", len(tracker.attempts), len(tracker.accounts))
+	}
+	tracker.mutex.Unlock()
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// refIntegerMax is the largest ref an integer column holds, systemRef, talkgroupRef and the refs of units
+	refIntegerMax = math.MaxInt32
+
+	// refBigintMax is the largest ref a bigint column holds, the unitRef of callUnits
+	refBigintMax = math.MaxInt64
+)
+
+// validRef reports whether a ref fits a column holding up to max. Negative refs parsed into a uint
+// wrap around to huge values, so they fail the same check.
+func validRef(ref uint, max uint64) bool {
+	return uint64(ref) <= max
+}
+
+// refValueError describes a ref of the configuration that isn't a whole number between 0 and max
+func refValueError(v any, max uint64) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		switch {
+		case v < 0:
+			return fmt.Sprintf("%v is negative", v)
+		case v != math.Trunc(v):
+			return fmt.Sprintf("%v is not a whole number", v)
+		case v > float64(max):
+			return fmt.Sprintf("%v exceeds %d", v, max)
+		}
+		return ""
+	default:
+		return fmt.Sprintf("%v is not a number", v)
+	}
+}
+
+// configRefErrors lists the refs of the systems, talkgroups and units of a configuration which
+// their columns can't hold. It checks the decoded json since negative or fractional refs are
+// already lost once converted.
+func configRefErrors(systems []any) []string {
+	errs := []string{}
+
+	check := func(where string, key string, m map[string]any) {
+		if e := refValueError(m[key], refIntegerMax); e != "" {
+			errs = append(errs, fmt.Sprintf("%s: %s %s", where, key, e))
+		}
+	}
+
+	for _, s := range systems {
+		system, ok := s.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		label, _ := system["label"].(string)
+		where := fmt.Sprintf("system %s", label)
+
+		check(where, "systemRef", system)
+
+		if talkgroups, ok := system["talkgroups"].([]any); ok {
+			for _, t := range talkgroups {
+				if talkgroup, ok := t.(map[string]any); ok {
+					label, _ := talkgroup["label"].(string)
+					check(fmt.Sprintf("%s talkgroup %s", where, label), "talkgroupRef", talkgroup)
+				}
+			}
+		}
+
+		if units, ok := system["units"].([]any); ok {
+			for _, u := range units {
+				if unit, ok := u.(map[string]any); ok {
+					label, _ := unit["label"].(string)
+					for _, key := range []string{"unitRef", "unitFrom", "unitTo"} {
+						check(fmt.Sprintf("%s unit %s", where, label), key, unit)
+					}
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// dropInvalidUnitRefs removes the units of the call whose ref doesn't fit the callUnits column and
+// returns them, e.g. the -1 sent by Trunk Recorder for an unknown radio
+func (call *Call) dropInvalidUnitRefs() []uint {
+	dropped := []uint{}
+
+	drop := func(unitRef uint) {
+		for _, d := range dropped {
+			if d == unitRef {
+				return
+			}
+		}
+		dropped = append(dropped, unitRef)
+	}
+
+	units := call.Units[:0]
+	for _, unit := range call.Units {
+		if validRef(unit.UnitRef, refBigintMax) {
+			units = append(units, unit)
+		} else {
+			drop(unit.UnitRef)
+		}
+	}
+	call.Units = units
+
+	// the labels are matched to the refs by index
+	unitRefs := call.Meta.UnitRefs[:0]
+	unitLabels := []string{}
+	for i, unitRef := range call.Meta.UnitRefs {
+		if !validRef(unitRef, refBigintMax) {
+			drop(unitRef)
+			continue
+		}
+		unitRefs = append(unitRefs, unitRef)
+		if i < len(call.Meta.UnitLabels) {
+			unitLabels = append(unitLabels, call.Meta.UnitLabels[i])
+		}
+	}
+	if len(call.Meta.UnitLabels) > 0 {
+		call.Meta.UnitLabels = unitLabels
+	}
+	call.Meta.UnitRefs = unitRefs
+
+	return dropped
+}
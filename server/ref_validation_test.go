@@ -0,0 +1,105 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConfigRefErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		ref   any
+		valid bool
+	}{
+		{"in range", float64(12345), true},
+		{"zero", float64(0), true},
+		{"column capacity", float64(math.MaxInt32), true},
+		{"over capacity", float64(math.MaxInt32) + 1, false},
+		{"negative", float64(-1), false},
+		{"fractional", 1.5, false},
+		{"not a number", "100", false},
+	}
+
+	for _, c := range cases {
+		for _, systems := range [][]any{
+			{map[string]any{"label": "County", "systemRef": c.ref}},
+			{map[string]any{"label": "County", "systemRef": float64(1), "talkgroups": []any{
+				map[string]any{"label": "Fire", "talkgroupRef": c.ref},
+			}}},
+			{map[string]any{"label": "County", "systemRef": float64(1), "units": []any{
+				map[string]any{"label": "Engine 5", "unitRef": c.ref},
+			}}},
+		} {
+			if errs := configRefErrors(systems); (len(errs) == 0) != c.valid {
+				t.Errorf("%s: expected valid %v, got %v", c.name, c.valid, errs)
+			}
+		}
+	}
+}
+
+func TestCallRefValidation(t *testing.T) {
+	call := func(systemId uint, talkgroupId uint) *Call {
+		call := NewCall()
+		call.Audio = make([]byte, 100)
+		call.Timestamp = time.Now()
+		call.SystemId = systemId
+		call.TalkgroupId = talkgroupId
+		return call
+	}
+
+	cases := []struct {
+		name  string
+		call  *Call
+		valid bool
+	}{
+		{"in range", call(1, 100), true},
+		{"column capacity", call(1, math.MaxInt32), true},
+		{"zero", call(1, 0), false},
+		{"over capacity system", call(math.MaxInt32+1, 100), false},
+		{"over capacity talkgroup", call(1, math.MaxInt32+1), false},
+	}
+
+	for _, c := range cases {
+		if ok, err := c.call.IsValid(); ok != c.valid {
+			t.Errorf("%s: expected valid %v, got %v %v", c.name, c.valid, ok, err)
+		}
+	}
+
+	// -1 sent for an unknown radio wraps around once parsed
+	unknown := uint(math.MaxUint64)
+
+	units := call(1, 100)
+	units.Units = []CallUnit{{UnitRef: 0}, {UnitRef: 4000000000}, {UnitRef: unknown, Offset: 1}}
+	units.Meta.UnitRefs = []uint{0, 4000000000, unknown}
+	units.Meta.UnitLabels = []string{"", "Engine 5", "Unknown"}
+
+	dropped := units.dropInvalidUnitRefs()
+
+	if len(dropped) != 1 || dropped[0] != unknown {
+		t.Errorf("expected the unknown unit to be dropped once, got %v", dropped)
+	}
+
+	if len(units.Units) != 2 || len(units.Meta.UnitRefs) != 2 || units.Meta.UnitLabels[1] != "Engine 5" {
+		t.Errorf("expected the units in range to be kept with their labels, got %v %v %v", units.Units, units.Meta.UnitRefs, units.Meta.UnitLabels)
+	}
+
+	if !validRef(4000000000, refBigintMax) || validRef(4000000000, refIntegerMax) {
+		t.Error("a large unit id fits a call unit but not the units table")
+	}
+}
@@ -42,6 +42,7 @@ type RegistrationCode struct {
 	IsOneTime   bool
 	IsActive    bool
 	CreatedAt   int64
+	LastUsedAt  int64
 }
 
 type RegistrationCodes struct {
@@ -143,7 +144,7 @@ func (rcs *RegistrationCodes) Load(db *Database) error {
 	rcs.mutex.Lock()
 	defer rcs.mutex.Unlock()
 
-	rows, err := db.Sql.Query(`SELECT "registrationCodeId", "code", "userGroupId", "createdBy", "expiresAt", "maxUses", "currentUses", "isOneTime", "isActive", "createdAt" FROM "registrationCodes"`)
+	rows, err := db.Sql.Query(`SELECT "registrationCodeId", "code", "userGroupId", "createdBy", "expiresAt", "maxUses", "currentUses", "isOneTime", "isActive", "createdAt", "lastUsedAt" FROM "registrationCodes"`)
 	if err != nil {
 		return err
 	}
@@ -168,6 +169,7 @@ func (rcs *RegistrationCodes) Load(db *Database) error {
 			&code.IsOneTime,
 			&code.IsActive,
 			&createdAt,
+			&code.LastUsedAt,
 		)
 		if err != nil {
 			log.Printf("Error loading registration code: %v", err)
@@ -229,14 +231,15 @@ func (rcs *RegistrationCodes) Use(code string, db *Database) error {
 	}
 
 	regCode.CurrentUses++
+	regCode.LastUsedAt = time.Now().Unix()
 
 	if regCode.IsOneTime {
 		regCode.IsActive = false
 	}
 
 	_, err := db.Sql.Exec(
-		`UPDATE "registrationCodes" SET "currentUses" = $1, "isActive" = $2 WHERE "registrationCodeId" = $3`,
-		regCode.CurrentUses, regCode.IsActive, regCode.Id,
+		`UPDATE "registrationCodes" SET "currentUses" = $1, "isActive" = $2, "lastUsedAt" = $3 WHERE "registrationCodeId" = $4`,
+		regCode.CurrentUses, regCode.IsActive, regCode.LastUsedAt, regCode.Id,
 	)
 
 	if err != nil {
@@ -261,11 +264,26 @@ func (rcs *RegistrationCodes) Add(code *RegistrationCode, db *Database) error {
 		createdBy = code.CreatedBy
 	}
 	
-	err := db.Sql.QueryRow(
-		`INSERT INTO "registrationCodes" ("code", "userGroupId", "createdBy", "expiresAt", "maxUses", "currentUses", "isOneTime", "isActive", "createdAt") 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "registrationCodeId"`,
-		code.Code, code.UserGroupId, createdBy, code.ExpiresAt, code.MaxUses, code.CurrentUses, code.IsOneTime, code.IsActive, code.CreatedAt,
-	).Scan(&id)
+	const maxAttempts = 5
+
+	var err error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = db.Sql.QueryRow(
+			`INSERT INTO "registrationCodes" ("code", "userGroupId", "createdBy", "expiresAt", "maxUses", "currentUses", "isOneTime", "isActive", "createdAt") 
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "registrationCodeId"`,
+			code.Code, code.UserGroupId, createdBy, code.ExpiresAt, code.MaxUses, code.CurrentUses, code.IsOneTime, code.IsActive, code.CreatedAt,
+		).Scan(&id)
+
+		if !isUniqueViolation(err) || attempt == maxAttempts {
+			break
+		}
+
+		// The code was taken in the meantime, codes are random so draw another one
+		if code.Code, err = generateRegistrationCode(); err != nil {
+			return err
+		}
+	}
 
 	if err != nil {
 		return err
@@ -305,6 +323,50 @@ func (rcs *RegistrationCodes) Delete(id uint64, db *Database) error {
 	return nil
 }
 
+// Spent reports whether the code expired or was used up before the cutoff, in seconds. A code
+// disabled by an admin isn't spent, it may be enabled again.
+func (code *RegistrationCode) Spent(cutoff int64) bool {
+	if code.ExpiresAt > 0 && code.ExpiresAt < cutoff {
+		return true
+	}
+
+	usedUp := (code.IsOneTime && !code.IsActive && code.CurrentUses > 0) || (code.MaxUses > 0 && code.CurrentUses >= code.MaxUses)
+	if !usedUp {
+		return false
+	}
+
+	// Codes used up before lastUsedAt was recorded only have their creation date
+	usedAt := code.LastUsedAt
+	if usedAt == 0 {
+		usedAt = code.CreatedAt
+	}
+
+	return usedAt < cutoff
+}
+
+// Purge deletes the codes spent before the cutoff, in seconds, and returns how many were deleted
+func (rcs *RegistrationCodes) Purge(db *Database, cutoff int64) (int64, error) {
+	ids := []uint64{}
+
+	rcs.mutex.RLock()
+	for _, code := range rcs.codes {
+		if code.Spent(cutoff) {
+			ids = append(ids, code.Id)
+		}
+	}
+	rcs.mutex.RUnlock()
+
+	var purged int64
+	for _, id := range ids {
+		if err := rcs.Delete(id, db); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
 func (rcs *RegistrationCodes) GetAll() []*RegistrationCode {
 	rcs.mutex.RLock()
 	defer rcs.mutex.RUnlock()
@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// registrationPurgeCutoff returns the time in seconds before which spent registration codes and
+// invitations are deleted, they are kept retentionDays for auditing
+func registrationPurgeCutoff(retentionDays uint, now time.Time) int64 {
+	return now.Add(-24 * time.Hour * time.Duration(retentionDays)).Unix()
+}
+
+// invitationSpent reports whether the invitation was used or expired before the cutoff, in seconds
+func invitationSpent(status string, usedAt int64, expiresAt int64, cutoff int64) bool {
+	if usedAt > 0 || status == "used" {
+		return usedAt < cutoff
+	}
+
+	return expiresAt > 0 && expiresAt < cutoff
+}
+
+// PurgeInvitations deletes the invitations spent before the cutoff, in seconds, and returns how
+// many were deleted. Pending invitations not yet expired are kept.
+func PurgeInvitations(db *Database, cutoff int64) (int64, error) {
+	rows, err := db.Sql.Query(`SELECT "userInvitationId", "status", "usedAt", "expiresAt" FROM "userInvitations"`)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var (
+			id        int64
+			status    string
+			usedAt    sql.NullInt64
+			expiresAt sql.NullInt64
+		)
+		if err := rows.Scan(&id, &status, &usedAt, &expiresAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if invitationSpent(status, usedAt.Int64, expiresAt.Int64, cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, id := range ids {
+		if _, err := db.Sql.Exec(`DELETE FROM "userInvitations" WHERE "userInvitationId" = $1`, id); err != nil {
+			return purged, fmt.Errorf("failed to delete invitation %d: %v", id, err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
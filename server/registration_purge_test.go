@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistrationCodeSpent(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := registrationPurgeCutoff(30, now)
+
+	old := now.Add(-40 * 24 * time.Hour).Unix()
+	recent := now.Add(-10 * 24 * time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		code  RegistrationCode
+		spent bool
+	}{
+		{"expired past grace", RegistrationCode{IsActive: true, ExpiresAt: old, CreatedAt: old}, true},
+		{"expired recently", RegistrationCode{IsActive: true, ExpiresAt: recent, CreatedAt: old}, false},
+		{"never expires", RegistrationCode{IsActive: true, CreatedAt: old}, false},
+		{"one time used past grace", RegistrationCode{IsOneTime: true, CurrentUses: 1, LastUsedAt: old, CreatedAt: old}, true},
+		{"one time used recently", RegistrationCode{IsOneTime: true, CurrentUses: 1, LastUsedAt: recent, CreatedAt: old}, false},
+		{"max uses reached past grace", RegistrationCode{IsActive: true, MaxUses: 5, CurrentUses: 5, LastUsedAt: old, CreatedAt: old}, true},
+		{"max uses not reached", RegistrationCode{IsActive: true, MaxUses: 5, CurrentUses: 4, LastUsedAt: old, CreatedAt: old}, false},
+		{"used up before lastUsedAt existed", RegistrationCode{IsOneTime: true, CurrentUses: 1, CreatedAt: old}, true},
+		{"disabled by an admin", RegistrationCode{IsActive: false, CreatedAt: old}, false},
+	}
+
+	for _, test := range tests {
+		if spent := test.code.Spent(cutoff); spent != test.spent {
+			t.Errorf("%s: Spent() = %v, want %v", test.name, spent, test.spent)
+		}
+	}
+}
+
+func TestRegistrationCodesPurgeKeepsRecent(t *testing.T) {
+	now := time.Now()
+	cutoff := registrationPurgeCutoff(30, now)
+
+	rcs := NewRegistrationCodes()
+	rcs.codes["RECENT"] = &RegistrationCode{Id: 1, Code: "RECENT", IsOneTime: true, CurrentUses: 1, LastUsedAt: now.Unix(), CreatedAt: now.Unix()}
+	rcs.codes["ACTIVE"] = &RegistrationCode{Id: 2, Code: "ACTIVE", IsActive: true, CreatedAt: now.Add(-365 * 24 * time.Hour).Unix()}
+
+	// Nothing is spent, the database isn't touched
+	purged, err := rcs.Purge(nil, cutoff)
+	if err != nil || purged != 0 {
+		t.Fatalf("Purge() = %d, %v, want nothing purged", purged, err)
+	}
+	if len(rcs.GetAll()) != 2 {
+		t.Errorf("recent and active codes should be kept, got %d", len(rcs.GetAll()))
+	}
+}
+
+func TestInvitationSpent(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := registrationPurgeCutoff(30, now)
+
+	old := now.Add(-40 * 24 * time.Hour).Unix()
+	recent := now.Add(-10 * 24 * time.Hour).Unix()
+	future := now.Add(10 * 24 * time.Hour).Unix()
+
+	tests := []struct {
+		name      string
+		status    string
+		usedAt    int64
+		expiresAt int64
+		spent     bool
+	}{
+		{"used past grace", "used", old, future, true},
+		{"used recently", "used", recent, old, false},
+		{"expired past grace", "pending", 0, old, true},
+		{"expired recently", "pending", 0, recent, false},
+		{"pending", "pending", 0, future, false},
+		{"pending without expiry", "pending", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		if spent := invitationSpent(test.status, test.usedAt, test.expiresAt, cutoff); spent != test.spent {
+			t.Errorf("%s: invitationSpent() = %v, want %v", test.name, spent, test.spent)
+		}
+	}
+}
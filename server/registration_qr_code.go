@@ -0,0 +1,123 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// registrationQRCodeSize is the width and height in pixels of the QR codes, enough to print
+	// them on a flyer or a badge without scaling them up
+	registrationQRCodeSize = 512
+
+	// registrationQRCodeLevel lets the QR codes scan with up to 30% of them smudged or covered
+	registrationQRCodeLevel = qrcode.High
+)
+
+// registrationBaseURL returns the base URL of the links to register, without its trailing slash
+func registrationBaseURL(baseURL string) string {
+	return strings.TrimRight(strings.TrimSpace(baseURL), "/")
+}
+
+// registrationQRCode returns a PNG of the QR code of the link
+func registrationQRCode(link string) ([]byte, error) {
+	png, err := qrcode.Encode(link, registrationQRCodeLevel, registrationQRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("qr code of %s: %v", link, err)
+	}
+
+	return png, nil
+}
+
+// RegistrationURL returns the link to register with the code
+func (code *RegistrationCode) RegistrationURL(baseURL string) string {
+	return registrationBaseURL(baseURL) + "/register?code=" + url.QueryEscape(code.Code)
+}
+
+// QRCode returns a PNG of the link to register with the code
+func (code *RegistrationCode) QRCode(baseURL string) ([]byte, error) {
+	return registrationQRCode(code.RegistrationURL(baseURL))
+}
+
+// RegistrationQRCodeHandler serves the QR code of the registration code of the code query
+// parameter, or of the invitation of the invite query parameter, as a PNG
+func (admin *Admin) RegistrationQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	baseURL := admin.Controller.Options.BaseUrl
+	if baseURL == "" {
+		scheme, host := getSchemeAndHost(r)
+		baseURL = fmt.Sprintf("%s://%s", scheme, host)
+	}
+
+	var (
+		png []byte
+		err error
+	)
+
+	query := r.URL.Query()
+	switch {
+	case query.Get("code") != "":
+		code := admin.Controller.RegistrationCodes.GetByCode(query.Get("code"))
+		if code == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		png, err = code.QRCode(baseURL)
+
+	case query.Get("invite") != "":
+		invitation, e := GetUserInvitation(query.Get("invite"), admin.Controller.Database)
+		if e != nil {
+			admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("registration qr code: %v", e))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if invitation == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		png, err = invitation.QRCode(baseURL)
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("registration qr code: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRegistrationCodeQRCode(t *testing.T) {
+	code := &RegistrationCode{Code: "AB#CD&EF!123"}
+
+	if link := code.RegistrationURL("https://scanner.example.com/ "); link != "https://scanner.example.com/register?code=AB%23CD%26EF%21123" {
+		t.Errorf("expected the code escaped in the link, got %s", link)
+	}
+
+	b, err := code.QRCode("https://scanner.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("expected a png, got %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != registrationQRCodeSize || size.Y != registrationQRCodeSize {
+		t.Errorf("expected a %dpx square, got %v", registrationQRCodeSize, size)
+	}
+}
+
+func TestUserInvitationQRCode(t *testing.T) {
+	invitation := &UserInvitation{Code: "inv123"}
+
+	if link := invitation.Link("https://scanner.example.com/"); link != "https://scanner.example.com/?invite=inv123" {
+		t.Errorf("expected the emailed invitation link, got %s", link)
+	}
+
+	if b, err := invitation.QRCode("https://scanner.example.com"); err != nil || !bytes.HasPrefix(b, []byte("\x89PNG")) {
+		t.Errorf("expected a png, got %v", err)
+	}
+}
+
+func TestRegistrationQRCodeHandler(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs(), RegistrationCodes: NewRegistrationCodes()}
+	controller.Options.secret = "secret"
+	controller.Options.BaseUrl = "https://scanner.example.com"
+	controller.Admin = NewAdmin(controller)
+	controller.RegistrationCodes.codes["EVENT2025"] = &RegistrationCode{Code: "EVENT2025"}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{ID: "test"}).SignedString([]byte(controller.Options.secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.Admin.Tokens = append(controller.Admin.Tokens, token)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/registration-qr-code?"+query, nil)
+		r.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.Admin.RegistrationQRCodeHandler(w, r)
+		return w
+	}
+
+	if w := get("code=event2025"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")) {
+		t.Errorf("expected the png of the registration code, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := get("code=unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown code not found, got %d", w.Code)
+	}
+	if w := get("invite=inv123"); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown invitation not found, got %d", w.Code)
+	}
+
+	d.rows = [][]driver.Value{{int64(4), "jane@example.com", int64(1), "pending", int64(0)}}
+	if w := get("invite=inv123"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("expected the png of the invitation, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := get(""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a code or an invitation required, got %d", w.Code)
+	}
+}
@@ -22,10 +22,12 @@ import (
 )
 
 type Scheduler struct {
-	Controller *Controller
-	Ticker     *time.Ticker
-	cancel     chan any
-	started    bool
+	Controller      *Controller
+	Ticker          *time.Ticker
+	EphemeralTicker *time.Ticker
+	cancel          chan any
+	lastOrphanSweep time.Time
+	started         bool
 }
 
 func NewScheduler(controller *Controller) *Scheduler {
@@ -56,6 +58,73 @@ func (scheduler *Scheduler) pruneDatabase() error {
 	return nil
 }
 
+// pruneEphemeral deletes expired calls of ephemeral talkgroups, it runs on a tighter schedule than pruneDatabase
+func (scheduler *Scheduler) pruneEphemeral() error {
+	pruned, err := scheduler.Controller.Calls.PruneEphemeral(scheduler.Controller.Database, scheduler.Controller.Systems.List)
+	if err != nil {
+		return err
+	}
+
+	if pruned > 0 {
+		scheduler.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("pruned %d expired ephemeral call(s)", pruned))
+	}
+
+	return nil
+}
+
+// sweepOrphans removes rows left behind by calls deleted outside the cascading foreign keys
+func (scheduler *Scheduler) sweepOrphans() error {
+	results, err := SweepOrphans(scheduler.Controller.Database.Sql)
+	for _, result := range results {
+		scheduler.Controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("removed %d orphaned row(s) from %s", result.Deleted, result.Table))
+	}
+
+	return err
+}
+
+// purgeRegistrations deletes the registration codes and invitations spent for longer than the retention
+func (scheduler *Scheduler) purgeRegistrations() error {
+	if scheduler.Controller.Options.RegistrationRetentionDays == 0 {
+		return nil
+	}
+
+	cutoff := registrationPurgeCutoff(scheduler.Controller.Options.RegistrationRetentionDays, time.Now())
+
+	codes, err := scheduler.Controller.RegistrationCodes.Purge(scheduler.Controller.Database, cutoff)
+	if codes > 0 {
+		scheduler.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("purged %d spent registration code(s)", codes))
+	}
+	if err != nil {
+		return fmt.Errorf("purge registration codes failed: %v", err)
+	}
+
+	invitations, err := PurgeInvitations(scheduler.Controller.Database, cutoff)
+	if invitations > 0 {
+		scheduler.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("purged %d spent invitation(s)", invitations))
+	}
+	if err != nil {
+		return fmt.Errorf("purge invitations failed: %v", err)
+	}
+
+	return nil
+}
+
+// pruneDeviceTokens deletes the device tokens unused for longer than the retention, those of the dead installs
+func (scheduler *Scheduler) pruneDeviceTokens() error {
+	if scheduler.Controller.Options.DeviceTokenRetentionDays == 0 {
+		return nil
+	}
+
+	maxAge := 24 * time.Hour * time.Duration(scheduler.Controller.Options.DeviceTokenRetentionDays)
+
+	pruned, err := scheduler.Controller.DeviceTokens.PruneStale(maxAge, scheduler.Controller.Database)
+	if pruned > 0 {
+		scheduler.Controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("pruned %d stale device token(s)", pruned))
+	}
+
+	return err
+}
+
 func (scheduler *Scheduler) run() {
 	// Run cleanup operations in background goroutines to avoid blocking the scheduler ticker
 	// This ensures the scheduler continues to run on schedule even if cleanup takes a long time
@@ -67,6 +136,48 @@ func (scheduler *Scheduler) run() {
 		}
 	}()
 
+	// Sweep orphaned call rows (runs every orphanSweepInterval hours) - runs in background
+	if orphanSweepDue(scheduler.lastOrphanSweep, time.Now(), scheduler.Controller.Options.OrphanSweepInterval) {
+		scheduler.lastOrphanSweep = time.Now()
+		go func() {
+			if err := scheduler.sweepOrphans(); err != nil {
+				scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.sweepOrphans: %s", err.Error()))
+			}
+		}()
+	}
+
+	// Purge spent registration codes and invitations past their retention - runs in background
+	go func() {
+		if err := scheduler.purgeRegistrations(); err != nil {
+			scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.purgeRegistrations: %s", err.Error()))
+		}
+	}()
+
+	// Prune the device tokens unused past their retention - runs in background
+	go func() {
+		if err := scheduler.pruneDeviceTokens(); err != nil {
+			scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.pruneDeviceTokens: %s", err.Error()))
+		}
+	}()
+
+	// Forget the suspected hallucinations no longer seen - runs in background
+	if scheduler.Controller.HallucinationDetector != nil {
+		go func() {
+			if err := scheduler.Controller.HallucinationDetector.Prune(time.Now()); err != nil {
+				scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.pruneHallucinations: %s", err.Error()))
+			}
+		}()
+	}
+
+	// Forget the admin actions and the user group changes past the audit log retention - runs in background
+	if scheduler.Controller.AuditLog != nil {
+		go func() {
+			if _, err := scheduler.Controller.AuditLog.Prune(time.Now()); err != nil {
+				scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.pruneAuditLog: %s", err.Error()))
+			}
+		}()
+	}
+
 	// Cleanup old alerts (runs periodically, not just when alerts are created) - runs in background
 	if scheduler.Controller.AlertEngine != nil {
 		go func() {
@@ -93,6 +204,9 @@ func (scheduler *Scheduler) Start() error {
 	// Then run every hour
 	scheduler.Ticker = time.NewTicker(time.Hour)
 
+	// Ephemeral talkgroups have TTLs in minutes
+	scheduler.EphemeralTicker = time.NewTicker(time.Minute)
+
 	go func() {
 		for {
 			select {
@@ -101,6 +215,10 @@ func (scheduler *Scheduler) Start() error {
 				return
 			case <-scheduler.Ticker.C:
 				scheduler.run()
+			case <-scheduler.EphemeralTicker.C:
+				if err := scheduler.pruneEphemeral(); err != nil {
+					scheduler.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("scheduler.pruneEphemeral: %s", err.Error()))
+				}
 			}
 		}
 	}()
@@ -115,6 +233,8 @@ func (scheduler *Scheduler) Stop() error {
 
 	scheduler.Ticker.Stop()
 	scheduler.Ticker = nil
+	scheduler.EphemeralTicker.Stop()
+	scheduler.EphemeralTicker = nil
 	scheduler.started = false
 
 	return nil
@@ -27,17 +27,21 @@ import (
 )
 
 type System struct {
-	Id           uint64
-	AutoPopulate bool
-	Blacklists   Blacklists
-	Delay        uint
-	Kind         string
-	Label        string
-	Order        uint
-	Sites        *Sites
-	SystemRef    uint
-	Talkgroups   *Talkgroups
-	Units        *Units
+	Id                  uint64
+	AutoPopulate        bool
+	Blacklists          Blacklists
+	DefaultGroupId      uint64 // group of the talkgroups auto populated without one
+	DefaultTagId        uint64 // tag of the talkgroups auto populated without one
+	Delay               uint
+	DisplayConfidence   float64 // transcripts under this confidence are shown as uncertain, 0 inherits the transcription config
+	Kind                string
+	Label               string
+	Order               uint
+	Sites               *Sites
+	SystemRef           uint
+	Talkgroups          *Talkgroups
+	Units               *Units
+	VocabularyProfileId uint64
 }
 
 func NewSystem() *System {
@@ -66,11 +70,26 @@ func (system *System) FromMap(m map[string]any) *System {
 		system.Blacklists = Blacklists(v)
 	}
 
+	switch v := m["defaultGroupId"].(type) {
+	case float64:
+		system.DefaultGroupId = uint64(v)
+	}
+
+	switch v := m["defaultTagId"].(type) {
+	case float64:
+		system.DefaultTagId = uint64(v)
+	}
+
 	switch v := m["delay"].(type) {
 	case float64:
 		system.Delay = uint(v)
 	}
 
+	switch v := m["displayConfidence"].(type) {
+	case float64:
+		system.DisplayConfidence = v
+	}
+
 	switch v := m["type"].(type) {
 	case string:
 		system.Kind = v
@@ -106,9 +125,52 @@ func (system *System) FromMap(m map[string]any) *System {
 		system.Units.FromMap(v)
 	}
 
+	switch v := m["vocabularyProfileId"].(type) {
+	case float64:
+		system.VocabularyProfileId = uint64(v)
+	}
+
 	return system
 }
 
+// keepSettings restores the settings of the previous system and of its talkgroups missing from the map
+// it was read from, the admin saving the systems without the settings it doesn't know of
+func (system *System) keepSettings(previous *System, m map[string]any) {
+	if _, ok := m["defaultGroupId"]; !ok {
+		system.DefaultGroupId = previous.DefaultGroupId
+	}
+	if _, ok := m["defaultTagId"]; !ok {
+		system.DefaultTagId = previous.DefaultTagId
+	}
+	if _, ok := m["displayConfidence"]; !ok {
+		system.DisplayConfidence = previous.DisplayConfidence
+	}
+	if _, ok := m["vocabularyProfileId"]; !ok {
+		system.VocabularyProfileId = previous.VocabularyProfileId
+	}
+
+	if v, ok := m["talkgroups"].([]any); ok && previous.Talkgroups != nil {
+		// the talkgroups are read from the maps in order, one for each
+		i := 0
+		for _, r := range v {
+			tm, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			talkgroup := system.Talkgroups.List[i]
+			i++
+
+			if talkgroup.Id == 0 {
+				continue
+			}
+			if p, ok := previous.Talkgroups.GetTalkgroupById(talkgroup.Id); ok {
+				talkgroup.keepSettings(p, tm)
+			}
+		}
+	}
+}
+
 func (system *System) MarshalJSON() ([]byte, error) {
 	m := map[string]any{
 		"id":           system.Id,
@@ -124,10 +186,22 @@ func (system *System) MarshalJSON() ([]byte, error) {
 		m["blacklists"] = system.Blacklists
 	}
 
+	if system.DefaultGroupId > 0 {
+		m["defaultGroupId"] = system.DefaultGroupId
+	}
+
+	if system.DefaultTagId > 0 {
+		m["defaultTagId"] = system.DefaultTagId
+	}
+
 	if system.Delay > 0 {
 		m["delay"] = system.Delay
 	}
 
+	if system.DisplayConfidence > 0 {
+		m["displayConfidence"] = system.DisplayConfidence
+	}
+
 	if len(system.Kind) > 0 {
 		m["type"] = system.Kind
 	}
@@ -136,6 +210,10 @@ func (system *System) MarshalJSON() ([]byte, error) {
 		m["order"] = system.Order
 	}
 
+	if system.VocabularyProfileId > 0 {
+		m["vocabularyProfileId"] = system.VocabularyProfileId
+	}
+
 	return json.Marshal(m)
 }
 
@@ -144,6 +222,8 @@ type SystemMap map[string]any
 type Systems struct {
 	List  []*System
 	mutex sync.RWMutex
+	byId  lookupCache[uint64, System]
+	byRef lookupCache[uint, System]
 }
 
 func NewSystems() *Systems {
@@ -156,6 +236,12 @@ func NewSystems() *Systems {
 func (systems *Systems) FromMap(f []any) *Systems {
 	systems.mutex.Lock()
 	defer systems.mutex.Unlock()
+	defer systems.rebuildLookups()
+
+	previous := map[uint64]*System{}
+	for _, system := range systems.List {
+		previous[system.Id] = system
+	}
 
 	systems.List = []*System{}
 
@@ -164,6 +250,9 @@ func (systems *Systems) FromMap(f []any) *Systems {
 		case map[string]any:
 			system := NewSystem()
 			system.FromMap(m)
+			if p, ok := previous[system.Id]; ok && system.Id > 0 {
+				system.keepSettings(p, m)
+			}
 			systems.List = append(systems.List, system)
 		}
 	}
@@ -188,6 +277,10 @@ NextRef:
 }
 
 func (systems *Systems) GetSystemById(id uint64) (system *System, ok bool) {
+	if system, ok := systems.byId.get(id); ok {
+		return system, true
+	}
+
 	systems.mutex.RLock()
 	defer systems.mutex.RUnlock()
 
@@ -225,6 +318,10 @@ func (systems *Systems) GetSystemByLabel(label string) (system *System, ok bool)
 }
 
 func (systems *Systems) GetSystemByRef(ref uint) (system *System, ok bool) {
+	if system, ok := systems.byRef.get(ref); ok {
+		return system, true
+	}
+
 	systems.mutex.RLock()
 	defer systems.mutex.RUnlock()
 
@@ -481,6 +578,7 @@ func (systems *Systems) Read(db *Database) error {
 
 	systems.mutex.Lock()
 	defer systems.mutex.Unlock()
+	defer systems.rebuildLookups()
 
 	systems.List = []*System{}
 
@@ -490,7 +588,7 @@ func (systems *Systems) Read(db *Database) error {
 		return formatError(err, "")
 	}
 
-	query = `SELECT "systemId", "autoPopulate", "blacklists", "delay", "label", "order", "systemRef", "type" FROM "systems"`
+	query = `SELECT "systemId", "autoPopulate", "blacklists", "defaultGroupId", "defaultTagId", "delay", "displayConfidence", "label", "order", "systemRef", "type", "vocabularyProfileId" FROM "systems"`
 	if rows, err = tx.Query(query); err != nil {
 		tx.Rollback()
 		return formatError(err, query)
@@ -499,7 +597,7 @@ func (systems *Systems) Read(db *Database) error {
 	for rows.Next() {
 		system := NewSystem()
 
-		if err = rows.Scan(&system.Id, &system.AutoPopulate, &system.Blacklists, &system.Delay, &system.Label, &system.Order, &system.SystemRef, &system.Kind); err != nil {
+		if err = rows.Scan(&system.Id, &system.AutoPopulate, &system.Blacklists, &system.DefaultGroupId, &system.DefaultTagId, &system.Delay, &system.DisplayConfidence, &system.Label, &system.Order, &system.SystemRef, &system.Kind, &system.VocabularyProfileId); err != nil {
 			break
 		}
 
@@ -544,6 +642,12 @@ func (systems *Systems) Read(db *Database) error {
 	return nil
 }
 
+// rebuildLookups publishes new lookup snapshots of the list, caller must hold the lock.
+func (systems *Systems) rebuildLookups() {
+	systems.byId.rebuild(systems.List, func(system *System) uint64 { return system.Id })
+	systems.byRef.rebuild(systems.List, func(system *System) uint { return system.SystemRef })
+}
+
 func (systems *Systems) Write(db *Database) error {
 	var (
 		err       error
@@ -629,6 +733,8 @@ func (systems *Systems) Write(db *Database) error {
 		var count uint
 		var existingId uint64
 
+		system.Label = SanitizeLabel(system.Label)
+
 		// First check if a system with this ID already exists
 		if system.Id > 0 {
 			query = fmt.Sprintf(`SELECT COUNT(*) FROM "systems" WHERE "systemId" = %d`, system.Id)
@@ -654,10 +760,10 @@ func (systems *Systems) Write(db *Database) error {
 		if count == 0 {
 			if system.Id > 0 {
 				// Preserve the explicit ID when inserting
-				query = fmt.Sprintf(`INSERT INTO "systems" ("systemId", "autoPopulate", "blacklists", "delay", "label", "order", "systemRef", "type") VALUES (%d, %t, '%s', %d, '%s', %d, %d, '%s')`, system.Id, system.AutoPopulate, system.Blacklists, system.Delay, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind)
+				query = fmt.Sprintf(`INSERT INTO "systems" ("systemId", "autoPopulate", "blacklists", "defaultGroupId", "defaultTagId", "delay", "displayConfidence", "label", "order", "systemRef", "type", "vocabularyProfileId") VALUES (%d, %t, '%s', %d, %d, %d, %v, '%s', %d, %d, '%s', %d)`, system.Id, system.AutoPopulate, system.Blacklists, system.DefaultGroupId, system.DefaultTagId, system.Delay, system.DisplayConfidence, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind, system.VocabularyProfileId)
 			} else {
 				// Let database assign auto-increment ID
-				query = fmt.Sprintf(`INSERT INTO "systems" ("autoPopulate", "blacklists", "delay", "label", "order", "systemRef", "type") VALUES (%t, '%s', %d, '%s', %d, %d, '%s')`, system.AutoPopulate, system.Blacklists, system.Delay, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind)
+				query = fmt.Sprintf(`INSERT INTO "systems" ("autoPopulate", "blacklists", "defaultGroupId", "defaultTagId", "delay", "displayConfidence", "label", "order", "systemRef", "type", "vocabularyProfileId") VALUES (%t, '%s', %d, %d, %d, %v, '%s', %d, %d, '%s', %d)`, system.AutoPopulate, system.Blacklists, system.DefaultGroupId, system.DefaultTagId, system.Delay, system.DisplayConfidence, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind, system.VocabularyProfileId)
 			}
 
 			if db.Config.DbType == DbTypePostgresql {
@@ -689,7 +795,7 @@ func (systems *Systems) Write(db *Database) error {
 			}
 
 		} else {
-			query = fmt.Sprintf(`UPDATE "systems" SET "autoPopulate" = %t, "blacklists" = '%s', "delay" = %d, "label" = '%s', "order" = %d, "systemRef" = %d, "type" = '%s' WHERE "systemId" = %d`, system.AutoPopulate, system.Blacklists, system.Delay, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind, system.Id)
+			query = fmt.Sprintf(`UPDATE "systems" SET "autoPopulate" = %t, "blacklists" = '%s', "defaultGroupId" = %d, "defaultTagId" = %d, "delay" = %d, "displayConfidence" = %v, "label" = '%s', "order" = %d, "systemRef" = %d, "type" = '%s', "vocabularyProfileId" = %d WHERE "systemId" = %d`, system.AutoPopulate, system.Blacklists, system.DefaultGroupId, system.DefaultTagId, system.Delay, system.DisplayConfidence, escapeQuotes(system.Label), system.Order, system.SystemRef, system.Kind, system.VocabularyProfileId, system.Id)
 			if _, err = tx.Exec(query); err != nil {
 				break
 			}
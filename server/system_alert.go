@@ -16,7 +16,9 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -24,7 +26,7 @@ import (
 // SystemAlert represents a system-level alert for administrators
 type SystemAlert struct {
 	Id        uint64 `json:"id"`
-	AlertType string `json:"alertType"` // "transcription_failure", "tone_detection_issue", "service_health", "manual"
+	AlertType string `json:"alertType"` // "transcription_failure", "tone_detection_issue", "call_rate_silent", "call_rate_flood", "service_health", "manual"
 	Severity  string `json:"severity"`  // "info", "warning", "error", "critical"
 	Title     string `json:"title"`
 	Message   string `json:"message"`
@@ -62,19 +64,31 @@ func (controller *Controller) CreateSystemAlert(alertType, severity, title, mess
 
 	var query string
 	if createdBy > 0 {
-		query = fmt.Sprintf(`INSERT INTO "systemAlerts" ("alertType", "severity", "title", "message", "data", "createdAt", "createdBy") VALUES ('%s', '%s', '%s', '%s', '%s', %d, %d)`,
+		query = fmt.Sprintf(`INSERT INTO "systemAlerts" ("alertType", "severity", "title", "message", "data", "createdAt", "createdBy") VALUES ('%s', '%s', '%s', '%s', '%s', %d, %d) RETURNING "alertId"`,
 			escapeQuotes(alertType), escapeQuotes(severity), escapeQuotes(title), escapeQuotes(message), escapeQuotes(dataJSON), createdAt, createdBy)
 	} else {
-		query = fmt.Sprintf(`INSERT INTO "systemAlerts" ("alertType", "severity", "title", "message", "data", "createdAt") VALUES ('%s', '%s', '%s', '%s', '%s', %d)`,
+		query = fmt.Sprintf(`INSERT INTO "systemAlerts" ("alertType", "severity", "title", "message", "data", "createdAt") VALUES ('%s', '%s', '%s', '%s', '%s', %d) RETURNING "alertId"`,
 			escapeQuotes(alertType), escapeQuotes(severity), escapeQuotes(title), escapeQuotes(message), escapeQuotes(dataJSON), createdAt)
 	}
 
-	if _, err := controller.Database.Sql.Exec(query); err != nil {
+	alert := &SystemAlert{
+		AlertType: alertType,
+		Severity:  severity,
+		Title:     title,
+		Message:   message,
+		Data:      dataJSON,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy,
+	}
+
+	if err := controller.Database.Sql.QueryRow(query).Scan(&alert.Id); err != nil {
 		return fmt.Errorf("failed to create system alert: %v", err)
 	}
 
 	controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("System alert created: [%s] %s - %s", severity, title, message))
 
+	controller.notifySystemAlert(alert, SystemAlertCreated, "")
+
 	// Send push notification to all system admins
 	go controller.SendSystemAlertNotification(title, message, alertType, severity, dataJSON)
 
@@ -122,7 +136,7 @@ func (controller *Controller) SendSystemAlertNotification(title, message, alertT
 	// Get device tokens for target users
 	var playerIds []string
 	for _, userId := range targetUserIds {
-		tokens := controller.DeviceTokens.GetByUser(userId)
+		tokens := controller.DeviceTokens.GetForUser(userId)
 		for _, token := range tokens {
 			if token.Token != "" {
 				playerIds = append(playerIds, token.Token)
@@ -201,13 +215,69 @@ func (controller *Controller) GetSystemAlerts(limit int, includeDismissed bool)
 
 // DismissSystemAlert marks a system alert as dismissed
 func (controller *Controller) DismissSystemAlert(alertId uint64) error {
-	query := fmt.Sprintf(`UPDATE "systemAlerts" SET "dismissed" = true WHERE "alertId" = %d`, alertId)
-	if _, err := controller.Database.Sql.Exec(query); err != nil {
+	alert := &SystemAlert{Id: alertId, Dismissed: true}
+
+	query := fmt.Sprintf(`UPDATE "systemAlerts" SET "dismissed" = true WHERE "alertId" = %d AND "dismissed" = false RETURNING "alertType", "severity", "title", "message", "data", "createdAt", COALESCE("createdBy", 0)`, alertId)
+	if err := controller.Database.Sql.QueryRow(query).Scan(&alert.AlertType, &alert.Severity, &alert.Title, &alert.Message, &alert.Data, &alert.CreatedAt, &alert.CreatedBy); err != nil {
+		// the alert is already dismissed or no longer exists
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
 		return fmt.Errorf("failed to dismiss system alert: %v", err)
 	}
+
+	controller.notifySystemAlert(alert, SystemAlertResolved, "dismissed")
+
 	return nil
 }
 
+// ResolveSystemAlerts dismisses the active alerts of the type whose data matches, once the
+// condition they reported has cleared. A nil match resolves every alert of the type.
+func (controller *Controller) ResolveSystemAlerts(alertType string, match func(data *SystemAlertData) bool) {
+	query := fmt.Sprintf(`SELECT "alertId", "severity", "title", "message", "data", "createdAt", COALESCE("createdBy", 0) FROM "systemAlerts" WHERE "alertType" = '%s' AND "dismissed" = false`, escapeQuotes(alertType))
+
+	rows, err := controller.Database.Sql.Query(query)
+	if err != nil {
+		controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("failed to query system alerts to resolve: %v", err))
+		return
+	}
+
+	alerts := []*SystemAlert{}
+	for rows.Next() {
+		alert := &SystemAlert{AlertType: alertType}
+		if err := rows.Scan(&alert.Id, &alert.Severity, &alert.Title, &alert.Message, &alert.Data, &alert.CreatedAt, &alert.CreatedBy); err != nil {
+			continue
+		}
+
+		data := &SystemAlertData{}
+		json.Unmarshal([]byte(alert.Data), data)
+
+		if match == nil || match(data) {
+			alerts = append(alerts, alert)
+		}
+	}
+	rows.Close()
+
+	for _, alert := range alerts {
+		res, err := controller.Database.Sql.Exec(fmt.Sprintf(`UPDATE "systemAlerts" SET "dismissed" = true WHERE "alertId" = %d AND "dismissed" = false`, alert.Id))
+		if err != nil {
+			controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("failed to resolve system alert %d: %v", alert.Id, err))
+			continue
+		}
+
+		// dismissed meanwhile, its resolution was already sent
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		alert.Dismissed = true
+
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("System alert resolved: [%s] %s", alert.Severity, alert.Title))
+
+		controller.notifySystemAlert(alert, SystemAlertResolved, "recovered")
+	}
+}
+
 // CleanupOldSystemAlerts removes system alerts older than retention days
 func (controller *Controller) CleanupOldSystemAlerts() {
 	retentionDays := controller.Options.AlertRetentionDays
@@ -233,6 +303,10 @@ func (controller *Controller) CleanupOldSystemAlerts() {
 	if rowsAffected > 0 {
 		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("cleaned up %d old system alerts (older than %d days)", rowsAffected, retentionDays))
 	}
+
+	if controller.SystemAlertWebhook != nil {
+		controller.SystemAlertWebhook.Prune(time.UnixMilli(cutoffTime))
+	}
 }
 
 // MonitorTranscriptionFailures monitors for transcription failures and creates system alerts
@@ -269,6 +343,8 @@ func (controller *Controller) MonitorTranscriptionFailures() {
 			data,
 			0, // System-generated
 		)
+	} else {
+		controller.ResolveSystemAlerts("transcription_failure", nil)
 	}
 }
 
@@ -329,6 +405,10 @@ func (controller *Controller) MonitorToneDetectionIssues() {
 				data,
 				0, // System-generated
 			)
+		} else {
+			controller.ResolveSystemAlerts("tone_detection_issue", func(data *SystemAlertData) bool {
+				return data.TalkgroupId == talkgroupId
+			})
 		}
 	}
 }
@@ -340,6 +420,7 @@ func (controller *Controller) StartSystemHealthMonitoring() {
 		for range ticker.C {
 			controller.MonitorTranscriptionFailures()
 			controller.MonitorToneDetectionIssues()
+			controller.MonitorCallRateAnomalies()
 		}
 	}()
 	
@@ -0,0 +1,150 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	SystemAlertCreated  = "created"
+	SystemAlertResolved = "resolved"
+)
+
+// systemAlertSeverities ranks the severities of system alerts, unknown ones rank as info
+var systemAlertSeverities = map[string]int{"info": 0, "warning": 1, "error": 2, "critical": 3}
+
+// SystemAlertWebhookEvent is the body posted to the system alert webhook
+type SystemAlertWebhookEvent struct {
+	Event      string          `json:"event"` // "system_alert.created" or "system_alert.resolved"
+	AlertId    uint64          `json:"alertId"`
+	AlertType  string          `json:"alertType"`
+	Severity   string          `json:"severity"`
+	Title      string          `json:"title"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Resolution string          `json:"resolution,omitempty"` // "dismissed" by an admin or "recovered" when the condition cleared
+	CreatedAt  int64           `json:"createdAt"`
+	Timestamp  int64           `json:"timestamp"`
+}
+
+// SystemAlertWebhook echoes system alerts to incident tooling, each event of an alert is only
+// delivered once even when the alert is dismissed and then recovers.
+type SystemAlertWebhook struct {
+	client      *http.Client
+	retryDelays []time.Duration
+	sent        map[uint64]map[string]time.Time
+	mutex       sync.Mutex
+}
+
+func NewSystemAlertWebhook() *SystemAlertWebhook {
+	return &SystemAlertWebhook{
+		client:      transcriptionWebhookClient,
+		retryDelays: transcriptionWebhookRetryDelays,
+		sent:        map[uint64]map[string]time.Time{},
+	}
+}
+
+// claim records the event of the alert and reports whether it was not sent already
+func (webhook *SystemAlertWebhook) claim(alertId uint64, event string, now time.Time) bool {
+	webhook.mutex.Lock()
+	defer webhook.mutex.Unlock()
+
+	if _, ok := webhook.sent[alertId][event]; ok {
+		return false
+	}
+
+	if webhook.sent[alertId] == nil {
+		webhook.sent[alertId] = map[string]time.Time{}
+	}
+	webhook.sent[alertId][event] = now
+
+	return true
+}
+
+// Prune forgets the alerts whose events were all sent before the cutoff
+func (webhook *SystemAlertWebhook) Prune(cutoff time.Time) {
+	webhook.mutex.Lock()
+	defer webhook.mutex.Unlock()
+
+	for alertId, events := range webhook.sent {
+		stale := true
+		for _, sentAt := range events {
+			if !sentAt.Before(cutoff) {
+				stale = false
+			}
+		}
+		if stale {
+			delete(webhook.sent, alertId)
+		}
+	}
+}
+
+// Notify posts the event of the alert when it is severe enough and was not sent already
+func (webhook *SystemAlertWebhook) Notify(options *Options, alert *SystemAlert, event string, resolution string) error {
+	if options.SystemAlertWebhookUrl == "" || systemAlertSeverities[alert.Severity] < systemAlertSeverities[options.SystemAlertWebhookSeverity] {
+		return nil
+	}
+
+	now := time.Now()
+
+	if !webhook.claim(alert.Id, event, now) {
+		return nil
+	}
+
+	body, err := json.Marshal(SystemAlertWebhookEvent{
+		Event:      "system_alert." + event,
+		AlertId:    alert.Id,
+		AlertType:  alert.AlertType,
+		Severity:   alert.Severity,
+		Title:      alert.Title,
+		Message:    alert.Message,
+		Data:       systemAlertWebhookData(alert.Data),
+		Resolution: resolution,
+		CreatedAt:  alert.CreatedAt,
+		Timestamp:  now.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return deliverTranscriptionWebhook(webhook.client, options.SystemAlertWebhookUrl, options.SystemAlertWebhookSecret, body, webhook.retryDelays)
+}
+
+// systemAlertWebhookData passes the data of the alert as is, unless it is not valid json
+func systemAlertWebhookData(data string) json.RawMessage {
+	if data == "" || !json.Valid([]byte(data)) {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// notifySystemAlert sends the event to the webhook in the background
+func (controller *Controller) notifySystemAlert(alert *SystemAlert, event string, resolution string) {
+	if controller.SystemAlertWebhook == nil || controller.Options.SystemAlertWebhookUrl == "" {
+		return
+	}
+
+	go func() {
+		if err := controller.SystemAlertWebhook.Notify(controller.Options, alert, event, resolution); err != nil {
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("system alert webhook for alert %d not delivered: %v", alert.Id, err))
+		}
+	}()
+}
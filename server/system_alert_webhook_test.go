@@ -0,0 +1,101 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSystemAlertWebhookEvents(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		events []SystemAlertWebhookEvent
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if got, want := r.Header.Get(transcriptionWebhookSignatureHeader), signTranscriptionWebhook("secret", body); got != want {
+			t.Errorf("expected signature %s, got %s", want, got)
+		}
+
+		event := SystemAlertWebhookEvent{}
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("invalid body %s: %v", body, err)
+		}
+
+		mutex.Lock()
+		events = append(events, event)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	options := NewOptions()
+	options.SystemAlertWebhookUrl = server.URL
+	options.SystemAlertWebhookSecret = "secret"
+	options.SystemAlertWebhookSeverity = "warning"
+
+	webhook := NewSystemAlertWebhook()
+	webhook.retryDelays = nil
+
+	alert := &SystemAlert{Id: 7, AlertType: CallRateAnomalySilent, Severity: "critical", Title: "System Gone Silent", Message: "no calls", Data: `{"systemId":3}`, CreatedAt: 1700000000000}
+
+	for _, notify := range []struct{ event, resolution string }{
+		{SystemAlertCreated, ""},
+		{SystemAlertCreated, ""},
+		{SystemAlertResolved, "dismissed"},
+		// the condition clearing after the dismissal does not resolve the alert again
+		{SystemAlertResolved, "recovered"},
+	} {
+		if err := webhook.Notify(options, alert, notify.event, notify.resolution); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// alerts less severe than the configured severity are not sent
+	if err := webhook.Notify(options, &SystemAlert{Id: 8, Severity: "info"}, SystemAlertCreated, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected a created and a resolved event, got %+v", events)
+	}
+
+	created, resolved := events[0], events[1]
+	if created.Event != "system_alert.created" || created.AlertId != 7 || created.AlertType != CallRateAnomalySilent || created.Severity != "critical" || created.Title != "System Gone Silent" || string(created.Data) != `{"systemId":3}` || created.Resolution != "" || created.CreatedAt != 1700000000000 {
+		t.Errorf("unexpected created event %+v", created)
+	}
+	if resolved.Event != "system_alert.resolved" || resolved.AlertId != 7 || resolved.Resolution != "dismissed" {
+		t.Errorf("unexpected resolved event %+v", resolved)
+	}
+
+	// the alerts are forgotten once past the retention
+	webhook.Prune(time.Now().Add(time.Minute))
+	if len(webhook.sent) != 0 {
+		t.Errorf("expected the sent events pruned, got %v", webhook.sent)
+	}
+
+	options.SystemAlertWebhookUrl = ""
+	if err := webhook.Notify(options, alert, SystemAlertCreated, ""); err != nil || len(events) != 2 {
+		t.Errorf("expected nothing sent without a webhook url, got %v %+v", err, events)
+	}
+}
@@ -81,6 +81,7 @@ func (tag *Tag) MarshalJSON() ([]byte, error) {
 type Tags struct {
 	List  []*Tag
 	mutex sync.RWMutex
+	byId  lookupCache[uint64, Tag]
 }
 
 func NewTags() *Tags {
@@ -93,6 +94,7 @@ func NewTags() *Tags {
 func (tags *Tags) FromMap(f []any) *Tags {
 	tags.mutex.Lock()
 	defer tags.mutex.Unlock()
+	defer tags.rebuildLookups()
 
 	tags.List = []*Tag{}
 
@@ -108,6 +110,10 @@ func (tags *Tags) FromMap(f []any) *Tags {
 }
 
 func (tags *Tags) GetTagById(id uint64) (tag *Tag, ok bool) {
+	if tag, ok := tags.byId.get(id); ok {
+		return tag, true
+	}
+
 	tags.mutex.RLock()
 	defer tags.mutex.RUnlock()
 
@@ -239,6 +245,7 @@ func (tags *Tags) Read(db *Database) error {
 
 	tags.mutex.Lock()
 	defer tags.mutex.Unlock()
+	defer tags.rebuildLookups()
 
 	tags.List = []*Tag{}
 
@@ -272,6 +279,11 @@ func (tags *Tags) Read(db *Database) error {
 	return nil
 }
 
+// rebuildLookups publishes a new lookup snapshot of the list, caller must hold the lock.
+func (tags *Tags) rebuildLookups() {
+	tags.byId.rebuild(tags.List, func(tag *Tag) uint64 { return tag.Id })
+}
+
 func (tags *Tags) Write(db *Database) error {
 	var (
 		err    error
@@ -335,6 +347,8 @@ func (tags *Tags) Write(db *Database) error {
 		var count uint
 		var existingId uint64
 
+		tag.Label = SanitizeLabel(tag.Label)
+
 		if tag.Id > 0 {
 			query = fmt.Sprintf(`SELECT COUNT(*) FROM "tags" WHERE "tagId" = %d`, tag.Id)
 			if err = tx.QueryRow(query).Scan(&count); err != nil {
@@ -363,10 +377,20 @@ func (tags *Tags) Write(db *Database) error {
 				// Let database assign auto-increment ID
 				query = fmt.Sprintf(`INSERT INTO "tags" ("label", "order", "color") VALUES ('%s', %d, '%s')`, escapeQuotes(tag.Label), tag.Order, escapeQuotes(tag.Color))
 			}
-			if _, err = tx.Exec(query); err != nil {
+			err = execSavepoint(tx, query)
+			if isUniqueViolation(err) {
+				// Tag with this label was inserted in the meantime, update it instead
+				query = fmt.Sprintf(`SELECT "tagId" FROM "tags" WHERE "label" = '%s' LIMIT 1`, escapeQuotes(tag.Label))
+				if err = tx.QueryRow(query).Scan(&tag.Id); err != nil {
+					break
+				}
+				count = 1
+			} else if err != nil {
 				break
 			}
-		} else {
+		}
+
+		if count > 0 {
 			query = fmt.Sprintf(`UPDATE "tags" SET "label" = '%s', "order" = %d, "color" = '%s' WHERE "tagId" = %d`, escapeQuotes(tag.Label), tag.Order, escapeQuotes(tag.Color), tag.Id)
 			if _, err = tx.Exec(query); err != nil {
 				break
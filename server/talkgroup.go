@@ -28,15 +28,21 @@ import (
 type Talkgroup struct {
 	Id                   uint64
 	Delay                uint
+	Ephemeral            bool
+	EphemeralTtl         uint // minutes, calls are deleted once older than this
 	Frequency            uint
+	DisplayConfidence    float64 // transcripts under this confidence are shown as uncertain, 0 inherits the one of the system
+	Gain                 float64 // dB applied to the audio served to listeners, 0 leaves it untouched
 	GroupIds             []uint64
 	Kind                 string
 	Label                string
 	Name                 string
 	Order                uint
+	Priority             uint // 0 is normal, higher talkgroups skip delays and duplicate detection
 	TagId                uint64
 	TalkgroupRef         uint
 	ToneDetectionEnabled bool
+	ToneLearningUntil    int64 // unix milliseconds, tone sets are learned from the pages of the talkgroup until then
 	ToneSets             []ToneSet
 }
 
@@ -59,11 +65,31 @@ func (talkgroup *Talkgroup) FromMap(m map[string]any) *Talkgroup {
 		talkgroup.Delay = uint(v)
 	}
 
+	switch v := m["ephemeral"].(type) {
+	case bool:
+		talkgroup.Ephemeral = v
+	}
+
+	switch v := m["ephemeralTtl"].(type) {
+	case float64:
+		talkgroup.EphemeralTtl = uint(v)
+	}
+
 	switch v := m["frequency"].(type) {
 	case float64:
 		talkgroup.Frequency = uint(v)
 	}
 
+	switch v := m["displayConfidence"].(type) {
+	case float64:
+		talkgroup.DisplayConfidence = v
+	}
+
+	switch v := m["gain"].(type) {
+	case float64:
+		talkgroup.Gain = v
+	}
+
 	switch v := m["groupIds"].(type) {
 	case []any:
 		talkgroup.GroupIds = []uint64{}
@@ -95,6 +121,11 @@ func (talkgroup *Talkgroup) FromMap(m map[string]any) *Talkgroup {
 		talkgroup.Order = uint(v)
 	}
 
+	switch v := m["priority"].(type) {
+	case float64:
+		talkgroup.Priority = uint(v)
+	}
+
 	switch v := m["tagId"].(type) {
 	case float64:
 		talkgroup.TagId = uint64(v)
@@ -105,6 +136,11 @@ func (talkgroup *Talkgroup) FromMap(m map[string]any) *Talkgroup {
 		talkgroup.TalkgroupRef = uint(v)
 	}
 
+	switch v := m["toneLearningUntil"].(type) {
+	case float64:
+		talkgroup.ToneLearningUntil = int64(v)
+	}
+
 	switch v := m["toneDetectionEnabled"].(type) {
 	case bool:
 		talkgroup.ToneDetectionEnabled = v
@@ -126,6 +162,29 @@ func (talkgroup *Talkgroup) FromMap(m map[string]any) *Talkgroup {
 	return talkgroup
 }
 
+// keepSettings restores the settings of the previous talkgroup missing from the map it was read from,
+// the admin saving the talkgroups without the settings it doesn't know of
+func (talkgroup *Talkgroup) keepSettings(previous *Talkgroup, m map[string]any) {
+	if _, ok := m["displayConfidence"]; !ok {
+		talkgroup.DisplayConfidence = previous.DisplayConfidence
+	}
+	if _, ok := m["ephemeral"]; !ok {
+		talkgroup.Ephemeral = previous.Ephemeral
+	}
+	if _, ok := m["ephemeralTtl"]; !ok {
+		talkgroup.EphemeralTtl = previous.EphemeralTtl
+	}
+	if _, ok := m["gain"]; !ok {
+		talkgroup.Gain = previous.Gain
+	}
+	if _, ok := m["priority"]; !ok {
+		talkgroup.Priority = previous.Priority
+	}
+	if _, ok := m["toneLearningUntil"]; !ok {
+		talkgroup.ToneLearningUntil = previous.ToneLearningUntil
+	}
+}
+
 func (talkgroup *Talkgroup) MarshalJSON() ([]byte, error) {
 	m := map[string]any{
 		"id":           talkgroup.Id,
@@ -139,10 +198,23 @@ func (talkgroup *Talkgroup) MarshalJSON() ([]byte, error) {
 		m["delay"] = talkgroup.Delay
 	}
 
+	if talkgroup.Ephemeral {
+		m["ephemeral"] = talkgroup.Ephemeral
+		m["ephemeralTtl"] = talkgroup.EphemeralTtl
+	}
+
 	if talkgroup.Frequency > 0 {
 		m["frequency"] = talkgroup.Frequency
 	}
 
+	if talkgroup.DisplayConfidence > 0 {
+		m["displayConfidence"] = talkgroup.DisplayConfidence
+	}
+
+	if talkgroup.Gain != 0 {
+		m["gain"] = talkgroup.Gain
+	}
+
 	if len(talkgroup.Kind) > 0 {
 		m["type"] = talkgroup.Kind
 	}
@@ -151,12 +223,20 @@ func (talkgroup *Talkgroup) MarshalJSON() ([]byte, error) {
 		m["talkgroup"] = talkgroup.Order
 	}
 
+	if talkgroup.Priority > 0 {
+		m["priority"] = talkgroup.Priority
+	}
+
 	if talkgroup.TagId > 0 {
 		m["tagId"] = talkgroup.TagId
 	}
 
 	m["toneDetectionEnabled"] = talkgroup.ToneDetectionEnabled
 
+	if talkgroup.ToneLearningUntil > 0 {
+		m["toneLearningUntil"] = talkgroup.ToneLearningUntil
+	}
+
 	if len(talkgroup.ToneSets) > 0 {
 		if toneSetsJson, err := SerializeToneSets(talkgroup.ToneSets); err == nil {
 			m["toneSets"] = json.RawMessage(toneSetsJson)
@@ -171,6 +251,8 @@ type TalkgroupMap map[string]any
 type Talkgroups struct {
 	List  []*Talkgroup
 	mutex sync.Mutex
+	byId  lookupCache[uint64, Talkgroup]
+	byRef lookupCache[uint, Talkgroup]
 }
 
 func NewTalkgroups() *Talkgroups {
@@ -183,6 +265,7 @@ func NewTalkgroups() *Talkgroups {
 func (talkgroups *Talkgroups) FromMap(f []any) *Talkgroups {
 	talkgroups.mutex.Lock()
 	defer talkgroups.mutex.Unlock()
+	defer talkgroups.rebuildLookups()
 
 	talkgroups.List = []*Talkgroup{}
 
@@ -198,6 +281,10 @@ func (talkgroups *Talkgroups) FromMap(f []any) *Talkgroups {
 }
 
 func (talkgroups *Talkgroups) GetTalkgroupById(id uint64) (system *Talkgroup, ok bool) {
+	if talkgroup, ok := talkgroups.byId.get(id); ok {
+		return talkgroup, true
+	}
+
 	talkgroups.mutex.Lock()
 	defer talkgroups.mutex.Unlock()
 
@@ -224,6 +311,10 @@ func (talkgroups *Talkgroups) GetTalkgroupByLabel(label string) (talkgroup *Talk
 }
 
 func (talkgroups *Talkgroups) GetTalkgroupByRef(ref uint) (talkgroup *Talkgroup, ok bool) {
+	if talkgroup, ok := talkgroups.byRef.get(ref); ok {
+		return talkgroup, true
+	}
+
 	talkgroups.mutex.Lock()
 	defer talkgroups.mutex.Unlock()
 
@@ -247,16 +338,17 @@ func (talkgroups *Talkgroups) ReadTx(tx *sql.Tx, systemId uint64, dbType string)
 
 	talkgroups.mutex.Lock()
 	defer talkgroups.mutex.Unlock()
+	defer talkgroups.rebuildLookups()
 
 	talkgroups.List = []*Talkgroup{}
 
 	formatError := errorFormatter("talkgroups", "read")
 
 	if dbType == DbTypePostgresql {
-		query = fmt.Sprintf(`SELECT t."talkgroupId", t."delay", t."frequency", t."label", t."name", t."order", t."tagId", t."talkgroupRef", t."type", t."toneDetectionEnabled", t."toneSets", STRING_AGG(CAST(COALESCE(tg."groupId", 0) AS text), ',') FROM "talkgroups" AS t LEFT JOIN "talkgroupGroups" AS tg ON tg."talkgroupId" = t."talkgroupId" WHERE t."systemId" = %d GROUP BY t."talkgroupId"`, systemId)
+		query = fmt.Sprintf(`SELECT t."talkgroupId", t."delay", t."ephemeral", t."ephemeralTtl", t."frequency", t."gain", t."displayConfidence", t."label", t."name", t."order", t."priority", t."tagId", t."talkgroupRef", t."type", t."toneDetectionEnabled", t."toneLearningUntil", t."toneSets", STRING_AGG(CAST(COALESCE(tg."groupId", 0) AS text), ',') FROM "talkgroups" AS t LEFT JOIN "talkgroupGroups" AS tg ON tg."talkgroupId" = t."talkgroupId" WHERE t."systemId" = %d GROUP BY t."talkgroupId"`, systemId)
 
 	} else {
-		query = fmt.Sprintf(`SELECT t."talkgroupId", t."delay", t."frequency", t."label", t."name", t."order", t."tagId", t."talkgroupRef", t."type", t."toneDetectionEnabled", t."toneSets", GROUP_CONCAT(COALESCE(tg."groupId", 0)) FROM "talkgroups" AS t LEFT JOIN "talkgroupGroups" AS tg ON tg."talkgroupId" = t."talkgroupId" WHERE t."systemId" = %d GROUP BY t."talkgroupId"`, systemId)
+		query = fmt.Sprintf(`SELECT t."talkgroupId", t."delay", t."ephemeral", t."ephemeralTtl", t."frequency", t."gain", t."displayConfidence", t."label", t."name", t."order", t."priority", t."tagId", t."talkgroupRef", t."type", t."toneDetectionEnabled", t."toneLearningUntil", t."toneSets", GROUP_CONCAT(COALESCE(tg."groupId", 0)) FROM "talkgroups" AS t LEFT JOIN "talkgroupGroups" AS tg ON tg."talkgroupId" = t."talkgroupId" WHERE t."systemId" = %d GROUP BY t."talkgroupId"`, systemId)
 	}
 
 	if rows, err = tx.Query(query); err != nil {
@@ -267,7 +359,7 @@ func (talkgroups *Talkgroups) ReadTx(tx *sql.Tx, systemId uint64, dbType string)
 		talkgroup := NewTalkgroup()
 		var toneSetsJson string
 
-		if err = rows.Scan(&talkgroup.Id, &talkgroup.Delay, &talkgroup.Frequency, &talkgroup.Label, &talkgroup.Name, &talkgroup.Order, &talkgroup.TagId, &talkgroup.TalkgroupRef, &talkgroup.Kind, &talkgroup.ToneDetectionEnabled, &toneSetsJson, &groupIds); err != nil {
+		if err = rows.Scan(&talkgroup.Id, &talkgroup.Delay, &talkgroup.Ephemeral, &talkgroup.EphemeralTtl, &talkgroup.Frequency, &talkgroup.Gain, &talkgroup.DisplayConfidence, &talkgroup.Label, &talkgroup.Name, &talkgroup.Order, &talkgroup.Priority, &talkgroup.TagId, &talkgroup.TalkgroupRef, &talkgroup.Kind, &talkgroup.ToneDetectionEnabled, &talkgroup.ToneLearningUntil, &toneSetsJson, &groupIds); err != nil {
 			break
 		}
 
@@ -300,6 +392,12 @@ func (talkgroups *Talkgroups) ReadTx(tx *sql.Tx, systemId uint64, dbType string)
 	return nil
 }
 
+// rebuildLookups publishes new lookup snapshots of the list, caller must hold the lock.
+func (talkgroups *Talkgroups) rebuildLookups() {
+	talkgroups.byId.rebuild(talkgroups.List, func(talkgroup *Talkgroup) uint64 { return talkgroup.Id })
+	talkgroups.byRef.rebuild(talkgroups.List, func(talkgroup *Talkgroup) uint { return talkgroup.TalkgroupRef })
+}
+
 func (talkgroups *Talkgroups) WriteTx(tx *sql.Tx, systemId uint64, dbType string) error {
 	var (
 		err   error
@@ -363,6 +461,9 @@ func (talkgroups *Talkgroups) WriteTx(tx *sql.Tx, systemId uint64, dbType string
 	for _, talkgroup := range talkgroups.List {
 		var count uint
 
+		talkgroup.Label = SanitizeLabel(talkgroup.Label)
+		talkgroup.Name = SanitizeLabel(talkgroup.Name)
+
 		if talkgroup.Id > 0 {
 			query = fmt.Sprintf(`SELECT COUNT(*) FROM "talkgroups" WHERE "talkgroupId" = %d`, talkgroup.Id)
 			if err = tx.QueryRow(query).Scan(&count); err != nil {
@@ -427,10 +528,10 @@ func (talkgroups *Talkgroups) WriteTx(tx *sql.Tx, systemId uint64, dbType string
 		if count == 0 {
 			if talkgroup.Id > 0 {
 				// Preserve the explicit ID when inserting
-				query = fmt.Sprintf(`INSERT INTO "talkgroups" ("talkgroupId", "delay", "frequency", "label", "name", "order", "systemId", "tagId", "talkgroupRef", "type", "toneDetectionEnabled", "toneSets") VALUES (%d, %d, %d, '%s', '%s', %d, %d, %d, %d, '%s', %t, '%s')`, talkgroup.Id, talkgroup.Delay, talkgroup.Frequency, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, systemId, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, escapeQuotes(toneSetsJson))
+				query = fmt.Sprintf(`INSERT INTO "talkgroups" ("talkgroupId", "delay", "ephemeral", "ephemeralTtl", "frequency", "gain", "displayConfidence", "label", "name", "order", "priority", "systemId", "tagId", "talkgroupRef", "type", "toneDetectionEnabled", "toneLearningUntil", "toneSets") VALUES (%d, %d, %t, %d, %d, %v, %v, '%s', '%s', %d, %d, %d, %d, %d, '%s', %t, %d, '%s')`, talkgroup.Id, talkgroup.Delay, talkgroup.Ephemeral, talkgroup.EphemeralTtl, talkgroup.Frequency, talkgroup.Gain, talkgroup.DisplayConfidence, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, talkgroup.Priority, systemId, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, talkgroup.ToneLearningUntil, escapeQuotes(toneSetsJson))
 			} else {
 				// Let database assign auto-increment ID
-				query = fmt.Sprintf(`INSERT INTO "talkgroups" ("delay", "frequency", "label", "name", "order", "systemId", "tagId", "talkgroupRef", "type", "toneDetectionEnabled", "toneSets") VALUES (%d, %d, '%s', '%s', %d, %d, %d, %d, '%s', %t, '%s')`, talkgroup.Delay, talkgroup.Frequency, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, systemId, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, escapeQuotes(toneSetsJson))
+				query = fmt.Sprintf(`INSERT INTO "talkgroups" ("delay", "ephemeral", "ephemeralTtl", "frequency", "gain", "displayConfidence", "label", "name", "order", "priority", "systemId", "tagId", "talkgroupRef", "type", "toneDetectionEnabled", "toneLearningUntil", "toneSets") VALUES (%d, %t, %d, %d, %v, %v, '%s', '%s', %d, %d, %d, %d, %d, '%s', %t, %d, '%s')`, talkgroup.Delay, talkgroup.Ephemeral, talkgroup.EphemeralTtl, talkgroup.Frequency, talkgroup.Gain, talkgroup.DisplayConfidence, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, talkgroup.Priority, systemId, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, talkgroup.ToneLearningUntil, escapeQuotes(toneSetsJson))
 			}
 
 			if dbType == DbTypePostgresql {
@@ -458,7 +559,7 @@ func (talkgroups *Talkgroups) WriteTx(tx *sql.Tx, systemId uint64, dbType string
 					toneSetsJson = json
 				}
 			}
-			query = fmt.Sprintf(`UPDATE "talkgroups" SET "delay" = %d, "frequency" = %d, "label" = '%s', "name" = '%s', "order" = %d, "tagId" = %d, "talkgroupRef" = %d, "type" = '%s', "toneDetectionEnabled" = %t, "toneSets" = '%s' WHERE "talkgroupId" = %d`, talkgroup.Delay, talkgroup.Frequency, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, escapeQuotes(toneSetsJson), talkgroup.Id)
+			query = fmt.Sprintf(`UPDATE "talkgroups" SET "delay" = %d, "ephemeral" = %t, "ephemeralTtl" = %d, "frequency" = %d, "gain" = %v, "displayConfidence" = %v, "label" = '%s', "name" = '%s', "order" = %d, "priority" = %d, "tagId" = %d, "talkgroupRef" = %d, "type" = '%s', "toneDetectionEnabled" = %t, "toneLearningUntil" = %d, "toneSets" = '%s' WHERE "talkgroupId" = %d`, talkgroup.Delay, talkgroup.Ephemeral, talkgroup.EphemeralTtl, talkgroup.Frequency, talkgroup.Gain, talkgroup.DisplayConfidence, escapeQuotes(talkgroup.Label), escapeQuotes(talkgroup.Name), talkgroup.Order, talkgroup.Priority, validTagId, talkgroup.TalkgroupRef, talkgroup.Kind, talkgroup.ToneDetectionEnabled, talkgroup.ToneLearningUntil, escapeQuotes(toneSetsJson), talkgroup.Id)
 			if _, err = tx.Exec(query); err != nil {
 				break
 			}
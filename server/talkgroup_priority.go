@@ -0,0 +1,38 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import "sort"
+
+// isPriorityCall reports whether the call belongs to a talkgroup marked with a priority above normal
+func isPriorityCall(call *Call) bool {
+	return call != nil && call.Talkgroup != nil && call.Talkgroup.Priority > 0
+}
+
+// checksDuplicate reports whether the call goes through duplicate detection, priority talkgroups never do
+func (controller *Controller) checksDuplicate(call *Call) bool {
+	return !controller.Options.DisableDuplicateDetection && !isPriorityCall(call)
+}
+
+// sortAlertsByPriority moves the alerts of the highest priority talkgroups first,
+// alerts of equal priority keep their order
+func sortAlertsByPriority(alerts []map[string]any) {
+	sort.SliceStable(alerts, func(i int, j int) bool {
+		priorityI, _ := alerts[i]["priority"].(uint)
+		priorityJ, _ := alerts[j]["priority"].(uint)
+		return priorityI > priorityJ
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import "testing"
+
+func TestTalkgroupPriorityBypassesDelayAndDedupe(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), UserGroups: NewUserGroups()}
+	controller.Options.DefaultSystemDelay = 5
+	delayer := NewDelayer(controller)
+
+	system := &System{Id: 1, SystemRef: 1, Delay: 3}
+	normal := &Call{System: system, Talkgroup: &Talkgroup{Id: 1, TalkgroupRef: 100, Delay: 10}}
+	priority := &Call{System: system, Talkgroup: &Talkgroup{Id: 2, TalkgroupRef: 200, Delay: 10, Priority: 1}}
+
+	user := &User{Delay: 7}
+
+	if delay := delayer.getSystemDelay(normal); delay != 10 {
+		t.Errorf("normal talkgroup should keep its delay, got %d", delay)
+	}
+	if delay := controller.userEffectiveDelay(user, normal, 5); delay != 7 {
+		t.Errorf("normal talkgroup should keep the user delay, got %d", delay)
+	}
+	if !controller.checksDuplicate(normal) {
+		t.Error("normal talkgroup should go through duplicate detection")
+	}
+
+	if delay := delayer.getSystemDelay(priority); delay != 0 {
+		t.Errorf("priority talkgroup should not be delayed, got %d", delay)
+	}
+	if delay := controller.userEffectiveDelay(user, priority, 5); delay != 0 {
+		t.Errorf("priority talkgroup should bypass the user delay, got %d", delay)
+	}
+	if controller.checksDuplicate(priority) {
+		t.Error("priority talkgroup should be exempt from duplicate detection")
+	}
+
+	controller.Options.DisableDuplicateDetection = true
+	if controller.checksDuplicate(normal) {
+		t.Error("duplicate detection should stay disabled when turned off")
+	}
+}
+
+func TestSortAlertsByPriority(t *testing.T) {
+	alerts := []map[string]any{
+		{"alertId": 1},
+		{"alertId": 2, "priority": uint(1)},
+		{"alertId": 3},
+		{"alertId": 4, "priority": uint(5)},
+		{"alertId": 5, "priority": uint(1)},
+	}
+
+	sortAlertsByPriority(alerts)
+
+	expected := []int{4, 2, 5, 1, 3}
+	for i, id := range expected {
+		if alerts[i]["alertId"] != id {
+			t.Fatalf("unexpected order at %d: got alert %v, want %d", i, alerts[i]["alertId"], id)
+		}
+	}
+}
+
+func TestSystemsFromMapKeepsSettings(t *testing.T) {
+	systems := NewSystems()
+	systems.FromMap([]any{map[string]any{"id": float64(1), "label": "County", "defaultTagId": float64(3), "talkgroups": []any{
+		map[string]any{"id": float64(10), "label": "Fire", "priority": float64(2), "gain": float64(6)},
+	}}})
+
+	// saved by an admin unaware of the settings
+	systems.FromMap([]any{map[string]any{"id": float64(1), "label": "County", "talkgroups": []any{
+		map[string]any{"id": float64(10), "label": "Fire Dispatch"},
+		map[string]any{"label": "EMS"},
+	}}})
+
+	system := systems.List[0]
+	if system.DefaultTagId != 3 {
+		t.Errorf("expected the default tag kept, got %d", system.DefaultTagId)
+	}
+
+	talkgroups := system.Talkgroups.List
+	if talkgroups[0].Label != "Fire Dispatch" || talkgroups[0].Priority != 2 || talkgroups[0].Gain != 6 {
+		t.Errorf("expected the talkgroup settings kept, got %+v", talkgroups[0])
+	}
+	if talkgroups[1].Priority != 0 || talkgroups[1].Gain != 0 {
+		t.Errorf("expected a new talkgroup without settings, got %+v", talkgroups[1])
+	}
+}
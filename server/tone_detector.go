@@ -50,13 +50,14 @@ type Tone struct {
 
 // ToneSet represents a configured set of tones for a talkgroup
 type ToneSet struct {
-	Id          string    `json:"id"`          // Unique identifier
-	Label       string    `json:"label"`       // User-friendly name (e.g., "Fire Dept", "EMS")
-	ATone       *ToneSpec `json:"aTone"`       // First tone specification (optional)
-	BTone       *ToneSpec `json:"bTone"`       // Second tone specification (optional)
-	LongTone    *ToneSpec `json:"longTone"`    // Long tone specification (optional)
-	Tolerance   float64   `json:"tolerance"`   // Frequency tolerance in Hz (default: ±10Hz)
-	MinDuration float64   `json:"minDuration"` // Minimum duration in seconds to be considered valid
+	Id           string    `json:"id"`                     // Unique identifier
+	Label        string    `json:"label"`                  // User-friendly name (e.g., "Fire Dept", "EMS")
+	ATone        *ToneSpec `json:"aTone"`                  // First tone specification (optional)
+	BTone        *ToneSpec `json:"bTone"`                  // Second tone specification (optional)
+	LongTone     *ToneSpec `json:"longTone"`               // Long tone specification (optional)
+	Tolerance    float64   `json:"tolerance"`              // Frequency tolerance in Hz (default: ±10Hz)
+	MinDuration  float64   `json:"minDuration"`            // Minimum duration in seconds to be considered valid
+	DTMFSequence string    `json:"dtmfSequence,omitempty"` // DTMF digits of a DTMF tone-out instead of tones (not detected yet)
 }
 
 // ToneSpec defines the expected frequency and duration ranges for a tone
@@ -76,6 +77,8 @@ type ToneSequence struct {
 	HasTones        bool       `json:"hasTones"`        // Quick flag for filtering
 	MatchedToneSet  *ToneSet   `json:"matchedToneSet"`  // Which configured tone set matched the full pattern (if any)
 	MatchedToneSets []*ToneSet `json:"matchedToneSets"` // All configured tone sets that matched any detected tone
+
+	measured []Tone // every sustained tone, matched or not, see ToneLearner
 }
 
 // PendingToneSequence represents tones detected on a call that are waiting to be attached to a subsequent voice call
@@ -170,13 +173,13 @@ func (detector *ToneDetector) Detect(audio []byte, audioMime string, toneSets []
 	}
 
 	// Perform FFT analysis to detect tones
-	detectedTones := detector.analyzeFrequencies(samples, sampleRate, toneSets)
+	detectedTones, measured := detector.analyzeFrequencies(samples, sampleRate, toneSets)
 
 	// Log tone detection analysis
 	fmt.Printf("tone detection: analyzed %d samples at %d Hz, found %d potential tone detections\n", len(samples), sampleRate, len(detectedTones))
 
 	if len(detectedTones) == 0 {
-		return &ToneSequence{Tones: []Tone{}, HasTones: false}, nil
+		return &ToneSequence{Tones: []Tone{}, HasTones: false, measured: measured}, nil
 	}
 
 	// Build tone sequence
@@ -184,6 +187,7 @@ func (detector *ToneDetector) Detect(audio []byte, audioMime string, toneSets []
 		Tones:    detectedTones,
 		HasTones: true,
 		Duration: float64(len(samples)) / float64(sampleRate),
+		measured: measured,
 	}
 
 	// Identify ATone, BTone, LongTone based on what they matched in the tone sets
@@ -280,7 +284,7 @@ func parabolicInterpolate(yMinus, y0, yPlus float64) float64 {
 // analyzeFrequencies performs FFT analysis to detect sustained tones
 // Enhanced with dynamic noise floor estimation, parabolic interpolation, and force-split detection
 // Techniques inspired by icad_tone_detection (thegreatcodeholio) for improved analog channel detection
-func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate int, toneSets []ToneSet) []Tone {
+func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate int, toneSets []ToneSet) ([]Tone, []Tone) {
 	windowSize := 2048     // FFT window size
 	hopSize := 512         // Slide window by this much
 	minToneDuration := 0.6 // Minimum 600ms to be considered a tone
@@ -340,7 +344,7 @@ func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate i
 
 	// Calculate dynamic noise floor (20th percentile method from icad_tone_detection)
 	if len(framePeaks) == 0 {
-		return []Tone{}
+		return []Tone{}, nil
 	}
 
 	// Find global peak
@@ -352,7 +356,7 @@ func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate i
 	}
 
 	if globalPeak < 1e-20 {
-		return []Tone{}
+		return []Tone{}, nil
 	}
 
 	// Calculate relative dB for each frame
@@ -576,6 +580,7 @@ func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate i
 
 	// Convert merged detections to tones (filter by duration and match against tone sets)
 	var tones []Tone
+	var measured []Tone // every merged detection, matched or not
 	var allDetections []freqDetection // For logging all detected frequencies (before merging)
 
 	// Log all raw detections for debugging
@@ -591,6 +596,13 @@ func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate i
 	for _, md := range mergedDetections {
 		duration := md.endTime - md.startTime
 
+		measured = append(measured, Tone{
+			Frequency: md.frequency,
+			StartTime: md.startTime,
+			EndTime:   md.endTime,
+			Duration:  duration,
+		})
+
 		// Check if frequency matches ANY configured tone set (check ALL, don't stop at first match)
 		matchedToneSets := []string{}         // Track all matches for logging
 		matchedTypes := make(map[string]bool) // Track which types this tone matched (A, B, Long)
@@ -753,7 +765,7 @@ func (detector *ToneDetector) analyzeFrequencies(samples []float64, sampleRate i
 		fmt.Printf("no tones detected meeting minimum duration (%.1fs)\n", minToneDuration)
 	}
 
-	return tones
+	return tones, measured
 }
 
 // dft performs Fast Fourier Transform (FFT) on real-valued samples
@@ -817,6 +829,11 @@ func (detector *ToneDetector) MatchToneSets(detected *ToneSequence, configured [
 func (detector *ToneDetector) matchesToneSet(detected *ToneSequence, toneSet ToneSet) bool {
 	baseTolerance := toneSet.Tolerance
 
+	// DTMF tone-outs aren't detected yet, without tones to check they would otherwise match any call
+	if toneSet.ATone == nil && toneSet.BTone == nil && toneSet.LongTone == nil {
+		return false
+	}
+
 	// If tone set only has a long tone (no A/B tones), only check for long tone
 	if toneSet.LongTone != nil && toneSet.ATone == nil && toneSet.BTone == nil {
 		actualTolerance := baseTolerance
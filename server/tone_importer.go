@@ -30,13 +30,16 @@ import (
 type ToneImportFormat string
 
 const (
-	ToneImportFormatTwoTone ToneImportFormat = "twotone"
-	ToneImportFormatCSV     ToneImportFormat = "csv"
+	ToneImportFormatTwoTone    ToneImportFormat = "twotone"
+	ToneImportFormatCSV        ToneImportFormat = "csv"
+	ToneImportFormatQuickCall2 ToneImportFormat = "quickcall2"
+	ToneImportFormatDTMF       ToneImportFormat = "dtmf"
 )
 
 type ToneImportRequest struct {
 	Format  string `json:"format"`
 	Content string `json:"content"`
+	Dedupe  bool   `json:"dedupe"` // collapse the tone sets with the same tones, keeping the first label
 }
 
 type ToneImportResponse struct {
@@ -51,6 +54,44 @@ type toneImportResult struct {
 	warnings []string
 }
 
+// the range of the two-tone paging frequencies, a tone outside of it most likely being a typo in the imported file
+const (
+	toneImportBandMin = 250.0
+	toneImportBandMax = 4000.0
+)
+
+// add appends a parsed tone set unless one of its frequencies isn't positive, and warns of the
+// frequencies outside of the paging band. The warnings start with the source, naming the section
+// or row of the imported file.
+func (result *toneImportResult) add(source string, toneSet *ToneSet) {
+	tones := []struct {
+		name string
+		spec *ToneSpec
+	}{{"A", toneSet.ATone}, {"B", toneSet.BTone}, {"long", toneSet.LongTone}}
+
+	warnings := []string{}
+
+	for _, tone := range tones {
+		if tone.spec == nil {
+			continue
+		}
+
+		f := tone.spec.Frequency
+
+		switch {
+		case math.IsNaN(f) || math.IsInf(f, 0) || f <= 0:
+			result.warnings = append(result.warnings, fmt.Sprintf("%s skipped, its %s tone frequency %v Hz is invalid", source, tone.name, f))
+			return
+
+		case f < toneImportBandMin || f > toneImportBandMax:
+			warnings = append(warnings, fmt.Sprintf("%s %s tone frequency %v Hz is outside the %v-%v Hz paging band", source, tone.name, f, toneImportBandMin, toneImportBandMax))
+		}
+	}
+
+	result.toneSets = append(result.toneSets, *toneSet)
+	result.warnings = append(result.warnings, warnings...)
+}
+
 func ParseToneImport(format string, content string) (*toneImportResult, error) {
 	content = strings.TrimSpace(content)
 	if content == "" {
@@ -62,6 +103,10 @@ func ParseToneImport(format string, content string) (*toneImportResult, error) {
 		return parseTwoToneDetectConfig(content)
 	case ToneImportFormatCSV:
 		return parseToneCSV(content)
+	case ToneImportFormatQuickCall2:
+		return parseQuickCall2(content)
+	case ToneImportFormatDTMF:
+		return parseDTMF(content)
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
@@ -118,7 +163,7 @@ func parseTwoToneDetectConfig(content string) (*toneImportResult, error) {
 
 	for _, sec := range sections {
 		if toneSet, warning := toneSetFromTwoToneSection(sec); toneSet != nil {
-			result.toneSets = append(result.toneSets, *toneSet)
+			result.add(fmt.Sprintf("section %s", sec.name), toneSet)
 			if warning != "" {
 				result.warnings = append(result.warnings, warning)
 			}
@@ -258,7 +303,8 @@ func parseToneCSV(content string) (*toneImportResult, error) {
 		}
 
 		if toneSet, warning := toneSetFromCSVRecord(record, headerIndex); toneSet != nil {
-			result.toneSets = append(result.toneSets, *toneSet)
+			line, _ := reader.FieldPos(0)
+			result.add(fmt.Sprintf("csv row %d (%s)", line, toneSet.Label), toneSet)
 			if warning != "" {
 				result.warnings = append(result.warnings, warning)
 			}
@@ -351,6 +397,296 @@ func toneSetFromCSVRecord(record []string, headerIndex map[string]int) (*ToneSet
 	return toneSet, ""
 }
 
+// parseQuickCall2 reads the headerless label,A,B lines of Quick Call II paging exports, a line with a
+// single tone being a long tone alert. Malformed lines are skipped with a warning.
+func parseQuickCall2(content string) (*toneImportResult, error) {
+	result := &toneImportResult{
+		toneSets: []ToneSet{},
+		warnings: []string{},
+	}
+
+	content = strings.TrimLeft(content, "\ufeff")
+	scanner := bufio.NewScanner(strings.NewReader(content))
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+
+		record, err := reader.Read()
+		if err != nil {
+			result.warnings = append(result.warnings, fmt.Sprintf("line %d is malformed: %v", lineNumber, err))
+			continue
+		}
+
+		if toneSet, warning := toneSetFromQuickCall2Record(record); toneSet != nil {
+			result.add(fmt.Sprintf("line %d %s", lineNumber, toneSet.Label), toneSet)
+		} else {
+			result.warnings = append(result.warnings, fmt.Sprintf("line %d %s", lineNumber, warning))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quick call ii export: %w", err)
+	}
+
+	return result, nil
+}
+
+func toneSetFromQuickCall2Record(record []string) (*ToneSet, string) {
+	if len(record) < 2 || len(record) > 3 {
+		return nil, fmt.Sprintf("has %d fields, expected label,A,B", len(record))
+	}
+
+	label := strings.TrimSpace(record[0])
+	if label == "" {
+		return nil, "is missing a label"
+	}
+
+	frequencies := []float64{}
+	for _, field := range record[1:] {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f <= 0 {
+			return nil, fmt.Sprintf("%s has an invalid frequency %q", label, field)
+		}
+		frequencies = append(frequencies, f)
+	}
+
+	toneSet := &ToneSet{
+		Id:        uuid.NewString(),
+		Label:     label,
+		Tolerance: 10,
+	}
+
+	switch len(frequencies) {
+	case 0:
+		return nil, fmt.Sprintf("%s is missing tone frequencies", label)
+	case 1:
+		toneSet.LongTone = &ToneSpec{
+			Frequency:   frequencies[0],
+			MinDuration: 5.0,
+		}
+	default:
+		toneSet.ATone = &ToneSpec{
+			Frequency:   frequencies[0],
+			MinDuration: 0.6,
+		}
+		toneSet.BTone = &ToneSpec{
+			Frequency:   frequencies[1],
+			MinDuration: 0.6,
+		}
+	}
+
+	toneSet.MinDuration = minDurationFromToneSpecs(toneSet)
+
+	return toneSet, ""
+}
+
+// maxDTMFSequenceLength bounds the digits of a DTMF tone-out, longer ones being a pasted phone number or a typo
+const maxDTMFSequenceLength = 16
+
+// parseDTMF reads the label,digits lines of DTMF tone-outs, e.g. Station 5,1234. Lines with invalid
+// digits are skipped with a warning.
+func parseDTMF(content string) (*toneImportResult, error) {
+	result := &toneImportResult{
+		toneSets: []ToneSet{},
+		warnings: []string{},
+	}
+
+	content = strings.TrimLeft(content, "\ufeff")
+	scanner := bufio.NewScanner(strings.NewReader(content))
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+
+		record, err := reader.Read()
+		if err != nil {
+			result.warnings = append(result.warnings, fmt.Sprintf("line %d is malformed: %v", lineNumber, err))
+			continue
+		}
+
+		if toneSet, warning := toneSetFromDTMFRecord(record); toneSet != nil {
+			result.toneSets = append(result.toneSets, *toneSet)
+		} else {
+			result.warnings = append(result.warnings, fmt.Sprintf("line %d %s", lineNumber, warning))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dtmf import: %w", err)
+	}
+
+	return result, nil
+}
+
+func toneSetFromDTMFRecord(record []string) (*ToneSet, string) {
+	if len(record) != 2 {
+		return nil, fmt.Sprintf("has %d fields, expected label,digits", len(record))
+	}
+
+	label := strings.TrimSpace(record[0])
+	if label == "" {
+		return nil, "is missing a label"
+	}
+
+	digits := strings.ToUpper(strings.TrimSpace(record[1]))
+	if digits == "" {
+		return nil, fmt.Sprintf("%s is missing DTMF digits", label)
+	}
+	if len(digits) > maxDTMFSequenceLength {
+		return nil, fmt.Sprintf("%s has %d DTMF digits %q, at most %d are expected", label, len(digits), record[1], maxDTMFSequenceLength)
+	}
+	for _, digit := range digits {
+		if !strings.ContainsRune("0123456789*#ABCD", digit) {
+			return nil, fmt.Sprintf("%s has an invalid DTMF digit %q in %q", label, digit, strings.TrimSpace(record[1]))
+		}
+	}
+
+	return &ToneSet{
+		Id:           uuid.NewString(),
+		Label:        label,
+		DTMFSequence: digits,
+	}, ""
+}
+
+// toneExportCSVHeader names the columns as parseToneCSV reads them once normalized
+var toneExportCSVHeader = []string{"label", "a_tone", "a_tone_length", "b_tone", "b_tone_length", "long_tone", "long_tone_length", "tolerance"}
+
+// dedupe drops the tone sets whose A, B and long tones all fall within the tolerance of those of
+// a tone set before them, with the same DTMF digits, warning of each label dropped
+func (result *toneImportResult) dedupe() {
+	sameSpec := func(kept *ToneSpec, spec *ToneSpec, tolerance float64) bool {
+		if kept == nil || spec == nil {
+			return kept == nil && spec == nil
+		}
+		return math.Abs(kept.Frequency-spec.Frequency) <= tolerance
+	}
+
+	toneSets := []ToneSet{}
+
+	for _, toneSet := range result.toneSets {
+		duplicate := false
+
+		for _, kept := range toneSets {
+			tolerance := kept.Tolerance
+			if tolerance <= 0 {
+				tolerance = 10
+			}
+
+			if kept.DTMFSequence == toneSet.DTMFSequence && sameSpec(kept.ATone, toneSet.ATone, tolerance) && sameSpec(kept.BTone, toneSet.BTone, tolerance) && sameSpec(kept.LongTone, toneSet.LongTone, tolerance) {
+				result.warnings = append(result.warnings, fmt.Sprintf("%s dropped, same tones as %s", toneSet.Label, kept.Label))
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			toneSets = append(toneSets, toneSet)
+		}
+	}
+
+	result.toneSets = toneSets
+}
+
+// ExportToneSets writes tone sets in one of the formats ParseToneImport reads back
+func ExportToneSets(format string, toneSets []ToneSet) (string, error) {
+	switch ToneImportFormat(strings.ToLower(strings.TrimSpace(format))) {
+	case ToneImportFormatTwoTone:
+		return exportTwoToneDetectConfig(toneSets), nil
+	case ToneImportFormatCSV:
+		return exportToneCSV(toneSets)
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func exportToneCSV(toneSets []ToneSet) (string, error) {
+	var b strings.Builder
+
+	writer := csv.NewWriter(&b)
+	writer.Write(toneExportCSVHeader)
+
+	for _, toneSet := range toneSets {
+		record := []string{toneSet.Label}
+		for _, spec := range []*ToneSpec{toneSet.ATone, toneSet.BTone, toneSet.LongTone} {
+			if spec == nil {
+				record = append(record, "", "")
+			} else {
+				record = append(record, formatToneNumber(spec.Frequency), formatToneNumber(spec.MinDuration))
+			}
+		}
+		record = append(record, formatToneNumber(toneSet.Tolerance))
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+func exportTwoToneDetectConfig(toneSets []ToneSet) string {
+	var b strings.Builder
+
+	sections := map[string]bool{}
+
+	for i, toneSet := range toneSets {
+		label := strings.Join(strings.Fields(toneSet.Label), " ")
+
+		// sections are named after the label, made unique
+		name := strings.NewReplacer("[", "", "]", "").Replace(label)
+		if name == "" {
+			name = fmt.Sprintf("Tone Set %d", i+1)
+		}
+		for base, n := name, 2; sections[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s %d", base, n)
+		}
+		sections[strings.ToLower(name)] = true
+
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", name)
+		if label != "" {
+			fmt.Fprintf(&b, "description = %s\n", label)
+		}
+		if toneSet.ATone != nil {
+			fmt.Fprintf(&b, "atone = %s\natonelength = %s\n", formatToneNumber(toneSet.ATone.Frequency), formatToneNumber(toneSet.ATone.MinDuration))
+		}
+		if toneSet.BTone != nil {
+			fmt.Fprintf(&b, "btone = %s\nbtonelength = %s\n", formatToneNumber(toneSet.BTone.Frequency), formatToneNumber(toneSet.BTone.MinDuration))
+		}
+		if toneSet.LongTone != nil {
+			fmt.Fprintf(&b, "longtone = %s\nlongtonelength = %s\n", formatToneNumber(toneSet.LongTone.Frequency), formatToneNumber(toneSet.LongTone.MinDuration))
+		}
+		fmt.Fprintf(&b, "tone_tolerance = %s\n", formatToneNumber(toneSet.Tolerance))
+	}
+
+	return b.String()
+}
+
+func formatToneNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func getDurationFallback(data map[string]string, keys ...string) float64 {
 	for _, key := range keys {
 		if value, ok := data[strings.ToLower(key)]; ok {
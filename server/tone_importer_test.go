@@ -0,0 +1,260 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseQuickCall2(t *testing.T) {
+	content := "\ufeffStation 1,853.2,960.0\n" +
+		"\"Engine 5, Ladder 5\",1092.4,1379.4\n" +
+		"\n" +
+		"County Siren,1500\n" +
+		"Broken,abc,960\n" +
+		",853.2,960\n" +
+		"Too Many,1,2,3\n" +
+		"Empty Tones,,\n"
+
+	result, err := ParseToneImport("QuickCall2", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.toneSets) != 3 {
+		t.Fatalf("expected 3 tone sets, got %d: %+v", len(result.toneSets), result.toneSets)
+	}
+	if len(result.warnings) != 4 {
+		t.Errorf("expected 4 warnings, got %q", result.warnings)
+	}
+
+	station := result.toneSets[0]
+	if station.Label != "Station 1" || station.ATone.Frequency != 853.2 || station.BTone.Frequency != 960 || station.LongTone != nil {
+		t.Errorf("unexpected two-tone set %+v", station)
+	}
+	if station.ATone.MinDuration != 0.6 || station.BTone.MinDuration != 0.6 || station.MinDuration != 0.6 || station.Tolerance != 10 {
+		t.Errorf("expected default durations and tolerance, got %+v", station)
+	}
+
+	if result.toneSets[1].Label != "Engine 5, Ladder 5" {
+		t.Errorf("expected a quoted label, got %q", result.toneSets[1].Label)
+	}
+
+	siren := result.toneSets[2]
+	if siren.ATone != nil || siren.BTone != nil || siren.LongTone == nil || siren.LongTone.Frequency != 1500 || siren.LongTone.MinDuration != 5 {
+		t.Errorf("expected a single tone to be a long tone, got %+v", siren)
+	}
+}
+
+func TestToneImportFrequencyBand(t *testing.T) {
+	config := "[Station 1]\n" +
+		"atone = 853.2\n" +
+		"btone = 10000\n" +
+		"\n" +
+		"[Station 2]\n" +
+		"atone = 0\n" +
+		"btone = 960\n" +
+		"\n" +
+		"[Siren]\n" +
+		"longtone = 1500\n"
+
+	result, err := ParseToneImport("twotone", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.toneSets) != 2 || result.toneSets[0].Label != "Station 1" || result.toneSets[1].Label != "Siren" {
+		t.Errorf("expected the out of band tone set kept and the invalid one skipped, got %+v", result.toneSets)
+	}
+	want := []string{
+		"section Station 1 B tone frequency 10000 Hz is outside the 250-4000 Hz paging band",
+		"section Station 2 skipped, its A tone frequency 0 Hz is invalid",
+	}
+	if strings.Join(result.warnings, "|") != strings.Join(want, "|") {
+		t.Errorf("warnings = %q, want %q", result.warnings, want)
+	}
+
+	csv := "label,a_tone,b_tone\n" +
+		"Engine 5,1092.4,1379.4\n" +
+		"Engine 6,0.5,1379.4\n" +
+		"Engine 7,-300,1379.4\n"
+
+	result, err = ParseToneImport("csv", csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.toneSets) != 2 {
+		t.Errorf("expected the negative frequency rejected, got %+v", result.toneSets)
+	}
+	want = []string{
+		"csv row 3 (Engine 6) A tone frequency 0.5 Hz is outside the 250-4000 Hz paging band",
+		"csv row 4 (Engine 7) skipped, its A tone frequency -300 Hz is invalid",
+	}
+	if strings.Join(result.warnings, "|") != strings.Join(want, "|") {
+		t.Errorf("warnings = %q, want %q", result.warnings, want)
+	}
+}
+
+func TestParseDTMF(t *testing.T) {
+	content := "Station 5,1234\n" +
+		"# comment\n" +
+		"\"Engine 5, Ladder 5\",*5a#\n" +
+		"Typo,12E4\n" +
+		"Phone,5551234567890123456\n" +
+		"No Digits,\n" +
+		"Station 5 Only\n"
+
+	result, err := ParseToneImport("DTMF", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.toneSets) != 2 {
+		t.Fatalf("expected 2 tone sets, got %+v", result.toneSets)
+	}
+	if station := result.toneSets[0]; station.Label != "Station 5" || station.DTMFSequence != "1234" || station.ATone != nil || station.BTone != nil || station.LongTone != nil {
+		t.Errorf("unexpected dtmf set %+v", station)
+	}
+	if digits := result.toneSets[1].DTMFSequence; digits != "*5A#" {
+		t.Errorf("expected the letter digits upper cased, got %q", digits)
+	}
+
+	want := []string{
+		`line 4 Typo has an invalid DTMF digit 'E' in "12E4"`,
+		`line 5 Phone has 19 DTMF digits "5551234567890123456", at most 16 are expected`,
+		"line 6 No Digits is missing DTMF digits",
+		"line 7 has 1 fields, expected label,digits",
+	}
+	if strings.Join(result.warnings, "|") != strings.Join(want, "|") {
+		t.Errorf("warnings = %q, want %q", result.warnings, want)
+	}
+
+	// without tones to check, a dtmf set never matches the detected tones
+	detected := &ToneSequence{HasTones: true, Tones: []Tone{{Frequency: 853.2, Duration: 1}}}
+	if matched := (&ToneDetector{}).MatchToneSet(detected, result.toneSets); matched != nil {
+		t.Errorf("expected no match for dtmf sets, got %+v", matched)
+	}
+
+	result.toneSets = append(result.toneSets, ToneSet{Label: "Station 5 Again", DTMFSequence: "1234"})
+	result.warnings = nil
+	result.dedupe()
+	if len(result.toneSets) != 2 || len(result.warnings) != 1 {
+		t.Errorf("expected only the same digits collapsed, got %+v %q", result.toneSets, result.warnings)
+	}
+}
+
+func TestToneImportDedupe(t *testing.T) {
+	content := "Station 1,853.2,960.0\n" +
+		"Station 1 Alt,855,958\n" +
+		"Station 2,853.2,1092.4\n" +
+		"Siren,853.2\n" +
+		"STA 1,870,975\n" +
+		"Siren Copy,850\n"
+
+	result, err := ParseToneImport("quickcall2", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.toneSets) != 6 {
+		t.Fatalf("expected every tone set without dedupe, got %d", len(result.toneSets))
+	}
+
+	result.dedupe()
+
+	labels := []string{}
+	for _, toneSet := range result.toneSets {
+		labels = append(labels, toneSet.Label)
+	}
+	if got := strings.Join(labels, "|"); got != "Station 1|Station 2|Siren|STA 1" {
+		t.Errorf("expected the first label of each tone signature, got %s", got)
+	}
+
+	want := []string{"Station 1 Alt dropped, same tones as Station 1", "Siren Copy dropped, same tones as Siren"}
+	if strings.Join(result.warnings, "|") != strings.Join(want, "|") {
+		t.Errorf("expected the dropped labels in the warnings, got %q", result.warnings)
+	}
+}
+
+func TestExportToneSetsRoundTrip(t *testing.T) {
+	config := "[Station 1]\n" +
+		"description = Station 1, Engine\n" +
+		"atone = 853.2\n" +
+		"atonelength = 0.8\n" +
+		"btone = 960\n" +
+		"btonelength = 2.5\n" +
+		"\n" +
+		"[Siren]\n" +
+		"description = Siren\n" +
+		"longtone = 1500\n" +
+		"longtonelength = 4\n" +
+		"tone_tolerance = 15\n" +
+		"\n" +
+		"[Siren 2]\n" +
+		"description = Siren\n" +
+		"longtone = 1200\n"
+
+	parsed, err := ParseToneImport("twotone", config)
+	if err != nil || len(parsed.toneSets) != 3 {
+		t.Fatalf("expected 3 tone sets, got %v %v", parsed, err)
+	}
+
+	equivalent := func(a []ToneSet, b []ToneSet) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		sameSpec := func(a *ToneSpec, b *ToneSpec) bool {
+			if a == nil || b == nil {
+				return a == nil && b == nil
+			}
+			return a.Frequency == b.Frequency && a.MinDuration == b.MinDuration
+		}
+		for i := range a {
+			if a[i].Label != b[i].Label || a[i].Tolerance != b[i].Tolerance || a[i].MinDuration != b[i].MinDuration ||
+				!sameSpec(a[i].ATone, b[i].ATone) || !sameSpec(a[i].BTone, b[i].BTone) || !sameSpec(a[i].LongTone, b[i].LongTone) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, format := range []string{"twotone", "csv"} {
+		content, err := ExportToneSets(format, parsed.toneSets)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		reparsed, err := ParseToneImport(format, content)
+		if err != nil {
+			t.Fatalf("%s: %v in\n%s", format, err, content)
+		}
+
+		if !equivalent(parsed.toneSets, reparsed.toneSets) {
+			t.Errorf("%s: expected %+v, got %+v from\n%s", format, parsed.toneSets, reparsed.toneSets, content)
+		}
+		if len(reparsed.warnings) > 0 {
+			t.Errorf("%s: unexpected warnings %q", format, reparsed.warnings)
+		}
+	}
+
+	csv, _ := ExportToneSets("csv", nil)
+	if strings.TrimSpace(csv) != strings.Join(toneExportCSVHeader, ",") {
+		t.Errorf("expected only the header, got %q", csv)
+	}
+
+	if _, err := ExportToneSets("xml", parsed.toneSets); err == nil {
+		t.Error("expected an unsupported format to fail")
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	toneLearningTolerance   = 10.0 // Hz within which two measured tones are the same tone
+	toneLearningMaxGap      = 0.5  // seconds between the end of an A tone and the start of its B tone
+	toneLearningMinLongTone = 2.0  // seconds a single tone lasts to be a long tone page
+	toneLearningMinSplit    = 20.0 // Hz between the A and B tones of a two-tone page
+	toneLearningMaxHours    = 720  // longest learning window an admin can start, 30 days
+)
+
+// ToneSetSuggestion is a tone set learned from the pages of a talkgroup, waiting for admin review
+type ToneSetSuggestion struct {
+	Id          uint64   `json:"id"`
+	SystemId    uint64   `json:"systemId"`
+	TalkgroupId uint64   `json:"talkgroupId"`
+	ToneSet     *ToneSet `json:"toneSet"`
+	Occurrences int      `json:"occurrences"` // pages measured with this tone set
+	FirstSeenAt int64    `json:"firstSeenAt"`
+	LastSeenAt  int64    `json:"lastSeenAt"`
+	Status      string   `json:"status"` // "pending", "approved", "rejected"
+}
+
+// ToneLearner proposes tone sets for the talkgroups in their tone learning window
+type ToneLearner struct {
+	controller *Controller
+	mutex      sync.Mutex
+}
+
+// NewToneLearner creates a new tone learner
+func NewToneLearner(controller *Controller) *ToneLearner {
+	return &ToneLearner{
+		controller: controller,
+	}
+}
+
+// learningTones reports whether tone sets are learned from the pages of the talkgroup
+func (talkgroup *Talkgroup) learningTones(now time.Time) bool {
+	return talkgroup.ToneLearningUntil > now.UnixMilli()
+}
+
+// toneSetCandidate returns the tone set paged by the measured tones of a call, an A tone followed by a
+// B tone or else a single long tone, nil when the tones don't make a page
+func toneSetCandidate(tones []Tone) *ToneSet {
+	sorted := append([]Tone{}, tones...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartTime < sorted[j].StartTime })
+
+	toneSpec := func(tone Tone) *ToneSpec {
+		// leave room for shorter pages of the same tone set
+		return &ToneSpec{
+			Frequency:   math.Round(tone.Frequency*10) / 10,
+			MinDuration: math.Floor(tone.Duration*0.8*10) / 10,
+		}
+	}
+
+	var toneSet *ToneSet
+
+	for i := 0; i+1 < len(sorted) && toneSet == nil; i++ {
+		a, b := sorted[i], sorted[i+1]
+		if b.StartTime-a.EndTime <= toneLearningMaxGap && math.Abs(a.Frequency-b.Frequency) >= toneLearningMinSplit {
+			toneSet = &ToneSet{ATone: toneSpec(a), BTone: toneSpec(b)}
+			toneSet.Label = fmt.Sprintf("Learned %.1f/%.1f Hz", toneSet.ATone.Frequency, toneSet.BTone.Frequency)
+		}
+	}
+
+	if toneSet == nil {
+		for _, tone := range sorted {
+			if tone.Duration >= toneLearningMinLongTone && (toneSet == nil || tone.Duration > toneSet.LongTone.MinDuration/0.8) {
+				toneSet = &ToneSet{LongTone: toneSpec(tone)}
+				toneSet.Label = fmt.Sprintf("Learned %.1f Hz long tone", toneSet.LongTone.Frequency)
+			}
+		}
+	}
+
+	if toneSet == nil {
+		return nil
+	}
+
+	toneSet.Id = uuid.NewString()
+	toneSet.Tolerance = toneLearningTolerance
+	toneSet.MinDuration = minDurationFromToneSpecs(toneSet)
+
+	return toneSet
+}
+
+// sameToneSet reports whether two tone sets have the same tones, within the learning tolerance
+func sameToneSet(a *ToneSet, b *ToneSet) bool {
+	sameSpec := func(a *ToneSpec, b *ToneSpec) bool {
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		return math.Abs(a.Frequency-b.Frequency) <= toneLearningTolerance
+	}
+
+	return sameSpec(a.ATone, b.ATone) && sameSpec(a.BTone, b.BTone) && sameSpec(a.LongTone, b.LongTone)
+}
+
+// mergeToneSetCandidate folds a candidate into the tone set of a suggestion seen occurrences times,
+// averaging the frequencies and keeping the shortest durations
+func mergeToneSetCandidate(toneSet *ToneSet, candidate *ToneSet, occurrences int) {
+	merge := func(spec *ToneSpec, measured *ToneSpec) {
+		if spec == nil || measured == nil {
+			return
+		}
+		spec.Frequency = math.Round((spec.Frequency*float64(occurrences)+measured.Frequency)/float64(occurrences+1)*10) / 10
+		spec.MinDuration = math.Min(spec.MinDuration, measured.MinDuration)
+	}
+
+	merge(toneSet.ATone, candidate.ATone)
+	merge(toneSet.BTone, candidate.BTone)
+	merge(toneSet.LongTone, candidate.LongTone)
+	toneSet.MinDuration = minDurationFromToneSpecs(toneSet)
+}
+
+// Observe proposes the tone set paged by the measured tones of a call, unless the talkgroup already has it
+// or it was rejected
+func (learner *ToneLearner) Observe(call *Call, tones []Tone) {
+	if call.System == nil || call.Talkgroup == nil {
+		return
+	}
+
+	candidate := toneSetCandidate(tones)
+	if candidate == nil {
+		return
+	}
+
+	for i := range call.Talkgroup.ToneSets {
+		if sameToneSet(&call.Talkgroup.ToneSets[i], candidate) {
+			return
+		}
+	}
+
+	learner.mutex.Lock()
+	defer learner.mutex.Unlock()
+
+	suggestions, err := learner.readSuggestions(fmt.Sprintf(`WHERE "talkgroupId" = %d AND "status" <> 'approved'`, call.Talkgroup.Id))
+	if err != nil {
+		learner.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("tone learning: %v", err))
+		return
+	}
+
+	now := time.Now().UnixMilli()
+
+	for _, suggestion := range suggestions {
+		if !sameToneSet(suggestion.ToneSet, candidate) {
+			continue
+		}
+
+		if suggestion.Status == "rejected" {
+			return
+		}
+
+		mergeToneSetCandidate(suggestion.ToneSet, candidate, suggestion.Occurrences)
+
+		toneSetJson, err := json.Marshal(suggestion.ToneSet)
+		if err != nil {
+			return
+		}
+
+		query := fmt.Sprintf(`UPDATE "toneSetSuggestions" SET "toneSet" = $1, "occurrences" = %d, "lastSeenAt" = %d WHERE "toneSetSuggestionId" = %d`, suggestion.Occurrences+1, now, suggestion.Id)
+		if _, err := learner.controller.Database.Sql.Exec(query, string(toneSetJson)); err != nil {
+			learner.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("tone learning: %v", err))
+		}
+		return
+	}
+
+	toneSetJson, err := json.Marshal(candidate)
+	if err != nil {
+		return
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "toneSetSuggestions" ("systemId", "talkgroupId", "toneSet", "occurrences", "firstSeenAt", "lastSeenAt", "status") VALUES (%d, %d, $1, 1, %d, %d, 'pending')`, call.System.Id, call.Talkgroup.Id, now, now)
+	if _, err := learner.controller.Database.Sql.Exec(query, string(toneSetJson)); err != nil {
+		learner.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("tone learning: %v", err))
+		return
+	}
+
+	learner.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("tone learning: proposed %s for system %s talkgroup %s", candidate.Label, call.System.Label, call.Talkgroup.Label))
+}
+
+// readSuggestions reads the tone set suggestions matching the where clause
+func (learner *ToneLearner) readSuggestions(where string) ([]*ToneSetSuggestion, error) {
+	query := fmt.Sprintf(`SELECT "toneSetSuggestionId", "systemId", "talkgroupId", "toneSet", "occurrences", "firstSeenAt", "lastSeenAt", "status" FROM "toneSetSuggestions" %s ORDER BY "occurrences" DESC, "lastSeenAt" DESC`, where)
+
+	rows, err := learner.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*ToneSetSuggestion{}
+
+	for rows.Next() {
+		var (
+			suggestion  ToneSetSuggestion
+			toneSetJson string
+		)
+
+		if err := rows.Scan(&suggestion.Id, &suggestion.SystemId, &suggestion.TalkgroupId, &toneSetJson, &suggestion.Occurrences, &suggestion.FirstSeenAt, &suggestion.LastSeenAt, &suggestion.Status); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(toneSetJson), &suggestion.ToneSet); err != nil || suggestion.ToneSet == nil {
+			continue
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// GetPendingSuggestions returns the tone sets waiting for admin review, most paged first
+func (learner *ToneLearner) GetPendingSuggestions() ([]*ToneSetSuggestion, error) {
+	return learner.readSuggestions(`WHERE "status" = 'pending'`)
+}
+
+// ApproveSuggestion adds the suggested tone set to its talkgroup, under the label when one is given,
+// and enables the tone detection of the talkgroup
+func (learner *ToneLearner) ApproveSuggestion(id uint64, label string) error {
+	learner.mutex.Lock()
+	defer learner.mutex.Unlock()
+
+	suggestions, err := learner.readSuggestions(fmt.Sprintf(`WHERE "toneSetSuggestionId" = %d`, id))
+	if err != nil {
+		return err
+	}
+	if len(suggestions) == 0 {
+		return fmt.Errorf("tone set suggestion %d not found", id)
+	}
+	suggestion := suggestions[0]
+
+	system, ok := learner.controller.Systems.GetSystemById(suggestion.SystemId)
+	if !ok {
+		return fmt.Errorf("system %d not found", suggestion.SystemId)
+	}
+	talkgroup, ok := system.Talkgroups.GetTalkgroupById(suggestion.TalkgroupId)
+	if !ok {
+		return fmt.Errorf("talkgroup %d not found", suggestion.TalkgroupId)
+	}
+
+	if label != "" {
+		suggestion.ToneSet.Label = label
+	}
+
+	toneSets := append(append([]ToneSet{}, talkgroup.ToneSets...), *suggestion.ToneSet)
+	toneSetsJson, err := SerializeToneSets(toneSets)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE "talkgroups" SET "toneDetectionEnabled" = true, "toneSets" = $1 WHERE "talkgroupId" = %d`, talkgroup.Id)
+	if _, err := learner.controller.Database.Sql.Exec(query, toneSetsJson); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`UPDATE "toneSetSuggestions" SET "status" = 'approved' WHERE "toneSetSuggestionId" = %d`, id)
+	if _, err := learner.controller.Database.Sql.Exec(query); err != nil {
+		return err
+	}
+
+	talkgroup.ToneSets = toneSets
+	talkgroup.ToneDetectionEnabled = true
+
+	learner.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("tone learning: approved %s for system %s talkgroup %s", suggestion.ToneSet.Label, system.Label, talkgroup.Label))
+
+	learner.controller.EmitConfig()
+
+	return nil
+}
+
+// SetLearningWindow starts learning tone sets from the pages of a talkgroup until the given time,
+// or stops learning with a zero time
+func (learner *ToneLearner) SetLearningWindow(systemId uint64, talkgroupId uint64, until time.Time) error {
+	system, ok := learner.controller.Systems.GetSystemById(systemId)
+	if !ok {
+		return fmt.Errorf("system %d not found", systemId)
+	}
+	talkgroup, ok := system.Talkgroups.GetTalkgroupById(talkgroupId)
+	if !ok {
+		return fmt.Errorf("talkgroup %d not found", talkgroupId)
+	}
+
+	toneLearningUntil := int64(0)
+	if !until.IsZero() {
+		toneLearningUntil = until.UnixMilli()
+	}
+
+	query := fmt.Sprintf(`UPDATE "talkgroups" SET "toneLearningUntil" = %d WHERE "talkgroupId" = %d`, toneLearningUntil, talkgroup.Id)
+	if _, err := learner.controller.Database.Sql.Exec(query); err != nil {
+		return err
+	}
+
+	talkgroup.ToneLearningUntil = toneLearningUntil
+
+	if toneLearningUntil == 0 {
+		learner.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("tone learning: stopped for system %s talkgroup %s", system.Label, talkgroup.Label))
+	} else {
+		learner.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("tone learning: started for system %s talkgroup %s until %s", system.Label, talkgroup.Label, until.Format(time.RFC3339)))
+	}
+
+	learner.controller.EmitConfig()
+
+	return nil
+}
+
+// RejectSuggestion marks a suggested tone set as rejected, it won't be proposed again for its talkgroup
+func (learner *ToneLearner) RejectSuggestion(id uint64) error {
+	learner.mutex.Lock()
+	defer learner.mutex.Unlock()
+
+	query := fmt.Sprintf(`UPDATE "toneSetSuggestions" SET "status" = 'rejected' WHERE "toneSetSuggestionId" = %d`, id)
+	_, err := learner.controller.Database.Sql.Exec(query)
+	return err
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// synthesizeTones returns 16 kHz samples of silence and sine tones, a zero frequency being silence
+func synthesizeTones(segments [][2]float64) []float64 {
+	const sampleRate = 16000
+
+	random := rand.New(rand.NewSource(1))
+	samples := []float64{}
+
+	for _, segment := range segments {
+		frequency, seconds := segment[0], segment[1]
+		for i := 0; i < int(seconds*sampleRate); i++ {
+			sample := random.Float64()*0.002 - 0.001
+			if frequency > 0 {
+				sample += 0.5 * math.Sin(2*math.Pi*frequency*float64(i)/sampleRate)
+			}
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples
+}
+
+func TestToneSetCandidateTwoTone(t *testing.T) {
+	samples := synthesizeTones([][2]float64{{0, 1}, {853.2, 1}, {960, 3}, {0, 1}})
+
+	_, measured := NewToneDetector().analyzeFrequencies(samples, 16000, nil)
+
+	toneSet := toneSetCandidate(measured)
+	if toneSet == nil {
+		t.Fatalf("no tone set learned from %+v", measured)
+	}
+	if toneSet.ATone == nil || toneSet.BTone == nil || toneSet.LongTone != nil {
+		t.Fatalf("expected a two-tone set, got %+v", toneSet)
+	}
+	if math.Abs(toneSet.ATone.Frequency-853.2) > toneLearningTolerance {
+		t.Errorf("A tone is %.1f Hz, expected 853.2 Hz", toneSet.ATone.Frequency)
+	}
+	if math.Abs(toneSet.BTone.Frequency-960) > toneLearningTolerance {
+		t.Errorf("B tone is %.1f Hz, expected 960 Hz", toneSet.BTone.Frequency)
+	}
+	if toneSet.ATone.MinDuration <= 0 || toneSet.ATone.MinDuration > 1 {
+		t.Errorf("A tone minimum duration is %.1fs, expected at most the 1s paged", toneSet.ATone.MinDuration)
+	}
+	if toneSet.BTone.MinDuration < 2 || toneSet.BTone.MinDuration > 3 {
+		t.Errorf("B tone minimum duration is %.1fs, expected at most the 3s paged", toneSet.BTone.MinDuration)
+	}
+}
+
+func TestToneSetCandidateLongTone(t *testing.T) {
+	toneSet := toneSetCandidate([]Tone{
+		{Frequency: 1000, StartTime: 0, EndTime: 0.5, Duration: 0.5},
+		{Frequency: 1500, StartTime: 2, EndTime: 6, Duration: 4},
+	})
+	if toneSet == nil || toneSet.LongTone == nil || toneSet.ATone != nil {
+		t.Fatalf("expected a long tone set, got %+v", toneSet)
+	}
+	if toneSet.LongTone.Frequency != 1500 || toneSet.LongTone.MinDuration != 3.2 {
+		t.Errorf("expected a 1500 Hz long tone of 3.2s, got %+v", toneSet.LongTone)
+	}
+
+	if toneSet := toneSetCandidate([]Tone{{Frequency: 1000, StartTime: 0, EndTime: 1, Duration: 1}}); toneSet != nil {
+		t.Errorf("expected no tone set from a short single tone, got %+v", toneSet)
+	}
+}
+
+func TestSameAndMergeToneSet(t *testing.T) {
+	a := &ToneSet{ATone: &ToneSpec{Frequency: 853.2, MinDuration: 0.8}, BTone: &ToneSpec{Frequency: 960, MinDuration: 2.4}}
+	b := &ToneSet{ATone: &ToneSpec{Frequency: 857.2, MinDuration: 0.7}, BTone: &ToneSpec{Frequency: 956, MinDuration: 2.6}}
+	c := &ToneSet{LongTone: &ToneSpec{Frequency: 853.2, MinDuration: 2}}
+
+	if !sameToneSet(a, b) {
+		t.Error("expected tone sets within the tolerance to be the same")
+	}
+	if sameToneSet(a, c) {
+		t.Error("expected a two-tone set and a long tone set to differ")
+	}
+
+	mergeToneSetCandidate(a, b, 1)
+	if a.ATone.Frequency != 855.2 || a.BTone.Frequency != 958 {
+		t.Errorf("expected averaged frequencies of 855.2/958 Hz, got %.1f/%.1f Hz", a.ATone.Frequency, a.BTone.Frequency)
+	}
+	if a.ATone.MinDuration != 0.7 || a.BTone.MinDuration != 2.4 {
+		t.Errorf("expected the shortest durations 0.7/2.4s, got %.1f/%.1fs", a.ATone.MinDuration, a.BTone.MinDuration)
+	}
+}
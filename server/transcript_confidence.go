@@ -0,0 +1,74 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import "fmt"
+
+// defaultDisplayConfidence is the confidence under which transcripts are shown as uncertain when neither
+// their talkgroup, their system nor the transcription config sets one
+const defaultDisplayConfidence = 0.6
+
+// validDisplayConfidence reports whether the display confidence is a confidence, 0 being unset
+func validDisplayConfidence(confidence float64) bool {
+	return confidence >= 0 && confidence <= 1
+}
+
+// transcriptDisplayConfidence resolves the confidence under which the transcript of the call is shown as
+// uncertain, from its talkgroup, then its system, then the transcription config. It only affects how
+// listeners render the transcript, alerts are gated on their own.
+func transcriptDisplayConfidence(call *Call, config TranscriptionConfig) float64 {
+	if call.Talkgroup != nil && call.Talkgroup.DisplayConfidence > 0 && validDisplayConfidence(call.Talkgroup.DisplayConfidence) {
+		return call.Talkgroup.DisplayConfidence
+	}
+
+	if call.System != nil && call.System.DisplayConfidence > 0 && validDisplayConfidence(call.System.DisplayConfidence) {
+		return call.System.DisplayConfidence
+	}
+
+	if config.DisplayConfidence > 0 && validDisplayConfidence(config.DisplayConfidence) {
+		return config.DisplayConfidence
+	}
+
+	return defaultDisplayConfidence
+}
+
+// resolveDisplayConfidence sets the display confidence sent to the listeners with the call
+func (controller *Controller) resolveDisplayConfidence(call *Call) {
+	call.displayConfidence = transcriptDisplayConfidence(call, controller.Options.TranscriptionConfig)
+}
+
+// displayConfidenceErrors lists the systems and talkgroups whose display confidence is not between 0 and 1
+func displayConfidenceErrors(systems []*System) []string {
+	errs := []string{}
+
+	for _, system := range systems {
+		if !validDisplayConfidence(system.DisplayConfidence) {
+			errs = append(errs, fmt.Sprintf("system %s: display confidence %v is out of the 0 to 1 range", system.Label, system.DisplayConfidence))
+		}
+
+		if system.Talkgroups == nil {
+			continue
+		}
+
+		for _, talkgroup := range system.Talkgroups.List {
+			if !validDisplayConfidence(talkgroup.DisplayConfidence) {
+				errs = append(errs, fmt.Sprintf("system %s talkgroup %s: display confidence %v is out of the 0 to 1 range", system.Label, talkgroup.Label, talkgroup.DisplayConfidence))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTranscriptDisplayConfidence(t *testing.T) {
+	controller := &Controller{Options: NewOptions()}
+
+	system := &System{Label: "County", DisplayConfidence: 0.7}
+	analog := &Talkgroup{Label: "Fire Dispatch", DisplayConfidence: 0.4}
+	digital := &Talkgroup{Label: "Police"}
+
+	newCall := func(system *System, talkgroup *Talkgroup) *Call {
+		call := NewCall()
+		call.System = system
+		call.Talkgroup = talkgroup
+		call.Transcript = "ENGINE 5 RESPOND"
+		call.TranscriptConfidence = 0.5
+		return call
+	}
+
+	for name, test := range map[string]struct {
+		call   *Call
+		global float64
+		want   float64
+	}{
+		"talkgroup":            {call: newCall(system, analog), want: 0.4},
+		"system":               {call: newCall(system, digital), want: 0.7},
+		"transcription config": {call: newCall(&System{}, digital), global: 0.8, want: 0.8},
+		"default":              {call: newCall(&System{}, digital), want: defaultDisplayConfidence},
+	} {
+		controller.Options.TranscriptionConfig.DisplayConfidence = test.global
+		controller.resolveDisplayConfidence(test.call)
+
+		b, err := json.Marshal(test.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m map[string]any
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := m["transcriptDisplayConfidence"]; got != test.want {
+			t.Errorf("%s: got display confidence %v, want %v", name, got, test.want)
+		}
+	}
+
+	errs := displayConfidenceErrors([]*System{{Label: "County", DisplayConfidence: 1.5, Talkgroups: &Talkgroups{List: []*Talkgroup{analog, {Label: "Bad", DisplayConfidence: -0.1}}}}})
+	if len(errs) != 2 {
+		t.Errorf("expected the system and a talkgroup refused, got %v", errs)
+	}
+}
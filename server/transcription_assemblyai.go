@@ -145,12 +145,7 @@ func (assemblyai *AssemblyAITranscription) Transcribe(audio []byte, options Tran
 	// Step 2: Submit transcription job
 	// Build transcript request body with absolute minimum required fields
 	// Start with only audio_url to ensure basic request works
-	transcriptBody := map[string]interface{}{
-		"audio_url": uploadResponse.UploadURL,
-	}
-	
-	// Only add optional fields if needed
-	// Try minimal request first - just audio_url
+	transcriptBody := assemblyAITranscriptBody(uploadResponse.UploadURL, options)
 
 	transcriptJSON, err := json.Marshal(transcriptBody)
 	if err != nil {
@@ -273,6 +268,20 @@ func (assemblyai *AssemblyAITranscription) Transcribe(audio []byte, options Tran
 	return nil, fmt.Errorf("AssemblyAI transcription timed out after %d attempts", maxAttempts)
 }
 
+// assemblyAITranscriptBody builds the transcript request, only adding optional fields when needed
+func assemblyAITranscriptBody(audioURL string, options TranscriptionOptions) map[string]interface{} {
+	body := map[string]interface{}{
+		"audio_url": audioURL,
+	}
+
+	// Vocabulary phrases boost the likelihood of these words being recognized
+	if len(options.Phrases) > 0 {
+		body["word_boost"] = options.Phrases
+	}
+
+	return body
+}
+
 // IsAvailable checks if AssemblyAI is available
 func (assemblyai *AssemblyAITranscription) IsAvailable() bool {
 	return assemblyai.available
@@ -293,8 +302,12 @@ func (assemblyai *AssemblyAITranscription) GetSupportedLanguages() []string {
 	}
 }
 
-// convertToWAV converts audio to WAV format using ffmpeg
+// convertToWAV converts audio to WAV format using ffmpeg, unless it already is a 16kHz 16 bits mono wav
 func convertToWAV(audio []byte) ([]byte, error) {
+	if _, sampleRate, ok := wavPcm16Mono(audio); ok && sampleRate == transcriptionChunkSampleRate {
+		return audio, nil
+	}
+
 	// Use ffmpeg to convert to WAV 16kHz mono
 	// This format is universally recognized and reduces upload size
 	ffArgs := []string{
@@ -0,0 +1,86 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultTranscriptionAvailabilityTTL is how long the availability of a provider is trusted when none is configured
+const defaultTranscriptionAvailabilityTTL = 30 * time.Second
+
+// CachedTranscription implements TranscriptionProvider over a provider whose availability is
+// checked at most once per ttl, so that the dispatch of every call and the capabilities and health
+// endpoints don't each hit the provider. The queue builds new ones on a configuration change.
+// The results are tagged with the configured name of the provider for the usage accounting.
+type CachedTranscription struct {
+	TranscriptionProvider
+
+	name      string
+	ttl       time.Duration
+	now       func() time.Time
+	available bool
+	checkedAt time.Time
+	mutex     sync.Mutex
+}
+
+// NewCachedTranscription caches the availability of the provider configured as name for ttl, the default one when 0
+func NewCachedTranscription(name string, provider TranscriptionProvider, ttl time.Duration) *CachedTranscription {
+	if ttl <= 0 {
+		ttl = defaultTranscriptionAvailabilityTTL
+	}
+
+	return &CachedTranscription{
+		TranscriptionProvider: provider,
+		name:                  name,
+		ttl:                   ttl,
+		now:                   time.Now,
+	}
+}
+
+// Transcribe transcribes the audio with the provider, naming it in the result
+func (cached *CachedTranscription) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	result, err := cached.TranscriptionProvider.Transcribe(audio, options)
+	if result != nil && result.Provider == "" {
+		result.Provider = cached.name
+	}
+
+	return result, err
+}
+
+// IsAvailable returns the last availability of the provider, checking it again once it is older than the ttl
+func (cached *CachedTranscription) IsAvailable() bool {
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+
+	now := cached.now()
+	if cached.checkedAt.IsZero() || now.Sub(cached.checkedAt) >= cached.ttl {
+		cached.available = cached.TranscriptionProvider.IsAvailable()
+		cached.checkedAt = now
+	}
+
+	return cached.available
+}
+
+// transcriptionAvailabilityTTL returns the configured availability cache duration
+func transcriptionAvailabilityTTL(config TranscriptionConfig) time.Duration {
+	if config.AvailabilityCacheSeconds <= 0 {
+		return defaultTranscriptionAvailabilityTTL
+	}
+
+	return time.Duration(config.AvailabilityCacheSeconds) * time.Second
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCachedTranscriptionAvailability(t *testing.T) {
+	provider := &scriptedTranscriptionProvider{name: "azure", available: true}
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cached := NewCachedTranscription("azure", provider, 30*time.Second)
+	cached.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if !cached.IsAvailable() {
+			t.Fatal("expected the provider to be available")
+		}
+	}
+	if provider.checks != 1 {
+		t.Errorf("expected the availability checked once within the ttl, got %d checks", provider.checks)
+	}
+
+	// an outage isn't seen until the ttl runs out
+	provider.available = false
+	now = now.Add(29 * time.Second)
+	if !cached.IsAvailable() {
+		t.Error("expected the cached availability within the ttl")
+	}
+
+	now = now.Add(time.Second)
+	if cached.IsAvailable() {
+		t.Error("expected the outage detected once the ttl ran out")
+	}
+
+	// nor is the recovery
+	provider.available = true
+	now = now.Add(10 * time.Second)
+	if cached.IsAvailable() {
+		t.Error("expected the cached outage within the ttl")
+	}
+
+	now = now.Add(20 * time.Second)
+	if !cached.IsAvailable() {
+		t.Error("expected the recovery detected once the ttl ran out")
+	}
+	if provider.checks != 3 {
+		t.Errorf("expected one check per ttl, got %d checks", provider.checks)
+	}
+
+	if name := cached.GetName(); name != "azure" {
+		t.Errorf("name = %q, want the one of the provider", name)
+	}
+}
+
+func TestTranscriptionAvailabilityTTL(t *testing.T) {
+	if ttl := transcriptionAvailabilityTTL(TranscriptionConfig{}); ttl != defaultTranscriptionAvailabilityTTL {
+		t.Errorf("ttl = %v, want the default when unset", ttl)
+	}
+	if ttl := transcriptionAvailabilityTTL(TranscriptionConfig{AvailabilityCacheSeconds: 120}); ttl != 2*time.Minute {
+		t.Errorf("ttl = %v, want 2m", ttl)
+	}
+}
@@ -0,0 +1,508 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AWSTranscription implements TranscriptionProvider for Amazon Transcribe. Transcribe only
+// reads audio from S3, so the audio is uploaded to the configured bucket, a transcription
+// job is started and polled until it completes, then the audio and the job are deleted.
+type AWSTranscription struct {
+	available     bool
+	region        string
+	bucket        string
+	credentials   awsCredentials
+	convert       func(audio []byte) ([]byte, error) // normalizes the audio to 16 bits mono wav
+	s3URL         string                             // base URL of the bucket
+	transcribeURL string
+	httpClient    *http.Client
+	pollInterval  time.Duration
+	timeout       time.Duration // longest wait for a job to complete
+	now           func() time.Time
+	warned        bool
+}
+
+// AWSConfig contains configuration for Amazon Transcribe
+type AWSConfig struct {
+	Region          string // AWS region of the bucket and of Transcribe (e.g., "us-east-1"), the default region when empty
+	Bucket          string // S3 bucket the audio is uploaded to
+	AccessKeyId     string // AWS access key, the AWS_ACCESS_KEY_ID environment variable is used when empty
+	SecretAccessKey string // AWS secret key, the AWS_SECRET_ACCESS_KEY environment variable is used when empty
+}
+
+type awsCredentials struct {
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// awsTranscribeKeyPrefix is the prefix of the audio uploaded to the bucket
+const awsTranscribeKeyPrefix = "rdio-scanner/"
+
+// NewAWSTranscription creates a new Amazon Transcribe transcription provider
+func NewAWSTranscription(config *AWSConfig) *AWSTranscription {
+	credentials := awsCredentials{
+		accessKeyId:     config.AccessKeyId,
+		secretAccessKey: config.SecretAccessKey,
+	}
+
+	// Without configured keys, use the ones of the environment like the AWS tools do
+	if credentials.accessKeyId == "" && credentials.secretAccessKey == "" {
+		credentials.accessKeyId = os.Getenv("AWS_ACCESS_KEY_ID")
+		credentials.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		credentials.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
+	region := config.Region
+	if region == "" {
+		region = defaults.options.transcriptionConfig.awsRegion
+	}
+
+	aws := &AWSTranscription{
+		region:        region,
+		bucket:        config.Bucket,
+		credentials:   credentials,
+		convert:       convertToWAV,
+		s3URL:         fmt.Sprintf("https://%s.s3.%s.amazonaws.com", config.Bucket, region),
+		transcribeURL: fmt.Sprintf("https://transcribe.%s.amazonaws.com/", region),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		pollInterval: 5 * time.Second,
+		timeout:      5 * time.Minute,
+		now:          time.Now,
+	}
+
+	// Check availability (basic validation)
+	aws.available = aws.region != "" && aws.bucket != "" && credentials.accessKeyId != "" && credentials.secretAccessKey != ""
+
+	return aws
+}
+
+// Transcribe transcribes audio using Amazon Transcribe
+func (aws *AWSTranscription) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	if !aws.available {
+		if !aws.warned {
+			aws.warned = true
+			return nil, fmt.Errorf("Amazon Transcribe not configured. Please provide region, bucket and credentials")
+		}
+		return nil, errors.New("Amazon Transcribe is not available")
+	}
+
+	// Convert audio to WAV format using ffmpeg, as the other providers do
+	wavAudio, err := aws.convert(audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert audio to WAV: %v", err)
+	}
+	if len(wavAudio) == 0 {
+		return nil, fmt.Errorf("WAV audio data is empty after conversion")
+	}
+
+	jobName := "rdio-scanner-" + uuid.New().String()
+	key := awsTranscribeKeyPrefix + jobName + ".wav"
+
+	// Step 1: Upload the audio to the bucket
+	if err := aws.putObject(key, wavAudio); err != nil {
+		return nil, err
+	}
+	defer aws.deleteObject(key)
+
+	// Step 2: Start the transcription job
+	if _, err := aws.call("StartTranscriptionJob", aws.startJobBody(jobName, key, options)); err != nil {
+		return nil, fmt.Errorf("failed to start transcription job: %v", err)
+	}
+	defer aws.call("DeleteTranscriptionJob", map[string]any{"TranscriptionJobName": jobName})
+
+	// Step 3: Poll for completion
+	deadline := aws.now().Add(aws.timeout)
+
+	for {
+		time.Sleep(aws.pollInterval)
+
+		body, err := aws.call("GetTranscriptionJob", map[string]any{"TranscriptionJobName": jobName})
+		if err != nil {
+			if aws.now().After(deadline) {
+				return nil, fmt.Errorf("failed to get transcription job: %v", err)
+			}
+			continue
+		}
+
+		var response struct {
+			TranscriptionJob struct {
+				TranscriptionJobStatus string `json:"TranscriptionJobStatus"`
+				FailureReason          string `json:"FailureReason"`
+				LanguageCode           string `json:"LanguageCode"`
+				Transcript             struct {
+					TranscriptFileUri string `json:"TranscriptFileUri"`
+				} `json:"Transcript"`
+			} `json:"TranscriptionJob"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse transcription job: %v", err)
+		}
+
+		job := response.TranscriptionJob
+
+		switch job.TranscriptionJobStatus {
+		case "COMPLETED":
+			// Step 4: Fetch the transcript, the uri is presigned
+			result, err := aws.fetchTranscript(job.Transcript.TranscriptFileUri)
+			if err != nil {
+				return nil, err
+			}
+			if result.Language == "" {
+				result.Language = job.LanguageCode
+			}
+			return result, nil
+
+		case "FAILED":
+			return nil, fmt.Errorf("Amazon Transcribe job failed: %s", job.FailureReason)
+		}
+
+		// Status is "QUEUED" or "IN_PROGRESS", continue polling
+		if aws.now().After(deadline) {
+			return nil, fmt.Errorf("Amazon Transcribe job timed out after %s", aws.timeout)
+		}
+	}
+}
+
+// startJobBody builds the StartTranscriptionJob request, the language is identified when set to auto
+func (aws *AWSTranscription) startJobBody(jobName string, key string, options TranscriptionOptions) map[string]any {
+	body := map[string]any{
+		"TranscriptionJobName": jobName,
+		"MediaFormat":          "wav",
+		"Media": map[string]any{
+			"MediaFileUri": fmt.Sprintf("s3://%s/%s", aws.bucket, key),
+		},
+	}
+
+	// Convert language code format if needed (e.g., "en" -> "en-US")
+	switch language := options.Language; {
+	case language == "auto":
+		body["IdentifyLanguage"] = true
+	case language == "":
+		body["LanguageCode"] = "en-US"
+	case len(language) == 2:
+		body["LanguageCode"] = language + "-US"
+	default:
+		body["LanguageCode"] = language
+	}
+
+	return body
+}
+
+// fetchTranscript downloads the transcript of a completed job and parses it
+func (aws *AWSTranscription) fetchTranscript(uri string) (*TranscriptionResult, error) {
+	if uri == "" {
+		return nil, errors.New("Amazon Transcribe job completed without a transcript")
+	}
+
+	resp, err := aws.httpClient.Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transcript: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Amazon Transcribe transcript fetch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return parseAWSTranscript(body)
+}
+
+// parseAWSTranscript converts the transcript file of a job, with a segment per audio
+// segment when the file has them and a single segment otherwise
+func parseAWSTranscript(body []byte) (*TranscriptionResult, error) {
+	type item struct {
+		Id           int    `json:"id"`
+		Type         string `json:"type"`
+		StartTime    string `json:"start_time"`
+		EndTime      string `json:"end_time"`
+		Alternatives []struct {
+			Confidence string `json:"confidence"`
+			Content    string `json:"content"`
+		} `json:"alternatives"`
+	}
+
+	var file struct {
+		Results struct {
+			LanguageCode string `json:"language_code"`
+			Transcripts  []struct {
+				Transcript string `json:"transcript"`
+			} `json:"transcripts"`
+			Items         []item `json:"items"`
+			AudioSegments []struct {
+				Transcript string `json:"transcript"`
+				StartTime  string `json:"start_time"`
+				EndTime    string `json:"end_time"`
+				Items      []int  `json:"items"`
+			} `json:"audio_segments"`
+		} `json:"results"`
+	}
+
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %v", err)
+	}
+
+	parseFloat := func(s string) float64 {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+
+	// The confidence of a span of words is the mean of the one of its words
+	items := map[int]item{}
+	confidence := func(ids []int) float64 {
+		var sum float64
+		var count int
+		for _, id := range ids {
+			if it, ok := items[id]; ok && it.Type == "pronunciation" && len(it.Alternatives) > 0 {
+				sum += parseFloat(it.Alternatives[0].Confidence)
+				count++
+			}
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float64(count)
+	}
+
+	ids := []int{}
+	var start, end float64
+	words := 0
+	for _, it := range file.Results.Items {
+		items[it.Id] = it
+		ids = append(ids, it.Id)
+		if it.Type != "pronunciation" {
+			continue
+		}
+		if words == 0 {
+			start = parseFloat(it.StartTime)
+		}
+		end = parseFloat(it.EndTime)
+		words++
+	}
+
+	transcripts := []string{}
+	for _, t := range file.Results.Transcripts {
+		transcripts = append(transcripts, t.Transcript)
+	}
+	transcript := strings.ToUpper(strings.TrimSpace(strings.Join(transcripts, " ")))
+
+	result := &TranscriptionResult{
+		Transcript: transcript,
+		Confidence: confidence(ids),
+		Language:   file.Results.LanguageCode,
+		Segments:   []TranscriptSegment{},
+	}
+
+	for _, segment := range file.Results.AudioSegments {
+		text := strings.ToUpper(strings.TrimSpace(segment.Transcript))
+		if text == "" {
+			continue
+		}
+		result.Segments = append(result.Segments, TranscriptSegment{
+			Text:       text,
+			StartTime:  parseFloat(segment.StartTime),
+			EndTime:    parseFloat(segment.EndTime),
+			Confidence: confidence(segment.Items),
+		})
+	}
+
+	if len(result.Segments) == 0 && transcript != "" {
+		result.Segments = append(result.Segments, TranscriptSegment{
+			Text:       transcript,
+			StartTime:  start,
+			EndTime:    end,
+			Confidence: result.Confidence,
+		})
+	}
+
+	return result, nil
+}
+
+// putObject uploads the audio to the bucket
+func (aws *AWSTranscription) putObject(key string, audio []byte) error {
+	req, err := http.NewRequest(http.MethodPut, aws.s3URL+"/"+key, bytes.NewReader(audio))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "audio/wav")
+	if _, err := aws.do(req, "s3", audio); err != nil {
+		return fmt.Errorf("failed to upload audio: %v", err)
+	}
+
+	return nil
+}
+
+// deleteObject removes uploaded audio, failures are left to the lifecycle rules of the bucket
+func (aws *AWSTranscription) deleteObject(key string) {
+	req, err := http.NewRequest(http.MethodDelete, aws.s3URL+"/"+key, nil)
+	if err != nil {
+		return
+	}
+
+	aws.do(req, "s3", nil)
+}
+
+// call invokes an action of the Transcribe JSON API
+func (aws *AWSTranscription) call(action string, payload map[string]any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, aws.transcribeURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Transcribe."+action)
+
+	return aws.do(req, "transcribe", body)
+}
+
+// do signs and sends a request, any status other than 2xx is an error
+func (aws *AWSTranscription) do(req *http.Request, service string, body []byte) ([]byte, error) {
+	// S3 requires the hash of the payload in a header of its own
+	if service == "s3" {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	}
+
+	awsSignRequest(req, body, aws.credentials, aws.region, service, aws.now())
+
+	resp, err := aws.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
+
+// awsSignRequest signs a request with AWS Signature Version 4, covering the host and every header set on the request
+func awsSignRequest(req *http.Request, body []byte, credentials awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	payloadHash := sha256.Sum256(body)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	hmacSha256 := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+
+	key := []byte("AWS4" + credentials.secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSha256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", credentials.accessKeyId, scope, signedHeaders, signature))
+}
+
+// IsAvailable checks if Amazon Transcribe is available
+func (aws *AWSTranscription) IsAvailable() bool {
+	return aws.available
+}
+
+// GetName returns the name of this transcription provider
+func (aws *AWSTranscription) GetName() string {
+	return "Amazon Transcribe"
+}
+
+// GetSupportedLanguages returns supported languages
+func (aws *AWSTranscription) GetSupportedLanguages() []string {
+	return []string{
+		"auto", "en", "en-US", "en-GB", "en-AU", "en-IN", "es", "es-US", "es-ES", "fr", "fr-FR", "fr-CA",
+		"de", "de-DE", "it", "it-IT", "pt", "pt-BR", "pt-PT", "ja", "ja-JP", "ko", "ko-KR", "zh", "zh-CN",
+		"nl", "nl-NL", "hi", "hi-IN", "ar", "ar-SA", "ru", "ru-RU", "sv", "sv-SE", "da", "da-DK",
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAwsSignRequest(t *testing.T) {
+	// get-vanilla of the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	credentials := awsCredentials{accessKeyId: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	awsSignRequest(req, nil, credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("unexpected authorization\n got %s\nwant %s", got, want)
+	}
+}
+
+type awsStub struct {
+	mutex    sync.Mutex
+	server   *httptest.Server
+	objects  map[string][]byte
+	actions  []string
+	polls    int
+	complete int // polls before the job completes, 0 never completes
+	job      map[string]any
+}
+
+func newAWSStub(t *testing.T, complete int) (*awsStub, *AWSTranscription) {
+	stub := &awsStub{objects: map[string][]byte{}, complete: complete}
+
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mutex.Lock()
+		defer stub.mutex.Unlock()
+
+		if r.URL.Path != "/transcript.json" && !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			t.Errorf("unsigned request %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		switch {
+		case r.Method == http.MethodPut:
+			if !strings.Contains(r.Header.Get("Authorization"), "/us-west-2/s3/aws4_request") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+				t.Errorf("unexpected upload headers %v", r.Header)
+			}
+			stub.objects[r.URL.Path] = body
+
+		case r.Method == http.MethodDelete:
+			delete(stub.objects, r.URL.Path)
+
+		case r.URL.Path == "/transcript.json":
+			w.Write([]byte(`{"results":{"language_code":"en-US","transcripts":[{"transcript":" Engine 5 responding. "}],"items":[
+				{"id":0,"type":"pronunciation","start_time":"0.5","end_time":"0.9","alternatives":[{"confidence":"0.9","content":"Engine"}]},
+				{"id":1,"type":"pronunciation","start_time":"1.0","end_time":"1.2","alternatives":[{"confidence":"0.7","content":"5"}]},
+				{"id":2,"type":"pronunciation","start_time":"1.3","end_time":"2.0","alternatives":[{"confidence":"0.8","content":"responding"}]},
+				{"id":3,"type":"punctuation","alternatives":[{"confidence":"0.0","content":"."}]}]}}`))
+
+		default:
+			action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Transcribe.")
+			stub.actions = append(stub.actions, action)
+
+			switch action {
+			case "StartTranscriptionJob":
+				json.Unmarshal(body, &stub.job)
+				w.Write([]byte(`{}`))
+			case "GetTranscriptionJob":
+				stub.polls++
+				status := "IN_PROGRESS"
+				if stub.complete > 0 && stub.polls >= stub.complete {
+					status = "COMPLETED"
+				}
+				json.NewEncoder(w).Encode(map[string]any{"TranscriptionJob": map[string]any{
+					"TranscriptionJobStatus": status,
+					"Transcript":             map[string]any{"TranscriptFileUri": stub.server.URL + "/transcript.json"},
+				}})
+			default:
+				w.Write([]byte(`{}`))
+			}
+		}
+	}))
+	t.Cleanup(stub.server.Close)
+
+	aws := NewAWSTranscription(&AWSConfig{Region: "us-west-2", Bucket: "calls", AccessKeyId: "key", SecretAccessKey: "secret"})
+	aws.convert = func(audio []byte) ([]byte, error) { return audio, nil }
+	aws.s3URL = stub.server.URL
+	aws.transcribeURL = stub.server.URL + "/"
+	aws.pollInterval = 0
+
+	return stub, aws
+}
+
+func TestAWSTranscriptionTranscribe(t *testing.T) {
+	stub, aws := newAWSStub(t, 2)
+
+	result, err := aws.Transcribe([]byte("RIFF audio"), TranscriptionOptions{Language: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Transcript != "ENGINE 5 RESPONDING." || result.Language != "en-US" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].StartTime != 0.5 || result.Segments[0].EndTime != 2.0 {
+		t.Errorf("unexpected segments %+v", result.Segments)
+	}
+	if result.Confidence < 0.79 || result.Confidence > 0.81 {
+		t.Errorf("expected the mean confidence of the words, got %v", result.Confidence)
+	}
+
+	media, _ := stub.job["Media"].(map[string]any)
+	if stub.job["LanguageCode"] != "en-US" || stub.job["MediaFormat"] != "wav" || !strings.HasPrefix(media["MediaFileUri"].(string), "s3://calls/"+awsTranscribeKeyPrefix) {
+		t.Errorf("unexpected job %v", stub.job)
+	}
+
+	want := []string{"StartTranscriptionJob", "GetTranscriptionJob", "GetTranscriptionJob", "DeleteTranscriptionJob"}
+	if strings.Join(stub.actions, ",") != strings.Join(want, ",") {
+		t.Errorf("expected actions %v, got %v", want, stub.actions)
+	}
+	if len(stub.objects) != 0 {
+		t.Errorf("expected the audio deleted from the bucket, got %v", stub.objects)
+	}
+}
+
+func TestAWSTranscriptionTimeout(t *testing.T) {
+	stub, aws := newAWSStub(t, 0)
+	aws.timeout = 0
+
+	if _, err := aws.Transcribe([]byte("RIFF audio"), TranscriptionOptions{Language: "auto"}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	if stub.job["IdentifyLanguage"] != true || stub.job["LanguageCode"] != nil {
+		t.Errorf("expected the language identified, got %v", stub.job)
+	}
+	if stub.actions[len(stub.actions)-1] != "DeleteTranscriptionJob" || len(stub.objects) != 0 {
+		t.Errorf("expected the job and the audio cleaned up, got %v %v", stub.actions, stub.objects)
+	}
+}
+
+func TestAWSTranscriptionDefaultRegion(t *testing.T) {
+	aws := NewAWSTranscription(&AWSConfig{Bucket: "calls", AccessKeyId: "id", SecretAccessKey: "secret"})
+
+	if aws.region != "us-east-1" || !aws.available {
+		t.Errorf("expected the default region, got %q available=%t", aws.region, aws.available)
+	}
+	if aws.transcribeURL != "https://transcribe.us-east-1.amazonaws.com/" {
+		t.Errorf("expected the endpoint of the default region, got %s", aws.transcribeURL)
+	}
+}
+
+func TestParseAWSTranscriptSegments(t *testing.T) {
+	result, err := parseAWSTranscript([]byte(`{"results":{"transcripts":[{"transcript":"copy. en route"}],"items":[
+		{"id":0,"type":"pronunciation","start_time":"0.1","end_time":"0.4","alternatives":[{"confidence":"1.0","content":"copy"}]},
+		{"id":1,"type":"punctuation","alternatives":[{"confidence":"0.0","content":"."}]},
+		{"id":2,"type":"pronunciation","start_time":"3.0","end_time":"3.2","alternatives":[{"confidence":"0.5","content":"en"}]},
+		{"id":3,"type":"pronunciation","start_time":"3.2","end_time":"3.6","alternatives":[{"confidence":"0.5","content":"route"}]}],
+		"audio_segments":[
+			{"transcript":"copy.","start_time":"0.1","end_time":"0.4","items":[0,1]},
+			{"transcript":" en route ","start_time":"3.0","end_time":"3.6","items":[2,3]}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Segments) != 2 || result.Segments[0].Text != "COPY." || result.Segments[0].Confidence != 1 || result.Segments[1].Text != "EN ROUTE" || result.Segments[1].StartTime != 3.0 || result.Segments[1].Confidence != 0.5 {
+		t.Errorf("unexpected segments %+v", result.Segments)
+	}
+
+	if aws := NewAWSTranscription(&AWSConfig{Region: "us-east-1", Bucket: "calls", AccessKeyId: "key"}); aws.IsAvailable() {
+		t.Error("expected the provider unavailable without a secret key")
+	}
+}
@@ -94,7 +94,7 @@ func (azure *AzureTranscription) Transcribe(audio []byte, options TranscriptionO
 		return nil, fmt.Errorf("WAV audio data is empty after conversion")
 	}
 
-	// Azure Speech Services endpoint
+	// Azure Speech Services endpoint (the short audio REST API has no phrase list, options.Phrases is not used)
 	endpoint := fmt.Sprintf("https://%s.stt.speech.microsoft.com/speech/recognition/conversation/cognitiveservices/v1?language=%s&format=detailed", azure.region, language)
 
 	// Create request
@@ -0,0 +1,122 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/base64"
+	"sync"
+)
+
+// defaultTranscriptionMaxInFlightMB is the memory budget of in-flight transcriptions when none is configured
+const defaultTranscriptionMaxInFlightMB = 256
+
+// TranscriptionBudget is a semaphore weighted by both the number of jobs and the
+// estimated bytes they hold in memory. A job is admitted only when both limits
+// allow it, so a burst of large calls waits for memory to free up instead of
+// exhausting it, while small calls are only limited by the job count.
+type TranscriptionBudget struct {
+	maxBytes      int64
+	maxJobs       int
+	inFlightBytes int64
+	inFlightJobs  int
+	cond          *sync.Cond
+	mutex         sync.Mutex
+}
+
+func NewTranscriptionBudget(maxJobs int, maxBytes int64) *TranscriptionBudget {
+	budget := &TranscriptionBudget{
+		maxBytes: maxBytes,
+		maxJobs:  maxJobs,
+	}
+
+	budget.cond = sync.NewCond(&budget.mutex)
+
+	return budget
+}
+
+// Acquire blocks until a job of the given cost fits in the budget and returns the cost charged,
+// which must be given back to Release. A job larger than the whole budget is charged the
+// whole budget so it can still run, alone.
+func (budget *TranscriptionBudget) Acquire(cost int64) int64 {
+	if budget.maxBytes > 0 && cost > budget.maxBytes {
+		cost = budget.maxBytes
+	}
+
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	for !budget.fits(cost) {
+		budget.cond.Wait()
+	}
+
+	budget.inFlightBytes += cost
+	budget.inFlightJobs++
+
+	return cost
+}
+
+// Release returns the cost of a finished job to the budget
+func (budget *TranscriptionBudget) Release(cost int64) {
+	budget.mutex.Lock()
+	budget.inFlightBytes -= cost
+	budget.inFlightJobs--
+	budget.mutex.Unlock()
+
+	budget.cond.Broadcast()
+}
+
+// InFlight returns the number of jobs and the bytes currently charged
+func (budget *TranscriptionBudget) InFlight() (int, int64) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	return budget.inFlightJobs, budget.inFlightBytes
+}
+
+func (budget *TranscriptionBudget) fits(cost int64) bool {
+	if budget.maxJobs > 0 && budget.inFlightJobs >= budget.maxJobs {
+		return false
+	}
+
+	if budget.maxBytes > 0 && budget.inFlightJobs > 0 && budget.inFlightBytes+cost > budget.maxBytes {
+		return false
+	}
+
+	return true
+}
+
+// estimateTranscriptionMemory estimates the peak memory a transcription of the audio holds:
+// the audio itself, a possible tone filtered copy, and the base64 encoded request body
+// for the providers sending audio inline
+func estimateTranscriptionMemory(provider TranscriptionProvider, audioSize int) int64 {
+	cost := int64(audioSize) * 2
+
+	switch provider := provider.(type) {
+	case *GoogleTranscription:
+		cost += int64(base64.StdEncoding.EncodedLen(audioSize))
+	case *CachedTranscription:
+		cost = estimateTranscriptionMemory(provider.TranscriptionProvider, audioSize)
+	case *ChainedTranscription:
+		// the providers run one after another, only the most expensive one counts
+		for _, p := range provider.providers {
+			if c := estimateTranscriptionMemory(p, audioSize); c > cost {
+				cost = c
+			}
+		}
+	}
+
+	return cost
+}
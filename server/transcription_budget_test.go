@@ -0,0 +1,96 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// peakConcurrency runs jobs of the given cost through the budget and returns the highest number running at once
+func peakConcurrency(budget *TranscriptionBudget, jobs int, cost int64) int {
+	var (
+		mutex   sync.Mutex
+		peak    int
+		running int
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			charged := budget.Acquire(cost)
+
+			mutex.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+
+			budget.Release(charged)
+		}()
+	}
+
+	wg.Wait()
+
+	return peak
+}
+
+func TestTranscriptionBudgetGatesLargeAudioByBytes(t *testing.T) {
+	budget := NewTranscriptionBudget(8, 100)
+
+	if peak := peakConcurrency(budget, 8, 40); peak != 2 {
+		t.Errorf("expected 2 large jobs at once within a 100 bytes budget, got %d", peak)
+	}
+
+	if jobs, bytes := budget.InFlight(); jobs != 0 || bytes != 0 {
+		t.Errorf("budget not fully released: %d jobs, %d bytes", jobs, bytes)
+	}
+}
+
+func TestTranscriptionBudgetGatesSmallAudioByCount(t *testing.T) {
+	budget := NewTranscriptionBudget(3, 100)
+
+	if peak := peakConcurrency(budget, 9, 1); peak != 3 {
+		t.Errorf("expected 3 small jobs at once with a count of 3, got %d", peak)
+	}
+}
+
+func TestTranscriptionBudgetOversizedJob(t *testing.T) {
+	budget := NewTranscriptionBudget(2, 100)
+
+	if charged := budget.Acquire(500); charged != 100 {
+		t.Errorf("expected oversized job to be charged the whole budget, got %d", charged)
+	}
+	budget.Release(100)
+
+	if cost := estimateTranscriptionMemory(&GoogleTranscription{}, 300); cost != 1000 {
+		t.Errorf("expected base64 overhead for inline audio providers, got %d", cost)
+	}
+	if cost := estimateTranscriptionMemory(&WhisperAPITranscription{}, 300); cost != 600 {
+		t.Errorf("unexpected estimate for multipart providers, got %d", cost)
+	}
+}
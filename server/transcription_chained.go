@@ -0,0 +1,125 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ChainedTranscription implements TranscriptionProvider over an ordered list of providers, each
+// one is tried in turn until one returns a transcript
+type ChainedTranscription struct {
+	providers []TranscriptionProvider
+	logs      *Logs
+}
+
+// NewChainedTranscription creates a provider trying the providers in order, the first being the primary one
+func NewChainedTranscription(providers []TranscriptionProvider, logs *Logs) *ChainedTranscription {
+	return &ChainedTranscription{
+		providers: providers,
+		logs:      logs,
+	}
+}
+
+// Transcribe transcribes the audio with the first available provider returning a non-empty
+// transcript. An empty transcript is returned only when no provider heard anything, an error
+// only when they all failed.
+func (chained *ChainedTranscription) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	var (
+		empty    *TranscriptionResult
+		failures []string
+	)
+
+	for i, provider := range chained.providers {
+		if !provider.IsAvailable() {
+			continue
+		}
+
+		result, err := provider.Transcribe(audio, options)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.GetName(), err))
+			chained.logEvent(LogLevelWarn, fmt.Sprintf("transcription provider %s failed: %v", provider.GetName(), err))
+			continue
+		}
+
+		if result == nil || strings.TrimSpace(result.Transcript) == "" {
+			if empty == nil {
+				empty = result
+			}
+			continue
+		}
+
+		if i > 0 {
+			chained.logEvent(LogLevelInfo, fmt.Sprintf("transcription fell back to provider %s", provider.GetName()))
+		} else {
+			chained.logEvent(LogLevelInfo, fmt.Sprintf("transcription by provider %s", provider.GetName()))
+		}
+
+		return result, nil
+	}
+
+	if empty != nil {
+		return empty, nil
+	}
+
+	if len(failures) == 0 {
+		return nil, errors.New("no transcription provider is available")
+	}
+
+	return nil, fmt.Errorf("all transcription providers failed: %s", strings.Join(failures, "; "))
+}
+
+func (chained *ChainedTranscription) logEvent(level string, message string) {
+	if chained.logs != nil {
+		chained.logs.LogEvent(level, message)
+	}
+}
+
+// IsAvailable returns true when any of the providers is available
+func (chained *ChainedTranscription) IsAvailable() bool {
+	for _, provider := range chained.providers {
+		if provider.IsAvailable() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetName returns the names of the providers, in the order they are tried
+func (chained *ChainedTranscription) GetName() string {
+	names := make([]string, 0, len(chained.providers))
+	for _, provider := range chained.providers {
+		names = append(names, provider.GetName())
+	}
+	return strings.Join(names, " -> ")
+}
+
+// GetSupportedLanguages returns the languages supported by any of the providers
+func (chained *ChainedTranscription) GetSupportedLanguages() []string {
+	languages := []string{}
+	seen := map[string]bool{}
+	for _, provider := range chained.providers {
+		for _, language := range provider.GetSupportedLanguages() {
+			if !seen[language] {
+				seen[language] = true
+				languages = append(languages, language)
+			}
+		}
+	}
+	return languages
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type scriptedTranscriptionProvider struct {
+	name       string
+	available  bool
+	transcript string
+	err        error
+	calls      int
+	checks     int
+}
+
+func (p *scriptedTranscriptionProvider) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &TranscriptionResult{Transcript: p.transcript}, nil
+}
+
+func (p *scriptedTranscriptionProvider) IsAvailable() bool {
+	p.checks++
+	return p.available
+}
+
+func (p *scriptedTranscriptionProvider) GetName() string { return p.name }
+
+func (p *scriptedTranscriptionProvider) GetSupportedLanguages() []string { return []string{"en"} }
+
+func TestChainedTranscriptionFallsBack(t *testing.T) {
+	azure := &scriptedTranscriptionProvider{name: "azure", available: true, err: errors.New("503")}
+	google := &scriptedTranscriptionProvider{name: "google", available: true}
+	whisper := &scriptedTranscriptionProvider{name: "whisper", available: true, transcript: "ENGINE 5"}
+	unused := &scriptedTranscriptionProvider{name: "unused", available: true, transcript: "NOT REACHED"}
+
+	chained := NewChainedTranscription([]TranscriptionProvider{azure, google, whisper, unused}, NewLogs())
+
+	result, err := chained.Transcribe(nil, TranscriptionOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Transcript != "ENGINE 5" {
+		t.Errorf("transcript = %q, want the one of the first provider with a transcript", result.Transcript)
+	}
+	if azure.calls != 1 || google.calls != 1 || whisper.calls != 1 || unused.calls != 0 {
+		t.Errorf("unexpected calls: azure %d, google %d, whisper %d, unused %d", azure.calls, google.calls, whisper.calls, unused.calls)
+	}
+}
+
+func TestChainedTranscriptionSkipsUnavailable(t *testing.T) {
+	offline := &scriptedTranscriptionProvider{name: "offline", transcript: "NOT REACHED"}
+	online := &scriptedTranscriptionProvider{name: "online", available: true, transcript: "MEDIC 3"}
+
+	chained := NewChainedTranscription([]TranscriptionProvider{offline, online}, nil)
+
+	if !chained.IsAvailable() {
+		t.Error("the chain should be available when any provider is")
+	}
+	if result, err := chained.Transcribe(nil, TranscriptionOptions{}); err != nil || result.Transcript != "MEDIC 3" {
+		t.Errorf("Transcribe() = %v, %v", result, err)
+	}
+	if offline.calls != 0 {
+		t.Error("an unavailable provider should not be tried")
+	}
+	if name := chained.GetName(); name != "offline -> online" {
+		t.Errorf("GetName() = %q", name)
+	}
+
+	online.available = false
+	if chained.IsAvailable() {
+		t.Error("the chain should be unavailable when no provider is")
+	}
+}
+
+func TestChainedTranscriptionAllEmptyOrFailed(t *testing.T) {
+	silent := &scriptedTranscriptionProvider{name: "silent", available: true}
+	failing := &scriptedTranscriptionProvider{name: "failing", available: true, err: errors.New("quota exceeded")}
+
+	result, err := NewChainedTranscription([]TranscriptionProvider{failing, silent}, nil).Transcribe(nil, TranscriptionOptions{})
+	if err != nil || result == nil || result.Transcript != "" {
+		t.Errorf("expected the empty transcript when no provider heard anything, got %v, %v", result, err)
+	}
+
+	_, err = NewChainedTranscription([]TranscriptionProvider{failing, failing}, nil).Transcribe(nil, TranscriptionOptions{})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the errors of every provider, got %v", err)
+	}
+}
+
+func TestTranscriptionChunkSecondsWithFallbacks(t *testing.T) {
+	config := TranscriptionConfig{Provider: "whisper-api", FallbackProviders: []string{"google"}}
+
+	if seconds := transcriptionChunkSeconds(config); seconds != defaultTranscriptionChunkSeconds["google"] {
+		t.Errorf("chunks should fit the google fallback, got %v", seconds)
+	}
+}
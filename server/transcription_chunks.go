@@ -0,0 +1,356 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// transcriptionChunkMinSeconds is the shortest chunk length, shorter settings are raised to it
+	transcriptionChunkMinSeconds = 5
+
+	// transcriptionChunkSampleRate is the sample rate the audio is decoded to before being chunked
+	transcriptionChunkSampleRate = 16000
+
+	// transcriptionSilenceRms is the level under which a window of samples is silent, about -40 dBFS
+	transcriptionSilenceRms = 330
+
+	// transcriptionSilenceWindow and transcriptionSilenceMin are the seconds analyzed at once and
+	// the seconds of silence a chunk may be cut in
+	transcriptionSilenceWindow = 0.05
+	transcriptionSilenceMin    = 0.3
+)
+
+// defaultTranscriptionChunkSeconds are the chunk lengths of the providers capping the length of
+// their synchronous requests, the other providers take the whole call
+var defaultTranscriptionChunkSeconds = map[string]float64{
+	"azure":  55, // short audio api, 60 seconds
+	"google": 55, // synchronous recognize, about a minute
+}
+
+// transcriptionChunk is a span of the audio transcribed on its own, in seconds
+type transcriptionChunk struct {
+	Start float64
+	End   float64
+}
+
+// transcriptionChunkSeconds returns the longest audio sent at once to the provider of the config,
+// 0 for no limit. The chunkSeconds setting of a provider overrides its default.
+func transcriptionChunkSeconds(config TranscriptionConfig) float64 {
+	// The same chunks go to the fallback providers, they must fit the strictest of them
+	seconds := providerChunkSeconds(config, config.Provider)
+	for _, provider := range config.FallbackProviders {
+		if s := providerChunkSeconds(config, provider); s > 0 && (seconds == 0 || s < seconds) {
+			seconds = s
+		}
+	}
+
+	return seconds
+}
+
+func providerChunkSeconds(config TranscriptionConfig, provider string) float64 {
+	if provider == "" {
+		provider = "whisper-api"
+	}
+
+	seconds := defaultTranscriptionChunkSeconds[provider]
+	if v, ok := config.ChunkSeconds[provider]; ok {
+		seconds = v
+	}
+
+	switch {
+	case seconds <= 0:
+		return 0
+	case seconds < transcriptionChunkMinSeconds:
+		return transcriptionChunkMinSeconds
+	}
+
+	return seconds
+}
+
+// wavPcm16Mono returns the samples and the sample rate of a 16 bits pcm mono wav audio. It returns
+// false for any other audio, which needs ffmpeg.
+func wavPcm16Mono(audio []byte) ([]int16, uint, bool) {
+	if len(audio) < 12 || string(audio[0:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		return nil, 0, false
+	}
+
+	var sampleRate uint
+	pcm16Mono := false
+
+	for offset := 12; offset+8 <= len(audio); {
+		id := string(audio[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(audio[offset+4 : offset+8]))
+		start := offset + 8
+
+		switch id {
+		case "fmt ":
+			if start+16 > len(audio) {
+				return nil, 0, false
+			}
+			format := binary.LittleEndian.Uint16(audio[start : start+2])
+			channels := binary.LittleEndian.Uint16(audio[start+2 : start+4])
+			sampleRate = uint(binary.LittleEndian.Uint32(audio[start+4 : start+8]))
+			bits := binary.LittleEndian.Uint16(audio[start+14 : start+16])
+			pcm16Mono = format == 1 && channels == 1 && bits == 16 && sampleRate > 0
+
+		case "data":
+			if !pcm16Mono {
+				return nil, 0, false
+			}
+
+			end := start + size
+			if end > len(audio) {
+				end = len(audio)
+			}
+
+			return pcm16Samples(audio[start:end]), sampleRate, true
+		}
+
+		offset = start + size + size%2
+	}
+
+	return nil, 0, false
+}
+
+// pcm16Samples decodes little endian 16 bits samples
+func pcm16Samples(b []byte) []int16 {
+	samples := make([]int16, len(b)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(b[i*2 : i*2+2]))
+	}
+	return samples
+}
+
+// encodeWavPcm16 returns the samples as a 16 bits pcm mono wav audio
+func encodeWavPcm16(samples []int16, sampleRate uint) []byte {
+	dataSize := uint32(len(samples) * 2)
+
+	b := &bytes.Buffer{}
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, 36+dataSize)
+	b.WriteString("WAVEfmt ")
+	binary.Write(b, binary.LittleEndian, uint32(16))
+	binary.Write(b, binary.LittleEndian, uint16(1)) // pcm
+	binary.Write(b, binary.LittleEndian, uint16(1))
+	binary.Write(b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(b, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(b, binary.LittleEndian, uint16(2))
+	binary.Write(b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(b, binary.LittleEndian, dataSize)
+	binary.Write(b, binary.LittleEndian, samples)
+
+	return b.Bytes()
+}
+
+// DecodePcm returns the audio as 16 bits mono samples at transcriptionChunkSampleRate
+func (ffmpeg *FFMpeg) DecodePcm(audio []byte) ([]int16, uint, error) {
+	if samples, sampleRate, ok := wavPcm16Mono(audio); ok {
+		return samples, sampleRate, nil
+	}
+
+	if ffmpeg == nil || !ffmpeg.available {
+		return nil, 0, errors.New("ffmpeg is not available")
+	}
+
+	cmd := exec.Command("ffmpeg", "-loglevel", "error", "-i", "-", "-ar", fmt.Sprintf("%d", transcriptionChunkSampleRate), "-ac", "1", "-f", "s16le", "-")
+	cmd.Stdin = bytes.NewReader(audio)
+
+	stdout := bytes.NewBuffer([]byte(nil))
+	cmd.Stdout = stdout
+
+	stderr := bytes.NewBuffer([]byte(nil))
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, 0, fmt.Errorf("%v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return pcm16Samples(stdout.Bytes()), transcriptionChunkSampleRate, nil
+}
+
+// findSilences returns the middle of each silence of the samples, in seconds
+func findSilences(samples []int16, sampleRate uint) []float64 {
+	silences := []float64{}
+
+	window := int(float64(sampleRate) * transcriptionSilenceWindow)
+	if window == 0 {
+		return silences
+	}
+
+	run := 0
+	flush := func(end int) {
+		if float64(run*window) >= transcriptionSilenceMin*float64(sampleRate) {
+			middle := end - run*window/2
+			silences = append(silences, float64(middle)/float64(sampleRate))
+		}
+		run = 0
+	}
+
+	for start := 0; start+window <= len(samples); start += window {
+		sum := 0.0
+		for _, sample := range samples[start : start+window] {
+			sum += float64(sample) * float64(sample)
+		}
+
+		if math.Sqrt(sum/float64(window)) < transcriptionSilenceRms {
+			run++
+		} else {
+			flush(start)
+		}
+	}
+	flush(len(samples) - len(samples)%window)
+
+	return silences
+}
+
+// planTranscriptionChunks splits the duration in chunks of at most maxSeconds, each cut in the last
+// silence of its second half, or at maxSeconds when there is none. The chunks are contiguous.
+func planTranscriptionChunks(duration float64, silences []float64, maxSeconds float64) []transcriptionChunk {
+	chunks := []transcriptionChunk{}
+
+	if maxSeconds <= 0 || duration <= maxSeconds {
+		return append(chunks, transcriptionChunk{Start: 0, End: duration})
+	}
+
+	for start := 0.0; start < duration; {
+		end := start + maxSeconds
+
+		if end >= duration {
+			end = duration
+		} else {
+			for _, silence := range silences {
+				if silence > start+maxSeconds/2 && silence <= start+maxSeconds {
+					end = silence
+				}
+			}
+		}
+
+		chunks = append(chunks, transcriptionChunk{Start: start, End: end})
+		start = end
+	}
+
+	return chunks
+}
+
+// stitchTranscriptionResults joins the results of the chunks in a single result, the times of the
+// segments shifted by the start of their chunk. A chunk without segments gets one spanning it.
+func stitchTranscriptionResults(chunks []transcriptionChunk, results []*TranscriptionResult) *TranscriptionResult {
+	stitched := &TranscriptionResult{Segments: []TranscriptSegment{}}
+
+	transcripts := []string{}
+	confidence := 0.0
+	duration := 0.0
+
+	for i, result := range results {
+		chunk := chunks[i]
+
+		if stitched.Language == "" {
+			stitched.Language = result.Language
+		}
+		if stitched.Provider == "" {
+			stitched.Provider = result.Provider
+		}
+
+		transcript := strings.TrimSpace(result.Transcript)
+		if transcript == "" {
+			continue
+		}
+		transcripts = append(transcripts, transcript)
+
+		confidence += result.Confidence * (chunk.End - chunk.Start)
+		duration += chunk.End - chunk.Start
+
+		if len(result.Segments) == 0 {
+			stitched.Segments = append(stitched.Segments, TranscriptSegment{
+				Text:       transcript,
+				StartTime:  chunk.Start,
+				EndTime:    chunk.End,
+				Confidence: result.Confidence,
+			})
+			continue
+		}
+
+		for _, segment := range result.Segments {
+			segment.StartTime = math.Min(chunk.Start+segment.StartTime, chunk.End)
+			segment.EndTime = math.Min(chunk.Start+segment.EndTime, chunk.End)
+			stitched.Segments = append(stitched.Segments, segment)
+		}
+	}
+
+	stitched.Transcript = strings.Join(transcripts, " ")
+
+	if duration > 0 {
+		stitched.Confidence = confidence / duration
+	}
+
+	return stitched
+}
+
+// transcribeInChunks transcribes the samples chunk by chunk, as wav audio, and stitches the results
+func transcribeInChunks(provider TranscriptionProvider, samples []int16, sampleRate uint, options TranscriptionOptions, maxSeconds float64) (*TranscriptionResult, error) {
+	duration := float64(len(samples)) / float64(sampleRate)
+
+	chunks := planTranscriptionChunks(duration, findSilences(samples, sampleRate), maxSeconds)
+	results := make([]*TranscriptionResult, 0, len(chunks))
+
+	options.AudioMime = "audio/wav"
+
+	for i, chunk := range chunks {
+		from := int(chunk.Start * float64(sampleRate))
+		to := int(chunk.End * float64(sampleRate))
+		if to > len(samples) {
+			to = len(samples)
+		}
+
+		result, err := provider.Transcribe(encodeWavPcm16(samples[from:to], sampleRate), options)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %v", i+1, len(chunks), err)
+		}
+
+		results = append(results, result)
+	}
+
+	return stitchTranscriptionResults(chunks, results), nil
+}
+
+// transcribe sends the audio to the provider, in chunks when it is longer than the provider accepts
+func (queue *TranscriptionQueue) transcribe(callId uint64, audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	maxSeconds := transcriptionChunkSeconds(queue.controller.Options.TranscriptionConfig)
+	if maxSeconds == 0 {
+		return queue.provider.Transcribe(audio, options)
+	}
+
+	samples, sampleRate, err := queue.controller.FFMpeg.DecodePcm(audio)
+	if err != nil {
+		queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription: unable to decode call %d for chunking: %v, sending it whole", callId, err))
+		return queue.provider.Transcribe(audio, options)
+	}
+
+	if float64(len(samples))/float64(sampleRate) <= maxSeconds {
+		return queue.provider.Transcribe(audio, options)
+	}
+
+	return transcribeInChunks(queue.provider, samples, sampleRate, options, maxSeconds)
+}
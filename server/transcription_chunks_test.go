@@ -0,0 +1,163 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// chunkRecorder is a provider transcribing a wav chunk as its number, with a segment spanning it
+type chunkRecorder struct {
+	durations []float64
+}
+
+func (p *chunkRecorder) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	samples, sampleRate, ok := wavPcm16Mono(audio)
+	if !ok || options.AudioMime != "audio/wav" {
+		return nil, errors.New("not a wav chunk")
+	}
+
+	duration := float64(len(samples)) / float64(sampleRate)
+	p.durations = append(p.durations, duration)
+
+	text := fmt.Sprintf("CHUNK %d", len(p.durations))
+
+	return &TranscriptionResult{
+		Transcript: text,
+		Confidence: 0.9,
+		Language:   "en",
+		Segments:   []TranscriptSegment{{Text: text, StartTime: 0, EndTime: duration, Confidence: 0.9}},
+	}, nil
+}
+
+func (p *chunkRecorder) IsAvailable() bool { return true }
+
+func (p *chunkRecorder) GetName() string { return "chunk recorder" }
+
+func (p *chunkRecorder) GetSupportedLanguages() []string { return []string{"en"} }
+
+// speechClip returns seconds of a 1 kHz tone broken every 10 seconds by a second of silence
+func speechClip(seconds int, sampleRate uint) []int16 {
+	samples := make([]int16, seconds*int(sampleRate))
+	for i := range samples {
+		t := float64(i) / float64(sampleRate)
+		if math.Mod(t, 10) < 9 {
+			samples[i] = int16(8000 * math.Sin(2*math.Pi*1000*t))
+		}
+	}
+	return samples
+}
+
+func TestTranscribeInChunks(t *testing.T) {
+	const sampleRate = 16000
+
+	samples := speechClip(150, sampleRate)
+
+	provider := &chunkRecorder{}
+
+	result, err := transcribeInChunks(provider, samples, sampleRate, TranscriptionOptions{AudioMime: "audio/mp4"}, 55)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(provider.durations) != 3 {
+		t.Fatalf("expected 3 chunks, got %v", provider.durations)
+	}
+
+	for i, duration := range provider.durations {
+		if duration > 55 {
+			t.Errorf("chunk %d is %.2fs, longer than the provider accepts", i+1, duration)
+		}
+	}
+
+	// the cuts fall in the silences, at 49.5s then 99.5s
+	if math.Abs(provider.durations[0]-49.5) > 0.1 || math.Abs(provider.durations[1]-50) > 0.1 {
+		t.Errorf("expected the chunks to be cut in the silences, got %v", provider.durations)
+	}
+
+	if result.Transcript != "CHUNK 1 CHUNK 2 CHUNK 3" {
+		t.Errorf("expected the transcripts in order, got %q", result.Transcript)
+	}
+
+	if len(result.Segments) != 3 {
+		t.Fatalf("expected 3 segments, got %v", result.Segments)
+	}
+
+	if result.Segments[0].StartTime != 0 || math.Abs(result.Segments[2].EndTime-150) > 0.001 {
+		t.Errorf("expected the segments to span the clip, got %v", result.Segments)
+	}
+
+	for i := 1; i < len(result.Segments); i++ {
+		if math.Abs(result.Segments[i].StartTime-result.Segments[i-1].EndTime) > 0.001 {
+			t.Errorf("expected contiguous segments, got %v", result.Segments)
+		}
+	}
+
+	if math.Abs(result.Confidence-0.9) > 0.0001 || result.Language != "en" {
+		t.Errorf("expected the confidence and language of the chunks, got %v %s", result.Confidence, result.Language)
+	}
+
+	// a clip within the limit is a single chunk
+	chunks := planTranscriptionChunks(30, findSilences(speechClip(30, sampleRate), sampleRate), 55)
+	if len(chunks) != 1 || chunks[0].End != 30 {
+		t.Errorf("expected a single chunk, got %v", chunks)
+	}
+
+	// without silence the chunks are cut at the limit
+	chunks = planTranscriptionChunks(120, nil, 55)
+	if len(chunks) != 3 || chunks[0].End != 55 || chunks[1].End != 110 || chunks[2].End != 120 {
+		t.Errorf("expected chunks cut at the limit, got %v", chunks)
+	}
+}
+
+func TestTranscriptionChunkSeconds(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   TranscriptionConfig
+		expected float64
+	}{
+		{"google default", TranscriptionConfig{Provider: "google"}, 55},
+		{"azure default", TranscriptionConfig{Provider: "azure"}, 55},
+		{"whisper whole", TranscriptionConfig{}, 0},
+		{"assemblyai whole", TranscriptionConfig{Provider: "assemblyai"}, 0},
+		{"configured", TranscriptionConfig{Provider: "whisper-api", ChunkSeconds: map[string]float64{"whisper-api": 600}}, 600},
+		{"disabled", TranscriptionConfig{Provider: "google", ChunkSeconds: map[string]float64{"google": 0}}, 0},
+		{"other provider", TranscriptionConfig{Provider: "google", ChunkSeconds: map[string]float64{"azure": 30}}, 55},
+		{"too short", TranscriptionConfig{Provider: "google", ChunkSeconds: map[string]float64{"google": 1}}, transcriptionChunkMinSeconds},
+	}
+
+	for _, c := range cases {
+		if seconds := transcriptionChunkSeconds(c.config); seconds != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, seconds)
+		}
+	}
+}
+
+func TestConvertToWAVKeepsConvertedAudio(t *testing.T) {
+	audio := encodeWavPcm16(make([]int16, transcriptionChunkSampleRate), transcriptionChunkSampleRate)
+
+	converted, err := convertToWAV(audio)
+	if err != nil {
+		t.Fatalf("a 16kHz wav should not need ffmpeg: %v", err)
+	}
+
+	if &converted[0] != &audio[0] {
+		t.Error("expected the 16kHz wav returned as is")
+	}
+}
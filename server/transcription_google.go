@@ -32,10 +32,17 @@ type GoogleTranscription struct {
 	available     bool
 	apiKey        string
 	credentials   string // Service account JSON (alternative to API key)
+	tokens        *googleTokenSource // OAuth2 tokens of the service account, when no API key is set
+	tokensErr     error
+	convert       func(audio []byte) ([]byte, error) // normalizes the audio to 16 bits mono wav
+	endpoint      string
 	httpClient    *http.Client
 	warned        bool
 }
 
+// googleSpeechEndpoint is the synchronous recognize endpoint of Google Cloud Speech-to-Text
+const googleSpeechEndpoint = "https://speech.googleapis.com/v1/speech:recognize"
+
 // GoogleConfig contains configuration for Google Cloud Speech-to-Text
 type GoogleConfig struct {
 	APIKey      string // Google Cloud API key
@@ -47,6 +54,8 @@ func NewGoogleTranscription(config *GoogleConfig) *GoogleTranscription {
 	google := &GoogleTranscription{
 		apiKey:      config.APIKey,
 		credentials: config.Credentials,
+		convert:     convertToWAV,
+		endpoint:    googleSpeechEndpoint,
 		httpClient: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
@@ -55,6 +64,11 @@ func NewGoogleTranscription(config *GoogleConfig) *GoogleTranscription {
 	// Check availability (basic validation)
 	google.available = google.apiKey != "" || google.credentials != ""
 
+	// The API key takes precedence, service account credentials are only used without one
+	if google.apiKey == "" && google.credentials != "" {
+		google.tokens, google.tokensErr = newGoogleTokenSource(google.credentials, google.httpClient)
+	}
+
 	return google
 }
 
@@ -78,22 +92,24 @@ func (google *GoogleTranscription) Transcribe(audio []byte, options Transcriptio
 		language = language + "-US"
 	}
 
+	// Convert audio to WAV format using ffmpeg, as Azure does
+	// Scanner audio is often 8kHz or 22.05kHz, Google rejects or mis-transcribes audio whose
+	// sample rate differs from the one of the request
+	wavAudio, err := google.convert(audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert audio to WAV: %v", err)
+	}
+
+	_, sampleRate, ok := wavPcm16Mono(wavAudio)
+	if !ok {
+		return nil, fmt.Errorf("WAV audio data is invalid after conversion")
+	}
+
 	// Base64 encode audio
-	audioBase64 := base64.StdEncoding.EncodeToString(audio)
+	audioBase64 := base64.StdEncoding.EncodeToString(wavAudio)
 
 	// Build request body
-	requestBody := map[string]interface{}{
-		"config": map[string]interface{}{
-			"encoding":        google.getAudioEncoding(options.AudioMime),
-			"sampleRateHertz": 16000, // Default, may need adjustment based on actual audio
-			"languageCode":    language,
-			"enableAutomaticPunctuation": true,
-			"enableWordTimeOffsets":      true,
-		},
-		"audio": map[string]interface{}{
-			"content": audioBase64,
-		},
-	}
+	requestBody := google.buildRequestBody(audioBase64, sampleRate, language, options)
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
@@ -101,7 +117,7 @@ func (google *GoogleTranscription) Transcribe(audio []byte, options Transcriptio
 	}
 
 	// Google Cloud Speech-to-Text endpoint
-	endpoint := "https://speech.googleapis.com/v1/speech:recognize"
+	endpoint := google.endpoint
 	if google.apiKey != "" {
 		endpoint += "?key=" + google.apiKey
 	}
@@ -113,11 +129,17 @@ func (google *GoogleTranscription) Transcribe(audio []byte, options Transcriptio
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	
-	// If using service account credentials, we'd need OAuth2 token
-	// For now, API key is simpler
-	if google.credentials != "" && google.apiKey == "" {
-		return nil, fmt.Errorf("service account credentials require OAuth2 token generation (not yet implemented). Please use API key instead")
+
+	// Without an API key, authenticate with an OAuth2 token of the service account
+	if google.apiKey == "" {
+		if google.tokensErr != nil {
+			return nil, google.tokensErr
+		}
+		token, err := google.tokens.Token()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
 	// Send request
@@ -207,21 +229,27 @@ func (google *GoogleTranscription) parseTime(timeStr string) float64 {
 	return seconds
 }
 
-// getAudioEncoding determines audio encoding from MIME type
-func (google *GoogleTranscription) getAudioEncoding(mimeType string) string {
-	switch mimeType {
-	case "audio/wav", "audio/wave":
-		return "LINEAR16"
-	case "audio/mp4", "audio/m4a":
-		return "MP3" // Google may accept m4a as MP3
-	case "audio/mpeg", "audio/mp3":
-		return "MP3"
-	case "audio/ogg":
-		return "OGG_OPUS"
-	case "audio/webm":
-		return "WEBM_OPUS"
-	default:
-		return "LINEAR16" // Default to WAV
+// buildRequestBody builds the recognize request, vocabulary phrases are passed as speech contexts
+func (google *GoogleTranscription) buildRequestBody(audioBase64 string, sampleRate uint, language string, options TranscriptionOptions) map[string]interface{} {
+	config := map[string]interface{}{
+		"encoding":        "LINEAR16", // The audio is converted to WAV
+		"sampleRateHertz": sampleRate,
+		"languageCode":    language,
+		"enableAutomaticPunctuation": true,
+		"enableWordTimeOffsets":      true,
+	}
+
+	if len(options.Phrases) > 0 {
+		config["speechContexts"] = []map[string]interface{}{
+			{"phrases": options.Phrases},
+		}
+	}
+
+	return map[string]interface{}{
+		"config": config,
+		"audio": map[string]interface{}{
+			"content": audioBase64,
+		},
 	}
 }
 
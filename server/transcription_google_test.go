@@ -0,0 +1,129 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+)
+
+// googleRecognizeServer answers recognize requests and records the config and the audio of the last one
+func googleRecognizeServer(t *testing.T, config *map[string]any, audio *[]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Config map[string]any `json:"config"`
+			Audio  struct {
+				Content string `json:"content"`
+			} `json:"audio"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		*config = body.Config
+		*audio, _ = base64.StdEncoding.DecodeString(body.Audio.Content)
+
+		w.Write([]byte(`{"results": [{"alternatives": [{"transcript": "engine 5 respond", "confidence": 0.9}]}]}`))
+	}))
+}
+
+func googleTestWav(sampleRate uint) []byte {
+	samples := make([]int16, sampleRate/10)
+	for i := range samples {
+		samples[i] = int16(i % 200 * 100)
+	}
+	return encodeWavPcm16(samples, sampleRate)
+}
+
+func TestGoogleTranscribeConvertsAudio(t *testing.T) {
+	var (
+		config map[string]any
+		audio  []byte
+	)
+
+	server := googleRecognizeServer(t, &config, &audio)
+	defer server.Close()
+
+	converted := false
+
+	google := NewGoogleTranscription(&GoogleConfig{APIKey: "key"})
+	google.endpoint = server.URL
+	google.convert = func(b []byte) ([]byte, error) {
+		converted = true
+
+		samples, sampleRate, ok := wavPcm16Mono(b)
+		if !ok || sampleRate != 8000 {
+			t.Errorf("expected the 8kHz audio to convert, got %d Hz", sampleRate)
+		}
+
+		// upsample the way ffmpeg would, to 16kHz
+		upsampled := make([]int16, 0, len(samples)*2)
+		for _, sample := range samples {
+			upsampled = append(upsampled, sample, sample)
+		}
+		return encodeWavPcm16(upsampled, 16000), nil
+	}
+
+	result, err := google.Transcribe(googleTestWav(8000), TranscriptionOptions{Language: "en", AudioMime: "audio/mp4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !converted {
+		t.Error("the audio should be converted before it is sent")
+	}
+
+	if config["sampleRateHertz"] != float64(16000) || config["encoding"] != "LINEAR16" {
+		t.Errorf("config = %v, want LINEAR16 at 16000 Hz", config)
+	}
+
+	if _, sampleRate, ok := wavPcm16Mono(audio); !ok || sampleRate != 16000 {
+		t.Errorf("sent audio at %d Hz, want the converted 16kHz wav", sampleRate)
+	}
+
+	if result.Transcript != "ENGINE 5 RESPOND" {
+		t.Errorf("transcript = %q", result.Transcript)
+	}
+}
+
+func TestGoogleTranscribeConvertsAudioWithFFMpeg(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not available")
+	}
+
+	var (
+		config map[string]any
+		audio  []byte
+	)
+
+	server := googleRecognizeServer(t, &config, &audio)
+	defer server.Close()
+
+	google := NewGoogleTranscription(&GoogleConfig{APIKey: "key"})
+	google.endpoint = server.URL
+
+	if _, err := google.Transcribe(googleTestWav(8000), TranscriptionOptions{Language: "en", AudioMime: "audio/wav"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config["sampleRateHertz"] != float64(16000) {
+		t.Errorf("sampleRateHertz = %v, want 16000", config["sampleRateHertz"])
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	googleCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenEndpoint      = "https://oauth2.googleapis.com/token"
+)
+
+// googleServiceAccount holds the fields of a service account key file used to request tokens
+type googleServiceAccount struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyId string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// googleTokenSource exchanges a JWT signed with the service account key for an OAuth2 access
+// token, and caches it until shortly before it expires
+type googleTokenSource struct {
+	account    googleServiceAccount
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+	now        func() time.Time
+	token      string
+	expiry     time.Time
+	mutex      sync.Mutex
+}
+
+// newGoogleTokenSource parses the service account credentials, given as the JSON of the key file
+// or as the path to it
+func newGoogleTokenSource(credentials string, httpClient *http.Client) (*googleTokenSource, error) {
+	credentials = strings.TrimSpace(credentials)
+
+	data := []byte(credentials)
+	if !strings.HasPrefix(credentials, "{") {
+		b, err := os.ReadFile(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account credentials: %v", err)
+		}
+		data = b
+	}
+
+	var account googleServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid service account credentials: %v", err)
+	}
+
+	if account.Type != "" && account.Type != "service_account" {
+		return nil, fmt.Errorf("credentials of type %q are not a service account", account.Type)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("service account credentials are missing client_email or private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenEndpoint
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %v", err)
+	}
+
+	return &googleTokenSource{
+		account:    account,
+		key:        key,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+// Token returns a valid access token, requesting a new one when the cached one is about to expire
+func (source *googleTokenSource) Token() (string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	now := source.now()
+
+	// Renew a minute early so the token doesn't expire while the request is in flight
+	if source.token != "" && now.Before(source.expiry.Add(-time.Minute)) {
+		return source.token, nil
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   source.account.ClientEmail,
+		"scope": googleCloudPlatformScope,
+		"aud":   source.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if source.account.PrivateKeyId != "" {
+		token.Header["kid"] = source.account.PrivateKeyId
+	}
+
+	assertion, err := token.SignedString(source.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account token request: %v", err)
+	}
+
+	resp, err := source.httpClient.PostForm(source.account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("access token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("failed to parse access token response: %v", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("access token response has no access_token")
+	}
+
+	source.token = tokenResponse.AccessToken
+	source.expiry = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+
+	return source.token, nil
+}
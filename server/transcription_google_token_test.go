@@ -0,0 +1,176 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// googleTokenServer issues access tokens for assertions signed with the key, and counts them
+func googleTokenServer(t *testing.T, key *rsa.PrivateKey, issued *int) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grantType := r.FormValue("grant_type"); grantType != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", grantType)
+		}
+
+		claims := jwt.MapClaims{}
+		// The assertions are dated with the clock of the test, skip the time checks
+		parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+		if _, err := parser.ParseWithClaims(r.FormValue("assertion"), claims, func(token *jwt.Token) (any, error) {
+			if token.Method != jwt.SigningMethodRS256 {
+				t.Errorf("assertion signed with %v, want RS256", token.Method.Alg())
+			}
+			return &key.PublicKey, nil
+		}); err != nil {
+			t.Errorf("invalid assertion: %v", err)
+		}
+
+		if claims["iss"] != "speech@project.iam.gserviceaccount.com" || claims["scope"] != googleCloudPlatformScope || claims["aud"] != server.URL {
+			t.Errorf("unexpected claims %v", claims)
+		}
+
+		*issued++
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", *issued),
+			"expires_in":   3600,
+			"token_type":   "Bearer",
+		})
+	}))
+	return server
+}
+
+func googleTestCredentials(t *testing.T, key *rsa.PrivateKey, tokenURI string) string {
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "speech@project.iam.gserviceaccount.com",
+		"private_key":    string(pem.EncodeToMemory(block)),
+		"private_key_id": "kid",
+		"token_uri":      tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(credentials)
+}
+
+func TestGoogleTokenSourceCachesToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued := 0
+	server := googleTokenServer(t, key, &issued)
+	defer server.Close()
+
+	source, err := newGoogleTokenSource(googleTestCredentials(t, key, server.URL), server.Client())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if token, err := source.Token(); err != nil || token != "token-1" {
+			t.Fatalf("Token() = %q, %v", token, err)
+		}
+	}
+	if issued != 1 {
+		t.Errorf("a cached token should be reused, %d were issued", issued)
+	}
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	if token, err := source.Token(); err != nil || token != "token-2" {
+		t.Errorf("a token about to expire should be renewed, got %q, %v", token, err)
+	}
+}
+
+func TestGoogleTokenSourceInvalidCredentials(t *testing.T) {
+	for _, credentials := range []string{
+		`{"type": "service_account"`,
+		`{"type": "authorized_user", "client_email": "a@b", "private_key": "x"}`,
+		`{"type": "service_account", "client_email": "a@b"}`,
+		`{"type": "service_account", "client_email": "a@b", "private_key": "not a key"}`,
+		"/nonexistent/credentials.json",
+	} {
+		if _, err := newGoogleTokenSource(credentials, http.DefaultClient); err == nil {
+			t.Errorf("expected an error for %s", credentials)
+		}
+	}
+}
+
+func TestGoogleTranscribeWithServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued := 0
+	tokenServer := googleTokenServer(t, key, &issued)
+	defer tokenServer.Close()
+
+	var authorization, query string
+	recognizeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, query = r.Header.Get("Authorization"), r.URL.RawQuery
+		w.Write([]byte(`{"results": [{"alternatives": [{"transcript": "engine 5", "confidence": 0.9}]}]}`))
+	}))
+	defer recognizeServer.Close()
+
+	credentials := googleTestCredentials(t, key, tokenServer.URL)
+	passthrough := func(b []byte) ([]byte, error) { return b, nil }
+
+	google := NewGoogleTranscription(&GoogleConfig{Credentials: credentials})
+	google.endpoint = recognizeServer.URL
+	google.convert = passthrough
+
+	if _, err := google.Transcribe(googleTestWav(16000), TranscriptionOptions{Language: "en"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authorization != "Bearer token-1" || query != "" {
+		t.Errorf("expected the bearer token and no key, got %q and %q", authorization, query)
+	}
+
+	// The API key wins when both are set
+	google = NewGoogleTranscription(&GoogleConfig{APIKey: "key", Credentials: credentials})
+	google.endpoint = recognizeServer.URL
+	google.convert = passthrough
+
+	if _, err := google.Transcribe(googleTestWav(16000), TranscriptionOptions{Language: "en"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if authorization != "" || query != "key=key" {
+		t.Errorf("expected the API key and no bearer token, got %q and %q", authorization, query)
+	}
+	if issued != 1 {
+		t.Errorf("no token should be requested with an API key, %d were issued", issued)
+	}
+}
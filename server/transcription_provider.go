@@ -31,6 +31,7 @@ type TranscriptionOptions struct {
 	Temperature  float64 // Temperature for sampling (0.0-1.0)
 	InitialPrompt string // Initial prompt/context
 	AudioMime    string  // MIME type of audio (e.g., "audio/mp4", "audio/mpeg")
+	Phrases      []string // Phrase hints biasing recognition toward these phrases (from the system's vocabulary profile and its keyword lists)
 }
 
 // TranscriptionResult contains the transcription result
@@ -39,6 +40,7 @@ type TranscriptionResult struct {
 	Confidence   float64            `json:"confidence"`    // Confidence score (0.0-1.0)
 	Language     string             `json:"language"`      // Detected language code
 	Segments     []TranscriptSegment `json:"segments"`     // Timestamped segments (optional)
+	Provider     string             `json:"-"`            // Configured name of the provider that transcribed the audio
 }
 
 // TranscriptSegment represents a timestamped segment of the transcript
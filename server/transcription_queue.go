@@ -17,10 +17,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 // TranscriptionJob represents a job in the transcription queue
@@ -32,22 +35,51 @@ type TranscriptionJob struct {
 	TalkgroupId uint64
 	Priority    int // Higher priority processed first
 	Reasons     []string
+	Timestamp   time.Time
+	Duration    float64 // seconds of the audio when already measured, 0 otherwise
 }
 
-// TranscriptionQueue manages transcription jobs with a worker pool
+// defaultTranscriptionQueueSize is the number of calls waiting for a worker when none is configured
+const defaultTranscriptionQueueSize = 100
+
+var (
+	errTranscriptionQueueFull    = errors.New("transcription queue is full")
+	errTranscriptionQueueStopped = errors.New("transcription queue is stopped")
+)
+
+// TranscriptionQueue manages transcription jobs with a worker pool. Every request to the
+// provider goes through one of the workers, so at most workerPoolSize run at once and a
+// burst of calls waits in the queue instead of hitting the provider rate limits.
 type TranscriptionQueue struct {
 	jobs       chan TranscriptionJob
 	workers    int
+	budget     *TranscriptionBudget
 	provider   TranscriptionProvider
 	controller *Controller
+	dropped    uint64
 	mutex      sync.Mutex
 	running    bool
 }
 
+// TranscriptionQueueStats is a snapshot of the queue for the admin dashboard
+type TranscriptionQueueStats struct {
+	Provider string `json:"provider"`
+	Workers  int    `json:"workers"`
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	InFlight int    `json:"inFlight"`
+	Dropped  uint64 `json:"dropped"`
+}
+
 // NewTranscriptionQueue creates a new transcription queue with worker pool
 func NewTranscriptionQueue(controller *Controller, config TranscriptionConfig) *TranscriptionQueue {
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultTranscriptionQueueSize
+	}
+
 	queue := &TranscriptionQueue{
-		jobs:       make(chan TranscriptionJob, 100), // Buffer 100 jobs
+		jobs:       make(chan TranscriptionJob, queueSize),
 		workers:    config.WorkerPoolSize,
 		controller: controller,
 		running:    true,
@@ -56,81 +88,183 @@ func NewTranscriptionQueue(controller *Controller, config TranscriptionConfig) *
 	if queue.workers == 0 {
 		queue.workers = 5 // Default worker pool size
 	}
+
+	// Gate concurrent work by job count and by estimated bytes in flight
+	maxInFlightMB := config.MaxInFlightMB
+	if maxInFlightMB == 0 {
+		maxInFlightMB = defaultTranscriptionMaxInFlightMB
+	}
+	queue.budget = NewTranscriptionBudget(queue.workers, int64(maxInFlightMB)<<20)
 	
-	// Initialize provider based on config
-	switch config.Provider {
+	// Initialize provider based on config, followed by its fallbacks, each checking its availability once per ttl
+	ttl := transcriptionAvailabilityTTL(config)
+	queue.provider = NewCachedTranscription(config.Provider, newTranscriptionProvider(config.Provider, config), ttl)
+	if len(config.FallbackProviders) > 0 {
+		providers := []TranscriptionProvider{queue.provider}
+		for _, name := range config.FallbackProviders {
+			providers = append(providers, NewCachedTranscription(name, newTranscriptionProvider(name, config), ttl))
+		}
+		queue.provider = NewChainedTranscription(providers, controller.Logs)
+	}
+
+	// Start worker pool
+	if queue.provider.IsAvailable() {
+		for i := 0; i < queue.workers; i++ {
+			go queue.worker(i)
+		}
+		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription queue started with %d workers using provider: %s", queue.workers, queue.provider.GetName()))
+	} else {
+		providerName := queue.provider.GetName()
+		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription provider '%s' not available, queue will not process jobs", providerName))
+		controller.Logs.LogEvent(LogLevelWarn, "Make sure your transcription provider is properly configured and accessible")
+	}
+	
+	return queue
+}
+
+// newTranscriptionProvider creates the provider of the given name from the config
+func newTranscriptionProvider(name string, config TranscriptionConfig) TranscriptionProvider {
+	switch name {
 	case "whisper-api":
 		// External OpenAI-compatible Whisper API server
-		queue.provider = NewWhisperAPITranscription(&WhisperAPIConfig{
+		return NewWhisperAPITranscription(&WhisperAPIConfig{
 			BaseURL: config.WhisperAPIURL,
 			APIKey:  config.WhisperAPIKey,
 		})
+	case "whisper-local":
+		// Whisper server on the local network
+		return NewWhisperLocalTranscription(&WhisperLocalConfig{
+			BaseURL: config.WhisperLocalURL,
+			APIKey:  config.WhisperLocalKey,
+		})
 	case "azure":
 		// Azure Speech Services
-		queue.provider = NewAzureTranscription(&AzureConfig{
+		return NewAzureTranscription(&AzureConfig{
 			APIKey: config.AzureKey,
 			Region: config.AzureRegion,
 		})
 	case "google":
 		// Google Cloud Speech-to-Text
-		queue.provider = NewGoogleTranscription(&GoogleConfig{
+		return NewGoogleTranscription(&GoogleConfig{
 			APIKey:      config.GoogleAPIKey,
 			Credentials: config.GoogleCredentials,
 		})
 	case "assemblyai":
 		// AssemblyAI
-		queue.provider = NewAssemblyAITranscription(&AssemblyAIConfig{
+		return NewAssemblyAITranscription(&AssemblyAIConfig{
 			APIKey: config.AssemblyAIKey,
 		})
+	case "aws":
+		// Amazon Transcribe
+		return NewAWSTranscription(&AWSConfig{
+			Region:          config.AWSRegion,
+			Bucket:          config.AWSBucket,
+			AccessKeyId:     config.AWSAccessKeyId,
+			SecretAccessKey: config.AWSSecretAccessKey,
+		})
 	default:
 		// Default to whisper-api
 		if config.WhisperAPIURL == "" {
 			config.WhisperAPIURL = "http://localhost:8000"
 		}
-		queue.provider = NewWhisperAPITranscription(&WhisperAPIConfig{
+		return NewWhisperAPITranscription(&WhisperAPIConfig{
 			BaseURL: config.WhisperAPIURL,
 			APIKey:  config.WhisperAPIKey,
 		})
 	}
-	
-	// Start worker pool
-	if queue.provider.IsAvailable() {
-		for i := 0; i < queue.workers; i++ {
-			go queue.worker(i)
-		}
-		controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription queue started with %d workers using provider: %s", queue.workers, queue.provider.GetName()))
-	} else {
-		providerName := queue.provider.GetName()
-		controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription provider '%s' not available, queue will not process jobs", providerName))
-		controller.Logs.LogEvent(LogLevelWarn, "Make sure your transcription provider is properly configured and accessible")
+}
+
+// transcriptionOptions builds the provider options of a call, with the phrase hints of its system's vocabulary profile
+func (queue *TranscriptionQueue) transcriptionOptions(call *Call, audioMime string) TranscriptionOptions {
+	options := TranscriptionOptions{
+		Language:      queue.controller.Options.TranscriptionConfig.Language,
+		InitialPrompt: queue.controller.Options.TranscriptionConfig.Prompt,
+		AudioMime:     audioMime,
 	}
-	
-	return queue
+
+	var system *System
+	if call != nil {
+		system = call.System
+	}
+
+	options.Phrases = queue.controller.VocabularyProfiles.PhraseHints(system)
+
+	return options
+}
+
+// Submit queues the transcription of a call, leveled by the gain of its talkgroup when configured
+func (queue *TranscriptionQueue) Submit(call *Call, priority int, reasons []string, duration float64) error {
+	job, err := transcriptionJobForCall(call, priority, reasons)
+	if err != nil {
+		return err
+	}
+	job.Duration = duration
+
+	queue.controller.levelTranscriptionJob(&job, call)
+
+	return queue.QueueJob(job)
 }
 
-// QueueJob adds a job to the transcription queue
-func (queue *TranscriptionQueue) QueueJob(job TranscriptionJob) {
+// QueueJob adds a job to the transcription queue, it never blocks and fails when the queue is full
+func (queue *TranscriptionQueue) QueueJob(job TranscriptionJob) error {
+	// Hold the lock so that Stop can't close the channel while the job is sent
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
 	if !queue.running {
-		return
+		return errTranscriptionQueueStopped
 	}
-	
+
 	select {
 	case queue.jobs <- job:
 		// Job queued successfully
 		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription job queued for call %d (priority: %d)", job.CallId, job.Priority))
+		return nil
 	default:
 		// Queue is full, log warning
+		atomic.AddUint64(&queue.dropped, 1)
 		queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription queue full, dropping call %d", job.CallId))
+		return errTranscriptionQueueFull
 	}
 }
 
+// Stats returns the depth of the queue and the jobs being transcribed
+func (queue *TranscriptionQueue) Stats() TranscriptionQueueStats {
+	stats := TranscriptionQueueStats{
+		Workers:  queue.workers,
+		Queued:   len(queue.jobs),
+		Capacity: cap(queue.jobs),
+		Dropped:  atomic.LoadUint64(&queue.dropped),
+	}
+
+	if queue.provider != nil {
+		stats.Provider = queue.provider.GetName()
+	}
+
+	if queue.budget != nil {
+		stats.InFlight, _ = queue.budget.InFlight()
+	}
+
+	return stats
+}
+
 // worker processes transcription jobs
 func (queue *TranscriptionQueue) worker(workerId int) {
 	for job := range queue.jobs {
 		if !queue.running {
 			return
 		}
+
+		// Leave calls past the configured age untranscribed, e.g. a backfill of old audio
+		if transcriptionTooOld(job.Timestamp, queue.controller.Options.TranscriptionConfig.MaxCallAgeDays, time.Now()) {
+			queue.updateCallTranscriptionStatus(job.CallId, "skipped")
+			queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d skipped call %d: older than %d days", workerId, job.CallId, queue.controller.Options.TranscriptionConfig.MaxCallAgeDays))
+			continue
+		}
 		
+		// Wait for enough memory budget, large calls are admitted fewer at a time
+		cost := queue.budget.Acquire(estimateTranscriptionMemory(queue.provider, len(job.Audio)))
+
 		startTime := time.Now()
 		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d starting call %d", workerId, job.CallId))
 		
@@ -141,6 +275,7 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 		call, err := queue.controller.Calls.GetCall(job.CallId)
 		audioToTranscribe := job.Audio
 		usedFilteredAudio := false
+		transcribedDuration := job.Duration
 		
 		// LOCK PENDING TONES: Prevent new tones from merging while this call transcribes
 		// This prevents unrelated tones (from a different incident) from being attached to this voice call
@@ -159,7 +294,13 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 			queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d: call %d has %d detected tones, filtering audio before transcription", workerId, job.CallId, len(call.ToneSequence.Tones)))
 			
 			// Calculate how much audio will remain after filtering
-			totalAudioDuration, durationErr := queue.controller.getAudioDuration(job.Audio, job.AudioMime)
+			totalAudioDuration, durationErr := job.Duration, error(nil)
+			if totalAudioDuration == 0 {
+				totalAudioDuration, durationErr = queue.controller.getAudioDuration(job.Audio, job.AudioMime)
+			}
+			if durationErr == nil {
+				transcribedDuration = totalAudioDuration
+			}
 			totalToneDuration := 0.0
 			for _, tone := range call.ToneSequence.Tones {
 				totalToneDuration += tone.Duration
@@ -181,6 +322,8 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 					Language:   queue.controller.Options.TranscriptionConfig.Language,
 				}
 				go queue.storeTranscription(job.CallId, emptyResult)
+				go queue.notifyTranscription(job, "completed", emptyResult, "")
+				queue.budget.Release(cost)
 				
 				duration := time.Since(startTime)
 				queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d skipped call %d in %v (tone-only)", workerId, job.CallId, duration))
@@ -195,6 +338,11 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 				// Filtering succeeded, use filtered audio
 				audioToTranscribe = filteredAudio
 				usedFilteredAudio = true
+				if durationErr == nil {
+					transcribedDuration = remainingDuration
+				} else {
+					transcribedDuration = 0
+				}
 				queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d: using filtered audio for call %d (removed %.1fs of tones, %.1fs remaining)", 
 					workerId, job.CallId, totalToneDuration, remainingDuration))
 			} else {
@@ -204,11 +352,8 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 		}
 		
 		// Transcribe audio (filtered if tones were present, original otherwise)
-		result, err := queue.provider.Transcribe(audioToTranscribe, TranscriptionOptions{
-			Language:      queue.controller.Options.TranscriptionConfig.Language,
-			InitialPrompt: queue.controller.Options.TranscriptionConfig.Prompt,
-			AudioMime:     job.AudioMime,
-		})
+		result, err := queue.transcribe(job.CallId, audioToTranscribe, queue.transcriptionOptions(call, job.AudioMime))
+		queue.budget.Release(cost)
 		
 		if err != nil {
 			errorMsg := err.Error()
@@ -222,19 +367,34 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 			}
 			
 			queue.updateCallTranscriptionStatus(job.CallId, "failed", errorMsg)
+			go queue.notifyTranscription(job, "failed", nil, errorMsg)
 			continue
 		}
+
+		// Account the audio sent to the provider, whatever is kept of its transcript
+		queue.accrueUsage(job, transcribedDuration, audioToTranscribe, usedFilteredAudio, result)
 		
+		// Strip invalid UTF-8 and control characters from the provider output before anything uses it
+		result.Transcript = SanitizeText(result.Transcript, queue.controller.Options.TranscriptionConfig.SanitizeMode)
+
 		// Clean the transcript of hallucinations before storing and processing
 		cleanedTranscript, hadHallucinations := queue.controller.cleanTranscript(result.Transcript, job.CallId)
+
+		// Nothing but hallucination patterns, neither stored nor matched against keywords and tones
+		if queue.rejectsHallucination(job, result.Transcript, cleanedTranscript) {
+			queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d rejected call %d in %v (hallucination only)", workerId, job.CallId, time.Since(startTime)))
+			continue
+		}
 		
 		// Store cleaned transcription result
 		cleanedResult := &TranscriptionResult{
 			Transcript: cleanedTranscript,
 			Confidence: result.Confidence,
 			Language:   result.Language,
+			Segments:   result.Segments,
 		}
 		go queue.storeTranscription(job.CallId, cleanedResult)
+		go queue.notifyTranscription(job, "completed", cleanedResult, "")
 		
 		// After transcription completes, check if we should attach pending tones to this call
 		// or if this call has its own tones with voice (trigger alert)
@@ -245,6 +405,9 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 				// Update call with cleaned transcript
 				call.Transcript = cleanedTranscript
 				call.TranscriptionStatus = "completed"
+
+				// Send it to the downstreams waiting for its transcript to match their keywords
+				go queue.controller.Downstreams.SendTranscribed(queue.controller, call)
 				
 				// Check if this call has actual voice (not just tones being transcribed)
 				hasVoice := queue.controller.isActualVoice(cleanedTranscript)
@@ -302,12 +465,55 @@ func (queue *TranscriptionQueue) worker(workerId int) {
 		
 		// Process keywords if needed - use cleaned transcript
 		go queue.processKeywords(job.CallId, job.SystemId, job.TalkgroupId, cleanedResult)
+
+		// Link the call to the recent calls mentioning the same incident keywords
+		go queue.controller.groupIncidentByTranscript(job.CallId, cleanedResult.Transcript)
 		
 		duration := time.Since(startTime)
 		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcription worker %d completed call %d in %v (confidence: %.2f)", workerId, job.CallId, duration, result.Confidence))
 	}
 }
 
+// transcriptionTooOld reports whether a call recorded at timestamp is past the maximum age in days
+// for transcription, a maximum of 0 meaning no limit
+func transcriptionTooOld(timestamp time.Time, maxAgeDays int, now time.Time) bool {
+	if maxAgeDays <= 0 || timestamp.IsZero() {
+		return false
+	}
+
+	return now.Sub(timestamp) > time.Duration(maxAgeDays)*24*time.Hour
+}
+
+// isHallucinationOnly reports whether a transcript is made of hallucination patterns only, i.e. nothing
+// but punctuation and spaces is left once they are removed
+func isHallucinationOnly(transcript string, cleanedTranscript string) bool {
+	if strings.TrimSpace(transcript) == "" {
+		return false
+	}
+
+	return strings.IndexFunc(cleanedTranscript, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) == -1
+}
+
+// rejectsHallucination marks the call of a transcript made of hallucination patterns only, when the policy
+// is enabled, notifies the webhook of it and tracks the transcript as rejected
+func (queue *TranscriptionQueue) rejectsHallucination(job TranscriptionJob, transcript string, cleanedTranscript string) bool {
+	if !queue.controller.Options.TranscriptionConfig.RejectHallucinations || !isHallucinationOnly(transcript, cleanedTranscript) {
+		return false
+	}
+
+	queue.updateCallTranscriptionStatus(job.CallId, "hallucination")
+	go queue.notifyTranscription(job, "hallucination", nil, "")
+
+	if queue.controller.HallucinationDetector != nil {
+		count := queue.controller.HallucinationDetector.TrackRejectedTranscript(transcript, job.SystemId)
+		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("transcript of call %d rejected as hallucination (%d so far)", job.CallId, count))
+	}
+
+	return true
+}
+
 // updateCallTranscriptionStatus updates the transcription status for a call
 func (queue *TranscriptionQueue) updateCallTranscriptionStatus(callId uint64, status string, failureReason ...string) {
 	var query string
@@ -328,6 +534,38 @@ func (queue *TranscriptionQueue) updateCallTranscriptionStatus(callId uint64, st
 	}
 }
 
+// accrueUsage adds the duration of the audio transcribed by the provider to the usage of the system of the job.
+// The duration is the one already measured, else the end of the last segment of the transcript. Only when
+// neither is known is the audio measured, the tone filtered audio being mp4.
+func (queue *TranscriptionQueue) accrueUsage(job TranscriptionJob, seconds float64, audio []byte, filtered bool, result *TranscriptionResult) {
+	if seconds <= 0 && len(result.Segments) > 0 {
+		seconds = result.Segments[len(result.Segments)-1].EndTime
+	}
+
+	if seconds <= 0 {
+		mime := job.AudioMime
+		if filtered {
+			mime = "audio/mp4"
+		}
+
+		measured, err := queue.controller.getAudioDuration(audio, mime)
+		if err != nil {
+			queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription usage: duration of call %d: %v", job.CallId, err))
+			return
+		}
+		seconds = measured
+	}
+
+	provider := result.Provider
+	if provider == "" {
+		provider = queue.controller.Options.TranscriptionConfig.Provider
+	}
+
+	if err := queue.controller.TranscriptionUsage.Accrue(job.SystemId, provider, seconds, time.Now()); err != nil {
+		queue.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("transcription usage: call %d: %v", job.CallId, err))
+	}
+}
+
 // storeTranscription stores the transcription result in the database
 func (queue *TranscriptionQueue) storeTranscription(callId uint64, result *TranscriptionResult) {
 	if result == nil {
@@ -378,7 +616,7 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 	queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("processing keywords for call %d (system=%d, talkgroup=%d)", callId, systemId, talkgroupId))
 	
 	// Get all users with keyword alerts enabled for this talkgroup
-	query := fmt.Sprintf(`SELECT "userId", "keywords", "keywordListIds" FROM "userAlertPreferences" WHERE "systemId" = %d AND "talkgroupId" = %d AND "keywordAlerts" = true`, systemId, talkgroupId)
+	query := fmt.Sprintf(`SELECT "userId", "keywords", "keywordListIds", "exclusions" FROM "userAlertPreferences" WHERE "systemId" = %d AND "talkgroupId" = %d AND "keywordAlerts" = true`, systemId, talkgroupId)
 	rows, err := queue.controller.Database.Sql.Query(query)
 	if err != nil {
 		queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to query user alert preferences: %v", err))
@@ -393,6 +631,7 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 		userId         uint64
 		keywords       []string
 		keywordListIds []uint64
+		exclusions     []string
 	}
 	var users []userKeywords
 	
@@ -401,9 +640,10 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 			userId         uint64
 			keywordsJson   string
 			keywordListIds string
+			exclusionsJson string
 		)
 		
-		if err := rows.Scan(&userId, &keywordsJson, &keywordListIds); err != nil {
+		if err := rows.Scan(&userId, &keywordsJson, &keywordListIds, &exclusionsJson); err != nil {
 			continue
 		}
 		
@@ -418,6 +658,11 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 		if keywordListIds != "" && keywordListIds != "[]" {
 			json.Unmarshal([]byte(keywordListIds), &user.keywordListIds)
 		}
+
+		// Parse user's exclusion keywords, suppressing the matches of the transcripts holding them
+		if exclusionsJson != "" && exclusionsJson != "[]" {
+			json.Unmarshal([]byte(exclusionsJson), &user.exclusions)
+		}
 		
 		users = append(users, user)
 	}
@@ -429,11 +674,13 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 	
 	// Step 2: Cache keyword lists (load each list only once)
 	keywordListCache := make(map[uint64][]string)
+	phoneticLists := make(map[uint64]bool)
 	for _, user := range users {
 		for _, listId := range user.keywordListIds {
 			if _, exists := keywordListCache[listId]; !exists {
-				listKeywords := queue.getKeywordsFromList(listId)
+				listKeywords, phonetic := queue.getKeywordsFromList(listId)
 				keywordListCache[listId] = listKeywords
+				phoneticLists[listId] = phonetic
 				queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("cached %d keywords from list %d", len(listKeywords), listId))
 			}
 		}
@@ -443,8 +690,10 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 	// Create a signature for each user's complete keyword set
 	type keywordSetSignature string
 	type keywordGroup struct {
-		keywords []string
-		userIds  []uint64
+		keywords         []string
+		phoneticKeywords []string // from the lists matched phonetically
+		exclusions       []string
+		userIds          []uint64
 	}
 	keywordGroups := make(map[keywordSetSignature]*keywordGroup)
 	
@@ -452,16 +701,21 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 		// Build complete keyword list for this user
 		allKeywords := make([]string, 0, len(user.keywords))
 		allKeywords = append(allKeywords, user.keywords...)
+		phoneticKeywords := []string{}
 		
 		// Add keywords from lists
 		for _, listId := range user.keywordListIds {
 			if listKeywords, exists := keywordListCache[listId]; exists {
-				allKeywords = append(allKeywords, listKeywords...)
+				if phoneticLists[listId] {
+					phoneticKeywords = append(phoneticKeywords, listKeywords...)
+				} else {
+					allKeywords = append(allKeywords, listKeywords...)
+				}
 			}
 		}
 		
 		// Create signature (sorted list IDs + personal keywords for grouping)
-		signature := keywordSetSignature(fmt.Sprintf("%v:%v", user.keywordListIds, user.keywords))
+		signature := keywordSetSignature(fmt.Sprintf("%v:%v:%v", user.keywordListIds, user.keywords, user.exclusions))
 		
 		if group, exists := keywordGroups[signature]; exists {
 			// Same keyword set - add user to existing group
@@ -469,8 +723,10 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 		} else {
 			// New keyword set - create new group
 			keywordGroups[signature] = &keywordGroup{
-				keywords: allKeywords,
-				userIds:  []uint64{user.userId},
+				keywords:         allKeywords,
+				phoneticKeywords: phoneticKeywords,
+				exclusions:       user.exclusions,
+				userIds:          []uint64{user.userId},
 			}
 		}
 	}
@@ -479,10 +735,13 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 	
 	// Step 4: Run matching once per unique keyword set, distribute to all users in group
 	for _, group := range keywordGroups {
-		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("checking %d keywords for %d users against transcript", len(group.keywords), len(group.userIds)))
+		queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("checking %d keywords for %d users against transcript", len(group.keywords)+len(group.phoneticKeywords), len(group.userIds)))
 		
 		// Match keywords ONCE for this group
-		matches := queue.controller.KeywordMatcher.MatchKeywords(transcript, group.keywords)
+		matches := queue.controller.KeywordMatcher.MatchKeywords(transcript, group.keywords, group.exclusions)
+		if len(group.phoneticKeywords) > 0 {
+			matches = append(matches, queue.controller.KeywordMatcher.MatchPhoneticKeywords(transcript, group.phoneticKeywords, group.exclusions)...)
+		}
 
 		// Debug log keyword matches
 		if queue.controller.DebugLogger != nil {
@@ -501,12 +760,6 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 				}
 			}
 
-			// Build keywords matched list
-			keywordsMatched := make([]string, len(matches))
-			for i, match := range matches {
-				keywordsMatched[i] = match.Keyword
-			}
-
 			// Check if there are pending tones for this talkgroup
 			key := fmt.Sprintf("%d:%d", systemId, talkgroupId)
 			queue.controller.pendingTonesMutex.Lock()
@@ -527,7 +780,7 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 			queue.controller.pendingTonesMutex.Unlock()
 
 			// Distribute matches to ALL users in this group
-			var eligibleUserIds []uint64
+			var pushBatches []*keywordPushBatch
 			var usersWithToneAlerts []uint64 // Users who have both keyword and tone alerts enabled
 			
 			for _, userId := range group.userIds {
@@ -539,9 +792,16 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 					match.CallId = callId
 					queue.storeKeywordMatch(&match)
 				}
+
+				// Matches of keywords the user was just alerted of are recorded but don't alert again
+				alertMatches := queue.controller.KeywordCooldowns.Filter(userId, matches, time.Duration(queue.controller.Options.KeywordAlertCooldown)*time.Minute, time.Now())
+				if len(alertMatches) == 0 {
+					queue.controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("keyword alerts for user %d on call %d held back, keyword cooldown", userId, callId))
+					continue
+				}
 				
 				// Trigger alerts (creates DB records and WebSocket notifications)
-				go queue.controller.AlertEngine.TriggerKeywordAlerts(callId, systemId, talkgroupId, userId, matches, result)
+				go queue.controller.AlertEngine.TriggerKeywordAlerts(callId, systemId, talkgroupId, userId, alertMatches, result)
 				
 				// Check if user has tone alerts enabled for this talkgroup
 				// If pending tones exist and user has tone alerts, skip keyword push notification
@@ -566,19 +826,21 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 				
 				// Collect user for batched push notification (only if not skipping)
 				if shouldSendKeywordAlert {
-					eligibleUserIds = append(eligibleUserIds, userId)
+					pushBatches = addKeywordPush(pushBatches, userId, alertMatches)
 				}
 			}
 
-			// Send batched push notification for users who should get keyword alerts
-			if len(eligibleUserIds) > 0 {
+			// Send batched push notifications for users who should get keyword alerts, one per set of keywords
+			if len(pushBatches) > 0 {
 				// Fetch call to get transcript
 				call, err := queue.controller.Calls.GetCall(callId)
 				if err != nil {
 					queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("failed to get call %d for push notification: %v", callId, err))
 					call = nil // Continue without call object
 				}
-				go queue.controller.sendBatchedPushNotification(eligibleUserIds, "keyword", call, systemLabel, talkgroupLabel, "", keywordsMatched)
+				for _, batch := range pushBatches {
+					go queue.controller.sendBatchedPushNotification(batch.userIds, "keyword", call, systemLabel, talkgroupLabel, "", batch.keywords)
+				}
 			}
 			
 			// Log users who will get tone alerts instead
@@ -593,12 +855,15 @@ func (queue *TranscriptionQueue) processKeywords(callId uint64, systemId uint64,
 	}
 }
 
-// getKeywordsFromList retrieves keywords from a keyword list
-func (queue *TranscriptionQueue) getKeywordsFromList(listId uint64) []string {
-	query := fmt.Sprintf(`SELECT "keywords" FROM "keywordLists" WHERE "keywordListId" = %d`, listId)
-	var keywordsJson string
-	if err := queue.controller.Database.Sql.QueryRow(query).Scan(&keywordsJson); err != nil {
-		return []string{}
+// getKeywordsFromList retrieves keywords from a keyword list, and whether they are matched phonetically
+func (queue *TranscriptionQueue) getKeywordsFromList(listId uint64) ([]string, bool) {
+	query := fmt.Sprintf(`SELECT "keywords", "phonetic" FROM "keywordLists" WHERE "keywordListId" = %d`, listId)
+	var (
+		keywordsJson string
+		phonetic     bool
+	)
+	if err := queue.controller.Database.Sql.QueryRow(query).Scan(&keywordsJson, &phonetic); err != nil {
+		return []string{}, false
 	}
 	
 	var keywords []string
@@ -606,7 +871,7 @@ func (queue *TranscriptionQueue) getKeywordsFromList(listId uint64) []string {
 		json.Unmarshal([]byte(keywordsJson), &keywords)
 	}
 	
-	return keywords
+	return keywords, phonetic
 }
 
 // storeKeywordMatch stores a keyword match in the database
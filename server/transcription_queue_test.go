@@ -0,0 +1,84 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTranscriptionQueueDepth(t *testing.T) {
+	controller := &Controller{Options: NewOptions(), Logs: NewLogs()}
+
+	queue := &TranscriptionQueue{
+		jobs:       make(chan TranscriptionJob, 2),
+		workers:    1,
+		budget:     NewTranscriptionBudget(1, 0),
+		provider:   &fakeTranscriptionProvider{available: true},
+		controller: controller,
+		running:    true,
+	}
+
+	call := &Call{
+		Id:        1,
+		Audio:     demoAudio(),
+		AudioMime: "audio/wav",
+		System:    &System{Id: 1, SystemRef: 1},
+		Talkgroup: &Talkgroup{Id: 2, TalkgroupRef: 100},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := queue.Submit(call, 0, nil, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := queue.Submit(call, 0, nil, 0); err != errTranscriptionQueueFull {
+		t.Errorf("expected the full queue to refuse the call, got %v", err)
+	}
+
+	cost := queue.budget.Acquire(1)
+
+	stats := queue.Stats()
+	if stats.Queued != 2 || stats.Capacity != 2 || stats.InFlight != 1 || stats.Dropped != 1 || stats.Workers != 1 || stats.Provider != "fake" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	queue.budget.Release(cost)
+	<-queue.jobs
+
+	if stats := queue.Stats(); stats.Queued != 1 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats once a job is taken, %+v", stats)
+	}
+
+	call.Audio = nil
+	if err := queue.Submit(call, 0, nil, 0); err != errRequeueAudioMissing {
+		t.Errorf("expected missing audio error, got %v", err)
+	}
+}
+
+func TestTranscriptionQueueStopped(t *testing.T) {
+	queue := &TranscriptionQueue{
+		jobs:       make(chan TranscriptionJob, 1),
+		controller: &Controller{Options: NewOptions(), Logs: NewLogs()},
+		running:    true,
+	}
+
+	queue.Stop()
+
+	if err := queue.QueueJob(TranscriptionJob{CallId: 1}); err != errTranscriptionQueueStopped {
+		t.Errorf("expected a stopped queue to refuse jobs, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// minRetranscriptionTtl is the shortest retention in minutes of an ephemeral talkgroup that
+// still leaves time to transcribe its calls again, e.g. for a keyword backfill
+const minRetranscriptionTtl = 60
+
+var (
+	errRequeueAudioMissing  = errors.New("call audio is not retained, it cannot be transcribed again")
+	errRequeueCallMissing   = errors.New("call not found")
+	errRequeueUnavailable   = errors.New("transcription is not enabled")
+	errRequeueSystemMissing = errors.New("call has no system or talkgroup")
+	errRequeueTooOld        = errors.New("call is older than the maximum transcription age")
+)
+
+// transcriptionJobForCall builds the transcription job of a stored call. Calls are always stored
+// with their audio, whether transcription is enabled or not, so they can be transcribed later.
+func transcriptionJobForCall(call *Call, priority int, reasons []string) (TranscriptionJob, error) {
+	if call == nil {
+		return TranscriptionJob{}, errRequeueCallMissing
+	}
+
+	if call.System == nil || call.Talkgroup == nil {
+		return TranscriptionJob{}, errRequeueSystemMissing
+	}
+
+	audio, audioMime := call.audioForAnalysis()
+	if len(audio) == 0 {
+		return TranscriptionJob{}, errRequeueAudioMissing
+	}
+
+	return TranscriptionJob{
+		CallId:      call.Id,
+		Audio:       audio,
+		AudioMime:   audioMime,
+		SystemId:    call.System.Id,
+		TalkgroupId: call.Talkgroup.Id,
+		Priority:    priority,
+		Reasons:     reasons,
+		Timestamp:   call.Timestamp,
+	}, nil
+}
+
+// RequeueTranscription queues a stored call for transcription again
+func (controller *Controller) RequeueTranscription(callId uint64) error {
+	if !controller.Options.TranscriptionConfig.Enabled {
+		return errRequeueUnavailable
+	}
+
+	if controller.TranscriptionQueue == nil {
+		controller.TranscriptionQueue = NewTranscriptionQueue(controller, controller.Options.TranscriptionConfig)
+	}
+
+	call, err := controller.Calls.GetCall(callId)
+	if err != nil {
+		return err
+	}
+
+	job, err := transcriptionJobForCall(call, 10, []string{"requeue"})
+	if err == errRequeueAudioMissing {
+		controller.markTranscriptionArchived(callId)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if transcriptionTooOld(job.Timestamp, controller.Options.TranscriptionConfig.MaxCallAgeDays, time.Now()) {
+		query := fmt.Sprintf(`UPDATE "calls" SET "transcriptionStatus" = 'skipped' WHERE "callId" = %d`, callId)
+		if _, err := controller.Database.Sql.Exec(query); err != nil {
+			return fmt.Errorf("%s in %s", err, query)
+		}
+		return errRequeueTooOld
+	}
+
+	query := fmt.Sprintf(`UPDATE "calls" SET "transcriptionStatus" = 'pending' WHERE "callId" = %d`, callId)
+	if _, err := controller.Database.Sql.Exec(query); err != nil {
+		return fmt.Errorf("%s in %s", err, query)
+	}
+
+	controller.levelTranscriptionJob(&job, call)
+
+	return controller.TranscriptionQueue.QueueJob(job)
+}
+
+// retranscriptionWarnings lists the talkgroups that the new configuration makes ephemeral, or
+// whose retention it shortens, to the point their calls could not be transcribed again
+func retranscriptionWarnings(current []*System, next []*System, transcriptionEnabled bool) []string {
+	warnings := []string{}
+
+	previous := map[uint64]*Talkgroup{}
+	for _, system := range current {
+		if system.Talkgroups == nil {
+			continue
+		}
+		for _, talkgroup := range system.Talkgroups.List {
+			previous[talkgroup.Id] = talkgroup
+		}
+	}
+
+	for _, system := range next {
+		if system.Talkgroups == nil {
+			continue
+		}
+
+		for _, talkgroup := range system.Talkgroups.List {
+			if !talkgroup.Ephemeral {
+				continue
+			}
+
+			ttl := talkgroup.EphemeralTtl
+			if ttl == 0 {
+				ttl = defaultEphemeralTtl
+			}
+
+			if prev, ok := previous[talkgroup.Id]; ok && talkgroup.Id > 0 && prev.Ephemeral {
+				prevTtl := prev.EphemeralTtl
+				if prevTtl == 0 {
+					prevTtl = defaultEphemeralTtl
+				}
+				if ttl >= prevTtl {
+					continue
+				}
+			}
+
+			switch {
+			case !transcriptionEnabled:
+				warnings = append(warnings, fmt.Sprintf("talkgroup %d of system %d is ephemeral while transcription is disabled, its calls will be deleted after %d minutes and can never be transcribed", talkgroup.TalkgroupRef, system.SystemRef, ttl))
+			case ttl < minRetranscriptionTtl:
+				warnings = append(warnings, fmt.Sprintf("talkgroup %d of system %d keeps its calls for %d minutes only, they cannot be transcribed again after that", talkgroup.TalkgroupRef, system.SystemRef, ttl))
+			}
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTranscriptionJobForCallKeepsAudio(t *testing.T) {
+	controller := &Controller{Options: NewOptions()}
+	controller.Options.TranscriptionConfig.Enabled = false
+
+	audio := demoAudio()
+	call := &Call{
+		Id:        42,
+		Audio:     audio,
+		AudioMime: "audio/wav",
+		System:    &System{Id: 1, SystemRef: 1},
+		Talkgroup: &Talkgroup{Id: 2, TalkgroupRef: 100},
+	}
+
+	if err := controller.RequeueTranscription(call.Id); err != errRequeueUnavailable {
+		t.Errorf("expected requeue to be refused while transcription is disabled, got %v", err)
+	}
+
+	job, err := transcriptionJobForCall(call, 10, []string{"requeue"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if job.CallId != 42 || job.SystemId != 1 || job.TalkgroupId != 2 || job.AudioMime != "audio/wav" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	if !bytes.Equal(job.Audio, audio) {
+		t.Errorf("job audio does not match the stored call audio")
+	}
+
+	call.Audio = nil
+	if _, err := transcriptionJobForCall(call, 10, nil); err != errRequeueAudioMissing {
+		t.Errorf("expected missing audio error, got %v", err)
+	}
+
+	call.Audio = audio
+	call.Talkgroup = nil
+	if _, err := transcriptionJobForCall(call, 10, nil); err != errRequeueSystemMissing {
+		t.Errorf("expected missing talkgroup error, got %v", err)
+	}
+}
+
+func TestRetranscriptionWarnings(t *testing.T) {
+	current := NewTalkgroups()
+	current.List = []*Talkgroup{
+		{Id: 1, TalkgroupRef: 100, Ephemeral: true, EphemeralTtl: 30},
+		{Id: 2, TalkgroupRef: 200},
+		{Id: 3, TalkgroupRef: 300, Ephemeral: true, EphemeralTtl: 240},
+	}
+
+	next := NewTalkgroups()
+	next.List = []*Talkgroup{
+		{Id: 1, TalkgroupRef: 100, Ephemeral: true, EphemeralTtl: 30},
+		{Id: 2, TalkgroupRef: 200, Ephemeral: true, EphemeralTtl: 120},
+		{Id: 3, TalkgroupRef: 300, Ephemeral: true, EphemeralTtl: 15},
+	}
+
+	currentSystems := []*System{{Id: 1, SystemRef: 1, Talkgroups: current}}
+	nextSystems := []*System{{Id: 1, SystemRef: 1, Talkgroups: next}}
+
+	if warnings := retranscriptionWarnings(currentSystems, currentSystems, false); len(warnings) != 0 {
+		t.Errorf("unchanged retention must not warn, got %v", warnings)
+	}
+
+	// transcription disabled, the newly ephemeral and the shortened talkgroups both warn
+	if warnings := retranscriptionWarnings(currentSystems, nextSystems, false); len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", warnings)
+	}
+
+	// transcription enabled, only the retention shorter than the minimum warns
+	if warnings := retranscriptionWarnings(currentSystems, nextSystems, true); len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", warnings)
+	}
+}
+
+func TestTranscriptionMaxCallAge(t *testing.T) {
+	now := time.Now()
+	options := NewOptions()
+	options.FromMap(map[string]any{"transcriptionConfig": map[string]any{"maxCallAgeDays": float64(30)}})
+
+	maxAge := options.TranscriptionConfig.MaxCallAgeDays
+	if maxAge != 30 {
+		t.Fatalf("expected a maximum age of 30 days, got %d", maxAge)
+	}
+
+	call := &Call{
+		Id:        1,
+		Audio:     demoAudio(),
+		AudioMime: "audio/wav",
+		System:    &System{Id: 1, SystemRef: 1},
+		Talkgroup: &Talkgroup{Id: 2, TalkgroupRef: 100},
+		Timestamp: now.Add(-2 * 24 * time.Hour),
+	}
+
+	recent, err := transcriptionJobForCall(call, 10, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transcriptionTooOld(recent.Timestamp, maxAge, now) {
+		t.Error("a 2 day old call must be transcribed")
+	}
+
+	call.Timestamp = now.Add(-45 * 24 * time.Hour)
+	old, err := transcriptionJobForCall(call, 10, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !transcriptionTooOld(old.Timestamp, maxAge, now) {
+		t.Error("a 45 day old call must be skipped")
+	}
+
+	if transcriptionTooOld(old.Timestamp, 0, now) {
+		t.Error("no maximum age must transcribe every call")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// transcriptionUsageMonthLayout is the layout of the months the usage is aggregated by, in UTC
+const transcriptionUsageMonthLayout = "2006-01"
+
+var transcriptionUsageMonthRegexp = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+// TranscriptionUsageRow is the usage of a provider by a system over a month
+type TranscriptionUsageRow struct {
+	SystemId       uint64  `json:"systemId"`
+	SystemLabel    string  `json:"systemLabel"`
+	Month          string  `json:"month"` // e.g. "2025-03"
+	Provider       string  `json:"provider"`
+	Transcriptions uint64  `json:"transcriptions"`
+	Seconds        float64 `json:"seconds"`
+	Cost           float64 `json:"cost"`
+}
+
+// TranscriptionUsage accounts the audio transcribed and its cost per system, month and provider
+type TranscriptionUsage struct {
+	controller *Controller
+}
+
+func NewTranscriptionUsage(controller *Controller) *TranscriptionUsage {
+	return &TranscriptionUsage{controller: controller}
+}
+
+// transcriptionCost returns the cost of seconds of audio at the rate per minute configured for the provider
+func transcriptionCost(config TranscriptionConfig, provider string, seconds float64) float64 {
+	return config.CostPerMinute[provider] * seconds / 60
+}
+
+// Accrue adds a transcription of seconds of audio by the provider to the usage of the system in the month of at
+func (usage *TranscriptionUsage) Accrue(systemId uint64, provider string, seconds float64, at time.Time) error {
+	if usage == nil {
+		return nil
+	}
+
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	month := at.UTC().Format(transcriptionUsageMonthLayout)
+	cost := transcriptionCost(usage.controller.Options.TranscriptionConfig, provider, seconds)
+
+	query := fmt.Sprintf(`INSERT INTO "transcriptionUsage" ("systemId", "month", "provider", "transcriptions", "seconds", "cost") VALUES (%d, $1, $2, 1, $3, $4) ON CONFLICT ("systemId", "month", "provider") DO UPDATE SET "transcriptions" = "transcriptionUsage"."transcriptions" + 1, "seconds" = "transcriptionUsage"."seconds" + EXCLUDED."seconds", "cost" = "transcriptionUsage"."cost" + EXCLUDED."cost"`, systemId)
+	if _, err := usage.controller.Database.Sql.Exec(query, month, provider, seconds, cost); err != nil {
+		return fmt.Errorf("%v in %s", err, query)
+	}
+
+	return nil
+}
+
+// Report returns the usage between the from and to months included, either empty for no bound,
+// by month then system and provider
+func (usage *TranscriptionUsage) Report(from string, to string) ([]*TranscriptionUsageRow, error) {
+	var (
+		args  = []any{}
+		where = ""
+	)
+
+	if from != "" {
+		args = append(args, from)
+		where = fmt.Sprintf(`WHERE u."month" >= $%d`, len(args))
+	}
+	if to != "" {
+		args = append(args, to)
+		if where == "" {
+			where = fmt.Sprintf(`WHERE u."month" <= $%d`, len(args))
+		} else {
+			where += fmt.Sprintf(` AND u."month" <= $%d`, len(args))
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT u."systemId", COALESCE(s."label", ''), u."month", u."provider", u."transcriptions", u."seconds", u."cost" FROM "transcriptionUsage" AS u LEFT JOIN "systems" AS s ON s."systemId" = u."systemId" %s ORDER BY u."month", u."systemId", u."provider"`, where)
+	rows, err := usage.controller.Database.Sql.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+
+	report := []*TranscriptionUsageRow{}
+	for rows.Next() {
+		row := &TranscriptionUsageRow{}
+		if err := rows.Scan(&row.SystemId, &row.SystemLabel, &row.Month, &row.Provider, &row.Transcriptions, &row.Seconds, &row.Cost); err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+
+	return report, rows.Err()
+}
+
+// TranscriptionUsageHandler returns the transcription usage between the from and to months, as YYYY-MM
+func (admin *Admin) TranscriptionUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	for key, month := range map[string]string{"from": from, "to": to} {
+		if month != "" && !transcriptionUsageMonthRegexp.MatchString(month) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid " + key + ", expected YYYY-MM"})
+			return
+		}
+	}
+
+	report, err := admin.Controller.TranscriptionUsage.Report(from, to)
+	if err != nil {
+		admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("transcription usage report: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read the transcription usage"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"usage": report})
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscriptionUsageAccrue(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions()}
+	controller.Options.TranscriptionConfig.CostPerMinute = map[string]float64{"azure": 0.06, "whisper-api": 0}
+	usage := NewTranscriptionUsage(controller)
+
+	// the provider names the results of the completed transcriptions
+	provider := NewCachedTranscription("azure", &scriptedTranscriptionProvider{name: "azure", available: true, transcript: "engine 5 respond"}, 0)
+	result, err := provider.Transcribe([]byte("audio"), TranscriptionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Provider != "azure" {
+		t.Fatalf("expected the result named after its provider, got %q", result.Provider)
+	}
+
+	march := time.Date(2025, 3, 31, 23, 30, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 1, 0, 30, 0, 0, time.UTC)
+
+	for _, accrual := range []struct {
+		systemId uint64
+		provider string
+		seconds  float64
+		at       time.Time
+	}{
+		{1, result.Provider, 90, march},
+		{2, result.Provider, 30, march},
+		{1, result.Provider, 120, april},
+		{1, "whisper-api", 60, april},
+	} {
+		if err := usage.Accrue(accrual.systemId, accrual.provider, accrual.seconds, accrual.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []struct {
+		systemId string
+		args     []driver.Value
+	}{
+		{"VALUES (1,", []driver.Value{"2025-03", "azure", 90.0, 0.09}},
+		{"VALUES (2,", []driver.Value{"2025-03", "azure", 30.0, 0.03}},
+		{"VALUES (1,", []driver.Value{"2025-04", "azure", 120.0, 0.12}},
+		{"VALUES (1,", []driver.Value{"2025-04", "whisper-api", 60.0, 0.0}},
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.queries) != len(want) {
+		t.Fatalf("expected %d usage upserts, got %v", len(want), d.queries)
+	}
+	for i, w := range want {
+		if !strings.Contains(d.queries[i], w.systemId) || !strings.Contains(d.queries[i], "ON CONFLICT") {
+			t.Errorf("upsert %d: expected the system %s, got %s", i, w.systemId, d.queries[i])
+		}
+		for j := range w.args {
+			got := d.args[i][j]
+			if f, ok := got.(float64); ok {
+				if expected := w.args[j].(float64); f < expected-1e-9 || f > expected+1e-9 {
+					t.Errorf("upsert %d argument %d: expected %v, got %v", i, j, expected, f)
+				}
+			} else if got != w.args[j] {
+				t.Errorf("upsert %d argument %d: expected %v, got %v", i, j, w.args[j], got)
+			}
+		}
+	}
+}
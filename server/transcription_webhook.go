@@ -0,0 +1,194 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const transcriptionWebhookSignatureHeader = "X-Thinline-Signature"
+
+// transcriptionWebhookRetryDelays are the waits before each new attempt of a webhook delivery
+// refused by a network error or a server error
+var transcriptionWebhookRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+var transcriptionWebhookClient = &http.Client{Timeout: 15 * time.Second}
+
+const (
+	// transcriptionWebhookMaxSegments and transcriptionWebhookMaxSegmentText bound the segments posted
+	// to the webhook, whatever the provider returned
+	transcriptionWebhookMaxSegments    = 1000
+	transcriptionWebhookMaxSegmentText = 500
+)
+
+// TranscriptionWebhookEvent is the body posted to the transcription webhook
+type TranscriptionWebhookEvent struct {
+	Event         string                        `json:"event"`
+	CallId        uint64                        `json:"callId"`
+	Status        string                        `json:"status"`
+	SystemRef     uint                          `json:"systemRef"`
+	TalkgroupRef  uint                          `json:"talkgroupRef"`
+	Transcript    string                        `json:"transcript"`
+	Confidence    float64                       `json:"confidence"`
+	Language      string                        `json:"language,omitempty"`
+	Segments      []TranscriptionWebhookSegment `json:"segments,omitempty"`
+	FailureReason string                        `json:"failureReason,omitempty"`
+	Timestamp     int64                         `json:"timestamp"`
+}
+
+// TranscriptionWebhookSegment is a timestamped segment of the transcript posted to the webhook
+type TranscriptionWebhookSegment struct {
+	Text      string  `json:"text"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+}
+
+// transcriptionWebhookSegments returns the text and times of the segments of the provider, sanitized
+// and bounded in number and length
+func transcriptionWebhookSegments(segments []TranscriptSegment, sanitizeMode string) []TranscriptionWebhookSegment {
+	if len(segments) > transcriptionWebhookMaxSegments {
+		segments = segments[:transcriptionWebhookMaxSegments]
+	}
+
+	webhookSegments := make([]TranscriptionWebhookSegment, len(segments))
+	for i, segment := range segments {
+		webhookSegments[i] = TranscriptionWebhookSegment{
+			Text:      truncatePushMessage(SanitizeText(segment.Text, sanitizeMode), transcriptionWebhookMaxSegmentText),
+			StartTime: segment.StartTime,
+			EndTime:   segment.EndTime,
+		}
+	}
+
+	return webhookSegments
+}
+
+// transcriptionWebhookMatches reports whether the webhook filter selects the talkgroup, an empty
+// filter selecting every talkgroup
+func transcriptionWebhookMatches(filter any, systemRef uint, talkgroupRef uint) bool {
+	switch v := filter.(type) {
+	case nil:
+		return true
+	case string:
+		if v == "" {
+			return true
+		}
+	case []any:
+		if len(v) == 0 {
+			return true
+		}
+	}
+
+	return systemsFilterMatches(filter, systemRef, talkgroupRef)
+}
+
+// signTranscriptionWebhook returns the hex HMAC-SHA256 of the body, as sent in the signature header
+func signTranscriptionWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverTranscriptionWebhook posts the body until it is accepted, retrying after each delay on a
+// network or server error. Client errors are not retried since the same body would be refused again.
+func deliverTranscriptionWebhook(client *http.Client, url string, secret string, body []byte, retryDelays []time.Duration) error {
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		var req *http.Request
+		if req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(body)); err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set(transcriptionWebhookSignatureHeader, signTranscriptionWebhook(secret, body))
+		}
+
+		var res *http.Response
+		if res, err = client.Do(req); err == nil {
+			res.Body.Close()
+
+			switch {
+			case res.StatusCode < 300:
+				return nil
+			case res.StatusCode < 500:
+				return fmt.Errorf("webhook refused with status %d", res.StatusCode)
+			}
+
+			err = fmt.Errorf("webhook failed with status %d", res.StatusCode)
+		}
+
+		if attempt >= len(retryDelays) {
+			return err
+		}
+
+		time.Sleep(retryDelays[attempt])
+	}
+}
+
+// notifyTranscription posts the outcome of a transcription to the configured webhook, when the
+// talkgroup of the call is selected. The worker calls it once per completed, failed or rejected call.
+func (queue *TranscriptionQueue) notifyTranscription(job TranscriptionJob, status string, result *TranscriptionResult, failureReason string) {
+	config := queue.controller.Options.TranscriptionConfig
+
+	if config.WebhookURL == "" {
+		return
+	}
+
+	event := TranscriptionWebhookEvent{
+		Event:         "transcription." + status,
+		CallId:        job.CallId,
+		Status:        status,
+		FailureReason: failureReason,
+		Timestamp:     time.Now().UnixMilli(),
+	}
+
+	if system, ok := queue.controller.Systems.GetSystemById(job.SystemId); ok {
+		event.SystemRef = system.SystemRef
+
+		if talkgroup, ok := system.Talkgroups.GetTalkgroupById(job.TalkgroupId); ok {
+			event.TalkgroupRef = talkgroup.TalkgroupRef
+		}
+	}
+
+	if !transcriptionWebhookMatches(config.WebhookSystems, event.SystemRef, event.TalkgroupRef) {
+		return
+	}
+
+	if result != nil {
+		event.Transcript = result.Transcript
+		event.Confidence = result.Confidence
+		event.Language = result.Language
+		event.Segments = transcriptionWebhookSegments(result.Segments, config.SanitizeMode)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		queue.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("transcription webhook for call %d: %v", job.CallId, err))
+		return
+	}
+
+	if err = deliverTranscriptionWebhook(transcriptionWebhookClient, config.WebhookURL, config.WebhookSecret, body, transcriptionWebhookRetryDelays); err != nil {
+		queue.controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("transcription webhook for call %d not delivered: %v", job.CallId, err))
+	}
+}
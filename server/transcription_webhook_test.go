@@ -0,0 +1,153 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTranscriptionWebhookMatches(t *testing.T) {
+	filter := []any{
+		map[string]any{"id": float64(10), "talkgroups": []any{float64(100)}},
+		map[string]any{"id": float64(20), "talkgroups": "*"},
+	}
+
+	cases := []struct {
+		filter       any
+		systemRef    uint
+		talkgroupRef uint
+		expected     bool
+	}{
+		{nil, 10, 100, true},
+		{"", 10, 100, true},
+		{"*", 30, 300, true},
+		{filter, 10, 100, true},
+		{filter, 10, 101, false},
+		{filter, 20, 999, true},
+		{filter, 30, 100, false},
+	}
+
+	for _, c := range cases {
+		if matches := transcriptionWebhookMatches(c.filter, c.systemRef, c.talkgroupRef); matches != c.expected {
+			t.Errorf("filter %v on %d/%d: expected %v, got %v", c.filter, c.systemRef, c.talkgroupRef, c.expected, matches)
+		}
+	}
+}
+
+func TestTranscriptionWebhookFiresOnce(t *testing.T) {
+	delays := transcriptionWebhookRetryDelays
+	transcriptionWebhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { transcriptionWebhookRetryDelays = delays }()
+
+	var (
+		requests  atomic.Int32
+		failFirst atomic.Bool
+		event     TranscriptionWebhookEvent
+		signature string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failFirst.CompareAndSwap(true, false) {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(transcriptionWebhookSignatureHeader)
+		if signature != signTranscriptionWebhook("secret", body) {
+			t.Errorf("invalid signature %s", signature)
+		}
+		json.Unmarshal(body, &event)
+	}))
+	defer server.Close()
+
+	controller := &Controller{
+		Options: NewOptions(),
+		Logs:    NewLogs(),
+		Systems: NewSystems().FromMap([]any{
+			map[string]any{"id": float64(1), "label": "County", "systemRef": float64(10), "talkgroups": []any{
+				map[string]any{"id": float64(2), "label": "Fire", "talkgroupRef": float64(100)},
+			}},
+		}),
+	}
+	controller.Options.TranscriptionConfig.WebhookURL = server.URL
+	controller.Options.TranscriptionConfig.WebhookSecret = "secret"
+
+	queue := &TranscriptionQueue{controller: controller}
+	job := TranscriptionJob{CallId: 42, SystemId: 1, TalkgroupId: 2}
+	result := &TranscriptionResult{
+		Transcript: "ENGINE 5 RESPONDING",
+		Confidence: 0.9,
+		Segments:   []TranscriptSegment{{Text: "ENGINE 5 RESPONDING", EndTime: 2}},
+	}
+
+	queue.notifyTranscription(job, "completed", result, "")
+
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("expected 1 delivery, got %d", n)
+	}
+
+	if event.CallId != 42 || event.Status != "completed" || event.Transcript != result.Transcript || event.SystemRef != 10 || event.TalkgroupRef != 100 || len(event.Segments) != 1 {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// a server error is retried until the event is accepted, then not sent again
+	failFirst.Store(true)
+	queue.notifyTranscription(job, "failed", nil, "timeout")
+
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("expected 1 retried delivery, got %d requests in total", n)
+	}
+
+	if event.Status != "failed" || event.FailureReason != "timeout" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// talkgroups outside the filter are not notified
+	controller.Options.TranscriptionConfig.WebhookSystems = []any{map[string]any{"id": float64(10), "talkgroups": []any{float64(200)}}}
+	queue.notifyTranscription(job, "completed", result, "")
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected no delivery for a filtered talkgroup, got %d requests in total", n)
+	}
+}
+
+func TestTranscriptionWebhookSegmentsBounded(t *testing.T) {
+	segments := make([]TranscriptSegment, transcriptionWebhookMaxSegments+10)
+	segments[0] = TranscriptSegment{Text: "ENGINE\x005 " + strings.Repeat("A", 2*transcriptionWebhookMaxSegmentText), StartTime: 1, EndTime: 2, Confidence: 0.5}
+
+	webhookSegments := transcriptionWebhookSegments(segments, TextSanitizeStrip)
+
+	if len(webhookSegments) != transcriptionWebhookMaxSegments {
+		t.Errorf("expected %d segments, got %d", transcriptionWebhookMaxSegments, len(webhookSegments))
+	}
+
+	first := webhookSegments[0]
+	if !strings.HasPrefix(first.Text, "ENGINE5 ") || len(first.Text) > transcriptionWebhookMaxSegmentText+len("…") {
+		t.Errorf("expected the text sanitized and truncated, got %d bytes %q", len(first.Text), first.Text[:10])
+	}
+	if first.StartTime != 1 || first.EndTime != 2 {
+		t.Errorf("expected the times kept, got %+v", first)
+	}
+}
@@ -188,23 +188,7 @@ func isRetryableError(err error) bool {
 // attemptTranscribe performs a single transcription attempt
 func (api *WhisperAPITranscription) attemptTranscribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
 	// Determine file extension from MIME type
-	filename := "audio.m4a" // Default
-	if options.AudioMime != "" {
-		switch options.AudioMime {
-		case "audio/mp4", "audio/m4a":
-			filename = "audio.m4a"
-		case "audio/mpeg", "audio/mp3":
-			filename = "audio.mp3"
-		case "audio/wav", "audio/wave":
-			filename = "audio.wav"
-		case "audio/ogg":
-			filename = "audio.ogg"
-		case "audio/webm":
-			filename = "audio.webm"
-		default:
-			filename = "audio.m4a" // Default to m4a
-		}
-	}
+	filename := whisperAudioFilename(options.AudioMime)
 
 	// Create multipart form data
 	var requestBody bytes.Buffer
@@ -253,8 +237,8 @@ func (api *WhisperAPITranscription) attemptTranscribe(audio []byte, options Tran
 	}
 
 	// Add prompt if specified (for custom terminology, formatting, etc.)
-	if options.InitialPrompt != "" {
-		if err := writer.WriteField("prompt", options.InitialPrompt); err != nil {
+	if prompt := whisperPrompt(options); prompt != "" {
+		if err := writer.WriteField("prompt", prompt); err != nil {
 			return nil, fmt.Errorf("failed to write prompt field: %v", err)
 		}
 	}
@@ -348,6 +332,37 @@ func (api *WhisperAPITranscription) attemptTranscribe(audio []byte, options Tran
 	}, nil
 }
 
+// whisperAudioFilename returns a file name whose extension tells the server the audio format
+func whisperAudioFilename(audioMime string) string {
+	switch audioMime {
+	case "audio/mpeg", "audio/mp3":
+		return "audio.mp3"
+	case "audio/wav", "audio/wave", "audio/x-wav":
+		return "audio.wav"
+	case "audio/ogg":
+		return "audio.ogg"
+	case "audio/webm":
+		return "audio.webm"
+	default:
+		return "audio.m4a"
+	}
+}
+
+// whisperPrompt appends the vocabulary phrases to the prompt, Whisper has no dedicated
+// biasing parameter but favors the words it sees in the prompt
+func whisperPrompt(options TranscriptionOptions) string {
+	if len(options.Phrases) == 0 {
+		return options.InitialPrompt
+	}
+
+	phrases := strings.Join(options.Phrases, ", ")
+	if options.InitialPrompt == "" {
+		return phrases
+	}
+
+	return options.InitialPrompt + " " + phrases
+}
+
 // IsAvailable checks if the API server is available
 func (api *WhisperAPITranscription) IsAvailable() bool {
 	return api.available
@@ -0,0 +1,186 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WhisperLocalTranscription implements TranscriptionProvider for a Whisper server on the local
+// network, such as faster-whisper behind an OpenAI-compatible /v1/audio/transcriptions endpoint
+type WhisperLocalTranscription struct {
+	available  bool
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	warned     bool
+}
+
+// WhisperLocalConfig contains configuration for a local Whisper server
+type WhisperLocalConfig struct {
+	BaseURL string // Base URL of the server (e.g., "http://192.168.1.10:8000")
+	APIKey  string // Optional API key
+}
+
+// NewWhisperLocalTranscription creates a new local Whisper transcription provider
+func NewWhisperLocalTranscription(config *WhisperLocalConfig) *WhisperLocalTranscription {
+	local := &WhisperLocalTranscription{
+		baseURL: strings.TrimSuffix(strings.TrimSpace(config.BaseURL), "/"),
+		apiKey:  config.APIKey,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+
+	// These servers don't all have a health endpoint, a configured url is enough
+	local.available = local.baseURL != ""
+
+	return local
+}
+
+// Transcribe transcribes audio using the local Whisper server
+func (local *WhisperLocalTranscription) Transcribe(audio []byte, options TranscriptionOptions) (*TranscriptionResult, error) {
+	if !local.available {
+		if !local.warned {
+			local.warned = true
+			return nil, fmt.Errorf("local Whisper server not configured. Please provide its base URL")
+		}
+		return nil, errors.New("local Whisper server is not available")
+	}
+
+	var requestBody bytes.Buffer
+	writer := multipart.NewWriter(&requestBody)
+
+	fileWriter, err := writer.CreateFormFile("file", whisperAudioFilename(options.AudioMime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err := fileWriter.Write(audio); err != nil {
+		return nil, fmt.Errorf("failed to write audio data: %v", err)
+	}
+
+	// The server picks its own model when given the OpenAI one
+	model := options.Model
+	if model == "" {
+		model = "whisper-1"
+	}
+
+	fields := map[string]string{
+		"model":           model,
+		"response_format": "json",
+	}
+	if options.Language != "" && options.Language != "auto" {
+		fields["language"] = options.Language
+	}
+	if options.Temperature > 0 {
+		fields["temperature"] = fmt.Sprintf("%.2f", options.Temperature)
+	}
+	if prompt := whisperPrompt(options); prompt != "" {
+		fields["prompt"] = prompt
+	}
+
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("failed to write %s field: %v", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, local.baseURL+"/v1/audio/transcriptions", &requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if local.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+local.apiKey)
+	}
+
+	resp, err := local.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to local Whisper server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("local Whisper server request failed with status %d %s: %s", resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(bodyBytes)))
+	}
+
+	var localResponse struct {
+		Text     string  `json:"text"`
+		Language string  `json:"language"`
+		Duration float64 `json:"duration"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&localResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse local Whisper server response: %v", err)
+	}
+
+	transcript := strings.ToUpper(strings.TrimSpace(localResponse.Text))
+
+	segments := []TranscriptSegment{}
+	if transcript != "" {
+		segments = append(segments, TranscriptSegment{
+			Text:       transcript,
+			StartTime:  0,
+			EndTime:    localResponse.Duration,
+			Confidence: 0.95, // The json response has no confidence
+		})
+	}
+
+	language := localResponse.Language
+	if language == "" {
+		language = options.Language
+	}
+
+	return &TranscriptionResult{
+		Transcript: transcript,
+		Confidence: 0.95,
+		Language:   language,
+		Segments:   segments,
+	}, nil
+}
+
+// IsAvailable checks if the local Whisper server is configured
+func (local *WhisperLocalTranscription) IsAvailable() bool {
+	return local.available
+}
+
+// GetName returns the name of this transcription provider
+func (local *WhisperLocalTranscription) GetName() string {
+	return fmt.Sprintf("Local Whisper Server (%s)", local.baseURL)
+}
+
+// GetSupportedLanguages returns supported languages
+func (local *WhisperLocalTranscription) GetSupportedLanguages() []string {
+	return []string{
+		"auto", "en", "es", "fr", "de", "it", "pt", "ru", "ja", "ko", "zh",
+		"nl", "tr", "pl", "ca", "fa", "ar", "cs", "el", "fi", "he", "hi",
+		"hu", "id", "ms", "no", "ro", "sk", "sv", "uk", "vi",
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWhisperLocalTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/audio/transcriptions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		audio, _ := io.ReadAll(file)
+
+		if string(audio) != "audio" || header.Filename != "audio.wav" || r.FormValue("language") != "en" || r.FormValue("response_format") != "json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text": "  Engine 5 respond to Main Street  "}`))
+	}))
+	defer server.Close()
+
+	local := NewWhisperLocalTranscription(&WhisperLocalConfig{BaseURL: server.URL + "/", APIKey: "secret"})
+	if !local.IsAvailable() {
+		t.Fatal("a configured local server should be available")
+	}
+
+	result, err := local.Transcribe([]byte("audio"), TranscriptionOptions{Language: "en", AudioMime: "audio/wav"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Transcript != "ENGINE 5 RESPOND TO MAIN STREET" {
+		t.Errorf("transcript = %q", result.Transcript)
+	}
+
+	if len(result.Segments) != 1 || result.Segments[0].Text != result.Transcript {
+		t.Errorf("segments = %+v, want a single segment of the transcript", result.Segments)
+	}
+}
+
+func TestWhisperLocalTranscribeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("model is loading"))
+	}))
+	defer server.Close()
+
+	_, err := NewWhisperLocalTranscription(&WhisperLocalConfig{BaseURL: server.URL}).Transcribe([]byte("audio"), TranscriptionOptions{})
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "model is loading") {
+		t.Errorf("error = %v, want the status and the body of the response", err)
+	}
+}
+
+func TestWhisperLocalUnconfigured(t *testing.T) {
+	local := NewWhisperLocalTranscription(&WhisperLocalConfig{})
+	if local.IsAvailable() {
+		t.Error("a local server without url should not be available")
+	}
+
+	if _, err := local.Transcribe([]byte("audio"), TranscriptionOptions{}); err == nil {
+		t.Error("transcribing without a server should fail")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/base32"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -338,36 +339,15 @@ func (u *User) PinExpired() bool {
 	return uint64(time.Now().Unix()) > u.PinExpiresAt
 }
 
+// EffectiveDelay returns the delay the user sets for the call, or defaultDelay when none is set.
+// The delays of the group of the user are resolved along with it by ResolveDelay.
 func (u *User) EffectiveDelay(call *Call, defaultDelay uint) uint {
-	if u == nil || call == nil || call.System == nil || call.Talkgroup == nil {
+	if u == nil {
 		return defaultDelay
 	}
 
-	// If user has a group, use group's delay settings
-	// Note: This requires access to Controller, which we'll handle in the caller
-	// For now, fall back to user-level delays if group is not available
-	if u.UserGroupId > 0 {
-		// Group delays will be checked in controller where we have access to UserGroups
-		// This method signature will need to be updated or we check group in the caller
-		// For backward compatibility, we'll check both
-	}
-
-	// Fall back to user-level delays
-	if len(u.talkgroupDelaysMap) > 0 {
-		key := fmt.Sprintf("%d:%d", call.System.SystemRef, call.Talkgroup.TalkgroupRef)
-		if delay, ok := u.talkgroupDelaysMap[key]; ok && delay > 0 {
-			return delay
-		}
-	}
-
-	if len(u.systemDelaysMap) > 0 {
-		if delay, ok := u.systemDelaysMap[uint64(call.System.SystemRef)]; ok && delay > 0 {
-			return delay
-		}
-	}
-
-	if u.Delay > 0 {
-		return uint(u.Delay)
+	if delay := delayOverride(u.talkgroupDelaysMap, u.systemDelaysMap, u.Delay, call); delay > 0 {
+		return delay
 	}
 
 	return defaultDelay
@@ -908,6 +888,9 @@ func (users *Users) CheckDuplicateEmails() map[string][]*User {
 	return duplicates
 }
 
+// errUserExists is returned when a new user collides with an existing one on its email
+var errUserExists = errors.New("user already exists")
+
 func (users *Users) SaveNewUser(user *User, db *Database) error {
 	formatError := errorFormatter("users", "saveNewUser")
 
@@ -953,8 +936,29 @@ func (users *Users) SaveNewUser(user *User, db *Database) error {
 	}
 
 	// Insert user with all fields including systems, delays, settings, and Stripe data
-	err := db.Sql.QueryRow(`INSERT INTO "users" ("email", "password", "pin", "pinExpiresAt", "connectionLimit", "verified", "verificationToken", "createdAt", "lastLogin", "firstName", "lastName", "zipCode", "systems", "delay", "systemDelays", "talkgroupDelays", "settings", "stripeCustomerId", "stripeSubscriptionId", "subscriptionStatus", "accountExpiresAt", "userGroupId", "isGroupAdmin", "systemAdmin") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24) RETURNING "userId"`,
-		user.Email, user.Password, user.Pin, user.PinExpiresAt, user.ConnectionLimit, user.Verified, user.VerificationToken, createdAtStr, lastLoginStr, user.FirstName, user.LastName, user.ZipCode, systems, user.Delay, systemDelays, talkgroupDelays, settings, stripeCustomerId, stripeSubscriptionId, subscriptionStatus, user.AccountExpiresAt, user.UserGroupId, user.IsGroupAdmin, user.SystemAdmin).Scan(&userId)
+	insert := func() error {
+		return db.Sql.QueryRow(`INSERT INTO "users" ("email", "password", "pin", "pinExpiresAt", "connectionLimit", "verified", "verificationToken", "createdAt", "lastLogin", "firstName", "lastName", "zipCode", "systems", "delay", "systemDelays", "talkgroupDelays", "settings", "stripeCustomerId", "stripeSubscriptionId", "subscriptionStatus", "accountExpiresAt", "userGroupId", "isGroupAdmin", "systemAdmin") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24) RETURNING "userId"`,
+			user.Email, user.Password, user.Pin, user.PinExpiresAt, user.ConnectionLimit, user.Verified, user.VerificationToken, createdAtStr, lastLoginStr, user.FirstName, user.LastName, user.ZipCode, systems, user.Delay, systemDelays, talkgroupDelays, settings, stripeCustomerId, stripeSubscriptionId, subscriptionStatus, user.AccountExpiresAt, user.UserGroupId, user.IsGroupAdmin, user.SystemAdmin).Scan(&userId)
+	}
+
+	err := insert()
+	for attempt := 0; attempt < 3 && isUniqueViolation(err); attempt++ {
+		var count uint
+		if err := db.Sql.QueryRow(`SELECT COUNT(*) FROM "users" WHERE "email" = $1`, user.Email).Scan(&count); err != nil {
+			return formatError(err, "")
+		}
+		if count > 0 || user.Pin == "" {
+			return errUserExists
+		}
+
+		// The pin was given to another user in the meantime, draw another one
+		pin, pinErr := users.GenerateUniquePin(0)
+		if pinErr != nil {
+			return formatError(pinErr, "")
+		}
+		user.Pin = pin
+		err = insert()
+	}
 	if err != nil {
 		return formatError(err, "")
 	}
@@ -0,0 +1,426 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const userConfigVersion = 1
+
+// UserConfig is the portable export of the alert settings of a single user.
+// Systems and talkgroups are referenced by their refs and keyword lists by their
+// labels, so the export can be imported on another deployment.
+type UserConfig struct {
+	Version      int                     `json:"version"`
+	Preferences  []UserConfigPreference  `json:"preferences"`
+	KeywordLists []UserConfigKeywordList `json:"keywordLists"`
+}
+
+type UserConfigPreference struct {
+	SystemRef     uint     `json:"systemRef"`
+	TalkgroupRef  uint     `json:"talkgroupRef"`
+	AlertEnabled  bool     `json:"alertEnabled"`
+	ToneAlerts    bool     `json:"toneAlerts"`
+	KeywordAlerts bool     `json:"keywordAlerts"`
+	Keywords      []string `json:"keywords"`
+	KeywordLists  []string `json:"keywordLists"` // keyword list labels
+	ToneSetIds    []string `json:"toneSetIds"`
+	Exclusions    []string `json:"exclusions"`
+}
+
+type UserConfigKeywordList struct {
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords"`
+}
+
+type UserConfigImportResult struct {
+	Imported            int      `json:"imported"`
+	Skipped             []string `json:"skipped"`
+	CreatedKeywordLists []string `json:"createdKeywordLists"`
+}
+
+// userAlertPreference is a row of the userAlertPreferences table
+type userAlertPreference struct {
+	SystemId       uint64
+	TalkgroupId    uint64
+	AlertEnabled   bool
+	ToneAlerts     bool
+	KeywordAlerts  bool
+	Keywords       []string
+	KeywordListIds []uint64
+	ToneSetIds     []string
+	Exclusions     []string // nil keeps the exclusions of an existing preference on import
+}
+
+// keywordList is a row of the keywordLists table
+type keywordList struct {
+	Id          uint64
+	Label       string
+	Description string
+	Keywords    []string
+}
+
+// ExportUserConfig returns the alert preferences of a user along with the keyword lists they reference
+func (controller *Controller) ExportUserConfig(userId uint64) (*UserConfig, error) {
+	preferences, err := controller.readUserAlertPreferences(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := controller.readKeywordLists()
+	if err != nil {
+		return nil, err
+	}
+
+	return buildUserConfig(controller.Systems.List, preferences, lists), nil
+}
+
+// ImportUserConfig applies an exported user config to a user. Keyword lists are shared by all users,
+// so missing ones are created only when createKeywordLists is set, that is for an admin, and are
+// skipped otherwise. Preferences for talkgroups that do not exist or that the user cannot access are skipped.
+func (controller *Controller) ImportUserConfig(userId uint64, data []byte, createKeywordLists bool) (*UserConfigImportResult, error) {
+	user := controller.Users.GetUserById(userId)
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userId)
+	}
+
+	config := &UserConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid user config: %v", err)
+	}
+
+	if config.Version == 0 || config.Version > userConfigVersion {
+		return nil, fmt.Errorf("unsupported user config version %d", config.Version)
+	}
+
+	lists, err := controller.readKeywordLists()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserConfigImportResult{
+		Skipped:             []string{},
+		CreatedKeywordLists: []string{},
+	}
+
+	tx, err := controller.Database.Sql.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, missing := range missingKeywordLists(config, lists) {
+		if !createKeywordLists {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("keyword list %s not found", missing.Label))
+			continue
+		}
+
+		missing.Label = SanitizeLabel(missing.Label)
+		missing.Description = SanitizeText(missing.Description, TextSanitizeStrip)
+
+		keywordsJson, _ := json.Marshal(nonNilStrings(missing.Keywords))
+
+		query := fmt.Sprintf(`INSERT INTO "keywordLists" ("label", "description", "keywords", "order", "createdAt") VALUES ('%s', '%s', '%s', 0, %d) RETURNING "keywordListId"`, escapeQuotes(missing.Label), escapeQuotes(missing.Description), escapeQuotes(string(keywordsJson)), time.Now().UnixMilli())
+
+		list := keywordList{Label: missing.Label, Description: missing.Description, Keywords: missing.Keywords}
+		if err := tx.QueryRow(query).Scan(&list.Id); err != nil {
+			return nil, fmt.Errorf("%s in %s", err, query)
+		}
+
+		lists = append(lists, list)
+		result.CreatedKeywordLists = append(result.CreatedKeywordLists, missing.Label)
+	}
+
+	preferences, skipped := controller.resolveUserConfig(user, config, lists)
+	result.Skipped = append(result.Skipped, skipped...)
+
+	for _, preference := range preferences {
+		keywordsJson, _ := json.Marshal(nonNilStrings(preference.Keywords))
+		keywordListIdsJson, _ := json.Marshal(preference.KeywordListIds)
+		toneSetIdsJson, _ := json.Marshal(nonNilStrings(preference.ToneSetIds))
+
+		// exports predating the exclusions leave them as they are
+		var exclusionsJson any
+		if preference.Exclusions != nil {
+			b, _ := json.Marshal(preference.Exclusions)
+			exclusionsJson = string(b)
+		}
+
+		query := fmt.Sprintf(`INSERT INTO "userAlertPreferences" ("userId", "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions") VALUES (%d, %d, %d, %t, %t, %t, $1, $2, $3, COALESCE($4::text, '[]')) ON CONFLICT ("userId", "systemId", "talkgroupId") DO UPDATE SET "alertEnabled" = %t, "toneAlerts" = %t, "keywordAlerts" = %t, "keywords" = $1, "keywordListIds" = $2, "toneSetIds" = $3, "exclusions" = COALESCE($4::text, "userAlertPreferences"."exclusions")`, userId, preference.SystemId, preference.TalkgroupId, preference.AlertEnabled, preference.ToneAlerts, preference.KeywordAlerts, preference.AlertEnabled, preference.ToneAlerts, preference.KeywordAlerts)
+
+		if _, err := tx.Exec(query, string(keywordsJson), string(keywordListIdsJson), string(toneSetIdsJson), exclusionsJson); err != nil {
+			return nil, fmt.Errorf("%s in %s", err, query)
+		}
+
+		result.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (controller *Controller) readUserAlertPreferences(userId uint64) ([]userAlertPreference, error) {
+	preferences := []userAlertPreference{}
+
+	query := fmt.Sprintf(`SELECT "systemId", "talkgroupId", "alertEnabled", "toneAlerts", "keywordAlerts", "keywords", "keywordListIds", "toneSetIds", "exclusions" FROM "userAlertPreferences" WHERE "userId" = %d ORDER BY "userAlertPreferenceId"`, userId)
+
+	rows, err := controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s in %s", err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			exclusionsJson     string
+			keywordsJson       string
+			keywordListIdsJson string
+			preference         userAlertPreference
+			toneSetIdsJson     string
+		)
+
+		if err := rows.Scan(&preference.SystemId, &preference.TalkgroupId, &preference.AlertEnabled, &preference.ToneAlerts, &preference.KeywordAlerts, &keywordsJson, &keywordListIdsJson, &toneSetIdsJson, &exclusionsJson); err != nil {
+			continue
+		}
+
+		json.Unmarshal([]byte(keywordsJson), &preference.Keywords)
+		json.Unmarshal([]byte(keywordListIdsJson), &preference.KeywordListIds)
+		json.Unmarshal([]byte(toneSetIdsJson), &preference.ToneSetIds)
+		json.Unmarshal([]byte(exclusionsJson), &preference.Exclusions)
+
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, rows.Err()
+}
+
+func (controller *Controller) readKeywordLists() ([]keywordList, error) {
+	lists := []keywordList{}
+
+	query := `SELECT "keywordListId", "label", "description", "keywords" FROM "keywordLists" ORDER BY "order" ASC, "createdAt" DESC`
+
+	rows, err := controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s in %s", err, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			keywordsJson string
+			list         keywordList
+		)
+
+		if err := rows.Scan(&list.Id, &list.Label, &list.Description, &keywordsJson); err != nil {
+			continue
+		}
+
+		json.Unmarshal([]byte(keywordsJson), &list.Keywords)
+
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// buildUserConfig converts database rows to a portable user config
+func buildUserConfig(systems []*System, preferences []userAlertPreference, lists []keywordList) *UserConfig {
+	config := &UserConfig{
+		Version:      userConfigVersion,
+		Preferences:  []UserConfigPreference{},
+		KeywordLists: []UserConfigKeywordList{},
+	}
+
+	listsById := map[uint64]keywordList{}
+	for _, list := range lists {
+		listsById[list.Id] = list
+	}
+
+	exported := map[uint64]bool{}
+
+	for _, preference := range preferences {
+		system, talkgroup := findSystemTalkgroup(systems, preference.SystemId, preference.TalkgroupId)
+		if system == nil || talkgroup == nil {
+			continue
+		}
+
+		p := UserConfigPreference{
+			SystemRef:     system.SystemRef,
+			TalkgroupRef:  talkgroup.TalkgroupRef,
+			AlertEnabled:  preference.AlertEnabled,
+			ToneAlerts:    preference.ToneAlerts,
+			KeywordAlerts: preference.KeywordAlerts,
+			Keywords:      nonNilStrings(preference.Keywords),
+			KeywordLists:  []string{},
+			ToneSetIds:    nonNilStrings(preference.ToneSetIds),
+			Exclusions:    nonNilStrings(preference.Exclusions),
+		}
+
+		for _, id := range preference.KeywordListIds {
+			list, ok := listsById[id]
+			if !ok {
+				continue
+			}
+
+			p.KeywordLists = append(p.KeywordLists, list.Label)
+
+			if !exported[id] {
+				exported[id] = true
+				config.KeywordLists = append(config.KeywordLists, UserConfigKeywordList{
+					Label:       list.Label,
+					Description: list.Description,
+					Keywords:    nonNilStrings(list.Keywords),
+				})
+			}
+		}
+
+		config.Preferences = append(config.Preferences, p)
+	}
+
+	return config
+}
+
+// missingKeywordLists returns the keyword lists referenced by a user config which do not exist yet
+func missingKeywordLists(config *UserConfig, lists []keywordList) []UserConfigKeywordList {
+	missing := []UserConfigKeywordList{}
+
+	existing := map[string]bool{}
+	for _, list := range lists {
+		existing[list.Label] = true
+	}
+
+	definitions := map[string]UserConfigKeywordList{}
+	for _, list := range config.KeywordLists {
+		definitions[list.Label] = list
+	}
+
+	for _, preference := range config.Preferences {
+		for _, label := range preference.KeywordLists {
+			if label == "" || existing[label] {
+				continue
+			}
+
+			existing[label] = true
+
+			if list, ok := definitions[label]; ok {
+				missing = append(missing, list)
+			} else {
+				missing = append(missing, UserConfigKeywordList{Label: label, Keywords: []string{}})
+			}
+		}
+	}
+
+	return missing
+}
+
+// resolveUserConfig maps a user config back to database ids, skipping the preferences
+// which cannot be resolved or which target a talkgroup the user has no access to
+func (controller *Controller) resolveUserConfig(user *User, config *UserConfig, lists []keywordList) ([]userAlertPreference, []string) {
+	preferences := []userAlertPreference{}
+	skipped := []string{}
+
+	listsByLabel := map[string]uint64{}
+	for _, list := range lists {
+		if _, ok := listsByLabel[list.Label]; !ok {
+			listsByLabel[list.Label] = list.Id
+		}
+	}
+
+	for _, p := range config.Preferences {
+		system, ok := controller.Systems.GetSystemByRef(p.SystemRef)
+		if !ok {
+			skipped = append(skipped, fmt.Sprintf("system %d not found", p.SystemRef))
+			continue
+		}
+
+		var talkgroup *Talkgroup
+		if system.Talkgroups != nil {
+			talkgroup, ok = system.Talkgroups.GetTalkgroupByRef(p.TalkgroupRef)
+		}
+		if talkgroup == nil {
+			skipped = append(skipped, fmt.Sprintf("talkgroup %d of system %d not found", p.TalkgroupRef, p.SystemRef))
+			continue
+		}
+
+		if !controller.userHasAccess(user, &Call{System: system, Talkgroup: talkgroup}) {
+			skipped = append(skipped, fmt.Sprintf("no access to talkgroup %d of system %d", p.TalkgroupRef, p.SystemRef))
+			continue
+		}
+
+		preference := userAlertPreference{
+			SystemId:       system.Id,
+			TalkgroupId:    talkgroup.Id,
+			AlertEnabled:   p.AlertEnabled,
+			ToneAlerts:     p.ToneAlerts && talkgroup.ToneDetectionEnabled,
+			KeywordAlerts:  p.KeywordAlerts,
+			Keywords:       nonNilStrings(p.Keywords),
+			KeywordListIds: []uint64{},
+			ToneSetIds:     nonNilStrings(p.ToneSetIds),
+		}
+
+		if p.Exclusions != nil {
+			preference.Exclusions = []string{}
+			for _, exclusion := range p.Exclusions {
+				if exclusion = strings.TrimSpace(exclusion); exclusion != "" {
+					preference.Exclusions = append(preference.Exclusions, exclusion)
+				}
+			}
+		}
+
+		for _, label := range p.KeywordLists {
+			if id, ok := listsByLabel[label]; ok {
+				preference.KeywordListIds = append(preference.KeywordListIds, id)
+			}
+		}
+
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, skipped
+}
+
+func findSystemTalkgroup(systems []*System, systemId uint64, talkgroupId uint64) (*System, *Talkgroup) {
+	for _, system := range systems {
+		if system.Id != systemId {
+			continue
+		}
+
+		if system.Talkgroups == nil {
+			return system, nil
+		}
+
+		talkgroup, _ := system.Talkgroups.GetTalkgroupById(talkgroupId)
+
+		return system, talkgroup
+	}
+
+	return nil, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
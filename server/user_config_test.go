@@ -0,0 +1,111 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func newUserConfigController(systemId uint64, talkgroupIds ...uint64) *Controller {
+	talkgroups := NewTalkgroups()
+	for i, id := range talkgroupIds {
+		talkgroups.List = append(talkgroups.List, &Talkgroup{Id: id, TalkgroupRef: uint(100 + i), ToneDetectionEnabled: true})
+	}
+
+	controller := &Controller{Systems: NewSystems(), UserGroups: NewUserGroups()}
+	controller.Systems.List = []*System{{Id: systemId, SystemRef: 10, Talkgroups: talkgroups}}
+
+	return controller
+}
+
+func TestUserConfigRoundTrip(t *testing.T) {
+	// source deployment
+	source := newUserConfigController(1, 11, 12)
+	sourceLists := []keywordList{
+		{Id: 3, Label: "Fire", Description: "fire words", Keywords: []string{"fire", "smoke"}},
+		{Id: 4, Label: "Medical", Keywords: []string{"cpr"}},
+	}
+	preferences := []userAlertPreference{
+		{SystemId: 1, TalkgroupId: 11, AlertEnabled: true, ToneAlerts: true, KeywordAlerts: true, Keywords: []string{"mayday"}, KeywordListIds: []uint64{3, 4}, ToneSetIds: []string{"a1"}, Exclusions: []string{"test"}},
+		{SystemId: 1, TalkgroupId: 12, AlertEnabled: true, KeywordAlerts: true, Keywords: []string{}, KeywordListIds: []uint64{4}, ToneSetIds: []string{}},
+	}
+
+	b, err := json.Marshal(buildUserConfig(source.Systems.List, preferences, sourceLists))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// target deployment with different ids and only the "Medical" list
+	target := newUserConfigController(50, 60, 61)
+	targetLists := []keywordList{{Id: 90, Label: "Medical", Keywords: []string{"cpr"}}}
+
+	config := &UserConfig{}
+	if err := json.Unmarshal(b, config); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := missingKeywordLists(config, targetLists)
+	if len(missing) != 1 || missing[0].Label != "Fire" || !reflect.DeepEqual(missing[0].Keywords, []string{"fire", "smoke"}) {
+		t.Fatalf("expected the Fire list to be created, got %+v", missing)
+	}
+	targetLists = append(targetLists, keywordList{Id: 91, Label: missing[0].Label, Keywords: missing[0].Keywords})
+
+	user := &User{Id: 1}
+	user.loadSystemScopes()
+
+	imported, skipped := target.resolveUserConfig(user, config, targetLists)
+	if len(skipped) != 0 {
+		t.Fatalf("unexpected skipped preferences: %v", skipped)
+	}
+
+	expected := []userAlertPreference{
+		{SystemId: 50, TalkgroupId: 60, AlertEnabled: true, ToneAlerts: true, KeywordAlerts: true, Keywords: []string{"mayday"}, KeywordListIds: []uint64{91, 90}, ToneSetIds: []string{"a1"}, Exclusions: []string{"test"}},
+		{SystemId: 50, TalkgroupId: 61, AlertEnabled: true, KeywordAlerts: true, Keywords: []string{}, KeywordListIds: []uint64{90}, ToneSetIds: []string{}, Exclusions: []string{}},
+	}
+
+	if !reflect.DeepEqual(imported, expected) {
+		t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", imported, expected)
+	}
+}
+
+func TestUserConfigImportChecksAccess(t *testing.T) {
+	controller := newUserConfigController(1, 11, 12)
+
+	config := &UserConfig{
+		Version: userConfigVersion,
+		Preferences: []UserConfigPreference{
+			{SystemRef: 10, TalkgroupRef: 100, AlertEnabled: true},
+			{SystemRef: 10, TalkgroupRef: 101, AlertEnabled: true},
+			{SystemRef: 99, TalkgroupRef: 100, AlertEnabled: true},
+		},
+	}
+
+	user := &User{Id: 1, Systems: `[{"id":10,"talkgroups":[100]}]`}
+	user.loadSystemScopes()
+
+	imported, skipped := controller.resolveUserConfig(user, config, nil)
+	if len(imported) != 1 || imported[0].TalkgroupId != 11 {
+		t.Errorf("expected only talkgroup 100 to be imported, got %+v", imported)
+	}
+	if len(imported) == 1 && imported[0].Exclusions != nil {
+		t.Errorf("expected the exclusions missing from the export to be kept, got %v", imported[0].Exclusions)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected 2 skipped preferences, got %v", skipped)
+	}
+}
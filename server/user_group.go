@@ -18,6 +18,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -27,42 +28,79 @@ import (
 )
 
 type PricingOption struct {
-	PriceId   string `json:"priceId"`   // Stripe Price ID
-	Label     string `json:"label"`     // Display label (e.g., "Monthly", "Yearly")
-	Amount    string `json:"amount"`    // Display amount (e.g., "$10/month", "$100/year")
-	TrialDays int    `json:"trialDays"` // Trial period in days (0 = no trial, 1-30 = trial days)
+	PriceId   string `json:"priceId"`            // Stripe Price ID
+	Label     string `json:"label"`              // Display label (e.g., "Monthly", "Yearly")
+	Amount    string `json:"amount"`             // Display amount (e.g., "$10/month", "$100/year")
+	TrialDays int    `json:"trialDays"`          // Trial period in days (0 = no trial, 1-30 = trial days)
+	CouponId  string `json:"couponId,omitempty"` // Stripe coupon applied to the checkout, unless the user types a coupon code
+}
+
+// PricingOptionUpdate is a pricing option of a group update, keeping the coupon of the option of the
+// same price when the coupon id is not in the request
+type PricingOptionUpdate struct {
+	PricingOption
+	CouponId *string `json:"couponId"`
+}
+
+func (update PricingOptionUpdate) Merge(current []PricingOption) PricingOption {
+	option := update.PricingOption
+
+	if update.CouponId != nil {
+		option.CouponId = strings.TrimSpace(*update.CouponId)
+		return option
+	}
+
+	for _, c := range current {
+		if c.PriceId == option.PriceId {
+			option.CouponId = c.CouponId
+			break
+		}
+	}
+
+	return option
 }
 
 type UserGroup struct {
-	Id                    uint64
-	Name                  string
-	Description           string
-	SystemAccess          string // JSON array of system IDs (legacy) or array of objects with id and talkgroups (new format)
-	Delay                 int
-	SystemDelays          string // JSON map
-	TalkgroupDelays       string // JSON map
-	ConnectionLimit       uint
-	MaxUsers              uint // Maximum number of users allowed in this group (0 = unlimited)
-	BillingEnabled        bool
-	StripePriceId         string // DEPRECATED: Legacy single price ID (kept for backward compatibility)
-	PricingOptions        string // JSON array of PricingOption objects (up to 3)
-	BillingMode           string // "all_users" (each user has own customer ID) or "group_admin" (all admins share one customer ID)
-	CollectSalesTax       bool   // Whether to collect sales tax via Stripe Automatic Tax
-	IsPublicRegistration  bool
-	AllowAddExistingUsers bool // Allow group admins to add existing users from any group
-	CreatedAt             int64
-	systemAccessData      []uint64 // Legacy format: simple array of system IDs
-	systemAccessDataNew   any      // New format: array of objects with id and talkgroups (same format as user systemsData)
-	systemDelaysMap       map[uint64]uint
-	talkgroupDelaysMap    map[string]uint
-	pricingOptionsData    []PricingOption
+	Id                          uint64
+	Name                        string
+	Description                 string
+	SystemAccess                string // JSON array of system IDs (legacy) or array of objects with id and talkgroups (new format), added to the parent access when inheriting
+	SystemAccessRemove          string // JSON, same format as SystemAccess, removed from the parent access when inheriting
+	ParentGroupId               uint64 // group whose system access is inherited, 0 for none
+	Delay                       int
+	SystemDelays                string // JSON map
+	TalkgroupDelays             string // JSON map
+	ConnectionLimit             uint
+	MaxUsers                    uint // Maximum number of users allowed in this group (0 = unlimited)
+	BillingEnabled              bool
+	StripePriceId               string // DEPRECATED: Legacy single price ID (kept for backward compatibility)
+	PricingOptions              string // JSON array of PricingOption objects (up to 3)
+	BillingMode                 string // "all_users" (each user has own customer ID) or "group_admin" (all admins share one customer ID)
+	CollectSalesTax             bool   // Whether to collect sales tax via Stripe Automatic Tax
+	IsPublicRegistration        bool
+	AllowAddExistingUsers       bool   // Allow group admins to add existing users from any group
+	DefaultAlertPreferences     string // JSON array of UserConfigPreference applied to users joining the group
+	CreatedAt                   int64
+	systemAccessData            []uint64 // Legacy format: simple array of system IDs
+	systemAccessDataNew         any      // New format: array of objects with id and talkgroups (same format as user systemsData)
+	systemDelaysMap             map[uint64]uint
+	talkgroupDelaysMap          map[string]uint
+	pricingOptionsData          []PricingOption
+	defaultAlertPreferencesData []UserConfigPreference
+	parent                      *UserGroup
+	removals                    *UserGroup // scopes of SystemAccessRemove, parsed like SystemAccess
 }
 
 type UserGroups struct {
-	mutex  sync.RWMutex
-	groups map[uint64]*UserGroup
+	mutex     sync.RWMutex
+	groups    map[uint64]*UserGroup
+	admission sync.Mutex // serializes the new users joining groups, so that the last slot of a group is taken once
+	auditLog  *AuditLog
 }
 
+// errGroupFull is returned when a user would join a group which reached its maximum number of users
+var errGroupFull = errors.New("group is full")
+
 func NewUserGroups() *UserGroups {
 	return &UserGroups{
 		groups: make(map[uint64]*UserGroup),
@@ -99,6 +137,58 @@ func (ug *UserGroup) loadSystemAccess() {
 	}
 }
 
+func (ug *UserGroup) loadSystemAccessRemove() {
+	ug.removals = nil
+
+	if strings.TrimSpace(ug.SystemAccessRemove) == "" || strings.TrimSpace(ug.SystemAccessRemove) == "[]" {
+		return
+	}
+
+	ug.removals = &UserGroup{Id: ug.Id, SystemAccess: ug.SystemAccessRemove}
+	ug.removals.loadSystemAccess()
+}
+
+// hasAdditions reports whether the group grants access of its own, an empty SystemAccess
+// meaning all systems only for a group not inheriting
+func (ug *UserGroup) hasAdditions() bool {
+	return len(ug.systemAccessData) > 0 || ug.systemAccessDataNew != nil
+}
+
+// removesSystem reports whether the removals take the whole system away, not only some talkgroups
+func (ug *UserGroup) removesSystem(systemId uint64) bool {
+	if ug.removals == nil || !ug.removals.hasAdditions() {
+		return false
+	}
+
+	return ug.removals.hasOwnSystemAccess(systemId) && !ug.removals.restrictsTalkgroups(systemId)
+}
+
+// restrictsTalkgroups reports whether the access to the system is limited to a list of talkgroups
+func (ug *UserGroup) restrictsTalkgroups(systemId uint64) bool {
+	scopes, ok := ug.systemAccessDataNew.([]map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, scope := range scopes {
+		var systemRef uint64
+		switch id := scope["id"].(type) {
+		case float64:
+			systemRef = uint64(id)
+		case string:
+			systemRef, _ = strconv.ParseUint(id, 10, 64)
+		}
+		if systemRef != systemId {
+			continue
+		}
+		if _, ok := scope["talkgroups"].([]interface{}); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (ug *UserGroup) loadSystemDelays() {
 	if strings.TrimSpace(ug.SystemDelays) == "" {
 		ug.systemDelaysMap = make(map[uint64]uint)
@@ -143,11 +233,87 @@ func (ug *UserGroup) loadPricingOptions() {
 	}
 }
 
+func (ug *UserGroup) loadDefaultAlertPreferences() {
+	ug.defaultAlertPreferencesData = []UserConfigPreference{}
+
+	if strings.TrimSpace(ug.DefaultAlertPreferences) == "" {
+		return
+	}
+
+	if err := json.Unmarshal([]byte(ug.DefaultAlertPreferences), &ug.defaultAlertPreferencesData); err != nil {
+		log.Printf("Error parsing default alert preferences for group %d: %v", ug.Id, err)
+		ug.defaultAlertPreferencesData = []UserConfigPreference{}
+	}
+}
+
+func (ug *UserGroup) GetDefaultAlertPreferences() []UserConfigPreference {
+	return ug.defaultAlertPreferencesData
+}
+
+// ValidateDefaultAlertPreferences checks that the default alert preferences template is valid JSON
+// and only references talkgroups the group has access to
+func (ug *UserGroup) ValidateDefaultAlertPreferences() error {
+	if strings.TrimSpace(ug.DefaultAlertPreferences) == "" {
+		return nil
+	}
+
+	var preferences []UserConfigPreference
+	if err := json.Unmarshal([]byte(ug.DefaultAlertPreferences), &preferences); err != nil {
+		return fmt.Errorf("invalid default alert preferences: %v", err)
+	}
+
+	ug.loadSystemAccess()
+	ug.loadSystemAccessRemove()
+
+	for _, p := range preferences {
+		if !ug.HasTalkgroupAccess(uint64(p.SystemRef), p.TalkgroupRef) {
+			return fmt.Errorf("default alert preferences reference talkgroup %d of system %d which the group cannot access", p.TalkgroupRef, p.SystemRef)
+		}
+	}
+
+	return nil
+}
+
 func (ug *UserGroup) GetPricingOptions() []PricingOption {
 	return ug.pricingOptionsData
 }
 
+// HasSystemAccess checks if the group has access to a system, its own or inherited from its parent
 func (ug *UserGroup) HasSystemAccess(systemId uint64) bool {
+	if ug.ParentGroupId == 0 {
+		return ug.hasOwnSystemAccess(systemId)
+	}
+
+	if ug.removesSystem(systemId) {
+		return false
+	}
+
+	if ug.parent != nil && ug.parent.HasSystemAccess(systemId) {
+		return true
+	}
+
+	return ug.hasAdditions() && ug.hasOwnSystemAccess(systemId)
+}
+
+// HasTalkgroupAccess checks if the group has access to a specific talkgroup in a system, its own or
+// inherited from its parent
+func (ug *UserGroup) HasTalkgroupAccess(systemId uint64, talkgroupId uint) bool {
+	if ug.ParentGroupId == 0 {
+		return ug.hasOwnTalkgroupAccess(systemId, talkgroupId)
+	}
+
+	if ug.removesSystem(systemId) || (ug.removals != nil && ug.removals.hasAdditions() && ug.removals.hasOwnTalkgroupAccess(systemId, talkgroupId)) {
+		return false
+	}
+
+	if ug.parent != nil && ug.parent.HasTalkgroupAccess(systemId, talkgroupId) {
+		return true
+	}
+
+	return ug.hasAdditions() && ug.hasOwnTalkgroupAccess(systemId, talkgroupId)
+}
+
+func (ug *UserGroup) hasOwnSystemAccess(systemId uint64) bool {
 	// If using new format, check it
 	if ug.systemAccessDataNew != nil {
 		switch v := ug.systemAccessDataNew.(type) {
@@ -186,10 +352,9 @@ func (ug *UserGroup) HasSystemAccess(systemId uint64) bool {
 	return false
 }
 
-// HasTalkgroupAccess checks if the group has access to a specific talkgroup in a system
-func (ug *UserGroup) HasTalkgroupAccess(systemId uint64, talkgroupId uint) bool {
+func (ug *UserGroup) hasOwnTalkgroupAccess(systemId uint64, talkgroupId uint) bool {
 	// If no system access, deny
-	if !ug.HasSystemAccess(systemId) {
+	if !ug.hasOwnSystemAccess(systemId) {
 		return false
 	}
 
@@ -250,26 +415,19 @@ func (ug *UserGroup) HasTalkgroupAccess(systemId uint64, talkgroupId uint) bool
 	return true
 }
 
+// hasDelays reports whether the group sets any delay, deciding the delays of its users
+func (ug *UserGroup) hasDelays() bool {
+	return ug != nil && (ug.Delay > 0 || len(ug.systemDelaysMap) > 0 || len(ug.talkgroupDelaysMap) > 0)
+}
+
+// EffectiveDelay returns the delay the group sets for the call, or defaultDelay when it sets none
 func (ug *UserGroup) EffectiveDelay(call *Call, defaultDelay uint) uint {
-	if ug == nil || call == nil || call.System == nil || call.Talkgroup == nil {
+	if ug == nil {
 		return defaultDelay
 	}
 
-	if len(ug.talkgroupDelaysMap) > 0 {
-		key := fmt.Sprintf("%d:%d", call.System.SystemRef, call.Talkgroup.TalkgroupRef)
-		if delay, ok := ug.talkgroupDelaysMap[key]; ok && delay > 0 {
-			return delay
-		}
-	}
-
-	if len(ug.systemDelaysMap) > 0 {
-		if delay, ok := ug.systemDelaysMap[uint64(call.System.SystemRef)]; ok && delay > 0 {
-			return delay
-		}
-	}
-
-	if ug.Delay > 0 {
-		return uint(ug.Delay)
+	if delay := delayOverride(ug.talkgroupDelaysMap, ug.systemDelaysMap, ug.Delay, call); delay > 0 {
+		return delay
 	}
 
 	return defaultDelay
@@ -279,7 +437,7 @@ func (ugs *UserGroups) Load(db *Database) error {
 	ugs.mutex.Lock()
 	defer ugs.mutex.Unlock()
 
-	rows, err := db.Sql.Query(`SELECT "userGroupId", "name", "description", "systemAccess", "delay", "systemDelays", "talkgroupDelays", "connectionLimit", "maxUsers", "billingEnabled", "stripePriceId", "pricingOptions", "billingMode", "collectSalesTax", "isPublicRegistration", "allowAddExistingUsers", "createdAt" FROM "userGroups"`)
+	rows, err := db.Sql.Query(`SELECT "userGroupId", "name", "description", "systemAccess", "delay", "systemDelays", "talkgroupDelays", "connectionLimit", "maxUsers", "billingEnabled", "stripePriceId", "pricingOptions", "billingMode", "collectSalesTax", "isPublicRegistration", "allowAddExistingUsers", "defaultAlertPreferences", "parentGroupId", "systemAccessRemove", "createdAt" FROM "userGroups"`)
 	if err != nil {
 		return err
 	}
@@ -320,6 +478,9 @@ func (ugs *UserGroups) Load(db *Database) error {
 			&collectSalesTax,
 			&group.IsPublicRegistration,
 			&allowAddExistingUsers,
+			&group.DefaultAlertPreferences,
+			&group.ParentGroupId,
+			&group.SystemAccessRemove,
 			&createdAt,
 		)
 		if err != nil {
@@ -368,9 +529,11 @@ func (ugs *UserGroups) Load(db *Database) error {
 		}
 
 		group.loadSystemAccess()
+		group.loadSystemAccessRemove()
 		group.loadSystemDelays()
 		group.loadTalkgroupDelays()
 		group.loadPricingOptions()
+		group.loadDefaultAlertPreferences()
 
 		ugs.groups[group.Id] = group
 		loadedFromDb[group.Id] = true
@@ -383,9 +546,56 @@ func (ugs *UserGroups) Load(db *Database) error {
 		}
 	}
 
+	ugs.relink()
+
 	return rows.Err()
 }
 
+// Link resolves the parent of the group, whose system access it inherits. It fails when the
+// parent doesn't exist or when inheriting from it would form a cycle.
+func (ugs *UserGroups) Link(group *UserGroup) error {
+	ugs.mutex.RLock()
+	defer ugs.mutex.RUnlock()
+
+	return ugs.link(group)
+}
+
+func (ugs *UserGroups) link(group *UserGroup) error {
+	group.parent = nil
+
+	if group.ParentGroupId == 0 {
+		return nil
+	}
+
+	parent := ugs.groups[group.ParentGroupId]
+	if parent == nil {
+		return fmt.Errorf("parent group %d not found", group.ParentGroupId)
+	}
+
+	for ancestor, depth := parent, 0; ancestor != nil; ancestor, depth = ugs.groups[ancestor.ParentGroupId], depth+1 {
+		if (group.Id > 0 && ancestor.Id == group.Id) || depth > len(ugs.groups) {
+			return fmt.Errorf("group %q cannot inherit from group %q, the inheritance would form a cycle", group.Name, parent.Name)
+		}
+		if ancestor.ParentGroupId == 0 {
+			break
+		}
+	}
+
+	group.parent = parent
+
+	return nil
+}
+
+// relink resolves the parents of all the groups once one is replaced or removed, a group whose
+// parent is gone or forms a cycle inherits nothing
+func (ugs *UserGroups) relink() {
+	for _, group := range ugs.groups {
+		if err := ugs.link(group); err != nil {
+			log.Printf("user group %d: %v", group.Id, err)
+		}
+	}
+}
+
 func (ugs *UserGroups) Get(id uint64) *UserGroup {
 	ugs.mutex.RLock()
 	defer ugs.mutex.RUnlock()
@@ -430,15 +640,21 @@ func (ugs *UserGroups) Add(group *UserGroup, db *Database) error {
 	}
 
 	group.loadSystemAccess()
+	group.loadSystemAccessRemove()
 	group.loadSystemDelays()
 	group.loadTalkgroupDelays()
 	group.loadPricingOptions()
+	group.loadDefaultAlertPreferences()
+
+	if err := ugs.Link(group); err != nil {
+		return err
+	}
 
 	var userId int64
 	err := db.Sql.QueryRow(
-		`INSERT INTO "userGroups" ("name", "description", "systemAccess", "delay", "systemDelays", "talkgroupDelays", "connectionLimit", "maxUsers", "billingEnabled", "stripePriceId", "pricingOptions", "billingMode", "collectSalesTax", "isPublicRegistration", "allowAddExistingUsers", "createdAt") 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING "userGroupId"`,
-		group.Name, group.Description, group.SystemAccess, group.Delay, group.SystemDelays, group.TalkgroupDelays, group.ConnectionLimit, group.MaxUsers, group.BillingEnabled, group.StripePriceId, group.PricingOptions, group.BillingMode, group.CollectSalesTax, group.IsPublicRegistration, group.AllowAddExistingUsers, group.CreatedAt,
+		`INSERT INTO "userGroups" ("name", "description", "systemAccess", "delay", "systemDelays", "talkgroupDelays", "connectionLimit", "maxUsers", "billingEnabled", "stripePriceId", "pricingOptions", "billingMode", "collectSalesTax", "isPublicRegistration", "allowAddExistingUsers", "defaultAlertPreferences", "parentGroupId", "systemAccessRemove", "createdAt") 
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) RETURNING "userGroupId"`,
+		group.Name, group.Description, group.SystemAccess, group.Delay, group.SystemDelays, group.TalkgroupDelays, group.ConnectionLimit, group.MaxUsers, group.BillingEnabled, group.StripePriceId, group.PricingOptions, group.BillingMode, group.CollectSalesTax, group.IsPublicRegistration, group.AllowAddExistingUsers, group.DefaultAlertPreferences, group.ParentGroupId, group.SystemAccessRemove, group.CreatedAt,
 	).Scan(&userId)
 
 	if err != nil {
@@ -451,18 +667,38 @@ func (ugs *UserGroups) Add(group *UserGroup, db *Database) error {
 	ugs.groups[group.Id] = group
 	ugs.mutex.Unlock()
 
+	settings, billing := userGroupAuditFields(group)
+	for field, value := range billing {
+		settings[field] = value
+	}
+	ugs.auditLog.RecordUserGroup(0, 0, group.Id, userGroupAuditCreate, settings)
+
 	return nil
 }
 
 func (ugs *UserGroups) Update(group *UserGroup, db *Database) error {
 	group.loadSystemAccess()
+	group.loadSystemAccessRemove()
 	group.loadSystemDelays()
 	group.loadTalkgroupDelays()
 	group.loadPricingOptions()
+	group.loadDefaultAlertPreferences()
+
+	if err := ugs.Link(group); err != nil {
+		return err
+	}
+
+	var previous *UserGroup
+	if ugs.auditLog != nil {
+		var err error
+		if previous, err = ugs.auditLog.previousGroup(group.Id, db); err != nil {
+			ugs.auditLog.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("user group audit log: %v", err))
+		}
+	}
 
 	_, err := db.Sql.Exec(
-		`UPDATE "userGroups" SET "name" = $1, "description" = $2, "systemAccess" = $3, "delay" = $4, "systemDelays" = $5, "talkgroupDelays" = $6, "connectionLimit" = $7, "maxUsers" = $8, "billingEnabled" = $9, "stripePriceId" = $10, "pricingOptions" = $11, "billingMode" = $12, "collectSalesTax" = $13, "isPublicRegistration" = $14, "allowAddExistingUsers" = $15 WHERE "userGroupId" = $16`,
-		group.Name, group.Description, group.SystemAccess, group.Delay, group.SystemDelays, group.TalkgroupDelays, group.ConnectionLimit, group.MaxUsers, group.BillingEnabled, group.StripePriceId, group.PricingOptions, group.BillingMode, group.CollectSalesTax, group.IsPublicRegistration, group.AllowAddExistingUsers, group.Id,
+		`UPDATE "userGroups" SET "name" = $1, "description" = $2, "systemAccess" = $3, "delay" = $4, "systemDelays" = $5, "talkgroupDelays" = $6, "connectionLimit" = $7, "maxUsers" = $8, "billingEnabled" = $9, "stripePriceId" = $10, "pricingOptions" = $11, "billingMode" = $12, "collectSalesTax" = $13, "isPublicRegistration" = $14, "allowAddExistingUsers" = $15, "defaultAlertPreferences" = $16, "parentGroupId" = $17, "systemAccessRemove" = $18 WHERE "userGroupId" = $19`,
+		group.Name, group.Description, group.SystemAccess, group.Delay, group.SystemDelays, group.TalkgroupDelays, group.ConnectionLimit, group.MaxUsers, group.BillingEnabled, group.StripePriceId, group.PricingOptions, group.BillingMode, group.CollectSalesTax, group.IsPublicRegistration, group.AllowAddExistingUsers, group.DefaultAlertPreferences, group.ParentGroupId, group.SystemAccessRemove, group.Id,
 	)
 
 	if err != nil {
@@ -471,21 +707,61 @@ func (ugs *UserGroups) Update(group *UserGroup, db *Database) error {
 
 	ugs.mutex.Lock()
 	ugs.groups[group.Id] = group
+	ugs.relink()
 	ugs.mutex.Unlock()
 
+	if previous != nil {
+		ugs.auditLog.recordChanges(previous, group)
+	}
+
 	return nil
 }
 
+// Delete removes a group, its child groups taking its parent as their own
 func (ugs *UserGroups) Delete(id uint64, db *Database) error {
-	_, err := db.Sql.Exec(`DELETE FROM "userGroups" WHERE "userGroupId" = $1`, id)
+	ugs.mutex.RLock()
+	group := ugs.groups[id]
+	ugs.mutex.RUnlock()
+
+	parentId := uint64(0)
+	if group != nil {
+		parentId = group.ParentGroupId
+	}
+
+	tx, err := db.Sql.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE "userGroups" SET "parentGroupId" = $1 WHERE "parentGroupId" = $2`, parentId, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM "userGroups" WHERE "userGroupId" = $1`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	ugs.mutex.Lock()
 	delete(ugs.groups, id)
+	for _, child := range ugs.groups {
+		if child.ParentGroupId == id {
+			child.ParentGroupId = parentId
+		}
+	}
+	ugs.relink()
 	ugs.mutex.Unlock()
 
+	details := map[string]any{}
+	if group != nil {
+		details["name"] = group.Name
+	}
+	ugs.auditLog.RecordUserGroup(0, 0, id, userGroupAuditDelete, details)
+
 	return nil
 }
 
@@ -516,3 +792,32 @@ func (ugs *UserGroups) GetUserCount(groupId uint64, users *Users) uint {
 
 	return count
 }
+
+// groupFullError is the error shown to a user who can't join the group as it is full
+func groupFullError(group *UserGroup) string {
+	return fmt.Sprintf("Group is full, it has reached its maximum of %d users", group.MaxUsers)
+}
+
+// CanAcceptNewUser tells whether the group has room for another user, its maximum number of users being 0 for unlimited
+func (ugs *UserGroups) CanAcceptNewUser(groupId uint64, users *Users) bool {
+	group := ugs.Get(groupId)
+	if group == nil {
+		return false
+	}
+
+	return group.MaxUsers == 0 || ugs.GetUserCount(groupId, users) < group.MaxUsers
+}
+
+// AdmitNewUser runs save, which adds a new user to the group, unless the group is full, in which case errGroupFull
+// is returned. The room left is checked again while the new users are serialized, so that two users registering
+// at once can't both take the last slot.
+func (ugs *UserGroups) AdmitNewUser(groupId uint64, users *Users, save func() error) error {
+	ugs.admission.Lock()
+	defer ugs.admission.Unlock()
+
+	if !ugs.CanAcceptNewUser(groupId, users) {
+		return errGroupFull
+	}
+
+	return save()
+}
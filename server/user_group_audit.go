@@ -0,0 +1,259 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Actions recorded in the user group audit log
+const (
+	userGroupAuditCreate   = "group.create"
+	userGroupAuditUpdate   = "group.update"
+	userGroupAuditBilling  = "group.billing"
+	userGroupAuditDelete   = "group.delete"
+	userGroupAuditTransfer = "member.transfer"
+)
+
+// UserGroupAuditEntry is a change to the membership or the billing of a user group
+type UserGroupAuditEntry struct {
+	Id           uint64          `json:"id"`
+	Timestamp    int64           `json:"timestamp"`    // unix milliseconds
+	ActorUserId  uint64          `json:"actorUserId"`  // 0 for the admin password or an email link, otherwise the group admin
+	TargetUserId uint64          `json:"targetUserId"` // 0 when the change is to the group itself
+	GroupId      uint64          `json:"groupId"`
+	Action       string          `json:"action"`
+	Details      json.RawMessage `json:"details"`
+}
+
+// userGroupAuditChange is the previous and new values of a field in the details of an entry
+type userGroupAuditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// RecordUserGroup writes a change to the membership or the billing of a user group, with its details
+// as json, to the user group table of the audit log so that a group can be reviewed on its own. Being
+// a compliance trail, it is always on, whatever the auditLogEnabled option. A failure is logged rather
+// than returned so that the change being recorded goes through anyway.
+func (auditLog *AuditLog) RecordUserGroup(actorUserId uint64, targetUserId uint64, groupId uint64, action string, details any) {
+	if auditLog == nil {
+		return
+	}
+
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	b, err := json.Marshal(details)
+	if err != nil {
+		auditLog.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("user group audit log: %s group %d: %v", action, groupId, err))
+		return
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "userGroupAuditLog" ("timestamp", "actorUserId", "targetUserId", "groupId", "action", "details") VALUES (%d, %d, %d, %d, $1, $2)`, time.Now().UnixMilli(), actorUserId, targetUserId, groupId)
+	if _, err := auditLog.controller.Database.Sql.Exec(query, action, string(b)); err != nil {
+		auditLog.controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("user group audit log: %s group %d: %v", action, groupId, err))
+	}
+}
+
+// RecordTransfer records a user moving between groups under both of them, fromGroupId being 0
+// when the user had no group, via naming the path of the move
+func (auditLog *AuditLog) RecordTransfer(actorUserId uint64, userId uint64, fromGroupId uint64, toGroupId uint64, via string) {
+	details := map[string]any{"fromGroupId": fromGroupId, "toGroupId": toGroupId, "via": via}
+
+	if fromGroupId > 0 && fromGroupId != toGroupId {
+		auditLog.RecordUserGroup(actorUserId, userId, fromGroupId, userGroupAuditTransfer, details)
+	}
+	auditLog.RecordUserGroup(actorUserId, userId, toGroupId, userGroupAuditTransfer, details)
+}
+
+// recordChanges records the settings and the billing fields that differ between the previous and
+// the updated group, as separate entries
+func (auditLog *AuditLog) recordChanges(previous *UserGroup, group *UserGroup) {
+	previousSettings, previousBilling := userGroupAuditFields(previous)
+	settings, billing := userGroupAuditFields(group)
+
+	if changes := userGroupAuditDiff(previousSettings, settings); len(changes) > 0 {
+		auditLog.RecordUserGroup(0, 0, group.Id, userGroupAuditUpdate, changes)
+	}
+	if changes := userGroupAuditDiff(previousBilling, billing); len(changes) > 0 {
+		auditLog.RecordUserGroup(0, 0, group.Id, userGroupAuditBilling, changes)
+	}
+}
+
+// previousGroup reads the stored fields of a group compared by recordChanges, the cached group
+// being possibly already modified in place by the caller of the update
+func (auditLog *AuditLog) previousGroup(id uint64, db *Database) (*UserGroup, error) {
+	group := &UserGroup{Id: id}
+
+	query := fmt.Sprintf(`SELECT "name", "connectionLimit", COALESCE("maxUsers", 0), "isPublicRegistration", COALESCE("allowAddExistingUsers", false), "parentGroupId", "billingEnabled", COALESCE("billingMode", ''), COALESCE("stripePriceId", ''), COALESCE("pricingOptions", ''), COALESCE("collectSalesTax", false) FROM "userGroups" WHERE "userGroupId" = %d`, id)
+	if err := db.Sql.QueryRow(query).Scan(&group.Name, &group.ConnectionLimit, &group.MaxUsers, &group.IsPublicRegistration, &group.AllowAddExistingUsers, &group.ParentGroupId, &group.BillingEnabled, &group.BillingMode, &group.StripePriceId, &group.PricingOptions, &group.CollectSalesTax); err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+
+	return group, nil
+}
+
+// userGroupAuditFields returns the audited settings and billing fields of a group
+func userGroupAuditFields(group *UserGroup) (map[string]any, map[string]any) {
+	settings := map[string]any{
+		"name":                  group.Name,
+		"maxUsers":              group.MaxUsers,
+		"connectionLimit":       group.ConnectionLimit,
+		"isPublicRegistration":  group.IsPublicRegistration,
+		"allowAddExistingUsers": group.AllowAddExistingUsers,
+		"parentGroupId":         group.ParentGroupId,
+	}
+
+	billing := map[string]any{
+		"billingEnabled":  group.BillingEnabled,
+		"billingMode":     group.BillingMode,
+		"stripePriceId":   group.StripePriceId,
+		"pricingOptions":  group.PricingOptions,
+		"collectSalesTax": group.CollectSalesTax,
+	}
+
+	return settings, billing
+}
+
+// userGroupAuditDiff returns the fields whose value changed, with their previous and new values
+func userGroupAuditDiff(previous map[string]any, current map[string]any) map[string]userGroupAuditChange {
+	changes := map[string]userGroupAuditChange{}
+	for field, value := range current {
+		if previous[field] != value {
+			changes[field] = userGroupAuditChange{From: previous[field], To: value}
+		}
+	}
+	return changes
+}
+
+// GetAuditLog returns the entries of a group, or of every group when groupId is 0, between the from
+// and to unix milliseconds included, either 0 for no bound, most recent first
+func (auditLog *AuditLog) GetAuditLog(groupId uint64, from int64, to int64, limit uint) ([]*UserGroupAuditEntry, error) {
+	where := "WHERE TRUE"
+
+	if groupId > 0 {
+		where += fmt.Sprintf(` AND "groupId" = %d`, groupId)
+	}
+	if from > 0 {
+		where += fmt.Sprintf(` AND "timestamp" >= %d`, from)
+	}
+	if to > 0 {
+		where += fmt.Sprintf(` AND "timestamp" <= %d`, to)
+	}
+
+	if limit == 0 {
+		limit = defaultAuditLogLimit
+	} else if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	query := fmt.Sprintf(`SELECT "userGroupAuditLogId", "timestamp", "actorUserId", "targetUserId", "groupId", "action", "details" FROM "userGroupAuditLog" %s ORDER BY "timestamp" DESC, "userGroupAuditLogId" DESC LIMIT %d`, where, limit)
+	rows, err := auditLog.controller.Database.Sql.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+	defer rows.Close()
+
+	entries := []*UserGroupAuditEntry{}
+	for rows.Next() {
+		var details string
+
+		entry := &UserGroupAuditEntry{}
+		if err := rows.Scan(&entry.Id, &entry.Timestamp, &entry.ActorUserId, &entry.TargetUserId, &entry.GroupId, &entry.Action, &details); err != nil {
+			return nil, err
+		}
+
+		if !json.Valid([]byte(details)) {
+			details = "{}"
+		}
+		entry.Details = json.RawMessage(details)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// UserGroupAuditLogHandler returns the user group audit log entries matching the groupId, from, to
+// and limit query parameters
+func (admin *Admin) UserGroupAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t := admin.GetAuthorization(r)
+	if !admin.ValidateToken(t) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	var (
+		groupId  uint64
+		from, to int64
+		limit    uint64
+	)
+
+	for _, param := range []struct {
+		key   string
+		value any
+	}{{"groupId", &groupId}, {"from", &from}, {"to", &to}, {"limit", &limit}} {
+		s := query.Get(param.key)
+		if s == "" {
+			continue
+		}
+
+		var err error
+		switch v := param.value.(type) {
+		case *uint64:
+			*v, err = strconv.ParseUint(s, 10, 64)
+		case *int64:
+			*v, err = strconv.ParseInt(s, 10, 64)
+			if *v < 0 {
+				err = strconv.ErrRange
+			}
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid " + param.key})
+			return
+		}
+	}
+
+	if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	entries, err := admin.Controller.AuditLog.GetAuditLog(groupId, from, to, uint(limit))
+	if err != nil {
+		admin.Controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("user group audit log: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read the user group audit log"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newUserGroupAuditTestController(t *testing.T) (*Controller, *recordingDriver) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs(), UserGroups: NewUserGroups()}
+	controller.AuditLog = NewAuditLog(controller)
+	controller.UserGroups.auditLog = controller.AuditLog
+
+	return controller, d
+}
+
+// userGroupAuditInserts returns the queries and the arguments of the user group audit log inserts
+func userGroupAuditInserts(d *recordingDriver) (queries []string, args [][]driver.Value) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i, query := range d.queries {
+		if strings.HasPrefix(query, `INSERT INTO "userGroupAuditLog"`) {
+			queries = append(queries, query)
+			args = append(args, d.args[i])
+		}
+	}
+
+	return queries, args
+}
+
+func TestUserGroupAuditLogUpdateBilling(t *testing.T) {
+	controller, d := newUserGroupAuditTestController(t)
+
+	// the stored group, before the update
+	d.rows = [][]driver.Value{{"Volunteers", int64(2), int64(10), false, false, int64(0), false, "", "", "", false}}
+
+	group := &UserGroup{Id: 3, Name: "Volunteers", ConnectionLimit: 2, MaxUsers: 10, BillingEnabled: true, BillingMode: "all_users"}
+	if err := controller.UserGroups.Update(group, controller.Database); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, args := userGroupAuditInserts(d)
+	if len(queries) != 1 {
+		t.Fatalf("expected a single billing entry, got %v", queries)
+	}
+	if !strings.Contains(queries[0], "0, 0, 3, $1, $2)") || args[0][0] != userGroupAuditBilling {
+		t.Errorf("expected a billing entry of the group 3 by the admin, got %s %v", queries[0], args[0])
+	}
+
+	details := map[string]userGroupAuditChange{}
+	if err := json.Unmarshal([]byte(args[0][1].(string)), &details); err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 2 || details["billingEnabled"].From != false || details["billingEnabled"].To != true || details["billingMode"].To != "all_users" {
+		t.Errorf("expected billingEnabled and billingMode changed, got %v", details)
+	}
+}
+
+func TestUserGroupAuditLogUnchanged(t *testing.T) {
+	controller, d := newUserGroupAuditTestController(t)
+
+	d.rows = [][]driver.Value{{"Volunteers", int64(2), int64(10), false, false, int64(0), false, "", "", "", false}}
+
+	group := &UserGroup{Id: 3, Name: "Volunteers", ConnectionLimit: 2, MaxUsers: 10}
+	if err := controller.UserGroups.Update(group, controller.Database); err != nil {
+		t.Fatal(err)
+	}
+
+	if queries, _ := userGroupAuditInserts(d); len(queries) != 0 {
+		t.Errorf("expected nothing recorded for an unchanged group, got %v", queries)
+	}
+}
+
+func TestUserGroupAuditLogAdminAuditLogDisabled(t *testing.T) {
+	controller, d := newUserGroupAuditTestController(t)
+	controller.Options.AuditLogEnabled = false
+
+	controller.AuditLog.RecordTransfer(0, 7, 3, 4, "admin")
+
+	if queries, _ := userGroupAuditInserts(d); len(queries) != 2 {
+		t.Errorf("expected the transfer recorded with the admin audit log disabled, got %v", queries)
+	}
+
+	var auditLog *AuditLog
+	auditLog.RecordTransfer(0, 7, 3, 4, "admin")
+}
+
+func TestUserGroupAuditLogTransfer(t *testing.T) {
+	controller, d := newUserGroupAuditTestController(t)
+
+	controller.AuditLog.RecordTransfer(5, 7, 3, 4, "transfer request")
+	controller.AuditLog.RecordTransfer(0, 8, 0, 4, "admin")
+
+	queries, args := userGroupAuditInserts(d)
+	want := []string{"5, 7, 3, $1, $2)", "5, 7, 4, $1, $2)", "0, 8, 4, $1, $2)"}
+	if len(queries) != len(want) {
+		t.Fatalf("expected %d transfer entries, got %v", len(want), queries)
+	}
+	for i, w := range want {
+		if !strings.Contains(queries[i], w) || args[i][0] != userGroupAuditTransfer {
+			t.Errorf("entry %d: expected %s, got %s %v", i, w, queries[i], args[i])
+		}
+	}
+	if args[0][1] != `{"fromGroupId":3,"toGroupId":4,"via":"transfer request"}` {
+		t.Errorf("expected the groups of the transfer in its details, got %v", args[0][1])
+	}
+}
+
+func TestUserGroupAuditLogGetAuditLog(t *testing.T) {
+	controller, d := newUserGroupAuditTestController(t)
+
+	d.rows = [][]driver.Value{{int64(1), int64(1700000000000), int64(0), int64(7), int64(3), userGroupAuditTransfer, `{"fromGroupId":2}`}}
+
+	entries, err := controller.AuditLog.GetAuditLog(3, 1600000000000, 0, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].TargetUserId != 7 || string(entries[0].Details) != `{"fromGroupId":2}` {
+		t.Errorf("expected the transfer entry, got %v", entries)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	query := d.queries[0]
+	if !strings.Contains(query, `"groupId" = 3`) || !strings.Contains(query, `"timestamp" >= 1600000000000`) || strings.Contains(query, `"timestamp" <=`) || !strings.Contains(query, "LIMIT 1000") {
+		t.Errorf("expected the entries of the group 3 since from, at most 1000, got %s", query)
+	}
+}
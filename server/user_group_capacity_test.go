@@ -0,0 +1,160 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCanAcceptNewUser(t *testing.T) {
+	ugs := NewUserGroups()
+	ugs.groups[1] = &UserGroup{Id: 1, MaxUsers: 2}
+	ugs.groups[2] = &UserGroup{Id: 2}
+
+	users := NewUsers()
+	users.users[10] = &User{Id: 10, UserGroupId: 1}
+
+	if !ugs.CanAcceptNewUser(1, users) {
+		t.Error("expected room left in the group")
+	}
+
+	users.users[11] = &User{Id: 11, UserGroupId: 1}
+	if ugs.CanAcceptNewUser(1, users) {
+		t.Error("expected the full group to refuse another user")
+	}
+
+	if !ugs.CanAcceptNewUser(2, users) {
+		t.Error("expected a group without maximum to accept users")
+	}
+
+	if ugs.CanAcceptNewUser(3, users) {
+		t.Error("expected an unknown group to refuse users")
+	}
+}
+
+func TestAdmitNewUserLastSlot(t *testing.T) {
+	ugs := NewUserGroups()
+	ugs.groups[1] = &UserGroup{Id: 1, MaxUsers: 2}
+
+	users := NewUsers()
+	users.users[10] = &User{Id: 10, UserGroupId: 1}
+
+	var (
+		admitted = 0
+		full     = 0
+		mutex    = sync.Mutex{}
+		wg       = sync.WaitGroup{}
+	)
+
+	// users registering at once, all of them seeing the last slot free before saving
+	for i := uint64(0); i < 10; i++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+
+			err := ugs.AdmitNewUser(1, users, func() error {
+				users.mutex.Lock()
+				users.users[id] = &User{Id: id, UserGroupId: 1}
+				users.mutex.Unlock()
+				return nil
+			})
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if errors.Is(err, errGroupFull) {
+				full++
+			} else if err == nil {
+				admitted++
+			}
+		}(100 + i)
+	}
+	wg.Wait()
+
+	if admitted != 1 || full != 9 || ugs.GetUserCount(1, users) != 2 {
+		t.Errorf("expected the last slot taken once, got %d admitted, %d refused, %d users", admitted, full, ugs.GetUserCount(1, users))
+	}
+}
+
+func TestGroupAdminAddUserRacingRegistration(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	d.rows = [][]driver.Value{{int64(30)}} // the id of the registered user
+
+	// the registration is saving its user when the group admin adds another one
+	var (
+		saving  = make(chan struct{})
+		added   = make(chan struct{})
+		pending = sync.Once{}
+	)
+	d.onQuery = func(query string) {
+		if strings.HasPrefix(query, `INSERT INTO "users"`) {
+			pending.Do(func() {
+				close(saving)
+				select {
+				case <-added:
+				case <-time.After(100 * time.Millisecond):
+				}
+			})
+		}
+	}
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs(), Users: NewUsers(), UserGroups: NewUserGroups()}
+	controller.Options.UserRegistrationEnabled = true
+	controller.Options.PublicRegistrationEnabled = true
+	controller.UserGroups.groups[1] = &UserGroup{Id: 1, Name: "Volunteers", MaxUsers: 2, IsPublicRegistration: true}
+
+	// the group admin takes the first slot, the user to add has no group yet
+	admin := &User{Id: 10, Email: "admin@example.com", Pin: "ADMIN-PIN", UserGroupId: 1, IsGroupAdmin: true}
+	existing := &User{Id: 20, Email: "jane@example.com", Pin: "JANE-PIN"}
+	for _, user := range []*User{admin, existing} {
+		controller.Users.users[user.Id] = user
+		controller.Users.pins[user.Pin] = user
+	}
+
+	api := &Api{Controller: controller}
+
+	registered := make(chan int)
+	go func() {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(`{"email":"john@example.com","password":"Correct-Horse-42","firstName":"John","lastName":"Doe","zipCode":"12345"}`))
+		w := httptest.NewRecorder()
+		api.UserRegisterHandler(w, r)
+		registered <- w.Code
+	}()
+
+	<-saving
+
+	r := httptest.NewRequest(http.MethodPost, "/api/group-admin/users", strings.NewReader(`{"email":"jane@example.com","firstName":"Jane","lastName":"Doe","zipCode":"12345"}`))
+	r.Header.Set("Authorization", "Bearer ADMIN-PIN")
+	w := httptest.NewRecorder()
+	api.GroupAdminAddUserHandler(w, r)
+	close(added)
+
+	if code := <-registered; code != http.StatusCreated {
+		t.Errorf("expected the registration to take the last slot, got %d", code)
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected the group admin add refused, got %d", w.Code)
+	}
+	if count := controller.UserGroups.GetUserCount(1, controller.Users); count != 2 {
+		t.Errorf("expected the group kept to its maximum of 2 users, got %d", count)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func newInheritanceGroup(ugs *UserGroups, id uint64, parentId uint64, systemAccess string, systemAccessRemove string) *UserGroup {
+	group := &UserGroup{Id: id, ParentGroupId: parentId, SystemAccess: systemAccess, SystemAccessRemove: systemAccessRemove}
+	group.loadSystemAccess()
+	group.loadSystemAccessRemove()
+	ugs.groups[id] = group
+	return group
+}
+
+func TestUserGroupInheritsParentAccess(t *testing.T) {
+	ugs := NewUserGroups()
+	newInheritanceGroup(ugs, 1, 0, `[{"id":1,"talkgroups":[10,11]},{"id":2,"talkgroups":"*"}]`, "")
+	child := newInheritanceGroup(ugs, 2, 1, "", "")
+	ugs.relink()
+
+	if !child.HasSystemAccess(1) || !child.HasSystemAccess(2) {
+		t.Error("child should inherit the systems of its parent")
+	}
+	if child.HasSystemAccess(3) {
+		t.Error("an empty child scope should not grant all systems")
+	}
+	if !child.HasTalkgroupAccess(1, 10) || child.HasTalkgroupAccess(1, 12) {
+		t.Error("child should inherit the talkgroups of its parent")
+	}
+
+	legacy := NewUserGroups()
+	newInheritanceGroup(legacy, 1, 0, "[1,2]", "")
+	child = newInheritanceGroup(legacy, 2, 1, "", "")
+	legacy.relink()
+
+	if !child.HasTalkgroupAccess(2, 99) || child.HasSystemAccess(3) {
+		t.Error("child should inherit a legacy parent scope")
+	}
+}
+
+func TestUserGroupInheritanceOverrides(t *testing.T) {
+	ugs := NewUserGroups()
+	newInheritanceGroup(ugs, 1, 0, `[{"id":1,"talkgroups":"*"},{"id":2,"talkgroups":"*"}]`, "")
+	child := newInheritanceGroup(ugs, 2, 1, `[{"id":3,"talkgroups":[30]}]`, `[{"id":2},{"id":1,"talkgroups":[10]}]`)
+	grandchild := newInheritanceGroup(ugs, 3, 2, "", "")
+	ugs.relink()
+
+	for _, group := range []*UserGroup{child, grandchild} {
+		if !group.HasTalkgroupAccess(3, 30) || group.HasTalkgroupAccess(3, 31) {
+			t.Errorf("group %d should get the talkgroup added by the child", group.Id)
+		}
+		if group.HasSystemAccess(2) || group.HasTalkgroupAccess(2, 20) {
+			t.Errorf("group %d should lose the system removed by the child", group.Id)
+		}
+		if !group.HasSystemAccess(1) || group.HasTalkgroupAccess(1, 10) || !group.HasTalkgroupAccess(1, 11) {
+			t.Errorf("group %d should only lose the talkgroup removed by the child", group.Id)
+		}
+	}
+}
+
+func TestUserGroupInheritanceMissingParent(t *testing.T) {
+	ugs := NewUserGroups()
+	child := newInheritanceGroup(ugs, 2, 1, "", "")
+
+	if err := ugs.Link(child); err == nil {
+		t.Error("linking to a missing parent should fail")
+	}
+	if child.HasSystemAccess(1) {
+		t.Error("a group whose parent is missing should inherit nothing")
+	}
+}
+
+func TestUserGroupInheritanceCycle(t *testing.T) {
+	ugs := NewUserGroups()
+	a := newInheritanceGroup(ugs, 1, 0, "[1]", "")
+	newInheritanceGroup(ugs, 2, 1, "", "")
+	newInheritanceGroup(ugs, 3, 2, "", "")
+
+	a.ParentGroupId = 3
+	if err := ugs.Link(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+
+	a.ParentGroupId = 1
+	if err := ugs.Link(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error for a group inheriting from itself, got %v", err)
+	}
+
+	a.ParentGroupId = 0
+	if err := ugs.Link(a); err != nil {
+		t.Errorf("unexpected error, %v", err)
+	}
+
+	candidate := &UserGroup{Name: "new", ParentGroupId: 3}
+	if err := ugs.Link(candidate); err != nil {
+		t.Errorf("a new group should inherit from an existing chain, got %v", err)
+	}
+}
+
+func TestUserGroupDeleteReparentsChildren(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	ugs := NewUserGroups()
+	newInheritanceGroup(ugs, 1, 0, `[{"id":1,"talkgroups":"*"}]`, "")
+	newInheritanceGroup(ugs, 2, 1, "", `[{"id":1,"talkgroups":[10]}]`)
+	grandchild := newInheritanceGroup(ugs, 3, 2, "", "")
+	ugs.relink()
+
+	if err := ugs.Delete(2, db); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.queries) != 2 || !strings.Contains(d.queries[0], `SET "parentGroupId" = $1 WHERE "parentGroupId" = $2`) || d.args[0][0] != int64(1) || d.args[0][1] != int64(2) {
+		t.Errorf("expected the children to be reparented before the delete, got %v %v", d.queries, d.args)
+	}
+	if grandchild.ParentGroupId != 1 || !grandchild.HasTalkgroupAccess(1, 10) {
+		t.Error("expected the grandchild to inherit from the parent of the deleted group")
+	}
+}
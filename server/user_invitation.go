@@ -0,0 +1,57 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// UserInvitation is an emailed invitation to register into a group
+type UserInvitation struct {
+	Id          uint64
+	Email       string
+	Code        string
+	UserGroupId uint64
+	Status      string
+	ExpiresAt   int64
+}
+
+// GetUserInvitation returns the invitation of the code, nil when there is none
+func GetUserInvitation(code string, db *Database) (*UserInvitation, error) {
+	invitation := &UserInvitation{Code: code}
+
+	query := `SELECT "userInvitationId", "email", "userGroupId", "status", "expiresAt" FROM "userInvitations" WHERE "code" = $1`
+	err := db.Sql.QueryRow(query, code).Scan(&invitation.Id, &invitation.Email, &invitation.UserGroupId, &invitation.Status, &invitation.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, query)
+	}
+
+	return invitation, nil
+}
+
+// Link returns the link of the invitation, as emailed to the invited user
+func (invitation *UserInvitation) Link(baseURL string) string {
+	return registrationBaseURL(baseURL) + "/?invite=" + url.QueryEscape(invitation.Code)
+}
+
+// QRCode returns a PNG of the link of the invitation
+func (invitation *UserInvitation) QRCode(baseURL string) ([]byte, error) {
+	return registrationQRCode(invitation.Link(baseURL))
+}
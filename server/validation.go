@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -63,6 +64,53 @@ func NormalizeEmail(email string) string {
 	return strings.ToLower(strings.TrimSpace(email))
 }
 
+// zip code formats of the zipCodeFormat option
+const (
+	ZipCodeFormatAny = "any" // a postal code of any country
+	ZipCodeFormatUS  = "us"  // 12345 or 12345-6789
+	ZipCodeFormatCA  = "ca"  // A1A 1A1
+)
+
+var (
+	zipCodeAnyRegex = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,8}[A-Z0-9]$`)
+	zipCodeUSRegex  = regexp.MustCompile(`^(\d{5})(?:-?(\d{4}))?$`)
+	zipCodeCARegex  = regexp.MustCompile(`^([A-Z]\d[A-Z]) ?(\d[A-Z]\d)$`)
+)
+
+// NormalizeZipCode trims, upper cases and collapses the spaces of a zip code, then checks it against the
+// format, writing US and Canadian codes the usual way. An empty zip code is left to the required checks.
+func NormalizeZipCode(zipCode string, format string) (string, error) {
+	zipCode = strings.ToUpper(strings.Join(strings.Fields(zipCode), " "))
+	if zipCode == "" {
+		return "", nil
+	}
+
+	switch format {
+	case ZipCodeFormatUS:
+		m := zipCodeUSRegex.FindStringSubmatch(zipCode)
+		if m == nil {
+			return "", fmt.Errorf("invalid ZIP code %q, expected 12345 or 12345-6789", zipCode)
+		}
+		if m[2] != "" {
+			return m[1] + "-" + m[2], nil
+		}
+		return m[1], nil
+
+	case ZipCodeFormatCA:
+		m := zipCodeCARegex.FindStringSubmatch(zipCode)
+		if m == nil {
+			return "", fmt.Errorf("invalid postal code %q, expected A1A 1A1", zipCode)
+		}
+		return m[1] + " " + m[2], nil
+
+	default:
+		if !zipCodeAnyRegex.MatchString(zipCode) {
+			return "", fmt.Errorf("invalid ZIP code %q", zipCode)
+		}
+		return zipCode, nil
+	}
+}
+
 // PasswordStrength represents password strength requirements
 type PasswordStrength struct {
 	MinLength      int
@@ -149,3 +197,47 @@ func ValidatePassword(password string) error {
 	return ValidatePasswordStrength(password, DefaultPasswordStrength())
 }
 
+// Text sanitization modes
+const (
+	TextSanitizeStrip   = "strip"   // drop invalid UTF-8 and control characters
+	TextSanitizeReplace = "replace" // replace them with U+FFFD
+)
+
+// SanitizeText removes invalid UTF-8 sequences and control characters which break
+// JSON responses and CSV exports, legitimate Unicode is preserved.
+// Tabs and line breaks are turned into spaces whatever the mode.
+func SanitizeText(s string, mode string) string {
+	if utf8.ValidString(s) && !strings.ContainsFunc(s, unicode.IsControl) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			if mode == TextSanitizeReplace {
+				b.WriteRune(utf8.RuneError)
+			}
+		case r == '\t' || r == '\n' || r == '\r':
+			b.WriteByte(' ')
+		case unicode.IsControl(r):
+			if mode == TextSanitizeReplace {
+				b.WriteRune(utf8.RuneError)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// SanitizeLabel sanitizes a user supplied label before it is saved
+func SanitizeLabel(label string) string {
+	return strings.TrimSpace(SanitizeText(label, TextSanitizeStrip))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		mode string
+		want string
+	}{
+		{"ENGINE 5 RESPONDING", TextSanitizeStrip, "ENGINE 5 RESPONDING"},
+		{"café 消防 \U0001F692", TextSanitizeStrip, "café 消防 \U0001F692"},
+		{"bad\xff\xfebytes", TextSanitizeStrip, "badbytes"},
+		{"bad\xffbytes", TextSanitizeReplace, "bad�bytes"},
+		{"null\x00and\x1bescape", TextSanitizeStrip, "nullandescape"},
+		{"null\x00byte", TextSanitizeReplace, "null�byte"},
+		{"line\nbreak\ttab", TextSanitizeStrip, "line break tab"},
+		{"c1\u0085control", TextSanitizeStrip, "c1control"},
+		{"truncated \xe6\xb6", TextSanitizeStrip, "truncated "},
+	}
+
+	for _, test := range tests {
+		got := SanitizeText(test.in, test.mode)
+		if got != test.want {
+			t.Errorf("SanitizeText(%q, %s) = %q, want %q", test.in, test.mode, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("SanitizeText(%q, %s) returned invalid UTF-8", test.in, test.mode)
+		}
+	}
+}
+
+func TestSanitizeLabelRoundTrip(t *testing.T) {
+	talkgroup := &Talkgroup{Label: " Fire\x00 Dispatch\xc3\x28 ", Name: "Fire Éast\r\n"}
+
+	talkgroup.Label = SanitizeLabel(talkgroup.Label)
+	talkgroup.Name = SanitizeLabel(talkgroup.Name)
+
+	b, err := json.Marshal(talkgroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := map[string]any{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded["label"] != "Fire Dispatch(" || decoded["name"] != "Fire Éast" {
+		t.Errorf("unexpected round trip: %s", b)
+	}
+}
+
+func TestNormalizeZipCode(t *testing.T) {
+	tests := []struct {
+		in     string
+		format string
+		want   string
+		valid  bool
+	}{
+		{" 12345 ", ZipCodeFormatUS, "12345", true},
+		{"12345-6789", ZipCodeFormatUS, "12345-6789", true},
+		{"123456789", ZipCodeFormatUS, "12345-6789", true},
+		{"1234", ZipCodeFormatUS, "", false},
+		{"12345-67", ZipCodeFormatUS, "", false},
+		{"ABCDE", ZipCodeFormatUS, "", false},
+		{"k1a0b6", ZipCodeFormatCA, "K1A 0B6", true},
+		{"h3z  2y7", ZipCodeFormatCA, "H3Z 2Y7", true},
+		{"12345", ZipCodeFormatCA, "", false},
+		{"sw1a  1aa", ZipCodeFormatAny, "SW1A 1AA", true},
+		{"75008", ZipCodeFormatAny, "75008", true},
+		{"N/A", ZipCodeFormatAny, "", false},
+		{"<script>", ZipCodeFormatAny, "", false},
+		{"12345678901", ZipCodeFormatAny, "", false},
+		{"  ", ZipCodeFormatUS, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := NormalizeZipCode(test.in, test.format)
+		if (err == nil) != test.valid {
+			t.Errorf("NormalizeZipCode(%q, %s) error = %v, want valid %t", test.in, test.format, err, test.valid)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("NormalizeZipCode(%q, %s) = %q, want %q", test.in, test.format, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VocabularyProfile is a named set of phrases passed to the transcription provider as
+// biasing hints for the calls of the systems it is assigned to, the keywords of the
+// keyword lists it references are added to its phrases
+type VocabularyProfile struct {
+	Id             uint64   `json:"id"`
+	Name           string   `json:"name"`
+	Phrases        []string `json:"phrases"`
+	KeywordListIds []uint64 `json:"keywordListIds"`
+	IsDefault      bool     `json:"isDefault"`
+}
+
+type VocabularyProfiles struct {
+	mutex        sync.RWMutex
+	profiles     map[uint64]*VocabularyProfile
+	keywordLists map[uint64][]string
+}
+
+func NewVocabularyProfiles() *VocabularyProfiles {
+	return &VocabularyProfiles{
+		profiles:     make(map[uint64]*VocabularyProfile),
+		keywordLists: make(map[uint64][]string),
+	}
+}
+
+// normalizePhrases trims, sanitizes and dedups phrases, keeping their order
+func normalizePhrases(phrases []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for _, phrase := range phrases {
+		phrase = SanitizeLabel(phrase)
+		if phrase == "" || seen[strings.ToLower(phrase)] {
+			continue
+		}
+		seen[strings.ToLower(phrase)] = true
+		normalized = append(normalized, phrase)
+	}
+
+	return normalized
+}
+
+// normalizeKeywordListIds drops zero and repeated ids, keeping their order
+func normalizeKeywordListIds(ids []uint64) []uint64 {
+	normalized := []uint64{}
+	seen := map[uint64]bool{}
+
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+
+	return normalized
+}
+
+func (vps *VocabularyProfiles) Load(db *Database) error {
+	rows, err := db.Sql.Query(`SELECT "vocabularyProfileId", "name", "phrases", "keywordListIds", "isDefault" FROM "vocabularyProfiles"`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	profiles := make(map[uint64]*VocabularyProfile)
+
+	for rows.Next() {
+		var phrases, keywordListIds string
+
+		profile := &VocabularyProfile{}
+		if err := rows.Scan(&profile.Id, &profile.Name, &phrases, &keywordListIds, &profile.IsDefault); err != nil {
+			log.Printf("Error loading vocabulary profile: %v", err)
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(phrases), &profile.Phrases); err != nil {
+			log.Printf("Error parsing phrases of vocabulary profile %d: %v", profile.Id, err)
+		}
+		profile.Phrases = nonNilStrings(profile.Phrases)
+
+		if err := json.Unmarshal([]byte(keywordListIds), &profile.KeywordListIds); err != nil {
+			log.Printf("Error parsing keyword lists of vocabulary profile %d: %v", profile.Id, err)
+		}
+		profile.KeywordListIds = normalizeKeywordListIds(profile.KeywordListIds)
+
+		profiles[profile.Id] = profile
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	vps.mutex.Lock()
+	vps.profiles = profiles
+	vps.mutex.Unlock()
+
+	return vps.LoadKeywordLists(db)
+}
+
+// LoadKeywordLists caches the keywords of the keyword lists, it is called again whenever a list changes
+func (vps *VocabularyProfiles) LoadKeywordLists(db *Database) error {
+	rows, err := db.Sql.Query(`SELECT "keywordListId", "keywords" FROM "keywordLists"`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keywordLists := make(map[uint64][]string)
+
+	for rows.Next() {
+		var (
+			id       uint64
+			keywords string
+			list     []string
+		)
+
+		if err := rows.Scan(&id, &keywords); err != nil {
+			log.Printf("Error loading keyword list: %v", err)
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(keywords), &list); err != nil {
+			log.Printf("Error parsing keywords of keyword list %d: %v", id, err)
+		}
+
+		// regex keywords are no phrases to hint the transcription with
+		phrases := []string{}
+		for _, keyword := range list {
+			if !isRegexKeyword(keyword) {
+				phrases = append(phrases, keyword)
+			}
+		}
+
+		keywordLists[id] = phrases
+	}
+
+	vps.mutex.Lock()
+	vps.keywordLists = keywordLists
+	vps.mutex.Unlock()
+
+	return rows.Err()
+}
+
+func (vps *VocabularyProfiles) Get(id uint64) *VocabularyProfile {
+	vps.mutex.RLock()
+	defer vps.mutex.RUnlock()
+	return vps.profiles[id]
+}
+
+func (vps *VocabularyProfiles) GetAll() []*VocabularyProfile {
+	vps.mutex.RLock()
+	defer vps.mutex.RUnlock()
+
+	profiles := make([]*VocabularyProfile, 0, len(vps.profiles))
+	for _, profile := range vps.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Id < profiles[j].Id
+	})
+
+	return profiles
+}
+
+// ForSystem returns the profile assigned to the system, or the default profile
+func (vps *VocabularyProfiles) ForSystem(system *System) *VocabularyProfile {
+	if vps == nil {
+		return nil
+	}
+
+	vps.mutex.RLock()
+	defer vps.mutex.RUnlock()
+
+	if system != nil && system.VocabularyProfileId > 0 {
+		if profile, ok := vps.profiles[system.VocabularyProfileId]; ok {
+			return profile
+		}
+	}
+
+	var fallback *VocabularyProfile
+	for _, profile := range vps.profiles {
+		if profile.IsDefault && (fallback == nil || profile.Id < fallback.Id) {
+			fallback = profile
+		}
+	}
+
+	return fallback
+}
+
+// PhraseHints returns the phrases of the profile of the system followed by the keywords of the
+// keyword lists it references, lists that no longer exist are skipped
+func (vps *VocabularyProfiles) PhraseHints(system *System) []string {
+	profile := vps.ForSystem(system)
+	if profile == nil {
+		return nil
+	}
+
+	vps.mutex.RLock()
+	defer vps.mutex.RUnlock()
+
+	if len(profile.KeywordListIds) == 0 {
+		return profile.Phrases
+	}
+
+	phrases := append([]string{}, profile.Phrases...)
+	for _, id := range profile.KeywordListIds {
+		phrases = append(phrases, vps.keywordLists[id]...)
+	}
+
+	return normalizePhrases(phrases)
+}
+
+func (vps *VocabularyProfiles) Add(profile *VocabularyProfile, db *Database) error {
+	profile.Name = SanitizeLabel(profile.Name)
+	profile.Phrases = normalizePhrases(profile.Phrases)
+	profile.KeywordListIds = normalizeKeywordListIds(profile.KeywordListIds)
+
+	if profile.Name == "" {
+		return errors.New("name is required")
+	}
+
+	phrases, _ := json.Marshal(profile.Phrases)
+	keywordListIds, _ := json.Marshal(profile.KeywordListIds)
+
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if profile.IsDefault {
+		if _, err := tx.Exec(`UPDATE "vocabularyProfiles" SET "isDefault" = false`); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.QueryRow(`INSERT INTO "vocabularyProfiles" ("name", "phrases", "keywordListIds", "isDefault") VALUES ($1, $2, $3, $4) RETURNING "vocabularyProfileId"`, profile.Name, string(phrases), string(keywordListIds), profile.IsDefault).Scan(&profile.Id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vps.mutex.Lock()
+	vps.setLocked(profile)
+	vps.mutex.Unlock()
+
+	return nil
+}
+
+func (vps *VocabularyProfiles) Update(profile *VocabularyProfile, db *Database) error {
+	profile.Name = SanitizeLabel(profile.Name)
+	profile.Phrases = normalizePhrases(profile.Phrases)
+	profile.KeywordListIds = normalizeKeywordListIds(profile.KeywordListIds)
+
+	if profile.Name == "" {
+		return errors.New("name is required")
+	}
+
+	phrases, _ := json.Marshal(profile.Phrases)
+	keywordListIds, _ := json.Marshal(profile.KeywordListIds)
+
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if profile.IsDefault {
+		if _, err := tx.Exec(`UPDATE "vocabularyProfiles" SET "isDefault" = false WHERE "vocabularyProfileId" <> $1`, profile.Id); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`UPDATE "vocabularyProfiles" SET "name" = $1, "phrases" = $2, "keywordListIds" = $3, "isDefault" = $4 WHERE "vocabularyProfileId" = $5`, profile.Name, string(phrases), string(keywordListIds), profile.IsDefault, profile.Id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("vocabulary profile not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vps.mutex.Lock()
+	vps.setLocked(profile)
+	vps.mutex.Unlock()
+
+	return nil
+}
+
+// Delete removes a profile, the systems using it fall back to the default profile
+func (vps *VocabularyProfiles) Delete(id uint64, db *Database) error {
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE "systems" SET "vocabularyProfileId" = 0 WHERE "vocabularyProfileId" = $1`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM "vocabularyProfiles" WHERE "vocabularyProfileId" = $1`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vps.mutex.Lock()
+	delete(vps.profiles, id)
+	vps.mutex.Unlock()
+
+	return nil
+}
+
+func (vps *VocabularyProfiles) setLocked(profile *VocabularyProfile) {
+	if profile.IsDefault {
+		for _, p := range vps.profiles {
+			p.IsDefault = false
+		}
+	}
+	vps.profiles[profile.Id] = profile
+}
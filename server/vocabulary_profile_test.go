@@ -0,0 +1,155 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func newVocabularyProfilesQueue() *TranscriptionQueue {
+	controller := &Controller{Options: NewOptions(), VocabularyProfiles: NewVocabularyProfiles()}
+	controller.Options.TranscriptionConfig.Prompt = "Radio traffic."
+
+	controller.VocabularyProfiles.profiles = map[uint64]*VocabularyProfile{
+		1: {Id: 1, Name: "General", Phrases: []string{"copy", "en route"}, IsDefault: true},
+		2: {Id: 2, Name: "Fire", Phrases: []string{"ladder", "mayday"}},
+		3: {Id: 3, Name: "EMS", Phrases: []string{"medic", "cardiac arrest"}},
+	}
+
+	return &TranscriptionQueue{controller: controller}
+}
+
+func TestVocabularyProfileForSystem(t *testing.T) {
+	queue := newVocabularyProfilesQueue()
+	profiles := queue.controller.VocabularyProfiles
+
+	cases := []struct {
+		name    string
+		system  *System
+		profile uint64
+	}{
+		{name: "assigned profile", system: &System{Id: 1, VocabularyProfileId: 3}, profile: 3},
+		{name: "unassigned falls back to default", system: &System{Id: 2}, profile: 1},
+		{name: "deleted profile falls back to default", system: &System{Id: 3, VocabularyProfileId: 9}, profile: 1},
+		{name: "no system uses default", system: nil, profile: 1},
+	}
+
+	for _, c := range cases {
+		if profile := profiles.ForSystem(c.system); profile == nil || profile.Id != c.profile {
+			t.Errorf("%s: expected profile %d, got %+v", c.name, c.profile, profile)
+		}
+	}
+
+	profiles.profiles[1].IsDefault = false
+	if profile := profiles.ForSystem(&System{Id: 2}); profile != nil {
+		t.Errorf("expected no profile without a default, got %+v", profile)
+	}
+}
+
+func TestVocabularyProfilePhrasesReachProvider(t *testing.T) {
+	queue := newVocabularyProfilesQueue()
+
+	call := &Call{System: &System{Id: 1, VocabularyProfileId: 2}}
+	options := queue.transcriptionOptions(call, "audio/mp4")
+
+	if !reflect.DeepEqual(options.Phrases, []string{"ladder", "mayday"}) {
+		t.Fatalf("expected the fire phrases, got %v", options.Phrases)
+	}
+
+	if prompt := whisperPrompt(options); prompt != "Radio traffic. ladder, mayday" {
+		t.Errorf("unexpected whisper prompt %q", prompt)
+	}
+
+	google := &GoogleTranscription{}
+	config := google.buildRequestBody("", 16000, "en-US", options)["config"].(map[string]interface{})
+	contexts, ok := config["speechContexts"].([]map[string]interface{})
+	if !ok || len(contexts) != 1 || !reflect.DeepEqual(contexts[0]["phrases"], options.Phrases) {
+		t.Errorf("expected the phrases in the google speech contexts, got %v", config["speechContexts"])
+	}
+
+	body := assemblyAITranscriptBody("https://example.com/audio", options)
+	if !reflect.DeepEqual(body["word_boost"], options.Phrases) {
+		t.Errorf("expected the phrases in the assemblyai word boost, got %v", body["word_boost"])
+	}
+
+	// without phrases the requests are left as they were
+	options.Phrases = nil
+	if prompt := whisperPrompt(options); prompt != "Radio traffic." {
+		t.Errorf("unexpected whisper prompt %q", prompt)
+	}
+	if _, ok := assemblyAITranscriptBody("https://example.com/audio", options)["word_boost"]; ok {
+		t.Error("expected no word boost without phrases")
+	}
+}
+
+func TestNormalizePhrases(t *testing.T) {
+	phrases := normalizePhrases([]string{" Mayday ", "", "mayday", "Engine\x00 5", "ladder"})
+
+	if !reflect.DeepEqual(phrases, []string{"Mayday", "Engine 5", "ladder"}) {
+		t.Fatalf("unexpected phrases %q", phrases)
+	}
+}
+
+func TestVocabularyProfileKeywordListHints(t *testing.T) {
+	queue := newVocabularyProfilesQueue()
+	profiles := queue.controller.VocabularyProfiles
+
+	profiles.keywordLists = map[uint64][]string{
+		1: {"Main Street", "ladder", "Engine 5"},
+		2: {"Station 7"},
+	}
+	profiles.profiles[2].KeywordListIds = []uint64{1, 9, 2}
+
+	options := queue.transcriptionOptions(&Call{System: &System{Id: 1, VocabularyProfileId: 2}}, "audio/mp4")
+
+	// the keywords follow the profile phrases, repeated ones and missing lists are skipped
+	if want := []string{"ladder", "mayday", "Main Street", "Engine 5", "Station 7"}; !reflect.DeepEqual(options.Phrases, want) {
+		t.Fatalf("expected %v, got %v", want, options.Phrases)
+	}
+
+	google := &GoogleTranscription{}
+	config := google.buildRequestBody("", 16000, "en-US", options)["config"].(map[string]interface{})
+	if contexts, ok := config["speechContexts"].([]map[string]interface{}); !ok || !reflect.DeepEqual(contexts[0]["phrases"], options.Phrases) {
+		t.Errorf("expected the keyword hints in the google speech contexts, got %v", config["speechContexts"])
+	}
+
+	// profiles without keyword lists are not affected
+	if phrases := profiles.PhraseHints(&System{Id: 2, VocabularyProfileId: 3}); !reflect.DeepEqual(phrases, []string{"medic", "cardiac arrest"}) {
+		t.Errorf("unexpected phrases %v", phrases)
+	}
+}
+
+func TestVocabularyProfileKeywordListsSkipRegexes(t *testing.T) {
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+	d.rows = [][]driver.Value{{int64(1), `["ladder", "/UNIT \\d+ DOWN/", "Engine 5"]`}}
+
+	profiles := NewVocabularyProfiles()
+	if err := profiles.LoadKeywordLists(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"ladder", "Engine 5"}; !reflect.DeepEqual(profiles.keywordLists[1], want) {
+		t.Errorf("expected the regex keywords left out of the hints, got %v", profiles.keywordLists[1])
+	}
+}
+
+func TestNormalizeKeywordListIds(t *testing.T) {
+	if ids := normalizeKeywordListIds([]uint64{3, 0, 1, 3}); !reflect.DeepEqual(ids, []uint64{3, 1}) {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
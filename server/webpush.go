@@ -0,0 +1,384 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// deviceTokenPlatformWeb is the platform of the device tokens holding a browser push subscription as JSON
+const deviceTokenPlatformWeb = "web"
+
+const (
+	webPushRecordSize       = 4096
+	webPushTTL              = time.Hour      // alerts are stale past it, the push services drop them
+	webPushVapidExpiration  = 12 * time.Hour // at most 24 hours by RFC 8292
+	webPushMaxMessageLength = 1024           // bytes of the message, the encrypted payload being bound to 4096 bytes
+)
+
+// WebPushSubscription is the PushSubscription of a browser, as serialized by its toJSON()
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// parseWebPushSubscription reads the subscription stored as the token of a web device
+func parseWebPushSubscription(token string) (*WebPushSubscription, error) {
+	subscription := &WebPushSubscription{}
+	if err := json.Unmarshal([]byte(token), subscription); err != nil {
+		return nil, fmt.Errorf("invalid web push subscription: %v", err)
+	}
+
+	u, err := url.Parse(subscription.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("invalid web push subscription endpoint")
+	}
+
+	if host := u.Hostname(); strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return nil, errors.New("web push subscription endpoint on a local address")
+	} else if ip := net.ParseIP(host); ip != nil && !webPushPublicAddress(ip) {
+		return nil, errors.New("web push subscription endpoint on a local address")
+	}
+
+	if subscription.Keys.P256dh == "" || subscription.Keys.Auth == "" {
+		return nil, errors.New("web push subscription without keys")
+	}
+
+	return subscription, nil
+}
+
+// webPushPublicAddress tells if an address may be the one of a push service, the endpoints being
+// set by the clients and not to reach the hosts of the local network
+func webPushPublicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// webPushDialControl refuses the connections to local addresses, whatever the endpoint host resolved to
+func webPushDialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !webPushPublicAddress(ip) {
+		return fmt.Errorf("web push endpoint resolved to the local address %s", host)
+	}
+
+	return nil
+}
+
+// decodeWebPushKey decodes the base64url keys, padded or not
+func decodeWebPushKey(key string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(key), "="))
+}
+
+// GenerateVAPIDKeys returns a new P-256 key pair for the web push options, base64url encoded
+func GenerateVAPIDKeys() (publicKey string, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// vapidSigningKey returns the signing key of the configured VAPID key pair, checking that its keys match
+func vapidSigningKey(publicKey string, privateKey string) (*ecdsa.PrivateKey, error) {
+	d, err := decodeWebPushKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+
+	public := key.PublicKey().Bytes()
+	if configured, err := decodeWebPushKey(publicKey); err != nil || !bytes.Equal(configured, public) {
+		return nil, errors.New("the VAPID public key doesn't match the private key")
+	}
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, nil
+}
+
+// encryptWebPushPayload encrypts the payload for the subscription as a single aes128gcm record (RFC 8291),
+// with the salt and the ephemeral key of the application server
+func encryptWebPushPayload(subscription *WebPushSubscription, payload []byte, salt []byte, serverKey *ecdh.PrivateKey) ([]byte, error) {
+	uaPublic, err := decodeWebPushKey(subscription.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %v", err)
+	}
+
+	authSecret, err := decodeWebPushKey(subscription.Keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription auth secret: %v", err)
+	}
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %v", err)
+	}
+
+	ecdhSecret, err := serverKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(serverPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header holds the salt, the record size and the key of the application server
+	body := make([]byte, 0, 16+4+1+len(serverPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, webPushRecordSize)
+	body = append(body, byte(len(serverPublic)))
+	body = append(body, serverPublic...)
+
+	// the last record is delimited by 0x02
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	if len(body)+len(plaintext)+gcm.Overhead() > webPushRecordSize {
+		return nil, fmt.Errorf("web push payload of %d bytes too large", len(payload))
+	}
+
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// WebPushSender delivers the alerts to the browsers subscribed to web push, as device tokens of the web platform
+type WebPushSender struct {
+	controller *Controller
+	client     *http.Client
+	now        func() time.Time
+}
+
+func NewWebPushSender(controller *Controller) *WebPushSender {
+	return &WebPushSender{
+		controller: controller,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: webPushDialControl}).DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// webPushConfigured tells whether the VAPID keys are configured
+func webPushConfigured(options *Options) bool {
+	return options.WebPushVapidPublicKey != "" && options.WebPushVapidPrivateKey != ""
+}
+
+// Configured tells whether the alerts can be delivered with web push
+func (sender *WebPushSender) Configured() bool {
+	return sender != nil && webPushConfigured(sender.controller.Options)
+}
+
+// vapidAuthorization returns the Authorization header of a request to the push service of the endpoint (RFC 8292)
+func (sender *WebPushSender) vapidAuthorization(endpoint string) (string, error) {
+	options := sender.controller.Options
+
+	key, err := vapidSigningKey(options.WebPushVapidPublicKey, options.WebPushVapidPrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"aud": fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+		"exp": sender.now().Add(webPushVapidExpiration).Unix(),
+	}
+	if options.WebPushVapidSubject != "" {
+		claims["sub"] = options.WebPushVapidSubject
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, strings.TrimRight(options.WebPushVapidPublicKey, "=")), nil
+}
+
+// Send encrypts and posts the payload to the push service of the subscription, returning its http status
+func (sender *WebPushSender) Send(subscription *WebPushSubscription, payload []byte) (int, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, err
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := encryptWebPushPayload(subscription, payload, salt, serverKey)
+	if err != nil {
+		return 0, err
+	}
+
+	authorization, err := sender.vapidAuthorization(subscription.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+
+	resp, err := sender.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// SendToUser sends the notification to the web subscriptions of the user, deleting the ones the push service
+// reports expired or unsubscribed. It returns the number of subscriptions the notification was delivered to.
+func (sender *WebPushSender) SendToUser(userId uint64, title, subtitle, message string, data map[string]interface{}) int {
+	if !sender.Configured() {
+		return 0
+	}
+
+	controller := sender.controller
+	delivered := 0
+
+	for _, device := range controller.DeviceTokens.GetForUser(userId) {
+		if device.Platform != deviceTokenPlatformWeb {
+			continue
+		}
+
+		subscription, err := parseWebPushSubscription(device.Token)
+		if err != nil {
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("web push: device %d of user %d: %v", device.Id, userId, err))
+			continue
+		}
+
+		status, err := sender.Send(subscription, webPushPayload(title, subtitle, message, device.Sound, data))
+		switch {
+		case err != nil:
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("web push: failed to send to device %d of user %d: %v", device.Id, userId, err))
+		case status == http.StatusNotFound || status == http.StatusGone:
+			controller.Logs.LogEvent(LogLevelInfo, fmt.Sprintf("web push: removing expired subscription of device %d of user %d (status %d)", device.Id, userId, status))
+			if err := controller.DeviceTokens.Delete(device.Id, controller.Database); err != nil {
+				controller.Logs.LogEvent(LogLevelError, fmt.Sprintf("web push: failed to remove device %d of user %d: %v", device.Id, userId, err))
+			}
+		case status >= 300:
+			controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("web push: push service refused device %d of user %d (status %d)", device.Id, userId, status))
+		default:
+			delivered++
+			if err := controller.DeviceTokens.Touch(device.Token, controller.Database); err != nil {
+				controller.Logs.LogEvent(LogLevelWarn, fmt.Sprintf("web push: failed to record the use of device %d of user %d: %v", device.Id, userId, err))
+			}
+		}
+	}
+
+	return delivered
+}
+
+// webPushPayload returns the notification shown by the service worker of the client
+func webPushPayload(title, subtitle, message, sound string, data map[string]interface{}) []byte {
+	if sound == "" {
+		sound = "startup.wav"
+	}
+
+	message = truncatePushMessage(message, webPushMaxMessageLength)
+
+	payload := map[string]interface{}{
+		"title":   title,
+		"message": message,
+		"sound":   sound,
+		"data":    data,
+	}
+	if subtitle != "" {
+		payload["subtitle"] = subtitle
+	}
+
+	b, _ := json.Marshal(payload)
+	return b
+}
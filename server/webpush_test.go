@@ -0,0 +1,296 @@
+// Copyright (C) 2025 Thinline Dynamic Solutions
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// newWebPushBrowser returns the keys of a browser and its subscription to the endpoint
+func newWebPushBrowser(t *testing.T, endpoint string) (*ecdh.PrivateKey, []byte, *WebPushSubscription) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	subscription := &WebPushSubscription{Endpoint: endpoint}
+	subscription.Keys.P256dh = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	subscription.Keys.Auth = base64.RawURLEncoding.EncodeToString(auth)
+
+	return key, auth, subscription
+}
+
+// decryptWebPushPayload decrypts the body as the browser would (RFC 8291)
+func decryptWebPushPayload(t *testing.T, key *ecdh.PrivateKey, auth []byte, body []byte) []byte {
+	salt, rs, idlen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if rs != webPushRecordSize || idlen != 65 {
+		t.Fatalf("unexpected header: record size %d, key id of %d bytes", rs, idlen)
+	}
+
+	serverPublic, ciphertext := body[21:21+idlen], body[21+idlen:]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := key.ECDH(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ikm, _ := hkdf.Key(sha256.New, secret, auth, "WebPush: info\x00"+string(key.PublicKey().Bytes())+string(serverPublic), 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt the payload: %v", err)
+	}
+
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("expected the last record delimiter, got %x", plaintext[len(plaintext)-1])
+	}
+
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestEncryptWebPushPayload(t *testing.T) {
+	key, auth, subscription := newWebPushBrowser(t, "https://push.example.com/send/1")
+
+	serverKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	salt := make([]byte, 16)
+	rand.Read(salt)
+
+	payload := []byte(`{"title":"COUNTY FIRE / DISPATCH","message":"STRUCTURE FIRE"}`)
+
+	body, err := encryptWebPushPayload(subscription, payload, salt, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(body[:16], salt) {
+		t.Error("expected the salt heading the body")
+	}
+
+	if got := decryptWebPushPayload(t, key, auth, body); !bytes.Equal(got, payload) {
+		t.Errorf("expected %s, got %s", payload, got)
+	}
+
+	if _, err := encryptWebPushPayload(subscription, make([]byte, webPushRecordSize), salt, serverKey); err == nil {
+		t.Error("expected a payload over the record size refused")
+	}
+}
+
+func TestParseWebPushSubscription(t *testing.T) {
+	for token, valid := range map[string]bool{
+		`{"endpoint":"https://push.example.com/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`: true,
+		`{"endpoint":"http://push.example.com/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`:  false,
+		`{"endpoint":"https://push.example.com/1","keys":{"p256dh":"BAAA"}}`:               false,
+		`onesignal-player-id`: false,
+		`{"endpoint":"https://localhost/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`:       false,
+		`{"endpoint":"https://127.0.0.1:8080/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`:  false,
+		`{"endpoint":"https://169.254.169.254/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`: false,
+		`{"endpoint":"https://[fd00::1]/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`:       false,
+		`{"endpoint":"https://10.0.0.5/1","keys":{"p256dh":"BAAA","auth":"AAAA"}}`:        false,
+	} {
+		if _, err := parseWebPushSubscription(token); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", token, valid, err)
+		}
+	}
+}
+
+func TestWebPushSendToUser(t *testing.T) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingKey, err := vapidSigningKey(publicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		bodies = [][]byte{}
+		mutex  = sync.Mutex{}
+	)
+
+	var origin string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "vapid t=") || !strings.HasSuffix(authorization, ", k="+publicKey) {
+			t.Errorf("unexpected authorization %s", authorization)
+		}
+
+		token, err := jwt.Parse(strings.TrimSuffix(strings.TrimPrefix(authorization, "vapid t="), ", k="+publicKey), func(*jwt.Token) (interface{}, error) {
+			return &signingKey.PublicKey, nil
+		})
+		if err != nil {
+			t.Errorf("invalid VAPID token: %v", err)
+		} else if claims := token.Claims.(jwt.MapClaims); claims["aud"] != origin || claims["sub"] != "mailto:admin@example.com" {
+			t.Errorf("unexpected claims %v", claims)
+		}
+
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+
+		mutex.Lock()
+		bodies = append(bodies, body.Bytes())
+		mutex.Unlock()
+
+		// the second browser unsubscribed
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	// the endpoints name a public host, the test server standing in for it
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	origin = "https://example.com:" + port
+
+	db, d := newRecordingDatabase(t, &Config{DbType: DbTypePostgresql})
+
+	controller := &Controller{Database: db, Options: NewOptions(), Logs: NewLogs(), DeviceTokens: NewDeviceTokens()}
+	controller.Options.WebPushVapidPublicKey = publicKey
+	controller.Options.WebPushVapidPrivateKey = privateKey
+	controller.Options.WebPushVapidSubject = "mailto:admin@example.com"
+
+	sender := NewWebPushSender(controller)
+	sender.client = server.Client()
+	sender.client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	key, auth, subscribed := newWebPushBrowser(t, origin+"/send/1")
+	_, _, gone := newWebPushBrowser(t, origin+"/gone")
+
+	for i, device := range []*DeviceToken{
+		{Id: 1, UserId: 5, Platform: deviceTokenPlatformWeb},
+		{Id: 2, UserId: 5, Platform: deviceTokenPlatformWeb},
+		{Id: 3, UserId: 5, Platform: "android", Token: "player-id"},
+	} {
+		if i < 2 {
+			subscription, _ := json.Marshal([]*WebPushSubscription{subscribed, gone}[i])
+			device.Token = string(subscription)
+		}
+		controller.DeviceTokens.tokens[device.Id] = device
+		controller.DeviceTokens.userTokens[device.UserId] = append(controller.DeviceTokens.userTokens[device.UserId], device)
+	}
+
+	if delivered := sender.SendToUser(5, "COUNTY FIRE", "", "STRUCTURE FIRE", map[string]interface{}{"callId": 7}); delivered != 1 {
+		t.Errorf("expected 1 delivery, got %d", delivered)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected the web devices pushed to, got %d requests", len(bodies))
+	}
+
+	notification := map[string]interface{}{}
+	if err := json.Unmarshal(decryptWebPushPayload(t, key, auth, bodies[0]), &notification); err != nil {
+		t.Fatal(err)
+	}
+	if notification["title"] != "COUNTY FIRE" || notification["message"] != "STRUCTURE FIRE" || notification["sound"] != "startup.wav" {
+		t.Errorf("unexpected notification %v", notification)
+	}
+
+	devices := controller.DeviceTokens.GetForUser(5)
+	if len(devices) != 2 || devices[0].Id != 1 || devices[1].Id != 3 {
+		t.Errorf("expected the unsubscribed device removed, got %v", devices)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// the use of the delivered device is recorded
+	if len(d.queries) != 2 || !strings.Contains(d.queries[0], `SET "lastUsed"`) {
+		t.Fatalf("expected the use of the device 1 recorded, got %v %v", d.queries, d.args)
+	}
+	if !strings.Contains(d.queries[1], `DELETE FROM "deviceTokens"`) || d.args[1][0] != int64(2) {
+		t.Errorf("expected the device 2 deleted, got %v %v", d.queries, d.args)
+	}
+}
+
+func TestVapidSigningKeyMismatch(t *testing.T) {
+	publicKey, _, _ := GenerateVAPIDKeys()
+	_, privateKey, _ := GenerateVAPIDKeys()
+
+	if _, err := vapidSigningKey(publicKey, privateKey); err == nil {
+		t.Error("expected mismatching VAPID keys refused")
+	}
+}
+
+func TestWebPushDialControl(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"142.250.74.74:443":     true,
+		"127.0.0.1:443":         false,
+		"192.168.1.10:443":      false,
+		"169.254.169.254:80":    false,
+		"[::1]:443":             false,
+		"[fe80::1]:443":         false,
+		"[2607:f8b0::200e]:443": true,
+	} {
+		if err := webPushDialControl("tcp", address, nil); (err == nil) != allowed {
+			t.Errorf("%s: expected allowed %v, got %v", address, allowed, err)
+		}
+	}
+}
+
+func TestOptionsKeepVapidKeys(t *testing.T) {
+	options := NewOptions()
+	options.WebPushVapidPublicKey = "public"
+	options.WebPushVapidPrivateKey = "private"
+
+	options.FromMap(map[string]any{"webPushVapidSubject": "mailto:admin@example.com"})
+
+	if options.WebPushVapidPublicKey != "public" || options.WebPushVapidPrivateKey != "private" {
+		t.Errorf("expected the VAPID keys kept, got %q %q", options.WebPushVapidPublicKey, options.WebPushVapidPrivateKey)
+	}
+}